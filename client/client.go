@@ -23,8 +23,11 @@ import (
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/pkg/grpcutil"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 // Client is a PD (Placement Driver) client.
@@ -44,6 +47,9 @@ type Client interface {
 	// taking care of region change.
 	// Also it may return nil if PD finds no Region for the key temporarily,
 	// client should retry later.
+	// The down and pending peers of the region are filled in the status
+	// attached to the ctx by WithPeerStatus, as well as by GetPrevRegion and
+	// GetRegionByID.
 	GetRegion(ctx context.Context, key []byte) (*metapb.Region, *metapb.Peer, error)
 	// GetPrevRegion gets the previous region and its leader Peer of the region where the key is located.
 	GetPrevRegion(ctx context.Context, key []byte) (*metapb.Region, *metapb.Peer, error)
@@ -67,7 +73,8 @@ type Client interface {
 	// Returns the new safePoint after updating.
 	UpdateGCSafePoint(ctx context.Context, safePoint uint64) (uint64, error)
 	// ScatterRegion scatters the specified region. Should use it for a batch of regions,
-	// and the distribution of these regions will be dispersed.
+	// and the distribution of these regions will be dispersed. The regions are
+	// dispersed in the scatter group attached to the ctx by WithScatterGroup.
 	ScatterRegion(ctx context.Context, regionID uint64) error
 	// GetOperator gets the status of operator of the specified region.
 	GetOperator(ctx context.Context, regionID uint64) (*pdpb.GetOperatorResponse, error)
@@ -431,11 +438,12 @@ func (c *client) GetRegion(ctx context.Context, key []byte) (*metapb.Region, *me
 	start := time.Now()
 	defer func() { cmdDurationGetRegion.Observe(time.Since(start).Seconds()) }()
 
+	ctx, opts, fillPeerStatus := withPeerStatusRequest(ctx)
 	ctx, cancel := context.WithTimeout(ctx, pdTimeout)
 	resp, err := c.leaderClient().GetRegion(ctx, &pdpb.GetRegionRequest{
 		Header:    c.requestHeader(),
 		RegionKey: key,
-	})
+	}, opts...)
 	cancel()
 
 	if err != nil {
@@ -443,6 +451,9 @@ func (c *client) GetRegion(ctx context.Context, key []byte) (*metapb.Region, *me
 		c.ScheduleCheckLeader()
 		return nil, nil, errors.WithStack(err)
 	}
+	if err := fillPeerStatus(); err != nil {
+		return nil, nil, err
+	}
 	return resp.GetRegion(), resp.GetLeader(), nil
 }
 
@@ -454,11 +465,12 @@ func (c *client) GetPrevRegion(ctx context.Context, key []byte) (*metapb.Region,
 	start := time.Now()
 	defer func() { cmdDurationGetPrevRegion.Observe(time.Since(start).Seconds()) }()
 
+	ctx, opts, fillPeerStatus := withPeerStatusRequest(ctx)
 	ctx, cancel := context.WithTimeout(ctx, pdTimeout)
 	resp, err := c.leaderClient().GetPrevRegion(ctx, &pdpb.GetRegionRequest{
 		Header:    c.requestHeader(),
 		RegionKey: key,
-	})
+	}, opts...)
 	cancel()
 
 	if err != nil {
@@ -466,6 +478,9 @@ func (c *client) GetPrevRegion(ctx context.Context, key []byte) (*metapb.Region,
 		c.ScheduleCheckLeader()
 		return nil, nil, errors.WithStack(err)
 	}
+	if err := fillPeerStatus(); err != nil {
+		return nil, nil, err
+	}
 	return resp.GetRegion(), resp.GetLeader(), nil
 }
 
@@ -477,11 +492,12 @@ func (c *client) GetRegionByID(ctx context.Context, regionID uint64) (*metapb.Re
 	start := time.Now()
 	defer func() { cmdDurationGetRegionByID.Observe(time.Since(start).Seconds()) }()
 
+	ctx, opts, fillPeerStatus := withPeerStatusRequest(ctx)
 	ctx, cancel := context.WithTimeout(ctx, pdTimeout)
 	resp, err := c.leaderClient().GetRegionByID(ctx, &pdpb.GetRegionByIDRequest{
 		Header:   c.requestHeader(),
 		RegionId: regionID,
-	})
+	}, opts...)
 	cancel()
 
 	if err != nil {
@@ -489,6 +505,9 @@ func (c *client) GetRegionByID(ctx context.Context, regionID uint64) (*metapb.Re
 		c.ScheduleCheckLeader()
 		return nil, nil, errors.WithStack(err)
 	}
+	if err := fillPeerStatus(); err != nil {
+		return nil, nil, err
+	}
 	return resp.GetRegion(), resp.GetLeader(), nil
 }
 
@@ -604,6 +623,49 @@ func (c *client) UpdateGCSafePoint(ctx context.Context, safePoint uint64) (uint6
 	return resp.GetNewSafePoint(), nil
 }
 
+// RegionPeerStatus is the status of the peers of a region, which is filled by
+// GetRegion, GetPrevRegion and GetRegionByID with the ctx returned by
+// WithPeerStatus.
+type RegionPeerStatus struct {
+	DownPeers    []*pdpb.PeerStats
+	PendingPeers []*metapb.Peer
+}
+
+type peerStatusKey struct{}
+
+// WithPeerStatus returns a context by which GetRegion, GetPrevRegion and
+// GetRegionByID fill the down and pending peers of the region in status.
+func WithPeerStatus(ctx context.Context, status *RegionPeerStatus) context.Context {
+	return context.WithValue(ctx, peerStatusKey{}, status)
+}
+
+// withPeerStatusRequest asks for the peer status if the ctx is returned by
+// WithPeerStatus, and returns the call options receiving it and the function
+// filling it after the call.
+func withPeerStatusRequest(ctx context.Context) (context.Context, []grpc.CallOption, func() error) {
+	status, ok := ctx.Value(peerStatusKey{}).(*RegionPeerStatus)
+	if !ok || status == nil {
+		return ctx, nil, func() error { return nil }
+	}
+	var header metadata.MD
+	fill := func() error {
+		downPeers, pendingPeers, err := grpcutil.ParsePeerStatusHeader(header)
+		if err != nil {
+			return err
+		}
+		status.DownPeers, status.PendingPeers = downPeers, pendingPeers
+		return nil
+	}
+	return grpcutil.WithPeerStatusRequest(ctx), []grpc.CallOption{grpc.Header(&header)}, fill
+}
+
+// WithScatterGroup returns a context by which ScatterRegion disperses the
+// regions among the ones of the same scatter group, regardless of the other
+// groups.
+func WithScatterGroup(ctx context.Context, group string) context.Context {
+	return grpcutil.WithScatterGroup(ctx, group)
+}
+
 func (c *client) ScatterRegion(ctx context.Context, regionID uint64) error {
 	if span := opentracing.SpanFromContext(ctx); span != nil {
 		span = opentracing.StartSpan("pdclient.ScatterRegion", opentracing.ChildOf(span.Context()))
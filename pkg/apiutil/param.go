@@ -0,0 +1,86 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiutil
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+)
+
+const (
+	// MaxIDParamLength is the max length of an ID parameter, which is the
+	// number of the digits of the max uint64.
+	MaxIDParamLength = 20
+	// MaxHexKeyParamLength is the max length of a hex key parameter, which
+	// encodes a key of 8 KiB.
+	MaxHexKeyParamLength = 16 * 1024
+)
+
+// ParamError is the error of an invalid request parameter. The handlers
+// respond it with 400 Bad Request.
+type ParamError struct {
+	Param  string
+	Reason string
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Param, e.Reason)
+}
+
+// ParseRegionIDParam parses the region ID in a path or query parameter.
+func ParseRegionIDParam(s string) (uint64, error) {
+	return parseIDParam("region id", s)
+}
+
+// ParseStoreIDParam parses the store ID in a path or query parameter.
+func ParseStoreIDParam(s string) (uint64, error) {
+	return parseIDParam("store id", s)
+}
+
+func parseIDParam(param, s string) (uint64, error) {
+	if s == "" {
+		return 0, &ParamError{Param: param, Reason: "should not be empty"}
+	}
+	if len(s) > MaxIDParamLength {
+		return 0, &ParamError{Param: param, Reason: fmt.Sprintf("should be at most %d digits", MaxIDParamLength)}
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, &ParamError{Param: param, Reason: fmt.Sprintf("%q is not a decimal integer", s)}
+		}
+	}
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, &ParamError{Param: param, Reason: fmt.Sprintf("%s is out of range", s)}
+	}
+	return id, nil
+}
+
+// ParseHexKeyParam parses the key in hex format, which is named param in the
+// errors. The empty string is parsed as the empty key, so that the callers
+// decide whether the key is required.
+func ParseHexKeyParam(param, s string) ([]byte, error) {
+	if len(s) > MaxHexKeyParamLength {
+		return nil, &ParamError{Param: param, Reason: fmt.Sprintf("should be at most %d hex digits", MaxHexKeyParamLength)}
+	}
+	if len(s)%2 != 0 {
+		return nil, &ParamError{Param: param, Reason: "should have an even number of hex digits"}
+	}
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, &ParamError{Param: param, Reason: "should be in hex format"}
+	}
+	return key, nil
+}
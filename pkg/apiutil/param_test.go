@@ -0,0 +1,119 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiutil
+
+import (
+	"encoding/hex"
+	"math/rand"
+	"strings"
+	"testing"
+
+	. "github.com/pingcap/check"
+)
+
+func TestAPIUtil(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&testParamSuite{})
+
+type testParamSuite struct{}
+
+func (s *testParamSuite) TestParseIDParam(c *C) {
+	id, err := ParseRegionIDParam("42")
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, uint64(42))
+	id, err = ParseStoreIDParam("18446744073709551615")
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, uint64(18446744073709551615))
+
+	for _, t := range []struct {
+		s      string
+		reason string
+	}{
+		{"", "should not be empty"},
+		{"-1", "is not a decimal integer"},
+		{"+1", "is not a decimal integer"},
+		{"0x10", "is not a decimal integer"},
+		{"1 ", "is not a decimal integer"},
+		{"18446744073709551616", "is out of range"},
+		{strings.Repeat("1", MaxIDParamLength+1), "should be at most"},
+	} {
+		_, err := ParseRegionIDParam(t.s)
+		c.Assert(err, NotNil)
+		c.Assert(err, FitsTypeOf, &ParamError{})
+		c.Assert(err.(*ParamError).Param, Equals, "region id")
+		c.Assert(err.Error(), Matches, ".*"+t.reason+".*")
+	}
+	_, err = ParseStoreIDParam("a")
+	c.Assert(err, ErrorMatches, "invalid store id: .*")
+}
+
+func (s *testParamSuite) TestParseHexKeyParam(c *C) {
+	key, err := ParseHexKeyParam("key", "")
+	c.Assert(err, IsNil)
+	c.Assert(key, HasLen, 0)
+	key, err = ParseHexKeyParam("key", "7A7a00")
+	c.Assert(err, IsNil)
+	c.Assert(key, DeepEquals, []byte("zz\x00"))
+	_, err = ParseHexKeyParam("key", strings.Repeat("00", MaxHexKeyParamLength/2))
+	c.Assert(err, IsNil)
+
+	for _, t := range []struct {
+		s      string
+		reason string
+	}{
+		{"abc", "should have an even number of hex digits"},
+		{"zz", "should be in hex format"},
+		{"0x00", "should be in hex format"},
+		{strings.Repeat("00", MaxHexKeyParamLength/2+1), "should be at most"},
+	} {
+		_, err := ParseHexKeyParam("start_key", t.s)
+		c.Assert(err, FitsTypeOf, &ParamError{})
+		c.Assert(err, ErrorMatches, "invalid start_key: .*"+t.reason+".*")
+	}
+}
+
+// TestParseHexKeyParamRandomly checks the properties of the parsing against
+// the random inputs, which are mostly hex digits.
+func (s *testParamSuite) TestParseHexKeyParamRandomly(c *C) {
+	const alphabet = "0123456789abcdefABCDEFxz\xff"
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 10000; i++ {
+		n := r.Intn(16)
+		if i%100 == 0 {
+			// Around the limit of the length.
+			n = MaxHexKeyParamLength - 2 + r.Intn(4)
+		}
+		b := make([]byte, n)
+		for j := range b {
+			if r.Intn(50) == 0 {
+				b[j] = alphabet[r.Intn(len(alphabet))]
+			} else {
+				b[j] = alphabet[r.Intn(22)]
+			}
+		}
+		str := string(b)
+
+		key, err := ParseHexKeyParam("key", str)
+		if err != nil {
+			c.Assert(err, FitsTypeOf, &ParamError{})
+			_, herr := hex.DecodeString(str)
+			c.Assert(herr != nil || len(str) > MaxHexKeyParamLength, IsTrue, Commentf("valid key %q is rejected: %v", str, err))
+			continue
+		}
+		c.Assert(len(str) <= MaxHexKeyParamLength, IsTrue, Commentf("invalid key %q is accepted", str))
+		c.Assert(strings.EqualFold(hex.EncodeToString(key), str), IsTrue, Commentf("key %q is not round-tripped: %x", str, key))
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/clientv3"
 	"go.etcd.io/etcd/etcdserver"
+	"go.etcd.io/etcd/etcdserver/etcdserverpb"
 	"go.etcd.io/etcd/pkg/types"
 	"go.uber.org/zap"
 )
@@ -39,6 +40,10 @@ const (
 	// DefaultSlowRequestTime 1s for the threshold for normal request, for those
 	// longer then 1s, they are considered as slow requests.
 	DefaultSlowRequestTime = 1 * time.Second
+
+	// DefaultDefragmentTimeout is the timeout of defragmenting a member, which
+	// may take a long time if the database is large.
+	DefaultDefragmentTimeout = 5 * time.Minute
 )
 
 // CheckClusterID checks Etcd's cluster ID, returns an error if mismatch.
@@ -97,6 +102,47 @@ func RemoveEtcdMember(client *clientv3.Client, id uint64) (*clientv3.MemberRemov
 	return rmResp, errors.WithStack(err)
 }
 
+// GetEtcdStatus returns the status of the etcd member serving the given endpoint.
+func GetEtcdStatus(client *clientv3.Client, endpoint string) (*clientv3.StatusResponse, error) {
+	ctx, cancel := context.WithTimeout(client.Ctx(), DefaultRequestTimeout)
+	statusResp, err := client.Status(ctx, endpoint)
+	cancel()
+	return statusResp, errors.WithStack(err)
+}
+
+// CountEtcdLeases returns the number of leases known by the etcd member
+// serving the given endpoint. The leases are listed from the member itself
+// rather than the cluster, so it works even if the member is partitioned.
+func CountEtcdLeases(ctx context.Context, client *clientv3.Client, endpoint string) (int, error) {
+	conn, err := client.Dial(endpoint)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer conn.Close()
+	resp, err := etcdserverpb.NewLeaseClient(conn).LeaseLeases(ctx, &etcdserverpb.LeaseLeasesRequest{})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return len(resp.GetLeases()), nil
+}
+
+// CompactEtcd compacts the etcd key-value store up to the given revision.
+func CompactEtcd(client *clientv3.Client, rev int64) error {
+	ctx, cancel := context.WithTimeout(client.Ctx(), DefaultRequestTimeout)
+	_, err := client.Compact(ctx, rev, clientv3.WithCompactPhysical())
+	cancel()
+	return errors.WithStack(err)
+}
+
+// DefragmentEtcd defragments the backend database of the etcd member serving
+// the given endpoint. The member is blocked from serving during defragmentation.
+func DefragmentEtcd(client *clientv3.Client, endpoint string) error {
+	ctx, cancel := context.WithTimeout(client.Ctx(), DefaultDefragmentTimeout)
+	_, err := client.Defragment(ctx, endpoint)
+	cancel()
+	return errors.WithStack(err)
+}
+
 // EtcdKVGet returns the etcd GetResponse by given key or key prefix
 func EtcdKVGet(c *clientv3.Client, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
 	ctx, cancel := context.WithTimeout(c.Ctx(), DefaultRequestTimeout)
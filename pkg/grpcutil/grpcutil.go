@@ -18,10 +18,30 @@ import (
 	"crypto/tls"
 	"net/url"
 
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/pkg/transport"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// ScatterGroupMetadataKey is the key of the gRPC metadata carrying the scatter
+// group of the ScatterRegion request, which has no field for it.
+const ScatterGroupMetadataKey = "pd-scatter-group"
+
+const (
+	// PeerStatusMetadataKey is the key of the gRPC metadata asking the
+	// GetRegion, GetPrevRegion and GetRegionByID requests for the down and
+	// pending peers of the region, which the responses have no field for.
+	PeerStatusMetadataKey = "pd-peer-status"
+	// DownPeersMetadataKey is the key of the gRPC header carrying the down
+	// peers, each value of which is a marshaled pdpb.PeerStats.
+	DownPeersMetadataKey = "pd-down-peers-bin"
+	// PendingPeersMetadataKey is the key of the gRPC header carrying the
+	// pending peers, each value of which is a marshaled metapb.Peer.
+	PendingPeersMetadataKey = "pd-pending-peers-bin"
 )
 
 // SecurityConfig is the configuration for supporting tls.
@@ -101,3 +121,81 @@ func GetClientConn(ctx context.Context, addr string, tlsCfg *tls.Config, do ...g
 	}
 	return cc, nil
 }
+
+// WithScatterGroup attaches the scatter group to the outgoing context of the
+// ScatterRegion request.
+func WithScatterGroup(ctx context.Context, group string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, ScatterGroupMetadataKey, group)
+}
+
+// GetScatterGroup returns the scatter group in the incoming context of the
+// ScatterRegion request, or empty if there is none.
+func GetScatterGroup(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if groups := md.Get(ScatterGroupMetadataKey); len(groups) > 0 {
+		return groups[0]
+	}
+	return ""
+}
+
+// WithPeerStatusRequest asks for the down and pending peers of the region in
+// the outgoing context of the GetRegion requests.
+func WithPeerStatusRequest(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, PeerStatusMetadataKey, "true")
+}
+
+// IsPeerStatusRequested returns true if the incoming context of the GetRegion
+// requests asks for the down and pending peers of the region.
+func IsPeerStatusRequested(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	values := md.Get(PeerStatusMetadataKey)
+	return len(values) > 0 && values[0] == "true"
+}
+
+// NewPeerStatusHeader returns the gRPC header carrying the down and pending
+// peers.
+func NewPeerStatusHeader(downPeers []*pdpb.PeerStats, pendingPeers []*metapb.Peer) (metadata.MD, error) {
+	md := metadata.MD{}
+	for _, peer := range downPeers {
+		data, err := peer.Marshal()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		md.Append(DownPeersMetadataKey, string(data))
+	}
+	for _, peer := range pendingPeers {
+		data, err := peer.Marshal()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		md.Append(PendingPeersMetadataKey, string(data))
+	}
+	return md, nil
+}
+
+// ParsePeerStatusHeader returns the down and pending peers in the gRPC header.
+func ParsePeerStatusHeader(md metadata.MD) ([]*pdpb.PeerStats, []*metapb.Peer, error) {
+	var downPeers []*pdpb.PeerStats
+	for _, value := range md.Get(DownPeersMetadataKey) {
+		peer := &pdpb.PeerStats{}
+		if err := peer.Unmarshal([]byte(value)); err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		downPeers = append(downPeers, peer)
+	}
+	var pendingPeers []*metapb.Peer
+	for _, value := range md.Get(PendingPeersMetadataKey) {
+		peer := &metapb.Peer{}
+		if err := peer.Unmarshal([]byte(value)); err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		pendingPeers = append(pendingPeers, peer)
+	}
+	return downPeers, pendingPeers, nil
+}
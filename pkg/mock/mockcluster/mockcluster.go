@@ -38,7 +38,11 @@ type Cluster struct {
 	*placement.RuleManager
 	*statistics.HotCache
 	*statistics.StoresStats
-	ID uint64
+	*core.ScheduleLocks
+	*core.LeaderPins
+	*core.RegionTracer
+	annotations *core.RegionAnnotations
+	ID          uint64
 }
 
 // NewCluster creates a new Cluster
@@ -50,8 +54,12 @@ func NewCluster(opt *mockoption.ScheduleOptions) *Cluster {
 		IDAllocator:     mockid.NewIDAllocator(),
 		ScheduleOptions: opt,
 		RuleManager:     ruleManager,
-		HotCache:        statistics.NewHotCache(),
+		HotCache:        statistics.NewHotCache(opt),
 		StoresStats:     statistics.NewStoresStats(),
+		ScheduleLocks:   core.NewScheduleLocks(core.NewStorage(kv.NewMemoryKV())),
+		LeaderPins:      core.NewLeaderPins(core.NewStorage(kv.NewMemoryKV())),
+		RegionTracer:    core.NewRegionTracer(),
+		annotations:     core.NewRegionAnnotations(),
 	}
 }
 
@@ -130,6 +138,26 @@ func (mc *Cluster) FitRegion(region *core.RegionInfo) *placement.RegionFit {
 	return mc.RuleManager.FitRegion(mc.BasicCluster, region)
 }
 
+// IsRegionScheduleLocked returns true if the region is protected by a schedule lock.
+func (mc *Cluster) IsRegionScheduleLocked(region *core.RegionInfo) bool {
+	return mc.ScheduleLocks.IsRegionLocked(region)
+}
+
+// IsRegionLeaderPinned returns true if the leader of the region is pinned.
+func (mc *Cluster) IsRegionLeaderPinned(regionID uint64) bool {
+	return mc.LeaderPins.IsRegionPinned(regionID)
+}
+
+// GetRegionTracer returns the region tracer of the cluster.
+func (mc *Cluster) GetRegionTracer() *core.RegionTracer {
+	return mc.RegionTracer
+}
+
+// GetRegionAnnotations returns the region annotations of the cluster.
+func (mc *Cluster) GetRegionAnnotations() *core.RegionAnnotations {
+	return mc.annotations
+}
+
 // GetRuleManager returns the ruleManager of the cluster.
 func (mc *Cluster) GetRuleManager() *placement.RuleManager {
 	return mc.RuleManager
@@ -184,6 +212,18 @@ func (mc *Cluster) SetStoreBusy(storeID uint64, busy bool) {
 	mc.PutStore(newStore)
 }
 
+// UpdateStoreOpLatency simulates a store heartbeat reporting the latency of an operation.
+func (mc *Cluster) UpdateStoreOpLatency(storeID uint64, key string, latency uint64) {
+	store := mc.GetStore(storeID)
+	newStats := proto.Clone(store.GetStoreStats()).(*pdpb.StoreStats)
+	newStats.OpLatencies = []*pdpb.RecordPair{{Key: key, Value: latency}}
+	newStore := store.Clone(
+		core.SetStoreStats(newStats),
+		core.SetLastHeartbeatTS(time.Now()),
+	)
+	mc.PutStore(newStore)
+}
+
 // AddLeaderStore adds store with specified count of leader.
 func (mc *Cluster) AddLeaderStore(storeID uint64, leaderCount int, leaderSizes ...int64) {
 	stats := &pdpb.StoreStats{}
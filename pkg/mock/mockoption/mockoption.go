@@ -39,10 +39,18 @@ const (
 	defaultHighSpaceRatio              = 0.6
 	defaultSchedulerMaxWaitingOperator = 3
 	defaultHotRegionCacheHitsThreshold = 3
+	defaultHotWriteByteRateThreshold   = 1 * 1024
+	defaultHotWriteKeyRateThreshold    = 32
+	defaultHotReadByteRateThreshold    = 8 * 1024
+	defaultHotReadKeyRateThreshold     = 128
 	defaultStrictlyMatchLabel          = true
 	defaultLeaderSchedulePolicy        = "count"
 	defaultEnablePlacementRules        = false
 	defaultKeyType                     = "table"
+	defaultOperatorPingPongWindow      = 10 * time.Minute
+	defaultOperatorPingPongAction      = "reject"
+	defaultScatterGroupTTL             = 10 * time.Minute
+	defaultScatterStoreSelectionLimit  = 1
 )
 
 // ScheduleOptions is a mock of ScheduleOptions
@@ -59,6 +67,12 @@ type ScheduleOptions struct {
 	MaxMergeRegionSize           uint64
 	MaxMergeRegionKeys           uint64
 	SchedulerMaxWaitingOperator  uint64
+	OperatorPingPongLimit        uint64
+	OperatorPingPongWindow       time.Duration
+	OperatorPingPongAction       string
+	ScatterGroupTTL              time.Duration
+	ScatterStoreSelectionLimit   uint64
+	ScatterIncludeBusyStores     bool
 	SplitMergeInterval           time.Duration
 	EnableOneWayMerge            bool
 	EnableCrossTableMerge        bool
@@ -68,6 +82,10 @@ type ScheduleOptions struct {
 	LocationLabels               []string
 	StrictlyMatchLabel           bool
 	HotRegionCacheHitsThreshold  int
+	HotWriteByteRateThreshold    float64
+	HotWriteKeyRateThreshold     float64
+	HotReadByteRateThreshold     float64
+	HotReadKeyRateThreshold      float64
 	TolerantSizeRatio            float64
 	LowSpaceRatio                float64
 	HighSpaceRatio               float64
@@ -100,12 +118,20 @@ func NewScheduleOptions() *ScheduleOptions {
 	mso.MaxMergeRegionSize = defaultMaxMergeRegionSize
 	mso.MaxMergeRegionKeys = defaultMaxMergeRegionKeys
 	mso.SchedulerMaxWaitingOperator = defaultSchedulerMaxWaitingOperator
+	mso.OperatorPingPongWindow = defaultOperatorPingPongWindow
+	mso.OperatorPingPongAction = defaultOperatorPingPongAction
+	mso.ScatterGroupTTL = defaultScatterGroupTTL
+	mso.ScatterStoreSelectionLimit = defaultScatterStoreSelectionLimit
 	mso.SplitMergeInterval = defaultSplitMergeInterval
 	mso.MaxStoreDownTime = defaultMaxStoreDownTime
 	mso.MaxReplicas = defaultMaxReplicas
 	mso.StrictlyMatchLabel = defaultStrictlyMatchLabel
 	mso.EnablePlacementRules = defaultEnablePlacementRules
 	mso.HotRegionCacheHitsThreshold = defaultHotRegionCacheHitsThreshold
+	mso.HotWriteByteRateThreshold = defaultHotWriteByteRateThreshold
+	mso.HotWriteKeyRateThreshold = defaultHotWriteKeyRateThreshold
+	mso.HotReadByteRateThreshold = defaultHotReadByteRateThreshold
+	mso.HotReadKeyRateThreshold = defaultHotReadKeyRateThreshold
 	mso.MaxPendingPeerCount = defaultMaxPendingPeerCount
 	mso.TolerantSizeRatio = defaultTolerantSizeRatio
 	mso.LowSpaceRatio = defaultLowSpaceRatio
@@ -215,6 +241,16 @@ func (mso *ScheduleOptions) GetHotRegionCacheHitsThreshold() int {
 	return mso.HotRegionCacheHitsThreshold
 }
 
+// GetHotWriteThresholds mocks method
+func (mso *ScheduleOptions) GetHotWriteThresholds() (float64, float64) {
+	return mso.HotWriteByteRateThreshold, mso.HotWriteKeyRateThreshold
+}
+
+// GetHotReadThresholds mocks method
+func (mso *ScheduleOptions) GetHotReadThresholds() (float64, float64) {
+	return mso.HotReadByteRateThreshold, mso.HotReadKeyRateThreshold
+}
+
 // GetTolerantSizeRatio mocks method
 func (mso *ScheduleOptions) GetTolerantSizeRatio() float64 {
 	return mso.TolerantSizeRatio
@@ -235,6 +271,36 @@ func (mso *ScheduleOptions) GetSchedulerMaxWaitingOperator() uint64 {
 	return mso.SchedulerMaxWaitingOperator
 }
 
+// GetOperatorPingPongLimit mocks method.
+func (mso *ScheduleOptions) GetOperatorPingPongLimit() uint64 {
+	return mso.OperatorPingPongLimit
+}
+
+// GetOperatorPingPongWindow mocks method.
+func (mso *ScheduleOptions) GetOperatorPingPongWindow() time.Duration {
+	return mso.OperatorPingPongWindow
+}
+
+// GetOperatorPingPongAction mocks method.
+func (mso *ScheduleOptions) GetOperatorPingPongAction() string {
+	return mso.OperatorPingPongAction
+}
+
+// GetScatterGroupTTL mocks method.
+func (mso *ScheduleOptions) GetScatterGroupTTL() time.Duration {
+	return mso.ScatterGroupTTL
+}
+
+// GetScatterStoreSelectionLimit mocks method.
+func (mso *ScheduleOptions) GetScatterStoreSelectionLimit() uint64 {
+	return mso.ScatterStoreSelectionLimit
+}
+
+// IsScatterBusyStoresIncluded mocks method.
+func (mso *ScheduleOptions) IsScatterBusyStoresIncluded() bool {
+	return mso.ScatterIncludeBusyStores
+}
+
 // SetMaxReplicas mocks method
 func (mso *ScheduleOptions) SetMaxReplicas(replicas int) {
 	mso.MaxReplicas = replicas
@@ -27,3 +27,9 @@ func ParseTS(ts uint64) (time.Time, uint64) {
 	physicalTime := time.Unix(int64(physical/1000), int64(physical)%1000*time.Millisecond.Nanoseconds())
 	return physicalTime, logical
 }
+
+// ComposeTS composes the ts from the physical part in milliseconds and the
+// logical part.
+func ComposeTS(physical, logical int64) uint64 {
+	return uint64(physical)<<physicalShiftBits + uint64(logical)
+}
@@ -14,12 +14,16 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/pd/v4/pkg/apiutil"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/pkg/errors"
 	"github.com/unrolled/render"
 )
 
@@ -37,9 +41,7 @@ func newAdminHandler(svr *server.Server, rd *render.Render) *adminHandler {
 
 func (h *adminHandler) HandleDropCacheRegion(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r.Context())
-	vars := mux.Vars(r)
-	regionIDStr := vars["id"]
-	regionID, err := strconv.ParseUint(regionIDStr, 10, 64)
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["id"])
 	if err != nil {
 		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
@@ -48,6 +50,72 @@ func (h *adminHandler) HandleDropCacheRegion(w http.ResponseWriter, r *http.Requ
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
+// defaultRegionTraceTTL is the default lifetime of a region trace in seconds.
+const defaultRegionTraceTTL = 600
+
+func (h *adminHandler) EnableRegionTrace(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["id"])
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ttl := uint64(defaultRegionTraceTTL)
+	if ttlStr := r.URL.Query().Get("ttl"); ttlStr != "" {
+		ttl, err = strconv.ParseUint(ttlStr, 10, 64)
+		if err != nil || ttl == 0 {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid ttl value")
+			return
+		}
+	}
+	if err = rc.GetRegionTracer().Enable(regionID, time.Duration(ttl)*time.Second); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *adminHandler) GetRegionTrace(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["id"])
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	trace := rc.GetRegionTracer().GetTrace(regionID)
+	if trace == nil {
+		h.rd.JSON(w, http.StatusNotFound, "the region is not traced")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, trace)
+}
+
+func (h *adminHandler) DisableRegionTrace(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["id"])
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rc.GetRegionTracer().Disable(regionID)
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *adminHandler) ExplainRegionCheck(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["id"])
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	explanation := rc.ExplainRegionCheck(regionID)
+	if explanation == nil {
+		h.rd.JSON(w, http.StatusNotFound, server.ErrRegionNotFound(regionID).Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, explanation)
+}
+
 func (h *adminHandler) ResetTS(w http.ResponseWriter, r *http.Request) {
 	handler := h.svr.GetHandler()
 	var input map[string]interface{}
@@ -65,12 +133,126 @@ func (h *adminHandler) ResetTS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = handler.ResetTS(ts); err != nil {
+	opts := server.ResetTSOptions{Consumer: getOperatorConsumer(r)}
+	if v, ok := input["safe-mode"]; ok {
+		if opts.SafeMode, ok = v.(bool); !ok {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid safe-mode value")
+			return
+		}
+	}
+
+	if err = handler.ResetTSWithOptions(ts, opts); err != nil {
 		if err == server.ErrServerNotStarted {
 			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		} else {
 			h.rd.JSON(w, http.StatusForbidden, err.Error())
 		}
+		return
 	}
 	h.rd.JSON(w, http.StatusOK, "success")
 }
+
+func (h *adminHandler) GetResetTSHistory(w http.ResponseWriter, r *http.Request) {
+	history, err := h.svr.GetHandler().GetTSOResetHistory()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, history)
+}
+
+func (h *adminHandler) GetScatterStatus(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	h.rd.JSON(w, http.StatusOK, rc.GetRegionScatter().GetGroupStatus())
+}
+
+func (h *adminHandler) ResetScatterGroup(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	group := mux.Vars(r)["group"]
+	if !rc.GetRegionScatter().ResetGroup(group) {
+		h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("scatter group %s not found", group))
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+// GetStorageUsage returns the space used by the keys of PD in etcd, broken
+// down by the prefixes. The result is cached for a few minutes unless
+// refresh=true is given.
+func (h *adminHandler) GetStorageUsage(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	var refresh bool
+	if refreshStr := r.URL.Query().Get("refresh"); refreshStr != "" {
+		var err error
+		refresh, err = strconv.ParseBool(refreshStr)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid refresh value")
+			return
+		}
+	}
+	usage, err := rc.GetStorageUsage(r.Context(), refresh)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, usage)
+}
+
+type cacheRebuildInput struct {
+	// Duration is how long the cache rebuild lasts in seconds.
+	Duration int64 `json:"duration"`
+}
+
+// StartCacheRebuild lets the heartbeats overwrite the region cache for a
+// while, e.g. after PD is restored from an old backup.
+func (h *adminHandler) StartCacheRebuild(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	var input cacheRebuildInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	d := cluster.DefaultCacheRebuildDuration
+	if input.Duration != 0 {
+		d = time.Duration(input.Duration) * time.Second
+	}
+	if d <= 0 || d > cluster.MaxCacheRebuildDuration {
+		h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("duration should be positive and no more than %v", cluster.MaxCacheRebuildDuration))
+		return
+	}
+	if err := rc.StartCacheRebuild(d); err != nil {
+		switch errors.Cause(err) {
+		case cluster.ErrCacheRebuildInProgress, cluster.ErrCacheRebuildBacklog:
+			h.rd.JSON(w, http.StatusConflict, err.Error())
+		default:
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, rc.GetCacheRebuildStatus())
+}
+
+// GetCacheRebuild returns the progress of the last cache rebuild.
+func (h *adminHandler) GetCacheRebuild(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	h.rd.JSON(w, http.StatusOK, rc.GetCacheRebuildStatus())
+}
+
+const (
+	defaultRegionCacheGapLimit = 1000
+	maxRegionCacheGapLimit     = 10000
+)
+
+// GetCacheGaps lists the key ranges not covered by the regions in the cache.
+func (h *adminHandler) GetCacheGaps(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	limit := defaultRegionCacheGapLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxRegionCacheGapLimit {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, rc.GetRegionCacheGaps(limit))
+}
@@ -23,7 +23,11 @@ import (
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/schedule/checker"
+	"github.com/pingcap/pd/v4/server/tso"
 )
 
 var _ = Suite(&testAdminSuite{})
@@ -83,6 +87,50 @@ func (s *testAdminSuite) TestDropRegion(c *C) {
 	c.Assert(region.GetRegionEpoch().Version, Equals, uint64(50))
 }
 
+func (s *testAdminSuite) TestCacheGaps(c *C) {
+	gapsURL := fmt.Sprintf("%s/admin/cache/gaps", s.urlPrefix)
+	var gaps []*cluster.RegionCacheGap
+	c.Assert(readJSON(gapsURL, &gaps), IsNil)
+	c.Assert(gaps, HasLen, 0)
+
+	// A split region replaces the bootstrapped one before the others.
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(100, 1, []byte("m"), []byte("n"), core.SetRegionVersion(10)))
+	c.Assert(readJSON(gapsURL, &gaps), IsNil)
+	c.Assert(gaps, HasLen, 2)
+	c.Assert(gaps[0].StartKey, Equals, "")
+	c.Assert(gaps[0].EndKey, Equals, "6d")
+	c.Assert(gaps[0].NextRegionID, Equals, uint64(100))
+	c.Assert(gaps[1].StartKey, Equals, "6e")
+	c.Assert(gaps[1].EndKey, Equals, "")
+	c.Assert(gaps[1].PrevRegionID, Equals, uint64(100))
+	c.Assert(readJSON(gapsURL+"?limit=1", &gaps), IsNil)
+	c.Assert(gaps, HasLen, 1)
+	for _, limit := range []string{"0", "abc", "10001"} {
+		c.Assert(readJSON(gapsURL+"?limit="+limit, &gaps), NotNil)
+	}
+
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(101, 1, []byte(""), []byte("m"), core.SetRegionVersion(10)))
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(102, 1, []byte("n"), []byte(""), core.SetRegionVersion(10)))
+	c.Assert(readJSON(gapsURL, &gaps), IsNil)
+	c.Assert(gaps, HasLen, 0)
+}
+
+func (s *testAdminSuite) TestExplainRegionCheck(c *C) {
+	region := s.svr.GetRaftCluster().GetRegionInfoByKey([]byte("foo"))
+	url := fmt.Sprintf("%s/debug/region/%d/check", s.urlPrefix, region.GetID())
+	var explanation checker.Explanation
+	c.Assert(readJSON(url, &explanation), IsNil)
+	// The only store reports no capacity, so the replica cannot be made up.
+	c.Assert(explanation.Checker, Equals, "replica-checker")
+	c.Assert(explanation.Results, DeepEquals, []string{"check", "no-target-store"})
+	c.Assert(explanation.Events, DeepEquals, []string{"no target store to make up replica"})
+	c.Assert(explanation.RejectedStores, DeepEquals, map[string][]uint64{"storage-threshold-filter": {1}})
+	c.Assert(explanation.Operator, Equals, "")
+
+	code, _ := requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/debug/region/%d/check", s.urlPrefix, 9999))
+	c.Assert(code, Equals, http.StatusNotFound)
+}
+
 var _ = Suite(&testTSOSuite{})
 
 type testTSOSuite struct {
@@ -110,6 +158,22 @@ func (s *testTSOSuite) TearDownSuite(c *C) {
 	s.cleanup()
 }
 
+func (s *testTSOSuite) TestTSOStatus(c *C) {
+	url := fmt.Sprintf("%s%s/api/v1/tso/status", s.svr.GetAddr(), apiPrefix)
+	var status tso.Status
+	c.Assert(readJSON(url, &status), IsNil)
+	c.Assert(status.Physical, Greater, int64(0))
+	// Reading the status does not allocate timestamps.
+	c.Assert(status.Logical, Equals, int64(0))
+	c.Assert(status.Logical, LessEqual, status.MaxLogical)
+	c.Assert(status.SavedPhysical, GreaterEqual, status.Physical)
+	c.Assert(status.SaveHeadroom.Duration, Greater, time.Duration(0))
+	c.Assert(status.SaveHeadroom.Duration, LessEqual, status.SaveInterval.Duration)
+	c.Assert(status.SaveInterval.Duration, Equals, s.svr.GetConfig().TsoSaveInterval.Duration)
+	c.Assert(status.UpdateInterval.Duration, Equals, tso.UpdateTimestampStep)
+	c.Assert(status.LeaseRemaining.Duration, Greater, time.Duration(0))
+}
+
 func (s *testTSOSuite) TestResetTS(c *C) {
 	args := make(map[string]interface{})
 	t1 := makeTS(time.Hour)
@@ -158,3 +222,106 @@ func (s *testTSOSuite) TestResetTS(c *C) {
 	c.Assert(err, NotNil)
 	c.Assert(err.Error(), Equals, "\"invalid tso value\"\n")
 }
+
+func (s *testTSOSuite) TestResetTSHistory(c *C) {
+	post := func(body string) int {
+		req, err := http.NewRequest(http.MethodPost, s.urlPrefix, strings.NewReader(body))
+		c.Assert(err, IsNil)
+		req.Header.Set("PD-Consumer", "br")
+		resp, err := dialClient.Do(req)
+		c.Assert(err, IsNil)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	ts := makeTS(3 * time.Hour)
+	c.Assert(post(fmt.Sprintf(`{"tso": "%d", "safe-mode": "yes"}`, ts)), Equals, http.StatusBadRequest)
+	start := time.Now()
+	c.Assert(post(fmt.Sprintf(`{"tso": "%d", "safe-mode": true}`, ts)), Equals, http.StatusOK)
+	window := s.svr.GetConfig().PDServerCfg.ResetTSPauseWindow.Duration
+	c.Assert(time.Since(start), GreaterEqual, window)
+
+	var history []*server.TSOResetRecord
+	c.Assert(readJSON(s.urlPrefix+"/history", &history), IsNil)
+	c.Assert(len(history), Greater, 0)
+	record := history[len(history)-1]
+	c.Assert(record.NewTS, Equals, ts)
+	c.Assert(record.OldTS, Less, ts)
+	c.Assert(record.Consumer, Equals, "br")
+	c.Assert(record.PauseWindow.Duration, Equals, window)
+	c.Assert(record.Time.After(start), IsTrue)
+}
+
+func (s *testAdminSuite) TestRollingRestart(c *C) {
+	mustPutStore(c, s.svr, 1, metapb.StoreState_Up, nil)
+	restartURL := s.urlPrefix + "/admin/rolling-restart"
+	post := func(url, body string) (int, *cluster.RollingRestart) {
+		resp, err := dialClient.Post(url, "application/json", strings.NewReader(body))
+		c.Assert(err, IsNil)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		restart := &cluster.RollingRestart{}
+		c.Assert(json.NewDecoder(resp.Body).Decode(restart), IsNil)
+		return resp.StatusCode, restart
+	}
+
+	status, _ := requestStatusBody(c, dialClient, http.MethodGet, restartURL)
+	c.Assert(status, Equals, http.StatusNotFound)
+	status, _ = post(restartURL, `{"store-ids": [1, 99]}`)
+	c.Assert(status, Equals, http.StatusBadRequest)
+	status, restart := post(restartURL, `{"store-ids": [1], "leader-drain-timeout": "5m"}`)
+	c.Assert(status, Equals, http.StatusOK)
+	c.Assert(restart.State, Equals, cluster.RollingRestartRunning)
+	c.Assert(restart.LeaderDrainTimeout.Duration, Equals, 5*time.Minute)
+	c.Assert(restart.Stores, HasLen, 1)
+	status, _ = post(restartURL, `{"store-ids": [1]}`)
+	c.Assert(status, Equals, http.StatusConflict)
+
+	for _, step := range []struct {
+		action string
+		status int
+		state  string
+	}{
+		{"resume", http.StatusConflict, ""},
+		{"pause", http.StatusOK, cluster.RollingRestartPaused},
+		{"resume", http.StatusOK, cluster.RollingRestartRunning},
+		{"abort", http.StatusOK, cluster.RollingRestartAborted},
+		{"abort", http.StatusConflict, ""},
+	} {
+		status, restart = post(restartURL+"/"+step.action, "")
+		c.Assert(status, Equals, step.status)
+		if status == http.StatusOK {
+			c.Assert(restart.State, Equals, step.state)
+		}
+	}
+	var got cluster.RollingRestart
+	c.Assert(readJSON(restartURL, &got), IsNil)
+	c.Assert(got.State, Equals, cluster.RollingRestartAborted)
+}
+
+func (s *testAdminSuite) TestScatterStatus(c *C) {
+	rc := s.svr.GetRaftCluster()
+	region := &metapb.Region{Id: 300, RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1}}
+	for id := uint64(301); id <= 303; id++ {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, nil)
+		region.Peers = append(region.Peers, &metapb.Peer{Id: id * 10, StoreId: id})
+	}
+	_, err := rc.GetRegionScatter().Scatter(core.NewRegionInfo(region, region.Peers[0]), "lightning")
+	c.Assert(err, IsNil)
+
+	var status []*schedule.ScatterGroupStatus
+	c.Assert(readJSON(s.urlPrefix+"/admin/scatter/status", &status), IsNil)
+	c.Assert(status, HasLen, 1)
+	c.Assert(status[0].Group, Equals, "lightning")
+	c.Assert(status[0].RegionsScattered, Equals, uint64(1))
+	c.Assert(status[0].StoreCounts, DeepEquals, map[uint64]uint64{301: 1, 302: 1, 303: 1})
+
+	groupURL := s.urlPrefix + "/admin/scatter/group/lightning"
+	code, _ := requestStatusBody(c, dialClient, http.MethodDelete, groupURL)
+	c.Assert(code, Equals, http.StatusOK)
+	code, _ = requestStatusBody(c, dialClient, http.MethodDelete, groupURL)
+	c.Assert(code, Equals, http.StatusNotFound)
+	c.Assert(readJSON(s.urlPrefix+"/admin/scatter/status", &status), IsNil)
+	c.Assert(status, HasLen, 0)
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/pkg/errors"
+	"github.com/unrolled/render"
+)
+
+type batchOfflineHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newBatchOfflineHandler(svr *server.Server, rd *render.Render) *batchOfflineHandler {
+	return &batchOfflineHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *batchOfflineHandler) Get(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	offline := rc.GetBatchOffline()
+	if offline == nil {
+		h.rd.JSON(w, http.StatusNotFound, cluster.ErrBatchOfflineNotFound.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, offline)
+}
+
+func (h *batchOfflineHandler) Start(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	var cfg cluster.BatchOfflineConfig
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &cfg); err != nil {
+		return
+	}
+	if err := rc.ValidateBatchOffline(&cfg); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	offline, err := rc.StartBatchOffline(&cfg)
+	if err != nil {
+		if errors.Cause(err) == cluster.ErrBatchOfflineInProgress {
+			h.rd.JSON(w, http.StatusConflict, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, offline)
+}
+
+func (h *batchOfflineHandler) Abort(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	err := rc.AbortBatchOffline()
+	switch errors.Cause(err) {
+	case nil:
+		h.rd.JSON(w, http.StatusOK, rc.GetBatchOffline())
+	case cluster.ErrBatchOfflineNotFound:
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+	case cluster.ErrBatchOfflineStateMismatch:
+		h.rd.JSON(w, http.StatusConflict, err.Error())
+	default:
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -44,3 +44,10 @@ func (h *clusterHandler) GetClusterStatus(w http.ResponseWriter, r *http.Request
 	}
 	h.rd.JSON(w, http.StatusOK, status)
 }
+
+// GetReplicationETA estimates the time to restore the full replication of the
+// regions, e.g. after a store fails.
+func (h *clusterHandler) GetReplicationETA(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	h.rd.JSON(w, http.StatusOK, rc.GetReplicationETA())
+}
@@ -17,8 +17,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/docker/go-units"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/v4/server"
 	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/pingcap/pd/v4/server/config"
@@ -79,3 +81,50 @@ func (s *testClusterSuite) TestGetClusterStatus(c *C) {
 	c.Assert(status.RaftBootstrapTime.After(now), IsTrue)
 	c.Assert(status.IsInitialized, IsTrue)
 }
+
+var _ = Suite(&testReplicationETASuite{})
+
+type testReplicationETASuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testReplicationETASuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) { cfg.Replication.MaxReplicas = 3 })
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testReplicationETASuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testReplicationETASuite) TestReplicationETA(c *C) {
+	rc := s.svr.GetRaftCluster()
+	for id := uint64(1); id <= 3; id++ {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, nil)
+		c.Assert(rc.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: id, Capacity: 100 * units.GiB, Available: 100 * units.GiB}), IsNil)
+	}
+	url := fmt.Sprintf("%s/cluster/replication-eta", s.urlPrefix)
+	var eta cluster.ReplicationETA
+	c.Assert(readJSON(url, &eta), IsNil)
+	c.Assert(eta.PendingPeers, Equals, 0)
+	c.Assert(*eta.ETA, Equals, 0.0)
+
+	// The region misses 2 peers.
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(100, 1, []byte("a"), []byte("b")))
+	c.Assert(readJSON(url, &eta), IsNil)
+	c.Assert(eta.UnderReplicatedRegions, Equals, 1)
+	c.Assert(eta.PendingPeers, Equals, 2)
+	c.Assert(eta.PendingSize, Equals, int64(20))
+	c.Assert(eta.LimitRate, Greater, 0.0)
+	c.Assert(eta.EffectiveRate, Equals, eta.LimitRate)
+	c.Assert(eta.ETA, NotNil)
+	c.Assert(*eta.ETA, Equals, 2/eta.LimitRate)
+	c.Assert(eta.UpdateTime.IsZero(), IsFalse)
+}
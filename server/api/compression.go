@@ -0,0 +1,214 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// minCompressSize is the min size of the response to be compressed, the
+// smaller responses are written as is.
+const minCompressSize = 1 << 10
+
+type compressionMiddleware struct {
+	minSize int
+}
+
+func newCompressionMiddleware(minSize int) compressionMiddleware {
+	return compressionMiddleware{minSize: minSize}
+}
+
+// Middleware compresses the responses with gzip for the clients accepting it.
+// The response is compressed once it reaches the min size, or once it is
+// flushed, so a streamed response is compressed chunk by chunk.
+func (m compressionMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w, minSize: m.minSize}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip returns true if the Accept-Encoding header accepts gzip.
+func acceptsGzip(acceptEncoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		rejected := false
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				rejected = err == nil && q == 0
+			}
+		}
+		if !rejected {
+			return true
+		}
+	}
+	return false
+}
+
+// countingWriter counts the bytes written.
+type countingWriter struct {
+	io.Writer
+	n int
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.Writer.Write(b)
+	c.n += n
+	return n, err
+}
+
+// gzipResponseWriter buffers the response until it reaches the min size, then
+// compresses the rest. The response already encoded by the handler is written
+// as is.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buf     []byte
+
+	passthrough  bool
+	gz           *gzip.Writer
+	compressed   *countingWriter
+	uncompressed int
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.passthrough || w.gz != nil {
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.gz != nil {
+		w.uncompressed += len(b)
+		return w.gz.Write(b)
+	}
+	if w.Header().Get("Content-Encoding") != "" {
+		if err := w.startPassthrough(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush compresses and flushes the written data, so a streamed response is
+// compressed regardless of its size.
+func (w *gzipResponseWriter) Flush() {
+	var err error
+	switch {
+	case w.passthrough:
+	case w.gz != nil:
+		err = w.gz.Flush()
+	case w.Header().Get("Content-Encoding") != "":
+		err = w.startPassthrough()
+	default:
+		if err = w.startGzip(); err == nil {
+			err = w.gz.Flush()
+		}
+	}
+	if err != nil {
+		log.Warn("failed to flush the compressed response", zap.Error(err))
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *gzipResponseWriter) writeHeader() {
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *gzipResponseWriter) startPassthrough() error {
+	w.passthrough = true
+	w.writeHeader()
+	_, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *gzipResponseWriter) startGzip() error {
+	h := w.Header()
+	if h.Get("Content-Type") == "" {
+		// Detects the type before the data is compressed, as net/http does.
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", "gzip")
+	w.writeHeader()
+	w.compressed = &countingWriter{Writer: w.ResponseWriter}
+	w.gz = gzip.NewWriter(w.compressed)
+	w.uncompressed = len(w.buf)
+	_, err := w.gz.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// close writes the rest of the response after the handler returns.
+func (w *gzipResponseWriter) close() {
+	switch {
+	case w.passthrough:
+	case w.gz != nil:
+		if err := w.gz.Close(); err != nil {
+			log.Warn("failed to close the compressed response", zap.Error(err))
+			return
+		}
+		compressedResponseCounter.Inc()
+		if saved := w.uncompressed - w.compressed.n; saved > 0 {
+			compressionSavedBytesCounter.Add(float64(saved))
+		}
+	default:
+		// The small response is written as is.
+		w.writeHeader()
+		if len(w.buf) > 0 {
+			if _, err := w.ResponseWriter.Write(w.buf); err != nil {
+				log.Warn("failed to write the response", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testCompressionSuite{})
+
+type testCompressionSuite struct{}
+
+func (s *testCompressionSuite) serve(c *C, handler http.HandlerFunc, acceptEncoding string) (*httptest.ResponseRecorder, []byte) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	newCompressionMiddleware(minCompressSize).Middleware(handler).ServeHTTP(w, r)
+	c.Assert(w.Header().Get("Vary"), Equals, "Accept-Encoding")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		return w, w.Body.Bytes()
+	}
+	gr, err := gzip.NewReader(w.Body)
+	c.Assert(err, IsNil)
+	body, err := ioutil.ReadAll(gr)
+	c.Assert(err, IsNil)
+	return w, body
+}
+
+func (s *testCompressionSuite) TestRegionList(c *C) {
+	rd := createStreamingRender()
+	regions := convertToAPIRegions(newTestRegions())
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		rd.JSON(w, http.StatusOK, regions)
+	}
+
+	w, plain := s.serve(c, handler, "")
+	c.Assert(w.Code, Equals, http.StatusOK)
+	c.Assert(w.Header().Get("Content-Encoding"), Equals, "")
+	w, body := s.serve(c, handler, "deflate, gzip;q=0.8")
+	c.Assert(w.Code, Equals, http.StatusOK)
+	c.Assert(w.Header().Get("Content-Encoding"), Equals, "gzip")
+	c.Assert(w.Header().Get("Content-Type"), Equals, "application/json; charset=UTF-8")
+	c.Assert(w.Body.Len(), Less, len(plain)/2)
+	c.Assert(body, DeepEquals, plain)
+
+	var decoded RegionsInfo
+	c.Assert(json.Unmarshal(body, &decoded), IsNil)
+	c.Assert(decoded.Count, Equals, regions.Count)
+	c.Assert(decoded.Regions[regions.Count-1].ID, Equals, regions.Regions[regions.Count-1].ID)
+
+	w, _ = s.serve(c, handler, "gzip;q=0")
+	c.Assert(w.Header().Get("Content-Encoding"), Equals, "")
+}
+
+func (s *testCompressionSuite) TestSmallResponse(c *C) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		createIndentRender().JSON(w, http.StatusNotFound, "region not found")
+	}
+	w, body := s.serve(c, handler, "gzip")
+	c.Assert(w.Code, Equals, http.StatusNotFound)
+	c.Assert(w.Header().Get("Content-Encoding"), Equals, "")
+	c.Assert(string(body), Equals, "\"region not found\"\n")
+}
+
+func (s *testCompressionSuite) TestFlush(c *C) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("chunk1,"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("chunk2"))
+	}
+	w, body := s.serve(c, handler, "gzip")
+	c.Assert(w.Header().Get("Content-Encoding"), Equals, "gzip")
+	c.Assert(w.Flushed, IsTrue)
+	c.Assert(string(body), Equals, "chunk1,chunk2")
+}
+
+func (s *testCompressionSuite) TestEncodedResponse(c *C) {
+	data := make([]byte, minCompressSize*2)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		w.Write(data)
+	}
+	w, body := s.serve(c, handler, "gzip, br")
+	c.Assert(w.Header().Get("Content-Encoding"), Equals, "br")
+	c.Assert(body, DeepEquals, data)
+}
@@ -19,6 +19,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/BurntSushi/toml"
@@ -26,7 +27,9 @@ import (
 	"github.com/pingcap/kvproto/pkg/configpb"
 	"github.com/pingcap/log"
 	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/pkg/typeutil"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/pingcap/pd/v4/server/config"
 	"github.com/pkg/errors"
 	"github.com/unrolled/render"
@@ -60,10 +63,24 @@ func (h *confHandler) GetDefault(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *confHandler) Post(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// The same as updating the replication config alone.
+	if r.URL.Query().Get("confirm") != "true" {
+		if impact := h.estimateLocationLabelsImpact(data); impact != nil {
+			h.rd.JSON(w, http.StatusPreconditionRequired, impact)
+			return
+		}
+	}
+
 	if h.svr.GetConfig().EnableDynamicConfig {
 		cm := h.svr.GetConfigManager()
 		m := make(map[string]interface{})
-		json.NewDecoder(r.Body).Decode(&m)
+		json.Unmarshal(data, &m)
 		entries, err := transToEntries(m)
 		if err != nil {
 			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
@@ -83,12 +100,6 @@ func (h *confHandler) Post(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	config := h.svr.GetConfig()
-	data, err := ioutil.ReadAll(r.Body)
-	r.Body.Close()
-	if err != nil {
-		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
-		return
-	}
 	found1, err := h.updateSchedule(data, config)
 	if err != nil {
 		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
@@ -214,10 +225,25 @@ func (h *confHandler) GetReplication(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *confHandler) SetReplication(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// Updating the location labels may move many replicas, so the impact is
+	// returned to be confirmed before applying.
+	if r.URL.Query().Get("confirm") != "true" {
+		if impact := h.estimateLocationLabelsImpact(data); impact != nil {
+			h.rd.JSON(w, http.StatusPreconditionRequired, impact)
+			return
+		}
+	}
+
 	if h.svr.GetConfig().EnableDynamicConfig {
 		cm := h.svr.GetConfigManager()
 		m := make(map[string]interface{})
-		json.NewDecoder(r.Body).Decode(&m)
+		json.Unmarshal(data, &m)
 		entries, err := transToEntries(m)
 		if err != nil {
 			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
@@ -238,7 +264,7 @@ func (h *confHandler) SetReplication(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	config := h.svr.GetReplicationConfig()
-	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &config); err != nil {
+	if err := apiutil.ReadJSONRespondError(h.rd, w, ioutil.NopCloser(bytes.NewReader(data)), &config); err != nil {
 		return
 	}
 
@@ -249,10 +275,115 @@ func (h *confHandler) SetReplication(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
+// estimateLocationLabelsImpact returns the impact of the update if it changes
+// the location labels of a bootstrapped cluster, otherwise returns nil.
+func (h *confHandler) estimateLocationLabelsImpact(data []byte) *cluster.LocationLabelsImpact {
+	var update struct {
+		LocationLabels *typeutil.StringSlice `json:"location-labels"`
+	}
+	if err := json.Unmarshal(data, &update); err != nil || update.LocationLabels == nil {
+		return nil
+	}
+	labels := *update.LocationLabels
+	if strings.Join(labels, ",") == strings.Join(h.svr.GetReplicationConfig().LocationLabels, ",") {
+		return nil
+	}
+	rc := h.svr.GetRaftCluster()
+	if rc == nil {
+		return nil
+	}
+	return rc.EstimateLocationLabelsImpact(labels)
+}
+
+func (h *confHandler) EnablePlacementRules(w http.ResponseWriter, r *http.Request) {
+	h.setPlacementRulesEnabled(w, r, true)
+}
+
+func (h *confHandler) DisablePlacementRules(w http.ResponseWriter, r *http.Request) {
+	h.setPlacementRulesEnabled(w, r, false)
+}
+
+// setPlacementRulesEnabled checks the current placements before enabling or
+// disabling the placement rules, which may change many regions. The check is
+// returned to be confirmed before applying.
+func (h *confHandler) setPlacementRulesEnabled(w http.ResponseWriter, r *http.Request, enable bool) {
+	rc := h.svr.GetRaftCluster()
+	if rc == nil {
+		h.rd.JSON(w, http.StatusInternalServerError, cluster.ErrNotBootstrapped.Error())
+		return
+	}
+	if rc.IsPlacementRulesEnabled() == enable {
+		h.rd.JSON(w, http.StatusOK, nil)
+		return
+	}
+	var check *cluster.PlacementRulesCheck
+	if enable {
+		var err error
+		if check, err = rc.CheckEnablePlacementRules(); err != nil {
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	} else {
+		check = rc.CheckDisablePlacementRules()
+	}
+	if r.URL.Query().Get("confirm") != "true" {
+		h.rd.JSON(w, http.StatusPreconditionRequired, check)
+		return
+	}
+
+	// The rules are converted when the config is updated.
+	if h.svr.GetConfig().EnableDynamicConfig {
+		entries, err := transToEntries(map[string]interface{}{"enable-placement-rules": strconv.FormatBool(enable)})
+		if err != nil {
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		client := h.svr.GetConfigClient()
+		if client == nil {
+			h.rd.JSON(w, http.StatusServiceUnavailable, "no leader")
+			return
+		}
+		if err := redirectUpdateReq(h.svr.Context(), client, h.svr.GetConfigManager(), entries); err != nil {
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusOK, check)
+		return
+	}
+	config := h.svr.GetReplicationConfig()
+	config.EnablePlacementRules = enable
+	if err := h.svr.SetReplicationConfig(*config); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, check)
+}
+
 func (h *confHandler) GetLabelProperty(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, h.svr.GetLabelProperty())
 }
 
+const (
+	defaultLabelPropertyImpactLimit = 1000
+	maxLabelPropertyImpactLimit     = 10000
+)
+
+// GetLabelPropertyImpact returns the stores matching the label properties and
+// the regions which cannot satisfy reject-leader.
+func (h *confHandler) GetLabelPropertyImpact(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	limit := defaultLabelPropertyImpactLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxLabelPropertyImpactLimit {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, rc.GetLabelPropertyImpacts(limit))
+}
+
 func (h *confHandler) SetLabelProperty(w http.ResponseWriter, r *http.Request) {
 	input := make(map[string]string)
 	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
@@ -325,6 +456,137 @@ func (h *confHandler) SetLabelProperty(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
+// SetBatch updates any of the schedule, replication and label property
+// configs at once. All the sections are validated jointly, and either all or
+// none of them are applied.
+func (h *confHandler) SetBatch(w http.ResponseWriter, r *http.Request) {
+	if h.svr.GetConfig().EnableDynamicConfig {
+		h.rd.JSON(w, http.StatusNotImplemented, "the config batch is not supported with the dynamic config")
+		return
+	}
+	var input struct {
+		Schedule      json.RawMessage            `json:"schedule"`
+		Replication   json.RawMessage            `json:"replication"`
+		LabelProperty config.LabelPropertyConfig `json:"label-property"`
+	}
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	if input.Schedule == nil && input.Replication == nil && input.LabelProperty == nil {
+		h.rd.JSON(w, http.StatusBadRequest, "no config section to update")
+		return
+	}
+	batch := &server.ConfigBatch{LabelProperty: input.LabelProperty}
+	if input.Schedule != nil {
+		batch.Schedule = h.svr.GetScheduleConfig().Clone()
+		if err := decodeConfigSection(input.Schedule, batch.Schedule); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, "schedule: "+err.Error())
+			return
+		}
+	}
+	if input.Replication != nil {
+		batch.Replication = h.svr.GetReplicationConfig()
+		if err := decodeConfigSection(input.Replication, batch.Replication); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, "replication: "+err.Error())
+			return
+		}
+		// The same as updating the replication config alone.
+		if r.URL.Query().Get("confirm") != "true" {
+			if impact := h.estimateLocationLabelsImpact(input.Replication); impact != nil {
+				h.rd.JSON(w, http.StatusPreconditionRequired, impact)
+				return
+			}
+		}
+	}
+
+	changes, err := h.svr.SetConfigBatch(batch)
+	if err != nil {
+		if _, ok := errors.Cause(err).(*server.ConfigBatchError); ok {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if changes == nil {
+		changes = []*config.Change{}
+	}
+	h.rd.JSON(w, http.StatusOK, changes)
+}
+
+// decodeConfigSection merges the items into the config section, which rejects
+// the unknown items.
+func decodeConfigSection(data []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	return d.Decode(v)
+}
+
+func (h *confHandler) GetEffective(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("scheduler")
+	if name == "" {
+		h.rd.JSON(w, http.StatusBadRequest, "scheduler is required")
+		return
+	}
+	conf, err := h.svr.GetHandler().GetSchedulerEffectiveConfig(name)
+	if err != nil {
+		if errors.Cause(err) == cluster.ErrSchedulerNotFound {
+			h.rd.JSON(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, conf)
+}
+
+func (h *confHandler) GetHotThreshold(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.svr.GetScheduleConfig().GetHotThresholds())
+}
+
+func (h *confHandler) SetHotThreshold(w http.ResponseWriter, r *http.Request) {
+	cfg := h.svr.GetScheduleConfig()
+	thresholds := cfg.GetHotThresholds()
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(thresholds); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	cfg.SetHotThresholds(thresholds)
+	if err := cfg.Validate(); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if h.svr.GetConfig().EnableDynamicConfig {
+		data, _ := json.Marshal(thresholds)
+		m := make(map[string]interface{})
+		json.Unmarshal(data, &m)
+		entries, err := transToEntries(m)
+		if err != nil {
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		client := h.svr.GetConfigClient()
+		if client == nil {
+			h.rd.JSON(w, http.StatusServiceUnavailable, "no leader")
+			return
+		}
+		if err := redirectUpdateReq(h.svr.Context(), client, h.svr.GetConfigManager(), entries); err != nil {
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusOK, nil)
+		return
+	}
+	if err := h.svr.SetScheduleConfig(*cfg); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
 func (h *confHandler) GetClusterVersion(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, h.svr.GetClusterVersion())
 }
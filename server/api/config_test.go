@@ -14,14 +14,23 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
 	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/v4/pkg/typeutil"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/pingcap/pd/v4/server/config"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/placement"
 )
 
 var _ = Suite(&testConfigSuite{})
@@ -179,6 +188,34 @@ func (s *testConfigSuite) TestConfigLabelProperty(c *C) {
 	c.Assert(cfg["foo"], DeepEquals, []config.StoreLabel{{Key: "zone", Value: "cn2"}})
 }
 
+func (s *testConfigSuite) TestConfigHotThreshold(c *C) {
+	addr := fmt.Sprintf("%s/config/hot-threshold", s.urlPrefix)
+	thresholds := &config.HotThresholds{}
+	c.Assert(readJSON(addr, thresholds), IsNil)
+	c.Assert(thresholds, DeepEquals, s.svr.GetScheduleConfig().GetHotThresholds())
+	c.Assert(thresholds.WriteByteRate, Greater, 0.0)
+
+	// The omitted thresholds are unchanged.
+	c.Assert(postJSON(addr, []byte(`{"hot-write-byte-rate-threshold": 100, "hot-read-key-rate-threshold": 10}`)), IsNil)
+	thresholds.WriteByteRate, thresholds.ReadKeyRate = 100, 10
+	time.Sleep(20 * time.Millisecond)
+	newThresholds := &config.HotThresholds{}
+	c.Assert(readJSON(addr, newThresholds), IsNil)
+	c.Assert(newThresholds, DeepEquals, thresholds)
+	c.Assert(s.svr.GetScheduleConfig().HotWriteByteRateThreshold, Equals, 100.0)
+
+	for _, data := range []string{
+		`{"hot-write-key-rate-threshold": -1}`,
+		`{"hot-region-cache-hits-threshold": -1}`,
+		`{"max-replicas": 1}`,
+	} {
+		err := postJSON(addr, []byte(data))
+		c.Assert(err, NotNil)
+	}
+	c.Assert(readJSON(addr, newThresholds), IsNil)
+	c.Assert(newThresholds, DeepEquals, thresholds)
+}
+
 func (s *testConfigSuite) TestConfigDefault(c *C) {
 	addr := fmt.Sprintf("%s/config", s.urlPrefix)
 
@@ -215,3 +252,319 @@ func (s *testConfigSuite) TestConfigDefault(c *C) {
 	c.Assert(defaultCfg.Schedule.RegionScheduleLimit, Equals, uint64(2048))
 	c.Assert(defaultCfg.PDServerCfg.MetricStorage, Equals, "")
 }
+
+var _ = Suite(&testLocationLabelsSuite{})
+
+type testLocationLabelsSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testLocationLabelsSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) { cfg.EnableDynamicConfig = false })
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testLocationLabelsSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testLocationLabelsSuite) TestUpdateLocationLabels(c *C) {
+	// The racks are named within the zones, and store 4 is a candidate in the
+	// same zone as store 1.
+	for id, location := range map[uint64][2]string{1: {"z1", "r1"}, 2: {"z2", "r1"}, 3: {"z3", "r2"}, 4: {"z1", "r3"}} {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, []*metapb.StoreLabel{
+			{Key: "zone", Value: location[0]},
+			{Key: "rack", Value: location[1]},
+		})
+	}
+	peers := []*metapb.Peer{{Id: 11, StoreId: 1}, {Id: 12, StoreId: 2}, {Id: 13, StoreId: 3}}
+	region := &metapb.Region{Id: 10, Peers: peers, StartKey: []byte("a"), RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1}}
+	mustRegionHeartbeat(c, s.svr, core.NewRegionInfo(region, peers[0]))
+
+	addr := fmt.Sprintf("%s/config/replicate", s.urlPrefix)
+	setLabels := func(labels string, confirm bool) (int, *cluster.LocationLabelsImpact) {
+		url := addr
+		if confirm {
+			url += "?confirm=true"
+		}
+		resp, err := dialClient.Post(url, "application/json", bytes.NewBufferString(`{"location-labels":"`+labels+`"}`))
+		c.Assert(err, IsNil)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusPreconditionRequired {
+			return resp.StatusCode, nil
+		}
+		impact := &cluster.LocationLabelsImpact{}
+		c.Assert(json.NewDecoder(resp.Body).Decode(impact), IsNil)
+		return resp.StatusCode, impact
+	}
+	getLabels := func() string {
+		rc := &config.ReplicationConfig{}
+		c.Assert(readJSON(fmt.Sprintf("%s/config/replicate", s.urlPrefix), rc), IsNil)
+		return strings.Join(rc.LocationLabels, ",")
+	}
+
+	status, _ := setLabels("zone", true)
+	c.Assert(status, Equals, http.StatusOK)
+	c.Assert(getLabels(), Equals, "zone")
+	// The update without changing the labels is applied at once.
+	status, _ = setLabels("zone", false)
+	c.Assert(status, Equals, http.StatusOK)
+
+	// Appending a label is a no-op.
+	status, impact := setLabels("zone,rack", false)
+	c.Assert(status, Equals, http.StatusPreconditionRequired)
+	c.Assert(impact.NewLabels, DeepEquals, []string{"zone", "rack"})
+	c.Assert(impact.TotalRegions, Equals, 1)
+	c.Assert(impact.ScoreChangedRegions, Equals, 0)
+	c.Assert(impact.EstimatedMoves, Equals, 0)
+	c.Assert(getLabels(), Equals, "zone")
+	status, _ = setLabels("zone,rack", true)
+	c.Assert(status, Equals, http.StatusOK)
+	c.Assert(getLabels(), Equals, "zone,rack")
+
+	// Isolating by the racks first moves the peer on store 1 or 2.
+	status, impact = setLabels("rack,zone", false)
+	c.Assert(status, Equals, http.StatusPreconditionRequired)
+	c.Assert(impact.ScoreChangedRegions, Equals, 1)
+	c.Assert(impact.EstimatedMoves, Equals, 1)
+	c.Assert(getLabels(), Equals, "zone,rack")
+
+	// Updating the labels via the whole config is guarded too.
+	addr = fmt.Sprintf("%s/config", s.urlPrefix)
+	status, impact = setLabels("rack,zone", false)
+	c.Assert(status, Equals, http.StatusPreconditionRequired)
+	c.Assert(impact.EstimatedMoves, Equals, 1)
+	c.Assert(getLabels(), Equals, "zone,rack")
+	status, _ = setLabels("rack,zone", true)
+	c.Assert(status, Equals, http.StatusOK)
+	c.Assert(getLabels(), Equals, "rack,zone")
+}
+
+var _ = Suite(&testConfigBatchSuite{})
+
+type testConfigBatchSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testConfigBatchSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) { cfg.EnableDynamicConfig = false })
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testConfigBatchSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testConfigBatchSuite) post(c *C, body string, confirm bool) (int, []byte) {
+	url := fmt.Sprintf("%s/config/batch", s.urlPrefix)
+	if confirm {
+		url += "?confirm=true"
+	}
+	resp, err := dialClient.Post(url, "application/json", bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp.StatusCode, data
+}
+
+func (s *testConfigBatchSuite) TestBatch(c *C) {
+	for id, zone := range map[uint64]string{1: "z1", 2: "z2", 3: "z3"} {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, []*metapb.StoreLabel{{Key: "zone", Value: zone}})
+	}
+
+	status, data := s.post(c, `{
+		"schedule": {"max-snapshot-count": 10},
+		"replication": {"max-replicas": 3, "location-labels": "zone"},
+		"label-property": {"reject-leader": [{"key": "zone", "value": "z1"}]}
+	}`, true)
+	c.Assert(status, Equals, http.StatusOK, Commentf("%s", data))
+	var changes []*config.Change
+	c.Assert(json.Unmarshal(data, &changes), IsNil)
+	items := make([]string, 0, len(changes))
+	for _, change := range changes {
+		items = append(items, change.Item)
+	}
+	c.Assert(items, DeepEquals, []string{"schedule.max-snapshot-count", "replication.location-labels", "label-property.reject-leader"})
+	c.Assert(changes[0].New, Equals, float64(10))
+	c.Assert(s.svr.GetScheduleConfig().MaxSnapshotCount, Equals, uint64(10))
+	c.Assert(s.svr.GetReplicationConfig().LocationLabels, DeepEquals, typeutil.StringSlice{"zone"})
+	c.Assert(s.svr.GetLabelProperty(), DeepEquals, config.LabelPropertyConfig{"reject-leader": {{Key: "zone", Value: "z1"}}})
+	persisted := &config.Config{}
+	_, err := s.svr.GetStorage().LoadConfig(persisted)
+	c.Assert(err, IsNil)
+	c.Assert(persisted.Schedule.MaxSnapshotCount, Equals, uint64(10))
+	c.Assert(persisted.LabelProperty, DeepEquals, s.svr.GetLabelProperty())
+
+	// Nothing is changed by the same batch.
+	status, data = s.post(c, `{"schedule": {"max-snapshot-count": 10}}`, false)
+	c.Assert(status, Equals, http.StatusOK)
+	c.Assert(string(data), Matches, `\[\]\s*`)
+
+	// Any invalid item leaves all the sections untouched.
+	scheduleCfg := s.svr.GetScheduleConfig()
+	replicationCfg := s.svr.GetReplicationConfig()
+	labelProperty := s.svr.GetLabelProperty()
+	testCases := []struct {
+		body   string
+		status int
+	}{
+		// There are only 3 zones.
+		{`{"schedule": {"max-snapshot-count": 20}, "replication": {"max-replicas": 5}, "label-property": {}}`, http.StatusBadRequest},
+		{`{"schedule": {"low-space-ratio": 2}, "replication": {"max-replicas": 1}}`, http.StatusBadRequest},
+		{`{"schedule": {"max-snapshot-count": 20}, "replication": {"max-replica": 1}}`, http.StatusBadRequest},
+		{`{"schedule": {"max-snapshot-count": "20"}}`, http.StatusBadRequest},
+		{`{"schedule": {"max-snapshot-count": 20}, "label-property": {"reject-follower": [{"key": "zone", "value": "z2"}]}}`, http.StatusBadRequest},
+		{`{"schedule": {"max-snapshot-count": 20}, "replication": {"enable-placement-rules": "true"}}`, http.StatusBadRequest},
+		{`{"schedule": {"max-snapshot-count": 20}, "replication": {"location-labels": "zone,host"}}`, http.StatusPreconditionRequired},
+		{`{}`, http.StatusBadRequest},
+	}
+	for _, t := range testCases {
+		status, data = s.post(c, t.body, false)
+		c.Assert(status, Equals, t.status, Commentf("%s: %s", t.body, data))
+		c.Assert(s.svr.GetScheduleConfig(), DeepEquals, scheduleCfg)
+		c.Assert(s.svr.GetReplicationConfig(), DeepEquals, replicationCfg)
+		c.Assert(s.svr.GetLabelProperty(), DeepEquals, labelProperty)
+	}
+
+	// The label property config is replaced.
+	status, data = s.post(c, `{"replication": {"max-replicas": 1}, "label-property": {}}`, false)
+	c.Assert(status, Equals, http.StatusOK, Commentf("%s", data))
+	c.Assert(json.Unmarshal(data, &changes), IsNil)
+	c.Assert(changes, HasLen, 2)
+	c.Assert(s.svr.GetReplicationConfig().MaxReplicas, Equals, uint64(1))
+	c.Assert(s.svr.GetLabelProperty(), HasLen, 0)
+}
+
+var _ = Suite(&testPlacementRulesSuite{})
+
+type testPlacementRulesSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testPlacementRulesSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) { cfg.EnableDynamicConfig = false })
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testPlacementRulesSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testPlacementRulesSuite) TestTogglePlacementRules(c *C) {
+	for id := uint64(1); id <= 3; id++ {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, nil)
+	}
+	peers := []*metapb.Peer{{Id: 11, StoreId: 1}, {Id: 12, StoreId: 2}, {Id: 13, StoreId: 3}}
+	region := &metapb.Region{Id: 10, Peers: peers, StartKey: []byte("a"), RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1}}
+	mustRegionHeartbeat(c, s.svr, core.NewRegionInfo(region, peers[0]))
+
+	toggle := func(action string, confirm bool) (int, *cluster.PlacementRulesCheck) {
+		url := fmt.Sprintf("%s/config/placement-rules/%s", s.urlPrefix, action)
+		if confirm {
+			url += "?confirm=true"
+		}
+		resp, err := dialClient.Post(url, "application/json", nil)
+		c.Assert(err, IsNil)
+		defer resp.Body.Close()
+		var check *cluster.PlacementRulesCheck
+		c.Assert(json.NewDecoder(resp.Body).Decode(&check), IsNil)
+		return resp.StatusCode, check
+	}
+
+	// The faithful conversion changes no region.
+	status, check := toggle("enable", false)
+	c.Assert(status, Equals, http.StatusPreconditionRequired)
+	c.Assert(check.Rules, HasLen, 1)
+	c.Assert(check.TotalRegions, Equals, 1)
+	c.Assert(check.EstimatedChanges, Equals, 0)
+	c.Assert(check.Warnings, HasLen, 0)
+	c.Assert(s.svr.GetReplicationConfig().EnablePlacementRules, IsFalse)
+	status, _ = toggle("enable", true)
+	c.Assert(status, Equals, http.StatusOK)
+	c.Assert(s.svr.GetReplicationConfig().EnablePlacementRules, IsTrue)
+	c.Assert(s.svr.GetRaftCluster().GetRuleManager().GetAllRules(), HasLen, 1)
+	status, check = toggle("enable", false)
+	c.Assert(status, Equals, http.StatusOK)
+	c.Assert(check, IsNil)
+
+	// The learner rules are warned when disabling.
+	rule := &placement.Rule{GroupID: "test", ID: "learner", Role: placement.Learner, Count: 1}
+	c.Assert(s.svr.GetRaftCluster().GetRuleManager().SetRule(rule), IsNil)
+	status, check = toggle("disable", false)
+	c.Assert(status, Equals, http.StatusPreconditionRequired)
+	c.Assert(check.Rules, HasLen, 2)
+	c.Assert(check.EstimatedChanges, Equals, 0)
+	c.Assert(check.Warnings, HasLen, 1)
+	c.Assert(s.svr.GetReplicationConfig().EnablePlacementRules, IsTrue)
+	status, _ = toggle("disable", true)
+	c.Assert(status, Equals, http.StatusOK)
+	c.Assert(s.svr.GetReplicationConfig().EnablePlacementRules, IsFalse)
+}
+
+var _ = Suite(&testLabelPropertyImpactSuite{})
+
+type testLabelPropertyImpactSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testLabelPropertyImpactSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) { cfg.EnableDynamicConfig = false })
+	mustWaitLeader(c, []*server.Server{s.svr})
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", s.svr.GetAddr(), apiPrefix)
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testLabelPropertyImpactSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testLabelPropertyImpactSuite) TestImpact(c *C) {
+	for id, zone := range map[uint64]string{1: "z1", 2: "z1", 3: "z2"} {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, []*metapb.StoreLabel{{Key: "zone", Value: zone}})
+	}
+	c.Assert(s.svr.SetLabelProperty("reject-leader", "zone", "z1"), IsNil)
+	// Only the voter on store 3 can take the leader, but it is down.
+	down := &metapb.Peer{Id: 103, StoreId: 3}
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(100, 1, []byte("a"), []byte("b"),
+		core.WithAddPeer(&metapb.Peer{Id: 102, StoreId: 2}),
+		core.WithAddPeer(down),
+		core.WithDownPeers([]*pdpb.PeerStats{{Peer: down, DownSeconds: 3600}})))
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(200, 1, []byte("b"), []byte("c"),
+		core.WithAddPeer(&metapb.Peer{Id: 202, StoreId: 2}),
+		core.WithAddPeer(&metapb.Peer{Id: 203, StoreId: 3})))
+
+	impacts := &cluster.LabelPropertyImpacts{}
+	c.Assert(readJSON(fmt.Sprintf("%s/config/label-property/impact", s.urlPrefix), impacts), IsNil)
+	c.Assert(impacts.Properties, HasLen, 1)
+	c.Assert(impacts.Properties[0].Type, Equals, "reject-leader")
+	c.Assert(impacts.Properties[0].Stores, DeepEquals, []uint64{1, 2})
+	c.Assert(impacts.UnsatisfiableRegions, DeepEquals, []uint64{100})
+	c.Assert(impacts.UnsatisfiableCount, Equals, 1)
+
+	status, _ := requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/config/label-property/impact?limit=0", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusBadRequest)
+}
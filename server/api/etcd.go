@@ -0,0 +1,57 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/pingcap/pd/v4/server"
+	"github.com/pkg/errors"
+	"github.com/unrolled/render"
+)
+
+type etcdHandler struct {
+	*server.Handler
+	rd *render.Render
+}
+
+func newEtcdHandler(handler *server.Handler, rd *render.Render) *etcdHandler {
+	return &etcdHandler{
+		Handler: handler,
+		rd:      rd,
+	}
+}
+
+func (h *etcdHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.GetEtcdStatus()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, status)
+}
+
+func (h *etcdHandler) DefragAndCompact(w http.ResponseWriter, r *http.Request) {
+	result, err := h.DefragAndCompactEtcd()
+	switch {
+	case errors.Cause(err) == server.ErrNotLeader:
+		h.rd.JSON(w, http.StatusServiceUnavailable, err.Error())
+	case err == server.ErrEtcdMaintaining:
+		h.rd.JSON(w, http.StatusConflict, err.Error())
+	case err != nil:
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	default:
+		h.rd.JSON(w, http.StatusOK, result)
+	}
+}
@@ -14,11 +14,14 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/v4/server"
 )
 
 var _ = Suite(&testEtcdAPISuite{})
@@ -43,3 +46,38 @@ func (s *testEtcdAPISuite) TestGRPCGateway(c *C) {
 	})
 	c.Assert(err, IsNil)
 }
+
+func (s *testEtcdAPISuite) TestDefragAndCompact(c *C) {
+	svr, clean := mustNewServer(c)
+	defer clean()
+	mustWaitLeader(c, []*server.Server{svr})
+
+	urlPrefix := fmt.Sprintf("%s%s/api/v1/etcd", svr.GetAddr(), apiPrefix)
+	var status server.EtcdStatus
+	c.Assert(readJSON(urlPrefix+"/status", &status), IsNil)
+	c.Assert(status.DBSize, Greater, int64(0))
+	c.Assert(status.DBSizeInUse, Greater, int64(0))
+	c.Assert(status.Quota, Equals, int64(svr.GetConfig().QuotaBackendBytes))
+	c.Assert(status.Revision, Greater, int64(0))
+	c.Assert(status.RaftTerm, Greater, uint64(0))
+	c.Assert(status.Leader, Equals, svr.GetMember().ID())
+
+	for i := 0; i < 10; i++ {
+		_, err := svr.GetClient().Put(context.Background(), "test", fmt.Sprintf("%d", i))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(readJSON(urlPrefix+"/status", &status), IsNil)
+	rev := status.Revision
+	c.Assert(status.CompactedRevision, Less, rev)
+
+	var result server.EtcdDefragResult
+	err := postJSON(urlPrefix+"/defrag-and-compact", nil, func(res []byte, code int) {
+		c.Assert(json.Unmarshal(res, &result), IsNil)
+	})
+	c.Assert(err, IsNil)
+	c.Assert(result.CompactedRevision, GreaterEqual, rev)
+	c.Assert(result.Defragmented, DeepEquals, []string{svr.Name()})
+
+	c.Assert(readJSON(urlPrefix+"/status", &status), IsNil)
+	c.Assert(status.CompactedRevision, Equals, result.CompactedRevision)
+}
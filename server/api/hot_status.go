@@ -17,6 +17,8 @@ import (
 	"net/http"
 
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/config"
+	"github.com/pingcap/pd/v4/server/statistics"
 	"github.com/unrolled/render"
 )
 
@@ -31,6 +33,23 @@ type HotStoreStats struct {
 	BytesReadStats  map[uint64]float64 `json:"bytes-read-rate,omitempty"`
 	KeysWriteStats  map[uint64]float64 `json:"keys-write-rate,omitempty"`
 	KeysReadStats   map[uint64]float64 `json:"keys-read-rate,omitempty"`
+	// IOCapacity is the IO capacities of the stores labeled with io-capacity,
+	// in bytes per second.
+	IOCapacity map[uint64]uint64 `json:"io-capacity,omitempty"`
+	// The byte rates normalized by the IO capacities as the hot region
+	// scheduler balances them, which are the byte rates as if the IO
+	// capacities of the stores were the average.
+	NormalizedBytesWriteStats map[uint64]float64 `json:"normalized-bytes-write-rate,omitempty"`
+	NormalizedBytesReadStats  map[uint64]float64 `json:"normalized-bytes-read-rate,omitempty"`
+}
+
+// HotRegionsStats is the hot regions annotated with the current leader term
+// and the thresholds in force. The hot regions are omitted while the
+// statistics are warming up.
+type HotRegionsStats struct {
+	*server.StatsAnnotation
+	Thresholds *config.HotThresholds `json:"thresholds"`
+	*statistics.StoreHotPeersInfos
 }
 
 func newHotStatusHandler(handler *server.Handler, rd *render.Render) *hotStatusHandler {
@@ -41,11 +60,27 @@ func newHotStatusHandler(handler *server.Handler, rd *render.Render) *hotStatusH
 }
 
 func (h *hotStatusHandler) GetHotWriteRegions(w http.ResponseWriter, r *http.Request) {
-	h.rd.JSON(w, http.StatusOK, h.Handler.GetHotWriteRegions())
+	h.writeHotRegions(w, h.Handler.GetHotWriteRegions)
 }
 
 func (h *hotStatusHandler) GetHotReadRegions(w http.ResponseWriter, r *http.Request) {
-	h.rd.JSON(w, http.StatusOK, h.Handler.GetHotReadRegions())
+	h.writeHotRegions(w, h.Handler.GetHotReadRegions)
+}
+
+func (h *hotStatusHandler) writeHotRegions(w http.ResponseWriter, getHotRegions func() *statistics.StoreHotPeersInfos) {
+	annotation, err := h.GetStatsAnnotation()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	stats := HotRegionsStats{
+		StatsAnnotation: annotation,
+		Thresholds:      h.GetScheduleConfig().GetHotThresholds(),
+	}
+	if !annotation.WarmingUp {
+		stats.StoreHotPeersInfos = getHotRegions()
+	}
+	h.rd.JSON(w, http.StatusOK, stats)
 }
 
 func (h *hotStatusHandler) GetHotStores(w http.ResponseWriter, r *http.Request) {
@@ -53,12 +88,32 @@ func (h *hotStatusHandler) GetHotStores(w http.ResponseWriter, r *http.Request)
 	bytesReadStats := h.GetHotBytesReadStores()
 	keysWriteStats := h.GetHotKeysWriteStores()
 	keysReadStats := h.GetHotKeysReadStores()
+	capacities, factors := h.GetStoresIOCapacity()
 
 	stats := HotStoreStats{
-		BytesWriteStats: bytesWriteStats,
-		BytesReadStats:  bytesReadStats,
-		KeysWriteStats:  keysWriteStats,
-		KeysReadStats:   keysReadStats,
+		BytesWriteStats:           bytesWriteStats,
+		BytesReadStats:            bytesReadStats,
+		KeysWriteStats:            keysWriteStats,
+		KeysReadStats:             keysReadStats,
+		IOCapacity:                capacities,
+		NormalizedBytesWriteStats: normalizeByteRates(bytesWriteStats, factors),
+		NormalizedBytesReadStats:  normalizeByteRates(bytesReadStats, factors),
 	}
 	h.rd.JSON(w, http.StatusOK, stats)
 }
+
+// normalizeByteRates divides the byte rates by the IO capacity factors of the
+// stores.
+func normalizeByteRates(rates map[uint64]float64, factors map[uint64]float64) map[uint64]float64 {
+	if rates == nil {
+		return nil
+	}
+	normalized := make(map[uint64]float64, len(rates))
+	for id, rate := range rates {
+		if factor, ok := factors[id]; ok && factor > 0 {
+			rate /= factor
+		}
+		normalized[id] = rate
+	}
+	return normalized
+}
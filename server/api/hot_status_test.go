@@ -14,10 +14,14 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/v4/pkg/testutil"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/core"
 	_ "github.com/pingcap/pd/v4/server/schedulers"
 )
 
@@ -48,3 +52,76 @@ func (s testHotStatusSuite) TestGetHotStore(c *C) {
 	err := readJSON(s.urlPrefix+"/stores", &stat)
 	c.Assert(err, IsNil)
 }
+
+func (s testHotStatusSuite) TestNormalizeByteRates(c *C) {
+	rates := map[uint64]float64{1: 200, 2: 100, 3: 100}
+	factors := map[uint64]float64{1: 2, 2: 0.5}
+	c.Assert(normalizeByteRates(rates, factors), DeepEquals, map[uint64]float64{1: 100, 2: 200, 3: 100})
+	c.Assert(normalizeByteRates(nil, factors), IsNil)
+}
+
+var _ = Suite(&testHotStatusLeaderChangeSuite{})
+
+type testHotStatusLeaderChangeSuite struct{}
+
+func (s *testHotStatusLeaderChangeSuite) TestLeaderChange(c *C) {
+	_, svrs, cleanup := mustNewCluster(c, 3)
+	defer cleanup()
+	leader := mustWaitLeader(c, svrs)
+	mustBootstrapCluster(c, leader)
+
+	defer func(d time.Duration) { server.StatsWarmUpDuration = d }(server.StatsWarmUpDuration)
+	server.StatsWarmUpDuration = time.Hour
+	// getHotRegions returns the annotated hot regions, and whether the hot regions are reported.
+	getHotRegions := func(svr *server.Server) (*HotRegionsStats, bool) {
+		url := fmt.Sprintf("%s%s/api/v1/hotspot/regions/write", svr.GetAddr(), apiPrefix)
+		stats := &HotRegionsStats{}
+		c.Assert(readJSON(url, stats), IsNil)
+		c.Assert(stats.StatsAnnotation, NotNil)
+		c.Assert(stats.Thresholds, DeepEquals, svr.GetScheduleConfig().GetHotThresholds())
+		var fields map[string]interface{}
+		c.Assert(readJSON(url, &fields), IsNil)
+		_, ok := fields["as_peer"]
+		return stats, ok
+	}
+
+	// The hot regions are omitted while warming up.
+	stats, reported := getHotRegions(leader)
+	c.Assert(stats.WarmingUp, IsTrue)
+	c.Assert(reported, IsFalse)
+	c.Assert(stats.LeaderTerm, Greater, int64(0))
+	c.Assert(stats.CoordinatorStartTime.IsZero(), IsFalse)
+	term, startTime := stats.LeaderTerm, stats.CoordinatorStartTime
+
+	// The hot regions are reported once the schedulers run and the statistics warm up.
+	for _, region := range leader.GetRaftCluster().GetRegions() {
+		mustRegionHeartbeat(c, leader, region.Clone(core.WithLeader(region.GetPeers()[0])))
+	}
+	server.StatsWarmUpDuration = 0
+	testutil.WaitUntil(c, func(c *C) bool {
+		stats, reported = getHotRegions(leader)
+		return reported
+	})
+	c.Assert(stats.WarmingUp, IsFalse)
+	c.Assert(stats.LeaderTerm, Equals, term)
+	c.Assert(stats.DataAge.Duration, Greater, time.Duration(0))
+
+	var trend Trend
+	c.Assert(readJSON(fmt.Sprintf("%s%s/api/v1/trend", leader.GetAddr(), apiPrefix), &trend), IsNil)
+	c.Assert(trend.History.StatsAnnotation, NotNil)
+	c.Assert(trend.History.LeaderTerm, Equals, term)
+
+	// The term bumps and the statistics warm up again after the leader changes.
+	server.StatsWarmUpDuration = time.Hour
+	c.Assert(leader.GetMember().ResignLeader(context.Background(), leader.Name(), ""), IsNil)
+	var newLeader *server.Server
+	testutil.WaitUntil(c, func(c *C) bool {
+		newLeader = mustWaitLeader(c, svrs)
+		return newLeader != leader
+	})
+	stats, reported = getHotRegions(newLeader)
+	c.Assert(stats.WarmingUp, IsTrue)
+	c.Assert(reported, IsFalse)
+	c.Assert(stats.LeaderTerm, Greater, term)
+	c.Assert(stats.CoordinatorStartTime.After(startTime), IsTrue)
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/unrolled/render"
+)
+
+type leaderPinHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newLeaderPinHandler(svr *server.Server, rd *render.Render) *leaderPinHandler {
+	return &leaderPinHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+type leaderPinInput struct {
+	StoreID uint64               `json:"store_id"`
+	Labels  []*metapb.StoreLabel `json:"labels"`
+}
+
+// List returns the leader pins along with their status.
+func (h *leaderPinHandler) List(w http.ResponseWriter, r *http.Request) {
+	cluster := getCluster(r.Context())
+	h.rd.JSON(w, http.StatusOK, cluster.GetLeaderPinStatus())
+}
+
+// Pin pins the leader of the region on a store, or on the stores matching the
+// labels. It replaces the pin of the region if there is one.
+func (h *leaderPinHandler) Pin(w http.ResponseWriter, r *http.Request) {
+	cluster := getCluster(r.Context())
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["id"])
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var input leaderPinInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	pin, err := cluster.PinRegionLeader(regionID, input.StoreID, input.Labels)
+	if err != nil {
+		apiutil.ErrorResp(h.rd, w, err)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, pin)
+}
+
+// Unpin removes the pin of the region.
+func (h *leaderPinHandler) Unpin(w http.ResponseWriter, r *http.Request) {
+	cluster := getCluster(r.Context())
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["id"])
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ok, err := cluster.GetLeaderPins().RemovePin(regionID)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !ok {
+		h.rd.JSON(w, http.StatusNotFound, "leader pin not found")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
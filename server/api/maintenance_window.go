@@ -0,0 +1,87 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/pkg/errors"
+	"github.com/unrolled/render"
+)
+
+type maintenanceWindowHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newMaintenanceWindowHandler(svr *server.Server, rd *render.Render) *maintenanceWindowHandler {
+	return &maintenanceWindowHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *maintenanceWindowHandler) List(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	h.rd.JSON(w, http.StatusOK, rc.GetMaintenanceWindows().GetWindows())
+}
+
+func (h *maintenanceWindowHandler) Get(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	window := rc.GetMaintenanceWindows().GetWindow(mux.Vars(r)["name"])
+	if window == nil {
+		h.rd.JSON(w, http.StatusNotFound, "maintenance window not found")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, window)
+}
+
+func (h *maintenanceWindowHandler) Set(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	var window cluster.MaintenanceWindow
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &window); err != nil {
+		return
+	}
+	if err := window.Validate(); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := rc.GetMaintenanceWindows().SetWindow(&window); err != nil {
+		if errors.Cause(err) == cluster.ErrMaintenanceWindowOverlapped {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *maintenanceWindowHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	ok, err := rc.GetMaintenanceWindows().RemoveWindow(mux.Vars(r)["name"])
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !ok {
+		h.rd.JSON(w, http.StatusNotFound, "maintenance window not found")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/pingcap/pd/v4/pkg/apiutil"
 	"github.com/pingcap/pd/v4/pkg/etcdutil"
 	"github.com/pingcap/pd/v4/server"
+	syncer "github.com/pingcap/pd/v4/server/region_syncer"
 	"github.com/pkg/errors"
 	"github.com/unrolled/render"
 	"go.uber.org/zap"
@@ -42,13 +43,37 @@ func newMemberHandler(svr *server.Server, rd *render.Render) *memberHandler {
 	}
 }
 
+// membersInfo is the same as pdpb.GetMembersResponse, except that the members
+// carry the etcd status.
+type membersInfo struct {
+	Header     *pdpb.ResponseHeader `json:"header,omitempty"`
+	Members    []*memberInfo        `json:"members,omitempty"`
+	Leader     *pdpb.Member         `json:"leader,omitempty"`
+	EtcdLeader *pdpb.Member         `json:"etcd_leader,omitempty"`
+}
+
+type memberInfo struct {
+	*pdpb.Member
+	*server.MemberEtcdStatus
+}
+
 func (h *memberHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
 	members, err := h.getMembers()
 	if err != nil {
 		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	h.rd.JSON(w, http.StatusOK, members)
+	statuses := h.svr.GetHandler().GetMembersEtcdStatus(members.GetMembers())
+	info := &membersInfo{
+		Header:     members.GetHeader(),
+		Members:    make([]*memberInfo, 0, len(members.GetMembers())),
+		Leader:     members.GetLeader(),
+		EtcdLeader: members.GetEtcdLeader(),
+	}
+	for _, m := range members.GetMembers() {
+		info.Members = append(info.Members, &memberInfo{Member: m, MemberEtcdStatus: statuses[m.GetMemberId()]})
+	}
+	h.rd.JSON(w, http.StatusOK, info)
 }
 
 func (h *memberHandler) getMembers() (*pdpb.GetMembersResponse, error) {
@@ -221,3 +246,50 @@ func (h *leaderHandler) Transfer(w http.ResponseWriter, r *http.Request) {
 
 	h.rd.JSON(w, http.StatusOK, nil)
 }
+
+// MemberSyncStatus is the status of syncing the regions to a member. Leader is
+// the view of the leader on the sync stream to the member, and Follower is the
+// view of the member itself, which is returned if the member handles the
+// request as a follower.
+type MemberSyncStatus struct {
+	Name     string                 `json:"name"`
+	Leader   *syncer.FollowerStatus `json:"leader,omitempty"`
+	Follower *syncer.SyncStatus     `json:"follower,omitempty"`
+}
+
+func (h *memberHandler) GetSyncStatus(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	status := &MemberSyncStatus{Name: name}
+	if !h.svr.GetMember().IsLeader() {
+		if name != h.svr.Name() {
+			h.rd.JSON(w, http.StatusBadRequest, "a follower only has the sync status of itself")
+			return
+		}
+		status.Follower = h.svr.GetRegionSyncer().GetSyncStatus()
+		h.rd.JSON(w, http.StatusOK, status)
+		return
+	}
+	status.Leader = h.svr.GetRegionSyncer().GetFollowerStatus(name)
+	if status.Leader == nil {
+		h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("no region sync stream to pd: %s", name))
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, status)
+}
+
+func (h *memberHandler) Resync(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if err := h.svr.GetRegionSyncer().Resync(name); err != nil {
+		if errors.Cause(err) == syncer.ErrFollowerNotFound {
+			h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("no region sync stream to pd: %s", name))
+			return
+		}
+		if errors.Cause(err) == syncer.ErrResyncInProgress {
+			h.rd.JSON(w, http.StatusConflict, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, fmt.Sprintf("resynced, pd: %s", name))
+}
@@ -19,6 +19,7 @@ import (
 	"math/rand"
 	"sort"
 	"strings"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/pdpb"
@@ -96,3 +97,67 @@ func (s *testMemberAPISuite) TestMemberLeader(c *C) {
 	c.Assert(got.GetClientUrls(), DeepEquals, leader.GetClientUrls())
 	c.Assert(got.GetMemberId(), Equals, leader.GetMemberId())
 }
+
+var _ = Suite(&testMemberEtcdStatusSuite{})
+
+type testMemberEtcdStatusSuite struct {
+	cfgs    []*config.Config
+	servers []*server.Server
+	clean   func()
+}
+
+func (s *testMemberEtcdStatusSuite) SetUpSuite(c *C) {
+	s.cfgs, s.servers, s.clean = mustNewCluster(c, 3)
+}
+
+func (s *testMemberEtcdStatusSuite) TearDownSuite(c *C) {
+	s.clean()
+}
+
+func (s *testMemberEtcdStatusSuite) TestMemberEtcdStatus(c *C) {
+	leader := mustWaitLeader(c, s.servers)
+	url := leader.GetAddr() + apiPrefix + "/api/v1/members"
+	var got membersInfo
+	c.Assert(readJSON(url, &got), IsNil)
+	c.Assert(got.Members, HasLen, 3)
+	etcdLeaders := 0
+	for _, m := range got.Members {
+		c.Assert(m.Partial, IsFalse)
+		c.Assert(m.Errors, HasLen, 0)
+		c.Assert(m.Alarms, HasLen, 0)
+		c.Assert(m.DBSize, Greater, int64(0))
+		// The PD leader holds a lease of the leader key.
+		c.Assert(m.LeaseCount, Greater, 0)
+		if m.IsEtcdLeader {
+			etcdLeaders++
+			c.Assert(m.GetMemberId(), Equals, got.EtcdLeader.GetMemberId())
+		}
+	}
+	c.Assert(etcdLeaders, Equals, 1)
+
+	// Kill a member which is neither the PD leader nor the etcd leader.
+	var killed *server.Server
+	for _, svr := range s.servers {
+		id := svr.GetMember().ID()
+		if id != leader.GetMember().ID() && id != got.EtcdLeader.GetMemberId() {
+			killed = svr
+			break
+		}
+	}
+	c.Assert(killed, NotNil)
+	killed.Close()
+
+	start := time.Now()
+	c.Assert(readJSON(url, &got), IsNil)
+	c.Assert(time.Since(start), Less, 10*time.Second)
+	c.Assert(got.Members, HasLen, 3)
+	for _, m := range got.Members {
+		if m.GetMemberId() == killed.GetMember().ID() {
+			c.Assert(m.Partial, IsTrue)
+			c.Assert(len(m.Errors), Greater, 0)
+		} else {
+			c.Assert(m.Partial, IsFalse)
+			c.Assert(m.DBSize, Greater, int64(0))
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	compressedResponseCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "api",
+			Name:      "compressed_responses_total",
+			Help:      "Counter of the HTTP responses compressed.",
+		})
+
+	compressionSavedBytesCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "api",
+			Name:      "compression_saved_bytes_total",
+			Help:      "Counter of the bytes saved by compressing the HTTP responses.",
+		})
+)
+
+func init() {
+	prometheus.MustRegister(compressedResponseCounter)
+	prometheus.MustRegister(compressionSavedBytesCounter)
+}
@@ -14,13 +14,20 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/pd/v4/pkg/apiutil"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule"
 	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"github.com/pkg/errors"
 	"github.com/unrolled/render"
 )
 
@@ -37,14 +44,22 @@ func newOperatorHandler(handler *server.Handler, r *render.Render) *operatorHand
 }
 
 func (h *operatorHandler) Get(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["region_id"]
-
-	regionID, err := strconv.ParseUint(id, 10, 64)
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["region_id"])
 	if err != nil {
 		h.r.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	splitInto, err := h.GetSplitIntoStatus(regionID)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if splitInto != nil {
+		h.r.JSON(w, http.StatusOK, splitInto)
+		return
+	}
+
 	op, err := h.GetOperatorStatus(regionID)
 	if err != nil {
 		h.r.JSON(w, http.StatusInternalServerError, err.Error())
@@ -88,15 +103,243 @@ func (h *operatorHandler) List(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if detail, _ := strconv.ParseBool(r.URL.Query().Get("detail")); detail {
+		h.r.JSON(w, http.StatusOK, newOperatorDescriptions(results))
+		return
+	}
 	h.r.JSON(w, http.StatusOK, results)
 }
 
+const defaultOperatorLatencyWindow = 10 * time.Minute
+
+func (h *operatorHandler) GetLatencies(w http.ResponseWriter, r *http.Request) {
+	window := defaultOperatorLatencyWindow
+	if windowStr := r.URL.Query().Get("window"); windowStr != "" {
+		var err error
+		window, err = time.ParseDuration(windowStr)
+		if err != nil || window <= 0 || window > schedule.MaxOperatorLatencyWindow {
+			h.r.JSON(w, http.StatusBadRequest, "invalid window")
+			return
+		}
+	}
+	oc, err := h.GetOperatorController()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, oc.GetOperatorLatencies(window))
+}
+
+const (
+	defaultOperatorHistoryLimit = 100
+	maxOperatorHistoryLimit     = 10000
+)
+
+// operatorHistoryEntry is a leader transfer or a peer movement of a finished
+// operator.
+type operatorHistoryEntry struct {
+	RegionID   uint64                   `json:"region_id"`
+	Desc       string                   `json:"desc"`
+	Kind       string                   `json:"kind"`
+	OpKind     string                   `json:"op_kind"`
+	FromStore  uint64                   `json:"from_store"`
+	ToStore    uint64                   `json:"to_store"`
+	FinishTime time.Time                `json:"finish_time"`
+	Duration   string                   `json:"duration"`
+	Result     operator.OpHistoryResult `json:"result"`
+}
+
+// GetHistory lists the histories of the operators finished since start, the
+// latest first. The histories are filtered by the kind, which is leader,
+// region or admin, and the store on either side of the movement.
+func (h *operatorHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	start := time.Unix(0, 0)
+	if startStr := query.Get("start"); startStr != "" {
+		ts, err := strconv.ParseInt(startStr, 10, 64)
+		if err != nil || ts < 0 {
+			h.r.JSON(w, http.StatusBadRequest, "invalid start")
+			return
+		}
+		start = time.Unix(ts, 0)
+	}
+	match := func(operator.OpHistory) bool { return true }
+	switch kind := query.Get("kind"); kind {
+	case "":
+	case "leader":
+		match = func(history operator.OpHistory) bool { return history.Kind == core.LeaderKind }
+	case "region":
+		match = func(history operator.OpHistory) bool { return history.Kind == core.RegionKind }
+	case "admin":
+		match = func(history operator.OpHistory) bool { return history.OpKind&operator.OpAdmin != 0 }
+	default:
+		h.r.JSON(w, http.StatusBadRequest, fmt.Sprintf("invalid kind %q", kind))
+		return
+	}
+	var storeID uint64
+	if storeStr := query.Get("store_id"); storeStr != "" {
+		var err error
+		if storeID, err = apiutil.ParseStoreIDParam(storeStr); err != nil {
+			h.r.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	limit := defaultOperatorHistoryLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxOperatorHistoryLimit {
+			h.r.JSON(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	}
+
+	histories, err := h.Handler.GetHistory(start)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	entries := make([]operatorHistoryEntry, 0, limit)
+	for _, history := range histories {
+		if len(entries) >= limit {
+			break
+		}
+		if !match(history) || (storeID != 0 && history.From != storeID && history.To != storeID) {
+			continue
+		}
+		entries = append(entries, operatorHistoryEntry{
+			RegionID:   history.RegionID,
+			Desc:       history.Desc,
+			Kind:       history.Kind.String(),
+			OpKind:     history.OpKind.String(),
+			FromStore:  history.From,
+			ToStore:    history.To,
+			FinishTime: history.FinishTime,
+			Duration:   history.Duration.String(),
+			Result:     history.Result,
+		})
+	}
+	h.r.JSON(w, http.StatusOK, entries)
+}
+
+func (h *operatorHandler) GetPingPong(w http.ResponseWriter, r *http.Request) {
+	oc, err := h.GetOperatorController()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, oc.GetPingPongStatus())
+}
+
 func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var shape map[string]json.RawMessage
+	if err = json.Unmarshal(data, &shape); err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	consumer := getOperatorConsumer(r)
+	if _, ok := shape["template"]; ok {
+		h.postTemplate(w, shape, func(args operatorArgs) { h.createTyped(w, args, consumer) })
+		return
+	}
+	// The typed requests put the arguments in args, while the legacy ones put
+	// them along with the name.
+	if _, ok := shape["args"]; ok {
+		h.postTyped(w, data, consumer)
+		return
+	}
+	setDeprecationHeaders(w, deprecationFlatOperator)
 	var input map[string]interface{}
-	if err := apiutil.ReadJSONRespondError(h.r, w, r.Body, &input); err != nil {
+	if err = json.Unmarshal(data, &input); err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.postLegacy(w, input, consumer)
+}
+
+func (h *operatorHandler) postTyped(w http.ResponseWriter, data []byte, consumer string) {
+	args, argErr := decodeOperatorRequest(data)
+	if argErr != nil {
+		h.r.JSON(w, http.StatusBadRequest, argErr)
+		return
+	}
+	h.createTyped(w, args, consumer)
+}
+
+func (h *operatorHandler) createTyped(w http.ResponseWriter, args operatorArgs, consumer string) {
+	ops, err := args.create(h.Handler, consumer)
+	if err != nil {
+		h.addOperatorErrorResp(w, err)
 		return
 	}
+	h.r.JSON(w, http.StatusOK, newOperatorDescriptions(ops))
+}
 
+// Precheck reports which checks would reject the operator in the request,
+// which is in any form accepted by Post, without creating it.
+func (h *operatorHandler) Precheck(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var shape map[string]json.RawMessage
+	if err = json.Unmarshal(data, &shape); err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, ok := shape["template"]; ok {
+		h.postTemplate(w, shape, func(args operatorArgs) { h.precheckTyped(w, args) })
+		return
+	}
+	if data, err = typedOperatorRequest(shape); err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	args, argErr := decodeOperatorRequest(data)
+	if argErr != nil {
+		h.r.JSON(w, http.StatusBadRequest, argErr)
+		return
+	}
+	h.precheckTyped(w, args)
+}
+
+// typedOperatorRequest returns the typed request of the request in any form.
+// The legacy requests share the names of the arguments with the typed ones.
+func typedOperatorRequest(shape map[string]json.RawMessage) ([]byte, error) {
+	if _, ok := shape["args"]; ok {
+		return json.Marshal(shape)
+	}
+	rawArgs := make(map[string]json.RawMessage, len(shape))
+	for k, v := range shape {
+		if k != "name" {
+			rawArgs[k] = v
+		}
+	}
+	data, err := json.Marshal(rawArgs)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]json.RawMessage{"name": shape["name"], "args": data})
+}
+
+func (h *operatorHandler) precheckTyped(w http.ResponseWriter, args operatorArgs) {
+	precheck, err := args.precheck(h.Handler)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, precheck)
+}
+
+func (h *operatorHandler) postLegacy(w http.ResponseWriter, input map[string]interface{}, consumer string) {
 	name, ok := input["name"].(string)
 	if !ok {
 		h.r.JSON(w, http.StatusBadRequest, "missing operator name")
@@ -115,8 +358,8 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusBadRequest, "missing store id to transfer leader to")
 			return
 		}
-		if err := h.AddTransferLeaderOperator(uint64(regionID), uint64(storeID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		if _, err := h.AddTransferLeaderOperator(consumer, uint64(regionID), uint64(storeID)); err != nil {
+			h.addOperatorErrorResp(w, err)
 			return
 		}
 	case "transfer-region":
@@ -134,8 +377,8 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusBadRequest, "missing store ids to transfer region to")
 			return
 		}
-		if err := h.AddTransferRegionOperator(uint64(regionID), storeIDs); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		if _, err := h.AddTransferRegionOperator(consumer, uint64(regionID), storeIDs); err != nil {
+			h.addOperatorErrorResp(w, err)
 			return
 		}
 	case "transfer-peer":
@@ -154,8 +397,8 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusBadRequest, "invalid store id to transfer peer to")
 			return
 		}
-		if err := h.AddTransferPeerOperator(uint64(regionID), uint64(fromID), uint64(toID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		if _, err := h.AddTransferPeerOperator(consumer, uint64(regionID), uint64(fromID), uint64(toID)); err != nil {
+			h.addOperatorErrorResp(w, err)
 			return
 		}
 	case "add-peer":
@@ -169,8 +412,8 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusBadRequest, "invalid store id to transfer peer to")
 			return
 		}
-		if err := h.AddAddPeerOperator(uint64(regionID), uint64(storeID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		if _, err := h.AddAddPeerOperator(consumer, uint64(regionID), uint64(storeID)); err != nil {
+			h.addOperatorErrorResp(w, err)
 			return
 		}
 	case "add-learner":
@@ -184,8 +427,8 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusBadRequest, "invalid store id to transfer peer to")
 			return
 		}
-		if err := h.AddAddLearnerOperator(uint64(regionID), uint64(storeID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		if _, err := h.AddAddLearnerOperator(consumer, uint64(regionID), uint64(storeID)); err != nil {
+			h.addOperatorErrorResp(w, err)
 			return
 		}
 	case "remove-peer":
@@ -199,8 +442,8 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusBadRequest, "invalid store id to transfer peer to")
 			return
 		}
-		if err := h.AddRemovePeerOperator(uint64(regionID), uint64(storeID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		if _, err := h.AddRemovePeerOperator(consumer, uint64(regionID), uint64(storeID)); err != nil {
+			h.addOperatorErrorResp(w, err)
 			return
 		}
 	case "merge-region":
@@ -214,8 +457,8 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusBadRequest, "invalid target region id to merge to")
 			return
 		}
-		if err := h.AddMergeRegionOperator(uint64(regionID), uint64(targetID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		if _, err := h.AddMergeRegionOperator(consumer, uint64(regionID), uint64(targetID)); err != nil {
+			h.addOperatorErrorResp(w, err)
 			return
 		}
 	case "split-region":
@@ -231,17 +474,45 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 		}
 		var keys []string
 		if ks, ok := input["keys"]; ok {
-			for _, k := range ks.([]interface{}) {
+			items, ok := ks.([]interface{})
+			if !ok {
+				h.r.JSON(w, http.StatusBadRequest, "bad format keys")
+				return
+			}
+			for _, k := range items {
 				key, ok := k.(string)
 				if !ok {
 					h.r.JSON(w, http.StatusBadRequest, "bad format keys")
 					return
 				}
+				if _, err := apiutil.ParseHexKeyParam("keys", key); err != nil {
+					h.r.JSON(w, http.StatusBadRequest, err.Error())
+					return
+				}
 				keys = append(keys, key)
 			}
 		}
-		if err := h.AddSplitRegionOperator(uint64(regionID), policy, keys); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		if _, err := h.AddSplitRegionOperator(consumer, uint64(regionID), policy, keys); err != nil {
+			h.addOperatorErrorResp(w, err)
+			return
+		}
+	case "split-region-into":
+		regionID, ok := input["region_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing region id")
+			return
+		}
+		parts, ok := input["parts"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing parts")
+			return
+		}
+		if parts < schedule.MinSplitIntoParts || parts > schedule.MaxSplitIntoParts || parts != float64(int(parts)) {
+			h.r.JSON(w, http.StatusBadRequest, fmt.Sprintf("parts should be an integer in [%d, %d]", schedule.MinSplitIntoParts, schedule.MaxSplitIntoParts))
+			return
+		}
+		if _, err := h.AddSplitRegionIntoOperator(consumer, uint64(regionID), int(parts)); err != nil {
+			h.addOperatorErrorResp(w, err)
 			return
 		}
 	case "scatter-region":
@@ -250,8 +521,9 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusBadRequest, "missing region id")
 			return
 		}
-		if err := h.AddScatterRegionOperator(uint64(regionID)); err != nil {
-			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		group, _ := input["group"].(string)
+		if _, err := h.AddScatterRegionOperator(consumer, uint64(regionID), group); err != nil {
+			h.addOperatorErrorResp(w, err)
 			return
 		}
 	default:
@@ -262,10 +534,32 @@ func (h *operatorHandler) Post(w http.ResponseWriter, r *http.Request) {
 	h.r.JSON(w, http.StatusOK, nil)
 }
 
-func (h *operatorHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["region_id"]
+// getOperatorConsumer identifies the consumer submitting operators by the
+// common name of the client certificate, or the PD-Consumer header.
+func getOperatorConsumer(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		if cn := r.TLS.PeerCertificates[0].Subject.CommonName; cn != "" {
+			return cn
+		}
+	}
+	return r.Header.Get("PD-Consumer")
+}
 
-	regionID, err := strconv.ParseUint(id, 10, 64)
+func (h *operatorHandler) addOperatorErrorResp(w http.ResponseWriter, err error) {
+	if _, ok := errors.Cause(err).(core.OperatorQuotaExceededErr); ok {
+		apiutil.ErrorResp(h.r, w, err)
+		return
+	}
+	switch errors.Cause(err) {
+	case schedule.ErrPrerequisiteNotFound, schedule.ErrPrerequisiteFailed:
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusInternalServerError, err.Error())
+}
+
+func (h *operatorHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["region_id"])
 	if err != nil {
 		h.r.JSON(w, http.StatusBadRequest, err.Error())
 		return
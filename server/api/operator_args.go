@@ -0,0 +1,547 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+)
+
+// operatorRequest is the typed request to create operators. The arguments
+// are decoded strictly according to the operator name.
+type operatorRequest struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args"`
+	// AfterRegionOperator is the operator returned by an earlier request,
+	// after which the operators are added once it finishes successfully.
+	AfterRegionOperator *operator.Dependency `json:"after_region_operator"`
+}
+
+// OperatorArgError is the error of an invalid argument in the operator request.
+type OperatorArgError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func (e *OperatorArgError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+func errArgRequired(field string) *OperatorArgError {
+	return &OperatorArgError{Field: field, Reason: "required"}
+}
+
+// parseHexKeyArg parses the key argument in hex format.
+func parseHexKeyArg(field, s string) ([]byte, *OperatorArgError) {
+	key, err := apiutil.ParseHexKeyParam(field, s)
+	if err != nil {
+		if perr, ok := err.(*apiutil.ParamError); ok {
+			return nil, &OperatorArgError{Field: field, Reason: perr.Reason}
+		}
+		return nil, &OperatorArgError{Field: field, Reason: err.Error()}
+	}
+	return key, nil
+}
+
+// OperatorDescription describes an operator in the structured form.
+type OperatorDescription struct {
+	RegionID uint64 `json:"region_id"`
+	Desc     string `json:"desc"`
+	Kind     string `json:"kind"`
+	Creator  string `json:"creator"`
+	// Reason is the structured cause of the operator, which is absent if its
+	// creator does not set one.
+	Reason *operator.Reason `json:"reason,omitempty"`
+	// RegionEpoch is the epoch of the region when the operator is created.
+	RegionEpoch *metapb.RegionEpoch `json:"region_epoch"`
+	Steps       []string            `json:"steps"`
+	CreateTime  time.Time           `json:"create_time"`
+	// Token identifies the operator among the ones of the region, by which
+	// the later operators are chained after it.
+	Token uint64               `json:"token"`
+	After *operator.Dependency `json:"after,omitempty"`
+}
+
+func newOperatorDescriptions(ops []*operator.Operator) []*OperatorDescription {
+	descs := make([]*OperatorDescription, 0, len(ops))
+	for _, op := range ops {
+		steps := make([]string, 0, op.Len())
+		for i := 0; i < op.Len(); i++ {
+			steps = append(steps, op.Step(i).String())
+		}
+		descs = append(descs, &OperatorDescription{
+			RegionID:    op.RegionID(),
+			Desc:        op.Desc(),
+			Kind:        op.Kind().String(),
+			Creator:     op.Creator(),
+			Reason:      op.Reason(),
+			RegionEpoch: op.RegionEpoch(),
+			Steps:       steps,
+			CreateTime:  op.GetCreateTime(),
+			Token:       op.Token(),
+			After:       op.After(),
+		})
+	}
+	return descs
+}
+
+// operatorArgs is the arguments to create an operator.
+type operatorArgs interface {
+	validate() *OperatorArgError
+	create(h *server.Handler, consumer string) ([]*operator.Operator, error)
+	// precheck reports which checks would reject the operator without
+	// creating it.
+	precheck(h *server.Handler) (*server.OperatorPrecheck, error)
+}
+
+// targetStoresArgs is the arguments of the operators which move the peers or
+// the leader to the target stores.
+type targetStoresArgs interface {
+	targetStores() []uint64
+}
+
+// chainableArgs is the arguments of the operators which can be built in
+// advance, so that they can be chained after another operator.
+type chainableArgs interface {
+	operatorArgs
+	build(h *server.Handler) ([]*operator.Operator, error)
+}
+
+// chainedArgs adds the operators of the arguments after the prerequisite
+// operator finishes successfully.
+type chainedArgs struct {
+	chainableArgs
+	after operator.Dependency
+}
+
+func (a *chainedArgs) create(h *server.Handler, consumer string) ([]*operator.Operator, error) {
+	ops, err := a.build(h)
+	rebuild := func() ([]*operator.Operator, error) { return a.build(h) }
+	return h.AddChainedOperators(consumer, a.after, rebuild, ops, err)
+}
+
+var operatorArgsBuilders = map[string]func() operatorArgs{
+	"transfer-leader":     func() operatorArgs { return &transferLeaderArgs{} },
+	"transfer-region":     func() operatorArgs { return &transferRegionArgs{} },
+	"transfer-peer":       func() operatorArgs { return &transferPeerArgs{} },
+	"add-peer":            func() operatorArgs { return &addPeerArgs{} },
+	"add-learner":         func() operatorArgs { return &addLearnerArgs{} },
+	"remove-peer":         func() operatorArgs { return &removePeerArgs{} },
+	"merge-region":        func() operatorArgs { return &mergeRegionArgs{} },
+	"merge-region-by-key": func() operatorArgs { return &mergeRegionByKeyArgs{} },
+	"split-region":        func() operatorArgs { return &splitRegionArgs{} },
+	"split-region-into":   func() operatorArgs { return &splitRegionIntoArgs{} },
+	"scatter-region":      func() operatorArgs { return &scatterRegionArgs{} },
+}
+
+// decodeOperatorRequest decodes the typed operator request strictly, which
+// rejects the unknown fields and the fields in wrong types.
+func decodeOperatorRequest(data []byte) (operatorArgs, *OperatorArgError) {
+	var req operatorRequest
+	if err := decodeStrictly(data, &req); err != nil {
+		return nil, err
+	}
+	if req.Name == "" {
+		return nil, errArgRequired("name")
+	}
+	builder, ok := operatorArgsBuilders[req.Name]
+	if !ok {
+		return nil, &OperatorArgError{Field: "name", Reason: "unknown operator"}
+	}
+	args := builder()
+	if err := decodeStrictly(req.Args, args); err != nil {
+		err.Field = "args." + err.Field
+		return nil, err
+	}
+	if err := args.validate(); err != nil {
+		err.Field = "args." + err.Field
+		return nil, err
+	}
+	if after := req.AfterRegionOperator; after != nil {
+		chainable, ok := args.(chainableArgs)
+		switch {
+		case !ok:
+			return nil, &OperatorArgError{Field: "after_region_operator", Reason: "the operator can not be chained"}
+		case after.RegionID == 0:
+			return nil, errArgRequired("after_region_operator.region_id")
+		case after.Token == 0:
+			return nil, errArgRequired("after_region_operator.token")
+		}
+		return &chainedArgs{chainableArgs: chainable, after: *after}, nil
+	}
+	return args, nil
+}
+
+func decodeStrictly(data []byte, v interface{}) *OperatorArgError {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*json.UnmarshalTypeError); ok {
+		return &OperatorArgError{Field: e.Field, Reason: "should be " + e.Type.String()}
+	}
+	// The error of an unknown field is not typed.
+	if field := strings.TrimPrefix(err.Error(), "json: unknown field "); field != err.Error() {
+		if f, err := strconv.Unquote(field); err == nil {
+			field = f
+		}
+		return &OperatorArgError{Field: field, Reason: "unknown field"}
+	}
+	return &OperatorArgError{Field: "body", Reason: err.Error()}
+}
+
+type transferLeaderArgs struct {
+	RegionID  uint64 `json:"region_id"`
+	ToStoreID uint64 `json:"to_store_id"`
+}
+
+func (a *transferLeaderArgs) validate() *OperatorArgError {
+	switch {
+	case a.RegionID == 0:
+		return errArgRequired("region_id")
+	case a.ToStoreID == 0:
+		return errArgRequired("to_store_id")
+	}
+	return nil
+}
+
+func (a *transferLeaderArgs) targetStores() []uint64 {
+	return []uint64{a.ToStoreID}
+}
+
+func (a *transferLeaderArgs) create(h *server.Handler, consumer string) ([]*operator.Operator, error) {
+	return h.AddTransferLeaderOperator(consumer, a.RegionID, a.ToStoreID)
+}
+
+func (a *transferLeaderArgs) precheck(h *server.Handler) (*server.OperatorPrecheck, error) {
+	return h.PrecheckOperators(h.BuildTransferLeaderOperator(a.RegionID, a.ToStoreID))
+}
+
+func (a *transferLeaderArgs) build(h *server.Handler) ([]*operator.Operator, error) {
+	return h.BuildTransferLeaderOperator(a.RegionID, a.ToStoreID)
+}
+
+type transferRegionArgs struct {
+	RegionID   uint64   `json:"region_id"`
+	ToStoreIDs []uint64 `json:"to_store_ids"`
+}
+
+func (a *transferRegionArgs) validate() *OperatorArgError {
+	if a.RegionID == 0 {
+		return errArgRequired("region_id")
+	}
+	if len(a.ToStoreIDs) == 0 {
+		return errArgRequired("to_store_ids")
+	}
+	ids := make(map[uint64]struct{}, len(a.ToStoreIDs))
+	for _, id := range a.ToStoreIDs {
+		if _, ok := ids[id]; ok || id == 0 {
+			return &OperatorArgError{Field: "to_store_ids", Reason: fmt.Sprintf("invalid or duplicated store %d", id)}
+		}
+		ids[id] = struct{}{}
+	}
+	return nil
+}
+
+func (a *transferRegionArgs) targetStores() []uint64 {
+	return a.ToStoreIDs
+}
+
+func (a *transferRegionArgs) create(h *server.Handler, consumer string) ([]*operator.Operator, error) {
+	ids := make(map[uint64]struct{}, len(a.ToStoreIDs))
+	for _, id := range a.ToStoreIDs {
+		ids[id] = struct{}{}
+	}
+	return h.AddTransferRegionOperator(consumer, a.RegionID, ids)
+}
+
+func (a *transferRegionArgs) precheck(h *server.Handler) (*server.OperatorPrecheck, error) {
+	return h.PrecheckOperators(a.build(h))
+}
+
+func (a *transferRegionArgs) build(h *server.Handler) ([]*operator.Operator, error) {
+	ids := make(map[uint64]struct{}, len(a.ToStoreIDs))
+	for _, id := range a.ToStoreIDs {
+		ids[id] = struct{}{}
+	}
+	return h.BuildTransferRegionOperator(a.RegionID, ids)
+}
+
+type transferPeerArgs struct {
+	RegionID    uint64 `json:"region_id"`
+	FromStoreID uint64 `json:"from_store_id"`
+	ToStoreID   uint64 `json:"to_store_id"`
+}
+
+func (a *transferPeerArgs) validate() *OperatorArgError {
+	switch {
+	case a.RegionID == 0:
+		return errArgRequired("region_id")
+	case a.FromStoreID == 0:
+		return errArgRequired("from_store_id")
+	case a.ToStoreID == 0:
+		return errArgRequired("to_store_id")
+	}
+	return nil
+}
+
+func (a *transferPeerArgs) targetStores() []uint64 {
+	return []uint64{a.ToStoreID}
+}
+
+func (a *transferPeerArgs) create(h *server.Handler, consumer string) ([]*operator.Operator, error) {
+	return h.AddTransferPeerOperator(consumer, a.RegionID, a.FromStoreID, a.ToStoreID)
+}
+
+func (a *transferPeerArgs) precheck(h *server.Handler) (*server.OperatorPrecheck, error) {
+	return h.PrecheckOperators(h.BuildTransferPeerOperator(a.RegionID, a.FromStoreID, a.ToStoreID))
+}
+
+func (a *transferPeerArgs) build(h *server.Handler) ([]*operator.Operator, error) {
+	return h.BuildTransferPeerOperator(a.RegionID, a.FromStoreID, a.ToStoreID)
+}
+
+// peerArgs is the arguments of the operators which add or remove a peer.
+type peerArgs struct {
+	RegionID uint64 `json:"region_id"`
+	StoreID  uint64 `json:"store_id"`
+}
+
+func (a *peerArgs) validate() *OperatorArgError {
+	switch {
+	case a.RegionID == 0:
+		return errArgRequired("region_id")
+	case a.StoreID == 0:
+		return errArgRequired("store_id")
+	}
+	return nil
+}
+
+type addPeerArgs struct{ peerArgs }
+
+func (a *addPeerArgs) targetStores() []uint64 {
+	return []uint64{a.StoreID}
+}
+
+func (a *addPeerArgs) create(h *server.Handler, consumer string) ([]*operator.Operator, error) {
+	return h.AddAddPeerOperator(consumer, a.RegionID, a.StoreID)
+}
+
+func (a *addPeerArgs) precheck(h *server.Handler) (*server.OperatorPrecheck, error) {
+	return h.PrecheckOperators(h.BuildAddPeerOperator(a.RegionID, a.StoreID))
+}
+
+func (a *addPeerArgs) build(h *server.Handler) ([]*operator.Operator, error) {
+	return h.BuildAddPeerOperator(a.RegionID, a.StoreID)
+}
+
+type addLearnerArgs struct{ peerArgs }
+
+func (a *addLearnerArgs) targetStores() []uint64 {
+	return []uint64{a.StoreID}
+}
+
+func (a *addLearnerArgs) create(h *server.Handler, consumer string) ([]*operator.Operator, error) {
+	return h.AddAddLearnerOperator(consumer, a.RegionID, a.StoreID)
+}
+
+func (a *addLearnerArgs) precheck(h *server.Handler) (*server.OperatorPrecheck, error) {
+	return h.PrecheckOperators(h.BuildAddLearnerOperator(a.RegionID, a.StoreID))
+}
+
+func (a *addLearnerArgs) build(h *server.Handler) ([]*operator.Operator, error) {
+	return h.BuildAddLearnerOperator(a.RegionID, a.StoreID)
+}
+
+type removePeerArgs struct{ peerArgs }
+
+func (a *removePeerArgs) create(h *server.Handler, consumer string) ([]*operator.Operator, error) {
+	return h.AddRemovePeerOperator(consumer, a.RegionID, a.StoreID)
+}
+
+func (a *removePeerArgs) precheck(h *server.Handler) (*server.OperatorPrecheck, error) {
+	return h.PrecheckOperators(h.BuildRemovePeerOperator(a.RegionID, a.StoreID))
+}
+
+func (a *removePeerArgs) build(h *server.Handler) ([]*operator.Operator, error) {
+	return h.BuildRemovePeerOperator(a.RegionID, a.StoreID)
+}
+
+type mergeRegionArgs struct {
+	SourceRegionID uint64 `json:"source_region_id"`
+	TargetRegionID uint64 `json:"target_region_id"`
+}
+
+func (a *mergeRegionArgs) validate() *OperatorArgError {
+	switch {
+	case a.SourceRegionID == 0:
+		return errArgRequired("source_region_id")
+	case a.TargetRegionID == 0:
+		return errArgRequired("target_region_id")
+	case a.SourceRegionID == a.TargetRegionID:
+		return &OperatorArgError{Field: "target_region_id", Reason: "same as the source region"}
+	}
+	return nil
+}
+
+func (a *mergeRegionArgs) create(h *server.Handler, consumer string) ([]*operator.Operator, error) {
+	return h.AddMergeRegionOperator(consumer, a.SourceRegionID, a.TargetRegionID)
+}
+
+func (a *mergeRegionArgs) precheck(h *server.Handler) (*server.OperatorPrecheck, error) {
+	return h.PrecheckOperators(h.BuildMergeRegionOperator(a.SourceRegionID, a.TargetRegionID))
+}
+
+func (a *mergeRegionArgs) build(h *server.Handler) ([]*operator.Operator, error) {
+	return h.BuildMergeRegionOperator(a.SourceRegionID, a.TargetRegionID)
+}
+
+// mergeRegionByKeyArgs merges the region covering the key into its adjacent
+// region in the direction.
+type mergeRegionByKeyArgs struct {
+	Key       string `json:"key"`
+	Direction string `json:"direction"`
+
+	key []byte
+}
+
+func (a *mergeRegionByKeyArgs) validate() *OperatorArgError {
+	if a.Key == "" {
+		return errArgRequired("key")
+	}
+	key, argErr := parseHexKeyArg("key", a.Key)
+	if argErr != nil {
+		return argErr
+	}
+	a.key = key
+	switch a.Direction {
+	case "":
+		return errArgRequired("direction")
+	case "next", "prev":
+	default:
+		return &OperatorArgError{Field: "direction", Reason: "should be next or prev"}
+	}
+	return nil
+}
+
+func (a *mergeRegionByKeyArgs) create(h *server.Handler, consumer string) ([]*operator.Operator, error) {
+	return h.AddMergeRegionByKeyOperator(consumer, a.key, a.Direction)
+}
+
+func (a *mergeRegionByKeyArgs) precheck(h *server.Handler) (*server.OperatorPrecheck, error) {
+	return h.PrecheckOperators(h.BuildMergeRegionByKeyOperator(a.key, a.Direction))
+}
+
+func (a *mergeRegionByKeyArgs) build(h *server.Handler) ([]*operator.Operator, error) {
+	return h.BuildMergeRegionByKeyOperator(a.key, a.Direction)
+}
+
+type splitRegionArgs struct {
+	RegionID uint64   `json:"region_id"`
+	Policy   string   `json:"policy"`
+	Keys     []string `json:"keys"`
+}
+
+func (a *splitRegionArgs) validate() *OperatorArgError {
+	if a.RegionID == 0 {
+		return errArgRequired("region_id")
+	}
+	if a.Policy == "" {
+		return errArgRequired("policy")
+	}
+	policy, ok := pdpb.CheckPolicy_value[strings.ToUpper(a.Policy)]
+	if !ok {
+		return &OperatorArgError{Field: "policy", Reason: "should be one of scan, approximate and usekey"}
+	}
+	if pdpb.CheckPolicy(policy) == pdpb.CheckPolicy_USEKEY && len(a.Keys) == 0 {
+		return errArgRequired("keys")
+	}
+	if pdpb.CheckPolicy(policy) != pdpb.CheckPolicy_USEKEY && len(a.Keys) != 0 {
+		return &OperatorArgError{Field: "keys", Reason: "only used by the usekey policy"}
+	}
+	for i, key := range a.Keys {
+		if _, argErr := parseHexKeyArg(fmt.Sprintf("keys[%d]", i), key); argErr != nil {
+			return argErr
+		}
+	}
+	return nil
+}
+
+func (a *splitRegionArgs) create(h *server.Handler, consumer string) ([]*operator.Operator, error) {
+	return h.AddSplitRegionOperator(consumer, a.RegionID, a.Policy, a.Keys)
+}
+
+func (a *splitRegionArgs) precheck(h *server.Handler) (*server.OperatorPrecheck, error) {
+	return h.PrecheckOperators(h.BuildSplitRegionOperator(a.RegionID, a.Policy, a.Keys))
+}
+
+func (a *splitRegionArgs) build(h *server.Handler) ([]*operator.Operator, error) {
+	return h.BuildSplitRegionOperator(a.RegionID, a.Policy, a.Keys)
+}
+
+type splitRegionIntoArgs struct {
+	RegionID uint64 `json:"region_id"`
+	Parts    int    `json:"parts"`
+}
+
+func (a *splitRegionIntoArgs) validate() *OperatorArgError {
+	if a.RegionID == 0 {
+		return errArgRequired("region_id")
+	}
+	if a.Parts < schedule.MinSplitIntoParts || a.Parts > schedule.MaxSplitIntoParts {
+		return &OperatorArgError{Field: "parts", Reason: fmt.Sprintf("should be in [%d, %d]", schedule.MinSplitIntoParts, schedule.MaxSplitIntoParts)}
+	}
+	return nil
+}
+
+func (a *splitRegionIntoArgs) create(h *server.Handler, consumer string) ([]*operator.Operator, error) {
+	return h.AddSplitRegionIntoOperator(consumer, a.RegionID, a.Parts)
+}
+
+func (a *splitRegionIntoArgs) precheck(h *server.Handler) (*server.OperatorPrecheck, error) {
+	return h.PrecheckOperators(h.BuildSplitRegionIntoOperator(a.RegionID, a.Parts))
+}
+
+type scatterRegionArgs struct {
+	RegionID uint64 `json:"region_id"`
+	Group    string `json:"group"`
+}
+
+func (a *scatterRegionArgs) validate() *OperatorArgError {
+	if a.RegionID == 0 {
+		return errArgRequired("region_id")
+	}
+	return nil
+}
+
+func (a *scatterRegionArgs) create(h *server.Handler, consumer string) ([]*operator.Operator, error) {
+	return h.AddScatterRegionOperator(consumer, a.RegionID, a.Group)
+}
+
+func (a *scatterRegionArgs) precheck(h *server.Handler) (*server.OperatorPrecheck, error) {
+	return h.PrecheckScatterRegionOperator(a.RegionID)
+}
@@ -0,0 +1,175 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pingcap/errcode"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/pkg/errors"
+)
+
+// maxOperatorBatchSize is the max number of the operator requests in a
+// batch, so that a batch cannot flood the operator controller.
+const maxOperatorBatchSize = 512
+
+// The codes of the errors of the operator requests in a batch.
+const (
+	batchErrInvalidArgument = "invalid-argument"
+	batchErrRegionNotFound  = "region-not-found"
+	batchErrOperatorExists  = "operator-exists"
+	batchErrFailed          = "failed"
+)
+
+// OperatorBatchError is why an operator request in the batch is rejected.
+type OperatorBatchError struct {
+	// Code is one of the codes above, or the code of the error such as
+	// "state.store.tombstoned".
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Field is the invalid field if the code is invalid-argument.
+	Field string `json:"field,omitempty"`
+}
+
+// OperatorBatchItem is the result of an operator request in the batch.
+type OperatorBatchItem struct {
+	Index int `json:"index"`
+	// RegionID is the region of the request, which is 0 if it is absent.
+	RegionID  uint64                 `json:"region_id,omitempty"`
+	Operators []*OperatorDescription `json:"operators,omitempty"`
+	Error     *OperatorBatchError    `json:"error,omitempty"`
+}
+
+// OperatorBatchResult is the results of the operator requests in the batch,
+// in the order of the requests.
+type OperatorBatchResult struct {
+	Added int `json:"added"`
+	// RejectedRegions is the regions of the rejected requests, in the order of
+	// the requests.
+	RejectedRegions []uint64             `json:"rejected_regions"`
+	Items           []*OperatorBatchItem `json:"items"`
+}
+
+// Batch creates the operators by the requests in the array, each of which is
+// in any form accepted by Post except the templates. All the requests are
+// validated before any operator is added, and the rejected ones do not abort
+// the others.
+func (h *operatorHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var shapes []map[string]json.RawMessage
+	if err = json.Unmarshal(data, &shapes); err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(shapes) == 0 || len(shapes) > maxOperatorBatchSize {
+		h.r.JSON(w, http.StatusBadRequest, fmt.Sprintf("the batch should have 1 to %d operators", maxOperatorBatchSize))
+		return
+	}
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	result := &OperatorBatchResult{
+		RejectedRegions: []uint64{},
+		Items:           make([]*OperatorBatchItem, len(shapes)),
+	}
+	argsList := make([]operatorArgs, len(shapes))
+	for i, shape := range shapes {
+		item := &OperatorBatchItem{Index: i, RegionID: batchRegionID(shape)}
+		result.Items[i] = item
+		args, argErr := decodeBatchRequest(shape)
+		if argErr != nil {
+			item.Error = &OperatorBatchError{Code: batchErrInvalidArgument, Message: argErr.Error(), Field: argErr.Field}
+			continue
+		}
+		if item.RegionID != 0 && rc.GetRegion(item.RegionID) == nil {
+			item.Error = &OperatorBatchError{Code: batchErrRegionNotFound, Message: server.ErrRegionNotFound(item.RegionID).Error()}
+			continue
+		}
+		argsList[i] = args
+	}
+
+	consumer := getOperatorConsumer(r)
+	for i, args := range argsList {
+		if args == nil {
+			continue
+		}
+		ops, err := args.create(h.Handler, consumer)
+		if err != nil {
+			result.Items[i].Error = newOperatorBatchError(err)
+			continue
+		}
+		result.Items[i].Operators = newOperatorDescriptions(ops)
+		result.Added++
+	}
+	for _, item := range result.Items {
+		if item.Error != nil && item.RegionID != 0 {
+			result.RejectedRegions = append(result.RejectedRegions, item.RegionID)
+		}
+	}
+	h.r.JSON(w, http.StatusOK, result)
+}
+
+// decodeBatchRequest decodes an operator request in the batch.
+func decodeBatchRequest(shape map[string]json.RawMessage) (operatorArgs, *OperatorArgError) {
+	if _, ok := shape["template"]; ok {
+		return nil, &OperatorArgError{Field: "template", Reason: "not supported in batch"}
+	}
+	data, err := typedOperatorRequest(shape)
+	if err != nil {
+		return nil, &OperatorArgError{Field: "body", Reason: err.Error()}
+	}
+	return decodeOperatorRequest(data)
+}
+
+// batchRegionID returns the region of the operator request in any form, or 0
+// if it is absent or invalid.
+func batchRegionID(shape map[string]json.RawMessage) uint64 {
+	var ids struct {
+		RegionID       uint64 `json:"region_id"`
+		SourceRegionID uint64 `json:"source_region_id"`
+	}
+	data := shape["args"]
+	if data == nil {
+		data, _ = json.Marshal(shape)
+	}
+	if json.Unmarshal(data, &ids) != nil {
+		return 0
+	}
+	if ids.RegionID != 0 {
+		return ids.RegionID
+	}
+	return ids.SourceRegionID
+}
+
+func newOperatorBatchError(err error) *OperatorBatchError {
+	if errors.Cause(err) == server.ErrAddOperator {
+		return &OperatorBatchError{Code: batchErrOperatorExists, Message: err.Error()}
+	}
+	if errCode := errcode.CodeChain(err); errCode != nil {
+		return &OperatorBatchError{Code: errCode.Code().CodeStr().String(), Message: err.Error()}
+	}
+	return &OperatorBatchError{Code: batchErrFailed, Message: err.Error()}
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/unrolled/render"
+)
+
+type operatorQuotaHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newOperatorQuotaHandler(svr *server.Server, rd *render.Render) *operatorQuotaHandler {
+	return &operatorQuotaHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *operatorQuotaHandler) List(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	h.rd.JSON(w, http.StatusOK, rc.GetOperatorQuotas().GetUsages())
+}
+
+func (h *operatorQuotaHandler) Set(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	consumer := mux.Vars(r)["consumer"]
+	if consumer == "" || strings.Contains(consumer, "/") {
+		h.rd.JSON(w, http.StatusBadRequest, "invalid consumer")
+		return
+	}
+	var quota cluster.OperatorQuota
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &quota); err != nil {
+		return
+	}
+	if quota.MaxConcurrent < 0 || quota.MaxPerHour < 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "quota should not be negative")
+		return
+	}
+	if err := rc.GetOperatorQuotas().SetQuota(consumer, quota); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *operatorQuotaHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	ok, err := rc.GetOperatorQuotas().RemoveQuota(mux.Vars(r)["consumer"])
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !ok {
+		h.rd.JSON(w, http.StatusNotFound, "operator quota not found")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
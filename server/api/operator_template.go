@@ -0,0 +1,186 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/unrolled/render"
+)
+
+type operatorTemplateHandler struct {
+	*server.Handler
+	r *render.Render
+}
+
+func newOperatorTemplateHandler(handler *server.Handler, r *render.Render) *operatorTemplateHandler {
+	return &operatorTemplateHandler{
+		Handler: handler,
+		r:       r,
+	}
+}
+
+func (h *operatorTemplateHandler) List(w http.ResponseWriter, r *http.Request) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, rc.GetOperatorTemplates().GetTemplates())
+}
+
+func (h *operatorTemplateHandler) Post(w http.ResponseWriter, r *http.Request) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var template cluster.OperatorTemplate
+	if err = apiutil.ReadJSONRespondError(h.r, w, r.Body, &template); err != nil {
+		return
+	}
+	if err = template.Validate(); err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if argErr := checkOperatorTemplate(rc, &template); argErr != nil {
+		h.r.JSON(w, http.StatusBadRequest, argErr)
+		return
+	}
+	if err = rc.GetOperatorTemplates().SetTemplate(&template, getOperatorConsumer(r)); err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, nil)
+}
+
+func (h *operatorTemplateHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ok, err := rc.GetOperatorTemplates().RemoveTemplate(mux.Vars(r)["name"], getOperatorConsumer(r))
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !ok {
+		h.r.JSON(w, http.StatusNotFound, "operator template not found")
+		return
+	}
+	h.r.JSON(w, http.StatusOK, nil)
+}
+
+// checkOperatorTemplate decodes the default arguments strictly according to
+// the operator, and checks if the constraints can be satisfied.
+func checkOperatorTemplate(rc *cluster.RaftCluster, template *cluster.OperatorTemplate) *OperatorArgError {
+	builder, ok := operatorArgsBuilders[template.Operator]
+	if !ok {
+		return &OperatorArgError{Field: "operator", Reason: "unknown operator"}
+	}
+	data, err := json.Marshal(template.Args)
+	if err != nil {
+		return &OperatorArgError{Field: "args", Reason: err.Error()}
+	}
+	args := builder()
+	if argErr := decodeStrictly(data, args); argErr != nil {
+		argErr.Field = "args." + argErr.Field
+		return argErr
+	}
+	if len(template.ExcludeLabels) == 0 {
+		return nil
+	}
+	targets, ok := args.(targetStoresArgs)
+	if !ok {
+		return &OperatorArgError{Field: "exclude-labels", Reason: "the operator has no target store"}
+	}
+	if argErr := checkTemplateTargets(rc, template, targets.targetStores()); argErr != nil {
+		return argErr
+	}
+	for _, store := range rc.GetStores() {
+		if store.IsUp() && !template.IsExcluded(store) {
+			return nil
+		}
+	}
+	return &OperatorArgError{Field: "exclude-labels", Reason: "all the stores are excluded"}
+}
+
+// checkTemplateTargets checks that none of the target stores is excluded by
+// the template.
+func checkTemplateTargets(rc *cluster.RaftCluster, template *cluster.OperatorTemplate, storeIDs []uint64) *OperatorArgError {
+	for _, id := range storeIDs {
+		if store := rc.GetStore(id); store != nil && template.IsExcluded(store) {
+			return &OperatorArgError{Field: "exclude-labels", Reason: fmt.Sprintf("store %d is excluded by template %s", id, template.Name)}
+		}
+	}
+	return nil
+}
+
+// postTemplate instantiates the template with the other fields of the request
+// overriding the default arguments, which is then handled as a typed request.
+func (h *operatorHandler) postTemplate(w http.ResponseWriter, shape map[string]json.RawMessage, handle func(operatorArgs)) {
+	var name string
+	if err := json.Unmarshal(shape["template"], &name); err != nil || name == "" {
+		h.r.JSON(w, http.StatusBadRequest, &OperatorArgError{Field: "template", Reason: "should be a non-empty string"})
+		return
+	}
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	template := rc.GetOperatorTemplates().GetTemplate(name)
+	if template == nil {
+		h.r.JSON(w, http.StatusBadRequest, &OperatorArgError{Field: "template", Reason: "not found"})
+		return
+	}
+	fields := make(map[string]json.RawMessage, len(template.Args)+len(shape))
+	for k, v := range template.Args {
+		fields[k] = v
+	}
+	for k, v := range shape {
+		if k != "template" {
+			fields[k] = v
+		}
+	}
+	rawArgs, err := json.Marshal(fields)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	data, err := json.Marshal(&operatorRequest{Name: template.Operator, Args: rawArgs})
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	args, argErr := decodeOperatorRequest(data)
+	if argErr != nil {
+		h.r.JSON(w, http.StatusBadRequest, argErr)
+		return
+	}
+	if targets, ok := args.(targetStoresArgs); ok {
+		if argErr = checkTemplateTargets(rc, template, targets.targetStores()); argErr != nil {
+			h.r.JSON(w, http.StatusBadRequest, argErr)
+			return
+		}
+	}
+	handle(args)
+}
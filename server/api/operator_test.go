@@ -14,10 +14,14 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"strings"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/failpoint"
@@ -27,6 +31,8 @@ import (
 	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/pingcap/pd/v4/server/config"
 	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
 )
 
 var _ = Suite(&testOperatorSuite{})
@@ -97,6 +103,12 @@ func (s *testOperatorSuite) TestAddRemovePeer(c *C) {
 	c.Assert(err, IsNil)
 	operator = mustReadURL(c, regionURL)
 	c.Assert(strings.Contains(operator, "add learner peer 2 on store 4"), IsTrue)
+	var descs []*OperatorDescription
+	c.Assert(readJSON(fmt.Sprintf("%s/operators?detail=true", s.urlPrefix), &descs), IsNil)
+	c.Assert(descs, HasLen, 1)
+	c.Assert(descs[0].RegionID, Equals, region.GetId())
+	c.Assert(descs[0].Creator, Equals, "admin")
+	c.Assert(descs[0].Reason, IsNil)
 
 	// Fail to add peer to tombstone store.
 	err = s.svr.GetRaftCluster().BuryStore(3, true)
@@ -135,6 +147,72 @@ func (s *testOperatorSuite) TestMergeRegionOperator(c *C) {
 	c.Assert(err, NotNil)
 }
 
+func (s *testOperatorSuite) TestOperatorQuota(c *C) {
+	r1 := newTestRegionInfo(60, 1, []byte("q1"), []byte("q2"), core.SetRegionVersion(10))
+	mustRegionHeartbeat(c, s.svr, r1)
+	r2 := newTestRegionInfo(61, 1, []byte("q2"), []byte("q3"), core.SetRegionVersion(10))
+	mustRegionHeartbeat(c, s.svr, r2)
+
+	quotaURL := fmt.Sprintf("%s/operator-quotas/import", s.urlPrefix)
+	c.Assert(postJSON(quotaURL, []byte(`{"max-concurrent": -1}`)), NotNil)
+	c.Assert(postJSON(quotaURL, []byte(`{"max-concurrent": 1}`)), IsNil)
+
+	postOperator := func(regionID uint64) *http.Response {
+		data := fmt.Sprintf(`{"name":"split-region", "region_id": %d, "policy": "approximate"}`, regionID)
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/operators", s.urlPrefix), bytes.NewBufferString(data))
+		c.Assert(err, IsNil)
+		req.Header.Set("PD-Consumer", "import")
+		resp, err := dialClient.Do(req)
+		c.Assert(err, IsNil)
+		return resp
+	}
+	resp := postOperator(60)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	resp.Body.Close()
+	resp = postOperator(61)
+	c.Assert(resp.StatusCode, Equals, http.StatusTooManyRequests)
+	body, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(strings.Contains(string(body), `"consumer": "import"`), IsTrue)
+
+	var usages []*cluster.OperatorQuotaUsage
+	c.Assert(readJSON(fmt.Sprintf("%s/operator-quotas", s.urlPrefix), &usages), IsNil)
+	c.Assert(usages, HasLen, 2)
+	c.Assert(usages[1], DeepEquals, &cluster.OperatorQuotaUsage{Consumer: "import", Quota: cluster.OperatorQuota{MaxConcurrent: 1}, Concurrent: 1, ThisHour: 1})
+
+	c.Assert(s.svr.GetHandler().RemoveOperator(60), IsNil)
+	resp = postOperator(61)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	resp.Body.Close()
+	c.Assert(s.svr.GetHandler().RemoveOperator(61), IsNil)
+
+	resp, err = doDelete(quotaURL)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	resp, err = doDelete(quotaURL)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
+
+func (s *testOperatorSuite) TestSplitRegionIntoOperator(c *C) {
+	r := newTestRegionInfo(40, 1, []byte("x"), []byte("y"), core.SetRegionVersion(10))
+	mustRegionHeartbeat(c, s.svr, r)
+
+	err := postJSON(fmt.Sprintf("%s/operators", s.urlPrefix), []byte(`{"name":"split-region-into", "region_id": 40, "parts": 1}`))
+	c.Assert(err, NotNil)
+	err = postJSON(fmt.Sprintf("%s/operators", s.urlPrefix), []byte(`{"name":"split-region-into", "region_id": 40, "parts": 257}`))
+	c.Assert(err, NotNil)
+	err = postJSON(fmt.Sprintf("%s/operators", s.urlPrefix), []byte(`{"name":"split-region-into", "region_id": 40, "parts": 16}`))
+	c.Assert(err, IsNil)
+
+	status := &schedule.SplitIntoStatus{}
+	err = readJSON(fmt.Sprintf("%s/operators/%d", s.urlPrefix, 40), status)
+	c.Assert(err, IsNil)
+	c.Assert(status, DeepEquals, &schedule.SplitIntoStatus{RegionID: 40, Parts: 16, FinishedParts: 0, Status: "RUNNING"})
+	c.Assert(s.svr.GetHandler().RemoveOperator(40), IsNil)
+}
+
 func mustPutStore(c *C, svr *server.Server, id uint64, state metapb.StoreState, labels []*metapb.StoreLabel) {
 	_, err := svr.PutStore(context.Background(), &pdpb.PutStoreRequest{
 		Header: &pdpb.RequestHeader{ClusterId: svr.ClusterID()},
@@ -168,3 +246,749 @@ func mustReadURL(c *C, url string) string {
 	c.Assert(err, IsNil)
 	return string(data)
 }
+
+func (s *testOperatorSuite) postOperator(c *C, body string) (*http.Response, []byte) {
+	resp, err := dialClient.Post(fmt.Sprintf("%s/operators", s.urlPrefix), "application/json", bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp, data
+}
+
+func (s *testOperatorSuite) TestOperatorFormats(c *C) {
+	mustPutStore(c, s.svr, 1, metapb.StoreState_Up, nil)
+	mustPutStore(c, s.svr, 5, metapb.StoreState_Up, nil)
+	// Many peers are added to store 5.
+	c.Assert(s.svr.GetHandler().SetStoreLimit(5, 1000), IsNil)
+	// The regions have peers on the stores, and the region next to it is
+	// the target to merge.
+	heartbeat := func(id uint64, storeIDs ...uint64) {
+		for _, regionID := range []uint64{id, id + 1} {
+			r := newTestRegionInfo(regionID, storeIDs[0], []byte(fmt.Sprintf("f%d", regionID)), []byte(fmt.Sprintf("f%d", regionID+1)),
+				core.SetRegionVersion(3), core.SetRegionConfVer(2))
+			for _, storeID := range storeIDs[1:] {
+				r = r.Clone(core.WithAddPeer(&metapb.Peer{Id: regionID*10 + storeID, StoreId: storeID}))
+			}
+			mustRegionHeartbeat(c, s.svr, r)
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		args   string
+		stores []uint64
+		desc   string
+		ops    int
+	}{
+		{"transfer-leader", `"region_id": %d, "to_store_id": 5`, []uint64{1, 5}, "admin-transfer-leader", 1},
+		{"transfer-region", `"region_id": %d, "to_store_ids": [5]`, []uint64{1}, "admin-move-region", 1},
+		{"transfer-peer", `"region_id": %d, "from_store_id": 1, "to_store_id": 5`, []uint64{1}, "admin-move-peer", 1},
+		{"add-peer", `"region_id": %d, "store_id": 5`, []uint64{1}, "admin-add-peer", 1},
+		{"add-learner", `"region_id": %d, "store_id": 5`, []uint64{1}, "admin-add-learner", 1},
+		{"remove-peer", `"region_id": %d, "store_id": 5`, []uint64{1, 5}, "admin-remove-peer", 1},
+		{"merge-region", `"source_region_id": %d, "target_region_id": %d`, []uint64{1}, "admin-merge-region", 2},
+		{"split-region", `"region_id": %d, "policy": "approximate"`, []uint64{1}, "admin-split-region", 1},
+		{"split-region-into", `"region_id": %d, "parts": 4`, []uint64{1}, schedule.SplitIntoDesc, 1},
+		{"scatter-region", `"region_id": %d`, []uint64{1}, "scatter-region", 0},
+	}
+	for i, t := range testCases {
+		for _, typed := range []bool{false, true} {
+			id := uint64(1000 + i*10)
+			if typed {
+				id += 5
+			}
+			heartbeat(id, t.stores...)
+			args := fmt.Sprintf(t.args, id, id+1)
+			if strings.Count(t.args, "%d") == 1 {
+				args = fmt.Sprintf(t.args, id)
+			}
+			comment := Commentf("operator %s, typed %v", t.name, typed)
+			var body string
+			if typed {
+				body = fmt.Sprintf(`{"name": "%s", "args": {%s}}`, t.name, args)
+			} else {
+				body = fmt.Sprintf(`{"name": "%s", %s}`, t.name, args)
+			}
+			resp, data := s.postOperator(c, body)
+			c.Assert(resp.StatusCode, Equals, http.StatusOK, Commentf("%s: %s", t.name, data))
+			if !typed {
+				c.Assert(resp.Header.Get("Deprecation"), Equals, "true", comment)
+			} else {
+				c.Assert(resp.Header.Get("Deprecation"), Equals, "", comment)
+				var descs []*OperatorDescription
+				c.Assert(json.Unmarshal(data, &descs), IsNil, comment)
+				// The scatter operator is not created if the region is in place.
+				if t.ops > 0 {
+					c.Assert(descs, HasLen, t.ops, comment)
+				}
+				for j, desc := range descs {
+					c.Assert(desc.RegionID, Equals, id+uint64(j), comment)
+					c.Assert(desc.Desc, Equals, t.desc, comment)
+					c.Assert(desc.Kind, Not(Equals), "", comment)
+					c.Assert(desc.RegionEpoch, DeepEquals, &metapb.RegionEpoch{ConfVer: 2, Version: 3}, comment)
+					c.Assert(desc.Steps, Not(HasLen), 0, comment)
+					c.Assert(time.Since(desc.CreateTime), Less, time.Minute, comment)
+				}
+			}
+			s.svr.GetHandler().RemoveOperator(id)
+			s.svr.GetHandler().RemoveOperator(id + 1)
+		}
+	}
+}
+
+func (s *testOperatorSuite) TestOperatorArgErrors(c *C) {
+	testCases := []struct {
+		body  string
+		field string
+	}{
+		{`{"args": {"region_id": 1}}`, "name"},
+		{`{"name": "unknown", "args": {}}`, "name"},
+		{`{"name": "add-peer", "args": {}, "store_id": 1}`, "store_id"},
+		{`{"name": "add-peer", "args": {"region_id": 1}}`, "args.store_id"},
+		{`{"name": "add-peer", "args": {"region_id": "1", "store_id": 1}}`, "args.region_id"},
+		{`{"name": "add-peer", "args": {"region_id": 1, "store_id": 1, "to_store_id": 2}}`, "args.to_store_id"},
+		{`{"name": "transfer-region", "args": {"region_id": 1, "to_store_ids": [1, 1]}}`, "args.to_store_ids"},
+		{`{"name": "merge-region", "args": {"source_region_id": 1, "target_region_id": 1}}`, "args.target_region_id"},
+		{`{"name": "merge-region-by-key", "args": {"key": "zz", "direction": "next"}}`, "args.key"},
+		{`{"name": "merge-region-by-key", "args": {"key": "61"}}`, "args.direction"},
+		{`{"name": "split-region", "args": {"region_id": 1, "policy": "usekey"}}`, "args.keys"},
+		{`{"name": "split-region", "args": {"region_id": 1, "policy": "unknown"}}`, "args.policy"},
+		{`{"name": "split-region-into", "args": {"region_id": 1, "parts": 1}}`, "args.parts"},
+		{`{"name": "scatter-region", "args": null}`, "args.region_id"},
+	}
+	for _, t := range testCases {
+		resp, data := s.postOperator(c, t.body)
+		c.Assert(resp.StatusCode, Equals, http.StatusBadRequest, Commentf(t.body))
+		var argErr OperatorArgError
+		c.Assert(json.Unmarshal(data, &argErr), IsNil, Commentf(t.body))
+		c.Assert(argErr.Field, Equals, t.field, Commentf(t.body))
+	}
+
+	// The legacy format keeps the old errors.
+	resp, data := s.postOperator(c, `{"name": "add-peer", "store_id": 1}`)
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	c.Assert(resp.Header.Get("Deprecation"), Equals, "true")
+	c.Assert(resp.Header.Get("Sunset"), Not(Equals), "")
+	c.Assert(strings.Contains(string(data), "missing region id"), IsTrue)
+}
+
+func (s *testOperatorSuite) TestOperatorTemplates(c *C) {
+	mustPutStore(c, s.svr, 21, metapb.StoreState_Up, nil)
+	mustPutStore(c, s.svr, 22, metapb.StoreState_Up, []*metapb.StoreLabel{{Key: "rack", Value: "r7"}})
+	mustPutStore(c, s.svr, 23, metapb.StoreState_Up, nil)
+	mustPutStore(c, s.svr, 24, metapb.StoreState_Up, nil)
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(2000, 21, []byte("t1"), []byte("t2")))
+	url := fmt.Sprintf("%s/operator-templates", s.urlPrefix)
+
+	// The templates are validated against the operators when saved.
+	testCases := []struct {
+		body  string
+		field string
+	}{
+		{`{"name": "t", "operator": "unknown"}`, "operator"},
+		{`{"name": "t", "operator": "transfer-region", "args": {"to_store_id": 23}}`, "args.to_store_id"},
+		{`{"name": "t", "operator": "transfer-region", "args": {"to_store_ids": 23}}`, "args.to_store_ids"},
+		{`{"name": "t", "operator": "split-region", "exclude-labels": [{"key": "rack", "value": "r7"}]}`, "exclude-labels"},
+		{`{"name": "t", "operator": "transfer-region", "args": {"to_store_ids": [22]}, "exclude-labels": [{"key": "rack", "value": "r7"}]}`, "exclude-labels"},
+		{`{"name": "t", "operator": "transfer-region", "exclude-labels": [{"key": "rack", "value": "r7"}, {"key": "rack", "value": ""}]}`, ""},
+	}
+	for _, t := range testCases {
+		err := postJSON(url, []byte(t.body))
+		c.Assert(err, NotNil, Commentf(t.body))
+		if t.field != "" {
+			var argErr OperatorArgError
+			c.Assert(json.Unmarshal([]byte(err.Error()), &argErr), IsNil, Commentf(t.body))
+			c.Assert(argErr.Field, Equals, t.field, Commentf(t.body))
+		}
+	}
+	c.Assert(postJSON(url, []byte(`{"name": "off-r7", "operator": "transfer-region", "args": {"to_store_ids": [23]}, "exclude-labels": [{"key": "rack", "value": "r7"}]}`)), IsNil)
+	var templates []*cluster.OperatorTemplate
+	c.Assert(readJSON(url, &templates), IsNil)
+	c.Assert(templates, HasLen, 1)
+	c.Assert(templates[0].Operator, Equals, "transfer-region")
+
+	// Instantiate the template with and without overrides.
+	for _, t := range []struct {
+		body    string
+		toStore uint64
+	}{
+		{`{"template": "off-r7", "region_id": 2000}`, 23},
+		{`{"template": "off-r7", "region_id": 2000, "to_store_ids": [24]}`, 24},
+	} {
+		resp, data := s.postOperator(c, t.body)
+		c.Assert(resp.StatusCode, Equals, http.StatusOK, Commentf("%s: %s", t.body, data))
+		var descs []*OperatorDescription
+		c.Assert(json.Unmarshal(data, &descs), IsNil)
+		c.Assert(descs, HasLen, 1)
+		c.Assert(descs[0].Desc, Equals, "admin-move-region")
+		c.Assert(strings.Contains(strings.Join(descs[0].Steps, ","), fmt.Sprintf("store %d", t.toStore)), IsTrue, Commentf("%v", descs[0].Steps))
+		s.svr.GetHandler().RemoveOperator(2000)
+	}
+	for _, t := range []struct {
+		body  string
+		field string
+	}{
+		{`{"template": "unknown", "region_id": 2000}`, "template"},
+		{`{"template": "off-r7"}`, "args.region_id"},
+		{`{"template": "off-r7", "region_id": 2000, "store_id": 23}`, "args.store_id"},
+		{`{"template": "off-r7", "region_id": 2000, "to_store_ids": [22]}`, "exclude-labels"},
+	} {
+		resp, data := s.postOperator(c, t.body)
+		c.Assert(resp.StatusCode, Equals, http.StatusBadRequest, Commentf(t.body))
+		var argErr OperatorArgError
+		c.Assert(json.Unmarshal(data, &argErr), IsNil, Commentf(t.body))
+		c.Assert(argErr.Field, Equals, t.field, Commentf(t.body))
+	}
+
+	resp, err := doDelete(url + "/off-r7")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	resp.Body.Close()
+	resp, err = doDelete(url + "/off-r7")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+	resp.Body.Close()
+}
+
+var _ = Suite(&testMergeRegionByKeySuite{})
+
+type testMergeRegionByKeySuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testMergeRegionByKeySuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) { cfg.Replication.MaxReplicas = 1 })
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testMergeRegionByKeySuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testMergeRegionByKeySuite) TestMergeRegionByKey(c *C) {
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(10, 1, []byte(""), []byte("b")))
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(20, 1, []byte("b"), []byte("c")))
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(30, 1, []byte("c"), []byte("")))
+
+	postMerge := func(key, direction string) (*http.Response, []byte) {
+		body := fmt.Sprintf(`{"name": "merge-region-by-key", "args": {"key": "%x", "direction": "%s"}}`, key, direction)
+		resp, err := dialClient.Post(fmt.Sprintf("%s/operators", s.urlPrefix), "application/json", bytes.NewBufferString(body))
+		c.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(resp.Body)
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		return resp, data
+	}
+	testCases := []struct {
+		key       string
+		direction string
+		regionIDs []uint64
+	}{
+		// The key at the boundary belongs to the region starting with it.
+		{"b", "next", []uint64{20, 30}},
+		{"b", "prev", []uint64{20, 10}},
+		{"a", "next", []uint64{10, 20}},
+		{"d", "prev", []uint64{30, 20}},
+	}
+	for _, t := range testCases {
+		comment := Commentf("key %s, direction %s", t.key, t.direction)
+		resp, data := postMerge(t.key, t.direction)
+		c.Assert(resp.StatusCode, Equals, http.StatusOK, Commentf("%s", data))
+		var descs []*OperatorDescription
+		c.Assert(json.Unmarshal(data, &descs), IsNil, comment)
+		c.Assert(descs, HasLen, 2, comment)
+		for i, desc := range descs {
+			c.Assert(desc.RegionID, Equals, t.regionIDs[i], comment)
+			c.Assert(desc.Desc, Equals, "admin-merge-region", comment)
+			c.Assert(s.svr.GetHandler().RemoveOperator(desc.RegionID), IsNil)
+		}
+	}
+
+	// No region is in the direction of the first or the last region.
+	resp, data := postMerge("a", "prev")
+	c.Assert(resp.StatusCode, Equals, http.StatusInternalServerError)
+	c.Assert(strings.Contains(string(data), "region 10 has no prev region"), IsTrue, Commentf("%s", data))
+	resp, data = postMerge("d", "next")
+	c.Assert(resp.StatusCode, Equals, http.StatusInternalServerError)
+	c.Assert(strings.Contains(string(data), "region 30 has no next region"), IsTrue, Commentf("%s", data))
+	c.Assert(s.svr.GetRaftCluster().GetOperatorController().GetOperators(), HasLen, 0)
+
+	resp, _ = postMerge("a", "up")
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	resp, _ = postMerge("", "next")
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+}
+
+func (s *testOperatorSuite) TestOperatorLatency(c *C) {
+	mustPutStore(c, s.svr, 1, metapb.StoreState_Up, nil)
+	mustPutStore(c, s.svr, 2, metapb.StoreState_Up, nil)
+	peer := &metapb.Peer{Id: 51, StoreId: 1}
+	region := &metapb.Region{
+		Id:          50,
+		Peers:       []*metapb.Peer{peer},
+		StartKey:    []byte("z"),
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}
+	mustRegionHeartbeat(c, s.svr, core.NewRegionInfo(region, peer))
+	c.Assert(postJSON(fmt.Sprintf("%s/operators", s.urlPrefix), []byte(`{"name":"add-peer", "region_id": 50, "store_id": 2}`)), IsNil)
+	defer doDelete(fmt.Sprintf("%s/operators/50", s.urlPrefix))
+
+	latencyURL := fmt.Sprintf("%s/operators/latency", s.urlPrefix)
+	var latencies []*schedule.OperatorLatency
+	c.Assert(readJSON(latencyURL+"?window=1h", &latencies), IsNil)
+	var found bool
+	for _, latency := range latencies {
+		if latency.Creator == operator.CreatorAdmin {
+			found = true
+			c.Assert(latency.Wait.Count, GreaterEqual, 1)
+		}
+	}
+	c.Assert(found, IsTrue)
+	for _, window := range []string{"0s", "2h", "abc"} {
+		c.Assert(readJSON(latencyURL+"?window="+window, &latencies), NotNil)
+	}
+}
+
+func (s *testOperatorSuite) TestOperatorHistory(c *C) {
+	mustPutStore(c, s.svr, 1, metapb.StoreState_Up, nil)
+	mustPutStore(c, s.svr, 2, metapb.StoreState_Up, nil)
+	peer := &metapb.Peer{Id: 61, StoreId: 1}
+	region := &metapb.Region{
+		Id:          60,
+		Peers:       []*metapb.Peer{peer},
+		StartKey:    []byte("zz"),
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}
+	mustRegionHeartbeat(c, s.svr, core.NewRegionInfo(region, peer))
+	start := time.Now().Unix()
+	c.Assert(postJSON(fmt.Sprintf("%s/operators", s.urlPrefix), []byte(`{"name":"transfer-peer", "region_id": 60, "from_store_id": 1, "to_store_id": 2}`)), IsNil)
+	_, err := doDelete(fmt.Sprintf("%s/operators/60", s.urlPrefix))
+	c.Assert(err, IsNil)
+
+	historyURL := fmt.Sprintf("%s/operators/history?start=%d", s.urlPrefix, start)
+	find := func(query string) *operatorHistoryEntry {
+		var entries []*operatorHistoryEntry
+		c.Assert(readJSON(historyURL+query, &entries), IsNil)
+		for _, entry := range entries {
+			if entry.RegionID == 60 {
+				return entry
+			}
+		}
+		return nil
+	}
+	entry := find("")
+	c.Assert(entry, NotNil)
+	c.Assert(entry.Kind, Equals, "region")
+	c.Assert(entry.OpKind, Equals, "leader,region,admin")
+	c.Assert(entry.FromStore, Equals, uint64(1))
+	c.Assert(entry.ToStore, Equals, uint64(2))
+	c.Assert(entry.Result, Equals, operator.OpCanceled)
+	c.Assert(find("&kind=region&store_id=2"), NotNil)
+	c.Assert(find("&kind=admin&limit=10"), NotNil)
+	// The leader is transferred to the new peer before removing the old one.
+	entry = find("&kind=leader")
+	c.Assert(entry, NotNil)
+	c.Assert(entry.Kind, Equals, "leader")
+	c.Assert(find("&store_id=3"), IsNil)
+	var entries []*operatorHistoryEntry
+	c.Assert(readJSON(fmt.Sprintf("%s/operators/history?start=%d", s.urlPrefix, time.Now().Add(time.Hour).Unix()), &entries), IsNil)
+	c.Assert(entries, HasLen, 0)
+	c.Assert(readJSON(fmt.Sprintf("%s/operators/history?start=abc", s.urlPrefix), &entries), NotNil)
+	for _, query := range []string{"&kind=waiting", "&store_id=-1", "&limit=0", "&limit=10001"} {
+		c.Assert(readJSON(historyURL+query, &entries), NotNil)
+	}
+}
+
+func (s *testOperatorSuite) TestOperatorPingPong(c *C) {
+	var status struct {
+		Offenders []*schedule.PingPongOffender `json:"offenders"`
+		Deferred  []string                     `json:"deferred"`
+	}
+	c.Assert(readJSON(fmt.Sprintf("%s/operators/pingpong", s.urlPrefix), &status), IsNil)
+	c.Assert(status.Offenders, HasLen, 0)
+	c.Assert(status.Deferred, HasLen, 0)
+}
+
+func (s *testOperatorSuite) TestRegionAnnotation(c *C) {
+	mustPutStore(c, s.svr, 1, metapb.StoreState_Up, nil)
+	mustPutStore(c, s.svr, 2, metapb.StoreState_Up, nil)
+	addOperator := func(body string) error {
+		return postJSON(fmt.Sprintf("%s/operators", s.urlPrefix), []byte(body))
+	}
+	r1 := newTestRegionInfo(70, 1, []byte("r1"), []byte("r2"), core.WithAddPeer(&metapb.Peer{Id: 702, StoreId: 2}))
+	r2 := newTestRegionInfo(71, 2, []byte("r2"), []byte("r3"), core.WithAddPeer(&metapb.Peer{Id: 711, StoreId: 1}))
+	mustRegionHeartbeat(c, s.svr, r1)
+	mustRegionHeartbeat(c, s.svr, r2)
+
+	// The transfer-leader operator is in the region detail after it completes.
+	c.Assert(addOperator(`{"name":"transfer-leader", "region_id": 70, "to_store_id": 2}`), IsNil)
+	r1 = r1.Clone(core.WithLeader(r1.GetStorePeer(2)))
+	mustRegionHeartbeat(c, s.svr, r1)
+	info := &RegionInfo{}
+	c.Assert(readJSON(fmt.Sprintf("%s/region/id/70", s.urlPrefix), info), IsNil)
+	c.Assert(info.Annotation, NotNil)
+	finished := info.Annotation.LastFinished
+	c.Assert(finished, NotNil)
+	c.Assert(finished.Desc, Equals, "admin-transfer-leader")
+	c.Assert(finished.Creator, Equals, "admin")
+	c.Assert(finished.Result, Equals, string(operator.OpSucceeded))
+	c.Assert(info.Annotation.LastRejected, IsNil)
+	// It is only in the region list with detail.
+	find := func(query string) *RegionInfo {
+		regionsInfo := &RegionsInfo{}
+		c.Assert(readJSON(fmt.Sprintf("%s/regions%s", s.urlPrefix, query), regionsInfo), IsNil)
+		for _, info := range regionsInfo.Regions {
+			if info.ID == 70 {
+				return info
+			}
+		}
+		return nil
+	}
+	c.Assert(find("").Annotation, IsNil)
+	c.Assert(find("?detail=true").Annotation.LastFinished.Desc, Equals, "admin-transfer-leader")
+	c.Assert(find("?detail=true&start_key=7231").Annotation, NotNil)
+
+	// The rejected operator is kept along with the finished one.
+	c.Assert(addOperator(`{"name":"remove-peer", "region_id": 70, "store_id": 1}`), IsNil)
+	c.Assert(addOperator(`{"name":"transfer-leader", "region_id": 70, "to_store_id": 1}`), NotNil)
+	annotation := s.svr.GetRaftCluster().GetRegionAnnotations().Get(70)
+	c.Assert(annotation.LastRejected, NotNil)
+	c.Assert(annotation.LastRejected.Desc, Equals, "admin-transfer-leader")
+	c.Assert(annotation.LastRejected.Reason, Equals, "operator admin-remove-peer is running")
+	c.Assert(annotation.LastFinished.Desc, Equals, "admin-transfer-leader")
+	r1 = r1.Clone(core.WithRemoveStorePeer(1), core.WithIncConfVer())
+	mustRegionHeartbeat(c, s.svr, r1)
+	c.Assert(addOperator(`{"name":"remove-peer", "region_id": 71, "store_id": 1}`), IsNil)
+	r2 = r2.Clone(core.WithRemoveStorePeer(1), core.WithIncConfVer())
+	mustRegionHeartbeat(c, s.svr, r2)
+	c.Assert(s.svr.GetRaftCluster().GetRegionAnnotations().Get(71).LastFinished.Desc, Equals, "admin-remove-peer")
+
+	// After the merge, the surviving region carries its own annotation only.
+	c.Assert(addOperator(`{"name":"merge-region", "source_region_id": 71, "target_region_id": 70}`), IsNil)
+	mustRegionHeartbeat(c, s.svr, r1.Clone(core.WithEndKey([]byte("r3")), core.WithIncVersion()))
+	s.svr.GetHandler().RemoveOperator(71)
+	c.Assert(readJSON(fmt.Sprintf("%s/region/id/70", s.urlPrefix), info), IsNil)
+	c.Assert(info.Annotation.LastFinished.Desc, Equals, "admin-merge-region")
+	c.Assert(info.Annotation.LastFinished.Result, Equals, string(operator.OpSucceeded))
+	c.Assert(info.Annotation.LastRejected.Reason, Equals, "operator admin-remove-peer is running")
+	c.Assert(s.svr.GetRaftCluster().GetRegionAnnotations().Get(71), IsNil)
+}
+
+var _ = Suite(&testOperatorPrecheckSuite{})
+
+type testOperatorPrecheckSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testOperatorPrecheckSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) {
+		cfg.Replication.MaxReplicas = 3
+		cfg.Replication.LocationLabels = []string{"zone"}
+	})
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testOperatorPrecheckSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testOperatorPrecheckSuite) precheck(c *C, body string) *server.OperatorPrecheck {
+	resp, err := dialClient.Post(fmt.Sprintf("%s/operators/precheck", s.urlPrefix), "application/json", bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK, Commentf("%s", data))
+	precheck := &server.OperatorPrecheck{}
+	c.Assert(json.Unmarshal(data, precheck), IsNil)
+	return precheck
+}
+
+func (s *testOperatorPrecheckSuite) TestPrecheck(c *C) {
+	zone := func(z string) []*metapb.StoreLabel {
+		return []*metapb.StoreLabel{{Key: "zone", Value: z}}
+	}
+	mustPutStore(c, s.svr, 1, metapb.StoreState_Up, zone("z1"))
+	mustPutStore(c, s.svr, 2, metapb.StoreState_Up, zone("z2"))
+	mustPutStore(c, s.svr, 3, metapb.StoreState_Up, zone("z3"))
+	mustPutStore(c, s.svr, 4, metapb.StoreState_Up, zone("z3"))
+	mustPutStore(c, s.svr, 5, metapb.StoreState_Offline, zone("z4"))
+	mustPutStore(c, s.svr, 6, metapb.StoreState_Tombstone, zone("z5"))
+	mustPutStore(c, s.svr, 7, metapb.StoreState_Up, zone("z1"))
+
+	// The regions have peers on stores 1, 2 and 3, and region 2 has a
+	// pending peer.
+	heartbeat := func(id uint64, start, end string, opts ...core.RegionCreateOption) {
+		r := newTestRegionInfo(id, 1, []byte(start), []byte(end))
+		for _, storeID := range []uint64{2, 3} {
+			r = r.Clone(core.WithAddPeer(&metapb.Peer{Id: id*10 + storeID, StoreId: storeID}))
+		}
+		mustRegionHeartbeat(c, s.svr, r.Clone(opts...))
+	}
+	heartbeat(1, "", "b")
+	heartbeat(2, "b", "c", core.WithPendingPeers([]*metapb.Peer{{Id: 23, StoreId: 3}}))
+	heartbeat(3, "c", "d")
+	heartbeat(4, "d", "")
+
+	testCases := []struct {
+		body   string
+		passed bool
+		// failed is the name of the check which fails.
+		failed string
+	}{
+		{`{"name":"transfer-leader","args":{"region_id":1,"to_store_id":2}}`, true, ""},
+		{`{"name":"transfer-leader","args":{"region_id":1,"to_store_id":4}}`, false, "arguments"},
+		{`{"name":"transfer-region","args":{"region_id":1,"to_store_ids":[1,2,4]}}`, true, ""},
+		{`{"name":"transfer-region","args":{"region_id":1,"to_store_ids":[1,2,5]}}`, false, "store-state"},
+		{`{"name":"transfer-peer","args":{"region_id":1,"from_store_id":3,"to_store_id":4}}`, true, ""},
+		{`{"name":"transfer-peer","args":{"region_id":1,"from_store_id":3,"to_store_id":7}}`, false, "placement"},
+		{`{"name":"add-peer","args":{"region_id":1,"store_id":4}}`, true, ""},
+		{`{"name":"add-peer","args":{"region_id":2,"store_id":4}}`, false, "region-health"},
+		{`{"name":"add-learner","args":{"region_id":1,"store_id":4}}`, true, ""},
+		{`{"name":"add-learner","args":{"region_id":1,"store_id":6}}`, false, "arguments"},
+		{`{"name":"remove-peer","args":{"region_id":1,"store_id":3}}`, true, ""},
+		{`{"name":"remove-peer","args":{"region_id":1,"store_id":4}}`, false, "arguments"},
+		{`{"name":"merge-region","args":{"source_region_id":3,"target_region_id":4}}`, true, ""},
+		{`{"name":"merge-region","args":{"source_region_id":1,"target_region_id":2}}`, false, "arguments"},
+		{`{"name":"merge-region-by-key","args":{"key":"63","direction":"next"}}`, true, ""},
+		{`{"name":"merge-region-by-key","args":{"key":"61","direction":"prev"}}`, false, "arguments"},
+		{`{"name":"split-region","args":{"region_id":1,"policy":"approximate"}}`, true, ""},
+		{`{"name":"split-region","args":{"region_id":100,"policy":"approximate"}}`, false, "arguments"},
+		{`{"name":"split-region-into","args":{"region_id":1,"parts":2}}`, true, ""},
+		{`{"name":"split-region-into","args":{"region_id":2,"parts":2}}`, false, "region-health"},
+		{`{"name":"scatter-region","args":{"region_id":1}}`, true, ""},
+		{`{"name":"scatter-region","args":{"region_id":2}}`, false, "region-health"},
+		{`{"name":"scatter-region","args":{"region_id":100}}`, false, "arguments"},
+		// The legacy format is accepted too.
+		{`{"name":"add-peer","region_id":1,"store_id":4}`, true, ""},
+		{`{"name":"add-peer","region_id":1,"store_id":5}`, false, "store-state"},
+	}
+	for _, t := range testCases {
+		precheck := s.precheck(c, t.body)
+		comment := Commentf("%s: %+v", t.body, precheck.Checks)
+		c.Assert(precheck.Passed, Equals, t.passed, comment)
+		for _, check := range precheck.Checks {
+			if check.Name == t.failed {
+				c.Assert(check.Result, Equals, server.CheckFailed, comment)
+				c.Assert(check.Detail, Not(Equals), "", comment)
+			} else {
+				c.Assert(check.Result, Not(Equals), server.CheckFailed, comment)
+			}
+		}
+	}
+	// None of the operators is created.
+	for id := uint64(1); id <= 4; id++ {
+		_, err := s.svr.GetHandler().GetOperator(id)
+		c.Assert(err, NotNil)
+	}
+
+	// A running operator conflicts with the new one.
+	_, err := s.svr.GetHandler().AddTransferLeaderOperator("", 1, 2)
+	c.Assert(err, IsNil)
+	precheck := s.precheck(c, `{"name":"transfer-leader","args":{"region_id":1,"to_store_id":3}}`)
+	c.Assert(precheck.Passed, IsFalse)
+	for _, check := range precheck.Checks {
+		if check.Name == "operator-conflict" {
+			c.Assert(check.Result, Equals, server.CheckFailed)
+		}
+	}
+	c.Assert(s.svr.GetHandler().RemoveOperator(1), IsNil)
+
+	// The malformed requests are rejected.
+	resp, err := dialClient.Post(fmt.Sprintf("%s/operators/precheck", s.urlPrefix), "application/json", bytes.NewBufferString(`{"name":"unknown"}`))
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	resp.Body.Close()
+}
+
+var _ = Suite(&testOperatorChainSuite{})
+
+type testOperatorChainSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testOperatorChainSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) { cfg.Replication.MaxReplicas = 2 })
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testOperatorChainSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testOperatorChainSuite) post(c *C, body string) (int, []byte) {
+	resp, err := dialClient.Post(fmt.Sprintf("%s/operators", s.urlPrefix), "application/json", bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp.StatusCode, data
+}
+
+func (s *testOperatorChainSuite) TestChain(c *C) {
+	mustPutStore(c, s.svr, 1, metapb.StoreState_Up, nil)
+	mustPutStore(c, s.svr, 2, metapb.StoreState_Up, nil)
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(1, 1, []byte(""), []byte("b"), core.WithAddPeer(&metapb.Peer{Id: 12, StoreId: 2})))
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(2, 1, []byte("b"), []byte(""), core.WithAddPeer(&metapb.Peer{Id: 22, StoreId: 2})))
+	oc := s.svr.GetRaftCluster().GetOperatorController()
+
+	status, data := s.post(c, `{"name":"transfer-leader","args":{"region_id":1,"to_store_id":2}}`)
+	c.Assert(status, Equals, http.StatusOK, Commentf("%s", data))
+	var descs []*OperatorDescription
+	c.Assert(json.Unmarshal(data, &descs), IsNil)
+	c.Assert(descs, HasLen, 1)
+	token := descs[0].Token
+	c.Assert(token, Not(Equals), uint64(0))
+
+	// The operator on region 2 is held until the one on region 1 finishes.
+	body := fmt.Sprintf(`{"name":"transfer-leader","args":{"region_id":2,"to_store_id":2},"after_region_operator":{"region_id":1,"token":%d}}`, token)
+	status, data = s.post(c, body)
+	c.Assert(status, Equals, http.StatusOK, Commentf("%s", data))
+	c.Assert(json.Unmarshal(data, &descs), IsNil)
+	c.Assert(descs, HasLen, 1)
+	c.Assert(descs[0].After, DeepEquals, &operator.Dependency{RegionID: 1, Token: token})
+	c.Assert(oc.GetOperator(2), IsNil)
+	waiting := mustReadURL(c, fmt.Sprintf("%s/operators?kind=waiting", s.urlPrefix))
+	c.Assert(strings.Contains(waiting, fmt.Sprintf("after:1(%d)", token)), IsTrue, Commentf("%s", waiting))
+
+	// The held operator is canceled as the prerequisite is removed.
+	c.Assert(s.svr.GetHandler().RemoveOperator(1), IsNil)
+	oc.PushOperators()
+	c.Assert(oc.GetOperator(2), IsNil)
+	c.Assert(oc.GetOperatorStatus(2).Status, Equals, pdpb.OperatorStatus_CANCEL)
+	status, data = s.post(c, body)
+	c.Assert(status, Equals, http.StatusBadRequest)
+	c.Assert(strings.Contains(string(data), schedule.ErrPrerequisiteFailed.Error()), IsTrue, Commentf("%s", data))
+
+	status, data = s.post(c, `{"name":"transfer-leader","args":{"region_id":2,"to_store_id":2},"after_region_operator":{"region_id":2,"token":1000000}}`)
+	c.Assert(status, Equals, http.StatusBadRequest)
+	c.Assert(strings.Contains(string(data), schedule.ErrPrerequisiteNotFound.Error()), IsTrue, Commentf("%s", data))
+	status, data = s.post(c, `{"name":"transfer-leader","args":{"region_id":2,"to_store_id":2},"after_region_operator":{"region_id":1}}`)
+	c.Assert(status, Equals, http.StatusBadRequest)
+	c.Assert(strings.Contains(string(data), "after_region_operator.token"), IsTrue, Commentf("%s", data))
+	status, data = s.post(c, fmt.Sprintf(`{"name":"scatter-region","args":{"region_id":2},"after_region_operator":{"region_id":1,"token":%d}}`, token))
+	c.Assert(status, Equals, http.StatusBadRequest)
+	c.Assert(strings.Contains(string(data), "can not be chained"), IsTrue, Commentf("%s", data))
+}
+
+var _ = Suite(&testOperatorBatchSuite{})
+
+type testOperatorBatchSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testOperatorBatchSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) { cfg.Replication.MaxReplicas = 1 })
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testOperatorBatchSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testOperatorBatchSuite) post(c *C, body string) (int, []byte) {
+	resp, err := dialClient.Post(fmt.Sprintf("%s/operators/batch", s.urlPrefix), "application/json", bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp.StatusCode, data
+}
+
+func (s *testOperatorBatchSuite) TestBatch(c *C) {
+	mustPutStore(c, s.svr, 1, metapb.StoreState_Up, nil)
+	mustPutStore(c, s.svr, 2, metapb.StoreState_Up, nil)
+	mustPutStore(c, s.svr, 3, metapb.StoreState_Tombstone, nil)
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(1, 1, []byte(""), []byte("b")))
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(2, 1, []byte("b"), []byte("c")))
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(3, 1, []byte("c"), []byte("")))
+
+	status, data := s.post(c, `[
+		{"name":"add-peer","args":{"region_id":1,"store_id":2}},
+		{"name":"add-peer","args":{"region_id":100,"store_id":2}},
+		{"name":"add-peer","args":{"region_id":1,"store_id":2}},
+		{"name":"add-learner","region_id":2,"store_id":2},
+		{"name":"add-peer","args":{"region_id":3,"store_id":3}},
+		{"name":"add-peer","args":{"region_id":3}},
+		{"template":"t","args":{}}
+	]`)
+	c.Assert(status, Equals, http.StatusOK, Commentf("%s", data))
+	result := &OperatorBatchResult{}
+	c.Assert(json.Unmarshal(data, result), IsNil)
+	c.Assert(result.Added, Equals, 2)
+	c.Assert(result.RejectedRegions, DeepEquals, []uint64{100, 1, 3, 3})
+	c.Assert(result.Items, HasLen, 7)
+
+	testCases := []struct {
+		regionID uint64
+		code     string
+		field    string
+	}{
+		{1, "", ""},
+		{100, batchErrRegionNotFound, ""},
+		{1, batchErrOperatorExists, ""},
+		{2, "", ""},
+		{3, "state.store.tombstoned", ""},
+		{3, batchErrInvalidArgument, "args.store_id"},
+		{0, batchErrInvalidArgument, "template"},
+	}
+	for i, t := range testCases {
+		item := result.Items[i]
+		c.Assert(item.Index, Equals, i)
+		c.Assert(item.RegionID, Equals, t.regionID)
+		if t.code == "" {
+			c.Assert(item.Error, IsNil, Commentf("%d: %+v", i, item.Error))
+			c.Assert(item.Operators, HasLen, 1)
+			continue
+		}
+		c.Assert(item.Error, NotNil, Commentf("%d", i))
+		c.Assert(item.Error.Code, Equals, t.code, Commentf("%d: %s", i, item.Error.Message))
+		c.Assert(item.Error.Field, Equals, t.field, Commentf("%d", i))
+		c.Assert(item.Operators, HasLen, 0)
+	}
+	oc := s.svr.GetRaftCluster().GetOperatorController()
+	c.Assert(oc.GetOperator(1), NotNil)
+	c.Assert(oc.GetOperator(2), NotNil)
+	c.Assert(oc.GetOperator(3), IsNil)
+
+	status, _ = s.post(c, `[]`)
+	c.Assert(status, Equals, http.StatusBadRequest)
+	status, _ = s.post(c, `{"name":"add-peer","args":{"region_id":3,"store_id":2}}`)
+	c.Assert(status, Equals, http.StatusBadRequest)
+	items := make([]string, maxOperatorBatchSize+1)
+	for i := range items {
+		items[i] = `{"name":"add-peer","args":{"region_id":3,"store_id":2}}`
+	}
+	status, _ = s.post(c, "["+strings.Join(items, ",")+"]")
+	c.Assert(status, Equals, http.StatusBadRequest)
+	c.Assert(oc.GetOperator(3), IsNil)
+}
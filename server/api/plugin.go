@@ -45,6 +45,15 @@ func (h *pluginHandler) UnloadPlugin(w http.ResponseWriter, r *http.Request) {
 	h.processPluginCommand(w, r, cluster.PluginUnload)
 }
 
+func (h *pluginHandler) GetPlugins(w http.ResponseWriter, r *http.Request) {
+	plugins, err := h.GetLoadedPlugins()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, plugins)
+}
+
 func (h *pluginHandler) processPluginCommand(w http.ResponseWriter, r *http.Request, action string) {
 	data := make(map[string]string)
 	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &data); err != nil {
@@ -0,0 +1,165 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pkg/errors"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultCPUProfileSeconds is the duration of a CPU profile if it is not
+	// specified.
+	defaultCPUProfileSeconds = 30
+	// maxCPUProfileSeconds is the longest duration of a CPU profile.
+	maxCPUProfileSeconds = 120
+	// profileCooldown is how long the next capture waits after a capture ends.
+	profileCooldown = 10 * time.Second
+)
+
+var (
+	errProfileInProgress = errors.New("another profile is being captured")
+	errProfileCooldown   = errors.New("profile capture is cooling down")
+)
+
+// profileLimiter allows one profile capture at a time, and the next one after
+// the cooldown since the last one ends, so that profiling cannot be used to
+// overload the server. It is threadsafe.
+type profileLimiter struct {
+	sync.Mutex
+	capturing bool
+	lastEnd   time.Time
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+func newProfileLimiter(cooldown time.Duration) *profileLimiter {
+	return &profileLimiter{cooldown: cooldown, now: time.Now}
+}
+
+// begin starts a capture, or returns the error and how long to wait if it is
+// not allowed.
+func (l *profileLimiter) begin() (time.Duration, error) {
+	l.Lock()
+	defer l.Unlock()
+	if l.capturing {
+		return 0, errProfileInProgress
+	}
+	if wait := l.lastEnd.Add(l.cooldown).Sub(l.now()); wait > 0 {
+		return wait, errProfileCooldown
+	}
+	l.capturing = true
+	return 0, nil
+}
+
+func (l *profileLimiter) end() {
+	l.Lock()
+	defer l.Unlock()
+	l.capturing = false
+	l.lastEnd = l.now()
+}
+
+// pprofHandler serves the profiles of runtime/pprof. The clients must present
+// a certificate of the allowed common names if they are configured, and the
+// captures are limited by the profileLimiter.
+type pprofHandler struct {
+	rd        *render.Render
+	allowedCN []string
+	limiter   *profileLimiter
+}
+
+func newPprofHandler(rd *render.Render, allowedCN []string) *pprofHandler {
+	return &pprofHandler{
+		rd:        rd,
+		allowedCN: allowedCN,
+		limiter:   newProfileLimiter(profileCooldown),
+	}
+}
+
+// authorize checks the common name of the client certificate against the
+// allowed ones, which are not checked if none is configured.
+func (h *pprofHandler) authorize(r *http.Request) (string, bool) {
+	var cn string
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		cn = r.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	if len(h.allowedCN) == 0 {
+		return cn, true
+	}
+	for _, allowed := range h.allowedCN {
+		if cn == allowed {
+			return cn, true
+		}
+	}
+	return cn, false
+}
+
+// capture serves the profile by the handler if the request is authorized and
+// the limiter allows it. The captures are logged for audit.
+func (h *pprofHandler) capture(kind string, handler http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cn, ok := h.authorize(r)
+		if !ok {
+			log.Warn("profile capture is denied", zap.String("kind", kind), zap.String("remote", r.RemoteAddr), zap.String("common-name", cn))
+			h.rd.JSON(w, http.StatusForbidden, "the common name of the client certificate is not allowed")
+			return
+		}
+		wait, err := h.limiter.begin()
+		if err != nil {
+			if wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			}
+			h.rd.JSON(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		start := time.Now()
+		log.Info("profile capture starts", zap.String("kind", kind), zap.String("remote", r.RemoteAddr), zap.String("common-name", cn), zap.String("query", r.URL.RawQuery))
+		defer func() {
+			h.limiter.end()
+			log.Info("profile capture ends", zap.String("kind", kind), zap.String("remote", r.RemoteAddr), zap.String("common-name", cn), zap.Duration("duration", time.Since(start)))
+		}()
+		handler.ServeHTTP(w, r)
+	}
+}
+
+// lookup returns the handler capturing the named profile.
+func (h *pprofHandler) lookup(name string) http.HandlerFunc {
+	return h.capture(name, pprof.Handler(name))
+}
+
+// CPU captures a CPU profile for the seconds, 30 by default.
+func (h *pprofHandler) CPU(w http.ResponseWriter, r *http.Request) {
+	if s := r.URL.Query().Get("seconds"); s != "" {
+		seconds, err := strconv.Atoi(s)
+		if err != nil || seconds <= 0 || seconds > maxCPUProfileSeconds {
+			h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("seconds should be an integer in [1, %d]", maxCPUProfileSeconds))
+			return
+		}
+	} else {
+		q := r.URL.Query()
+		q.Set("seconds", strconv.Itoa(defaultCPUProfileSeconds))
+		r.URL.RawQuery = q.Encode()
+	}
+	h.capture("cpu", http.HandlerFunc(pprof.Profile))(w, r)
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testPprofSuite{})
+
+type testPprofSuite struct{}
+
+func (s *testPprofSuite) newHandler(allowedCN ...string) *pprofHandler {
+	h := newPprofHandler(createIndentRender(), allowedCN)
+	h.limiter.cooldown = 0
+	return h
+}
+
+func (s *testPprofSuite) serve(handler http.HandlerFunc, method, url string, cn string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, url, nil)
+	if cn != "" {
+		r.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: cn}}},
+		}
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// checkProfile checks the body is a gzipped profile in the protobuf format,
+// whose fields are all varints or length-delimited.
+func (s *testPprofSuite) checkProfile(c *C, w *httptest.ResponseRecorder) {
+	c.Assert(w.Code, Equals, http.StatusOK)
+	gr, err := gzip.NewReader(w.Body)
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(gr)
+	c.Assert(err, IsNil)
+	c.Assert(data, Not(HasLen), 0)
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		c.Assert(n > 0, IsTrue)
+		data = data[n:]
+		switch key & 7 {
+		case 0:
+			_, n = binary.Uvarint(data)
+			c.Assert(n > 0, IsTrue)
+			data = data[n:]
+		case 2:
+			size, n := binary.Uvarint(data)
+			c.Assert(n > 0 && uint64(len(data)-n) >= size, IsTrue)
+			data = data[uint64(n)+size:]
+		default:
+			c.Fatalf("unexpected wire type of field %d", key>>3)
+		}
+	}
+}
+
+func (s *testPprofSuite) TestProfiles(c *C) {
+	h := s.newHandler()
+	for _, name := range []string{"heap", "goroutine", "mutex"} {
+		s.checkProfile(c, s.serve(h.lookup(name), http.MethodGet, "/debug/pprof/"+name, ""))
+	}
+	s.checkProfile(c, s.serve(h.CPU, http.MethodPost, "/debug/pprof/cpu?seconds=1", ""))
+
+	c.Assert(s.serve(h.CPU, http.MethodPost, "/debug/pprof/cpu?seconds=0", "").Code, Equals, http.StatusBadRequest)
+	c.Assert(s.serve(h.CPU, http.MethodPost, "/debug/pprof/cpu?seconds=121", "").Code, Equals, http.StatusBadRequest)
+}
+
+func (s *testPprofSuite) TestAuthorize(c *C) {
+	h := s.newHandler("pd-admin")
+	c.Assert(s.serve(h.lookup("heap"), http.MethodGet, "/debug/pprof/heap", "").Code, Equals, http.StatusForbidden)
+	c.Assert(s.serve(h.lookup("heap"), http.MethodGet, "/debug/pprof/heap", "tidb").Code, Equals, http.StatusForbidden)
+	s.checkProfile(c, s.serve(h.lookup("heap"), http.MethodGet, "/debug/pprof/heap", "pd-admin"))
+}
+
+func (s *testPprofSuite) TestConcurrentCapture(c *C) {
+	h := s.newHandler()
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- s.serve(h.CPU, http.MethodPost, "/debug/pprof/cpu?seconds=1", "")
+	}()
+	for {
+		h.limiter.Lock()
+		capturing := h.limiter.capturing
+		h.limiter.Unlock()
+		if capturing {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(s.serve(h.lookup("heap"), http.MethodGet, "/debug/pprof/heap", "").Code, Equals, http.StatusTooManyRequests)
+	c.Assert(s.serve(h.CPU, http.MethodPost, "/debug/pprof/cpu?seconds=1", "").Code, Equals, http.StatusTooManyRequests)
+	s.checkProfile(c, <-done)
+	s.checkProfile(c, s.serve(h.lookup("heap"), http.MethodGet, "/debug/pprof/heap", ""))
+}
+
+func (s *testPprofSuite) TestCooldown(c *C) {
+	l := newProfileLimiter(10 * time.Second)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	_, err := l.begin()
+	c.Assert(err, IsNil)
+	_, err = l.begin()
+	c.Assert(err, Equals, errProfileInProgress)
+	l.end()
+
+	now = now.Add(4 * time.Second)
+	wait, err := l.begin()
+	c.Assert(err, Equals, errProfileCooldown)
+	c.Assert(wait, Equals, 6*time.Second)
+	now = now.Add(6 * time.Second)
+	_, err = l.begin()
+	c.Assert(err, IsNil)
+}
@@ -14,16 +14,23 @@
 package api
 
 import (
+	"bytes"
 	"container/heap"
+	"encoding/hex"
+	"fmt"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/pkg/apiutil"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pkg/errors"
 	"github.com/unrolled/render"
 )
 
@@ -44,6 +51,15 @@ type RegionInfo struct {
 	ReadKeys        uint64            `json:"read_keys"`
 	ApproximateSize int64             `json:"approximate_size"`
 	ApproximateKeys int64             `json:"approximate_keys"`
+	// Term is the raft term of the leader cached in PD, and 0 means unknown.
+	Term uint64 `json:"term,omitempty"`
+
+	// Activity and SuggestedHeartbeatInterval are only filled for the region detail.
+	Activity                   string `json:"activity,omitempty"`
+	SuggestedHeartbeatInterval string `json:"suggested_heartbeat_interval,omitempty"`
+	// Annotation is what PD did to the region lately, which is filled for the
+	// region detail and the region list with detail.
+	Annotation *core.RegionAnnotation `json:"annotation,omitempty"`
 }
 
 // NewRegionInfo create a new api RegionInfo.
@@ -71,6 +87,7 @@ func InitRegion(r *core.RegionInfo, s *RegionInfo) *RegionInfo {
 	s.ReadKeys = r.GetKeysRead()
 	s.ApproximateSize = r.GetApproximateSize()
 	s.ApproximateKeys = r.GetApproximateKeys()
+	s.Term = r.GetTerm()
 
 	return s
 }
@@ -96,16 +113,14 @@ func newRegionHandler(svr *server.Server, rd *render.Render) *regionHandler {
 func (h *regionHandler) GetRegionByID(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r.Context())
 
-	vars := mux.Vars(r)
-	regionIDStr := vars["id"]
-	regionID, err := strconv.ParseUint(regionIDStr, 10, 64)
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["id"])
 	if err != nil {
 		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	regionInfo := rc.GetRegion(regionID)
-	h.rd.JSON(w, http.StatusOK, NewRegionInfo(regionInfo))
+	h.rd.JSON(w, http.StatusOK, newRegionDetail(rc, regionInfo))
 }
 
 func (h *regionHandler) GetRegionByKey(w http.ResponseWriter, r *http.Request) {
@@ -113,7 +128,109 @@ func (h *regionHandler) GetRegionByKey(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	key := vars["key"]
 	regionInfo := rc.GetRegionInfoByKey([]byte(key))
-	h.rd.JSON(w, http.StatusOK, NewRegionInfo(regionInfo))
+	h.rd.JSON(w, http.StatusOK, newRegionDetail(rc, regionInfo))
+}
+
+// newRegionDetail creates a new api RegionInfo with the activity of the region.
+func newRegionDetail(rc *cluster.RaftCluster, r *core.RegionInfo) *RegionInfo {
+	info := NewRegionInfo(r)
+	if info == nil {
+		return nil
+	}
+	if activity, ok := rc.GetRegionActivity(r.GetID()); ok {
+		info.Activity = activity.String()
+		info.SuggestedHeartbeatInterval = activity.SuggestedHeartbeatInterval().String()
+	}
+	info.Annotation = rc.GetRegionAnnotations().Get(r.GetID())
+	return info
+}
+
+// RankedPeer is a peer ranked by how close it is to a location.
+type RankedPeer struct {
+	Peer     *metapb.Peer `json:"peer"`
+	Score    int          `json:"score"`
+	IsLeader bool         `json:"is_leader"`
+}
+
+// ClosestPeers records the live peers of a region from the closest to the
+// farthest to a location.
+type ClosestPeers struct {
+	RegionID uint64        `json:"region_id"`
+	Peers    []*RankedPeer `json:"peers"`
+}
+
+func newClosestPeers(rc *cluster.RaftCluster, region *core.RegionInfo, location []*metapb.StoreLabel) *ClosestPeers {
+	ranked := core.RankPeersByLocation(region, rc.GetStore, rc.GetLocationLabels(), location)
+	peers := make([]*RankedPeer, 0, len(ranked))
+	for _, p := range ranked {
+		peers = append(peers, &RankedPeer{
+			Peer:     p.Peer,
+			Score:    p.Score,
+			IsLeader: p.Peer.GetId() == region.GetLeader().GetId(),
+		})
+	}
+	return &ClosestPeers{RegionID: region.GetID(), Peers: peers}
+}
+
+// parseLocation parses the location in the form of "zone=z1,rack=r1".
+func parseLocation(s string) ([]*metapb.StoreLabel, error) {
+	var location []*metapb.StoreLabel
+	keys := make(map[string]struct{})
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, errors.Errorf("invalid label %q, should be in the form of key=value", part)
+		}
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		if _, ok := keys[key]; ok {
+			return nil, errors.Errorf("duplicated label key %s", key)
+		}
+		keys[key] = struct{}{}
+		location = append(location, &metapb.StoreLabel{Key: key, Value: strings.TrimSpace(kv[1])})
+	}
+	return location, nil
+}
+
+func (h *regionHandler) GetClosestPeers(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["id"])
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	location, err := parseLocation(r.URL.Query().Get("labels"))
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	region := rc.GetRegion(regionID)
+	if region == nil {
+		h.rd.JSON(w, http.StatusNotFound, server.ErrRegionNotFound(regionID).Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, newClosestPeers(rc, region, location))
+}
+
+const defaultRegionAncestryDepth = 8
+
+// GetAncestry walks the lineage of the region, which may have vanished after
+// a split or merge.
+func (h *regionHandler) GetAncestry(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["id"])
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	depth := defaultRegionAncestryDepth
+	if depthStr := r.URL.Query().Get("depth"); depthStr != "" {
+		depth, err = strconv.Atoi(depthStr)
+		if err != nil || depth <= 0 || depth > cluster.MaxRegionLineageDepth {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid depth")
+			return
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, rc.GetRegionLineage().GetAncestry(regionID, depth))
 }
 
 type regionsHandler struct {
@@ -144,11 +261,77 @@ func convertToAPIRegions(regions []*core.RegionInfo) *RegionsInfo {
 	}
 }
 
+// RegionsPage is a page of the regions ordered by the start keys.
+type RegionsPage struct {
+	*RegionsInfo
+	// NextKey is the hex encoded start key of the next page, which is empty
+	// if HasMore is false.
+	NextKey string `json:"next_key"`
+	HasMore bool   `json:"has_more"`
+}
+
+// annotateAPIRegions fills the annotations of the regions.
+func annotateAPIRegions(rc *cluster.RaftCluster, regionsInfo *RegionsInfo) {
+	annotations := rc.GetRegionAnnotations()
+	for _, info := range regionsInfo.Regions {
+		info.Annotation = annotations.Get(info.ID)
+	}
+}
+
+// GetAll lists all the regions, or a page of them if start_key or limit is
+// given. The annotations of the regions are included if detail is true.
 func (h *regionsHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r.Context())
-	regions := rc.GetRegions()
-	regionsInfo := convertToAPIRegions(regions)
-	h.rd.JSON(w, http.StatusOK, regionsInfo)
+	query := r.URL.Query()
+	detail := false
+	if detailStr := query.Get("detail"); detailStr != "" {
+		var err error
+		if detail, err = strconv.ParseBool(detailStr); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("invalid detail %s", detailStr))
+			return
+		}
+	}
+	startKeyHex, limitStr := query.Get("start_key"), query.Get("limit")
+	if startKeyHex == "" && limitStr == "" {
+		regions := rc.GetRegions()
+		regionsInfo := convertToAPIRegions(regions)
+		if detail {
+			annotateAPIRegions(rc, regionsInfo)
+		}
+		h.rd.JSON(w, http.StatusOK, regionsInfo)
+		return
+	}
+
+	startKey, err := apiutil.ParseHexKeyParam("start_key", startKeyHex)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit := defaultRegionLimit
+	if limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %s", limitStr))
+			return
+		}
+	}
+	if limit > maxRegionLimit {
+		limit = maxRegionLimit
+	}
+	regions := rc.ScanRegions(startKey, nil, limit)
+	page := &RegionsPage{RegionsInfo: convertToAPIRegions(regions)}
+	if detail {
+		annotateAPIRegions(rc, page.RegionsInfo)
+	}
+	// There may be more regions only if the page is full, and its last region
+	// does not reach the end of the key space.
+	if len(regions) == limit {
+		if endKey := regions[len(regions)-1].GetEndKey(); len(endKey) > 0 {
+			page.NextKey = core.HexRegionKeyStr(endKey)
+			page.HasMore = true
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, page)
 }
 
 func (h *regionsHandler) ScanRegions(w http.ResponseWriter, r *http.Request) {
@@ -167,7 +350,24 @@ func (h *regionsHandler) ScanRegions(w http.ResponseWriter, r *http.Request) {
 	if limit > maxRegionLimit {
 		limit = maxRegionLimit
 	}
-	regions := rc.ScanRegions([]byte(startKey), nil, limit)
+	endKey := r.URL.Query().Get("end_key")
+
+	var regions []*core.RegionInfo
+	if storeIDStr := r.URL.Query().Get("store_id"); storeIDStr != "" {
+		storeID, err := apiutil.ParseStoreIDParam(storeIDStr)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		role := core.PeerRole(r.URL.Query().Get("role"))
+		if !core.IsValidPeerRole(role) {
+			h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("invalid role %s", role))
+			return
+		}
+		regions = rc.ScanStoreRegions(storeID, role, []byte(startKey), []byte(endKey), limit)
+	} else {
+		regions = rc.ScanRegions([]byte(startKey), []byte(endKey), limit)
+	}
 	regionsInfo := convertToAPIRegions(regions)
 	h.rd.JSON(w, http.StatusOK, regionsInfo)
 }
@@ -178,16 +378,31 @@ func (h *regionsHandler) GetRegionCount(w http.ResponseWriter, r *http.Request)
 	h.rd.JSON(w, http.StatusOK, &RegionsInfo{Count: count})
 }
 
+// GetStoreRegions lists the regions which have a peer on the store, or the
+// ones whose peer on the store has the role, or only counts them if count is
+// given.
 func (h *regionsHandler) GetStoreRegions(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r.Context())
 
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	storeID, err := apiutil.ParseStoreIDParam(mux.Vars(r)["id"])
 	if err != nil {
 		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	regions := rc.GetStoreRegions(uint64(id))
+	if rc.GetStore(storeID) == nil {
+		apiutil.ErrorResp(h.rd, w, core.NewStoreNotFoundErr(storeID))
+		return
+	}
+	role := core.PeerRole(r.URL.Query().Get("role"))
+	if !core.IsValidPeerRole(role) {
+		h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("invalid role %s", role))
+		return
+	}
+	if _, ok := r.URL.Query()["count"]; ok {
+		h.rd.JSON(w, http.StatusOK, &RegionsInfo{Count: rc.GetStoreRegionCountByRole(storeID, role)})
+		return
+	}
+	regions := rc.GetStoreRegionsByRole(storeID, role)
 	regionsInfo := convertToAPIRegions(regions)
 	h.rd.JSON(w, http.StatusOK, regionsInfo)
 }
@@ -258,6 +473,51 @@ func (h *regionsHandler) GetEmptyRegion(w http.ResponseWriter, r *http.Request)
 	h.rd.JSON(w, http.StatusOK, regionsInfo)
 }
 
+// GetSizeAgeDistribution returns the distribution of how long the approximate
+// size and keys of the regions have not changed.
+func (h *regionsHandler) GetSizeAgeDistribution(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	h.rd.JSON(w, http.StatusOK, rc.GetRegionSizeAgeDistribution())
+}
+
+// GetLeaderChurnRegions returns the regions with the most leader changes in
+// the recent window.
+func (h *regionsHandler) GetLeaderChurnRegions(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	top := defaultLeaderChurnTop
+	if topStr := r.URL.Query().Get("top"); topStr != "" {
+		var err error
+		top, err = strconv.Atoi(topStr)
+		if err != nil || top <= 0 {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid top")
+			return
+		}
+	}
+	if top > maxRegionLimit {
+		top = maxRegionLimit
+	}
+	h.rd.JSON(w, http.StatusOK, rc.GetLeaderChurnRegions(top))
+}
+
+// GetGrowthRegions returns the regions whose approximate sizes grow the
+// fastest recently.
+func (h *regionsHandler) GetGrowthRegions(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	top := defaultRegionGrowthTop
+	if topStr := r.URL.Query().Get("top"); topStr != "" {
+		var err error
+		top, err = strconv.Atoi(topStr)
+		if err != nil || top <= 0 {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid top")
+			return
+		}
+	}
+	if top > maxRegionLimit {
+		top = maxRegionLimit
+	}
+	h.rd.JSON(w, http.StatusOK, rc.GetRegionGrowthTopGrowers(top))
+}
+
 type histItem struct {
 	Start int64 `json:"start"`
 	End   int64 `json:"end"`
@@ -350,15 +610,14 @@ func calHist(bound int, list *[]int64) *[]*histItem {
 func (h *regionsHandler) GetRegionSiblings(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r.Context())
 
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["id"])
 	if err != nil {
 		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	region := rc.GetRegion(uint64(id))
+	region := rc.GetRegion(regionID)
 	if region == nil {
-		h.rd.JSON(w, http.StatusNotFound, server.ErrRegionNotFound(uint64(id)).Error())
+		h.rd.JSON(w, http.StatusNotFound, server.ErrRegionNotFound(regionID).Error())
 		return
 	}
 
@@ -367,9 +626,38 @@ func (h *regionsHandler) GetRegionSiblings(w http.ResponseWriter, r *http.Reques
 	h.rd.JSON(w, http.StatusOK, regionsInfo)
 }
 
+type closestPeersInput struct {
+	RegionIDs []uint64 `json:"region_ids"`
+	Labels    string   `json:"labels"`
+}
+
+// BatchGetClosestPeers ranks the peers of the regions, and the regions not
+// found are omitted.
+func (h *regionsHandler) BatchGetClosestPeers(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	var input closestPeersInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	location, err := parseLocation(input.Labels)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	res := make([]*ClosestPeers, 0, len(input.RegionIDs))
+	for _, id := range input.RegionIDs {
+		if region := rc.GetRegion(id); region != nil {
+			res = append(res, newClosestPeers(rc, region, location))
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, res)
+}
+
 const (
 	defaultRegionLimit     = 16
 	maxRegionLimit         = 10240
+	defaultLeaderChurnTop  = 50
+	defaultRegionGrowthTop = 50
 	minRegionHistogramSize = 1
 	minRegionHistogramKeys = 1000
 )
@@ -478,3 +766,70 @@ func TopNRegions(regions []*core.RegionInfo, less func(a, b *core.RegionInfo) bo
 	}
 	return res
 }
+
+const defaultScatterRegionsLimit = 1024
+
+// scatterRegionsInput is the key range of the regions to scatter. The keys are
+// in hex format, and the empty end key means the end of the key space.
+type scatterRegionsInput struct {
+	StartKey string `json:"start_key"`
+	EndKey   string `json:"end_key"`
+	Group    string `json:"group"`
+	Limit    int    `json:"limit"`
+}
+
+// ScatterRegionsResult is the result of scattering the regions in a key range.
+type ScatterRegionsResult struct {
+	Scattered  int `json:"scattered"`
+	Hot        int `json:"hot"`
+	Failed     int `json:"failed"`
+	NoOperator int `json:"no_operator"`
+	// NextKey is the start key in hex format to scatter the remaining regions from,
+	// or empty if all the regions in the range are handled.
+	NextKey string `json:"next_key"`
+}
+
+func (h *regionsHandler) ScatterRegions(w http.ResponseWriter, r *http.Request) {
+	var input scatterRegionsInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	startKey, err := apiutil.ParseHexKeyParam("start_key", input.StartKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	endKey, err := apiutil.ParseHexKeyParam("end_key", input.EndKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(endKey) > 0 && bytes.Compare(startKey, endKey) >= 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "start_key should be less than end_key")
+		return
+	}
+	limit := input.Limit
+	if limit < 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "limit should not be negative")
+		return
+	}
+	if limit == 0 {
+		limit = defaultScatterRegionsLimit
+	}
+	if limit > maxRegionLimit {
+		limit = maxRegionLimit
+	}
+
+	res, err := h.svr.GetHandler().ScatterRegions(getOperatorConsumer(r), startKey, endKey, input.Group, limit)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, &ScatterRegionsResult{
+		Scattered:  res.Scattered,
+		Hot:        res.Hot,
+		Failed:     res.Failed,
+		NoOperator: res.NoOperator,
+		NextKey:    hex.EncodeToString(res.NextKey),
+	})
+}
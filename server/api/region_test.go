@@ -15,17 +15,26 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math/rand"
+	"net/http"
 	"net/url"
 	"sort"
 	"testing"
+	"time"
 
+	"github.com/docker/go-units"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/statistics"
 )
 
 var _ = Suite(&testRegionSuite{})
@@ -75,6 +84,86 @@ func newTestRegionInfo(regionID, storeID uint64, start, end []byte, opts ...core
 	return region
 }
 
+var _ = Suite(&testScatterRegionsSuite{})
+
+type testScatterRegionsSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testScatterRegionsSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testScatterRegionsSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testScatterRegionsSuite) scatter(c *C, input map[string]interface{}) *ScatterRegionsResult {
+	data, err := json.Marshal(input)
+	c.Assert(err, IsNil)
+	res := &ScatterRegionsResult{}
+	err = postJSON(s.urlPrefix+"/regions/scatter", data, func(body []byte, code int) {
+		c.Assert(json.Unmarshal(body, res), IsNil)
+	})
+	c.Assert(err, IsNil)
+	return res
+}
+
+func (s *testScatterRegionsSuite) TestScatterRegions(c *C) {
+	rc := s.svr.GetRaftCluster()
+	for id := uint64(1); id <= 4; id++ {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, nil)
+		c.Assert(rc.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: id, Capacity: 100 * units.GiB, Available: 100 * units.GiB}), IsNil)
+	}
+	// Regions 2 to 11 have 3 peers on the stores 1, 2 and 3, and region 12
+	// misses its peers.
+	for id := uint64(2); id <= 11; id++ {
+		region := newTestRegionInfo(id, 1, []byte{byte(id)}, []byte{byte(id + 1)},
+			core.WithAddPeer(&metapb.Peer{Id: id*10 + 2, StoreId: 2}),
+			core.WithAddPeer(&metapb.Peer{Id: id*10 + 3, StoreId: 3}))
+		mustRegionHeartbeat(c, s.svr, region)
+	}
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(12, 1, []byte{12}, []byte{13}))
+
+	// Bad inputs.
+	url := s.urlPrefix + "/regions/scatter"
+	for _, input := range []string{
+		`{"start_key": "zz"}`,
+		`{"end_key": "0"}`,
+		`{"start_key": "03", "end_key": "03"}`,
+		`{"limit": -1}`,
+	} {
+		c.Assert(postJSON(url, []byte(input)), NotNil, Commentf("%s", input))
+	}
+
+	// The limit is reached.
+	res := s.scatter(c, map[string]interface{}{"start_key": "02", "end_key": "0d", "limit": 2})
+	c.Assert(res.Scattered+res.Hot+res.Failed+res.NoOperator, Equals, 2)
+	c.Assert(res.NextKey, Equals, "04")
+
+	// The region missing peers fails.
+	res = s.scatter(c, map[string]interface{}{"start_key": "0c"})
+	c.Assert(res.Failed, Equals, 1)
+	c.Assert(res.NextKey, Equals, "")
+
+	// Each store takes at most one more peer, so it stops once the store
+	// limit is exhausted.
+	rc.GetOperatorController().SetAllStoresLimit(0.01, schedule.StoreLimitManual)
+	res = s.scatter(c, map[string]interface{}{"start_key": "04", "end_key": "0c", "group": "restore"})
+	c.Assert(res.NextKey, Not(Equals), "", Commentf("%+v", res))
+	next, err := hex.DecodeString(res.NextKey)
+	c.Assert(err, IsNil)
+	c.Assert(next[0], Equals, byte(4+res.Scattered+res.Hot+res.Failed+res.NoOperator))
+}
+
 func (s *testRegionSuite) TestRegion(c *C) {
 	r := newTestRegionInfo(2, 1, []byte("a"), []byte("b"))
 	mustRegionHeartbeat(c, s.svr, r)
@@ -83,7 +172,11 @@ func (s *testRegionSuite) TestRegion(c *C) {
 	r1m := make(map[string]interface{})
 	err := readJSON(url, r1)
 	c.Assert(err, IsNil)
-	c.Assert(r1, DeepEquals, NewRegionInfo(r))
+	// The new region is not idle as its epoch is just changed.
+	expected := NewRegionInfo(r)
+	expected.Activity = "normal"
+	expected.SuggestedHeartbeatInterval = "1m0s"
+	c.Assert(r1, DeepEquals, expected)
 	err = readJSON(url, &r1m)
 	c.Assert(err, IsNil)
 	c.Assert(r1m["written_bytes"].(float64), Equals, float64(r.GetBytesWritten()))
@@ -95,7 +188,7 @@ func (s *testRegionSuite) TestRegion(c *C) {
 	r2 := &RegionInfo{}
 	err = readJSON(url, r2)
 	c.Assert(err, IsNil)
-	c.Assert(r2, DeepEquals, NewRegionInfo(r))
+	c.Assert(r2, DeepEquals, expected)
 }
 
 func (s *testRegionSuite) TestRegionCheck(c *C) {
@@ -107,7 +200,10 @@ func (s *testRegionSuite) TestRegionCheck(c *C) {
 	r1 := &RegionInfo{}
 	err := readJSON(url, r1)
 	c.Assert(err, IsNil)
-	c.Assert(r1, DeepEquals, NewRegionInfo(r))
+	expected := NewRegionInfo(r)
+	expected.Activity = "normal"
+	expected.SuggestedHeartbeatInterval = "1m0s"
+	c.Assert(r1, DeepEquals, expected)
 
 	url = fmt.Sprintf("%s/regions/check/%s", s.urlPrefix, "down-peer")
 	r2 := &RegionsInfo{}
@@ -162,6 +258,14 @@ func (s *testRegionSuite) TestRegionCheck(c *C) {
 	histKeys[0] = histKey
 	c.Assert(err, IsNil)
 	c.Assert(r7, DeepEquals, histKeys)
+
+	url = fmt.Sprintf("%s/regions/check/%s", s.urlPrefix, "size-age")
+	var sizeAges []*statistics.RegionSizeAgeBucket
+	err = readJSON(url, &sizeAges)
+	c.Assert(err, IsNil)
+	c.Assert(sizeAges, HasLen, 5)
+	c.Assert(sizeAges[0].Max.Duration, Equals, 10*time.Minute)
+	c.Assert(sizeAges[0].Count, Equals, 1)
 }
 
 func (s *testRegionSuite) TestRegions(c *C) {
@@ -191,40 +295,58 @@ func (s *testRegionSuite) TestRegions(c *C) {
 }
 
 func (s *testRegionSuite) TestStoreRegions(c *C) {
+	for _, id := range []uint64{2, 3} {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, nil)
+	}
 	r1 := newTestRegionInfo(2, 1, []byte("a"), []byte("b"))
-	r2 := newTestRegionInfo(3, 1, []byte("b"), []byte("c"))
-	r3 := newTestRegionInfo(4, 2, []byte("c"), []byte("d"))
+	r2 := newTestRegionInfo(3, 1, []byte("b"), []byte("c"), core.WithAddPeer(&metapb.Peer{Id: 31, StoreId: 2, IsLearner: true}))
+	r3 := newTestRegionInfo(4, 2, []byte("c"), []byte("d"), core.WithAddPeer(&metapb.Peer{Id: 41, StoreId: 1}))
 	mustRegionHeartbeat(c, s.svr, r1)
 	mustRegionHeartbeat(c, s.svr, r2)
 	mustRegionHeartbeat(c, s.svr, r3)
 
-	regionIDs := []uint64{2, 3}
-	url := fmt.Sprintf("%s/regions/store/%d", s.urlPrefix, 1)
-	r4 := &RegionsInfo{}
-	err := readJSON(url, r4)
-	c.Assert(err, IsNil)
-	c.Assert(r4.Count, Equals, len(regionIDs))
-	sort.Slice(r4.Regions, func(i, j int) bool { return r4.Regions[i].ID < r4.Regions[j].ID })
-	for i, r := range r4.Regions {
-		c.Assert(r.ID, Equals, regionIDs[i])
-	}
+	check := func(query string, storeID uint64, regionIDs []uint64) {
+		url := fmt.Sprintf("%s/regions/store/%d%s", s.urlPrefix, storeID, query)
+		regions := &RegionsInfo{}
+		c.Assert(readJSON(url, regions), IsNil)
+		c.Assert(regions.Count, Equals, len(regionIDs))
+		c.Assert(regions.Regions, HasLen, len(regionIDs))
+		sort.Slice(regions.Regions, func(i, j int) bool { return regions.Regions[i].ID < regions.Regions[j].ID })
+		for i, r := range regions.Regions {
+			c.Assert(r.ID, Equals, regionIDs[i])
+		}
 
-	regionIDs = []uint64{4}
-	url = fmt.Sprintf("%s/regions/store/%d", s.urlPrefix, 2)
-	r5 := &RegionsInfo{}
-	err = readJSON(url, r5)
-	c.Assert(err, IsNil)
-	c.Assert(r5.Count, Equals, len(regionIDs))
-	for i, r := range r5.Regions {
-		c.Assert(r.ID, Equals, regionIDs[i])
+		// Only the count is returned in the count-only mode.
+		if query == "" {
+			url += "?count"
+		} else {
+			url += "&count"
+		}
+		regions = &RegionsInfo{}
+		c.Assert(readJSON(url, regions), IsNil)
+		c.Assert(regions.Count, Equals, len(regionIDs))
+		c.Assert(regions.Regions, HasLen, 0)
 	}
-
-	regionIDs = []uint64{}
-	url = fmt.Sprintf("%s/regions/store/%d", s.urlPrefix, 3)
-	r6 := &RegionsInfo{}
-	err = readJSON(url, r6)
-	c.Assert(err, IsNil)
-	c.Assert(r6.Count, Equals, len(regionIDs))
+	check("", 1, []uint64{2, 3, 4})
+	check("?role=leader", 1, []uint64{2, 3})
+	check("?role=follower", 1, []uint64{4})
+	check("?role=learner", 1, nil)
+	check("", 2, []uint64{3, 4})
+	check("?role=leader", 2, []uint64{4})
+	check("?role=learner", 2, []uint64{3})
+
+	// The store without regions returns an empty list rather than null.
+	url := fmt.Sprintf("%s/regions/store/%d?role=leader", s.urlPrefix, 3)
+	_, body := requestStatusBody(c, dialClient, http.MethodGet, url)
+	c.Assert(string(body), Matches, `(?s).*"regions": \[\].*`)
+	check("", 3, nil)
+
+	url = fmt.Sprintf("%s/regions/store/%d?role=voter", s.urlPrefix, 1)
+	code, _ := requestStatusBody(c, dialClient, http.MethodGet, url)
+	c.Assert(code, Equals, http.StatusBadRequest)
+	url = fmt.Sprintf("%s/regions/store/%d", s.urlPrefix, 100)
+	code, _ = requestStatusBody(c, dialClient, http.MethodGet, url)
+	c.Assert(code, Equals, http.StatusNotFound)
 }
 
 func (s *testRegionSuite) TestTopFlow(c *C) {
@@ -360,6 +482,41 @@ func (s *testGetRegionSuite) TestScanRegionByKey(c *C) {
 	for i, v := range regionIds {
 		c.Assert(v, Equals, regions.Regions[i].ID)
 	}
+
+	url = fmt.Sprintf("%s/regions/key?key=%s&end_key=%s", s.urlPrefix, "b", "x")
+	regionIds = []uint64{3, 4}
+	regions = &RegionsInfo{}
+	err = readJSON(url, regions)
+	c.Assert(err, IsNil)
+	c.Assert(len(regionIds), Equals, regions.Count)
+	for i, v := range regionIds {
+		c.Assert(v, Equals, regions.Regions[i].ID)
+	}
+	url = fmt.Sprintf("%s/regions/key?key=%s&store_id=%d", s.urlPrefix, "a", 2)
+	regionIds = []uint64{4, 5}
+	regions = &RegionsInfo{}
+	err = readJSON(url, regions)
+	c.Assert(err, IsNil)
+	c.Assert(len(regionIds), Equals, regions.Count)
+	for i, v := range regionIds {
+		c.Assert(v, Equals, regions.Regions[i].ID)
+	}
+	url = fmt.Sprintf("%s/regions/key?key=%s&end_key=%s&store_id=%d&role=leader", s.urlPrefix, "b", "x", 1)
+	regionIds = []uint64{3}
+	regions = &RegionsInfo{}
+	err = readJSON(url, regions)
+	c.Assert(err, IsNil)
+	c.Assert(len(regionIds), Equals, regions.Count)
+	for i, v := range regionIds {
+		c.Assert(v, Equals, regions.Regions[i].ID)
+	}
+	url = fmt.Sprintf("%s/regions/key?key=%s&store_id=%d&role=learner", s.urlPrefix, "a", 1)
+	regions = &RegionsInfo{}
+	err = readJSON(url, regions)
+	c.Assert(err, IsNil)
+	c.Assert(regions.Count, Equals, 0)
+	url = fmt.Sprintf("%s/regions/key?key=%s&store_id=%d&role=voter", s.urlPrefix, "a", 1)
+	c.Assert(readJSON(url, regions), NotNil)
 }
 
 // Create n regions (0..n) of n stores (0..n).
@@ -428,3 +585,278 @@ func BenchmarkHexRegionKeyStr(b *testing.B) {
 		_ = core.HexRegionKeyStr(key)
 	}
 }
+
+var _ = Suite(&testLeaderChurnSuite{})
+
+type testLeaderChurnSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testLeaderChurnSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testLeaderChurnSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testLeaderChurnSuite) TestLeaderChurn(c *C) {
+	newRegion := func(id uint64, start, end string, leaderStore uint64) *core.RegionInfo {
+		peers := []*metapb.Peer{{Id: id*10 + 1, StoreId: 1}, {Id: id*10 + 2, StoreId: 2}}
+		region := &metapb.Region{
+			Id:          id,
+			StartKey:    []byte(start),
+			EndKey:      []byte(end),
+			Peers:       peers,
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+		}
+		return core.NewRegionInfo(region, peers[leaderStore-1])
+	}
+	// Region 20 changes the leader 4 times and region 21 changes twice.
+	for i, store := range []uint64{1, 2, 1, 2, 1} {
+		mustRegionHeartbeat(c, s.svr, newRegion(20, "p", "q", store))
+		if i < 3 {
+			mustRegionHeartbeat(c, s.svr, newRegion(21, "q", "r", 3-store))
+		}
+	}
+
+	var churns []*statistics.RegionLeaderChurn
+	c.Assert(readJSON(fmt.Sprintf("%s/regions/leader-churn", s.urlPrefix), &churns), IsNil)
+	c.Assert(churns, HasLen, 2)
+	c.Assert(churns[0], DeepEquals, &statistics.RegionLeaderChurn{RegionID: 20, Changes: 4, LeaderStores: []uint64{1, 2, 1, 2, 1}})
+	c.Assert(churns[1], DeepEquals, &statistics.RegionLeaderChurn{RegionID: 21, Changes: 2, LeaderStores: []uint64{2, 1, 2}})
+	churns = nil
+	c.Assert(readJSON(fmt.Sprintf("%s/regions/leader-churn?top=1", s.urlPrefix), &churns), IsNil)
+	c.Assert(churns, HasLen, 1)
+	c.Assert(churns[0].RegionID, Equals, uint64(20))
+
+	c.Assert(readJSON(fmt.Sprintf("%s/regions/leader-churn?top=0", s.urlPrefix), &churns), NotNil)
+	c.Assert(readJSON(fmt.Sprintf("%s/regions/leader-churn?top=x", s.urlPrefix), &churns), NotNil)
+
+	// The counters are cleaned up after the regions are merged.
+	merged := newRegion(22, "p", "r", 1)
+	merged.GetMeta().RegionEpoch = &metapb.RegionEpoch{ConfVer: 1, Version: 2}
+	mustRegionHeartbeat(c, s.svr, merged)
+	churns = nil
+	c.Assert(readJSON(fmt.Sprintf("%s/regions/leader-churn", s.urlPrefix), &churns), IsNil)
+	c.Assert(churns, HasLen, 0)
+}
+
+var _ = Suite(&testClosestPeerSuite{})
+
+type testClosestPeerSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testClosestPeerSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testClosestPeerSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testClosestPeerSuite) TestClosestPeers(c *C) {
+	for i, zone := range []string{"z1", "z1", "z2"} {
+		labels := []*metapb.StoreLabel{{Key: "zone", Value: zone}, {Key: "host", Value: fmt.Sprintf("h%d", i)}}
+		mustPutStore(c, s.svr, uint64(101+i), metapb.StoreState_Up, labels)
+	}
+	peers := []*metapb.Peer{{Id: 1001, StoreId: 101}, {Id: 1002, StoreId: 102}, {Id: 1003, StoreId: 103}}
+	r := core.NewRegionInfo(&metapb.Region{
+		Id:          1000,
+		StartKey:    []byte("closest-a"),
+		EndKey:      []byte("closest-b"),
+		Peers:       peers,
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}, peers[0], core.WithPendingPeers(peers[1:2]))
+	mustRegionHeartbeat(c, s.svr, r)
+
+	url := fmt.Sprintf("%s/region/id/%d/closest-peer?labels=zone=z2,host=h2", s.urlPrefix, r.GetID())
+	closest := &ClosestPeers{}
+	c.Assert(readJSON(url, closest), IsNil)
+	c.Assert(closest.RegionID, Equals, r.GetID())
+	// The pending peer is excluded.
+	c.Assert(closest.Peers, HasLen, 2)
+	c.Assert(closest.Peers[0].Peer.GetStoreId(), Equals, uint64(103))
+	c.Assert(closest.Peers[0].Score, Equals, 2)
+	c.Assert(closest.Peers[0].IsLeader, IsFalse)
+	c.Assert(closest.Peers[1].Peer.GetStoreId(), Equals, uint64(101))
+	c.Assert(closest.Peers[1].Score, Equals, 0)
+	c.Assert(closest.Peers[1].IsLeader, IsTrue)
+
+	status, _ := requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/region/id/%d/closest-peer?labels=zone", s.urlPrefix, r.GetID()))
+	c.Assert(status, Equals, http.StatusBadRequest)
+	status, _ = requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/region/id/%d/closest-peer?labels=zone=z1", s.urlPrefix, 9999))
+	c.Assert(status, Equals, http.StatusNotFound)
+
+	data, err := json.Marshal(map[string]interface{}{"region_ids": []uint64{r.GetID(), 9999}, "labels": "zone=z1"})
+	c.Assert(err, IsNil)
+	resp, err := dialClient.Post(fmt.Sprintf("%s/regions/closest-peer", s.urlPrefix), "application/json", bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	var batch []*ClosestPeers
+	c.Assert(json.NewDecoder(resp.Body).Decode(&batch), IsNil)
+	c.Assert(batch, HasLen, 1)
+	c.Assert(batch[0].Peers, HasLen, 2)
+	c.Assert(batch[0].Peers[0].Peer.GetStoreId(), Equals, uint64(101))
+	c.Assert(batch[0].Peers[0].Score, Equals, 1)
+}
+
+var _ = Suite(&testRegionAncestrySuite{})
+
+type testRegionAncestrySuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testRegionAncestrySuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testRegionAncestrySuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testRegionAncestrySuite) TestRegionAncestry(c *C) {
+	// Region 801 is split into regions 802 and 801, and region 802 is merged
+	// into region 803 then.
+	_, err := s.svr.ReportSplit(context.Background(), &pdpb.ReportSplitRequest{
+		Header: &pdpb.RequestHeader{ClusterId: s.svr.ClusterID()},
+		Left:   &metapb.Region{Id: 802, StartKey: []byte("x1"), EndKey: []byte("x2")},
+		Right:  &metapb.Region{Id: 801, StartKey: []byte("x2"), EndKey: []byte("x3")},
+	})
+	c.Assert(err, IsNil)
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(802, 1, []byte("x1"), []byte("x2")))
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(803, 1, []byte("x0"), []byte("x1")))
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(803, 1, []byte("x0"), []byte("x2"), core.SetRegionVersion(2)))
+
+	ancestry := &cluster.RegionAncestry{}
+	c.Assert(readJSON(fmt.Sprintf("%s/region/id/%d/ancestry", s.urlPrefix, 802), ancestry), IsNil)
+	c.Assert(ancestry.RegionID, Equals, uint64(802))
+	c.Assert(ancestry.Ancestors, HasLen, 1)
+	c.Assert(ancestry.Ancestors[0].Type, Equals, cluster.RegionLineageSplit)
+	c.Assert(ancestry.Ancestors[0].Parents, DeepEquals, []uint64{801})
+	c.Assert(ancestry.Descendants, HasLen, 1)
+	c.Assert(ancestry.Descendants[0].Type, Equals, cluster.RegionLineageMerge)
+	c.Assert(ancestry.Descendants[0].Parents, DeepEquals, []uint64{803, 802})
+	c.Assert(ancestry.Descendants[0].Children, DeepEquals, []uint64{803})
+
+	// Region 803 is traced back to region 801.
+	c.Assert(readJSON(fmt.Sprintf("%s/region/id/%d/ancestry?depth=1", s.urlPrefix, 803), ancestry), IsNil)
+	c.Assert(ancestry.Ancestors, HasLen, 1)
+	c.Assert(readJSON(fmt.Sprintf("%s/region/id/%d/ancestry?depth=2", s.urlPrefix, 803), ancestry), IsNil)
+	c.Assert(ancestry.Ancestors, HasLen, 2)
+	c.Assert(ancestry.Ancestors[0].Parents, DeepEquals, []uint64{801})
+
+	status, _ := requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/region/id/%d/ancestry?depth=0", s.urlPrefix, 803))
+	c.Assert(status, Equals, http.StatusBadRequest)
+	status, _ = requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/region/id/%d/ancestry?depth=%d", s.urlPrefix, 803, cluster.MaxRegionLineageDepth+1))
+	c.Assert(status, Equals, http.StatusBadRequest)
+}
+
+var _ = Suite(&testRegionsPageSuite{})
+
+type testRegionsPageSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testRegionsPageSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testRegionsPageSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testRegionsPageSuite) TestPages(c *C) {
+	keys := []string{"", "b", "d", "f", "h", ""}
+	for i := 0; i < len(keys)-1; i++ {
+		mustRegionHeartbeat(c, s.svr, newTestRegionInfo(uint64(100+i), 1, []byte(keys[i]), []byte(keys[i+1])))
+	}
+
+	// Page through all the regions from the empty key.
+	var (
+		ids      []uint64
+		startKey string
+		pages    int
+	)
+	for {
+		page := &RegionsPage{}
+		err := readJSON(fmt.Sprintf("%s/regions?start_key=%s&limit=2", s.urlPrefix, startKey), page)
+		c.Assert(err, IsNil)
+		c.Assert(page.Count, Equals, len(page.Regions))
+		c.Assert(page.Count <= 2, IsTrue)
+		for _, r := range page.Regions {
+			ids = append(ids, r.ID)
+		}
+		pages++
+		if !page.HasMore {
+			c.Assert(page.NextKey, Equals, "")
+			break
+		}
+		startKey = page.NextKey
+	}
+	c.Assert(pages, Equals, 3)
+	c.Assert(ids, DeepEquals, []uint64{100, 101, 102, 103, 104})
+
+	// The page starts from the region containing the start key, and ends at
+	// the last region.
+	page := &RegionsPage{}
+	err := readJSON(fmt.Sprintf("%s/regions?start_key=%s&limit=3", s.urlPrefix, hex.EncodeToString([]byte("e"))), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Count, Equals, 3)
+	c.Assert(page.Regions[0].ID, Equals, uint64(102))
+	c.Assert(page.HasMore, IsFalse)
+	c.Assert(page.NextKey, Equals, "")
+
+	page = &RegionsPage{}
+	err = readJSON(fmt.Sprintf("%s/regions?limit=1", s.urlPrefix), page)
+	c.Assert(err, IsNil)
+	c.Assert(page.Regions[0].ID, Equals, uint64(100))
+	c.Assert(page.HasMore, IsTrue)
+	c.Assert(page.NextKey, Equals, core.HexRegionKeyStr([]byte("b")))
+
+	// Without the parameters, all the regions are listed.
+	regions := &RegionsInfo{}
+	c.Assert(readJSON(fmt.Sprintf("%s/regions", s.urlPrefix), regions), IsNil)
+	c.Assert(regions.Count, Equals, 5)
+
+	for _, query := range []string{"start_key=xyz", "limit=0", "limit=-1", "limit=a"} {
+		resp, err := dialClient.Get(fmt.Sprintf("%s/regions?%s", s.urlPrefix, query))
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	}
+}
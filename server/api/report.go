@@ -0,0 +1,56 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/unrolled/render"
+)
+
+const defaultReportDays = 7
+
+type reportHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newReportHandler(svr *server.Server, rd *render.Render) *reportHandler {
+	return &reportHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *reportHandler) GetSchedulingReport(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	days := defaultReportDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		var err error
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days <= 0 || days > cluster.SchedulingReportRetentionDays {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid days")
+			return
+		}
+	}
+	summary, err := rc.GetSchedulingReporter().GetSummary(days)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, summary)
+}
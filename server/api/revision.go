@@ -0,0 +1,177 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/unrolled/render"
+)
+
+// revisionCheckHeader is set to "skipped" on the responses of the updates
+// without If-Match, which may overwrite the concurrent updates of others.
+const revisionCheckHeader = "PD-Revision-Check"
+
+// revisionLoader returns the name of the persisted resource the request reads
+// or updates, and the function loading its revision. An empty resource means
+// the request is not guarded.
+type revisionLoader func(r *http.Request) (resource string, load func() (string, error))
+
+// revisionGuard guards the updates of the persisted configs by their
+// revisions. The reads respond the revision in the ETag header, and the
+// updates with If-Match are rejected with 412 if the revision has been changed
+// by others since it is read. The updates are serialized, so the revision
+// cannot be changed between the check and the update.
+type revisionGuard struct {
+	sync.Mutex
+	rd *render.Render
+}
+
+func newRevisionGuard(rd *render.Render) *revisionGuard {
+	return &revisionGuard{rd: rd}
+}
+
+// guard wraps the handler of the route to check and respond the revision.
+func (g *revisionGuard) guard(route *mux.Route, load revisionLoader) *mux.Route {
+	next := route.GetHandler()
+	return route.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resource, loadRevision := load(r)
+		if resource == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rw := &revisionResponseWriter{ResponseWriter: w, load: loadRevision}
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			// The revision is loaded before the resource is read, so the ETag
+			// is never newer than the response. If the resource is updated in
+			// between, the updates with the ETag are rejected as expected.
+			revision, err := loadRevision()
+			if err != nil {
+				g.rd.JSON(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			rw.load = func() (string, error) { return revision, nil }
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		g.Lock()
+		defer g.Unlock()
+		revision, err := loadRevision()
+		if err != nil {
+			g.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		ifMatch := r.Header.Get("If-Match")
+		if ifMatch == "" {
+			rw.skipped = true
+		} else if !matchETag(ifMatch, revision) {
+			w.Header().Set("ETag", formatETag(revision))
+			g.rd.JSON(w, http.StatusPreconditionFailed,
+				fmt.Sprintf("the %s has been modified by others, the current revision is %s", resource, revision))
+			return
+		}
+		next.ServeHTTP(rw, r)
+	}))
+}
+
+// revisionResponseWriter sets the revision headers on the successful response
+// right before it is written, which is after the update is persisted. For the
+// reads, the revision loaded before reading is set.
+type revisionResponseWriter struct {
+	http.ResponseWriter
+	load        func() (string, error)
+	skipped     bool
+	wroteHeader bool
+}
+
+func (w *revisionResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status < http.StatusMultipleChoices {
+			if revision, err := w.load(); err == nil {
+				w.Header().Set("ETag", formatETag(revision))
+			}
+			if w.skipped {
+				w.Header().Set(revisionCheckHeader, "skipped")
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *revisionResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func formatETag(revision string) string {
+	return `"` + revision + `"`
+}
+
+// matchETag returns true if any of the entity tags in If-Match is the one of
+// the revision, or is "*".
+func matchETag(ifMatch, revision string) bool {
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == formatETag(revision) {
+			return true
+		}
+	}
+	return false
+}
+
+// configRevision returns the revision of the persisted cluster config, which
+// includes the schedule, replication, label property and PD server configs
+// and the cluster version.
+func configRevision(svr *server.Server) revisionLoader {
+	return func(r *http.Request) (string, func() (string, error)) {
+		return "config", svr.GetStorage().LoadConfigRevision
+	}
+}
+
+// rulesRevision returns the revision of all the persisted placement rules.
+func rulesRevision(svr *server.Server) revisionLoader {
+	return func(r *http.Request) (string, func() (string, error)) {
+		return "placement rules", svr.GetStorage().LoadRulesRevision
+	}
+}
+
+// schedulerConfigRevision returns the revision of the persisted config of the
+// scheduler named in the path following the scheduler config path.
+func schedulerConfigRevision(svr *server.Server) revisionLoader {
+	return func(r *http.Request) (string, func() (string, error)) {
+		i := strings.Index(r.URL.Path, server.SchedulerConfigHandlerPath+"/")
+		if i < 0 {
+			return "", nil
+		}
+		name := r.URL.Path[i+len(server.SchedulerConfigHandlerPath)+1:]
+		if j := strings.Index(name, "/"); j >= 0 {
+			name = name[:j]
+		}
+		if name == "" {
+			return "", nil
+		}
+		return "config of scheduler " + name, func() (string, error) {
+			return svr.GetStorage().LoadScheduleConfigRevision(name)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/mux"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/config"
+	"github.com/unrolled/render"
+)
+
+var _ = Suite(&testRevisionSuite{})
+
+type testRevisionSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testRevisionSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c, func(cfg *config.Config) { cfg.EnableDynamicConfig = false })
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testRevisionSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testRevisionSuite) do(c *C, method, url, body, ifMatch string) (int, http.Header) {
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	resp, err := dialClient.Do(req)
+	c.Assert(err, IsNil)
+	_, err = ioutil.ReadAll(resp.Body)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp.StatusCode, resp.Header
+}
+
+func (s *testRevisionSuite) TestConfig(c *C) {
+	url := fmt.Sprintf("%s/config/schedule", s.urlPrefix)
+	code, header := s.do(c, http.MethodGet, url, "", "")
+	c.Assert(code, Equals, http.StatusOK)
+	etag := header.Get("ETag")
+	c.Assert(etag, Not(Equals), "")
+	// All the sections share the revision of the persisted config.
+	_, header = s.do(c, http.MethodGet, s.urlPrefix+"/config/replicate", "", "")
+	c.Assert(header.Get("ETag"), Equals, etag)
+
+	// Two operators read the same revision, and the second update is rejected.
+	code, header = s.do(c, http.MethodPost, url, `{"leader-schedule-limit": 10}`, etag)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(header.Get(revisionCheckHeader), Equals, "")
+	newETag := header.Get("ETag")
+	c.Assert(newETag, Not(Equals), etag)
+	code, header = s.do(c, http.MethodPost, url, `{"leader-schedule-limit": 20}`, etag)
+	c.Assert(code, Equals, http.StatusPreconditionFailed)
+	c.Assert(header.Get("ETag"), Equals, newETag)
+	c.Assert(s.svr.GetScheduleConfig().LeaderScheduleLimit, Equals, uint64(10))
+	code, header = s.do(c, http.MethodPost, s.urlPrefix+"/config/replicate", `{"max-replicas": 5}`, etag)
+	c.Assert(code, Equals, http.StatusPreconditionFailed)
+	c.Assert(s.svr.GetReplicationConfig().MaxReplicas, Equals, uint64(3))
+
+	// The second operator retries with the current revision.
+	code, header = s.do(c, http.MethodPost, url, `{"leader-schedule-limit": 20}`, `W/"x", `+newETag)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(s.svr.GetScheduleConfig().LeaderScheduleLimit, Equals, uint64(20))
+	etag = header.Get("ETag")
+	_, header = s.do(c, http.MethodGet, url, "", "")
+	c.Assert(header.Get("ETag"), Equals, etag)
+
+	// The updates without If-Match still work, but are marked.
+	code, header = s.do(c, http.MethodPost, url, `{"leader-schedule-limit": 30}`, "")
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(header.Get(revisionCheckHeader), Equals, "skipped")
+	c.Assert(header.Get("ETag"), Not(Equals), etag)
+	code, _ = s.do(c, http.MethodPost, url, `{"leader-schedule-limit": 40}`, "*")
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(s.svr.GetScheduleConfig().LeaderScheduleLimit, Equals, uint64(40))
+}
+
+func (s *testRevisionSuite) TestSchedulerConfig(c *C) {
+	name := "balance-leader-scheduler"
+	c.Assert(postJSON(s.urlPrefix+"/schedulers", []byte(`{"name": "`+name+`"}`)), IsNil)
+	listURL := fmt.Sprintf("%s%s%s/%s/list", s.svr.GetAddr(), apiPrefix, server.SchedulerConfigHandlerPath, name)
+	updateURL := fmt.Sprintf("%s%s%s/%s/config", s.svr.GetAddr(), apiPrefix, server.SchedulerConfigHandlerPath, name)
+	code, header := s.do(c, http.MethodGet, listURL, "", "")
+	c.Assert(code, Equals, http.StatusOK)
+	etag := header.Get("ETag")
+	c.Assert(etag, Not(Equals), "")
+	// The scheduler config has its own revision.
+	_, header = s.do(c, http.MethodGet, s.urlPrefix+"/config", "", "")
+	c.Assert(header.Get("ETag"), Not(Equals), etag)
+
+	code, header = s.do(c, http.MethodPost, updateURL, `{"tolerant-count": 2}`, etag)
+	c.Assert(code, Equals, http.StatusOK)
+	newETag := header.Get("ETag")
+	c.Assert(newETag, Not(Equals), etag)
+	code, _ = s.do(c, http.MethodPost, updateURL, `{"tolerant-count": 3}`, etag)
+	c.Assert(code, Equals, http.StatusPreconditionFailed)
+	data, err := s.svr.GetStorage().LoadScheduleConfig(name)
+	c.Assert(err, IsNil)
+	c.Assert(data, Matches, `.*"tolerant-count":2.*`)
+
+	code, header = s.do(c, http.MethodPost, updateURL, `{"tolerant-count": 3}`, newETag)
+	c.Assert(code, Equals, http.StatusOK)
+	_, listHeader := s.do(c, http.MethodGet, listURL, "", "")
+	c.Assert(listHeader.Get("ETag"), Equals, header.Get("ETag"))
+}
+
+func (s *testRevisionSuite) TestRules(c *C) {
+	code, _ := s.do(c, http.MethodPost, s.urlPrefix+"/config/placement-rules/enable?confirm=true", "", "")
+	c.Assert(code, Equals, http.StatusOK)
+	defer func() {
+		code, _ := s.do(c, http.MethodPost, s.urlPrefix+"/config/placement-rules/disable?confirm=true", "", "")
+		c.Assert(code, Equals, http.StatusOK)
+	}()
+
+	ruleURL := s.urlPrefix + "/config/rule"
+	code, header := s.do(c, http.MethodGet, s.urlPrefix+"/config/rules", "", "")
+	c.Assert(code, Equals, http.StatusOK)
+	etag := header.Get("ETag")
+	c.Assert(etag, Not(Equals), "")
+	// All the rules share the revision, which is not the one of the config.
+	_, header = s.do(c, http.MethodGet, ruleURL+"/pd/default", "", "")
+	c.Assert(header.Get("ETag"), Equals, etag)
+	_, header = s.do(c, http.MethodGet, s.urlPrefix+"/config", "", "")
+	c.Assert(header.Get("ETag"), Not(Equals), etag)
+
+	// Two operators read the same revision, and the second update is rejected.
+	code, header = s.do(c, http.MethodPost, ruleURL, `{"group_id": "test", "id": "1", "role": "voter", "count": 1}`, etag)
+	c.Assert(code, Equals, http.StatusOK)
+	newETag := header.Get("ETag")
+	c.Assert(newETag, Not(Equals), etag)
+	code, header = s.do(c, http.MethodPost, ruleURL, `{"group_id": "test", "id": "2", "role": "voter", "count": 1}`, etag)
+	c.Assert(code, Equals, http.StatusPreconditionFailed)
+	c.Assert(header.Get("ETag"), Equals, newETag)
+	c.Assert(s.svr.GetRaftCluster().GetRuleManager().GetRule("test", "2"), IsNil)
+	code, _ = s.do(c, http.MethodDelete, ruleURL+"/test/1", "", etag)
+	c.Assert(code, Equals, http.StatusPreconditionFailed)
+	c.Assert(s.svr.GetRaftCluster().GetRuleManager().GetRule("test", "1"), NotNil)
+
+	// The second operator retries with the current revision.
+	code, header = s.do(c, http.MethodDelete, ruleURL+"/test/1", "", newETag)
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(s.svr.GetRaftCluster().GetRuleManager().GetRule("test", "1"), IsNil)
+	_, listHeader := s.do(c, http.MethodGet, s.urlPrefix+"/config/rules/group/pd", "", "")
+	c.Assert(listHeader.Get("ETag"), Equals, header.Get("ETag"))
+}
+
+// TestReadRevision checks the ETag of a read is the revision before it reads,
+// even if the resource is updated during the read.
+func (s *testRevisionSuite) TestReadRevision(c *C) {
+	revision := "1"
+	router := mux.NewRouter()
+	route := router.HandleFunc("/resource", func(w http.ResponseWriter, r *http.Request) {
+		body := "value of revision " + revision
+		revision = "2"
+		w.Write([]byte(body))
+	}).Methods("GET")
+	newRevisionGuard(render.New()).guard(route, func(r *http.Request) (string, func() (string, error)) {
+		return "resource", func() (string, error) { return revision, nil }
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/resource", nil))
+	c.Assert(w.Code, Equals, http.StatusOK)
+	c.Assert(w.Body.String(), Equals, "value of revision 1")
+	c.Assert(w.Header().Get("ETag"), Equals, `"1"`)
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/pkg/errors"
+	"github.com/unrolled/render"
+)
+
+type rollingRestartHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newRollingRestartHandler(svr *server.Server, rd *render.Render) *rollingRestartHandler {
+	return &rollingRestartHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *rollingRestartHandler) Get(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	restart := rc.GetRollingRestart()
+	if restart == nil {
+		h.rd.JSON(w, http.StatusNotFound, cluster.ErrRollingRestartNotFound.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, restart)
+}
+
+func (h *rollingRestartHandler) Start(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	var cfg cluster.RollingRestartConfig
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &cfg); err != nil {
+		return
+	}
+	if err := rc.ValidateRollingRestart(&cfg); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	restart, err := rc.StartRollingRestart(&cfg)
+	if err != nil {
+		if errors.Cause(err) == cluster.ErrRollingRestartInProgress {
+			h.rd.JSON(w, http.StatusConflict, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, restart)
+}
+
+func (h *rollingRestartHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	h.respond(w, rc, rc.PauseRollingRestart())
+}
+
+func (h *rollingRestartHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	h.respond(w, rc, rc.ResumeRollingRestart())
+}
+
+func (h *rollingRestartHandler) Abort(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	h.respond(w, rc, rc.AbortRollingRestart())
+}
+
+// respond responds the rolling restart updated, or the error of the update.
+func (h *rollingRestartHandler) respond(w http.ResponseWriter, rc *cluster.RaftCluster, err error) {
+	switch errors.Cause(err) {
+	case nil:
+		h.rd.JSON(w, http.StatusOK, rc.GetRollingRestart())
+	case cluster.ErrRollingRestartNotFound:
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+	case cluster.ErrRollingRestartStateMismatch:
+		h.rd.JSON(w, http.StatusConflict, err.Error())
+	default:
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -18,7 +18,6 @@ import (
 	"errors"
 	"io"
 	"net/http"
-	"net/http/pprof"
 	"net/url"
 
 	"github.com/golang/protobuf/proto"
@@ -50,7 +49,19 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) (*mux.
 	rd := createIndentRender()
 
 	rootRouter := mux.NewRouter().PathPrefix(prefix).Subrouter()
+	rootRouter.Use(newCompressionMiddleware(minCompressSize).Middleware)
 	handler := svr.GetHandler()
+	registry := newAPIRegistry()
+	revisions := newRevisionGuard(rd)
+	guardConfig := func(route *mux.Route) *mux.Route {
+		return registry.provide(revisions.guard(route, configRevision(svr)), featureConfigRevisions)
+	}
+	guardSchedulerConfig := func(route *mux.Route) *mux.Route {
+		return registry.provide(revisions.guard(route, schedulerConfigRevision(svr)), featureConfigRevisions)
+	}
+	guardRules := func(route *mux.Route) *mux.Route {
+		return registry.provide(revisions.guard(route, rulesRevision(svr)), featureConfigRevisions)
+	}
 
 	apiRouter := rootRouter.PathPrefix("/api/v1").Subrouter()
 
@@ -58,59 +69,102 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) (*mux.
 	clusterRouter.Use(newClusterMiddleware(svr).Middleware)
 
 	operatorHandler := newOperatorHandler(handler, rd)
-	apiRouter.HandleFunc("/operators", operatorHandler.List).Methods("GET")
-	apiRouter.HandleFunc("/operators", operatorHandler.Post).Methods("POST")
+	registry.provide(apiRouter.HandleFunc("/operators", operatorHandler.List).Methods("GET"), featureOperatorReasons)
+	postOperatorRoute := apiRouter.HandleFunc("/operators", operatorHandler.Post).Methods("POST")
+	registry.provide(postOperatorRoute, featureTypedOperators, featureChainedOperators, featureOperatorTemplates)
+	registry.deprecate(postOperatorRoute, deprecationFlatOperator)
+	registry.provide(apiRouter.HandleFunc("/operators/precheck", operatorHandler.Precheck).Methods("POST"), featureOperatorPrecheck)
+	registry.provide(apiRouter.HandleFunc("/operators/batch", operatorHandler.Batch).Methods("POST"), featureOperatorBatch)
+	apiRouter.HandleFunc("/operators/latency", operatorHandler.GetLatencies).Methods("GET")
+	apiRouter.HandleFunc("/operators/pingpong", operatorHandler.GetPingPong).Methods("GET")
+	apiRouter.HandleFunc("/operators/history", operatorHandler.GetHistory).Methods("GET")
 	apiRouter.HandleFunc("/operators/{region_id}", operatorHandler.Get).Methods("GET")
 	apiRouter.HandleFunc("/operators/{region_id}", operatorHandler.Delete).Methods("DELETE")
 
+	operatorTemplateHandler := newOperatorTemplateHandler(handler, rd)
+	registry.provide(apiRouter.HandleFunc("/operator-templates", operatorTemplateHandler.List).Methods("GET"), featureOperatorTemplates)
+	registry.provide(apiRouter.HandleFunc("/operator-templates", operatorTemplateHandler.Post).Methods("POST"), featureOperatorTemplates)
+	registry.provide(apiRouter.HandleFunc("/operator-templates/{name}", operatorTemplateHandler.Delete).Methods("DELETE"), featureOperatorTemplates)
+
 	schedulerHandler := newSchedulerHandler(handler, rd)
 	apiRouter.HandleFunc("/schedulers", schedulerHandler.List).Methods("GET")
 	apiRouter.HandleFunc("/schedulers", schedulerHandler.Post).Methods("POST")
+	apiRouter.HandleFunc("/schedulers/preview-range", schedulerHandler.PreviewRange).Methods("POST")
+	apiRouter.HandleFunc("/schedulers/{name}", schedulerHandler.Get).Methods("GET")
 	apiRouter.HandleFunc("/schedulers/{name}", schedulerHandler.Delete).Methods("DELETE")
 	apiRouter.HandleFunc("/schedulers/{name}", schedulerHandler.PauseOrResume).Methods("POST")
+	apiRouter.HandleFunc("/schedulers/{name}/observed", schedulerHandler.GetObserved).Methods("GET")
 	schedulerConfigHandler := newSchedulerConfigHandler(svr, rd)
-	rootRouter.PathPrefix(server.SchedulerConfigHandlerPath).Handler(schedulerConfigHandler)
+	apiRouter.HandleFunc("/scheduler-config", schedulerConfigHandler.ListPersisted).Methods("GET")
+	guardSchedulerConfig(apiRouter.HandleFunc("/scheduler-config/{name}", schedulerConfigHandler.DeletePersisted).Methods("DELETE"))
+	guardSchedulerConfig(rootRouter.PathPrefix(server.SchedulerConfigHandlerPath).Handler(schedulerConfigHandler))
 
 	clusterHandler := newClusterHandler(svr, rd)
 	apiRouter.Handle("/cluster", clusterHandler).Methods("GET")
 	apiRouter.HandleFunc("/cluster/status", clusterHandler.GetClusterStatus).Methods("GET")
+	clusterRouter.HandleFunc("/cluster/replication-eta", clusterHandler.GetReplicationETA).Methods("GET")
 
 	confHandler := newConfHandler(svr, rd)
-	apiRouter.HandleFunc("/config", confHandler.Get).Methods("GET")
-	apiRouter.HandleFunc("/config", confHandler.Post).Methods("POST")
+	guardConfig(apiRouter.HandleFunc("/config", confHandler.Get).Methods("GET"))
+	guardConfig(apiRouter.HandleFunc("/config", confHandler.Post).Methods("POST"))
+	guardConfig(registry.provide(apiRouter.HandleFunc("/config/batch", confHandler.SetBatch).Methods("POST"), featureConfigBatch))
 	apiRouter.HandleFunc("/config/default", confHandler.GetDefault).Methods("GET")
-	apiRouter.HandleFunc("/config/schedule", confHandler.GetSchedule).Methods("GET")
-	apiRouter.HandleFunc("/config/schedule", confHandler.SetSchedule).Methods("POST")
-	apiRouter.HandleFunc("/config/replicate", confHandler.GetReplication).Methods("GET")
-	apiRouter.HandleFunc("/config/replicate", confHandler.SetReplication).Methods("POST")
-	apiRouter.HandleFunc("/config/label-property", confHandler.GetLabelProperty).Methods("GET")
-	apiRouter.HandleFunc("/config/label-property", confHandler.SetLabelProperty).Methods("POST")
-	apiRouter.HandleFunc("/config/cluster-version", confHandler.GetClusterVersion).Methods("GET")
-	apiRouter.HandleFunc("/config/cluster-version", confHandler.SetClusterVersion).Methods("POST")
+	apiRouter.HandleFunc("/config/effective", confHandler.GetEffective).Methods("GET")
+	guardConfig(apiRouter.HandleFunc("/config/schedule", confHandler.GetSchedule).Methods("GET"))
+	guardConfig(apiRouter.HandleFunc("/config/schedule", confHandler.SetSchedule).Methods("POST"))
+	guardConfig(apiRouter.HandleFunc("/config/replicate", confHandler.GetReplication).Methods("GET"))
+	guardConfig(apiRouter.HandleFunc("/config/replicate", confHandler.SetReplication).Methods("POST"))
+	guardConfig(apiRouter.HandleFunc("/config/placement-rules/enable", confHandler.EnablePlacementRules).Methods("POST"))
+	guardConfig(apiRouter.HandleFunc("/config/placement-rules/disable", confHandler.DisablePlacementRules).Methods("POST"))
+	guardConfig(apiRouter.HandleFunc("/config/label-property", confHandler.GetLabelProperty).Methods("GET"))
+	guardConfig(apiRouter.HandleFunc("/config/label-property", confHandler.SetLabelProperty).Methods("POST"))
+	clusterRouter.HandleFunc("/config/label-property/impact", confHandler.GetLabelPropertyImpact).Methods("GET")
+	guardConfig(apiRouter.HandleFunc("/config/hot-threshold", confHandler.GetHotThreshold).Methods("GET"))
+	guardConfig(apiRouter.HandleFunc("/config/hot-threshold", confHandler.SetHotThreshold).Methods("POST"))
+	guardConfig(apiRouter.HandleFunc("/config/cluster-version", confHandler.GetClusterVersion).Methods("GET"))
+	guardConfig(apiRouter.HandleFunc("/config/cluster-version", confHandler.SetClusterVersion).Methods("POST"))
 
 	rulesHandler := newRulesHandler(svr, rd)
-	clusterRouter.HandleFunc("/config/rules", rulesHandler.GetAll).Methods("GET")
-	clusterRouter.HandleFunc("/config/rules/group/{group}", rulesHandler.GetAllByGroup).Methods("GET")
+	guardRules(clusterRouter.HandleFunc("/config/rules", rulesHandler.GetAll).Methods("GET"))
+	guardRules(clusterRouter.HandleFunc("/config/rules/group/{group}", rulesHandler.GetAllByGroup).Methods("GET"))
 	clusterRouter.HandleFunc("/config/rules/region/{region}", rulesHandler.GetAllByRegion).Methods("GET")
 	clusterRouter.HandleFunc("/config/rules/key/{key}", rulesHandler.GetAllByKey).Methods("GET")
-	clusterRouter.HandleFunc("/config/rule/{group}/{id}", rulesHandler.Get).Methods("GET")
-	clusterRouter.HandleFunc("/config/rule", rulesHandler.Set).Methods("POST")
-	clusterRouter.HandleFunc("/config/rule/{group}/{id}", rulesHandler.Delete).Methods("DELETE")
+	guardRules(clusterRouter.HandleFunc("/config/rule/{group}/{id}", rulesHandler.Get).Methods("GET"))
+	guardRules(clusterRouter.HandleFunc("/config/rule", rulesHandler.Set).Methods("POST"))
+	guardRules(clusterRouter.HandleFunc("/config/rule/{group}/{id}", rulesHandler.Delete).Methods("DELETE"))
 
 	storeHandler := newStoreHandler(handler, rd)
+	clusterRouter.HandleFunc("/store/address/{address}", storeHandler.GetByAddress).Methods("GET")
 	clusterRouter.HandleFunc("/store/{id}", storeHandler.Get).Methods("GET")
 	clusterRouter.HandleFunc("/store/{id}", storeHandler.Delete).Methods("DELETE")
 	clusterRouter.HandleFunc("/store/{id}/state", storeHandler.SetState).Methods("POST")
 	clusterRouter.HandleFunc("/store/{id}/label", storeHandler.SetLabels).Methods("POST")
 	clusterRouter.HandleFunc("/store/{id}/weight", storeHandler.SetWeight).Methods("POST")
+	clusterRouter.HandleFunc("/store/{id}/meta", storeHandler.SetAnnotation).Methods("POST")
+	clusterRouter.HandleFunc("/store/{id}/space-floor", storeHandler.SetSpaceFloor).Methods("POST")
 	clusterRouter.HandleFunc("/store/{id}/limit", storeHandler.SetLimit).Methods("POST")
+	clusterRouter.HandleFunc("/store/{id}/limit", storeHandler.RemoveLimit).Methods("DELETE")
+	clusterRouter.HandleFunc("/store/{id}/residual-peers", storeHandler.GetResidualPeers).Methods("GET")
+	clusterRouter.HandleFunc("/store/{id}/offline-impact", storeHandler.GetOfflineImpact).Methods("GET")
+	clusterRouter.HandleFunc("/store/{id}/operators", storeHandler.GetOperators).Methods("GET")
+	clusterRouter.HandleFunc("/store/{id}/hot-ranges", storeHandler.GetHotRanges).Methods("GET")
 	storesHandler := newStoresHandler(handler, rd)
 	clusterRouter.Handle("/stores", storesHandler).Methods("GET")
 	clusterRouter.HandleFunc("/stores/remove-tombstone", storesHandler.RemoveTombStone).Methods("DELETE")
 	clusterRouter.HandleFunc("/stores/limit", storesHandler.GetAllLimit).Methods("GET")
 	clusterRouter.HandleFunc("/stores/limit", storesHandler.SetAllLimit).Methods("POST")
+	clusterRouter.HandleFunc("/stores/limit", storesHandler.RemoveAllLimit).Methods("DELETE")
 	clusterRouter.HandleFunc("/stores/limit/scene", storesHandler.SetStoreLimitScene).Methods("POST")
 	clusterRouter.HandleFunc("/stores/limit/scene", storesHandler.GetStoreLimitScene).Methods("GET")
+	clusterRouter.HandleFunc("/stores/limit/forecast", storesHandler.GetLimitForecast).Methods("GET")
+	clusterRouter.HandleFunc("/stores/problems", storesHandler.GetProblems).Methods("GET")
+	clusterRouter.HandleFunc("/stores/history", storesHandler.GetHistory).Methods("GET")
+	clusterRouter.HandleFunc("/stores/weights", storesHandler.GetWeights).Methods("GET")
+	clusterRouter.HandleFunc("/stores/weights", storesHandler.SetWeights).Methods("POST")
+	batchOfflineHandler := newBatchOfflineHandler(svr, rd)
+	clusterRouter.HandleFunc("/stores/batch-offline", batchOfflineHandler.Get).Methods("GET")
+	clusterRouter.HandleFunc("/stores/batch-offline", batchOfflineHandler.Start).Methods("POST")
+	clusterRouter.HandleFunc("/stores/batch-offline/abort", batchOfflineHandler.Abort).Methods("POST")
 
 	labelsHandler := newLabelsHandler(svr, rd)
 	clusterRouter.HandleFunc("/labels", labelsHandler.Get).Methods("GET")
@@ -124,20 +178,25 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) (*mux.
 	regionHandler := newRegionHandler(svr, rd)
 	clusterRouter.HandleFunc("/region/id/{id}", regionHandler.GetRegionByID).Methods("GET")
 	clusterRouter.HandleFunc("/region/key/{key}", regionHandler.GetRegionByKey).Methods("GET")
+	clusterRouter.HandleFunc("/region/id/{id}/closest-peer", regionHandler.GetClosestPeers).Methods("GET")
+	clusterRouter.HandleFunc("/region/id/{id}/ancestry", regionHandler.GetAncestry).Methods("GET")
 
 	srd := createStreamingRender()
 	regionsAllHandler := newRegionsHandler(svr, srd)
-	clusterRouter.HandleFunc("/regions", regionsAllHandler.GetAll).Methods("GET")
+	registry.provide(clusterRouter.HandleFunc("/regions", regionsAllHandler.GetAll).Methods("GET"), featureStreamingRegions, featurePagination)
 
 	regionsHandler := newRegionsHandler(svr, rd)
-	clusterRouter.HandleFunc("/regions/key", regionsHandler.ScanRegions).Methods("GET")
+	registry.provide(clusterRouter.HandleFunc("/regions/key", regionsHandler.ScanRegions).Methods("GET"), featurePagination)
 	clusterRouter.HandleFunc("/regions/count", regionsHandler.GetRegionCount).Methods("GET")
+	clusterRouter.HandleFunc("/regions/scatter", regionsHandler.ScatterRegions).Methods("POST")
 	clusterRouter.HandleFunc("/regions/store/{id}", regionsHandler.GetStoreRegions).Methods("GET")
 	clusterRouter.HandleFunc("/regions/writeflow", regionsHandler.GetTopWriteFlow).Methods("GET")
 	clusterRouter.HandleFunc("/regions/readflow", regionsHandler.GetTopReadFlow).Methods("GET")
 	clusterRouter.HandleFunc("/regions/confver", regionsHandler.GetTopConfVer).Methods("GET")
 	clusterRouter.HandleFunc("/regions/version", regionsHandler.GetTopVersion).Methods("GET")
 	clusterRouter.HandleFunc("/regions/size", regionsHandler.GetTopSize).Methods("GET")
+	clusterRouter.HandleFunc("/regions/leader-churn", regionsHandler.GetLeaderChurnRegions).Methods("GET")
+	clusterRouter.HandleFunc("/regions/growth", regionsHandler.GetGrowthRegions).Methods("GET")
 	clusterRouter.HandleFunc("/regions/check/miss-peer", regionsHandler.GetMissPeerRegions).Methods("GET")
 	clusterRouter.HandleFunc("/regions/check/extra-peer", regionsHandler.GetExtraPeerRegions).Methods("GET")
 	clusterRouter.HandleFunc("/regions/check/pending-peer", regionsHandler.GetPendingPeerRegions).Methods("GET")
@@ -146,9 +205,40 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) (*mux.
 	clusterRouter.HandleFunc("/regions/check/empty-region", regionsHandler.GetEmptyRegion).Methods("GET")
 	clusterRouter.HandleFunc("/regions/check/hist-size", regionsHandler.GetSizeHistogram).Methods("GET")
 	clusterRouter.HandleFunc("/regions/check/hist-keys", regionsHandler.GetKeysHistogram).Methods("GET")
+	clusterRouter.HandleFunc("/regions/check/size-age", regionsHandler.GetSizeAgeDistribution).Methods("GET")
 	clusterRouter.HandleFunc("/regions/sibling/{id}", regionsHandler.GetRegionSiblings).Methods("GET")
+	clusterRouter.HandleFunc("/regions/closest-peer", regionsHandler.BatchGetClosestPeers).Methods("POST")
+
+	scheduleLockHandler := newScheduleLockHandler(svr, rd)
+	registry.provide(clusterRouter.HandleFunc("/regions/schedule-lock", scheduleLockHandler.List).Methods("GET"), featureScheduleLocks)
+	registry.provide(clusterRouter.HandleFunc("/regions/schedule-lock", scheduleLockHandler.Acquire).Methods("POST"), featureScheduleLocks)
+	registry.provide(clusterRouter.HandleFunc("/regions/schedule-lock/{id}", scheduleLockHandler.Release).Methods("DELETE"), featureScheduleLocks)
+
+	splitRangeHandler := newSplitRangeHandler(svr, rd)
+	registry.provide(clusterRouter.HandleFunc("/regions/split-range", splitRangeHandler.Submit).Methods("POST"), featureSplitRange)
+	registry.provide(clusterRouter.HandleFunc("/regions/split-range/{id}", splitRangeHandler.Get).Methods("GET"), featureSplitRange)
+
+	leaderPinHandler := newLeaderPinHandler(svr, rd)
+	registry.provide(clusterRouter.HandleFunc("/regions/pin-leader", leaderPinHandler.List).Methods("GET"), featureLeaderPins)
+	registry.provide(clusterRouter.HandleFunc("/region/id/{id}/pin-leader", leaderPinHandler.Pin).Methods("POST"), featureLeaderPins)
+	registry.provide(clusterRouter.HandleFunc("/region/id/{id}/pin-leader", leaderPinHandler.Unpin).Methods("DELETE"), featureLeaderPins)
+
+	operatorQuotaHandler := newOperatorQuotaHandler(svr, rd)
+	clusterRouter.HandleFunc("/operator-quotas", operatorQuotaHandler.List).Methods("GET")
+	clusterRouter.HandleFunc("/operator-quotas/{consumer}", operatorQuotaHandler.Set).Methods("POST")
+	clusterRouter.HandleFunc("/operator-quotas/{consumer}", operatorQuotaHandler.Delete).Methods("DELETE")
+
+	maintenanceWindowHandler := newMaintenanceWindowHandler(svr, rd)
+	clusterRouter.HandleFunc("/schedule/windows", maintenanceWindowHandler.List).Methods("GET")
+	clusterRouter.HandleFunc("/schedule/windows", maintenanceWindowHandler.Set).Methods("POST")
+	clusterRouter.HandleFunc("/schedule/windows/{name}", maintenanceWindowHandler.Get).Methods("GET")
+	clusterRouter.HandleFunc("/schedule/windows/{name}", maintenanceWindowHandler.Delete).Methods("DELETE")
+
+	reportHandler := newReportHandler(svr, rd)
+	clusterRouter.HandleFunc("/reports/scheduling", reportHandler.GetSchedulingReport).Methods("GET")
 
 	apiRouter.Handle("/version", newVersionHandler(rd)).Methods("GET")
+	apiRouter.Handle("/version-info", newVersionInfoHandler(registry, rd)).Methods("GET")
 	apiRouter.Handle("/status", newStatusHandler(svr, rd)).Methods("GET")
 
 	memberHandler := newMemberHandler(svr, rd)
@@ -156,6 +246,8 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) (*mux.
 	apiRouter.HandleFunc("/members/name/{name}", memberHandler.DeleteByName).Methods("DELETE")
 	apiRouter.HandleFunc("/members/id/{id}", memberHandler.DeleteByID).Methods("DELETE")
 	apiRouter.HandleFunc("/members/name/{name}", memberHandler.SetMemberPropertyByName).Methods("POST")
+	apiRouter.HandleFunc("/members/{name}/sync-status", memberHandler.GetSyncStatus).Methods("GET")
+	apiRouter.HandleFunc("/members/{name}/resync", memberHandler.Resync).Methods("POST")
 
 	leaderHandler := newLeaderHandler(svr, rd)
 	apiRouter.HandleFunc("/leader", leaderHandler.Get).Methods("GET")
@@ -164,13 +256,36 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) (*mux.
 
 	statsHandler := newStatsHandler(svr, rd)
 	clusterRouter.HandleFunc("/stats/region", statsHandler.Region).Methods("GET")
+	clusterRouter.HandleFunc("/stats/snapshot-flows", statsHandler.SnapshotFlows).Methods("GET")
+	clusterRouter.HandleFunc("/stats/capacity-forecast", statsHandler.CapacityForecast).Methods("GET")
+	clusterRouter.HandleFunc("/stats/cross-zone-traffic", statsHandler.CrossZoneTraffic).Methods("GET")
+	clusterRouter.HandleFunc("/stats/balance-score", statsHandler.BalanceScore).Methods("GET")
+	clusterRouter.HandleFunc("/metrics/summary", statsHandler.MetricsSummary).Methods("GET")
 
 	trendHandler := newTrendHandler(svr, rd)
 	apiRouter.HandleFunc("/trend", trendHandler.Handle).Methods("GET")
 
 	adminHandler := newAdminHandler(svr, rd)
 	clusterRouter.HandleFunc("/admin/cache/region/{id}", adminHandler.HandleDropCacheRegion).Methods("DELETE")
+	clusterRouter.HandleFunc("/admin/cache/rebuild", adminHandler.StartCacheRebuild).Methods("POST")
+	clusterRouter.HandleFunc("/admin/cache/rebuild", adminHandler.GetCacheRebuild).Methods("GET")
+	clusterRouter.HandleFunc("/admin/cache/gaps", adminHandler.GetCacheGaps).Methods("GET")
 	clusterRouter.HandleFunc("/admin/reset-ts", adminHandler.ResetTS).Methods("POST")
+	apiRouter.HandleFunc("/admin/reset-ts/history", adminHandler.GetResetTSHistory).Methods("GET")
+	clusterRouter.HandleFunc("/admin/trace/region/{id}", adminHandler.EnableRegionTrace).Methods("POST")
+	clusterRouter.HandleFunc("/admin/trace/region/{id}", adminHandler.GetRegionTrace).Methods("GET")
+	clusterRouter.HandleFunc("/admin/trace/region/{id}", adminHandler.DisableRegionTrace).Methods("DELETE")
+	registry.provide(clusterRouter.HandleFunc("/debug/region/{id}/check", adminHandler.ExplainRegionCheck).Methods("GET"), featureRegionCheckExplain)
+	clusterRouter.HandleFunc("/admin/scatter/status", adminHandler.GetScatterStatus).Methods("GET")
+	clusterRouter.HandleFunc("/admin/scatter/group/{group}", adminHandler.ResetScatterGroup).Methods("DELETE")
+	clusterRouter.HandleFunc("/admin/storage-usage", adminHandler.GetStorageUsage).Methods("GET")
+
+	rollingRestartHandler := newRollingRestartHandler(svr, rd)
+	clusterRouter.HandleFunc("/admin/rolling-restart", rollingRestartHandler.Get).Methods("GET")
+	clusterRouter.HandleFunc("/admin/rolling-restart", rollingRestartHandler.Start).Methods("POST")
+	clusterRouter.HandleFunc("/admin/rolling-restart/pause", rollingRestartHandler.Pause).Methods("POST")
+	clusterRouter.HandleFunc("/admin/rolling-restart/resume", rollingRestartHandler.Resume).Methods("POST")
+	clusterRouter.HandleFunc("/admin/rolling-restart/abort", rollingRestartHandler.Abort).Methods("POST")
 
 	logHandler := newlogHandler(svr, rd)
 	apiRouter.HandleFunc("/admin/log", logHandler.Handle).Methods("POST")
@@ -178,6 +293,14 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) (*mux.
 	pluginHandler := newPluginHandler(handler, rd)
 	apiRouter.HandleFunc("/plugin", pluginHandler.LoadPlugin).Methods("POST")
 	apiRouter.HandleFunc("/plugin", pluginHandler.UnloadPlugin).Methods("DELETE")
+	apiRouter.HandleFunc("/plugins", pluginHandler.GetPlugins).Methods("GET")
+
+	tsoHandler := newTSOHandler(handler, rd)
+	apiRouter.HandleFunc("/tso/status", tsoHandler.GetStatus).Methods("GET")
+
+	etcdHandler := newEtcdHandler(handler, rd)
+	apiRouter.HandleFunc("/etcd/status", etcdHandler.GetStatus).Methods("GET")
+	apiRouter.HandleFunc("/etcd/defrag-and-compact", etcdHandler.DefragAndCompact).Methods("POST")
 
 	apiRouter.Handle("/health", newHealthHandler(svr, rd)).Methods("GET")
 	apiRouter.Handle("/diagnose", newDiagnoseHandler(svr, rd)).Methods("GET")
@@ -187,19 +310,21 @@ func createRouter(ctx context.Context, prefix string, svr *server.Server) (*mux.
 	apiRouter.Handle("/metric/query_range", newQueryMetric(svr)).Methods("GET", "POST")
 
 	// profile API
-	apiRouter.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	apiRouter.Handle("/debug/pprof/heap", pprof.Handler("heap"))
-	apiRouter.Handle("/debug/pprof/mutex", pprof.Handler("mutex"))
-	apiRouter.Handle("/debug/pprof/allocs", pprof.Handler("allocs"))
-	apiRouter.Handle("/debug/pprof/block", pprof.Handler("block"))
-	apiRouter.Handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
+	pprofHandler := newPprofHandler(rd, svr.GetConfig().Security.CertAllowedCN)
+	apiRouter.HandleFunc("/debug/pprof/cpu", pprofHandler.CPU).Methods("POST")
+	apiRouter.HandleFunc("/debug/pprof/profile", pprofHandler.CPU)
+	apiRouter.HandleFunc("/debug/pprof/heap", pprofHandler.lookup("heap"))
+	apiRouter.HandleFunc("/debug/pprof/mutex", pprofHandler.lookup("mutex"))
+	apiRouter.HandleFunc("/debug/pprof/allocs", pprofHandler.lookup("allocs"))
+	apiRouter.HandleFunc("/debug/pprof/block", pprofHandler.lookup("block"))
+	apiRouter.HandleFunc("/debug/pprof/goroutine", pprofHandler.lookup("goroutine"))
 
 	// Deprecated
-	rootRouter.Handle("/health", newHealthHandler(svr, rd)).Methods("GET")
+	registry.deprecate(rootRouter.Handle("/health", newHealthHandler(svr, rd)).Methods("GET"), deprecationRootRoutes)
 	// Deprecated
-	rootRouter.Handle("/diagnose", newDiagnoseHandler(svr, rd)).Methods("GET")
+	registry.deprecate(rootRouter.Handle("/diagnose", newDiagnoseHandler(svr, rd)).Methods("GET"), deprecationRootRoutes)
 	// Deprecated
-	rootRouter.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+	registry.deprecate(rootRouter.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET"), deprecationRootRoutes)
 
 	if svr.GetConfig().EnableDynamicConfig {
 		apiRouter.HandleFunc("/component/ids/{component}", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,114 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/server"
+)
+
+var _ = Suite(&testRouterSuite{})
+
+type testRouterSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testRouterSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testRouterSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+// TestMalformedParams drives the router with the malformed IDs and keys, which
+// should be rejected with 400 rather than panicking or being accepted.
+func (s *testRouterSuite) TestMalformedParams(c *C) {
+	longID := strings.Repeat("1", apiutil.MaxIDParamLength+1)
+	longKey := strings.Repeat("00", apiutil.MaxHexKeyParamLength/2+1)
+	for _, t := range []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{"GET", "/region/id/abc", ""},
+		{"GET", "/region/id/-1", ""},
+		{"GET", "/region/id/18446744073709551616", ""},
+		{"GET", "/region/id/" + longID, ""},
+		{"GET", "/region/id/1e3/closest-peer", ""},
+		{"GET", "/region/id/-1/ancestry", ""},
+		{"POST", "/region/id/abc/pin-leader", "{}"},
+		{"DELETE", "/region/id/abc/pin-leader", ""},
+		{"GET", "/regions/store/-1", ""},
+		{"GET", "/regions/store/1?role=voter", ""},
+		{"GET", "/regions/sibling/-1", ""},
+		{"GET", "/regions?start_key=abc", ""},
+		{"GET", "/regions?start_key=zz", ""},
+		{"GET", "/regions?detail=abc", ""},
+		{"GET", "/regions/key?store_id=-1", ""},
+		{"GET", "/store/abc", ""},
+		{"GET", "/store/" + longID, ""},
+		{"GET", "/stores/history?include_removed=maybe", ""},
+		{"GET", "/operators/abc", ""},
+		{"DELETE", "/operators/-1", ""},
+		{"GET", "/operators/history?store_id=abc", ""},
+		{"DELETE", "/admin/cache/region/abc", ""},
+		{"GET", "/admin/cache/gaps?limit=-1", ""},
+		{"GET", "/config/label-property/impact?limit=abc", ""},
+		{"POST", "/admin/trace/region/abc", ""},
+		{"GET", "/admin/trace/region/-1", ""},
+		{"DELETE", "/admin/trace/region/0x1", ""},
+		{"GET", "/debug/region/abc/check", ""},
+		{"POST", "/regions/schedule-lock", `{"start_key": "abc", "end_key": "", "ttl": 10}`},
+		{"POST", "/regions/schedule-lock", `{"start_key": "", "end_key": "0g", "ttl": 10}`},
+		{"POST", "/regions/schedule-lock", `{"start_key": "` + longKey + `", "end_key": "", "ttl": 10}`},
+		{"POST", "/regions/split-range", `{"start-key": "abc", "end-key": "", "stride": 1024}`},
+		{"POST", "/regions/split-range", `{"start-key": "` + longKey + `", "end-key": "", "stride": 1024}`},
+		{"POST", "/regions/split-range", `{"start-key": "", "end-key": "", "keys": ["zz"]}`},
+		{"POST", "/regions/split-range", `{"start-key": "", "end-key": "", "keys": ["61"], "stride": 1024}`},
+		{"GET", "/regions/split-range/abc", ""},
+		{"POST", "/regions/scatter", `{"start_key": "abc"}`},
+		{"POST", "/regions/scatter", `{"start_key": "` + longKey + `"}`},
+		{"POST", "/schedulers/preview-range", `{"format": "hex", "start_key": "abc", "end_key": ""}`},
+		{"POST", "/operators", `{"name": "split-region", "region_id": 1, "policy": "usekey", "keys": "abc"}`},
+		{"POST", "/operators", `{"name": "split-region", "region_id": 1, "policy": "usekey", "keys": ["abc"]}`},
+		{"POST", "/operators", `{"name": "split-region", "args": {"region_id": 1, "policy": "usekey", "keys": ["00", "abc"]}}`},
+		{"POST", "/operators", `{"name": "merge-region-by-key", "args": {"key": "abc", "direction": "next"}}`},
+		{"POST", "/operators/precheck", `{"name": "split-region", "region_id": 1, "policy": "usekey", "keys": ["zz"]}`},
+	} {
+		comment := Commentf("%s %s %s", t.method, t.path, t.body)
+		req, err := http.NewRequest(t.method, s.urlPrefix+t.path, strings.NewReader(t.body))
+		c.Assert(err, IsNil, comment)
+		resp, err := dialClient.Do(req)
+		c.Assert(err, IsNil, comment)
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		c.Assert(err, IsNil, comment)
+		c.Assert(resp.StatusCode, Equals, http.StatusBadRequest, Commentf("%s %s %s: %s", t.method, t.path, t.body, data))
+	}
+}
@@ -15,9 +15,7 @@ package api
 
 import (
 	"bytes"
-	"encoding/hex"
 	"net/http"
-	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/pd/v4/pkg/apiutil"
@@ -70,10 +68,9 @@ func (h *ruleHandler) GetAllByRegion(w http.ResponseWriter, r *http.Request) {
 		h.rd.JSON(w, http.StatusPreconditionFailed, errPlacementDisabled.Error())
 		return
 	}
-	regionStr := mux.Vars(r)["region"]
-	regionID, err := strconv.ParseUint(regionStr, 10, 64)
+	regionID, err := apiutil.ParseRegionIDParam(mux.Vars(r)["region"])
 	if err != nil {
-		h.rd.JSON(w, http.StatusBadRequest, "invalid region id")
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	region := cluster.GetRegion(regionID)
@@ -91,10 +88,9 @@ func (h *ruleHandler) GetAllByKey(w http.ResponseWriter, r *http.Request) {
 		h.rd.JSON(w, http.StatusPreconditionFailed, errPlacementDisabled.Error())
 		return
 	}
-	keyHex := mux.Vars(r)["key"]
-	key, err := hex.DecodeString(keyHex)
+	key, err := apiutil.ParseHexKeyParam("key", mux.Vars(r)["key"])
 	if err != nil {
-		h.rd.JSON(w, http.StatusBadRequest, "key should be in hex format")
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	rules := cluster.GetRuleManager().GetRulesByKey(key)
@@ -138,13 +134,13 @@ func (h *ruleHandler) Set(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ruleHandler) checkRule(r *placement.Rule) error {
-	start, err := hex.DecodeString(r.StartKeyHex)
+	start, err := apiutil.ParseHexKeyParam("start_key", r.StartKeyHex)
 	if err != nil {
-		return errors.Wrap(err, "start key is not in hex format")
+		return err
 	}
-	end, err := hex.DecodeString(r.EndKeyHex)
+	end, err := apiutil.ParseHexKeyParam("end_key", r.EndKeyHex)
 	if err != nil {
-		return errors.Wrap(err, "end key is not hex format")
+		return err
 	}
 	if len(start) > 0 && bytes.Compare(end, start) <= 0 {
 		return errors.New("endKey should be greater than startKey")
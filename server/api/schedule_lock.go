@@ -0,0 +1,100 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/unrolled/render"
+)
+
+type scheduleLockHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newScheduleLockHandler(svr *server.Server, rd *render.Render) *scheduleLockHandler {
+	return &scheduleLockHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+type scheduleLockInput struct {
+	StartKey string `json:"start_key"`
+	EndKey   string `json:"end_key"`
+	// TTL is the lifetime of the lock in seconds.
+	TTL int64 `json:"ttl"`
+}
+
+func (h *scheduleLockHandler) List(w http.ResponseWriter, r *http.Request) {
+	cluster := getCluster(r.Context())
+	h.rd.JSON(w, http.StatusOK, cluster.GetScheduleLocks().GetLocks())
+}
+
+func (h *scheduleLockHandler) Acquire(w http.ResponseWriter, r *http.Request) {
+	cluster := getCluster(r.Context())
+	var input scheduleLockInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	startKey, err := apiutil.ParseHexKeyParam("start_key", input.StartKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	endKey, err := apiutil.ParseHexKeyParam("end_key", input.EndKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(endKey) > 0 && bytes.Compare(endKey, startKey) <= 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "end key should be greater than start key")
+		return
+	}
+	if input.TTL <= 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "ttl should be positive")
+		return
+	}
+	lock, err := cluster.AddScheduleLock(startKey, endKey, time.Duration(input.TTL)*time.Second)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, lock)
+}
+
+func (h *scheduleLockHandler) Release(w http.ResponseWriter, r *http.Request) {
+	cluster := getCluster(r.Context())
+	id, errParse := apiutil.ParseUint64VarsField(mux.Vars(r), "id")
+	if errParse != nil {
+		h.rd.JSON(w, http.StatusBadRequest, errParse.Error())
+		return
+	}
+	ok, err := cluster.GetScheduleLocks().RemoveLock(id)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !ok {
+		h.rd.JSON(w, http.StatusNotFound, "schedule lock not found")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
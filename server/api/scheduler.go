@@ -14,16 +14,22 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/pd/v4/pkg/apiutil"
 	"github.com/pingcap/pd/v4/server"
 	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/pingcap/pd/v4/server/core"
 	"github.com/pingcap/pd/v4/server/schedulers"
+	"github.com/pkg/errors"
 	"github.com/unrolled/render"
 )
 
@@ -42,6 +48,10 @@ func newSchedulerHandler(handler *server.Handler, r *render.Render) *schedulerHa
 }
 
 func (h *schedulerHandler) List(w http.ResponseWriter, r *http.Request) {
+	if status := r.URL.Query().Get("status"); status != "" {
+		h.listStatuses(w, status)
+		return
+	}
 	schedulers, err := h.GetSchedulers()
 	if err != nil {
 		h.r.JSON(w, http.StatusInternalServerError, err.Error())
@@ -50,6 +60,35 @@ func (h *schedulerHandler) List(w http.ResponseWriter, r *http.Request) {
 	h.r.JSON(w, http.StatusOK, schedulers)
 }
 
+// listStatuses lists the statuses of the schedulers which are paused, disabled
+// or all.
+func (h *schedulerHandler) listStatuses(w http.ResponseWriter, status string) {
+	var match func(*cluster.SchedulerStatus) bool
+	switch status {
+	case "paused":
+		match = func(s *cluster.SchedulerStatus) bool { return s.Paused }
+	case "disabled":
+		match = func(s *cluster.SchedulerStatus) bool { return s.Disabled }
+	case "all":
+		match = func(*cluster.SchedulerStatus) bool { return true }
+	default:
+		h.r.JSON(w, http.StatusBadRequest, "status should be paused, disabled or all")
+		return
+	}
+	statuses, err := h.GetSchedulerStatuses()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	matched := make([]*cluster.SchedulerStatus, 0, len(statuses))
+	for _, s := range statuses {
+		if match(s) {
+			matched = append(matched, s)
+		}
+	}
+	h.r.JSON(w, http.StatusOK, matched)
+}
+
 func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 	var input map[string]interface{}
 	if err := apiutil.ReadJSONRespondError(h.r, w, r.Body, &input); err != nil {
@@ -107,6 +146,17 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		rc, err := h.GetRaftCluster()
+		if err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if preview := previewRange(rc, []byte(args[0]), []byte(args[1])); preview.RegionCount == 0 {
+			h.r.JSON(w, http.StatusOK, &addSchedulerResult{
+				Warning: "the range does not match any region currently, please check the keys",
+			})
+			return
+		}
 
 	case schedulers.AdjacentRegionName:
 		var args []string
@@ -141,6 +191,24 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	case schedulers.EvictLeaderName:
+		if selector, ok := input["selector"].(string); ok {
+			if _, err := schedulers.ParseStoreLabelSelector(selector); err != nil {
+				h.r.JSON(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			err := h.AddEvictLeaderByLabel(selector)
+			if err == cluster.ErrSchedulerExisted {
+				if err := h.redirectSchedulerUpdateSelector(schedulers.EvictLeaderName, selector); err != nil {
+					h.r.JSON(w, http.StatusInternalServerError, err.Error())
+					return
+				}
+			}
+			if err != nil && err != cluster.ErrSchedulerExisted {
+				h.r.JSON(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			break
+		}
 		storeID, ok := input["store_id"].(float64)
 		if !ok {
 			h.r.JSON(w, http.StatusBadRequest, "missing store id")
@@ -157,6 +225,11 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+	case schedulers.EvictSlowStoreName:
+		if err := h.AddEvictSlowStoreScheduler(); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
 	case schedulers.ShuffleLeaderName:
 		if err := h.AddShuffleLeaderScheduler(); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
@@ -190,6 +263,128 @@ func (h *schedulerHandler) Post(w http.ResponseWriter, r *http.Request) {
 	h.r.JSON(w, http.StatusOK, nil)
 }
 
+// addSchedulerResult is the response of adding a scheduler when there is
+// something the caller should notice.
+type addSchedulerResult struct {
+	Warning string `json:"warning,omitempty"`
+}
+
+// rangePreview describes the regions in a key range.
+type rangePreview struct {
+	RegionCount int                  `json:"region_count"`
+	TotalSize   int64                `json:"total_size"`
+	FirstRegion *rangePreviewBound   `json:"first_region,omitempty"`
+	LastRegion  *rangePreviewBound   `json:"last_region,omitempty"`
+	Stores      []*rangePreviewStore `json:"stores"`
+}
+
+// rangePreviewBound is the boundary of a region, the keys are in hex format.
+type rangePreviewBound struct {
+	ID       uint64 `json:"id"`
+	StartKey string `json:"start_key"`
+	EndKey   string `json:"end_key"`
+}
+
+// rangePreviewStore is the number of regions and leaders in the range a store holds.
+type rangePreviewStore struct {
+	StoreID     uint64 `json:"store_id"`
+	RegionCount int    `json:"region_count"`
+	LeaderCount int    `json:"leader_count"`
+}
+
+func newRangePreviewBound(region *core.RegionInfo) *rangePreviewBound {
+	return &rangePreviewBound{
+		ID:       region.GetID(),
+		StartKey: core.HexRegionKeyStr(region.GetStartKey()),
+		EndKey:   core.HexRegionKeyStr(region.GetEndKey()),
+	}
+}
+
+// previewRange collects the regions in the range the same way as the
+// scatter-range scheduler does.
+func previewRange(rc *cluster.RaftCluster, startKey, endKey []byte) *rangePreview {
+	preview := &rangePreview{Stores: []*rangePreviewStore{}}
+	if len(endKey) > 0 && bytes.Compare(startKey, endKey) >= 0 {
+		return preview
+	}
+	regions := rc.ScanRegions(startKey, endKey, -1)
+	if len(regions) == 0 {
+		return preview
+	}
+	stores := make(map[uint64]*rangePreviewStore)
+	for _, region := range regions {
+		preview.TotalSize += region.GetApproximateSize()
+		for _, peer := range region.GetPeers() {
+			store, ok := stores[peer.GetStoreId()]
+			if !ok {
+				store = &rangePreviewStore{StoreID: peer.GetStoreId()}
+				stores[peer.GetStoreId()] = store
+				preview.Stores = append(preview.Stores, store)
+			}
+			store.RegionCount++
+			if peer.GetId() == region.GetLeader().GetId() {
+				store.LeaderCount++
+			}
+		}
+	}
+	sort.Slice(preview.Stores, func(i, j int) bool { return preview.Stores[i].StoreID < preview.Stores[j].StoreID })
+	preview.RegionCount = len(regions)
+	preview.FirstRegion = newRangePreviewBound(regions[0])
+	preview.LastRegion = newRangePreviewBound(regions[len(regions)-1])
+	return preview
+}
+
+func parseRangeKey(option, format string, input map[string]interface{}) ([]byte, error) {
+	v, ok := input[option].(string)
+	if !ok {
+		return nil, errOptionNotExist(option)
+	}
+	switch format {
+	case "raw":
+		key, err := url.QueryUnescape(v)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(key), nil
+	case "hex":
+		return apiutil.ParseHexKeyParam(option, v)
+	}
+	return nil, errors.Errorf("unknown key format %s", format)
+}
+
+// PreviewRange shows the regions in a key range, so that the range can be
+// checked before adding the scatter-range scheduler.
+func (h *schedulerHandler) PreviewRange(w http.ResponseWriter, r *http.Request) {
+	var input map[string]interface{}
+	if err := apiutil.ReadJSONRespondError(h.r, w, r.Body, &input); err != nil {
+		return
+	}
+	format := "raw"
+	if f, ok := input["format"].(string); ok {
+		format = f
+	}
+	startKey, err := parseRangeKey("start_key", format, input)
+	if err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	endKey, err := parseRangeKey("end_key", format, input)
+	if err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(endKey) > 0 && bytes.Compare(startKey, endKey) > 0 {
+		h.r.JSON(w, http.StatusBadRequest, "start key should not be greater than end key")
+		return
+	}
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, previewRange(rc, startKey, endKey))
+}
+
 func (h *schedulerHandler) redirectSchedulerUpdate(name string, storeID float64) error {
 	input := make(map[string]interface{})
 	input["name"] = name
@@ -202,6 +397,18 @@ func (h *schedulerHandler) redirectSchedulerUpdate(name string, storeID float64)
 	return postJSON(updateURL, body)
 }
 
+func (h *schedulerHandler) redirectSchedulerUpdateSelector(name string, selector string) error {
+	input := make(map[string]interface{})
+	input["name"] = name
+	input["selector"] = selector
+	updateURL := fmt.Sprintf("%s/%s/%s/config", h.GetAddr(), schedulerConfigPrefix, name)
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	return postJSON(updateURL, body)
+}
+
 func (h *schedulerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	name := mux.Vars(r)["name"]
 	switch {
@@ -225,9 +432,14 @@ func (h *schedulerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *schedulerHandler) redirectSchedulerDelete(name, schedulerName string) error {
-	args := strings.Split(name, "-")
-	args = args[len(args)-1:]
-	url := fmt.Sprintf("%s/%s/%s/delete/%s", h.GetAddr(), schedulerConfigPrefix, schedulerName, args[0])
+	arg := strings.TrimPrefix(name, schedulerName+"-")
+	path := "delete/" + arg
+	// The evict-leader-scheduler can be deleted by the label selector, such as
+	// evict-leader-scheduler-zone=z1.
+	if strings.Contains(arg, "=") {
+		path = "delete-selector/" + url.PathEscape(arg)
+	}
+	url := fmt.Sprintf("%s/%s/%s/%s", h.GetAddr(), schedulerConfigPrefix, schedulerName, path)
 	resp, err := doDelete(url)
 	if resp.StatusCode != 200 {
 		return cluster.ErrSchedulerNotFound
@@ -238,7 +450,27 @@ func (h *schedulerHandler) redirectSchedulerDelete(name, schedulerName string) e
 	return nil
 }
 
+func (h *schedulerHandler) Get(w http.ResponseWriter, r *http.Request) {
+	detail, err := h.GetSchedulerDetail(mux.Vars(r)["name"])
+	if err != nil {
+		if errors.Cause(err) == cluster.ErrSchedulerNotFound {
+			h.r.JSON(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, detail)
+}
+
+// PauseOrResume pauses or resumes the scheduler by the delay in the body, or
+// sets whether it only logs its operators by the observe query parameter.
 func (h *schedulerHandler) PauseOrResume(w http.ResponseWriter, r *http.Request) {
+	if observeStr := r.URL.Query().Get("observe"); observeStr != "" {
+		h.observe(w, mux.Vars(r)["name"], observeStr)
+		return
+	}
+
 	var input map[string]int
 	if err := apiutil.ReadJSONRespondError(h.r, w, r.Body, &input); err != nil {
 		return
@@ -257,6 +489,38 @@ func (h *schedulerHandler) PauseOrResume(w http.ResponseWriter, r *http.Request)
 	h.r.JSON(w, http.StatusOK, nil)
 }
 
+func (h *schedulerHandler) observe(w http.ResponseWriter, name, observeStr string) {
+	observe, err := strconv.ParseBool(observeStr)
+	if err != nil {
+		h.r.JSON(w, http.StatusBadRequest, "invalid observe")
+		return
+	}
+	if err := h.ObserveScheduler(name, observe); err != nil {
+		if errors.Cause(err) == cluster.ErrSchedulerNotFound {
+			h.r.JSON(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, nil)
+}
+
+// GetObserved returns the recent operators which the scheduler would have
+// added in the observe mode, the oldest first.
+func (h *schedulerHandler) GetObserved(w http.ResponseWriter, r *http.Request) {
+	ops, err := h.GetObservedOperators(mux.Vars(r)["name"])
+	if err != nil {
+		if errors.Cause(err) == cluster.ErrSchedulerNotFound {
+			h.r.JSON(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, ops)
+}
+
 type schedulerConfigHandler struct {
 	svr *server.Server
 	rd  *render.Render
@@ -278,3 +542,29 @@ func (h *schedulerConfigHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	}
 	h.rd.JSON(w, http.StatusNotAcceptable, errNoImplement)
 }
+
+// ListPersisted lists the raw scheduler configs in the storage, which is
+// useful to back up the configs or to find the ones failed to be loaded.
+func (h *schedulerConfigHandler) ListPersisted(w http.ResponseWriter, r *http.Request) {
+	configs, err := h.svr.GetHandler().GetPersistedSchedulerConfigs()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, configs)
+}
+
+// DeletePersisted removes a stale scheduler config from the storage.
+func (h *schedulerConfigHandler) DeletePersisted(w http.ResponseWriter, r *http.Request) {
+	err := h.svr.GetHandler().RemovePersistedSchedulerConfig(mux.Vars(r)["name"])
+	switch errors.Cause(err) {
+	case nil:
+		h.rd.JSON(w, http.StatusOK, nil)
+	case server.ErrSchedulerConfigNotFound:
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+	case server.ErrSchedulerConfigInUse:
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+	default:
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	}
+}
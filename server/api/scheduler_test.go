@@ -16,11 +16,17 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/pkg/testutil"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule"
 	_ "github.com/pingcap/pd/v4/server/schedulers"
 )
 
@@ -86,6 +92,36 @@ func (s *testScheduleSuite) TestOriginAPI(c *C) {
 	c.Assert(r.StatusCode, Equals, 500)
 }
 
+func (s *testScheduleSuite) TestEvictLeaderByLabel(c *C) {
+	input := map[string]interface{}{"name": "evict-leader-scheduler", "selector": "zone"}
+	body, err := json.Marshal(input)
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.urlPrefix, body), NotNil)
+
+	for _, selector := range []string{"zone=z1", "zone=z2,host=h1"} {
+		input["selector"] = selector
+		body, err = json.Marshal(input)
+		c.Assert(err, IsNil)
+		c.Assert(postJSON(s.urlPrefix, body), IsNil)
+	}
+	rc := s.svr.GetRaftCluster()
+	c.Assert(rc.GetSchedulers(), HasLen, 1)
+	listURL := fmt.Sprintf("%s%s%s/%s/list", s.svr.GetAddr(), apiPrefix, server.SchedulerConfigHandlerPath, "evict-leader-scheduler")
+	resp := make(map[string]interface{})
+	c.Assert(readJSON(listURL, &resp), IsNil)
+	c.Assert(resp["selectors"], DeepEquals, []interface{}{"zone=z1", "host=h1,zone=z2"})
+
+	_, err = doDelete(fmt.Sprintf("%s/%s", s.urlPrefix, "evict-leader-scheduler-zone=z1"))
+	c.Assert(err, IsNil)
+	c.Assert(rc.GetSchedulers(), HasLen, 1)
+	resp = make(map[string]interface{})
+	c.Assert(readJSON(listURL, &resp), IsNil)
+	c.Assert(resp["selectors"], DeepEquals, []interface{}{"host=h1,zone=z2"})
+	_, err = doDelete(fmt.Sprintf("%s/%s", s.urlPrefix, "evict-leader-scheduler-zone=z2,host=h1"))
+	c.Assert(err, IsNil)
+	c.Assert(rc.GetSchedulers(), HasLen, 0)
+}
+
 func (s *testScheduleSuite) TestAPI(c *C) {
 	type arg struct {
 		opt   string
@@ -97,7 +133,55 @@ func (s *testScheduleSuite) TestAPI(c *C) {
 		args          []arg
 		extraTestFunc func(name string, c *C)
 	}{
-		{name: "balance-leader-scheduler"},
+		{
+			name: "balance-leader-scheduler",
+			extraTestFunc: func(name string, c *C) {
+				resp := make(map[string]interface{})
+				listURL := fmt.Sprintf("%s%s%s/%s/list", s.svr.GetAddr(), apiPrefix, server.SchedulerConfigHandlerPath, name)
+				c.Assert(readJSON(listURL, &resp), IsNil)
+				_, ok := resp["tolerant-count"]
+				c.Assert(ok, IsFalse)
+				effectiveURL := fmt.Sprintf("%s%s/api/v1/config/effective?scheduler=balance-leader", s.svr.GetAddr(), apiPrefix)
+				getItems := func() map[string]*schedule.EffectiveConfigItem {
+					var conf cluster.SchedulerEffectiveConfig
+					c.Assert(readJSON(effectiveURL, &conf), IsNil)
+					c.Assert(conf.Name, Equals, name)
+					items := make(map[string]*schedule.EffectiveConfigItem)
+					for _, item := range conf.Items {
+						items[item.Name] = item
+					}
+					return items
+				}
+				items := getItems()
+				c.Assert(items["leader-schedule-limit"].Source, Equals, schedule.ConfigSourceCluster)
+				c.Assert(items["tolerant-size-ratio"].ShadowedBy, Equals, "")
+				c.Assert(items["tolerant-count"], IsNil)
+
+				updateURL := fmt.Sprintf("%s%s%s/%s/config", s.svr.GetAddr(), apiPrefix, server.SchedulerConfigHandlerPath, name)
+				c.Assert(postJSON(updateURL, []byte(`{"tolerant-count": 2}`)), IsNil)
+				resp = make(map[string]interface{})
+				c.Assert(readJSON(listURL, &resp), IsNil)
+				c.Assert(resp["tolerant-count"], Equals, 2.0)
+				// The config should be persisted.
+				data, err := s.svr.GetStorage().LoadScheduleConfig(name)
+				c.Assert(err, IsNil)
+				c.Assert(strings.Contains(data, `"tolerant-count":2`), IsTrue)
+				// The tolerant count of the scheduler shadows the tolerant size ratio of the cluster.
+				items = getItems()
+				c.Assert(items["tolerant-size-ratio"].Source, Equals, schedule.ConfigSourceCluster)
+				c.Assert(items["tolerant-size-ratio"].ShadowedBy, Equals, "tolerant-count")
+				c.Assert(items["tolerant-count"].Source, Equals, schedule.ConfigSourceScheduler)
+				c.Assert(items["tolerant-count"].Value, Equals, 2.0)
+
+				c.Assert(postJSON(updateURL, []byte(`{"tolerant-count": -1}`)), NotNil)
+
+				c.Assert(postJSON(updateURL, []byte(`{"tolerant-count": null}`)), IsNil)
+				resp = make(map[string]interface{})
+				c.Assert(readJSON(listURL, &resp), IsNil)
+				_, ok = resp["tolerant-count"]
+				c.Assert(ok, IsFalse)
+			},
+		},
 		{
 			name: "balance-hot-region-scheduler",
 			extraTestFunc: func(name string, c *C) {
@@ -138,7 +222,52 @@ func (s *testScheduleSuite) TestAPI(c *C) {
 				c.Assert(err, IsNil)
 			},
 		},
-		{name: "balance-region-scheduler"},
+		{
+			name: "balance-region-scheduler",
+			extraTestFunc: func(name string, c *C) {
+				resp := make(map[string]interface{})
+				listURL := fmt.Sprintf("%s%s%s/%s/list", s.svr.GetAddr(), apiPrefix, server.SchedulerConfigHandlerPath, name)
+				c.Assert(readJSON(listURL, &resp), IsNil)
+				c.Assert(resp["write-flow-cost-multiplier"], Equals, 0.0)
+
+				updateURL := fmt.Sprintf("%s%s%s/%s/config", s.svr.GetAddr(), apiPrefix, server.SchedulerConfigHandlerPath, name)
+				c.Assert(postJSON(updateURL, []byte(`{"write-flow-cost-multiplier": 1.5, "snapshot-duration": "1m"}`)), IsNil)
+				resp = make(map[string]interface{})
+				c.Assert(readJSON(listURL, &resp), IsNil)
+				c.Assert(resp["write-flow-cost-multiplier"], Equals, 1.5)
+				c.Assert(resp["snapshot-duration"], Equals, "1m0s")
+				// The config should be persisted.
+				data, err := s.svr.GetStorage().LoadScheduleConfig(name)
+				c.Assert(err, IsNil)
+				c.Assert(strings.Contains(data, `"write-flow-cost-multiplier":1.5`), IsTrue)
+
+				c.Assert(postJSON(updateURL, []byte(`{"write-flow-cost-multiplier": -1}`)), NotNil)
+				c.Assert(postJSON(updateURL, []byte(`{"write-flow-cost-multiplier": 0}`)), IsNil)
+				resp = make(map[string]interface{})
+				c.Assert(readJSON(listURL, &resp), IsNil)
+				c.Assert(resp["write-flow-cost-multiplier"], Equals, 0.0)
+				c.Assert(resp["snapshot-duration"], Equals, "1m0s")
+			},
+		},
+		{
+			name: "evict-slow-store-scheduler",
+			extraTestFunc: func(name string, c *C) {
+				resp := make(map[string]interface{})
+				listURL := fmt.Sprintf("%s%s%s/%s/list", s.svr.GetAddr(), apiPrefix, server.SchedulerConfigHandlerPath, name)
+				c.Assert(readJSON(listURL, &resp), IsNil)
+				c.Assert(resp["slow-store"], Equals, 0.0)
+				c.Assert(resp["evicted-store"], Equals, 0.0)
+				c.Assert(resp["slow-ratio"], Equals, 3.0)
+
+				updateURL := fmt.Sprintf("%s%s%s/%s/config", s.svr.GetAddr(), apiPrefix, server.SchedulerConfigHandlerPath, name)
+				c.Assert(postJSON(updateURL, []byte(`{"max-evict-duration": "1h"}`)), IsNil)
+				c.Assert(postJSON(updateURL, []byte(`{"slow-ratio": 0.5}`)), NotNil)
+				resp = make(map[string]interface{})
+				c.Assert(readJSON(listURL, &resp), IsNil)
+				c.Assert(resp["max-evict-duration"], Equals, "1h0m0s")
+				c.Assert(resp["slow-ratio"], Equals, 3.0)
+			},
+		},
 		{name: "shuffle-leader-scheduler"},
 		{name: "shuffle-region-scheduler"},
 		{
@@ -329,6 +458,166 @@ func (s *testScheduleSuite) TestAPI(c *C) {
 
 }
 
+func (s *testScheduleSuite) TestSchedulerDetail(c *C) {
+	body, err := json.Marshal(map[string]interface{}{"name": "balance-region-scheduler"})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.urlPrefix, body), IsNil)
+	defer s.deleteScheduler("balance-region-scheduler", c)
+
+	var detail cluster.SchedulerDetail
+	c.Assert(readJSON(s.urlPrefix+"/balance-region-scheduler", &detail), IsNil)
+	c.Assert(detail, DeepEquals, cluster.SchedulerDetail{
+		Name:    "balance-region-scheduler",
+		Allowed: true,
+		Backoff: cluster.SchedulerBackoffStatus{SuccessRate: 1},
+	})
+
+	pauseArgs, err := json.Marshal(map[string]interface{}{"delay": 30})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.urlPrefix+"/balance-region-scheduler", pauseArgs), IsNil)
+	c.Assert(readJSON(s.urlPrefix+"/balance-region-scheduler", &detail), IsNil)
+	c.Assert(detail.Paused, IsTrue)
+
+	resp, err := dialClient.Get(s.urlPrefix + "/unknown-scheduler")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
+
+func (s *testScheduleSuite) TestObserveScheduler(c *C) {
+	body, err := json.Marshal(map[string]interface{}{"name": "shuffle-region-scheduler"})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.urlPrefix, body), IsNil)
+	defer s.deleteScheduler("shuffle-region-scheduler", c)
+
+	url := s.urlPrefix + "/shuffle-region-scheduler"
+	c.Assert(postJSON(url+"?observe=true", nil), IsNil)
+	var detail cluster.SchedulerDetail
+	c.Assert(readJSON(url, &detail), IsNil)
+	c.Assert(detail.Observing, IsTrue)
+	c.Assert(detail.Paused, IsFalse)
+	var ops []*cluster.ObservedOperator
+	c.Assert(readJSON(url+"/observed", &ops), IsNil)
+	c.Assert(postJSON(url+"?observe=false", nil), IsNil)
+	c.Assert(readJSON(url, &detail), IsNil)
+	c.Assert(detail.Observing, IsFalse)
+
+	for _, t := range []struct {
+		url    string
+		status int
+	}{
+		{url + "?observe=maybe", http.StatusBadRequest},
+		{s.urlPrefix + "/unknown-scheduler?observe=true", http.StatusNotFound},
+	} {
+		resp, err := dialClient.Post(t.url, "application/json", nil)
+		c.Assert(err, IsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, Equals, t.status)
+	}
+	resp, err := dialClient.Get(s.urlPrefix + "/unknown-scheduler/observed")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
+
+func (s *testScheduleSuite) TestSchedulerStatuses(c *C) {
+	for _, input := range []map[string]interface{}{
+		{"name": "balance-region-scheduler"},
+		{"name": "evict-leader-scheduler", "store_id": 1},
+	} {
+		body, err := json.Marshal(input)
+		c.Assert(err, IsNil)
+		c.Assert(postJSON(s.urlPrefix, body), IsNil)
+	}
+	defer s.deleteScheduler("evict-leader-scheduler", c)
+	getStatuses := func(status string) map[string]*cluster.SchedulerStatus {
+		var statuses []*cluster.SchedulerStatus
+		c.Assert(readJSON(s.urlPrefix+"?status="+status, &statuses), IsNil)
+		res := make(map[string]*cluster.SchedulerStatus)
+		for _, status := range statuses {
+			res[status.Name] = status
+		}
+		return res
+	}
+
+	pauseArgs, err := json.Marshal(map[string]interface{}{"delay": 30})
+	c.Assert(err, IsNil)
+	start := time.Now()
+	c.Assert(postJSON(s.urlPrefix+"/balance-region-scheduler", pauseArgs), IsNil)
+	statuses := getStatuses("paused")
+	c.Assert(statuses, HasLen, 1)
+	paused := statuses["balance-region-scheduler"]
+	c.Assert(paused, NotNil)
+	c.Assert(paused.Paused, IsTrue)
+	c.Assert(paused.PausedUntil, NotNil)
+	c.Assert(paused.PausedUntil.Sub(start) > 29*time.Second, IsTrue)
+	c.Assert(paused.PausedUntil.Sub(start) <= 31*time.Second, IsTrue)
+
+	statuses = getStatuses("all")
+	c.Assert(statuses, HasKey, "evict-leader-scheduler")
+	evict := statuses["evict-leader-scheduler"]
+	c.Assert(evict.Paused, IsFalse)
+	c.Assert(evict.PausedUntil, IsNil)
+	var config map[string]interface{}
+	c.Assert(json.Unmarshal(evict.Config, &config), IsNil)
+	c.Assert(config, HasKey, "store-id-ranges")
+
+	// The removed default scheduler is disabled. The scheduler configs may be
+	// overwritten by a stale version from the config manager for a while.
+	s.deleteScheduler("balance-region-scheduler", c)
+	c.Assert(getStatuses("paused"), HasLen, 0)
+	testutil.WaitUntil(c, func(c *C) bool {
+		disabled := getStatuses("disabled")["balance-region-scheduler"]
+		return disabled != nil && disabled.Disabled
+	})
+	c.Assert(getStatuses("all"), HasKey, "balance-region-scheduler")
+
+	resp, err := dialClient.Get(s.urlPrefix + "?status=running")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+}
+
+func (s *testScheduleSuite) TestPersistedConfig(c *C) {
+	body, err := json.Marshal(map[string]interface{}{"name": "evict-leader-scheduler", "store_id": 1})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.urlPrefix, body), IsNil)
+	defer s.deleteScheduler("evict-leader-scheduler", c)
+	// The config fails to be decoded when the scheduler is loaded.
+	c.Assert(s.svr.GetStorage().SaveScheduleConfig("grant-leader-scheduler", []byte("{invalid")), IsNil)
+
+	configURL := fmt.Sprintf("%s%s%s", s.svr.GetAddr(), apiPrefix, server.SchedulerConfigHandlerPath)
+	getConfigs := func() map[string]server.PersistedSchedulerConfig {
+		var configs []server.PersistedSchedulerConfig
+		c.Assert(readJSON(configURL, &configs), IsNil)
+		res := make(map[string]server.PersistedSchedulerConfig)
+		for _, config := range configs {
+			res[config.Name] = config
+		}
+		return res
+	}
+	configs := getConfigs()
+	c.Assert(configs["evict-leader-scheduler"].Loaded, IsTrue)
+	c.Assert(configs["grant-leader-scheduler"], DeepEquals, server.PersistedSchedulerConfig{Name: "grant-leader-scheduler", Config: "{invalid"})
+
+	// The routes of the running schedulers still work.
+	var list map[string]interface{}
+	c.Assert(readJSON(configURL+"/evict-leader-scheduler/list", &list), IsNil)
+	c.Assert(list, HasKey, "store-id-ranges")
+
+	deleteConfig := func(name string) int {
+		resp, err := doDelete(configURL + "/" + name)
+		c.Assert(err, IsNil)
+		return resp.StatusCode
+	}
+	c.Assert(deleteConfig("evict-leader-scheduler"), Equals, http.StatusBadRequest)
+	c.Assert(deleteConfig("grant-leader-scheduler"), Equals, http.StatusOK)
+	c.Assert(deleteConfig("grant-leader-scheduler"), Equals, http.StatusNotFound)
+	configs = getConfigs()
+	c.Assert(configs, HasKey, "evict-leader-scheduler")
+	c.Assert(configs, Not(HasKey), "grant-leader-scheduler")
+}
+
 func (s *testScheduleSuite) addScheduler(name, createdName string, body []byte, extraTest func(string, *C), c *C) {
 	if createdName == "" {
 		createdName = name
@@ -400,3 +689,147 @@ func (s *testScheduleSuite) testPauseOrResume(name, createdName string, body []b
 
 	s.deleteScheduler(createdName, c)
 }
+
+var _ = Suite(&testPreviewRangeSuite{})
+
+type testPreviewRangeSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testPreviewRangeSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1/schedulers", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+	mustPutStore(c, s.svr, 1, metapb.StoreState_Up, nil)
+	mustPutStore(c, s.svr, 2, metapb.StoreState_Up, nil)
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(2, 1, []byte(""), []byte("b")))
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(3, 1, []byte("b"), []byte("d"), core.WithAddPeer(&metapb.Peer{Id: 13, StoreId: 2})))
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(4, 2, []byte("d"), []byte("")))
+}
+
+func (s *testPreviewRangeSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testPreviewRangeSuite) preview(c *C, input map[string]interface{}) (*rangePreview, error) {
+	body, err := json.Marshal(input)
+	c.Assert(err, IsNil)
+	preview := &rangePreview{}
+	err = postJSON(s.urlPrefix+"/preview-range", body, func(res []byte, _ int) {
+		c.Assert(json.Unmarshal(res, preview), IsNil)
+	})
+	return preview, err
+}
+
+func (s *testPreviewRangeSuite) TestPreviewRange(c *C) {
+	// Empty range.
+	preview, err := s.preview(c, map[string]interface{}{"start_key": "c", "end_key": "c"})
+	c.Assert(err, IsNil)
+	c.Assert(preview, DeepEquals, &rangePreview{Stores: []*rangePreviewStore{}})
+
+	// Partial overlap.
+	expected := &rangePreview{
+		RegionCount: 2,
+		TotalSize:   20,
+		FirstRegion: &rangePreviewBound{ID: 3, StartKey: "62", EndKey: "64"},
+		LastRegion:  &rangePreviewBound{ID: 4, StartKey: "64", EndKey: ""},
+		Stores: []*rangePreviewStore{
+			{StoreID: 1, RegionCount: 1, LeaderCount: 1},
+			{StoreID: 2, RegionCount: 2, LeaderCount: 1},
+		},
+	}
+	preview, err = s.preview(c, map[string]interface{}{"start_key": "c", "end_key": "e"})
+	c.Assert(err, IsNil)
+	c.Assert(preview, DeepEquals, expected)
+	preview, err = s.preview(c, map[string]interface{}{"start_key": "63", "end_key": "65", "format": "hex"})
+	c.Assert(err, IsNil)
+	c.Assert(preview, DeepEquals, expected)
+
+	// Full keyspace.
+	preview, err = s.preview(c, map[string]interface{}{"start_key": "", "end_key": ""})
+	c.Assert(err, IsNil)
+	c.Assert(preview.RegionCount, Equals, 3)
+	c.Assert(preview.TotalSize, Equals, int64(30))
+	c.Assert(preview.FirstRegion, DeepEquals, &rangePreviewBound{ID: 2, StartKey: "", EndKey: "62"})
+	c.Assert(preview.LastRegion, DeepEquals, &rangePreviewBound{ID: 4, StartKey: "64", EndKey: ""})
+	c.Assert(preview.Stores, HasLen, 2)
+
+	// Bad requests.
+	for _, input := range []map[string]interface{}{
+		{"start_key": "e", "end_key": "c"},
+		{"start_key": "c"},
+		{"start_key": "zz", "end_key": "", "format": "hex"},
+		{"start_key": "c", "end_key": "e", "format": "encode"},
+	} {
+		_, err = s.preview(c, input)
+		c.Assert(err, NotNil)
+	}
+}
+
+func (s *testPreviewRangeSuite) TestScatterRangeWarning(c *C) {
+	addScatterRange := func(startKey, endKey, name string) string {
+		body, err := json.Marshal(map[string]interface{}{
+			"name":       "scatter-range",
+			"start_key":  startKey,
+			"end_key":    endKey,
+			"range_name": name,
+		})
+		c.Assert(err, IsNil)
+		var warning string
+		c.Assert(postJSON(s.urlPrefix, body, func(res []byte, _ int) {
+			result := &addSchedulerResult{}
+			c.Assert(json.Unmarshal(res, result), IsNil)
+			warning = result.Warning
+		}), IsNil)
+		return warning
+	}
+	c.Assert(addScatterRange("c", "e", "matched"), Equals, "")
+	c.Assert(addScatterRange("c", "c", "unmatched"), Not(Equals), "")
+	for _, name := range []string{"matched", "unmatched"} {
+		_, err := doDelete(fmt.Sprintf("%s/scatter-range-%s", s.urlPrefix, name))
+		c.Assert(err, IsNil)
+	}
+	schedulers := s.svr.GetRaftCluster().GetSchedulers()
+	c.Assert(schedulers["scatter-range-matched"], IsNil)
+	c.Assert(schedulers["scatter-range-unmatched"], IsNil)
+}
+
+func (s *testScheduleSuite) TestMaintenanceWindow(c *C) {
+	windowsURL := fmt.Sprintf("%s%s/api/v1/schedule/windows", s.svr.GetAddr(), apiPrefix)
+	c.Assert(postJSON(windowsURL, []byte(`{"name": "day", "weekdays": ["mon"], "start": "18:00", "end": "09:00", "schedulers": ["test"]}`)), NotNil)
+	c.Assert(postJSON(windowsURL, []byte(`{"name": "day", "weekdays": ["mon", "fri"], "start": "09:00", "end": "18:00", "schedulers": ["test"]}`)), IsNil)
+	c.Assert(postJSON(windowsURL, []byte(`{"name": "overlap", "weekdays": ["fri"], "start": "17:00", "end": "19:00", "schedulers": ["test"]}`)), NotNil)
+	c.Assert(postJSON(windowsURL, []byte(`{"name": "evening", "weekdays": ["fri"], "start": "18:00", "end": "24:00", "schedulers": ["test"], "include-checkers": true}`)), IsNil)
+
+	var windows []*cluster.MaintenanceWindow
+	c.Assert(readJSON(windowsURL, &windows), IsNil)
+	c.Assert(windows, HasLen, 2)
+	c.Assert(windows[0].Name, Equals, "day")
+	var window cluster.MaintenanceWindow
+	c.Assert(readJSON(windowsURL+"/evening", &window), IsNil)
+	c.Assert(window.IncludeCheckers, IsTrue)
+	c.Assert(readJSON(windowsURL+"/overlap", &window), NotNil)
+
+	for _, name := range []string{"day", "evening"} {
+		resp, err := doDelete(windowsURL + "/" + name)
+		c.Assert(err, IsNil)
+		c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	}
+	resp, err := doDelete(windowsURL + "/day")
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, http.StatusNotFound)
+}
+
+func (s *testScheduleSuite) TestEffectiveConfigInvalid(c *C) {
+	effectiveURL := fmt.Sprintf("%s%s/api/v1/config/effective", s.svr.GetAddr(), apiPrefix)
+	status, _ := requestStatusBody(c, dialClient, http.MethodGet, effectiveURL)
+	c.Assert(status, Equals, http.StatusBadRequest)
+	status, _ = requestStatusBody(c, dialClient, http.MethodGet, effectiveURL+"?scheduler=no-such")
+	c.Assert(status, Equals, http.StatusNotFound)
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/pkg/errors"
+	"github.com/unrolled/render"
+)
+
+type splitRangeHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newSplitRangeHandler(svr *server.Server, rd *render.Render) *splitRangeHandler {
+	return &splitRangeHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+func (h *splitRangeHandler) Get(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	id, errParse := apiutil.ParseUint64VarsField(mux.Vars(r), "id")
+	if errParse != nil {
+		h.rd.JSON(w, http.StatusBadRequest, errParse.Error())
+		return
+	}
+	job := rc.GetSplitRangeJob(id)
+	if job == nil {
+		h.rd.JSON(w, http.StatusNotFound, cluster.ErrSplitRangeNotFound.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, job)
+}
+
+func (h *splitRangeHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	var cfg cluster.SplitRangeConfig
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &cfg); err != nil {
+		return
+	}
+	keys := append([]string{cfg.StartKey, cfg.EndKey}, cfg.Keys...)
+	for i, key := range keys {
+		param := "keys"
+		switch i {
+		case 0:
+			param = "start-key"
+		case 1:
+			param = "end-key"
+		}
+		if _, err := apiutil.ParseHexKeyParam(param, key); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if err := rc.ValidateSplitRange(&cfg); err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	job, err := rc.SubmitSplitRange(&cfg)
+	if err != nil {
+		if errors.Cause(err) == cluster.ErrSplitRangeTooManyKeys {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+var _ = Suite(&testSplitRangeSuite{})
+
+type testSplitRangeSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testSplitRangeSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testSplitRangeSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testSplitRangeSuite) TestSplitRange(c *C) {
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(80, 1, []byte("a"), []byte("c")))
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(81, 1, []byte("c"), []byte("e")))
+
+	// The range start and end keys are boundaries already.
+	submit := func() *cluster.SplitRangeJob {
+		input := map[string]interface{}{"start-key": "61", "end-key": "63", "keys": []string{"62"}, "max-concurrent": 1}
+		data, err := json.Marshal(input)
+		c.Assert(err, IsNil)
+		job := &cluster.SplitRangeJob{}
+		err = postJSON(s.urlPrefix+"/regions/split-range", data, func(res []byte, _ int) {
+			c.Assert(json.Unmarshal(res, job), IsNil)
+		})
+		c.Assert(err, IsNil)
+		return job
+	}
+	job := submit()
+	c.Assert(job.State, Equals, cluster.SplitRangeRunning)
+	c.Assert(job.PendingKeys, DeepEquals, []string{"62"})
+	// Submitting it again does not start another job.
+	c.Assert(submit().ID, Equals, job.ID)
+
+	var got cluster.SplitRangeJob
+	c.Assert(readJSON(fmt.Sprintf("%s/regions/split-range/%d", s.urlPrefix, job.ID), &got), IsNil)
+	c.Assert(got.ID, Equals, job.ID)
+	c.Assert(got.TotalKeys, Equals, 1)
+	code, _ := requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/regions/split-range/%d", s.urlPrefix, job.ID+1000))
+	c.Assert(code, Equals, http.StatusNotFound)
+
+	// Too many keys to split at.
+	data, err := json.Marshal(map[string]interface{}{"start-key": "", "end-key": "", "stride": 1})
+	c.Assert(err, IsNil)
+	mustRegionHeartbeat(c, s.svr, newTestRegionInfo(82, 1, []byte("e"), []byte("f"), core.SetApproximateSize(100)))
+	c.Assert(postJSON(s.urlPrefix+"/regions/split-range", data), ErrorMatches, "(?s).*more than .* split keys.*")
+}
@@ -15,11 +15,21 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/unrolled/render"
 )
 
+const (
+	defaultCapacityForecastDays = 30
+	// defaultCrossZoneTrafficTop is the default number of the top regions
+	// contributing the cross-zone traffic.
+	defaultCrossZoneTrafficTop = 10
+	maxCrossZoneTrafficTop     = 1000
+)
+
 type statsHandler struct {
 	svr *server.Server
 	rd  *render.Render
@@ -38,3 +48,65 @@ func (h *statsHandler) Region(w http.ResponseWriter, r *http.Request) {
 	stats := rc.GetRegionStats([]byte(startKey), []byte(endKey))
 	h.rd.JSON(w, http.StatusOK, stats)
 }
+
+func (h *statsHandler) SnapshotFlows(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	flows := rc.GetOperatorController().GetSnapshotFlows()
+	h.rd.JSON(w, http.StatusOK, flows)
+}
+
+func (h *statsHandler) CapacityForecast(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	days := defaultCapacityForecastDays
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		var err error
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days <= 0 || days > cluster.CapacitySampleRetentionDays {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid days")
+			return
+		}
+	}
+	opt := h.svr.GetScheduleConfig()
+	forecast, err := rc.GetCapacitySampler().GetForecast(days, opt.HighSpaceRatio, opt.LowSpaceRatio)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, forecast)
+}
+
+// CrossZoneTraffic estimates the replication traffic crossing the zones by
+// the write byte rates of the regions and the zone labels of their stores.
+func (h *statsHandler) CrossZoneTraffic(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	label := cluster.DefaultZoneLabel
+	if l := r.URL.Query().Get("label"); l != "" {
+		label = l
+	}
+	top := defaultCrossZoneTrafficTop
+	if topStr := r.URL.Query().Get("top"); topStr != "" {
+		var err error
+		top, err = strconv.Atoi(topStr)
+		if err != nil || top < 0 || top > maxCrossZoneTrafficTop {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid top")
+			return
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, rc.GetCrossZoneTraffic(label, top))
+}
+
+// BalanceScore reports the skew of the leaders and the regions over the Up
+// stores, which is recalculated with the metrics instead of per request.
+func (h *statsHandler) BalanceScore(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	h.rd.JSON(w, http.StatusOK, rc.GetBalanceScore())
+}
+
+func (h *statsHandler) MetricsSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.svr.GetHandler().GetMetricsSummary()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, summary)
+}
@@ -15,13 +15,17 @@ package api
 
 import (
 	"fmt"
+	"math"
 	"net/url"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/pd/v4/pkg/apiutil"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule"
 	"github.com/pingcap/pd/v4/server/statistics"
 )
 
@@ -175,3 +179,121 @@ func (s *testStatsSuite) TestRegionStats(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(stats, DeepEquals, stats23)
 }
+
+func (s *testStatsSuite) TestSnapshotFlows(c *C) {
+	flows := &schedule.SnapshotFlows{}
+	err := readJSON(s.urlPrefix+"/stats/snapshot-flows", flows)
+	c.Assert(err, IsNil)
+	c.Assert(flows.Flows, HasLen, 0)
+	c.Assert(flows.Stores, HasLen, 0)
+}
+
+func (s *testStatsSuite) TestSchedulingReport(c *C) {
+	reportURL := s.urlPrefix + "/reports/scheduling"
+	s.svr.GetRaftCluster().GetSchedulingReporter().ObserveSplit(2)
+	var summary cluster.SchedulingReportSummary
+	c.Assert(readJSON(reportURL, &summary), IsNil)
+	c.Assert(summary.Reports, HasLen, 1)
+	c.Assert(summary.Reports[0].Splits, Equals, 2)
+	c.Assert(summary.Total.Splits, Equals, 2)
+	c.Assert(readJSON(reportURL+"?days=1", &summary), IsNil)
+	c.Assert(summary.Reports, HasLen, 1)
+	for _, days := range []string{"0", "91", "abc"} {
+		c.Assert(readJSON(reportURL+"?days="+days, &summary), NotNil)
+	}
+}
+
+func (s *testStatsSuite) TestCapacityForecast(c *C) {
+	forecastURL := s.urlPrefix + "/stats/capacity-forecast"
+	// The available space of store 100 decreases 5GB a day from 60GB in the
+	// last 5 days.
+	const gb = 1 << 30
+	start := time.Now().AddDate(0, 0, -5)
+	for day := 0; day < 5; day++ {
+		date := start.AddDate(0, 0, day).Format("2006-01-02")
+		sample := &cluster.CapacitySample{
+			Date:   date,
+			Stores: []cluster.StoreCapacitySample{{StoreID: 100, Capacity: 100 * gb, Available: uint64(60-5*day) * gb}},
+		}
+		c.Assert(s.svr.GetStorage().SaveCapacitySample(date, sample), IsNil)
+	}
+	findStore := func(forecast *cluster.CapacityForecast) *cluster.StoreCapacityForecast {
+		for _, store := range forecast.Stores {
+			if store.StoreID == 100 {
+				return store
+			}
+		}
+		return nil
+	}
+
+	var forecast cluster.CapacityForecast
+	c.Assert(readJSON(forecastURL, &forecast), IsNil)
+	c.Assert(forecast.Days, Equals, 30)
+	c.Assert(forecast.HighSpaceRatio, Equals, s.svr.GetScheduleConfig().HighSpaceRatio)
+	store := findStore(&forecast)
+	c.Assert(store, NotNil)
+	c.Assert(store.Samples, Equals, 5)
+	c.Assert(store.AvailableGrowth, Equals, float64(-5*gb))
+	// Crossing the ratio r takes (60GB-(1-r)*100GB)/5GB days since the first
+	// sample.
+	for _, item := range []struct {
+		date  string
+		ratio float64
+	}{{store.HighSpaceDate, forecast.HighSpaceRatio}, {store.LowSpaceDate, forecast.LowSpaceRatio}} {
+		days := int(math.Ceil((60 - (1-item.ratio)*100) / 5))
+		c.Assert(item.date, Equals, start.AddDate(0, 0, days).Format("2006-01-02"))
+	}
+
+	c.Assert(readJSON(forecastURL+"?days=2", &forecast), IsNil)
+	c.Assert(findStore(&forecast).LowSpaceDate, Equals, cluster.ForecastUnknown)
+	for _, days := range []string{"0", "181", "abc"} {
+		c.Assert(readJSON(forecastURL+"?days="+days, &forecast), NotNil)
+	}
+}
+
+func (s *testStatsSuite) TestCrossZoneTraffic(c *C) {
+	trafficURL := s.urlPrefix + "/stats/cross-zone-traffic"
+	var traffic cluster.CrossZoneTraffic
+	c.Assert(readJSON(trafficURL, &traffic), IsNil)
+	c.Assert(traffic.ZoneLabel, Equals, cluster.DefaultZoneLabel)
+	c.Assert(traffic.TopRegions, NotNil)
+	c.Assert(readJSON(trafficURL+"?label=rack&top=1", &traffic), IsNil)
+	c.Assert(traffic.ZoneLabel, Equals, "rack")
+	for _, top := range []string{"-1", "1001", "abc"} {
+		c.Assert(readJSON(trafficURL+"?top="+top, &traffic), NotNil)
+	}
+}
+
+func (s *testStatsSuite) TestBalanceScore(c *C) {
+	var score cluster.BalanceScore
+	c.Assert(readJSON(s.urlPrefix+"/stats/balance-score", &score), IsNil)
+	c.Assert(score.Method, Equals, cluster.BalanceScoreMethod)
+	c.Assert(score.Stores, Greater, 0)
+	c.Assert(score.UpdateTime.IsZero(), IsFalse)
+}
+
+func (s *testStatsSuite) TestMetricsSummary(c *C) {
+	summaryURL := s.urlPrefix + "/metrics/summary"
+	mustPutStore(c, s.svr, 200, metapb.StoreState_Up, nil)
+	region := &metapb.Region{
+		Id:          200,
+		StartKey:    []byte("m"),
+		EndKey:      []byte("n"),
+		Peers:       []*metapb.Peer{{Id: 201, StoreId: 200}},
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}
+	mustRegionHeartbeat(c, s.svr, core.NewRegionInfo(region, region.Peers[0]))
+
+	var summary server.MetricsSummary
+	c.Assert(readJSON(summaryURL, &summary), IsNil)
+	c.Assert(summary.StoreHeartbeats.Window.Duration, Equals, cluster.HeartbeatRateWindow)
+	c.Assert(summary.StoreHeartbeats.Rate, Greater, 0.0)
+	c.Assert(summary.RegionHeartbeats.Window.Duration, Equals, cluster.HeartbeatRateWindow)
+	c.Assert(summary.RegionHeartbeats.Rate, Greater, 0.0)
+	c.Assert(summary.TSORequests.Window.Duration, Equals, server.TSORateWindow)
+	c.Assert(summary.OperatorsCreated.Window.Duration, Equals, schedule.OperatorCountWindow)
+	c.Assert(summary.OperatorsFinished.Window.Duration, Equals, schedule.OperatorCountWindow)
+	c.Assert(summary.RegionCount, GreaterEqual, 1)
+	c.Assert(summary.LeaderCount, GreaterEqual, 1)
+	c.Assert(summary.AbnormalRegions, HasLen, 7)
+}
@@ -16,7 +16,9 @@ package api
 import (
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -25,9 +27,12 @@ import (
 	"github.com/pingcap/pd/v4/pkg/apiutil"
 	"github.com/pingcap/pd/v4/pkg/typeutil"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/pingcap/pd/v4/server/config"
 	"github.com/pingcap/pd/v4/server/core"
 	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"github.com/pingcap/pd/v4/server/statistics"
 	"github.com/pkg/errors"
 	"github.com/unrolled/render"
 )
@@ -35,7 +40,9 @@ import (
 // MetaStore contains meta information about a store.
 type MetaStore struct {
 	*metapb.Store
-	StateName string `json:"state_name"`
+	StateName string            `json:"state_name"`
+	Nickname  string            `json:"nickname,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
 }
 
 // StoreStatus contains status about a store.
@@ -58,6 +65,15 @@ type StoreStatus struct {
 	StartTS            *time.Time         `json:"start_ts,omitempty"`
 	LastHeartbeatTS    *time.Time         `json:"last_heartbeat_ts,omitempty"`
 	Uptime             *typeutil.Duration `json:"uptime,omitempty"`
+	RestartCount       int                `json:"restart_count,omitempty"`
+	LastRestartTS      *time.Time         `json:"last_restart_ts,omitempty"`
+	IsFlapping         bool               `json:"is_flapping,omitempty"`
+	// EffectiveSpaceFloor is the larger one of the free space required by the
+	// low-space-ratio and the space floor of the store, and the store is not a
+	// target of the new replicas if SpaceFloorBinding.
+	SpaceFloor          *core.StoreSpaceFloor `json:"space_floor,omitempty"`
+	EffectiveSpaceFloor typeutil.ByteSize     `json:"effective_space_floor"`
+	SpaceFloorBinding   bool                  `json:"space_floor_binding,omitempty"`
 }
 
 // StoreInfo contains information about a store.
@@ -96,6 +112,21 @@ func newStoreInfo(opt *config.ScheduleConfig, store *core.StoreInfo) *StoreInfo
 		},
 	}
 
+	if annotation := store.GetAnnotation(); annotation != nil {
+		s.Store.Nickname = annotation.Nickname
+		s.Store.Metadata = annotation.Metadata
+	}
+
+	lowSpaceFloor := uint64(float64(store.GetCapacity()) * (1 - opt.LowSpaceRatio))
+	s.Status.EffectiveSpaceFloor = typeutil.ByteSize(lowSpaceFloor)
+	if floor := store.GetSpaceFloor(); floor != nil {
+		s.Status.SpaceFloor = floor
+		if bytes := floor.Bytes(store.GetCapacity()); bytes > lowSpaceFloor {
+			s.Status.EffectiveSpaceFloor = typeutil.ByteSize(bytes)
+		}
+	}
+	s.Status.SpaceFloorBinding = store.IsLowSpace(opt.LowSpaceRatio) || store.IsBelowSpaceFloor()
+
 	if store.GetStoreStats() != nil {
 		startTS := store.GetStartTime()
 		s.Status.StartTS = &startTS
@@ -107,6 +138,12 @@ func newStoreInfo(opt *config.ScheduleConfig, store *core.StoreInfo) *StoreInfo
 		duration := typeutil.NewDuration(upTime)
 		s.Status.Uptime = &duration
 	}
+	if restarts := store.GetRestarts(); restarts != nil {
+		lastRestart := restarts.GetLastRestart()
+		s.Status.RestartCount = restarts.Count
+		s.Status.LastRestartTS = &lastRestart
+		s.Status.IsFlapping = store.IsFlapping()
+	}
 
 	if store.GetState() == metapb.StoreState_Up {
 		if store.DownTime() > opt.MaxStoreDownTime.Duration {
@@ -120,7 +157,9 @@ func newStoreInfo(opt *config.ScheduleConfig, store *core.StoreInfo) *StoreInfo
 
 // StoresInfo records stores' info.
 type StoresInfo struct {
-	Count  int          `json:"count"`
+	Count int `json:"count"`
+	// Total is the number of stores before paginating.
+	Total  int          `json:"total"`
 	Stores []*StoreInfo `json:"stores"`
 }
 
@@ -136,12 +175,76 @@ func newStoreHandler(handler *server.Handler, rd *render.Render) *storeHandler {
 	}
 }
 
+// storeAddressPrefix marks a store in the path which is given by the address
+// instead of the ID, such as "address:127.0.0.1:20160".
+const storeAddressPrefix = "address:"
+
+// resolveStoreID returns the store ID in the path variable "id", which is
+// either an ID or an address with storeAddressPrefix.
+func resolveStoreID(rc *cluster.RaftCluster, vars map[string]string) (uint64, error) {
+	if id := vars["id"]; strings.HasPrefix(id, storeAddressPrefix) {
+		store, err := resolveStoreByAddress(rc, strings.TrimPrefix(id, storeAddressPrefix))
+		if err != nil {
+			return 0, err
+		}
+		return store.GetID(), nil
+	}
+	storeID, err := apiutil.ParseStoreIDParam(vars["id"])
+	if err != nil {
+		return 0, errcode.NewInvalidInputErr(err)
+	}
+	return storeID, nil
+}
+
+// resolveStoreByAddress returns the store with the address. As the address of
+// a tombstone store can be reused by a new store, the stores which are not
+// tombstone are preferred.
+func resolveStoreByAddress(rc *cluster.RaftCluster, address string) (*core.StoreInfo, error) {
+	var alive, tombstones []*core.StoreInfo
+	for _, store := range rc.GetStores() {
+		if store.GetAddress() != address {
+			continue
+		}
+		if store.IsTombstone() {
+			tombstones = append(tombstones, store)
+		} else {
+			alive = append(alive, store)
+		}
+	}
+	candidates := alive
+	if len(candidates) == 0 {
+		candidates = tombstones
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, errcode.NewNotFoundErr(errors.Errorf("store with address %s not found", address))
+	case 1:
+		return candidates[0], nil
+	}
+	ids := make([]uint64, 0, len(candidates))
+	for _, store := range candidates {
+		ids = append(ids, store.GetID())
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return nil, &core.StoreAddressConflictErr{Address: address, Candidates: ids}
+}
+
+// GetByAddress returns the store with the address in the path.
+func (h *storeHandler) GetByAddress(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	store, err := resolveStoreByAddress(rc, mux.Vars(r)["address"])
+	if err != nil {
+		apiutil.ErrorResp(h.rd, w, err)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, newStoreInfo(h.GetScheduleConfig(), store))
+}
+
 func (h *storeHandler) Get(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r.Context())
-	vars := mux.Vars(r)
-	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
-	if errParse != nil {
-		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+	storeID, errResolve := resolveStoreID(rc, mux.Vars(r))
+	if errResolve != nil {
+		apiutil.ErrorResp(h.rd, w, errResolve)
 		return
 	}
 
@@ -155,17 +258,181 @@ func (h *storeHandler) Get(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, storeInfo)
 }
 
+// StoreResidualPeers contains the cached regions which still have peers on
+// an offline or tombstone store.
+type StoreResidualPeers struct {
+	Count   int           `json:"count"`
+	Regions []*RegionInfo `json:"regions"`
+}
+
+func (h *storeHandler) GetResidualPeers(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	storeID, errResolve := resolveStoreID(rc, mux.Vars(r))
+	if errResolve != nil {
+		apiutil.ErrorResp(h.rd, w, errResolve)
+		return
+	}
+	limit := defaultRegionLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if limit > maxRegionLimit {
+		limit = maxRegionLimit
+	}
+
+	regions, err := rc.GetStoreResidualRegions(storeID)
+	if err != nil {
+		apiutil.ErrorResp(h.rd, w, err)
+		return
+	}
+	count := len(regions)
+	if limit >= 0 && len(regions) > limit {
+		regions = regions[:limit]
+	}
+	h.rd.JSON(w, http.StatusOK, &StoreResidualPeers{
+		Count:   count,
+		Regions: convertToAPIRegions(regions).Regions,
+	})
+}
+
+// GetOfflineImpact estimates the regions to be moved if the store is taken
+// offline. It is for planning and never creates operators.
+func (h *storeHandler) GetOfflineImpact(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	storeID, errResolve := resolveStoreID(rc, mux.Vars(r))
+	if errResolve != nil {
+		apiutil.ErrorResp(h.rd, w, errResolve)
+		return
+	}
+	impact, err := rc.GetStoreOfflineImpact(storeID)
+	if err != nil {
+		apiutil.ErrorResp(h.rd, w, err)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, impact)
+}
+
+// defaultHotRangeTop is the number of the hot ranges returned by default.
+const defaultHotRangeTop = 20
+
+// GetHotRanges returns the top contiguous key ranges made of the hot regions
+// on the store, by which the hot store is diagnosed.
+func (h *storeHandler) GetHotRanges(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	storeID, errResolve := resolveStoreID(rc, mux.Vars(r))
+	if errResolve != nil {
+		apiutil.ErrorResp(h.rd, w, errResolve)
+		return
+	}
+	query := r.URL.Query()
+	var kind statistics.FlowKind
+	switch query.Get("rw") {
+	case "", "write":
+		kind = statistics.WriteFlow
+	case "read":
+		kind = statistics.ReadFlow
+	default:
+		h.rd.JSON(w, http.StatusBadRequest, "rw should be read or write")
+		return
+	}
+	by := query.Get("by")
+	switch by {
+	case "":
+		by = cluster.HotRangeByByte
+	case cluster.HotRangeByByte, cluster.HotRangeByKey:
+	default:
+		h.rd.JSON(w, http.StatusBadRequest, "by should be byte or key")
+		return
+	}
+	top := defaultHotRangeTop
+	if topStr := query.Get("top"); topStr != "" {
+		var err error
+		top, err = strconv.Atoi(topStr)
+		if err != nil || top <= 0 {
+			h.rd.JSON(w, http.StatusBadRequest, "top should be a positive integer")
+			return
+		}
+	}
+	ranges, err := rc.GetStoreHotRanges(storeID, kind, by, top)
+	if err != nil {
+		apiutil.ErrorResp(h.rd, w, err)
+		return
+	}
+	if ranges == nil {
+		ranges = []*cluster.StoreHotRange{}
+	}
+	h.rd.JSON(w, http.StatusOK, ranges)
+}
+
+// StoreOperators contains the running and waiting operators involving a store.
+type StoreOperators struct {
+	StoreID uint64               `json:"store_id"`
+	Running []*operator.Operator `json:"running"`
+	Waiting []*operator.Operator `json:"waiting"`
+}
+
+func (h *storeHandler) GetOperators(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	storeID, errResolve := resolveStoreID(rc, mux.Vars(r))
+	if errResolve != nil {
+		apiutil.ErrorResp(h.rd, w, errResolve)
+		return
+	}
+	if rc.GetStore(storeID) == nil {
+		apiutil.ErrorResp(h.rd, w, core.NewStoreNotFoundErr(storeID))
+		return
+	}
+	oc := rc.GetOperatorController()
+	result := &StoreOperators{
+		StoreID: storeID,
+		Running: make([]*operator.Operator, 0),
+		Waiting: make([]*operator.Operator, 0),
+	}
+	for _, op := range oc.GetStoreOperators(storeID) {
+		if oc.GetOperator(op.RegionID()) == op {
+			result.Running = append(result.Running, op)
+		} else {
+			result.Waiting = append(result.Waiting, op)
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, result)
+}
+
 func (h *storeHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r.Context())
-	vars := mux.Vars(r)
-	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
-	if errParse != nil {
-		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+	storeID, errResolve := resolveStoreID(rc, mux.Vars(r))
+	if errResolve != nil {
+		apiutil.ErrorResp(h.rd, w, errResolve)
 		return
 	}
 
 	var err error
 	_, force := r.URL.Query()["force"]
+	if dryRunStr := r.URL.Query().Get("dry_run"); dryRunStr != "" {
+		dryRun, err := strconv.ParseBool(dryRunStr)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid dry_run value")
+			return
+		}
+		if dryRun {
+			if force {
+				h.rd.JSON(w, http.StatusBadRequest, "dry_run is not supported with force")
+				return
+			}
+			res, err := rc.DryRunRemoveStore(storeID)
+			if err != nil {
+				apiutil.ErrorResp(h.rd, w, err)
+				return
+			}
+			h.rd.JSON(w, http.StatusOK, res)
+			return
+		}
+	}
 	if force {
 		err = rc.BuryStore(storeID, force)
 	} else {
@@ -176,16 +443,21 @@ func (h *storeHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		apiutil.ErrorResp(h.rd, w, err)
 		return
 	}
+	if reason := r.URL.Query().Get("reason"); reason != "" {
+		if err := rc.GetStoreHistory().SetReason(storeID, reason); err != nil {
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
 
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
 func (h *storeHandler) SetState(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r.Context())
-	vars := mux.Vars(r)
-	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
-	if errParse != nil {
-		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+	storeID, errResolve := resolveStoreID(rc, mux.Vars(r))
+	if errResolve != nil {
+		apiutil.ErrorResp(h.rd, w, errResolve)
 		return
 	}
 
@@ -207,10 +479,9 @@ func (h *storeHandler) SetState(w http.ResponseWriter, r *http.Request) {
 
 func (h *storeHandler) SetLabels(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r.Context())
-	vars := mux.Vars(r)
-	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
-	if errParse != nil {
-		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+	storeID, errResolve := resolveStoreID(rc, mux.Vars(r))
+	if errResolve != nil {
+		apiutil.ErrorResp(h.rd, w, errResolve)
 		return
 	}
 
@@ -243,10 +514,9 @@ func (h *storeHandler) SetLabels(w http.ResponseWriter, r *http.Request) {
 
 func (h *storeHandler) SetWeight(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r.Context())
-	vars := mux.Vars(r)
-	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
-	if errParse != nil {
-		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+	storeID, errResolve := resolveStoreID(rc, mux.Vars(r))
+	if errResolve != nil {
+		apiutil.ErrorResp(h.rd, w, errResolve)
 		return
 	}
 
@@ -284,11 +554,61 @@ func (h *storeHandler) SetWeight(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
+func (h *storeHandler) SetAnnotation(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	storeID, errResolve := resolveStoreID(rc, mux.Vars(r))
+	if errResolve != nil {
+		apiutil.ErrorResp(h.rd, w, errResolve)
+		return
+	}
+
+	var annotation core.StoreAnnotation
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &annotation); err != nil {
+		return
+	}
+	if err := annotation.Validate(); err != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(err))
+		return
+	}
+
+	if err := rc.SetStoreAnnotation(storeID, &annotation); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func (h *storeHandler) SetSpaceFloor(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	storeID, errResolve := resolveStoreID(rc, mux.Vars(r))
+	if errResolve != nil {
+		apiutil.ErrorResp(h.rd, w, errResolve)
+		return
+	}
+
+	var floor core.StoreSpaceFloor
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &floor); err != nil {
+		return
+	}
+	if err := floor.Validate(); err != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(err))
+		return
+	}
+
+	if err := rc.SetStoreSpaceFloor(storeID, &floor); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
 func (h *storeHandler) SetLimit(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
-	if errParse != nil {
-		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+	rc := getCluster(r.Context())
+	storeID, errResolve := resolveStoreID(rc, mux.Vars(r))
+	if errResolve != nil {
+		apiutil.ErrorResp(h.rd, w, errResolve)
 		return
 	}
 
@@ -316,6 +636,24 @@ func (h *storeHandler) SetLimit(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
+// RemoveLimit removes the manual balance rate limit of the store, so that the
+// limit is set by PD itself by the scene of the cluster load again.
+func (h *storeHandler) RemoveLimit(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	storeID, errResolve := resolveStoreID(rc, mux.Vars(r))
+	if errResolve != nil {
+		apiutil.ErrorResp(h.rd, w, errResolve)
+		return
+	}
+
+	if err := h.RemoveStoreLimit(storeID); err != nil {
+		apiutil.ErrorResp(h.rd, w, err)
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
 type storesHandler struct {
 	*server.Handler
 	rd *render.Render
@@ -330,7 +668,8 @@ func newStoresHandler(handler *server.Handler, rd *render.Render) *storesHandler
 
 func (h *storesHandler) RemoveTombStone(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r.Context())
-	err := rc.RemoveTombStoneRecords()
+	_, force := r.URL.Query()["force"]
+	err := rc.RemoveTombStoneRecords(force)
 	if err != nil {
 		apiutil.ErrorResp(h.rd, w, err)
 		return
@@ -364,6 +703,16 @@ func (h *storesHandler) SetAllLimit(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, nil)
 }
 
+// RemoveAllLimit removes the manual balance rate limits of all stores.
+func (h *storesHandler) RemoveAllLimit(w http.ResponseWriter, r *http.Request) {
+	if err := h.RemoveAllStoresLimit(); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
 func (h *storesHandler) GetAllLimit(w http.ResponseWriter, r *http.Request) {
 	limits, err := h.GetAllStoresLimit()
 	if err != nil {
@@ -373,18 +722,105 @@ func (h *storesHandler) GetAllLimit(w http.ResponseWriter, r *http.Request) {
 	type LimitResp struct {
 		Rate float64 `json:"rate"`
 		Mode string  `json:"mode"`
+		// Source is "persisted" for the manual limits, which survive the
+		// restarts, or "default" for the ones set by PD itself.
+		Source string `json:"source"`
 	}
 	resp := make(map[uint64]*LimitResp)
 	for s, l := range limits {
+		source := "default"
+		if l.Mode() == schedule.StoreLimitManual {
+			source = "persisted"
+		}
 		resp[s] = &LimitResp{
-			Rate: l.Rate() * schedule.StoreBalanceBaseTime,
-			Mode: l.Mode().String(),
+			Rate:   l.Rate() * schedule.StoreBalanceBaseTime,
+			Mode:   l.Mode().String(),
+			Source: source,
 		}
 	}
 
 	h.rd.JSON(w, http.StatusOK, resp)
 }
 
+// GetLimitForecast returns the forecast of the store limits, which takes the
+// waiting operators into account.
+func (h *storesHandler) GetLimitForecast(w http.ResponseWriter, r *http.Request) {
+	budgets, err := h.GetStoreBudgets()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, budgets)
+}
+
+// The problems of the stores.
+const (
+	storeProblemDisconnected = "disconnected"
+	storeProblemDown         = "down"
+	storeProblemBusy         = "busy"
+	storeProblemLowSpace     = "low-space"
+	storeProblemFlapping     = "flapping"
+)
+
+// StoreProblems contains the problems of a store which affect the scheduling.
+type StoreProblems struct {
+	StoreID  uint64   `json:"store_id"`
+	Address  string   `json:"address"`
+	Problems []string `json:"problems"`
+	// FlappingUntil is the time until which the store is not selected as the
+	// target of the schedules for restarting too frequently.
+	FlappingUntil *time.Time `json:"flapping_until,omitempty"`
+}
+
+func (h *storesHandler) GetProblems(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	opt := h.GetScheduleConfig()
+	problems := make([]*StoreProblems, 0)
+	for _, store := range rc.GetStores() {
+		if store.IsTombstone() {
+			continue
+		}
+		p := &StoreProblems{StoreID: store.GetID(), Address: store.GetAddress()}
+		if store.DownTime() > opt.MaxStoreDownTime.Duration {
+			p.Problems = append(p.Problems, storeProblemDown)
+		} else if store.IsDisconnected() {
+			p.Problems = append(p.Problems, storeProblemDisconnected)
+		}
+		if store.IsBusy() {
+			p.Problems = append(p.Problems, storeProblemBusy)
+		}
+		if store.IsLowSpace(opt.LowSpaceRatio) {
+			p.Problems = append(p.Problems, storeProblemLowSpace)
+		}
+		if store.IsFlapping() {
+			p.Problems = append(p.Problems, storeProblemFlapping)
+			until := store.GetRestarts().FlappingUntil
+			p.FlappingUntil = &until
+		}
+		if len(p.Problems) > 0 {
+			problems = append(problems, p)
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].StoreID < problems[j].StoreID })
+	h.rd.JSON(w, http.StatusOK, problems)
+}
+
+// GetHistory returns the lifecycle records of the stores, including the ones
+// of the removed stores if include_removed is true.
+func (h *storesHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	var includeRemoved bool
+	if value := r.URL.Query().Get("include_removed"); value != "" {
+		var err error
+		includeRemoved, err = strconv.ParseBool(value)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid include_removed value")
+			return
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, rc.GetStoreHistory().GetRecords(includeRemoved))
+}
+
 func (h *storesHandler) SetStoreLimitScene(w http.ResponseWriter, r *http.Request) {
 	scene := h.Handler.GetStoreLimitScene()
 	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &scene); err != nil {
@@ -411,6 +847,11 @@ func (h *storesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	listOption, err := newStoreListOption(r.URL)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	stores = urlFilter.filter(rc.GetMetaStores())
 	for _, s := range stores {
@@ -424,11 +865,94 @@ func (h *storesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		storeInfo := newStoreInfo(h.GetScheduleConfig(), store)
 		StoresInfo.Stores = append(StoresInfo.Stores, storeInfo)
 	}
+	StoresInfo.Total = len(StoresInfo.Stores)
+	StoresInfo.Stores = listOption.apply(StoresInfo.Stores)
 	StoresInfo.Count = len(StoresInfo.Stores)
 
 	h.rd.JSON(w, http.StatusOK, StoresInfo)
 }
 
+// storeSortKeys are the fields that the store list can be sorted by.
+var storeSortKeys = map[string]func(*StoreInfo) float64{
+	"leader_count": func(s *StoreInfo) float64 { return float64(s.Status.LeaderCount) },
+	"region_count": func(s *StoreInfo) float64 { return float64(s.Status.RegionCount) },
+	"leader_score": func(s *StoreInfo) float64 { return s.Status.LeaderScore },
+	"region_score": func(s *StoreInfo) float64 { return s.Status.RegionScore },
+	"available":    func(s *StoreInfo) float64 { return float64(s.Status.Available) },
+	"uptime": func(s *StoreInfo) float64 {
+		if s.Status.Uptime == nil {
+			return 0
+		}
+		return float64(s.Status.Uptime.Duration)
+	},
+}
+
+type storeListOption struct {
+	sortKey func(*StoreInfo) float64
+	desc    bool
+	offset  int
+	// limit is 0 if the result is not limited.
+	limit int
+}
+
+func newStoreListOption(u *url.URL) (*storeListOption, error) {
+	query := u.Query()
+	option := &storeListOption{}
+	if sortBy := query.Get("sort_by"); sortBy != "" {
+		sortKey, ok := storeSortKeys[sortBy]
+		if !ok {
+			keys := make([]string, 0, len(storeSortKeys))
+			for k := range storeSortKeys {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return nil, errors.Errorf("invalid sort_by %q, should be one of [%s]", sortBy, strings.Join(keys, ", "))
+		}
+		option.sortKey = sortKey
+	}
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		option.desc = true
+	default:
+		return nil, errors.Errorf("invalid order %q, should be asc or desc", order)
+	}
+	var err error
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		if option.offset, err = strconv.Atoi(offsetStr); err != nil || option.offset < 0 {
+			return nil, errors.Errorf("invalid offset %q", offsetStr)
+		}
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if option.limit, err = strconv.Atoi(limitStr); err != nil || option.limit <= 0 {
+			return nil, errors.Errorf("invalid limit %q", limitStr)
+		}
+	}
+	return option, nil
+}
+
+// apply sorts the stores and returns the requested page. Stores with the same
+// sort key are ordered by ID so that the pages are stable.
+func (o *storeListOption) apply(stores []*StoreInfo) []*StoreInfo {
+	sort.Slice(stores, func(i, j int) bool {
+		if o.sortKey != nil {
+			ki, kj := o.sortKey(stores[i]), o.sortKey(stores[j])
+			if ki != kj {
+				return (ki < kj) != o.desc
+			}
+		}
+		return stores[i].Store.GetId() < stores[j].Store.GetId()
+	})
+	if o.offset >= len(stores) {
+		return stores[:0]
+	}
+	stores = stores[o.offset:]
+	if o.limit > 0 && o.limit < len(stores) {
+		stores = stores[:o.limit]
+	}
+	return stores
+}
+
 type storeStateFilter struct {
 	accepts []metapb.StoreState
 }
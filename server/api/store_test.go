@@ -28,9 +28,13 @@ import (
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/pkg/typeutil"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/pingcap/pd/v4/server/config"
 	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
 )
 
 var _ = Suite(&testStoreSuite{})
@@ -142,6 +146,83 @@ func (s *testStoreSuite) TestStoresList(c *C) {
 
 }
 
+func (s *testStoreSuite) TestStoresListPagination(c *C) {
+	info := new(StoresInfo)
+	err := readJSON(fmt.Sprintf("%s/stores?state=0&state=1&order=desc&offset=1&limit=1", s.urlPrefix), info)
+	c.Assert(err, IsNil)
+	c.Assert(info.Total, Equals, 3)
+	c.Assert(info.Count, Equals, 1)
+	checkStoresInfo(c, info.Stores, s.stores[1:2])
+
+	info = new(StoresInfo)
+	err = readJSON(fmt.Sprintf("%s/stores?offset=5", s.urlPrefix), info)
+	c.Assert(err, IsNil)
+	c.Assert(info.Total, Equals, 3)
+	c.Assert(info.Stores, HasLen, 0)
+
+	for _, query := range []string{"sort_by=capacity", "order=up", "limit=0", "offset=-1"} {
+		code, body := requestStatusBody(c, &http.Client{}, http.MethodGet, fmt.Sprintf("%s/stores?%s", s.urlPrefix, query))
+		c.Assert(code, Equals, http.StatusBadRequest)
+		if query == "sort_by=capacity" {
+			c.Assert(strings.Contains(string(body), "available, leader_count, leader_score, region_count, region_score, uptime"), IsTrue)
+		}
+	}
+}
+
+func (s *testStoreSuite) TestStoreListOption(c *C) {
+	newStore := func(id uint64, count int, score float64, available uint64, uptime time.Duration) *StoreInfo {
+		duration := typeutil.NewDuration(uptime)
+		return &StoreInfo{
+			Store: &MetaStore{Store: &metapb.Store{Id: id}},
+			Status: &StoreStatus{
+				LeaderCount: count,
+				RegionCount: count,
+				LeaderScore: score,
+				RegionScore: score,
+				Available:   typeutil.ByteSize(available),
+				Uptime:      &duration,
+			},
+		}
+	}
+	newStores := func() []*StoreInfo {
+		return []*StoreInfo{
+			newStore(3, 30, 1, 100, time.Hour),
+			newStore(1, 10, 3, 300, time.Minute),
+			newStore(2, 20, 3, 200, 2*time.Hour),
+			newStore(4, 20, 2, 400, time.Second),
+		}
+	}
+	storeIDs := func(stores []*StoreInfo) []uint64 {
+		ids := make([]uint64, 0, len(stores))
+		for _, s := range stores {
+			ids = append(ids, s.Store.GetId())
+		}
+		return ids
+	}
+	testCases := []struct {
+		query  string
+		expect []uint64
+	}{
+		{"", []uint64{1, 2, 3, 4}},
+		{"sort_by=leader_count", []uint64{1, 2, 4, 3}},
+		{"sort_by=region_count&order=desc", []uint64{3, 2, 4, 1}},
+		{"sort_by=leader_score", []uint64{3, 4, 1, 2}},
+		{"sort_by=region_score&order=desc", []uint64{1, 2, 4, 3}},
+		{"sort_by=available&order=desc", []uint64{4, 1, 2, 3}},
+		{"sort_by=uptime", []uint64{4, 1, 3, 2}},
+		{"sort_by=region_score&order=desc&limit=2", []uint64{1, 2}},
+		{"sort_by=region_score&order=desc&offset=2&limit=2", []uint64{4, 3}},
+		{"sort_by=region_score&order=desc&offset=3&limit=2", []uint64{3}},
+	}
+	for _, t := range testCases {
+		u, err := url.Parse("/stores?" + t.query)
+		c.Assert(err, IsNil)
+		option, err := newStoreListOption(u)
+		c.Assert(err, IsNil)
+		c.Assert(storeIDs(option.apply(newStores())), DeepEquals, t.expect, Commentf("query: %s", t.query))
+	}
+}
+
 func (s *testStoreSuite) TestStoreGet(c *C) {
 	url := fmt.Sprintf("%s/store/1", s.urlPrefix)
 	s.svr.StoreHeartbeat(
@@ -188,7 +269,7 @@ func (s *testStoreSuite) TestStoreLabel(c *C) {
 	c.Assert(strings.Contains(err.Error(), "key matching the label was not found"), IsTrue)
 	locationLabels := map[string]string{"location-labels": "zone,host"}
 	ll, _ := json.Marshal(locationLabels)
-	err = postJSON(s.urlPrefix+"/config", ll)
+	err = postJSON(s.urlPrefix+"/config?confirm=true", ll)
 	c.Assert(err, IsNil)
 	time.Sleep(20 * time.Millisecond)
 	err = postJSON(url+"/label", b)
@@ -260,6 +341,190 @@ func (s *testStoreSuite) TestStoreDelete(c *C) {
 	}
 }
 
+func (s *testStoreSuite) TestStoreDeleteDryRun(c *C) {
+	r := newTestRegionInfo(300, 1, []byte("dry-run-a"), []byte("dry-run-b"))
+	mustRegionHeartbeat(c, s.svr, r)
+	rc := s.svr.GetRaftCluster()
+
+	url := fmt.Sprintf("%s/store/1?dry_run=true", s.urlPrefix)
+	status, body := requestStatusBody(c, dialClient, http.MethodDelete, url)
+	c.Assert(status, Equals, http.StatusOK)
+	dryRun := &cluster.StoreRemovalDryRun{}
+	c.Assert(json.Unmarshal(body, dryRun), IsNil)
+	c.Assert(dryRun.StoreID, Equals, uint64(1))
+	// The stores left up cannot hold the replacements, which the batch
+	// offline refuses too.
+	var found bool
+	for _, id := range dryRun.UnplaceableRegions {
+		found = found || id == r.GetID()
+	}
+	c.Assert(found, IsTrue)
+	expected, err := rc.DryRunRemoveStore(1)
+	c.Assert(err, IsNil)
+	c.Assert(dryRun.Refused, IsTrue)
+	c.Assert(dryRun.RefusedReason, Equals, expected.RefusedReason)
+	c.Assert(rc.ValidateBatchOffline(&cluster.BatchOfflineConfig{StoreIDs: []uint64{1}}), NotNil)
+	c.Assert(rc.GetStore(1).IsUp(), IsTrue)
+
+	for _, t := range []struct {
+		url    string
+		status int
+	}{
+		{fmt.Sprintf("%s/store/1?dry_run=maybe", s.urlPrefix), http.StatusBadRequest},
+		{fmt.Sprintf("%s/store/1?dry_run=true&force", s.urlPrefix), http.StatusBadRequest},
+		{fmt.Sprintf("%s/store/100?dry_run=true", s.urlPrefix), http.StatusNotFound},
+		{fmt.Sprintf("%s/store/7?dry_run=true", s.urlPrefix), http.StatusGone},
+	} {
+		status, _ := requestStatusBody(c, dialClient, http.MethodDelete, t.url)
+		c.Assert(status, Equals, t.status, Commentf("%s", t.url))
+	}
+	c.Assert(rc.GetStore(1).IsUp(), IsTrue)
+}
+
+func (s *testStoreSuite) TestResidualPeers(c *C) {
+	status, _ := requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/store/1/residual-peers", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusBadRequest)
+	status, _ = requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/store/100/residual-peers", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusNotFound)
+
+	// The region still has a peer on the tombstone store 7.
+	r := newTestRegionInfo(100, 1, []byte("residual-a"), []byte("residual-b"))
+	r = r.Clone(core.WithAddPeer(&metapb.Peer{Id: 101, StoreId: 7}))
+	mustRegionHeartbeat(c, s.svr, r)
+	url := fmt.Sprintf("%s/store/7/residual-peers", s.urlPrefix)
+	residual := &StoreResidualPeers{}
+	c.Assert(readJSON(url, residual), IsNil)
+	c.Assert(residual.Count, Equals, 1)
+	c.Assert(residual.Regions, HasLen, 1)
+	c.Assert(residual.Regions[0].ID, Equals, r.GetID())
+	residual = &StoreResidualPeers{}
+	c.Assert(readJSON(url+"?limit=0", residual), IsNil)
+	c.Assert(residual.Count, Equals, 1)
+	c.Assert(residual.Regions, HasLen, 0)
+
+	// Refuse to remove the tombstone store with residual peers.
+	status, _ = requestStatusBody(c, dialClient, http.MethodDelete, fmt.Sprintf("%s/stores/remove-tombstone", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusConflict)
+	c.Assert(s.svr.GetRaftCluster().GetStore(7), NotNil)
+
+	// The residual peers drain after the peer is removed.
+	r = r.Clone(core.WithRemoveStorePeer(7), core.WithIncConfVer())
+	mustRegionHeartbeat(c, s.svr, r)
+	residual = &StoreResidualPeers{}
+	c.Assert(readJSON(url, residual), IsNil)
+	c.Assert(residual.Count, Equals, 0)
+}
+
+func (s *testStoreSuite) TestOfflineImpact(c *C) {
+	status, _ := requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/store/100/offline-impact", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusNotFound)
+
+	r := newTestRegionInfo(200, 1, []byte("impact-a"), []byte("impact-b"))
+	mustRegionHeartbeat(c, s.svr, r)
+	impact := &cluster.StoreOfflineImpact{}
+	c.Assert(readJSON(fmt.Sprintf("%s/store/1/offline-impact", s.urlPrefix), impact), IsNil)
+	c.Assert(impact.StoreID, Equals, uint64(1))
+	c.Assert(impact.Leaders.Count, GreaterEqual, 1)
+	// The stores report no capacity, so no store can take the replacement.
+	c.Assert(impact.Unplaceable.Count, Equals, len(impact.UnplaceableRegions))
+	var found bool
+	for _, id := range impact.UnplaceableRegions {
+		found = found || id == r.GetID()
+	}
+	c.Assert(found, IsTrue)
+	c.Assert(s.svr.GetRaftCluster().GetOperatorController().GetOperator(r.GetID()), IsNil)
+}
+
+func (s *testStoreSuite) TestStoreOperators(c *C) {
+	status, _ := requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/store/100/operators", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusNotFound)
+
+	r := newTestRegionInfo(210, 1, []byte("operators-a"), []byte("operators-b"))
+	mustRegionHeartbeat(c, s.svr, r)
+	oc := s.svr.GetRaftCluster().GetOperatorController()
+	op := operator.NewOperator("test", "test", r.GetID(), r.GetRegionEpoch(), operator.OpRegion,
+		operator.AddPeer{ToStore: 4, PeerID: 211}, operator.RemovePeer{FromStore: 1})
+	c.Assert(op.Start(), IsTrue)
+	oc.SetOperator(op)
+	defer oc.RemoveOperator(op)
+
+	// The operators are marshaled as their descriptions.
+	type storeOperators struct {
+		StoreID uint64   `json:"store_id"`
+		Running []string `json:"running"`
+		Waiting []string `json:"waiting"`
+	}
+	for _, id := range []uint64{1, 4} {
+		var ops storeOperators
+		c.Assert(readJSON(fmt.Sprintf("%s/store/%d/operators", s.urlPrefix, id), &ops), IsNil)
+		c.Assert(ops.StoreID, Equals, id)
+		c.Assert(ops.Running, HasLen, 1)
+		c.Assert(ops.Waiting, HasLen, 0)
+	}
+	var ops storeOperators
+	c.Assert(readJSON(fmt.Sprintf("%s/store/6/operators", s.urlPrefix), &ops), IsNil)
+	c.Assert(ops.Running, HasLen, 0)
+}
+
+func (s *testStoreSuite) TestLimitForecast(c *C) {
+	c.Assert(s.svr.GetHandler().SetStoreLimit(1, 2), IsNil)
+	var budgets []*schedule.StoreBudget
+	c.Assert(readJSON(fmt.Sprintf("%s/stores/limit/forecast", s.urlPrefix), &budgets), IsNil)
+	var found bool
+	for _, budget := range budgets {
+		if budget.StoreID != 1 {
+			continue
+		}
+		found = true
+		c.Assert(budget.Waiting, Equals, float64(0))
+		c.Assert(budget.Remaining, Equals, budget.Available)
+	}
+	c.Assert(found, IsTrue)
+}
+
+func (s *testStoreSuite) TestGetAllLimit(c *C) {
+	c.Assert(s.svr.GetHandler().SetStoreLimit(1, 2), IsNil)
+	var limits map[string]struct {
+		Mode   string `json:"mode"`
+		Source string `json:"source"`
+	}
+	c.Assert(readJSON(fmt.Sprintf("%s/stores/limit", s.urlPrefix), &limits), IsNil)
+	c.Assert(limits["1"].Mode, Equals, "manual")
+	c.Assert(limits["1"].Source, Equals, "persisted")
+}
+
+func (s *testStoreSuite) TestRemoveLimit(c *C) {
+	type limitResp struct {
+		Mode string `json:"mode"`
+	}
+	getLimits := func() map[string]limitResp {
+		var limits map[string]limitResp
+		c.Assert(readJSON(fmt.Sprintf("%s/stores/limit", s.urlPrefix), &limits), IsNil)
+		return limits
+	}
+	c.Assert(s.svr.GetHandler().SetStoreLimit(1, 2), IsNil)
+	c.Assert(s.svr.GetHandler().SetStoreLimit(4, 2), IsNil)
+
+	url := fmt.Sprintf("%s/store/1/limit", s.urlPrefix)
+	status, _ := requestStatusBody(c, dialClient, http.MethodDelete, url)
+	c.Assert(status, Equals, http.StatusOK)
+	limits := getLimits()
+	c.Assert(limits["1"].Mode, Equals, "auto")
+	c.Assert(limits["4"].Mode, Equals, "manual")
+	// Removing the limit of a store without a manual one is a no-op.
+	status, _ = requestStatusBody(c, dialClient, http.MethodDelete, url)
+	c.Assert(status, Equals, http.StatusOK)
+
+	status, _ = requestStatusBody(c, dialClient, http.MethodDelete, fmt.Sprintf("%s/store/7/limit", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusGone)
+	status, _ = requestStatusBody(c, dialClient, http.MethodDelete, fmt.Sprintf("%s/store/100/limit", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusNotFound)
+
+	status, _ = requestStatusBody(c, dialClient, http.MethodDelete, fmt.Sprintf("%s/stores/limit", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusOK)
+	c.Assert(getLimits()["4"].Mode, Equals, "auto")
+}
+
 func (s *testStoreSuite) TestStoreSetState(c *C) {
 	url := fmt.Sprintf("%s/store/1", s.urlPrefix)
 	info := StoreInfo{}
@@ -353,3 +618,255 @@ func (s *testStoreSuite) TestDownState(c *C) {
 	storeInfo = newStoreInfo(s.svr.GetScheduleConfig(), newStore)
 	c.Assert(storeInfo.Store.StateName, Equals, downStateName)
 }
+
+func (s *testStoreSuite) TestStoreRestarts(c *C) {
+	rc := s.svr.GetRaftCluster()
+	limit := s.svr.GetScheduleConfig().StoreFlappingRestartLimit
+	for startTime := uint32(1); startTime <= uint32(limit)+2; startTime++ {
+		c.Assert(rc.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: 4, StartTime: startTime, Capacity: 100 * units.GiB, Available: 100 * units.GiB}), IsNil)
+	}
+	info := new(StoreInfo)
+	c.Assert(readJSON(fmt.Sprintf("%s/store/4", s.urlPrefix), info), IsNil)
+	c.Assert(info.Status.RestartCount, Equals, int(limit)+1)
+	c.Assert(info.Status.LastRestartTS, NotNil)
+	c.Assert(info.Status.IsFlapping, IsTrue)
+
+	var problems []*StoreProblems
+	c.Assert(readJSON(fmt.Sprintf("%s/stores/problems", s.urlPrefix), &problems), IsNil)
+	var found bool
+	for _, p := range problems {
+		c.Assert(p.StoreID, Not(Equals), uint64(7))
+		if p.StoreID != 4 {
+			continue
+		}
+		found = true
+		c.Assert(p.Problems, DeepEquals, []string{storeProblemFlapping})
+		c.Assert(p.FlappingUntil, NotNil)
+		c.Assert(p.FlappingUntil.After(time.Now()), IsTrue)
+	}
+	c.Assert(found, IsTrue)
+}
+
+func (s *testStoreSuite) TestStoreSpaceFloor(c *C) {
+	rc := s.svr.GetRaftCluster()
+	c.Assert(rc.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: 1, Capacity: 100 * units.GiB, Available: 50 * units.GiB}), IsNil)
+	url := fmt.Sprintf("%s/store/1/space-floor", s.urlPrefix)
+	info := new(StoreInfo)
+	c.Assert(readJSON(fmt.Sprintf("%s/store/1", s.urlPrefix), info), IsNil)
+	c.Assert(info.Status.SpaceFloor, IsNil)
+	c.Assert(info.Status.EffectiveSpaceFloor, Equals, typeutil.ByteSize(20*units.GiB))
+	c.Assert(info.Status.SpaceFloorBinding, IsFalse)
+
+	c.Assert(postJSON(url, []byte(`{"min-free-percent": 101}`)), NotNil)
+	c.Assert(postJSON(url, []byte(`{"min-free-bytes": 10737418240, "min-free-percent": 60}`)), IsNil)
+	info = new(StoreInfo)
+	c.Assert(readJSON(fmt.Sprintf("%s/store/1", s.urlPrefix), info), IsNil)
+	c.Assert(info.Status.SpaceFloor, DeepEquals, &core.StoreSpaceFloor{MinFreeBytes: 10 * units.GiB, MinFreePercent: 60})
+	c.Assert(info.Status.EffectiveSpaceFloor, Equals, typeutil.ByteSize(60*units.GiB))
+	c.Assert(info.Status.SpaceFloorBinding, IsTrue)
+
+	// An empty space floor removes it.
+	c.Assert(postJSON(url, []byte(`{}`)), IsNil)
+	c.Assert(rc.GetStore(1).GetSpaceFloor(), IsNil)
+}
+
+var _ = Suite(&testStoreAddressSuite{})
+
+type testStoreAddressSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testStoreAddressSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", s.svr.GetAddr(), apiPrefix)
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testStoreAddressSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testStoreAddressSuite) putStore(c *C, id uint64, address string, state metapb.StoreState) {
+	_, err := s.svr.PutStore(context.Background(), &pdpb.PutStoreRequest{
+		Header: &pdpb.RequestHeader{ClusterId: s.svr.ClusterID()},
+		Store: &metapb.Store{
+			Id:      id,
+			Address: address,
+			State:   state,
+			Version: (*cluster.MinSupportedVersion(cluster.Version2_0)).String(),
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *testStoreAddressSuite) TestResolveStoreByAddress(c *C) {
+	rc := s.svr.GetRaftCluster()
+	getStore := func(address string) (int, *StoreInfo) {
+		code, body := requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/store/address/%s", s.urlPrefix, address))
+		info := new(StoreInfo)
+		if code == http.StatusOK {
+			c.Assert(json.Unmarshal(body, info), IsNil)
+		}
+		return code, info
+	}
+
+	code, _ := getStore("127.0.0.1:20160")
+	c.Assert(code, Equals, http.StatusNotFound)
+
+	// The tombstone store is resolved if no other store has the address.
+	s.putStore(c, 10, "127.0.0.1:20160", metapb.StoreState_Up)
+	c.Assert(rc.BuryStore(10, true), IsNil)
+	code, info := getStore("127.0.0.1:20160")
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(info.Store.GetId(), Equals, uint64(10))
+
+	// The store re-registered on the address is preferred.
+	s.putStore(c, 11, "127.0.0.1:20160", metapb.StoreState_Up)
+	code, info = getStore("127.0.0.1:20160")
+	c.Assert(code, Equals, http.StatusOK)
+	c.Assert(info.Store.GetId(), Equals, uint64(11))
+
+	// The store-targeted endpoints accept the address in place of the ID.
+	prefix := fmt.Sprintf("%s/store/address:127.0.0.1:20160", s.urlPrefix)
+	c.Assert(postJSON(prefix+"/label", []byte(`{"zone": "z1"}`)), IsNil)
+	c.Assert(rc.GetStore(11).GetLabelValue("zone"), Equals, "z1")
+	c.Assert(rc.GetStore(10).GetLabelValue("zone"), Equals, "")
+	c.Assert(postJSON(prefix+"/weight", []byte(`{"leader": 2, "region": 3}`)), IsNil)
+	c.Assert(rc.GetStore(11).GetLeaderWeight(), Equals, 2.0)
+	c.Assert(postJSON(prefix+"/limit", []byte(`{"rate": 30}`)), IsNil)
+	limits, err := s.svr.GetHandler().GetAllStoresLimit()
+	c.Assert(err, IsNil)
+	c.Assert(limits[11].Rate()*schedule.StoreBalanceBaseTime, Equals, 30.0)
+	c.Assert(postJSON(prefix+"/state?state=Offline", nil), IsNil)
+	c.Assert(rc.GetStore(11).IsOffline(), IsTrue)
+	code, _ = requestStatusBody(c, dialClient, http.MethodGet, prefix)
+	c.Assert(code, Equals, http.StatusOK)
+	code, _ = requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/store/address:127.0.0.1:20161", s.urlPrefix))
+	c.Assert(code, Equals, http.StatusNotFound)
+
+	// The tombstone stores sharing the address are listed as the candidates.
+	s.putStore(c, 12, "127.0.0.1:20170", metapb.StoreState_Tombstone)
+	s.putStore(c, 13, "127.0.0.1:20170", metapb.StoreState_Tombstone)
+	code, body := requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/store/address/127.0.0.1:20170", s.urlPrefix))
+	c.Assert(code, Equals, http.StatusConflict)
+	var conflict struct {
+		Data *core.StoreAddressConflictErr `json:"data"`
+	}
+	c.Assert(json.Unmarshal(body, &conflict), IsNil)
+	c.Assert(conflict.Data.Candidates, DeepEquals, []uint64{12, 13})
+	code, _ = requestStatusBody(c, dialClient, http.MethodPost, fmt.Sprintf("%s/store/address:127.0.0.1:20170/label", s.urlPrefix))
+	c.Assert(code, Equals, http.StatusConflict)
+}
+
+var _ = Suite(&testBatchOfflineSuite{})
+
+type testBatchOfflineSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testBatchOfflineSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1/stores/batch-offline", s.svr.GetAddr(), apiPrefix)
+	mustBootstrapCluster(c, s.svr)
+	for id := uint64(2); id <= 5; id++ {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, nil)
+	}
+}
+
+func (s *testBatchOfflineSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testBatchOfflineSuite) TestBatchOffline(c *C) {
+	code, _ := requestStatusBody(c, dialClient, http.MethodGet, s.urlPrefix)
+	c.Assert(code, Equals, http.StatusNotFound)
+	code, _ = requestStatusBody(c, dialClient, http.MethodPost, s.urlPrefix+"/abort")
+	c.Assert(code, Equals, http.StatusNotFound)
+
+	// Only store 1 would be left up for 3 replicas.
+	err := postJSON(s.urlPrefix, []byte(`{"store-ids": [2, 3, 4, 5]}`))
+	c.Assert(strings.Contains(err.Error(), "max replicas"), IsTrue)
+	c.Assert(postJSON(s.urlPrefix, []byte(`{"store-ids": [2, 3]}`)), IsNil)
+	offline := &cluster.BatchOffline{}
+	c.Assert(readJSON(s.urlPrefix, offline), IsNil)
+	c.Assert(offline.State, Equals, cluster.BatchOfflineRunning)
+	c.Assert(offline.MaxConcurrent, Equals, 1)
+	c.Assert(offline.Stores, HasLen, 2)
+	c.Assert(offline.Stores[0].Phase, Equals, cluster.StoreOfflinePending)
+	err = postJSON(s.urlPrefix, []byte(`{"store-ids": [4]}`))
+	c.Assert(strings.Contains(err.Error(), "in progress"), IsTrue)
+
+	c.Assert(postJSON(s.urlPrefix+"/abort", nil), IsNil)
+	c.Assert(readJSON(s.urlPrefix, offline), IsNil)
+	c.Assert(offline.State, Equals, cluster.BatchOfflineAborted)
+	code, _ = requestStatusBody(c, dialClient, http.MethodPost, s.urlPrefix+"/abort")
+	c.Assert(code, Equals, http.StatusConflict)
+	c.Assert(s.svr.GetRaftCluster().GetStore(2).IsUp(), IsTrue)
+}
+
+var _ = Suite(&testStoreHistorySuite{})
+
+type testStoreHistorySuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testStoreHistorySuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", s.svr.GetAddr(), apiPrefix)
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testStoreHistorySuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testStoreHistorySuite) TestLifecycle(c *C) {
+	rc := s.svr.GetRaftCluster()
+	mustPutStore(c, s.svr, 3, metapb.StoreState_Up, nil)
+	c.Assert(rc.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: 3, Capacity: 100 * units.GiB, Available: 60 * units.GiB, UsedSize: 40 * units.GiB}), IsNil)
+
+	status, _ := requestStatusBody(c, dialClient, http.MethodDelete, fmt.Sprintf("%s/store/3?reason=%s", s.urlPrefix, url.QueryEscape("disk failure")))
+	c.Assert(status, Equals, http.StatusOK)
+	c.Assert(rc.BuryStore(3, false), IsNil)
+	status, _ = requestStatusBody(c, dialClient, http.MethodDelete, fmt.Sprintf("%s/stores/remove-tombstone", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusOK)
+	c.Assert(rc.GetStore(3), IsNil)
+
+	var records []*cluster.StoreLifecycleRecord
+	c.Assert(readJSON(fmt.Sprintf("%s/stores/history", s.urlPrefix), &records), IsNil)
+	for _, record := range records {
+		c.Assert(record.StoreID, Not(Equals), uint64(3))
+	}
+	records = nil
+	c.Assert(readJSON(fmt.Sprintf("%s/stores/history?include_removed=true", s.urlPrefix), &records), IsNil)
+	var record *cluster.StoreLifecycleRecord
+	for _, r := range records {
+		if r.StoreID == 3 {
+			record = r
+		}
+	}
+	c.Assert(record, NotNil)
+	c.Assert(record.Address, Equals, "tikv3")
+	c.Assert(record.State, Equals, cluster.StoreStateRemoved)
+	c.Assert(record.JoinTime, NotNil)
+	c.Assert(record.OfflineTime, NotNil)
+	c.Assert(record.TombstoneTime, NotNil)
+	c.Assert(record.RemoveTime, NotNil)
+	c.Assert(record.JoinTime.After(*record.OfflineTime), IsFalse)
+	c.Assert(record.OfflineTime.After(*record.TombstoneTime), IsFalse)
+	c.Assert(record.TombstoneTime.After(*record.RemoveTime), IsFalse)
+	c.Assert(record.PeakUsedSize, Equals, uint64(40*units.GiB))
+	c.Assert(record.Reason, Equals, "disk failure")
+
+	status, _ = requestStatusBody(c, dialClient, http.MethodGet, fmt.Sprintf("%s/stores/history?include_removed=maybe", s.urlPrefix))
+	c.Assert(status, Equals, http.StatusBadRequest)
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/pkg/errors"
+)
+
+const csvContentType = "text/csv"
+
+// storeWeightsCSVHeader is the first row of the store weights in CSV.
+var storeWeightsCSVHeader = []string{"store_id", "leader", "region"}
+
+// GetWeights exports the weights of all the stores, in JSON by default or in
+// CSV with format=csv.
+func (h *storesHandler) GetWeights(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	weights := rc.GetStoreWeights()
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		if weights == nil {
+			weights = []*cluster.StoreWeight{}
+		}
+		h.rd.JSON(w, http.StatusOK, weights)
+	case "csv":
+		w.Header().Set("Content-Type", csvContentType)
+		w.WriteHeader(http.StatusOK)
+		writeStoreWeightsCSV(w, weights)
+	default:
+		h.rd.JSON(w, http.StatusBadRequest, "invalid format")
+	}
+}
+
+// SetWeights imports the weights of the stores in the form exported, which is
+// CSV if the content type is text/csv, or JSON otherwise. The whole batch is
+// rejected if any row is invalid.
+func (h *storesHandler) SetWeights(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r.Context())
+	var weights []*cluster.StoreWeight
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == csvContentType {
+		var err error
+		weights, err = readStoreWeightsCSV(r.Body)
+		r.Body.Close()
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &weights); err != nil {
+		return
+	}
+	if err := rc.SetStoreWeights(weights); err != nil {
+		if weightsErr, ok := errors.Cause(err).(*cluster.StoreWeightsError); ok {
+			h.rd.JSON(w, http.StatusBadRequest, weightsErr)
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, nil)
+}
+
+func writeStoreWeightsCSV(w io.Writer, weights []*cluster.StoreWeight) {
+	cw := csv.NewWriter(w)
+	_ = cw.Write(storeWeightsCSVHeader)
+	for _, weight := range weights {
+		_ = cw.Write([]string{
+			strconv.FormatUint(weight.StoreID, 10),
+			strconv.FormatFloat(weight.Leader, 'f', -1, 64),
+			strconv.FormatFloat(weight.Region, 'f', -1, 64),
+		})
+	}
+	cw.Flush()
+}
+
+func readStoreWeightsCSV(r io.Reader) ([]*cluster.StoreWeight, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(storeWeightsCSVHeader)
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	// The header is optional.
+	if len(records) > 0 && records[0][0] == storeWeightsCSVHeader[0] {
+		records = records[1:]
+	}
+	weights := make([]*cluster.StoreWeight, 0, len(records))
+	for i, record := range records {
+		var weight cluster.StoreWeight
+		if weight.StoreID, err = strconv.ParseUint(record[0], 10, 64); err != nil {
+			return nil, errors.Errorf("row %d: invalid store id %q", i+1, record[0])
+		}
+		if weight.Leader, err = parseWeight(record[1]); err != nil {
+			return nil, errors.Errorf("row %d: invalid leader weight %q", i+1, record[1])
+		}
+		if weight.Region, err = parseWeight(record[2]); err != nil {
+			return nil, errors.Errorf("row %d: invalid region weight %q", i+1, record[2])
+		}
+		weights = append(weights, &weight)
+	}
+	return weights, nil
+}
+
+// parseWeight parses a weight, which is a finite number. NaN and Inf are
+// accepted by strconv.ParseFloat, but not by the balance schedulers.
+func parseWeight(s string) (float64, error) {
+	weight, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(weight) || math.IsInf(weight, 0) {
+		return 0, errors.New("not a finite number")
+	}
+	return weight, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/cluster"
+)
+
+var _ = Suite(&testStoreWeightsSuite{})
+
+type testStoreWeightsSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testStoreWeightsSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s/api/v1", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+	for id := uint64(1); id <= 3; id++ {
+		mustPutStore(c, s.svr, id, metapb.StoreState_Up, nil)
+	}
+}
+
+func (s *testStoreWeightsSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testStoreWeightsSuite) getWeights(c *C) []*cluster.StoreWeight {
+	var weights []*cluster.StoreWeight
+	c.Assert(readJSON(s.urlPrefix+"/stores/weights", &weights), IsNil)
+	return weights
+}
+
+func (s *testStoreWeightsSuite) postCSV(c *C, data string) int {
+	resp, err := dialClient.Post(s.urlPrefix+"/stores/weights", "text/csv", bytes.NewBufferString(data))
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *testStoreWeightsSuite) TestRoundTrip(c *C) {
+	data, err := json.Marshal([]*cluster.StoreWeight{
+		{StoreID: 1, Leader: 2, Region: 0.5},
+		{StoreID: 3, Leader: 0, Region: 4},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.urlPrefix+"/stores/weights", data), IsNil)
+	weights := s.getWeights(c)
+	c.Assert(weights, DeepEquals, []*cluster.StoreWeight{
+		{StoreID: 1, Leader: 2, Region: 0.5},
+		{StoreID: 2, Leader: 1, Region: 1},
+		{StoreID: 3, Leader: 0, Region: 4},
+	})
+	c.Assert(s.svr.GetRaftCluster().GetStore(1).GetLeaderWeight(), Equals, float64(2))
+
+	// Importing the export changes nothing, in either format.
+	data, err = json.Marshal(weights)
+	c.Assert(err, IsNil)
+	c.Assert(postJSON(s.urlPrefix+"/stores/weights", data), IsNil)
+	c.Assert(s.getWeights(c), DeepEquals, weights)
+
+	resp, err := dialClient.Get(s.urlPrefix + "/stores/weights?format=csv")
+	c.Assert(err, IsNil)
+	csv, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(resp.Header.Get("Content-Type"), Equals, "text/csv")
+	c.Assert(string(csv), Equals, "store_id,leader,region\n1,2,0.5\n2,1,1\n3,0,4\n")
+	c.Assert(s.postCSV(c, string(csv)), Equals, http.StatusOK)
+	c.Assert(s.getWeights(c), DeepEquals, weights)
+
+	c.Assert(s.postCSV(c, "2,3,3\n"), Equals, http.StatusOK)
+	c.Assert(s.svr.GetRaftCluster().GetStore(2).GetRegionWeight(), Equals, float64(3))
+	c.Assert(s.postCSV(c, "2,x,3\n"), Equals, http.StatusBadRequest)
+}
+
+func (s *testStoreWeightsSuite) TestRejectBatch(c *C) {
+	before := s.getWeights(c)
+	data, err := json.Marshal([]*cluster.StoreWeight{
+		{StoreID: 1, Leader: 5, Region: 5},
+		{StoreID: 2, Leader: -1, Region: 5},
+		{StoreID: 9, Leader: 5, Region: 5},
+		{StoreID: 1, Leader: 6, Region: 6},
+	})
+	c.Assert(err, IsNil)
+	resp, err := dialClient.Post(s.urlPrefix+"/stores/weights", "application/json", bytes.NewBuffer(data))
+	c.Assert(err, IsNil)
+	var weightsErr cluster.StoreWeightsError
+	c.Assert(json.NewDecoder(resp.Body).Decode(&weightsErr), IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	c.Assert(weightsErr.Problems, HasLen, 3)
+
+	// The valid row in the batch is not applied.
+	c.Assert(s.getWeights(c), DeepEquals, before)
+}
+
+func (s *testStoreWeightsSuite) TestRejectInvalidWeights(c *C) {
+	before := s.getWeights(c)
+	for _, data := range []string{"1,NaN,1\n", "1,1,Inf\n", "1,-Inf,1\n", "1,1,+Inf\n"} {
+		c.Assert(s.postCSV(c, data), Equals, http.StatusBadRequest, Commentf("data: %s", data))
+	}
+
+	resp, err := dialClient.Post(s.urlPrefix+"/stores/weights", "application/json", bytes.NewBufferString("[null]"))
+	c.Assert(err, IsNil)
+	var weightsErr cluster.StoreWeightsError
+	c.Assert(json.NewDecoder(resp.Body).Decode(&weightsErr), IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusBadRequest)
+	c.Assert(weightsErr.Problems, DeepEquals, []string{"row 1: empty row"})
+
+	// JSON cannot carry NaN or Inf, but the cluster rejects them as well.
+	err = s.svr.GetRaftCluster().SetStoreWeights([]*cluster.StoreWeight{
+		{StoreID: 1, Leader: math.NaN(), Region: 1},
+		{StoreID: 2, Leader: 1, Region: math.Inf(-1)},
+	})
+	c.Assert(err, FitsTypeOf, &cluster.StoreWeightsError{})
+	c.Assert(err.(*cluster.StoreWeightsError).Problems, HasLen, 2)
+	c.Assert(s.getWeights(c), DeepEquals, before)
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/pingcap/pd/v4/pkg/typeutil"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
 	"github.com/pingcap/pd/v4/server/statistics"
 	"github.com/unrolled/render"
 )
@@ -49,6 +50,7 @@ type trendStore struct {
 }
 
 type trendHistory struct {
+	*server.StatsAnnotation
 	StartTime int64               `json:"start"`
 	EndTime   int64               `json:"end"`
 	Entries   []trendHistoryEntry `json:"entries"`
@@ -158,9 +160,17 @@ func (h *trendHandler) getTrendHistory(start time.Time) (*trendHistory, error) {
 	if err != nil {
 		return nil, err
 	}
+	annotation, err := h.GetStatsAnnotation()
+	if err != nil {
+		return nil, err
+	}
 	// Use a tmp map to merge same histories together.
 	historyMap := make(map[trendHistoryEntry]int)
 	for _, entry := range operatorHistory {
+		// Only the succeeded operators have moved the leaders or the peers.
+		if entry.Result != operator.OpSucceeded {
+			continue
+		}
 		historyMap[trendHistoryEntry{
 			From: entry.From,
 			To:   entry.To,
@@ -173,8 +183,9 @@ func (h *trendHandler) getTrendHistory(start time.Time) (*trendHistory, error) {
 		history = append(history, entry)
 	}
 	return &trendHistory{
-		StartTime: start.Unix(),
-		EndTime:   time.Now().Unix(),
-		Entries:   history,
+		StatsAnnotation: annotation,
+		StartTime:       start.Unix(),
+		EndTime:         time.Now().Unix(),
+		Entries:         history,
 	}, nil
 }
@@ -48,10 +48,13 @@ func (s *testTrendSuite) TestTrend(c *C) {
 	mustRegionHeartbeat(c, svr, region6)
 
 	// Create 3 operators that transfers leader, moves follower, moves leader.
-	c.Assert(svr.GetHandler().AddTransferLeaderOperator(4, 2), IsNil)
-	c.Assert(svr.GetHandler().AddTransferPeerOperator(5, 2, 3), IsNil)
+	_, err := svr.GetHandler().AddTransferLeaderOperator("", 4, 2)
+	c.Assert(err, IsNil)
+	_, err = svr.GetHandler().AddTransferPeerOperator("", 5, 2, 3)
+	c.Assert(err, IsNil)
 	time.Sleep(1 * time.Second)
-	c.Assert(svr.GetHandler().AddTransferPeerOperator(6, 1, 3), IsNil)
+	_, err = svr.GetHandler().AddTransferPeerOperator("", 6, 1, 3)
+	c.Assert(err, IsNil)
 
 	// Complete the operators.
 	mustRegionHeartbeat(c, svr, region4.Clone(core.WithLeader(region4.GetStorePeer(2))))
@@ -0,0 +1,42 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/pingcap/pd/v4/server"
+	"github.com/unrolled/render"
+)
+
+type tsoHandler struct {
+	*server.Handler
+	rd *render.Render
+}
+
+func newTSOHandler(handler *server.Handler, rd *render.Render) *tsoHandler {
+	return &tsoHandler{
+		Handler: handler,
+		rd:      rd,
+	}
+}
+
+func (h *tsoHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := h.GetTSOStatus()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, status)
+}
@@ -0,0 +1,218 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/v4/server"
+	"github.com/unrolled/render"
+)
+
+// apiVersion is the version of the HTTP API, which is the prefix of the routes.
+const apiVersion = "v1"
+
+// The features of the HTTP API, which the clients detect once by the version
+// info instead of probing the routes.
+const (
+	featurePagination         = "pagination"
+	featureStreamingRegions   = "streaming-regions"
+	featureTypedOperators     = "typed-operators"
+	featureChainedOperators   = "chained-operators"
+	featureOperatorPrecheck   = "operator-precheck"
+	featureOperatorTemplates  = "operator-templates"
+	featureScheduleLocks      = "schedule-locks"
+	featureLeaderPins         = "leader-pins"
+	featureOperatorReasons    = "operator-reasons"
+	featureOperatorBatch      = "operator-batch"
+	featureConfigBatch        = "config-batch"
+	featureConfigRevisions    = "config-revisions"
+	featureSplitRange         = "split-range"
+	featureRegionCheckExplain = "region-check-explain"
+)
+
+var featureDescriptions = map[string]string{
+	featurePagination:         "Scan the regions from a key with a limit, and go on from the end key of the last one, or from the next_key of a page of all the regions.",
+	featureStreamingRegions:   "The response of all the regions is streamed instead of buffered.",
+	featureTypedOperators:     `Create the operators by {"name": ..., "args": {...}}.`,
+	featureChainedOperators:   "Hold the operators until the prerequisite operator finishes, by after_region_operator.",
+	featureOperatorPrecheck:   "Report which checks would reject an operator without creating it.",
+	featureOperatorTemplates:  "Create the operators from the named templates.",
+	featureScheduleLocks:      "Protect the key ranges from being merged or balanced.",
+	featureLeaderPins:         "Pin the leaders of the regions on the stores.",
+	featureOperatorReasons:    "List the operators in the structured form with the creators and the reasons, by detail=true.",
+	featureOperatorBatch:      "Create the operators in batch, and report which regions are rejected and why.",
+	featureConfigBatch:        "Update the schedule, replication and label property configs at once, all or none.",
+	featureConfigRevisions:    "Respond the revisions of the config and the scheduler configs in ETag, and reject the updates with a stale If-Match.",
+	featureSplitRange:         "Split the regions in a key range at the keys or by a stride in a persisted job, and track its progress by the ID.",
+	featureRegionCheckExplain: "Explain why the replica checker or the rule checker does or does not create an operator for a region.",
+}
+
+// The deprecated formats of the HTTP API.
+const (
+	deprecationFlatOperator = "flat-operator-request"
+	deprecationRootRoutes   = "unversioned-routes"
+)
+
+// deprecation is a legacy format which is removed after the sunset.
+type deprecation struct {
+	description string
+	sunset      time.Time
+	// byRequest means only some requests to the routes are in the legacy
+	// format, so the handler sets the headers by itself, e.g. by the shape of
+	// the body. Otherwise the router sets them on all the responses.
+	byRequest bool
+}
+
+var deprecations = map[string]deprecation{
+	deprecationFlatOperator: {
+		description: `the flat operator request is deprecated, use {"name": ..., "args": {...}} instead`,
+		sunset:      time.Date(2021, time.December, 31, 0, 0, 0, 0, time.UTC),
+		byRequest:   true,
+	},
+	deprecationRootRoutes: {
+		description: "the routes without the API version are deprecated, use the ones under /api/" + apiVersion + " instead",
+		sunset:      time.Date(2021, time.December, 31, 0, 0, 0, 0, time.UTC),
+	},
+}
+
+// setDeprecationHeaders marks the response as in the deprecated format.
+func setDeprecationHeaders(w http.ResponseWriter, name string) {
+	d := deprecations[name]
+	w.Header().Set("Deprecation", "true")
+	w.Header().Set("Sunset", d.sunset.Format(http.TimeFormat))
+	w.Header().Set("Warning", fmt.Sprintf("299 - %q", d.description))
+}
+
+// APIFeature is a feature of the HTTP API along with the routes supporting it.
+// A route is the method followed by the path template.
+type APIFeature struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Routes      []string `json:"routes"`
+}
+
+// APIDeprecation is a deprecated format of the HTTP API along with the routes
+// accepting or responding it.
+type APIDeprecation struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Sunset      time.Time `json:"sunset"`
+	Routes      []string  `json:"routes"`
+}
+
+// VersionInfo describes what the HTTP API of the PD supports.
+type VersionInfo struct {
+	Version      string            `json:"version"`
+	APIVersion   string            `json:"api_version"`
+	Features     []*APIFeature     `json:"features"`
+	Deprecations []*APIDeprecation `json:"deprecations"`
+}
+
+// apiRegistry collects the features and the deprecations of the routes as they
+// are defined. It is not changed after the router is created.
+type apiRegistry struct {
+	features     map[string][]string
+	deprecations map[string][]string
+}
+
+func newAPIRegistry() *apiRegistry {
+	return &apiRegistry{
+		features:     make(map[string][]string),
+		deprecations: make(map[string][]string),
+	}
+}
+
+func routeName(route *mux.Route) string {
+	path, err := route.GetPathTemplate()
+	if err != nil {
+		panic(err)
+	}
+	methods, _ := route.GetMethods()
+	return strings.TrimSpace(strings.Join(methods, ",") + " " + path)
+}
+
+// provide registers the features supported by the route.
+func (r *apiRegistry) provide(route *mux.Route, features ...string) *mux.Route {
+	for _, feature := range features {
+		if _, ok := featureDescriptions[feature]; !ok {
+			panic(fmt.Sprintf("unknown api feature %s", feature))
+		}
+		r.features[feature] = append(r.features[feature], routeName(route))
+	}
+	return route
+}
+
+// deprecate registers the deprecated format of the route. The router sets the
+// deprecation headers on all the responses of the route unless the format is
+// told by the request.
+func (r *apiRegistry) deprecate(route *mux.Route, name string) *mux.Route {
+	d, ok := deprecations[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown api deprecation %s", name))
+	}
+	r.deprecations[name] = append(r.deprecations[name], routeName(route))
+	if d.byRequest {
+		return route
+	}
+	next := route.GetHandler()
+	return route.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		setDeprecationHeaders(w, name)
+		next.ServeHTTP(w, req)
+	}))
+}
+
+func (r *apiRegistry) versionInfo() *VersionInfo {
+	info := &VersionInfo{
+		Version:      server.PDReleaseVersion,
+		APIVersion:   apiVersion,
+		Features:     make([]*APIFeature, 0, len(r.features)),
+		Deprecations: make([]*APIDeprecation, 0, len(r.deprecations)),
+	}
+	for name, routes := range r.features {
+		routes = append(routes[:0:0], routes...)
+		sort.Strings(routes)
+		info.Features = append(info.Features, &APIFeature{Name: name, Description: featureDescriptions[name], Routes: routes})
+	}
+	for name, routes := range r.deprecations {
+		routes = append(routes[:0:0], routes...)
+		sort.Strings(routes)
+		d := deprecations[name]
+		info.Deprecations = append(info.Deprecations, &APIDeprecation{Name: name, Description: d.description, Sunset: d.sunset, Routes: routes})
+	}
+	sort.Slice(info.Features, func(i, j int) bool { return info.Features[i].Name < info.Features[j].Name })
+	sort.Slice(info.Deprecations, func(i, j int) bool { return info.Deprecations[i].Name < info.Deprecations[j].Name })
+	return info
+}
+
+type versionInfoHandler struct {
+	registry *apiRegistry
+	rd       *render.Render
+}
+
+func newVersionInfoHandler(registry *apiRegistry, rd *render.Render) *versionInfoHandler {
+	return &versionInfoHandler{
+		registry: registry,
+		rd:       rd,
+	}
+}
+
+func (h *versionInfoHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.registry.versionInfo())
+}
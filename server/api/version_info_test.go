@@ -0,0 +1,102 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/v4/server"
+)
+
+var _ = Suite(&testVersionInfoSuite{})
+
+type testVersionInfoSuite struct {
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func (s *testVersionInfoSuite) SetUpSuite(c *C) {
+	s.svr, s.cleanup = mustNewServer(c)
+	mustWaitLeader(c, []*server.Server{s.svr})
+
+	addr := s.svr.GetAddr()
+	s.urlPrefix = fmt.Sprintf("%s%s", addr, apiPrefix)
+
+	mustBootstrapCluster(c, s.svr)
+}
+
+func (s *testVersionInfoSuite) TearDownSuite(c *C) {
+	s.cleanup()
+}
+
+func (s *testVersionInfoSuite) TestVersionInfo(c *C) {
+	var info VersionInfo
+	c.Assert(readJSON(s.urlPrefix+"/api/v1/version-info", &info), IsNil)
+	c.Assert(info.Version, Equals, server.PDReleaseVersion)
+	c.Assert(info.APIVersion, Equals, apiVersion)
+
+	// Every route in the version info is mounted.
+	router, _ := createRouter(context.Background(), apiPrefix, s.svr)
+	mounted := make(map[string]struct{})
+	c.Assert(router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if _, err := route.GetPathTemplate(); err == nil {
+			mounted[routeName(route)] = struct{}{}
+		}
+		return nil
+	}), IsNil)
+	features := make(map[string][]string)
+	for _, f := range info.Features {
+		c.Assert(f.Description, Not(Equals), "")
+		c.Assert(f.Routes, Not(HasLen), 0)
+		for _, route := range f.Routes {
+			_, ok := mounted[route]
+			c.Assert(ok, IsTrue, Commentf("feature %s route %s", f.Name, route))
+		}
+		features[f.Name] = f.Routes
+	}
+	// All the known features are provided by some routes.
+	c.Assert(features, HasLen, len(featureDescriptions))
+	c.Assert(features[featureTypedOperators], DeepEquals, []string{"POST " + apiPrefix + "/api/v1/operators"})
+	c.Assert(features[featureStreamingRegions], DeepEquals, []string{"GET " + apiPrefix + "/api/v1/regions"})
+
+	c.Assert(info.Deprecations, HasLen, len(deprecations))
+	for _, d := range info.Deprecations {
+		c.Assert(d.Sunset.IsZero(), IsFalse)
+		for _, route := range d.Routes {
+			_, ok := mounted[route]
+			c.Assert(ok, IsTrue, Commentf("deprecation %s route %s", d.Name, route))
+		}
+	}
+}
+
+func (s *testVersionInfoSuite) TestDeprecationHeaders(c *C) {
+	resp, err := dialClient.Get(s.urlPrefix + "/ping")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(resp.Header.Get("Deprecation"), Equals, "true")
+	c.Assert(resp.Header.Get("Sunset"), Equals, deprecations[deprecationRootRoutes].sunset.Format(http.TimeFormat))
+
+	resp, err = dialClient.Get(s.urlPrefix + "/api/v1/ping")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	c.Assert(resp.Header.Get("Deprecation"), Equals, "")
+	c.Assert(resp.Header.Get("Sunset"), Equals, "")
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+// BalanceScoreMethod is how the balance scores are calculated.
+const BalanceScoreMethod = "coefficient of variation (population standard deviation / mean) over the Up stores " +
+	"of leader_count/leader_weight, region_count/region_weight and " +
+	"region_size/(region_weight*capacity/mean_capacity), where mean_capacity is the mean capacity " +
+	"of the Up stores reporting the capacity and the stores not reporting it use mean_capacity; " +
+	"the stores with zero weight are skipped, and the score is 0 if there are less than 2 stores or the mean is 0"
+
+// BalanceScore is the skew of the distribution of the leaders and the
+// regions over the stores. 0 means the distribution is balanced as the
+// weights expect, and it grows with the skew, e.g. 0.1 means the values of
+// the stores deviate from their mean by 10% typically.
+type BalanceScore struct {
+	Method      string    `json:"method"`
+	Stores      int       `json:"stores"`
+	LeaderCount float64   `json:"leader-count"`
+	RegionCount float64   `json:"region-count"`
+	RegionSize  float64   `json:"region-size"`
+	UpdateTime  time.Time `json:"update-time"`
+}
+
+// calculateBalanceScore calculates the balance scores of the Up stores.
+func calculateBalanceScore(stores []*core.StoreInfo) *BalanceScore {
+	var upStores []*core.StoreInfo
+	var totalCapacity float64
+	var capacityStores int
+	for _, s := range stores {
+		if !s.IsUp() {
+			continue
+		}
+		upStores = append(upStores, s)
+		if s.GetCapacity() > 0 {
+			totalCapacity += float64(s.GetCapacity())
+			capacityStores++
+		}
+	}
+	var leaderCounts, regionCounts, regionSizes []float64
+	for _, s := range upStores {
+		if w := s.GetLeaderWeight(); w > 0 {
+			leaderCounts = append(leaderCounts, float64(s.GetLeaderCount())/w)
+		}
+		w := s.GetRegionWeight()
+		if w <= 0 {
+			continue
+		}
+		regionCounts = append(regionCounts, float64(s.GetRegionCount())/w)
+		capacityRatio := 1.0
+		if s.GetCapacity() > 0 {
+			capacityRatio = float64(s.GetCapacity()) / (totalCapacity / float64(capacityStores))
+		}
+		regionSizes = append(regionSizes, float64(s.GetRegionSize())/(w*capacityRatio))
+	}
+	return &BalanceScore{
+		Method:      BalanceScoreMethod,
+		Stores:      len(upStores),
+		LeaderCount: coefficientOfVariation(leaderCounts),
+		RegionCount: coefficientOfVariation(regionCounts),
+		RegionSize:  coefficientOfVariation(regionSizes),
+		UpdateTime:  time.Now(),
+	}
+}
+
+// coefficientOfVariation returns the population standard deviation of the
+// values divided by their mean.
+func coefficientOfVariation(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if mean == 0 {
+		return 0
+	}
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values))
+	return math.Sqrt(variance) / mean
+}
+
+// balanceScoreCache keeps the balance score calculated lastly. It is
+// threadsafe.
+type balanceScoreCache struct {
+	sync.RWMutex
+	score *BalanceScore
+}
+
+func (c *balanceScoreCache) get() *BalanceScore {
+	c.RLock()
+	defer c.RUnlock()
+	return c.score
+}
+
+func (c *balanceScoreCache) set(score *BalanceScore) {
+	c.Lock()
+	defer c.Unlock()
+	c.score = score
+}
+
+// GetBalanceScore returns the balance score calculated by the background
+// jobs lastly. It is calculated at once if it is not calculated yet.
+func (c *RaftCluster) GetBalanceScore() *BalanceScore {
+	if score := c.balanceScore.get(); score != nil {
+		return score
+	}
+	return c.updateBalanceScore()
+}
+
+func (c *RaftCluster) updateBalanceScore() *BalanceScore {
+	score := calculateBalanceScore(c.GetStores())
+	c.balanceScore.set(score)
+	return score
+}
+
+func (c *RaftCluster) collectBalanceScoreMetrics() {
+	score := c.updateBalanceScore()
+	balanceScoreGauge.WithLabelValues("leader_count").Set(score.LeaderCount)
+	balanceScoreGauge.WithLabelValues("region_count").Set(score.RegionCount)
+	balanceScoreGauge.WithLabelValues("region_size").Set(score.RegionSize)
+}
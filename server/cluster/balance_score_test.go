@@ -0,0 +1,113 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"math"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+var _ = Suite(&testBalanceScoreSuite{})
+
+type testBalanceScoreSuite struct{}
+
+func (s *testBalanceScoreSuite) newStore(id uint64, state metapb.StoreState, leaderCount, regionCount int, regionSize int64, capacity uint64, leaderWeight, regionWeight float64) *core.StoreInfo {
+	return core.NewStoreInfo(&metapb.Store{Id: id, State: state},
+		core.SetStoreStats(&pdpb.StoreStats{Capacity: capacity}),
+		core.SetLeaderCount(leaderCount),
+		core.SetRegionCount(regionCount),
+		core.SetRegionSize(regionSize),
+		core.SetLeaderWeight(leaderWeight),
+		core.SetRegionWeight(regionWeight),
+	)
+}
+
+func (s *testBalanceScoreSuite) TestCoefficientOfVariation(c *C) {
+	c.Assert(coefficientOfVariation(nil), Equals, 0.0)
+	c.Assert(coefficientOfVariation([]float64{10}), Equals, 0.0)
+	c.Assert(coefficientOfVariation([]float64{0, 0}), Equals, 0.0)
+	c.Assert(coefficientOfVariation([]float64{5, 5, 5}), Equals, 0.0)
+	// The mean is 20 and the standard deviation is 10.
+	c.Assert(coefficientOfVariation([]float64{10, 30}), Equals, 0.5)
+	// The mean is 20 and the variance is (100+0+100)/3.
+	c.Assert(math.Abs(coefficientOfVariation([]float64{10, 20, 30})-math.Sqrt(200.0/3)/20) < 1e-9, IsTrue)
+}
+
+func (s *testBalanceScoreSuite) TestBalanceScore(c *C) {
+	stores := []*core.StoreInfo{
+		s.newStore(1, metapb.StoreState_Up, 10, 20, 100, 100, 1, 1),
+		// The counts are halved by the weights, and the region size is
+		// divided by 2 and by 1.5 for the capacity.
+		s.newStore(2, metapb.StoreState_Up, 60, 40, 1200, 300, 2, 2),
+		// The store with zero weights is skipped, but its capacity counts in
+		// the mean capacity, which is 200.
+		s.newStore(3, metapb.StoreState_Up, 100, 100, 100, 200, 0, 0),
+		s.newStore(4, metapb.StoreState_Offline, 1000, 1000, 1000, 200, 1, 1),
+	}
+	score := calculateBalanceScore(stores)
+	c.Assert(score.Method, Equals, BalanceScoreMethod)
+	c.Assert(score.Stores, Equals, 3)
+	// The leader counts are 10 and 30.
+	c.Assert(score.LeaderCount, Equals, 0.5)
+	// The region counts are 20 and 20.
+	c.Assert(score.RegionCount, Equals, 0.0)
+	// The region sizes are 100/0.5 and 1200/(2*1.5), i.e. 200 and 400, whose
+	// mean is 300 and standard deviation is 100.
+	c.Assert(math.Abs(score.RegionSize-1.0/3) < 1e-9, IsTrue)
+
+	// The stores without the capacity use the mean capacity.
+	stores = []*core.StoreInfo{
+		s.newStore(1, metapb.StoreState_Up, 10, 10, 100, 100, 1, 1),
+		s.newStore(2, metapb.StoreState_Up, 10, 30, 100, 300, 1, 1),
+		s.newStore(3, metapb.StoreState_Up, 10, 20, 100, 0, 1, 1),
+	}
+	score = calculateBalanceScore(stores)
+	c.Assert(score.LeaderCount, Equals, 0.0)
+	c.Assert(math.Abs(score.RegionCount-math.Sqrt(200.0/3)/20) < 1e-9, IsTrue)
+	// The region sizes are 100/0.5, 100/1.5 and 100, whose mean is 1100/9.
+	sizes := []float64{200, 200.0 / 3, 100}
+	mean := 1100.0 / 9
+	var variance float64
+	for _, size := range sizes {
+		variance += (size - mean) * (size - mean) / 3
+	}
+	c.Assert(math.Abs(score.RegionSize-math.Sqrt(variance)/mean) < 1e-9, IsTrue)
+
+	// A single store is always balanced.
+	score = calculateBalanceScore(stores[:1])
+	c.Assert(score.Stores, Equals, 1)
+	c.Assert(score.LeaderCount, Equals, 0.0)
+	c.Assert(score.RegionCount, Equals, 0.0)
+	c.Assert(score.RegionSize, Equals, 0.0)
+}
+
+func (s *testBalanceScoreSuite) TestBalanceScoreCache(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	tc := newTestCluster(opt)
+	c.Assert(tc.addLeaderStore(1, 10), IsNil)
+	c.Assert(tc.addLeaderStore(2, 30), IsNil)
+	score := tc.GetBalanceScore()
+	c.Assert(score.LeaderCount, Equals, 0.5)
+
+	// The score is not recalculated per request.
+	c.Assert(tc.updateLeaderCount(2, 10), IsNil)
+	c.Assert(tc.GetBalanceScore(), Equals, score)
+	tc.collectBalanceScoreMetrics()
+	c.Assert(tc.GetBalanceScore().LeaderCount, Equals, 0.0)
+}
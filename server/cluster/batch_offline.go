@@ -0,0 +1,294 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// The states of a batch offline.
+const (
+	BatchOfflineRunning  = "running"
+	BatchOfflineAborted  = "aborted"
+	BatchOfflineFinished = "finished"
+)
+
+// The phases of a store in a batch offline.
+const (
+	// StoreOfflinePending means the store waits for a slot to go offline.
+	StoreOfflinePending = "pending"
+	// StoreOfflineDraining means the store is offline and its regions are
+	// being moved out.
+	StoreOfflineDraining = "draining"
+	// StoreOfflineDrained means the store has no region left.
+	StoreOfflineDrained = "drained"
+	// StoreOfflineSkipped means the store is removed before it goes offline.
+	StoreOfflineSkipped = "skipped"
+	// StoreOfflineRestored means the store is brought back up as the batch
+	// offline is aborted while it is draining.
+	StoreOfflineRestored = "restored"
+)
+
+var (
+	// ErrBatchOfflineInProgress is error info for starting a batch offline
+	// when another one is running.
+	ErrBatchOfflineInProgress = errors.New("batch offline in progress")
+	// ErrBatchOfflineNotFound is error info for no batch offline.
+	ErrBatchOfflineNotFound = errors.New("batch offline not found")
+	// ErrBatchOfflineStateMismatch is error info for aborting a batch offline
+	// which is not running.
+	ErrBatchOfflineStateMismatch = errors.New("batch offline state mismatch")
+)
+
+// BatchOfflineConfig is the stores to take offline in order and the number of
+// the stores draining at the same time.
+type BatchOfflineConfig struct {
+	StoreIDs      []uint64 `json:"store-ids"`
+	MaxConcurrent int      `json:"max-concurrent"`
+}
+
+// StoreOffline is the progress of a store in a batch offline.
+type StoreOffline struct {
+	StoreID uint64 `json:"store-id"`
+	Phase   string `json:"phase"`
+	// PhaseStartTime is the time when the store enters the phase.
+	PhaseStartTime time.Time `json:"phase-start-time"`
+	// RegionCount is the regions left on the store when last checked.
+	RegionCount int `json:"region-count"`
+}
+
+// BatchOffline takes the stores offline a few at a time. The next store goes
+// offline once an earlier one is drained.
+type BatchOffline struct {
+	BatchOfflineConfig
+	State     string          `json:"state"`
+	StartTime time.Time       `json:"start-time"`
+	Stores    []*StoreOffline `json:"stores"`
+}
+
+// Clone returns a deep copy of the batch offline.
+func (b *BatchOffline) Clone() *BatchOffline {
+	offline := *b
+	offline.StoreIDs = append(b.StoreIDs[:0:0], b.StoreIDs...)
+	offline.Stores = make([]*StoreOffline, 0, len(b.Stores))
+	for _, s := range b.Stores {
+		store := *s
+		offline.Stores = append(offline.Stores, &store)
+	}
+	return &offline
+}
+
+// BatchOfflineController manages the batch offline, which is persisted so
+// that it is resumed after the leader of PD changes. It is threadsafe. Its
+// lock is held while the lock of the cluster is taken to change the states of
+// the stores, so it should not be taken with the lock of the cluster held.
+type BatchOfflineController struct {
+	sync.RWMutex
+	storage *core.Storage
+	offline *BatchOffline
+	now     func() time.Time
+}
+
+// NewBatchOfflineController creates a BatchOfflineController instance.
+func NewBatchOfflineController(storage *core.Storage) *BatchOfflineController {
+	return &BatchOfflineController{
+		storage: storage,
+		now:     time.Now,
+	}
+}
+
+// Load loads the batch offline from storage.
+func (m *BatchOfflineController) Load() error {
+	m.Lock()
+	defer m.Unlock()
+	var offline BatchOffline
+	ok, err := m.storage.LoadBatchOffline(&offline)
+	if err != nil || !ok {
+		return err
+	}
+	m.offline = &offline
+	return nil
+}
+
+// Get returns the batch offline, or nil if there is none.
+func (m *BatchOfflineController) Get() *BatchOffline {
+	m.RLock()
+	defer m.RUnlock()
+	if m.offline == nil {
+		return nil
+	}
+	return m.offline.Clone()
+}
+
+// Start persists and starts a batch offline, which replaces the finished or
+// aborted one. The stores should be validated by the caller.
+func (m *BatchOfflineController) Start(cfg *BatchOfflineConfig) (*BatchOffline, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.offline != nil && m.offline.State == BatchOfflineRunning {
+		return nil, ErrBatchOfflineInProgress
+	}
+	offline := &BatchOffline{
+		BatchOfflineConfig: *cfg,
+		State:              BatchOfflineRunning,
+		StartTime:          m.now(),
+	}
+	if offline.MaxConcurrent <= 0 {
+		offline.MaxConcurrent = 1
+	}
+	for _, id := range cfg.StoreIDs {
+		offline.Stores = append(offline.Stores, &StoreOffline{StoreID: id, Phase: StoreOfflinePending})
+	}
+	if err := m.storage.SaveBatchOffline(offline); err != nil {
+		return nil, err
+	}
+	m.offline = offline
+	log.Info("batch offline started", zap.Uint64s("store-ids", cfg.StoreIDs), zap.Int("max-concurrent", offline.MaxConcurrent))
+	return offline.Clone(), nil
+}
+
+// Abort aborts the running batch offline. The pending stores are never taken
+// offline, and the draining ones are brought back up.
+func (m *BatchOfflineController) Abort(c *RaftCluster) error {
+	m.Lock()
+	defer m.Unlock()
+	if m.offline == nil {
+		return ErrBatchOfflineNotFound
+	}
+	if m.offline.State != BatchOfflineRunning {
+		return errors.Wrapf(ErrBatchOfflineStateMismatch, "the batch offline is %s", m.offline.State)
+	}
+	offline := m.offline.Clone()
+	for _, s := range offline.Stores {
+		if s.Phase != StoreOfflineDraining {
+			continue
+		}
+		if store := c.GetStore(s.StoreID); store != nil && store.IsOffline() {
+			if err := c.SetStoreState(s.StoreID, metapb.StoreState_Up); err != nil {
+				return err
+			}
+		}
+		m.enterPhase(s, StoreOfflineRestored)
+	}
+	offline.State = BatchOfflineAborted
+	if err := m.storage.SaveBatchOffline(offline); err != nil {
+		return err
+	}
+	m.offline = offline
+	log.Info("batch offline aborted")
+	return nil
+}
+
+// advance checks the draining stores, and takes the pending stores offline
+// while there are free slots. It is called periodically.
+func (m *BatchOfflineController) advance(c *RaftCluster) {
+	m.Lock()
+	defer m.Unlock()
+	if m.offline == nil || m.offline.State != BatchOfflineRunning {
+		return
+	}
+	offline := m.offline.Clone()
+	var changed bool
+	var draining int
+	for _, s := range offline.Stores {
+		if s.Phase != StoreOfflineDraining {
+			continue
+		}
+		phase, regionCount := s.Phase, s.RegionCount
+		m.checkDraining(c, s)
+		if s.Phase == StoreOfflineDraining {
+			draining++
+		}
+		changed = changed || s.Phase != phase || s.RegionCount != regionCount
+	}
+	finished := true
+	for _, s := range offline.Stores {
+		if s.Phase == StoreOfflineDraining {
+			finished = false
+		}
+		if s.Phase != StoreOfflinePending {
+			continue
+		}
+		if draining >= offline.MaxConcurrent {
+			finished = false
+			break
+		}
+		m.startDraining(c, s)
+		switch s.Phase {
+		case StoreOfflinePending:
+			finished = false
+		case StoreOfflineDraining:
+			draining++
+			changed = true
+		default:
+			changed = true
+		}
+	}
+	if finished {
+		offline.State = BatchOfflineFinished
+		changed = true
+		log.Info("batch offline finished")
+	}
+	if !changed {
+		return
+	}
+	if err := m.storage.SaveBatchOffline(offline); err != nil {
+		log.Error("failed to persist batch offline", zap.Error(err))
+		return
+	}
+	m.offline = offline
+}
+
+// checkDraining moves the draining store to drained if it has no region left.
+// A store which is buried is drained too.
+func (m *BatchOfflineController) checkDraining(c *RaftCluster, s *StoreOffline) {
+	store := c.GetStore(s.StoreID)
+	if store == nil || store.IsTombstone() {
+		s.RegionCount = 0
+		m.enterPhase(s, StoreOfflineDrained)
+		return
+	}
+	s.RegionCount = c.core.GetStoreRegionCount(s.StoreID)
+	if s.RegionCount == 0 {
+		m.enterPhase(s, StoreOfflineDrained)
+	}
+}
+
+// startDraining takes the pending store offline. It stays pending if it fails.
+func (m *BatchOfflineController) startDraining(c *RaftCluster, s *StoreOffline) {
+	store := c.GetStore(s.StoreID)
+	if store == nil || store.IsTombstone() {
+		log.Warn("skip taking the store offline which is removed", zap.Uint64("store-id", s.StoreID))
+		m.enterPhase(s, StoreOfflineSkipped)
+		return
+	}
+	if err := c.RemoveStore(s.StoreID); err != nil {
+		log.Error("failed to take the store offline for batch offline", zap.Uint64("store-id", s.StoreID), zap.Error(err))
+		return
+	}
+	s.RegionCount = c.core.GetStoreRegionCount(s.StoreID)
+	m.enterPhase(s, StoreOfflineDraining)
+}
+
+func (m *BatchOfflineController) enterPhase(s *StoreOffline, phase string) {
+	log.Info("store offline phase changed", zap.Uint64("store-id", s.StoreID), zap.String("from", s.Phase), zap.String("to", phase))
+	s.Phase, s.PhaseStartTime = phase, m.now()
+}
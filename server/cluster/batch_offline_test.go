@@ -0,0 +1,142 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/placement"
+)
+
+var _ = Suite(&testBatchOfflineSuite{})
+
+type testBatchOfflineSuite struct{}
+
+func (s *testBatchOfflineSuite) TestBatchOffline(c *C) {
+	tc, _, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+	for id := uint64(1); id <= 6; id++ {
+		c.Assert(tc.addRegionStore(id, 0), IsNil)
+	}
+	c.Assert(tc.addLeaderRegion(1, 1, 2, 4), IsNil)
+	c.Assert(tc.addLeaderRegion(2, 2, 3, 5), IsNil)
+	c.Assert(tc.addLeaderRegion(3, 3, 4, 5), IsNil)
+	m := tc.batchOffline
+	phases := func() []string {
+		var phases []string
+		for _, store := range m.Get().Stores {
+			phases = append(phases, store.Phase)
+		}
+		return phases
+	}
+
+	// The 2 stores left up cannot hold 3 replicas.
+	for _, ids := range [][]uint64{{}, {1, 1}, {1, 9}, {1, 2, 3, 4}} {
+		_, err := tc.StartBatchOffline(&BatchOfflineConfig{StoreIDs: ids})
+		c.Assert(err, NotNil)
+	}
+	offline, err := tc.StartBatchOffline(&BatchOfflineConfig{StoreIDs: []uint64{1, 2, 3}, MaxConcurrent: 2})
+	c.Assert(err, IsNil)
+	c.Assert(offline.State, Equals, BatchOfflineRunning)
+	_, err = tc.StartBatchOffline(&BatchOfflineConfig{StoreIDs: []uint64{6}})
+	c.Assert(err, Equals, ErrBatchOfflineInProgress)
+
+	// Stores 1 and 2 go offline, and store 3 waits for a slot.
+	m.advance(tc.RaftCluster)
+	c.Assert(phases(), DeepEquals, []string{StoreOfflineDraining, StoreOfflineDraining, StoreOfflinePending})
+	c.Assert(tc.GetStore(1).IsOffline(), IsTrue)
+	c.Assert(tc.GetStore(2).IsOffline(), IsTrue)
+	c.Assert(tc.GetStore(3).IsUp(), IsTrue)
+	c.Assert(m.Get().Stores[1].RegionCount, Equals, 2)
+
+	// Store 1 is drained, so store 3 goes offline.
+	c.Assert(tc.addLeaderRegion(1, 6, 2, 4), IsNil)
+	m.advance(tc.RaftCluster)
+	c.Assert(phases(), DeepEquals, []string{StoreOfflineDrained, StoreOfflineDraining, StoreOfflineDraining})
+	c.Assert(tc.GetStore(3).IsOffline(), IsTrue)
+
+	// The progress is resumed after the leader changes.
+	m = NewBatchOfflineController(tc.storage)
+	c.Assert(m.Load(), IsNil)
+	c.Assert(phases(), DeepEquals, []string{StoreOfflineDrained, StoreOfflineDraining, StoreOfflineDraining})
+
+	// Store 3 is buried, and store 2 is drained.
+	tc.Lock()
+	c.Assert(tc.putStoreLocked(tc.GetStore(3).Clone(core.SetStoreState(metapb.StoreState_Tombstone))), IsNil)
+	tc.Unlock()
+	c.Assert(tc.addLeaderRegion(1, 6, 5, 4), IsNil)
+	c.Assert(tc.addLeaderRegion(2, 6, 4, 5), IsNil)
+	m.advance(tc.RaftCluster)
+	c.Assert(phases(), DeepEquals, []string{StoreOfflineDrained, StoreOfflineDrained, StoreOfflineDrained})
+	c.Assert(m.Get().State, Equals, BatchOfflineFinished)
+	c.Assert(m.Abort(tc.RaftCluster), NotNil)
+}
+
+func (s *testBatchOfflineSuite) TestAbort(c *C) {
+	tc, _, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(tc.addRegionStore(id, 0), IsNil)
+	}
+	c.Assert(tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(tc.AbortBatchOffline(), Equals, ErrBatchOfflineNotFound)
+	_, err := tc.StartBatchOffline(&BatchOfflineConfig{StoreIDs: []uint64{1, 2}})
+	c.Assert(err, IsNil)
+	tc.batchOffline.advance(tc.RaftCluster)
+	c.Assert(tc.GetStore(1).IsOffline(), IsTrue)
+
+	// Store 1 is brought back up, and store 2 is never taken offline.
+	c.Assert(tc.AbortBatchOffline(), IsNil)
+	offline := tc.GetBatchOffline()
+	c.Assert(offline.State, Equals, BatchOfflineAborted)
+	c.Assert(offline.Stores[0].Phase, Equals, StoreOfflineRestored)
+	c.Assert(offline.Stores[1].Phase, Equals, StoreOfflinePending)
+	c.Assert(tc.GetStore(1).IsUp(), IsTrue)
+	tc.batchOffline.advance(tc.RaftCluster)
+	c.Assert(tc.GetStore(2).IsUp(), IsTrue)
+	c.Assert(tc.AbortBatchOffline(), NotNil)
+}
+
+func (s *testBatchOfflineSuite) TestPlacementRules(c *C) {
+	tc, _, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(tc.addRegionStore(id, 0), IsNil)
+	}
+	// Stores 1, 2 and 3 are in zone z1.
+	labels := []*metapb.StoreLabel{{Key: "zone", Value: "z1"}}
+	tc.Lock()
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(tc.putStoreLocked(tc.GetStore(id).Clone(core.SetStoreLabels(labels))), IsNil)
+	}
+	tc.Unlock()
+	replication := *tc.opt.GetReplication().Load()
+	replication.EnablePlacementRules = true
+	tc.opt.GetReplication().Store(&replication)
+	tc.ruleManager = placement.NewRuleManager(tc.storage)
+	c.Assert(tc.ruleManager.Initialize(3, []string{"zone"}), IsNil)
+	c.Assert(tc.ruleManager.SetRule(&placement.Rule{
+		GroupID:          "pd",
+		ID:               "z1",
+		Role:             placement.Voter,
+		Count:            2,
+		LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: placement.In, Values: []string{"z1"}}},
+	}), IsNil)
+
+	// Only store 3 in zone z1 is left up.
+	c.Assert(tc.ValidateBatchOffline(&BatchOfflineConfig{StoreIDs: []uint64{1, 2}}), NotNil)
+	c.Assert(tc.ValidateBatchOffline(&BatchOfflineConfig{StoreIDs: []uint64{1, 4}}), IsNil)
+	c.Assert(tc.ValidateBatchOffline(&BatchOfflineConfig{StoreIDs: []uint64{1, 4, 5}}), NotNil)
+}
@@ -0,0 +1,145 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultCacheRebuildDuration is the default duration of a cache rebuild.
+	DefaultCacheRebuildDuration = 10 * time.Minute
+	// MaxCacheRebuildDuration is the longest duration of a cache rebuild.
+	MaxCacheRebuildDuration = time.Hour
+)
+
+var (
+	// ErrCacheRebuildInProgress is error info for starting a cache rebuild
+	// when another one is active.
+	ErrCacheRebuildInProgress = errors.New("cache rebuild in progress")
+	// ErrCacheRebuildBacklog is error info for starting a cache rebuild when
+	// there are operators not finished yet.
+	ErrCacheRebuildBacklog = errors.New("operator backlog exists")
+)
+
+// CacheRebuildStatus is the progress of the last cache rebuild.
+type CacheRebuildStatus struct {
+	Active    bool      `json:"active"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// Refreshed is the number of the regions whose heartbeats overwrote the
+	// cache during the rebuild.
+	Refreshed int `json:"refreshed"`
+	// RegionCount is the number of the regions in the cache.
+	RegionCount int `json:"region_count"`
+}
+
+// cacheRebuild is a window in which the first heartbeat of each region
+// overwrites the cached region as long as its epoch is not older, even if
+// the term is lower or nothing seems changed. It is used to refresh the cache
+// restored from an old backup. It is kept in memory, so it ends when the
+// leader of PD changes. It is threadsafe.
+type cacheRebuild struct {
+	sync.Mutex
+	startTime time.Time
+	endTime   time.Time
+	refreshed map[uint64]struct{}
+	active    bool
+	now       func() time.Time
+}
+
+func newCacheRebuild() *cacheRebuild {
+	return &cacheRebuild{now: time.Now}
+}
+
+func (r *cacheRebuild) start(d time.Duration) error {
+	r.Lock()
+	defer r.Unlock()
+	if r.isActiveLocked() {
+		return ErrCacheRebuildInProgress
+	}
+	r.startTime = r.now()
+	r.endTime = r.startTime.Add(d)
+	r.refreshed = make(map[uint64]struct{})
+	r.active = true
+	log.Warn("region cache rebuild starts, the heartbeats not older than the cache overwrite it",
+		zap.Duration("duration", d), zap.Time("end-time", r.endTime))
+	return nil
+}
+
+// isActiveLocked returns true if the rebuild is not expired. The expiration is
+// found and logged lazily.
+func (r *cacheRebuild) isActiveLocked() bool {
+	if r.active && !r.now().Before(r.endTime) {
+		r.active = false
+		log.Warn("region cache rebuild expires, the heartbeats are checked strictly again",
+			zap.Int("refreshed", len(r.refreshed)))
+	}
+	return r.active
+}
+
+// shouldRefresh returns true if the heartbeat of the region should overwrite
+// the cache.
+func (r *cacheRebuild) shouldRefresh(regionID uint64) bool {
+	r.Lock()
+	defer r.Unlock()
+	if !r.isActiveLocked() {
+		return false
+	}
+	_, ok := r.refreshed[regionID]
+	return !ok
+}
+
+func (r *cacheRebuild) markRefreshed(regionID uint64) {
+	r.Lock()
+	defer r.Unlock()
+	if r.isActiveLocked() {
+		r.refreshed[regionID] = struct{}{}
+	}
+}
+
+func (r *cacheRebuild) status() *CacheRebuildStatus {
+	r.Lock()
+	defer r.Unlock()
+	return &CacheRebuildStatus{
+		Active:    r.isActiveLocked(),
+		StartTime: r.startTime,
+		EndTime:   r.endTime,
+		Refreshed: len(r.refreshed),
+	}
+}
+
+// StartCacheRebuild starts to rebuild the region cache for the duration. It
+// is refused if there are operators not finished yet, as they are created by
+// the stale cache.
+func (c *RaftCluster) StartCacheRebuild(d time.Duration) error {
+	c.RLock()
+	defer c.RUnlock()
+	if n := len(c.coordinator.opController.GetOperators()) + len(c.coordinator.opController.GetWaitingOperators()); n > 0 {
+		return errors.Wrapf(ErrCacheRebuildBacklog, "%d operators are not finished", n)
+	}
+	return c.cacheRebuild.start(d)
+}
+
+// GetCacheRebuildStatus returns the progress of the last cache rebuild.
+func (c *RaftCluster) GetCacheRebuildStatus() *CacheRebuildStatus {
+	status := c.cacheRebuild.status()
+	status.RegionCount = c.GetRegionCount()
+	return status
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/pkg/mock/mockhbstream"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"github.com/pkg/errors"
+)
+
+var _ = Suite(&testCacheRebuildSuite{})
+
+type testCacheRebuildSuite struct{}
+
+func (s *testCacheRebuildSuite) newRegion(leaderStoreID, term uint64) *core.RegionInfo {
+	peers := []*metapb.Peer{{Id: 11, StoreId: 1}, {Id: 12, StoreId: 2}}
+	meta := &metapb.Region{
+		Id:          1,
+		Peers:       peers,
+		StartKey:    []byte("a"),
+		EndKey:      []byte("b"),
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}
+	return core.NewRegionInfo(meta, peers[leaderStoreID-1], core.SetTerm(term))
+}
+
+func (s *testCacheRebuildSuite) TestRebuild(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	tc := newTestCluster(opt)
+	hbStreams := mockhbstream.NewHeartbeatStreams(tc.getClusterID(), true /* no need to run */)
+	defer hbStreams.Close()
+	tc.coordinator = newCoordinator(ctx, tc.RaftCluster, hbStreams)
+	for id := uint64(1); id <= 2; id++ {
+		c.Assert(tc.addRegionStore(id, 1), IsNil)
+	}
+	now := time.Now()
+	tc.cacheRebuild.now = func() time.Time { return now }
+
+	// The cache is restored with a term higher than the stores report.
+	c.Assert(tc.processRegionHeartbeat(s.newRegion(1, 10)), IsNil)
+	c.Assert(errors.Cause(tc.processRegionHeartbeat(s.newRegion(2, 3))), Equals, core.ErrRegionTermIsStale)
+	c.Assert(tc.GetRegion(1).GetLeader().GetStoreId(), Equals, uint64(1))
+
+	// An operator backlog refuses the rebuild.
+	op := newTestOperator(1, tc.GetRegion(1).GetRegionEpoch(), operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	c.Assert(tc.coordinator.opController.AddOperator(op), IsTrue)
+	c.Assert(errors.Cause(tc.StartCacheRebuild(time.Minute)), Equals, ErrCacheRebuildBacklog)
+	tc.coordinator.opController.RemoveOperator(op)
+
+	c.Assert(tc.StartCacheRebuild(time.Minute), IsNil)
+	c.Assert(tc.StartCacheRebuild(time.Minute), Equals, ErrCacheRebuildInProgress)
+	status := tc.GetCacheRebuildStatus()
+	c.Assert(status.Active, IsTrue)
+	c.Assert(status.Refreshed, Equals, 0)
+	c.Assert(status.RegionCount, Equals, 1)
+
+	// The stale entry is replaced by the first heartbeat in the rebuild.
+	c.Assert(tc.processRegionHeartbeat(s.newRegion(2, 3)), IsNil)
+	c.Assert(tc.GetRegion(1).GetLeader().GetStoreId(), Equals, uint64(2))
+	c.Assert(tc.GetRegion(1).GetTerm(), Equals, uint64(3))
+	c.Assert(tc.GetCacheRebuildStatus().Refreshed, Equals, 1)
+	// Only once for each region.
+	c.Assert(errors.Cause(tc.processRegionHeartbeat(s.newRegion(1, 2))), Equals, core.ErrRegionTermIsStale)
+
+	// The strict check resumes after the rebuild expires.
+	now = now.Add(time.Minute)
+	status = tc.GetCacheRebuildStatus()
+	c.Assert(status.Active, IsFalse)
+	c.Assert(status.Refreshed, Equals, 1)
+	c.Assert(tc.StartCacheRebuild(time.Minute), IsNil)
+	now = now.Add(time.Minute)
+	c.Assert(errors.Cause(tc.processRegionHeartbeat(s.newRegion(1, 1))), Equals, core.ErrRegionTermIsStale)
+	c.Assert(tc.GetRegion(1).GetLeader().GetStoreId(), Equals, uint64(2))
+}
@@ -0,0 +1,311 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// CapacitySampleRetentionDays is the number of the days whose capacity
+	// samples are kept.
+	CapacitySampleRetentionDays = 180
+	// minForecastSamples is the least number of the samples to fit a trend.
+	minForecastSamples = 3
+)
+
+// The projected dates which are not a date.
+const (
+	// ForecastUnknown means there is not enough history to fit a trend.
+	ForecastUnknown = "unknown"
+	// ForecastNever means the available space is not decreasing.
+	ForecastNever = "never"
+)
+
+// StoreCapacitySample is the space usage of a store when sampled.
+type StoreCapacitySample struct {
+	StoreID   uint64 `json:"store-id"`
+	Capacity  uint64 `json:"capacity"`
+	Available uint64 `json:"available"`
+	Used      uint64 `json:"used"`
+}
+
+// CapacitySample is the space usage of the cluster in a day, in the local
+// time of PD.
+type CapacitySample struct {
+	Date   string                `json:"date"`
+	Stores []StoreCapacitySample `json:"stores"`
+	// RegionSize is the approximate total size of the regions in MB.
+	RegionSize int64 `json:"region-size"`
+}
+
+// CapacityTrend is the linear trend of the available space fitted over the
+// samples, and the projected dates when the used ratio crosses the high and
+// low space ratios. A projected date is either a date like "2020-05-04", the
+// date of the latest sample if it has been crossed already, or one of
+// "unknown" and "never".
+type CapacityTrend struct {
+	Samples   int    `json:"samples"`
+	Capacity  uint64 `json:"capacity"`
+	Available uint64 `json:"available"`
+	// AvailableGrowth is the growth of the available space in bytes per day,
+	// which is negative if the available space is decreasing.
+	AvailableGrowth float64 `json:"available-growth"`
+	HighSpaceDate   string  `json:"high-space-date"`
+	LowSpaceDate    string  `json:"low-space-date"`
+}
+
+// StoreCapacityForecast is the capacity forecast of a store.
+type StoreCapacityForecast struct {
+	StoreID uint64 `json:"store-id"`
+	CapacityTrend
+}
+
+// ClusterCapacityForecast is the capacity forecast of the sum of the stores.
+type ClusterCapacityForecast struct {
+	CapacityTrend
+	// RegionSizeGrowth is the growth of the total region size in MB per day.
+	RegionSizeGrowth float64 `json:"region-size-growth"`
+	// FirstLowSpaceStore is the store projected to cross the low space ratio
+	// earliest, and FirstLowSpaceDate is the date, which are empty if no store
+	// is projected to.
+	FirstLowSpaceStore uint64 `json:"first-low-space-store,omitempty"`
+	FirstLowSpaceDate  string `json:"first-low-space-date,omitempty"`
+}
+
+// CapacityForecast is the capacity forecast over the recent days.
+type CapacityForecast struct {
+	Days           int                      `json:"days"`
+	HighSpaceRatio float64                  `json:"high-space-ratio"`
+	LowSpaceRatio  float64                  `json:"low-space-ratio"`
+	Cluster        ClusterCapacityForecast  `json:"cluster"`
+	Stores         []*StoreCapacityForecast `json:"stores"`
+}
+
+// CapacitySampler samples the space usage of the stores once a day, and
+// forecasts when the stores run out of space. It is threadsafe.
+type CapacitySampler struct {
+	sync.Mutex
+	storage *core.Storage
+	// lastDate is the date of the latest sample.
+	lastDate string
+	now      func() time.Time
+}
+
+// NewCapacitySampler creates a CapacitySampler instance.
+func NewCapacitySampler(storage *core.Storage) *CapacitySampler {
+	return &CapacitySampler{
+		storage: storage,
+		now:     time.Now,
+	}
+}
+
+// Load loads the date of the latest sample, so that a day is sampled only once
+// after the leader changes.
+func (s *CapacitySampler) Load() error {
+	s.Lock()
+	defer s.Unlock()
+	today := s.now().Format(schedulingReportDateFormat)
+	return s.storage.LoadCapacitySamples(today, func(k, v string) {
+		s.lastDate = k
+	})
+}
+
+// Sample samples the stores and the total region size if today has not been
+// sampled. The tombstone stores are skipped.
+func (s *CapacitySampler) Sample(stores []*core.StoreInfo, regionSize int64) error {
+	s.Lock()
+	defer s.Unlock()
+	now := s.now()
+	date := now.Format(schedulingReportDateFormat)
+	if date == s.lastDate {
+		return nil
+	}
+	sample := &CapacitySample{Date: date, RegionSize: regionSize}
+	for _, store := range stores {
+		if store.IsTombstone() || store.GetStoreStats() == nil {
+			continue
+		}
+		sample.Stores = append(sample.Stores, StoreCapacitySample{
+			StoreID:   store.GetID(),
+			Capacity:  store.GetCapacity(),
+			Available: store.GetAvailable(),
+			Used:      store.GetUsedSize(),
+		})
+	}
+	sort.Slice(sample.Stores, func(i, j int) bool { return sample.Stores[i].StoreID < sample.Stores[j].StoreID })
+	if err := s.storage.SaveCapacitySample(date, sample); err != nil {
+		return err
+	}
+	s.lastDate = date
+	return s.gcLocked(now)
+}
+
+// gcLocked removes the samples out of the retention.
+func (s *CapacitySampler) gcLocked(now time.Time) error {
+	cutoff := now.AddDate(0, 0, -CapacitySampleRetentionDays).Format(schedulingReportDateFormat)
+	var expired []string
+	err := s.storage.LoadCapacitySamples("", func(k, v string) {
+		if k < cutoff {
+			expired = append(expired, k)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for _, date := range expired {
+		if err := s.storage.DeleteCapacitySample(date); err != nil {
+			return err
+		}
+		log.Info("capacity sample expired", zap.String("date", date))
+	}
+	return nil
+}
+
+// GetForecast fits the trends over the samples of the recent days including
+// today, and projects when the used ratio crosses the space ratios.
+func (s *CapacitySampler) GetForecast(days int, highSpaceRatio, lowSpaceRatio float64) (*CapacityForecast, error) {
+	s.Lock()
+	from := s.now().AddDate(0, 0, 1-days).Format(schedulingReportDateFormat)
+	var samples []*CapacitySample
+	err := s.storage.LoadCapacitySamples(from, func(k, v string) {
+		var sample CapacitySample
+		if err := json.Unmarshal([]byte(v), &sample); err != nil {
+			log.Error("failed to unmarshal capacity sample", zap.String("date", k), zap.String("sample", v))
+			return
+		}
+		samples = append(samples, &sample)
+	})
+	s.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	forecast := &CapacityForecast{
+		Days:           days,
+		HighSpaceRatio: highSpaceRatio,
+		LowSpaceRatio:  lowSpaceRatio,
+		Stores:         make([]*StoreCapacityForecast, 0),
+	}
+	var (
+		clusterPoints    []capacityPoint
+		regionSizePoints []capacityPoint
+		storePoints      = make(map[uint64][]capacityPoint)
+	)
+	for _, sample := range samples {
+		date, err := time.ParseInLocation(schedulingReportDateFormat, sample.Date, time.Local)
+		if err != nil {
+			log.Error("invalid capacity sample date", zap.String("date", sample.Date))
+			continue
+		}
+		clusterPoint := capacityPoint{date: date}
+		for _, store := range sample.Stores {
+			storePoints[store.StoreID] = append(storePoints[store.StoreID], capacityPoint{date: date, capacity: store.Capacity, available: store.Available})
+			clusterPoint.capacity += store.Capacity
+			clusterPoint.available += store.Available
+		}
+		clusterPoints = append(clusterPoints, clusterPoint)
+		regionSizePoints = append(regionSizePoints, capacityPoint{date: date, available: uint64(sample.RegionSize)})
+	}
+
+	for id, points := range storePoints {
+		store := &StoreCapacityForecast{StoreID: id, CapacityTrend: fitCapacityTrend(points, highSpaceRatio, lowSpaceRatio)}
+		forecast.Stores = append(forecast.Stores, store)
+	}
+	sort.Slice(forecast.Stores, func(i, j int) bool { return forecast.Stores[i].StoreID < forecast.Stores[j].StoreID })
+	for _, store := range forecast.Stores {
+		if !isForecastDate(store.LowSpaceDate) {
+			continue
+		}
+		if forecast.Cluster.FirstLowSpaceDate == "" || store.LowSpaceDate < forecast.Cluster.FirstLowSpaceDate {
+			forecast.Cluster.FirstLowSpaceStore, forecast.Cluster.FirstLowSpaceDate = store.StoreID, store.LowSpaceDate
+		}
+	}
+	forecast.Cluster.CapacityTrend = fitCapacityTrend(clusterPoints, highSpaceRatio, lowSpaceRatio)
+	if len(regionSizePoints) >= minForecastSamples {
+		forecast.Cluster.RegionSizeGrowth, _ = fitLinear(regionSizePoints)
+	}
+	return forecast, nil
+}
+
+func isForecastDate(date string) bool {
+	return date != ForecastUnknown && date != ForecastNever
+}
+
+type capacityPoint struct {
+	date      time.Time
+	capacity  uint64
+	available uint64
+}
+
+// fitLinear fits the available space against the days since the first point
+// with the least squares, and returns the slope and the intercept.
+func fitLinear(points []capacityPoint) (slope, intercept float64) {
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		x, y := daysBetween(points[0].date, p.date), float64(p.available)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(len(points))
+	if d := n*sumXX - sumX*sumX; d != 0 {
+		slope = (n*sumXY - sumX*sumY) / d
+	}
+	return slope, (sumY - slope*sumX) / n
+}
+
+// daysBetween returns the days between the dates, which is robust to the
+// daylight saving time.
+func daysBetween(from, to time.Time) float64 {
+	return math.Round(to.Sub(from).Hours() / 24)
+}
+
+func fitCapacityTrend(points []capacityPoint, highSpaceRatio, lowSpaceRatio float64) CapacityTrend {
+	trend := CapacityTrend{Samples: len(points), HighSpaceDate: ForecastUnknown, LowSpaceDate: ForecastUnknown}
+	if len(points) == 0 {
+		return trend
+	}
+	last := points[len(points)-1]
+	trend.Capacity, trend.Available = last.capacity, last.available
+	if len(points) < minForecastSamples {
+		return trend
+	}
+	slope, intercept := fitLinear(points)
+	trend.AvailableGrowth = slope
+	project := func(ratio float64) string {
+		threshold := (1 - ratio) * float64(last.capacity)
+		if float64(last.available) <= threshold {
+			return last.date.Format(schedulingReportDateFormat)
+		}
+		if slope >= 0 {
+			return ForecastNever
+		}
+		// The crossing is later than the latest sample, in which the available
+		// space is still above the threshold.
+		days := math.Max((threshold-intercept)/slope, daysBetween(points[0].date, last.date)+1)
+		return points[0].date.AddDate(0, 0, int(math.Ceil(days))).Format(schedulingReportDateFormat)
+	}
+	trend.HighSpaceDate, trend.LowSpaceDate = project(highSpaceRatio), project(lowSpaceRatio)
+	return trend
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/kv"
+)
+
+var _ = Suite(&testCapacityForecastSuite{})
+
+type testCapacityForecastSuite struct{}
+
+func (s *testCapacityForecastSuite) TestCapacityForecast(c *C) {
+	const gb = 1 << 30
+	storage := core.NewStorage(kv.NewMemoryKV())
+	now := time.Date(2020, 5, 4, 12, 0, 0, 0, time.Local)
+	newSampler := func() *CapacitySampler {
+		sampler := NewCapacitySampler(storage)
+		sampler.now = func() time.Time { return now }
+		c.Assert(sampler.Load(), IsNil)
+		return sampler
+	}
+	sampler := newSampler()
+	newStore := func(id, capacity, available uint64) *core.StoreInfo {
+		return core.NewStoreInfo(&metapb.Store{Id: id}, core.SetStoreStats(&pdpb.StoreStats{
+			StoreId:   id,
+			Capacity:  capacity,
+			Available: available,
+			UsedSize:  capacity - available,
+		}))
+	}
+	// The available space of store 1 decreases 5GB a day from 60GB, and the
+	// one of store 2 is stable at 80GB.
+	sample := func(day int) {
+		stores := []*core.StoreInfo{
+			newStore(1, 100*gb, uint64(60-5*day)*gb),
+			newStore(2, 100*gb, 80*gb),
+			newStore(3, 100*gb, 0).Clone(core.SetStoreState(metapb.StoreState_Tombstone)),
+		}
+		c.Assert(sampler.Sample(stores, int64(1000+100*day)), IsNil)
+	}
+	countSamples := func() int {
+		var count int
+		c.Assert(storage.LoadCapacitySamples("", func(k, v string) { count++ }), IsNil)
+		return count
+	}
+
+	for day := 0; day < 2; day++ {
+		sample(day)
+		now = now.AddDate(0, 0, 1)
+	}
+	forecast, err := sampler.GetForecast(30, 0.7, 0.8)
+	c.Assert(err, IsNil)
+	c.Assert(forecast.Stores, HasLen, 2)
+	for _, trend := range []CapacityTrend{forecast.Stores[0].CapacityTrend, forecast.Stores[1].CapacityTrend, forecast.Cluster.CapacityTrend} {
+		c.Assert(trend.Samples, Equals, 2)
+		c.Assert(trend.HighSpaceDate, Equals, ForecastUnknown)
+		c.Assert(trend.LowSpaceDate, Equals, ForecastUnknown)
+	}
+	c.Assert(forecast.Cluster.FirstLowSpaceDate, Equals, "")
+
+	for day := 2; day < 5; day++ {
+		sample(day)
+		// A day is sampled only once.
+		sample(day + 1)
+		now = now.AddDate(0, 0, 1)
+	}
+	c.Assert(countSamples(), Equals, 5)
+	now = now.AddDate(0, 0, -1)
+	forecast, err = sampler.GetForecast(30, 0.7, 0.8)
+	c.Assert(err, IsNil)
+	store1, store2 := forecast.Stores[0], forecast.Stores[1]
+	c.Assert(store1.StoreID, Equals, uint64(1))
+	c.Assert(store1.Samples, Equals, 5)
+	c.Assert(store1.Available, Equals, uint64(40*gb))
+	c.Assert(store1.AvailableGrowth, Equals, float64(-5*gb))
+	// 60GB-5GB*6 = 30GB is 70% used, and 60GB-5GB*8 = 20GB is 80% used.
+	c.Assert(store1.HighSpaceDate, Equals, "2020-05-10")
+	c.Assert(store1.LowSpaceDate, Equals, "2020-05-12")
+	c.Assert(store2.AvailableGrowth, Equals, float64(0))
+	c.Assert(store2.HighSpaceDate, Equals, ForecastNever)
+	c.Assert(store2.LowSpaceDate, Equals, ForecastNever)
+	// 140GB-5GB*16 = 60GB is 70% used, and 140GB-5GB*20 = 40GB is 80% used.
+	c.Assert(forecast.Cluster.Capacity, Equals, uint64(200*gb))
+	c.Assert(forecast.Cluster.HighSpaceDate, Equals, "2020-05-20")
+	c.Assert(forecast.Cluster.LowSpaceDate, Equals, "2020-05-24")
+	c.Assert(forecast.Cluster.RegionSizeGrowth, Equals, float64(100))
+	c.Assert(forecast.Cluster.FirstLowSpaceStore, Equals, uint64(1))
+	c.Assert(forecast.Cluster.FirstLowSpaceDate, Equals, "2020-05-12")
+
+	// The lookback limits the samples.
+	forecast, err = sampler.GetForecast(2, 0.7, 0.8)
+	c.Assert(err, IsNil)
+	c.Assert(forecast.Stores[0].Samples, Equals, 2)
+	c.Assert(forecast.Stores[0].LowSpaceDate, Equals, ForecastUnknown)
+
+	// The crossed ratios are reported as the date of the latest sample.
+	now = now.AddDate(0, 0, 5)
+	sample(9)
+	forecast, err = sampler.GetForecast(30, 0.7, 0.8)
+	c.Assert(err, IsNil)
+	c.Assert(forecast.Stores[0].HighSpaceDate, Equals, "2020-05-13")
+	c.Assert(forecast.Stores[0].LowSpaceDate, Equals, "2020-05-13")
+
+	// A day is sampled only once after the leader changes.
+	sampler = newSampler()
+	sample(10)
+	c.Assert(countSamples(), Equals, 6)
+
+	// The samples out of the retention are removed.
+	now = now.AddDate(0, 0, CapacitySampleRetentionDays-1)
+	sample(0)
+	c.Assert(countSamples(), Equals, 2)
+}
@@ -14,9 +14,12 @@
 package cluster
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -36,6 +39,7 @@ import (
 	syncer "github.com/pingcap/pd/v4/server/region_syncer"
 	"github.com/pingcap/pd/v4/server/schedule"
 	"github.com/pingcap/pd/v4/server/schedule/checker"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
 	"github.com/pingcap/pd/v4/server/schedule/opt"
 	"github.com/pingcap/pd/v4/server/schedule/placement"
 	"github.com/pingcap/pd/v4/server/statistics"
@@ -49,6 +53,13 @@ var backgroundJobInterval = time.Minute
 const (
 	clientTimeout              = 3 * time.Second
 	defaultChangedRegionsLimit = 10000
+	// HeartbeatRateWindow is the window in which the heartbeat rates are
+	// counted, and heartbeatRateBuckets divide it.
+	HeartbeatRateWindow  = time.Minute
+	heartbeatRateBuckets = 60
+	// maxGrowthSplitsPerRound is the max number of the fast growing regions
+	// split by a round of the background jobs.
+	maxGrowthSplitsPerRound = 16
 )
 
 // Server is the interface for cluster.
@@ -93,6 +104,13 @@ type RaftCluster struct {
 	regionStats     *statistics.RegionStatistics
 	storesStats     *statistics.StoresStats
 	hotSpotCache    *statistics.HotCache
+	activityStats   *statistics.RegionActivityStats
+	sizeAgeStats    *statistics.RegionSizeAgeStats
+	churnStats      *statistics.RegionLeaderChurnStats
+	growthStats     *statistics.RegionGrowthStats
+	// storeHeartbeats and regionHeartbeats count the heartbeats handled.
+	storeHeartbeats  *statistics.SlidingCounter
+	regionHeartbeats *statistics.SlidingCounter
 
 	coordinator *coordinator
 
@@ -100,8 +118,27 @@ type RaftCluster struct {
 	quit         chan struct{}
 	regionSyncer *syncer.RegionSyncer
 
-	ruleManager *placement.RuleManager
-	client      *clientv3.Client
+	ruleManager   *placement.RuleManager
+	scheduleLocks *core.ScheduleLocks
+	leaderPins    *core.LeaderPins
+	opQuotas      *OperatorQuotas
+	windows       *MaintenanceWindows
+	templates     *OperatorTemplates
+	restarts      *RollingRestartController
+	batchOffline  *BatchOfflineController
+	splitRange    *SplitRangeController
+	reporter      *SchedulingReporter
+	sampler       *CapacitySampler
+	lineage       *RegionLineage
+	storeHistory  *StoreHistory
+	regionTracer  *core.RegionTracer
+	annotations   *core.RegionAnnotations
+	storageUsage  *storageUsageCache
+	cacheRebuild  *cacheRebuild
+	regionGaps    *regionGapRepair
+	replicaRates  *replicationRates
+	balanceScore  *balanceScoreCache
+	client        *clientv3.Client
 
 	schedulersCallback func()
 	configCheck        bool
@@ -111,6 +148,11 @@ type RaftCluster struct {
 type Status struct {
 	RaftBootstrapTime time.Time `json:"raft_bootstrap_time,omitempty"`
 	IsInitialized     bool      `json:"is_initialized"`
+	// BootstrapStoreVersion is the version of the store which bootstrapped the cluster.
+	BootstrapStoreVersion string `json:"bootstrap_store_version,omitempty"`
+	// RepairFirst is the progress of the repair-first phase, which is absent
+	// if the cluster is not running.
+	RepairFirst *RepairFirstStatus `json:"repair_first,omitempty"`
 }
 
 // NewRaftCluster create a new cluster.
@@ -125,7 +167,7 @@ func NewRaftCluster(ctx context.Context, root string, clusterID uint64, regionSy
 	}
 }
 
-// LoadClusterStatus loads the cluster status.
+// LoadClusterStatus loads the cluster status. The caller should hold the lock.
 func (c *RaftCluster) LoadClusterStatus() (*Status, error) {
 	bootstrapTime, err := c.loadBootstrapTime()
 	if err != nil {
@@ -135,9 +177,15 @@ func (c *RaftCluster) LoadClusterStatus() (*Status, error) {
 	if bootstrapTime != typeutil.ZeroTime {
 		isInitialized = c.isInitialized()
 	}
+	bootstrapStoreVersion, err := c.storage.Load(c.storage.ClusterStatePath("raft_bootstrap_store_version"))
+	if err != nil {
+		return nil, err
+	}
 	return &Status{
-		RaftBootstrapTime: bootstrapTime,
-		IsInitialized:     isInitialized,
+		RaftBootstrapTime:     bootstrapTime,
+		IsInitialized:         isInitialized,
+		BootstrapStoreVersion: bootstrapStoreVersion,
+		RepairFirst:           c.repairFirstStatusLocked(),
 	}, nil
 }
 
@@ -183,7 +231,32 @@ func (c *RaftCluster) InitCluster(id id.Allocator, opt *config.ScheduleOption, s
 	c.storesStats = statistics.NewStoresStats()
 	c.prepareChecker = newPrepareChecker()
 	c.changedRegions = make(chan *core.RegionInfo, defaultChangedRegionsLimit)
-	c.hotSpotCache = statistics.NewHotCache()
+	c.hotSpotCache = statistics.NewHotCache(opt)
+	c.activityStats = statistics.NewRegionActivityStats()
+	c.sizeAgeStats = statistics.NewRegionSizeAgeStats()
+	c.churnStats = statistics.NewRegionLeaderChurnStats()
+	c.growthStats = statistics.NewRegionGrowthStats()
+	c.storeHeartbeats = statistics.NewSlidingCounter(HeartbeatRateWindow, heartbeatRateBuckets)
+	c.regionHeartbeats = statistics.NewSlidingCounter(HeartbeatRateWindow, heartbeatRateBuckets)
+	c.scheduleLocks = core.NewScheduleLocks(storage)
+	c.leaderPins = core.NewLeaderPins(storage)
+	c.opQuotas = NewOperatorQuotas(storage)
+	c.windows = NewMaintenanceWindows(storage)
+	c.templates = NewOperatorTemplates(storage)
+	c.restarts = NewRollingRestartController(storage)
+	c.batchOffline = NewBatchOfflineController(storage)
+	c.splitRange = NewSplitRangeController(storage)
+	c.reporter = NewSchedulingReporter(storage)
+	c.sampler = NewCapacitySampler(storage)
+	c.lineage = NewRegionLineage(storage)
+	c.storeHistory = NewStoreHistory(storage)
+	c.regionTracer = core.NewRegionTracer()
+	c.annotations = core.NewRegionAnnotations()
+	c.storageUsage = newStorageUsageCache(storage)
+	c.cacheRebuild = newCacheRebuild()
+	c.regionGaps = newRegionGapRepair()
+	c.replicaRates = newReplicationRates()
+	c.balanceScore = &balanceScoreCache{}
 	c.schedulersCallback = cb
 }
 
@@ -214,9 +287,58 @@ func (c *RaftCluster) Start(s Server) error {
 		}
 	}
 
+	if err = c.scheduleLocks.Load(); err != nil {
+		return err
+	}
+
+	if err = c.leaderPins.Load(); err != nil {
+		return err
+	}
+
+	if err = c.opQuotas.Load(); err != nil {
+		return err
+	}
+
+	if err = c.windows.Load(); err != nil {
+		return err
+	}
+
+	if err = c.templates.Load(); err != nil {
+		return err
+	}
+
+	if err = c.restarts.Load(); err != nil {
+		return err
+	}
+	if err = c.batchOffline.Load(); err != nil {
+		return err
+	}
+	if err = c.splitRange.Load(); err != nil {
+		return err
+	}
+
+	if err = c.reporter.Load(); err != nil {
+		return err
+	}
+
+	if err = c.sampler.Load(); err != nil {
+		return err
+	}
+
+	if err = c.lineage.Load(); err != nil {
+		return err
+	}
+
+	if err = c.storeHistory.Load(); err != nil {
+		return err
+	}
+
 	c.coordinator = newCoordinator(c.ctx, cluster, s.GetHBStreams())
 	c.regionStats = statistics.NewRegionStatistics(c.opt)
 	c.limiter = NewStoreLimiter(c.coordinator.opController)
+	if err = c.loadStoreLimits(); err != nil {
+		return err
+	}
 	c.quit = make(chan struct{})
 
 	c.wg.Add(3)
@@ -285,6 +407,17 @@ func (c *RaftCluster) runBackgroundJobs(interval time.Duration) {
 			c.checkStores()
 			c.collectMetrics()
 			c.coordinator.opController.PruneHistory()
+			c.observeReplicationRate()
+			c.splitFastGrowingRegions()
+			if err := c.scheduleLocks.GCExpiredLocks(); err != nil {
+				log.Error("failed to remove expired schedule locks", zap.Error(err))
+			}
+			if err := c.reporter.Tick(len(c.GetRegionStatsByType(statistics.MissPeer))); err != nil {
+				log.Error("failed to checkpoint scheduling report", zap.Error(err))
+			}
+			if err := c.sampler.Sample(c.GetStores(), c.core.GetAverageRegionSize()*int64(c.core.GetRegionCount())); err != nil {
+				log.Error("failed to sample capacity", zap.Error(err))
+			}
 		}
 	}
 }
@@ -382,6 +515,7 @@ func (c *RaftCluster) SetStorage(s *core.Storage) {
 
 // HandleStoreHeartbeat updates the store status.
 func (c *RaftCluster) HandleStoreHeartbeat(stats *pdpb.StoreStats) error {
+	c.storeHeartbeats.Add(1)
 	c.Lock()
 	defer c.Unlock()
 
@@ -391,18 +525,25 @@ func (c *RaftCluster) HandleStoreHeartbeat(stats *pdpb.StoreStats) error {
 		return core.NewStoreNotFoundErr(storeID)
 	}
 	newStore := store.Clone(core.SetStoreStats(stats), core.SetLastHeartbeatTS(time.Now()))
+	if start := store.GetStoreStats().GetStartTime(); start != 0 && stats.GetStartTime() != start {
+		newStore = c.recordStoreRestart(newStore)
+	}
 	if newStore.IsLowSpace(c.GetLowSpaceRatio()) {
 		log.Warn("store does not have enough disk space",
 			zap.Uint64("store-id", newStore.GetID()),
 			zap.Uint64("capacity", newStore.GetCapacity()),
 			zap.Uint64("available", newStore.GetAvailable()))
 	}
+	c.storeHistory.observe(newStore, c.core.GetStoreRegionCount(storeID))
 	if newStore.NeedPersist() && c.storage != nil {
 		if err := c.storage.SaveStore(store.GetMeta()); err != nil {
 			log.Error("failed to persist store", zap.Uint64("store-id", newStore.GetID()))
 		} else {
 			newStore = newStore.Clone(core.SetLastPersistTime(time.Now()))
 		}
+		if err := c.storeHistory.flush(storeID); err != nil {
+			log.Error("failed to persist the store lifecycle", zap.Uint64("store-id", storeID), zap.Error(err))
+		}
 	}
 	c.core.PutStore(newStore)
 	c.storesStats.Observe(newStore.GetID(), newStore.GetStoreStats())
@@ -416,28 +557,73 @@ func (c *RaftCluster) HandleStoreHeartbeat(stats *pdpb.StoreStats) error {
 	return nil
 }
 
+// countStaleTerm counts the heartbeat rejected for the stale term.
+func countStaleTerm(err error) {
+	if errors.Cause(err) == core.ErrRegionTermIsStale {
+		regionEventCounter.WithLabelValues("stale_term").Inc()
+	}
+}
+
+// isRangeExtended checks if the range of the region is extended from the
+// origin, which happens when other regions are merged into it.
+func isRangeExtended(origin, region *core.RegionInfo) bool {
+	if bytes.Compare(region.GetStartKey(), origin.GetStartKey()) < 0 {
+		return true
+	}
+	return len(origin.GetEndKey()) > 0 &&
+		(len(region.GetEndKey()) == 0 || bytes.Compare(region.GetEndKey(), origin.GetEndKey()) > 0)
+}
+
+// recordStoreRestart records a restart of the store detected by the changed
+// start time in the heartbeats, and detects whether the store is flapping.
+func (c *RaftCluster) recordStoreRestart(store *core.StoreInfo) *core.StoreInfo {
+	wasFlapping := store.IsFlapping()
+	restarts := store.GetRestarts().Record(time.Now(), c.opt.GetStoreFlappingRestartLimit(),
+		c.opt.GetStoreFlappingWindow(), c.opt.GetStoreFlappingCooldown())
+	store = store.Clone(core.SetStoreRestarts(restarts))
+	storeRestartEventCounter.WithLabelValues("restart").Inc()
+	log.Info("store restarted",
+		zap.Uint64("store-id", store.GetID()),
+		zap.Int("restart-count", restarts.Count))
+	if store.IsFlapping() && !wasFlapping {
+		storeRestartEventCounter.WithLabelValues("flapping").Inc()
+		log.Warn("store is flapping, stop scheduling to it",
+			zap.Uint64("store-id", store.GetID()),
+			zap.Time("until", restarts.FlappingUntil))
+	}
+	return store
+}
+
 // processRegionHeartbeat updates the region information.
 func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
+	c.regionHeartbeats.Add(1)
+	// A region is refreshed once in a cache rebuild, even if its term is lower
+	// than the cached one.
+	rebuild := c.cacheRebuild.shouldRefresh(region.GetID())
+	// The region covering a gap in the cache is put even if nothing changes.
+	repairGap := c.regionGaps.shouldRepair(region)
 	c.RLock()
 	origin, err := c.core.PreCheckPutRegion(region)
-	if err != nil {
+	if err != nil && !(rebuild && errors.Cause(err) == core.ErrRegionTermIsStale) {
 		c.RUnlock()
+		countStaleTerm(err)
 		return err
 	}
 	writeItems := c.CheckWriteStatus(region)
 	readItems := c.CheckReadStatus(region)
+	isHot := c.hotSpotCache.IsRegionHot(region, c.GetHotRegionCacheHitsThreshold())
 	c.RUnlock()
 
 	// Save to storage if meta is updated.
 	// Save to cache if meta or leader is updated, or contains any down/pending peer.
 	// Mark isNew if the region in cache does not have leader.
-	var saveKV, saveCache, isNew, statsChange bool
+	var saveKV, saveCache, isNew, statsChange, epochChanged bool
 	if origin == nil {
 		log.Debug("insert new region",
 			zap.Uint64("region-id", region.GetID()),
 			zap.Stringer("meta-region", core.RegionToHexMeta(region.GetMeta())),
 		)
-		saveKV, saveCache, isNew = true, true, true
+		saveKV, saveCache, isNew, epochChanged = true, true, true, true
 	} else {
 		r := region.GetRegionEpoch()
 		o := origin.GetRegionEpoch()
@@ -448,7 +634,7 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 				zap.Uint64("old-version", o.GetVersion()),
 				zap.Uint64("new-version", r.GetVersion()),
 			)
-			saveKV, saveCache = true, true
+			saveKV, saveCache, epochChanged = true, true, true
 		}
 		if r.GetConfVer() > o.GetConfVer() {
 			log.Info("region ConfVer changed",
@@ -457,7 +643,7 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 				zap.Uint64("old-confver", o.GetConfVer()),
 				zap.Uint64("new-confver", r.GetConfVer()),
 			)
-			saveKV, saveCache = true, true
+			saveKV, saveCache, epochChanged = true, true, true
 		}
 		if region.GetLeader().GetId() != origin.GetLeader().GetId() {
 			if origin.GetLeader().GetId() == 0 {
@@ -471,6 +657,9 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 			}
 			saveCache = true
 		}
+		if region.GetTerm() > origin.GetTerm() {
+			saveCache = true
+		}
 		if len(region.GetDownPeers()) > 0 || len(region.GetPendingPeers()) > 0 {
 			saveCache = true
 		}
@@ -493,6 +682,15 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 			saveCache, statsChange = true, true
 		}
 	}
+	if rebuild || repairGap {
+		saveKV, saveCache = true, true
+	}
+
+	// Idle regions usually return early, so classify the region before that.
+	c.activityStats.Observe(region, isHot, epochChanged)
+	c.sizeAgeStats.Observe(region)
+	c.churnStats.Observe(origin, region)
+	c.growthStats.Observe(region)
 
 	if len(writeItems) == 0 && len(readItems) == 0 && !saveKV && !saveCache && !isNew {
 		return nil
@@ -502,17 +700,32 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 		time.Sleep(500 * time.Millisecond)
 	})
 
+	// merged is the regions merged into the region.
+	var merged []uint64
 	c.Lock()
+	// The region only differs from the cached one in the flow stats, so replace
+	// it in place rather than rebuilding the region trees.
+	if saveCache && !saveKV && !isNew && core.IsOnlyFlowChanged(origin, region) && c.core.UpdateRegionFlow(origin, region) {
+		saveCache = false
+		regionEventCounter.WithLabelValues("update_flow").Inc()
+	}
 	if saveCache {
 		// To prevent a concurrent heartbeat of another region from overriding the up-to-date region info by a stale one,
 		// check its validation again here.
 		//
 		// However it can't solve the race condition of concurrent heartbeats from the same region.
-		if _, err := c.core.PreCheckPutRegion(region); err != nil {
+		if _, err := c.core.PreCheckPutRegion(region); err != nil && !(rebuild && errors.Cause(err) == core.ErrRegionTermIsStale) {
 			c.Unlock()
+			countStaleTerm(err)
 			return err
 		}
 		overlaps := c.core.PutRegion(region)
+		if rebuild {
+			c.cacheRebuild.markRefreshed(region.GetID())
+		}
+		if repairGap {
+			regionCacheGapCounter.WithLabelValues("repaired").Add(float64(c.regionGaps.repaired(region)))
+		}
 		if c.storage != nil {
 			for _, item := range overlaps {
 				if err := c.storage.DeleteRegion(item.GetMeta()); err != nil {
@@ -523,11 +736,25 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 				}
 			}
 		}
+		if origin != nil && isRangeExtended(origin, region) {
+			for _, item := range overlaps {
+				if item.GetID() != region.GetID() {
+					merged = append(merged, item.GetID())
+				}
+			}
+		}
 		for _, item := range overlaps {
 			if c.regionStats != nil {
 				c.regionStats.ClearDefunctRegion(item.GetID())
 			}
 			c.labelLevelStats.ClearDefunctRegion(item.GetID(), c.GetLocationLabels())
+			c.activityStats.ClearDefunctRegion(item.GetID())
+			c.sizeAgeStats.ClearDefunctRegion(item.GetID())
+			c.churnStats.ClearDefunctRegion(item.GetID())
+			c.growthStats.ClearDefunctRegion(item.GetID())
+			if item.GetID() != region.GetID() {
+				c.annotations.Remove(item.GetID())
+			}
 		}
 
 		// Update related stores.
@@ -558,6 +785,13 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 	}
 	c.Unlock()
 
+	if len(merged) > 0 {
+		parents := append([]uint64{region.GetID()}, merged...)
+		if err := c.lineage.RecordMerge(parents, region.GetID(), region.GetStartKey(), region.GetEndKey()); err != nil {
+			log.Error("failed to record region merge", zap.Uint64("region-id", region.GetID()), zap.Error(err))
+		}
+	}
+
 	// If there are concurrent heartbeats from the same region, the last write will win even if
 	// writes to storage in the critical area. So don't use mutex to protect it.
 	if saveKV && c.storage != nil {
@@ -629,12 +863,24 @@ func (c *RaftCluster) GetRegionInfoByKey(regionKey []byte) *core.RegionInfo {
 	return c.core.SearchRegion(regionKey)
 }
 
+// GetPrevRegionInfoByKey gets the regionInfo of the previous region by the
+// region key from cluster.
+func (c *RaftCluster) GetPrevRegionInfoByKey(regionKey []byte) *core.RegionInfo {
+	return c.core.SearchPrevRegion(regionKey)
+}
+
 // ScanRegions scans region with start key, until the region contains endKey, or
 // total number greater than limit.
 func (c *RaftCluster) ScanRegions(startKey, endKey []byte, limit int) []*core.RegionInfo {
 	return c.core.ScanRange(startKey, endKey, limit)
 }
 
+// ScanStoreRegions scans region with start key which has a peer of the role on
+// the store, until the region contains endKey, or total number greater than limit.
+func (c *RaftCluster) ScanStoreRegions(storeID uint64, role core.PeerRole, startKey, endKey []byte, limit int) []*core.RegionInfo {
+	return c.core.ScanStoreRange(storeID, role, startKey, endKey, limit)
+}
+
 // GetRegionByID gets region and leader peer by regionID from cluster.
 func (c *RaftCluster) GetRegionByID(regionID uint64) (*metapb.Region, *metapb.Peer) {
 	region := c.GetRegion(regionID)
@@ -669,6 +915,18 @@ func (c *RaftCluster) GetStoreRegions(storeID uint64) []*core.RegionInfo {
 	return c.core.GetStoreRegions(storeID)
 }
 
+// GetStoreRegionsByRole returns the regions which have a peer of the role on
+// the store.
+func (c *RaftCluster) GetStoreRegionsByRole(storeID uint64, role core.PeerRole) []*core.RegionInfo {
+	return c.core.GetStoreRegionsByRole(storeID, role)
+}
+
+// GetStoreRegionCountByRole returns the number of the regions which have a
+// peer of the role on the store.
+func (c *RaftCluster) GetStoreRegionCountByRole(storeID uint64, role core.PeerRole) int {
+	return c.core.GetStoreRegionCountByRole(storeID, role)
+}
+
 // RandLeaderRegion returns a random region that has leader on the store.
 func (c *RaftCluster) RandLeaderRegion(storeID uint64, ranges []core.KeyRange, opts ...core.RegionOption) *core.RegionInfo {
 	return c.core.RandLeaderRegion(storeID, ranges, opts...)
@@ -737,6 +995,12 @@ func (c *RaftCluster) GetRegionStats(startKey, endKey []byte) *statistics.Region
 	return statistics.GetRegionStats(c.core.ScanRange(startKey, endKey, -1))
 }
 
+// GetHeartbeatRates returns the store and region heartbeats handled per
+// second in the recent window.
+func (c *RaftCluster) GetHeartbeatRates() (store, region float64) {
+	return c.storeHeartbeats.Rate(), c.regionHeartbeats.Rate()
+}
+
 // GetStoresStats returns stores' statistics from cluster.
 func (c *RaftCluster) GetStoresStats() *statistics.StoresStats {
 	c.RLock()
@@ -787,6 +1051,12 @@ func (c *RaftCluster) GetAdjacentRegions(region *core.RegionInfo) (*core.RegionI
 	return c.core.GetAdjacentRegions(region)
 }
 
+// GetRegionWithAdjacentByKey returns the region covering the key and its
+// adjacent regions, which are read at the same time.
+func (c *RaftCluster) GetRegionWithAdjacentByKey(regionKey []byte) (*core.RegionInfo, *core.RegionInfo, *core.RegionInfo) {
+	return c.core.SearchRegionWithAdjacent(regionKey)
+}
+
 // UpdateStoreLabels updates a store's location labels
 // If 'force' is true, then update the store's labels forcibly.
 func (c *RaftCluster) UpdateStoreLabels(storeID uint64, labels []*metapb.StoreLabel, force bool) error {
@@ -1018,11 +1288,194 @@ func (c *RaftCluster) SetStoreWeight(storeID uint64, leaderWeight, regionWeight
 	return c.putStoreLocked(newStore)
 }
 
+// SetStoreAnnotation sets up a store's nickname and metadata. They are only
+// used for display and the schedulers ignore them.
+func (c *RaftCluster) SetStoreAnnotation(storeID uint64, annotation *core.StoreAnnotation) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.GetStore(storeID)
+	if store == nil {
+		return core.NewStoreNotFoundErr(storeID)
+	}
+	if err := annotation.Validate(); err != nil {
+		return err
+	}
+
+	if c.storage != nil {
+		if err := c.storage.SaveStoreAnnotation(storeID, annotation); err != nil {
+			return err
+		}
+	}
+	if annotation.IsEmpty() {
+		annotation = nil
+	}
+	log.Info("store annotation changed",
+		zap.Uint64("store-id", storeID),
+		zap.Reflect("old", store.GetAnnotation()),
+		zap.Reflect("new", annotation))
+	c.core.PutStore(store.Clone(core.SetStoreAnnotation(annotation)))
+	return nil
+}
+
+// SetStoreSpaceFloor sets up the minimum free space reserved on a store, below
+// which the store is never a target of the new replicas.
+func (c *RaftCluster) SetStoreSpaceFloor(storeID uint64, floor *core.StoreSpaceFloor) error {
+	c.Lock()
+	defer c.Unlock()
+
+	store := c.GetStore(storeID)
+	if store == nil {
+		return core.NewStoreNotFoundErr(storeID)
+	}
+	if err := floor.Validate(); err != nil {
+		return err
+	}
+
+	if c.storage != nil {
+		if err := c.storage.SaveStoreSpaceFloor(storeID, floor); err != nil {
+			return err
+		}
+	}
+	if floor.IsEmpty() {
+		floor = nil
+	}
+	log.Info("store space floor changed",
+		zap.Uint64("store-id", storeID),
+		zap.Reflect("old", store.GetSpaceFloor()),
+		zap.Reflect("new", floor))
+	c.core.PutStore(store.Clone(core.SetStoreSpaceFloor(floor)))
+	return nil
+}
+
+// manualStoreLimit is a manual store limit persisted in the storage, so that
+// it survives the restarts and the leader changes.
+type manualStoreLimit struct {
+	Rate float64 `json:"rate"`
+	Mode string  `json:"mode"`
+}
+
+// SetStoreLimit sets and persists the manual limit of a store.
+func (c *RaftCluster) SetStoreLimit(storeID uint64, rate float64) error {
+	if c.GetStore(storeID) == nil {
+		return core.NewStoreNotFoundErr(storeID)
+	}
+	if c.storage != nil {
+		limit := &manualStoreLimit{Rate: rate, Mode: schedule.StoreLimitManual.String()}
+		if err := c.storage.SaveStoreLimit(storeID, limit); err != nil {
+			return err
+		}
+	}
+	c.coordinator.opController.SetStoreLimit(storeID, rate, schedule.StoreLimitManual)
+	return nil
+}
+
+// SetAllStoresLimit sets and persists the manual limit of all stores.
+func (c *RaftCluster) SetAllStoresLimit(rate float64) error {
+	if c.storage != nil {
+		limit := &manualStoreLimit{Rate: rate, Mode: schedule.StoreLimitManual.String()}
+		for _, store := range c.GetStores() {
+			if store.IsTombstone() {
+				continue
+			}
+			if err := c.storage.SaveStoreLimit(store.GetID(), limit); err != nil {
+				return err
+			}
+		}
+	}
+	c.coordinator.opController.SetAllStoresLimit(rate, schedule.StoreLimitManual)
+	return nil
+}
+
+// RemoveStoreLimit removes the manual limit of a store, so that the limit is
+// set by PD itself again.
+func (c *RaftCluster) RemoveStoreLimit(storeID uint64) error {
+	store := c.GetStore(storeID)
+	if store == nil {
+		return core.NewStoreNotFoundErr(storeID)
+	}
+	if store.IsTombstone() {
+		return errcode.Op("store.limit").AddTo(core.StoreTombstonedErr{StoreID: storeID})
+	}
+	if c.storage != nil {
+		if err := c.storage.DeleteStoreLimit(storeID); err != nil {
+			return err
+		}
+	}
+	c.coordinator.opController.ResetStoreLimit(storeID, c.autoStoreLimitRate())
+	return nil
+}
+
+// RemoveAllStoresLimit removes the manual limits of all stores.
+func (c *RaftCluster) RemoveAllStoresLimit() error {
+	if c.storage != nil {
+		for _, store := range c.GetStores() {
+			if store.IsTombstone() {
+				continue
+			}
+			if err := c.storage.DeleteStoreLimit(store.GetID()); err != nil {
+				return err
+			}
+		}
+	}
+	c.coordinator.opController.ResetAllStoresLimit(c.autoStoreLimitRate())
+	return nil
+}
+
+// autoStoreLimitRate returns the rate of the store limits set by PD itself,
+// which is by the scene of the current load if it is known.
+func (c *RaftCluster) autoStoreLimitRate() float64 {
+	if c.limiter != nil {
+		if rate := c.limiter.CurrentRate(); rate > 0 {
+			return rate
+		}
+	}
+	return c.opt.GetStoreBalanceRate() / schedule.StoreBalanceBaseTime
+}
+
+// loadStoreLimits restores the persisted manual store limits, and drops the
+// ones of the stores no longer in the cluster.
+func (c *RaftCluster) loadStoreLimits() error {
+	if c.storage == nil {
+		return nil
+	}
+	var removed []uint64
+	err := c.storage.LoadStoreLimits(func(k, v string) {
+		storeID, err := strconv.ParseUint(k, 10, 64)
+		if err != nil {
+			log.Error("failed to parse the store limit key", zap.String("key", k), zap.Error(err))
+			return
+		}
+		if c.GetStore(storeID) == nil {
+			removed = append(removed, storeID)
+			return
+		}
+		var limit manualStoreLimit
+		if err := json.Unmarshal([]byte(v), &limit); err != nil {
+			log.Error("failed to load the store limit", zap.Uint64("store-id", storeID), zap.Error(err))
+			return
+		}
+		c.coordinator.opController.SetStoreLimit(storeID, limit.Rate, schedule.StoreLimitManual)
+	})
+	if err != nil {
+		return err
+	}
+	for _, storeID := range removed {
+		if err := c.storage.DeleteStoreLimit(storeID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *RaftCluster) putStoreLocked(store *core.StoreInfo) error {
 	if c.storage != nil {
 		if err := c.storage.SaveStore(store.GetMeta()); err != nil {
 			return err
 		}
+		if err := c.storeHistory.onPut(c.GetStore(store.GetID()), store); err != nil {
+			log.Error("failed to record the store lifecycle", zap.Uint64("store-id", store.GetID()), zap.Error(err))
+		}
 	}
 	c.core.PutStore(store)
 	c.storesStats.CreateRollingStoreStats(store.GetID())
@@ -1072,11 +1525,40 @@ func (c *RaftCluster) checkStores() {
 	}
 }
 
-// RemoveTombStoneRecords removes the tombStone Records.
-func (c *RaftCluster) RemoveTombStoneRecords() error {
+// GetStoreResidualRegions returns the cached regions which still have peers on
+// the offline or tombstone store.
+func (c *RaftCluster) GetStoreResidualRegions(storeID uint64) ([]*core.RegionInfo, error) {
+	store := c.GetStore(storeID)
+	if store == nil {
+		return nil, core.NewStoreNotFoundErr(storeID)
+	}
+	if store.IsUp() {
+		return nil, errcode.NewInvalidInputErr(errors.Errorf("store %v is neither offline nor tombstone", storeID))
+	}
+	return c.core.GetStoreRegions(storeID), nil
+}
+
+// RemoveTombStoneRecords removes the tombStone Records. It refuses to remove
+// any store if some tombstone stores still have residual peers in the cached
+// regions, unless force is true.
+func (c *RaftCluster) RemoveTombStoneRecords(force bool) error {
 	c.Lock()
 	defer c.Unlock()
 
+	for _, store := range c.GetStores() {
+		if !store.IsTombstone() {
+			continue
+		}
+		if count := len(c.core.GetStoreRegions(store.GetID())); count > 0 {
+			if !force {
+				return core.StoreResidualPeersErr{StoreID: store.GetID(), Count: count}
+			}
+			log.Warn("remove tombstone store with residual peers",
+				zap.Uint64("store-id", store.GetID()),
+				zap.Int("residual-peer-count", count))
+		}
+	}
+
 	for _, store := range c.GetStores() {
 		if store.IsTombstone() {
 			// the store has already been tombstone
@@ -1088,6 +1570,11 @@ func (c *RaftCluster) RemoveTombStoneRecords() error {
 				return err
 			}
 			c.coordinator.opController.RemoveStoreLimit(store.GetID())
+			if c.storage != nil {
+				if err := c.storage.DeleteStoreLimit(store.GetID()); err != nil {
+					return err
+				}
+			}
 			log.Info("delete store succeeded",
 				zap.Stringer("store", store.GetMeta()))
 		}
@@ -1100,6 +1587,9 @@ func (c *RaftCluster) deleteStoreLocked(store *core.StoreInfo) error {
 		if err := c.storage.DeleteStore(store.GetMeta()); err != nil {
 			return err
 		}
+		if err := c.storeHistory.onRemove(store); err != nil {
+			log.Error("failed to record the store lifecycle", zap.Uint64("store-id", store.GetID()), zap.Error(err))
+		}
 	}
 	c.core.DeleteStore(store)
 	c.storesStats.RemoveRollingStoreStats(store.GetID())
@@ -1113,6 +1603,8 @@ func (c *RaftCluster) collectMetrics() {
 		statsMap.Observe(s, c.storesStats)
 	}
 	statsMap.Collect()
+	c.collectResidualPeerMetrics(stores)
+	c.collectBalanceScoreMetrics()
 
 	c.coordinator.collectSchedulerMetrics()
 	c.coordinator.collectHotSpotMetrics()
@@ -1120,9 +1612,21 @@ func (c *RaftCluster) collectMetrics() {
 	c.collectHealthStatus()
 }
 
+func (c *RaftCluster) collectResidualPeerMetrics(stores []*core.StoreInfo) {
+	residualPeersGauge.Reset()
+	for _, s := range stores {
+		if s.IsTombstone() {
+			count := len(c.core.GetStoreRegions(s.GetID()))
+			residualPeersGauge.WithLabelValues(strconv.FormatUint(s.GetID(), 10)).Set(float64(count))
+		}
+	}
+}
+
 func (c *RaftCluster) resetMetrics() {
 	statsMap := statistics.NewStoreStatisticsMap(c.opt)
 	statsMap.Reset()
+	residualPeersGauge.Reset()
+	balanceScoreGauge.Reset()
 
 	c.coordinator.resetSchedulerMetrics()
 	c.coordinator.resetHotSpotMetrics()
@@ -1137,6 +1641,8 @@ func (c *RaftCluster) collectClusterMetrics() {
 	}
 	c.regionStats.Collect()
 	c.labelLevelStats.Collect()
+	c.activityStats.Collect()
+	c.churnStats.Collect()
 	// collect hot cache metrics
 	c.hotSpotCache.CollectMetrics(c.storesStats)
 }
@@ -1149,6 +1655,8 @@ func (c *RaftCluster) resetClusterMetrics() {
 	}
 	c.regionStats.Reset()
 	c.labelLevelStats.Reset()
+	c.activityStats.Reset()
+	c.churnStats.Reset()
 	// reset hot cache metrics
 	c.hotSpotCache.ResetMetrics()
 }
@@ -1178,6 +1686,16 @@ func (c *RaftCluster) GetRegionStatsByType(typ statistics.RegionStatisticType) [
 	return c.regionStats.GetRegionStatsByType(typ)
 }
 
+// GetRegionStatsCount gets the count of the regions of the status type.
+func (c *RaftCluster) GetRegionStatsCount(typ statistics.RegionStatisticType) int {
+	c.RLock()
+	defer c.RUnlock()
+	if c.regionStats == nil {
+		return 0
+	}
+	return c.regionStats.GetRegionStatsCount(typ)
+}
+
 func (c *RaftCluster) updateRegionsLabelLevelStats(regions []*core.RegionInfo) {
 	c.Lock()
 	defer c.Unlock()
@@ -1281,6 +1799,27 @@ func (c *RaftCluster) GetMergeChecker() *checker.MergeChecker {
 	return c.coordinator.checkers.GetMergeChecker()
 }
 
+// ExplainRegionCheck checks the region in the explain mode of the checkers,
+// and returns how it is handled by the patrol, and why no operator is created
+// if so. It returns nil if the region does not exist.
+func (c *RaftCluster) ExplainRegionCheck(regionID uint64) *checker.Explanation {
+	c.RLock()
+	co := c.coordinator
+	c.RUnlock()
+	region := c.GetRegion(regionID)
+	if region == nil {
+		return nil
+	}
+	explanation := co.checkers.ExplainRegion(region)
+	if co.isCheckersPaused() {
+		explanation.Notes = append(explanation.Notes, "the checkers are paused by a maintenance window")
+	}
+	if op := co.opController.GetOperator(regionID); op != nil {
+		explanation.Notes = append(explanation.Notes, fmt.Sprintf("the region has operator %s running", op.Desc()))
+	}
+	return explanation
+}
+
 // GetOpt returns the scheduling options.
 func (c *RaftCluster) GetOpt() *config.ScheduleOption {
 	return c.opt
@@ -1336,6 +1875,52 @@ func (c *RaftCluster) GetSchedulerMaxWaitingOperator() uint64 {
 	return c.opt.GetSchedulerMaxWaitingOperator()
 }
 
+// GetHotWriteThresholds returns the min write byte rate and key rate of a hot peer.
+func (c *RaftCluster) GetHotWriteThresholds() (float64, float64) {
+	return c.opt.GetHotWriteThresholds()
+}
+
+// GetHotReadThresholds returns the min read byte rate and key rate of a hot leader.
+func (c *RaftCluster) GetHotReadThresholds() (float64, float64) {
+	return c.opt.GetHotReadThresholds()
+}
+
+// GetOperatorPingPongLimit returns the number of the operators finished for a
+// region in the window at which the new operators for the region are guarded.
+func (c *RaftCluster) GetOperatorPingPongLimit() uint64 {
+	return c.opt.GetOperatorPingPongLimit()
+}
+
+// GetOperatorPingPongWindow returns the window in which the operators finished
+// for a region are counted.
+func (c *RaftCluster) GetOperatorPingPongWindow() time.Duration {
+	return c.opt.GetOperatorPingPongWindow()
+}
+
+// GetOperatorPingPongAction returns how the new operators for a region are
+// guarded, either rejected or deferred.
+func (c *RaftCluster) GetOperatorPingPongAction() string {
+	return c.opt.GetOperatorPingPongAction()
+}
+
+// GetScatterGroupTTL returns how long the stores selected for an idle scatter
+// group are kept.
+func (c *RaftCluster) GetScatterGroupTTL() time.Duration {
+	return c.opt.GetScatterGroupTTL()
+}
+
+// GetScatterStoreSelectionLimit returns the number of times a store is
+// selected in a scatter group before it is excluded from the candidates.
+func (c *RaftCluster) GetScatterStoreSelectionLimit() uint64 {
+	return c.opt.GetScatterStoreSelectionLimit()
+}
+
+// IsScatterBusyStoresIncluded returns if the busy stores are selected when
+// scattering regions.
+func (c *RaftCluster) IsScatterBusyStoresIncluded() bool {
+	return c.opt.IsScatterBusyStoresIncluded()
+}
+
 // GetMaxSnapshotCount returns the number of the max snapshot which is allowed to send.
 func (c *RaftCluster) GetMaxSnapshotCount() uint64 {
 	return c.opt.GetMaxSnapshotCount()
@@ -1529,6 +2114,317 @@ func (c *RaftCluster) GetRuleManager() *placement.RuleManager {
 	return c.ruleManager
 }
 
+// GetScheduleLocks returns the schedule locks reference.
+func (c *RaftCluster) GetScheduleLocks() *core.ScheduleLocks {
+	c.RLock()
+	defer c.RUnlock()
+	return c.scheduleLocks
+}
+
+// AddScheduleLock protects the key range [startKey, endKey) from being merged
+// or balanced until the ttl expires.
+func (c *RaftCluster) AddScheduleLock(startKey, endKey []byte, ttl time.Duration) (*core.ScheduleLock, error) {
+	id, err := c.AllocID()
+	if err != nil {
+		return nil, err
+	}
+	lock := &core.ScheduleLock{
+		ID:         id,
+		StartKey:   startKey,
+		EndKey:     endKey,
+		ExpireTime: time.Now().Add(ttl),
+	}
+	if err := c.GetScheduleLocks().AddLock(lock); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// IsRegionScheduleLocked returns true if the region overlaps with any schedule lock.
+func (c *RaftCluster) IsRegionScheduleLocked(region *core.RegionInfo) bool {
+	return c.GetScheduleLocks().IsRegionLocked(region)
+}
+
+// GetLeaderPins returns the leader pins reference.
+func (c *RaftCluster) GetLeaderPins() *core.LeaderPins {
+	c.RLock()
+	defer c.RUnlock()
+	return c.leaderPins
+}
+
+// IsRegionLeaderPinned returns true if the leader of the region is pinned.
+func (c *RaftCluster) IsRegionLeaderPinned(regionID uint64) bool {
+	return c.GetLeaderPins().IsRegionPinned(regionID)
+}
+
+// PinRegionLeader pins the leader of the region on the store, or on any store
+// matching all the labels if the store is 0.
+func (c *RaftCluster) PinRegionLeader(regionID, storeID uint64, labels []*metapb.StoreLabel) (*core.LeaderPin, error) {
+	if c.GetRegion(regionID) == nil {
+		return nil, errcode.NewNotFoundErr(errors.Errorf("region %d not found", regionID))
+	}
+	switch {
+	case storeID == 0 && len(labels) == 0:
+		return nil, errcode.NewInvalidInputErr(errors.New("either the store or the labels should be specified"))
+	case storeID != 0 && len(labels) != 0:
+		return nil, errcode.NewInvalidInputErr(errors.New("the store and the labels should not be both specified"))
+	case storeID != 0 && c.GetStore(storeID) == nil:
+		return nil, core.NewStoreNotFoundErr(storeID)
+	}
+	pin := &core.LeaderPin{
+		RegionID:   regionID,
+		StoreID:    storeID,
+		Labels:     labels,
+		CreateTime: time.Now(),
+	}
+	if err := c.GetLeaderPins().SetPin(pin); err != nil {
+		return nil, err
+	}
+	return pin, nil
+}
+
+// GetLeaderPinStatus returns the status of the leader pins sorted by the
+// region ID.
+func (c *RaftCluster) GetLeaderPinStatus() []*LeaderPinStatus {
+	c.RLock()
+	co := c.coordinator
+	c.RUnlock()
+	return co.pinChecker.getStatus(c.GetLeaderPins().GetPins())
+}
+
+// GetOperatorQuotas returns the operator quotas reference.
+func (c *RaftCluster) GetOperatorQuotas() *OperatorQuotas {
+	c.RLock()
+	defer c.RUnlock()
+	return c.opQuotas
+}
+
+// GetCapacitySampler returns the capacity sampler reference.
+func (c *RaftCluster) GetCapacitySampler() *CapacitySampler {
+	c.RLock()
+	defer c.RUnlock()
+	return c.sampler
+}
+
+// GetRegionLineage returns the region lineage reference.
+func (c *RaftCluster) GetRegionLineage() *RegionLineage {
+	c.RLock()
+	defer c.RUnlock()
+	return c.lineage
+}
+
+// GetStoreHistory returns the store history reference.
+func (c *RaftCluster) GetStoreHistory() *StoreHistory {
+	c.RLock()
+	defer c.RUnlock()
+	return c.storeHistory
+}
+
+// GetSchedulingReporter returns the scheduling reporter reference.
+func (c *RaftCluster) GetSchedulingReporter() *SchedulingReporter {
+	c.RLock()
+	defer c.RUnlock()
+	return c.reporter
+}
+
+// GetMaintenanceWindows returns the maintenance windows reference.
+func (c *RaftCluster) GetMaintenanceWindows() *MaintenanceWindows {
+	c.RLock()
+	defer c.RUnlock()
+	return c.windows
+}
+
+// GetOperatorTemplates returns the operator templates reference.
+func (c *RaftCluster) GetOperatorTemplates() *OperatorTemplates {
+	c.RLock()
+	defer c.RUnlock()
+	return c.templates
+}
+
+// GetRollingRestart returns the rolling restart, or nil if there is none.
+func (c *RaftCluster) GetRollingRestart() *RollingRestart {
+	c.RLock()
+	defer c.RUnlock()
+	return c.restarts.Get()
+}
+
+// ValidateRollingRestart checks the config and the stores of a rolling restart.
+func (c *RaftCluster) ValidateRollingRestart(cfg *RollingRestartConfig) error {
+	if len(cfg.StoreIDs) == 0 {
+		return errors.New("store-ids should not be empty")
+	}
+	if cfg.PendingPeerTolerance < 0 {
+		return errors.New("pending-peer-tolerance should not be negative")
+	}
+	ids := make(map[uint64]struct{}, len(cfg.StoreIDs))
+	for _, id := range cfg.StoreIDs {
+		if _, ok := ids[id]; ok {
+			return errors.Errorf("duplicated store %d", id)
+		}
+		ids[id] = struct{}{}
+		store := c.GetStore(id)
+		if store == nil {
+			return core.NewStoreNotFoundErr(id)
+		}
+		if store.IsTombstone() {
+			return errors.Errorf("store %d is tombstone", id)
+		}
+	}
+	return nil
+}
+
+// StartRollingRestart validates the config and starts a rolling restart.
+func (c *RaftCluster) StartRollingRestart(cfg *RollingRestartConfig) (*RollingRestart, error) {
+	if err := c.ValidateRollingRestart(cfg); err != nil {
+		return nil, err
+	}
+	c.RLock()
+	defer c.RUnlock()
+	return c.restarts.Start(cfg)
+}
+
+// PauseRollingRestart pauses the running rolling restart.
+func (c *RaftCluster) PauseRollingRestart() error {
+	c.RLock()
+	defer c.RUnlock()
+	return c.restarts.Pause()
+}
+
+// ResumeRollingRestart resumes the paused rolling restart.
+func (c *RaftCluster) ResumeRollingRestart() error {
+	c.RLock()
+	defer c.RUnlock()
+	return c.restarts.Resume()
+}
+
+// AbortRollingRestart aborts the running or paused rolling restart.
+func (c *RaftCluster) AbortRollingRestart() error {
+	c.RLock()
+	defer c.RUnlock()
+	return c.restarts.Abort(c.coordinator)
+}
+
+// GetBatchOffline returns the batch offline, or nil if there is none. The
+// lock of the cluster is not held, as the controller takes it to change the
+// states of the stores while holding its own lock.
+func (c *RaftCluster) GetBatchOffline() *BatchOffline {
+	return c.batchOffline.Get()
+}
+
+// ValidateBatchOffline checks the config of a batch offline, and that the
+// stores left up can still hold the replicas, which are the max replicas, or
+// the count of each placement rule on the stores matching its constraints.
+func (c *RaftCluster) ValidateBatchOffline(cfg *BatchOfflineConfig) error {
+	if len(cfg.StoreIDs) == 0 {
+		return errors.New("store-ids should not be empty")
+	}
+	if cfg.MaxConcurrent < 0 {
+		return errors.New("max-concurrent should not be negative")
+	}
+	ids := make(map[uint64]struct{}, len(cfg.StoreIDs))
+	for _, id := range cfg.StoreIDs {
+		if _, ok := ids[id]; ok {
+			return errors.Errorf("duplicated store %d", id)
+		}
+		ids[id] = struct{}{}
+		store := c.GetStore(id)
+		if store == nil {
+			return core.NewStoreNotFoundErr(id)
+		}
+		if !store.IsUp() {
+			return errors.Errorf("store %d is not up", id)
+		}
+	}
+
+	return c.checkStoresLeftUp(ids)
+}
+
+// StartBatchOffline validates the config and starts a batch offline.
+func (c *RaftCluster) StartBatchOffline(cfg *BatchOfflineConfig) (*BatchOffline, error) {
+	if err := c.ValidateBatchOffline(cfg); err != nil {
+		return nil, err
+	}
+	return c.batchOffline.Start(cfg)
+}
+
+// AbortBatchOffline aborts the running batch offline. The lock of the cluster
+// is not held, as the draining stores are brought back up.
+func (c *RaftCluster) AbortBatchOffline() error {
+	return c.batchOffline.Abort(c)
+}
+
+// GetRegionTracer returns the region tracer reference.
+func (c *RaftCluster) GetRegionTracer() *core.RegionTracer {
+	return c.regionTracer
+}
+
+// GetRegionAnnotations returns the region annotations reference.
+func (c *RaftCluster) GetRegionAnnotations() *core.RegionAnnotations {
+	return c.annotations
+}
+
+// GetRegionActivity returns the activity class of the region, which is
+// classified when the region heartbeats.
+func (c *RaftCluster) GetRegionActivity(regionID uint64) (statistics.RegionActivity, bool) {
+	return c.activityStats.GetRegionActivity(regionID)
+}
+
+// GetCoordinatorStartTime returns the time when the coordinator starts, since
+// when the operator history and the hot region statistics are collected.
+func (c *RaftCluster) GetCoordinatorStartTime() time.Time {
+	return c.coordinator.startTime
+}
+
+// GetRegionSizeAgeDistribution returns the distribution of how long the
+// approximate size and keys of the regions have not changed.
+func (c *RaftCluster) GetRegionSizeAgeDistribution() []*statistics.RegionSizeAgeBucket {
+	return c.sizeAgeStats.GetDistribution()
+}
+
+// GetLeaderChurnRegions returns at most top regions with the most leader
+// changes in the recent window.
+func (c *RaftCluster) GetLeaderChurnRegions(top int) []*statistics.RegionLeaderChurn {
+	return c.churnStats.GetTopRegions(top)
+}
+
+// GetRegionGrowthTopGrowers returns at most top regions growing in size, the
+// fastest first.
+func (c *RaftCluster) GetRegionGrowthTopGrowers(top int) []*statistics.RegionGrowth {
+	return c.growthStats.GetTopGrowers(top)
+}
+
+// splitFastGrowingRegions splits the regions whose approximate sizes grow
+// faster than the growth split rate, before they become too large. The
+// regions not larger than twice the max merge size are skipped, otherwise
+// the halves would be merged back.
+func (c *RaftCluster) splitFastGrowingRegions() int {
+	c.growthStats.EvictStale()
+	rate := c.opt.GetGrowthSplitRate()
+	if rate <= 0 {
+		return 0
+	}
+	minSize := 2 * int64(c.opt.GetMaxMergeRegionSize())
+	var count int
+	for _, growth := range c.growthStats.GetTopGrowers(maxGrowthSplitsPerRound) {
+		if growth.SizeRate < rate {
+			break
+		}
+		region := c.GetRegion(growth.RegionID)
+		if region == nil || region.GetApproximateSize() <= minSize {
+			continue
+		}
+		op := operator.CreateSplitRegionOperator("growth-split-region", region, 0, pdpb.CheckPolicy_APPROXIMATE, nil)
+		op.SetReason(operator.NewReason(operator.ReasonFastGrowth, 0, 0).
+			With("size-rate", growth.SizeRate).
+			With("growth-split-rate", rate))
+		if c.coordinator.opController.AddOperator(op) {
+			regionEventCounter.WithLabelValues("growth_split").Inc()
+			count++
+		}
+	}
+	return count
+}
+
 // FitRegion tries to fit the region with placement rules.
 func (c *RaftCluster) FitRegion(region *core.RegionInfo) *placement.RegionFit {
 	return c.GetRuleManager().FitRegion(c, region)
@@ -1598,6 +2494,9 @@ func (c *RaftCluster) GetHotReadRegions() *statistics.StoreHotPeersInfos {
 func (c *RaftCluster) GetSchedulers() map[string]*scheduleController {
 	c.RLock()
 	defer c.RUnlock()
+	if !c.running {
+		return nil
+	}
 	return c.coordinator.getSchedulers()
 }
 
@@ -1605,6 +2504,9 @@ func (c *RaftCluster) GetSchedulers() map[string]*scheduleController {
 func (c *RaftCluster) AddScheduler(scheduler schedule.Scheduler, args ...string) error {
 	c.Lock()
 	defer c.Unlock()
+	if !c.running {
+		return ErrClusterStopping
+	}
 	return c.coordinator.addScheduler(scheduler, args...)
 }
 
@@ -1612,16 +2514,81 @@ func (c *RaftCluster) AddScheduler(scheduler schedule.Scheduler, args ...string)
 func (c *RaftCluster) RemoveScheduler(name string) error {
 	c.Lock()
 	defer c.Unlock()
+	if !c.running {
+		return ErrClusterStopping
+	}
 	return c.coordinator.removeScheduler(name)
 }
 
+// GetSchedulerDetail returns the running status of a scheduler.
+func (c *RaftCluster) GetSchedulerDetail(name string) (*SchedulerDetail, error) {
+	c.RLock()
+	defer c.RUnlock()
+	if !c.running {
+		return nil, ErrClusterStopping
+	}
+	return c.coordinator.getSchedulerDetail(name)
+}
+
+// GetSchedulerStatuses returns the statuses of the running and the disabled
+// schedulers.
+func (c *RaftCluster) GetSchedulerStatuses() ([]*SchedulerStatus, error) {
+	c.RLock()
+	defer c.RUnlock()
+	if !c.running {
+		return nil, ErrClusterStopping
+	}
+	return c.coordinator.getSchedulerStatuses(), nil
+}
+
+// GetSchedulerEffectiveConfig returns the config items a scheduler uses at
+// schedule time.
+func (c *RaftCluster) GetSchedulerEffectiveConfig(name string) (*SchedulerEffectiveConfig, error) {
+	c.RLock()
+	defer c.RUnlock()
+	if !c.running {
+		return nil, ErrClusterStopping
+	}
+	return c.coordinator.getSchedulerEffectiveConfig(name)
+}
+
 // PauseOrResumeScheduler pauses or resumes a scheduler.
 func (c *RaftCluster) PauseOrResumeScheduler(name string, t int64) error {
 	c.RLock()
 	defer c.RUnlock()
+	if !c.running {
+		return ErrClusterStopping
+	}
 	return c.coordinator.pauseOrResumeScheduler(name, t)
 }
 
+// ObserveScheduler sets whether a scheduler only logs its operators.
+func (c *RaftCluster) ObserveScheduler(name string, observe bool) error {
+	c.RLock()
+	defer c.RUnlock()
+	if !c.running {
+		return ErrClusterStopping
+	}
+	return c.coordinator.observeScheduler(name, observe)
+}
+
+// GetObservedOperators returns the recent operators logged by a scheduler in
+// the observe mode.
+func (c *RaftCluster) GetObservedOperators(name string) ([]*ObservedOperator, error) {
+	return c.coordinator.getObservedOperators(name)
+}
+
+// LoadPlugin loads the plugin and returns the name of the created scheduler.
+// See coordinator.LoadPlugin for the channels.
+func (c *RaftCluster) LoadPlugin(pluginPath string, ch chan string, ack chan error, exited chan struct{}) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+	if !c.running {
+		return "", ErrClusterStopping
+	}
+	return c.coordinator.LoadPlugin(pluginPath, ch, ack, exited)
+}
+
 // GetStoreLimiter returns the dynamic adjusting limiter
 func (c *RaftCluster) GetStoreLimiter() *StoreLimiter {
 	return c.limiter
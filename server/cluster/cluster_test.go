@@ -14,8 +14,10 @@
 package cluster
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -24,12 +26,17 @@ import (
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/pkg/mock/mockhbstream"
 	"github.com/pingcap/pd/v4/pkg/mock/mockid"
 	"github.com/pingcap/pd/v4/server/config"
 	"github.com/pingcap/pd/v4/server/core"
 	"github.com/pingcap/pd/v4/server/id"
 	"github.com/pingcap/pd/v4/server/kv"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
 	"github.com/pingcap/pd/v4/server/schedule/opt"
+	"github.com/pkg/errors"
+	promtestutil "github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func Test(t *testing.T) {
@@ -86,6 +93,32 @@ func (s *testClusterInfoSuite) TestStoreHeartbeat(c *C) {
 	}
 }
 
+func (s *testClusterInfoSuite) TestStoreRestart(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, core.NewStorage(kv.NewMemoryKV()), core.NewBasicCluster())
+	store := newTestStores(1)[0]
+	c.Assert(cluster.putStoreLocked(store), IsNil)
+	heartbeat := func(startTime uint32) *core.StoreInfo {
+		c.Assert(cluster.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: store.GetID(), StartTime: startTime}), IsNil)
+		return cluster.GetStore(store.GetID())
+	}
+
+	// The first heartbeat after PD starts is not a restart.
+	c.Assert(heartbeat(100).GetRestarts(), IsNil)
+	c.Assert(heartbeat(100).GetRestarts(), IsNil)
+	for i := 1; i <= int(opt.GetStoreFlappingRestartLimit()); i++ {
+		restarts := heartbeat(100 + uint32(i)).GetRestarts()
+		c.Assert(restarts.Count, Equals, i)
+		c.Assert(cluster.GetStore(store.GetID()).IsFlapping(), IsFalse)
+	}
+	// The restart going backwards is also counted.
+	s1 := heartbeat(50)
+	c.Assert(s1.GetRestarts().Count, Equals, int(opt.GetStoreFlappingRestartLimit())+1)
+	c.Assert(s1.IsFlapping(), IsTrue)
+	c.Assert(heartbeat(50).IsFlapping(), IsTrue)
+}
+
 func (s *testClusterInfoSuite) TestRegionHeartbeat(c *C) {
 	_, opt, err := newTestScheduleConfig()
 	c.Assert(err, IsNil)
@@ -311,6 +344,121 @@ func (s *testClusterInfoSuite) TestRegionHeartbeat(c *C) {
 	}
 }
 
+func (s *testClusterInfoSuite) TestRegionHeartbeatFlowOnly(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, core.NewStorage(kv.NewMemoryKV()), core.NewBasicCluster())
+	stores := newTestStores(3)
+	for _, store := range stores {
+		c.Assert(cluster.putStoreLocked(store), IsNil)
+	}
+	regions := newTestRegions(3, 3)
+	for _, region := range regions {
+		c.Assert(cluster.processRegionHeartbeat(region), IsNil)
+	}
+
+	fastPath := regionEventCounter.WithLabelValues("update_flow")
+	heartbeat := func(region *core.RegionInfo, expectFastPath bool) {
+		hits := promtestutil.ToFloat64(fastPath)
+		regions[region.GetID()] = region
+		c.Assert(cluster.processRegionHeartbeat(region), IsNil)
+		if expectFastPath {
+			c.Assert(promtestutil.ToFloat64(fastPath), Equals, hits+1)
+		} else {
+			c.Assert(promtestutil.ToFloat64(fastPath), Equals, hits)
+		}
+
+		// The cache should be the same as the one which puts all regions normally.
+		checkRegions(c, cluster.core.Regions, regions)
+		checkRegionsKV(c, cluster.storage, regions)
+		expected := core.NewBasicCluster()
+		for _, region := range regions {
+			expected.PutRegion(region)
+			checkRegion(c, cluster.core.SearchRegion(region.GetStartKey()), region)
+		}
+		for _, store := range stores {
+			id := store.GetID()
+			c.Assert(cluster.core.GetStoreRegionSize(id), Equals, expected.GetStoreRegionSize(id))
+			c.Assert(cluster.core.GetStoreLeaderRegionSize(id), Equals, expected.GetStoreLeaderRegionSize(id))
+			c.Assert(cluster.core.GetStorePendingPeerCount(id), Equals, expected.GetStorePendingPeerCount(id))
+			c.Assert(cluster.GetStore(id).GetRegionSize(), Equals, expected.GetStoreRegionSize(id))
+		}
+	}
+
+	var flow uint64
+	withFlow := func(region *core.RegionInfo) *core.RegionInfo {
+		flow++
+		return region.Clone(core.SetWrittenBytes(flow*1024), core.SetReadKeys(flow))
+	}
+	for _, region := range regions {
+		region = withFlow(region)
+		heartbeat(region, true)
+		// Unchanged heartbeats return early.
+		heartbeat(region, false)
+
+		region = region.Clone(core.WithLeader(region.GetPeers()[1]))
+		heartbeat(region, false)
+		region = withFlow(region)
+		heartbeat(region, true)
+
+		region = withFlow(region.Clone(core.WithIncVersion()))
+		heartbeat(region, false)
+		region = withFlow(region)
+		heartbeat(region, true)
+
+		region = withFlow(region.Clone(core.SetApproximateSize(int64(flow))))
+		heartbeat(region, false)
+		region = withFlow(region)
+		heartbeat(region, true)
+
+		region = withFlow(region.Clone(core.WithPendingPeers(region.GetPeers()[2:])))
+		heartbeat(region, false)
+		region = withFlow(region.Clone(core.WithPendingPeers(nil)))
+		heartbeat(region, false)
+		region = withFlow(region)
+		heartbeat(region, true)
+	}
+}
+
+func (s *testClusterInfoSuite) TestRegionHeartbeatStaleTerm(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, core.NewStorage(kv.NewMemoryKV()), core.NewBasicCluster())
+	for _, store := range newTestStores(3) {
+		c.Assert(cluster.putStoreLocked(store), IsNil)
+	}
+	region := newTestRegions(1, 3)[0]
+	// The former leader on store 1 is partitioned away, and keeps reporting
+	// the same epoch as the new leader on store 2 elected in a higher term.
+	oldLeader := region.Clone(core.WithLeader(region.GetPeers()[0]), core.SetTerm(5))
+	newLeader := region.Clone(core.WithLeader(region.GetPeers()[1]), core.SetTerm(6))
+	rejected := regionEventCounter.WithLabelValues("stale_term")
+
+	c.Assert(cluster.processRegionHeartbeat(oldLeader), IsNil)
+	c.Assert(cluster.GetRegion(region.GetID()).GetTerm(), Equals, uint64(5))
+	var flow uint64
+	for i := 0; i < 3; i++ {
+		flow++
+		c.Assert(cluster.processRegionHeartbeat(newLeader.Clone(core.SetWrittenBytes(flow))), IsNil)
+		cached := cluster.GetRegion(region.GetID())
+		c.Assert(cached.GetLeader().GetStoreId(), Equals, newLeader.GetLeader().GetStoreId())
+		c.Assert(cached.GetTerm(), Equals, uint64(6))
+
+		hits := promtestutil.ToFloat64(rejected)
+		flow++
+		err := cluster.processRegionHeartbeat(oldLeader.Clone(core.SetWrittenBytes(flow)))
+		c.Assert(errors.Cause(err), Equals, core.ErrRegionTermIsStale)
+		c.Assert(promtestutil.ToFloat64(rejected), Equals, hits+1)
+		cached = cluster.GetRegion(region.GetID())
+		c.Assert(cached.GetLeader().GetStoreId(), Equals, newLeader.GetLeader().GetStoreId())
+		c.Assert(cached.GetBytesWritten(), Equals, flow-1)
+	}
+
+	// The term is not compared if unknown.
+	c.Assert(cluster.processRegionHeartbeat(oldLeader.Clone(core.SetTerm(0))), IsNil)
+	c.Assert(cluster.GetRegion(region.GetID()).GetLeader().GetStoreId(), Equals, oldLeader.GetLeader().GetStoreId())
+}
+
 func (s *testClusterInfoSuite) TestConcurrentRegionHeartbeat(c *C) {
 	_, opt, err := newTestScheduleConfig()
 	c.Assert(err, IsNil)
@@ -478,6 +626,150 @@ func (s *testClusterInfoSuite) TestUpdateStorePendingPeerCount(c *C) {
 	checkPendingPeerCount([]int{0, 0, 0, 1}, tc.RaftCluster, c)
 }
 
+func (s *testClusterInfoSuite) TestSplitFastGrowingRegions(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	tc := newTestCluster(opt)
+	hbStreams := mockhbstream.NewHeartbeatStreams(tc.getClusterID(), true /* no need to run */)
+	defer hbStreams.Close()
+	tc.coordinator = newCoordinator(ctx, tc.RaftCluster, hbStreams)
+
+	newRegion := func(id uint64, size int64) *core.RegionInfo {
+		peer := &metapb.Peer{Id: id + 100, StoreId: 1}
+		meta := &metapb.Region{
+			Id:          id,
+			Peers:       []*metapb.Peer{peer},
+			StartKey:    []byte{byte(id)},
+			EndKey:      []byte{byte(id + 1)},
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+		}
+		return core.NewRegionInfo(meta, peer, core.SetApproximateSize(size))
+	}
+	// Region 1 grows fast, region 2 grows slowly, and region 3 grows fast but
+	// is too small to split.
+	for id, size := range map[uint64]int64{1: 100, 2: 100, 3: 10} {
+		c.Assert(tc.processRegionHeartbeat(newRegion(id, size)), IsNil)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	for id, size := range map[uint64]int64{1: 200, 2: 101, 3: 30} {
+		c.Assert(tc.processRegionHeartbeat(newRegion(id, size)), IsNil)
+	}
+
+	growers := tc.GetRegionGrowthTopGrowers(10)
+	c.Assert(growers, HasLen, 3)
+	c.Assert(growers[0].RegionID, Equals, uint64(1))
+	c.Assert(growers[1].RegionID, Equals, uint64(3))
+	c.Assert(growers[2].RegionID, Equals, uint64(2))
+
+	// Disabled by default.
+	c.Assert(tc.splitFastGrowingRegions(), Equals, 0)
+	cfg.GrowthSplitRate = 100
+	c.Assert(tc.splitFastGrowingRegions(), Equals, 1)
+	op := tc.coordinator.opController.GetOperator(1)
+	c.Assert(op, NotNil)
+	c.Assert(op.Kind()&operator.OpSplit, Equals, operator.OpSplit)
+	c.Assert(op.Reason().Code, Equals, operator.ReasonFastGrowth)
+	c.Assert(tc.coordinator.opController.GetOperator(3), IsNil)
+	// The region is not split twice while the operator is running.
+	c.Assert(tc.splitFastGrowingRegions(), Equals, 0)
+}
+
+func (s *testClusterInfoSuite) TestStoreAnnotation(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	storage := core.NewStorage(kv.NewMemoryKV())
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, storage, core.NewBasicCluster())
+	store := &metapb.Store{Id: 1, Address: "mock://tikv-1", Version: "2.0.0"}
+	c.Assert(cluster.PutStore(store, false), IsNil)
+
+	annotation := &core.StoreAnnotation{Nickname: "tikv-rack3-slot2", Metadata: map[string]string{"sn": "A123"}}
+	c.Assert(cluster.SetStoreAnnotation(2, annotation), NotNil)
+	c.Assert(cluster.SetStoreAnnotation(1, &core.StoreAnnotation{Nickname: strings.Repeat("a", 100)}), NotNil)
+	c.Assert(cluster.SetStoreAnnotation(1, annotation), IsNil)
+	c.Assert(cluster.GetStore(1).GetAnnotation(), DeepEquals, annotation)
+
+	// The annotation survives the store restart.
+	c.Assert(cluster.PutStore(store, false), IsNil)
+	c.Assert(cluster.GetStore(1).GetAnnotation(), DeepEquals, annotation)
+
+	// The annotation is loaded from storage after PD restarts.
+	cluster = newTestRaftCluster(mockid.NewIDAllocator(), opt, storage, core.NewBasicCluster())
+	c.Assert(storage.LoadStores(cluster.core.PutStore), IsNil)
+	c.Assert(cluster.GetStore(1).GetAnnotation(), DeepEquals, annotation)
+
+	c.Assert(cluster.SetStoreAnnotation(1, &core.StoreAnnotation{}), IsNil)
+	c.Assert(cluster.GetStore(1).GetAnnotation(), IsNil)
+}
+
+func (s *testClusterInfoSuite) TestStoreLimitPersistence(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	storage := core.NewStorage(kv.NewMemoryKV())
+	newCluster := func() *RaftCluster {
+		cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, storage, core.NewBasicCluster())
+		c.Assert(storage.LoadStores(cluster.core.PutStore), IsNil)
+		cluster.coordinator = newCoordinator(ctx, cluster, mockhbstream.NewHeartbeatStreams(cluster.getClusterID(), true))
+		c.Assert(cluster.loadStoreLimits(), IsNil)
+		return cluster
+	}
+	cluster := newCluster()
+	for id := uint64(1); id <= 2; id++ {
+		c.Assert(cluster.PutStore(&metapb.Store{Id: id, Address: fmt.Sprintf("mock://tikv-%d", id), Version: "2.0.0"}, false), IsNil)
+	}
+	c.Assert(cluster.SetStoreLimit(3, 5), NotNil)
+	c.Assert(cluster.SetStoreLimit(1, 5), IsNil)
+	// The limit of a store no longer in the cluster is dropped when loading.
+	c.Assert(storage.SaveStoreLimit(3, &manualStoreLimit{Rate: 5, Mode: schedule.StoreLimitManual.String()}), IsNil)
+
+	// The manual limit survives the restart.
+	cluster = newCluster()
+	limits := cluster.GetOperatorController().GetAllStoresLimit()
+	c.Assert(limits, HasLen, 1)
+	c.Assert(limits[1].Rate(), Equals, float64(5))
+	c.Assert(limits[1].Mode(), Equals, schedule.StoreLimitManual)
+	var keys []string
+	c.Assert(storage.LoadStoreLimits(func(k, v string) { keys = append(keys, k) }), IsNil)
+	c.Assert(keys, HasLen, 1)
+
+	c.Assert(cluster.SetAllStoresLimit(2), IsNil)
+	cluster = newCluster()
+	limits = cluster.GetOperatorController().GetAllStoresLimit()
+	c.Assert(limits, HasLen, 2)
+	c.Assert(limits[2].Rate(), Equals, float64(2))
+
+	// The removed limit falls back to the automatic one and is not persisted.
+	c.Assert(cluster.RemoveStoreLimit(3), NotNil)
+	c.Assert(cluster.RemoveStoreLimit(2), IsNil)
+	limits = cluster.GetOperatorController().GetAllStoresLimit()
+	c.Assert(limits[2].Mode(), Equals, schedule.StoreLimitAuto)
+	c.Assert(limits[2].Rate(), Equals, opt.GetStoreBalanceRate()/schedule.StoreBalanceBaseTime)
+	c.Assert(limits[1].Mode(), Equals, schedule.StoreLimitManual)
+	// Removing it again is a no-op.
+	c.Assert(cluster.RemoveStoreLimit(2), IsNil)
+	cluster = newCluster()
+	limits = cluster.GetOperatorController().GetAllStoresLimit()
+	c.Assert(limits, HasLen, 1)
+	c.Assert(limits[1].Mode(), Equals, schedule.StoreLimitManual)
+
+	c.Assert(cluster.RemoveAllStoresLimit(), IsNil)
+	c.Assert(cluster.GetOperatorController().GetAllStoresLimit()[1].Mode(), Equals, schedule.StoreLimitAuto)
+	keys = keys[:0]
+	c.Assert(storage.LoadStoreLimits(func(k, v string) { keys = append(keys, k) }), IsNil)
+	c.Assert(keys, HasLen, 0)
+	c.Assert(cluster.SetAllStoresLimit(2), IsNil)
+
+	// The limit is removed along with the tombstone store.
+	c.Assert(cluster.BuryStore(1, true), IsNil)
+	c.Assert(cluster.RemoveTombStoneRecords(false), IsNil)
+	keys = keys[:0]
+	c.Assert(storage.LoadStoreLimits(func(k, v string) { keys = append(keys, k) }), IsNil)
+	c.Assert(keys, DeepEquals, []string{fmt.Sprintf("%020d", 2)})
+}
+
 var _ = Suite(&testStoresInfoSuite{})
 
 type testStoresInfoSuite struct{}
@@ -799,3 +1091,42 @@ func checkStaleRegion(origin *metapb.Region, region *metapb.Region) error {
 
 	return nil
 }
+
+func BenchmarkProcessRegionHeartbeat(b *testing.B) {
+	_, opt, err := newTestScheduleConfig()
+	if err != nil {
+		b.Fatal(err)
+	}
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, core.NewStorage(kv.NewMemoryKV()), core.NewBasicCluster())
+	n := uint64(100)
+	for _, store := range newTestStores(n) {
+		if err := cluster.putStoreLocked(store); err != nil {
+			b.Fatal(err)
+		}
+	}
+	regions := newTestRegions(n, 3)
+	for _, region := range regions {
+		if err := cluster.processRegionHeartbeat(region); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	// The heartbeats only change the flow stats, which take the fast path.
+	b.Run("flow-only", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			region := regions[uint64(i)%n].Clone(core.SetWrittenBytes(uint64(i)))
+			if err := cluster.processRegionHeartbeat(region); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	// The heartbeats also change the approximate size, which update the region trees.
+	b.Run("size-changed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			region := regions[uint64(i)%n].Clone(core.SetWrittenBytes(uint64(i)), core.SetApproximateSize(int64(i)))
+			if err := cluster.processRegionHeartbeat(region); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
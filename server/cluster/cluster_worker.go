@@ -196,6 +196,10 @@ func (c *RaftCluster) HandleReportSplit(request *pdpb.ReportSplitRequest) (*pdpb
 	log.Info("region split, generate new region",
 		zap.Uint64("region-id", originRegion.GetId()),
 		zap.Stringer("region-meta", core.RegionToHexMeta(left)))
+	c.reporter.ObserveSplit(1)
+	if err := c.lineage.RecordSplit(originRegion.GetId(), []uint64{left.GetId(), right.GetId()}, left.GetStartKey(), right.GetEndKey()); err != nil {
+		log.Error("failed to record region split", zap.Uint64("region-id", originRegion.GetId()), zap.Error(err))
+	}
 	return &pdpb.ReportSplitResponse{}, nil
 }
 
@@ -218,5 +222,13 @@ func (c *RaftCluster) HandleBatchReportSplit(request *pdpb.ReportBatchSplitReque
 		zap.Uint64("region-id", originRegion.GetId()),
 		zap.Stringer("origin", hrm),
 		zap.Int("total", last))
+	c.reporter.ObserveSplit(last)
+	children := make([]uint64, 0, len(regions))
+	for _, region := range regions {
+		children = append(children, region.GetId())
+	}
+	if err := c.lineage.RecordSplit(originRegion.GetId(), children, regions[0].GetStartKey(), regions[last].GetEndKey()); err != nil {
+		log.Error("failed to record region split", zap.Uint64("region-id", originRegion.GetId()), zap.Error(err))
+	}
 	return &pdpb.ReportBatchSplitResponse{}, nil
 }
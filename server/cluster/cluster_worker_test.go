@@ -27,23 +27,33 @@ var _ = Suite(&testClusterWorkerSuite{})
 type testClusterWorkerSuite struct{}
 
 func (s *testClusterWorkerSuite) TestReportSplit(c *C) {
-	var cluster RaftCluster
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestCluster(opt)
 	left := &metapb.Region{Id: 1, StartKey: []byte("a"), EndKey: []byte("b")}
 	right := &metapb.Region{Id: 2, StartKey: []byte("b"), EndKey: []byte("c")}
-	_, err := cluster.HandleReportSplit(&pdpb.ReportSplitRequest{Left: left, Right: right})
+	_, err = cluster.HandleReportSplit(&pdpb.ReportSplitRequest{Left: left, Right: right})
 	c.Assert(err, IsNil)
 	_, err = cluster.HandleReportSplit(&pdpb.ReportSplitRequest{Left: right, Right: left})
 	c.Assert(err, NotNil)
+	summary, err := cluster.GetSchedulingReporter().GetSummary(1)
+	c.Assert(err, IsNil)
+	c.Assert(summary.Total.Splits, Equals, 1)
 }
 
 func (s *testClusterWorkerSuite) TestReportBatchSplit(c *C) {
-	var cluster RaftCluster
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	cluster := newTestCluster(opt)
 	regions := []*metapb.Region{
 		{Id: 1, StartKey: []byte(""), EndKey: []byte("a")},
 		{Id: 2, StartKey: []byte("a"), EndKey: []byte("b")},
 		{Id: 3, StartKey: []byte("b"), EndKey: []byte("c")},
 		{Id: 3, StartKey: []byte("c"), EndKey: []byte("")},
 	}
-	_, err := cluster.HandleBatchReportSplit(&pdpb.ReportBatchSplitRequest{Regions: regions})
+	_, err = cluster.HandleBatchReportSplit(&pdpb.ReportBatchSplitRequest{Regions: regions})
+	c.Assert(err, IsNil)
+	summary, err := cluster.GetSchedulingReporter().GetSummary(1)
 	c.Assert(err, IsNil)
+	c.Assert(summary.Total.Splits, Equals, 3)
 }
@@ -15,7 +15,10 @@ package cluster
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -23,6 +26,8 @@ import (
 	"github.com/pingcap/log"
 	"github.com/pingcap/pd/v4/pkg/logutil"
 	"github.com/pingcap/pd/v4/server/config"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/kv"
 	"github.com/pingcap/pd/v4/server/schedule"
 	"github.com/pingcap/pd/v4/server/schedule/operator"
 	"github.com/pingcap/pd/v4/server/schedule/opt"
@@ -38,6 +43,18 @@ const (
 	collectTimeout            = 5 * time.Minute
 	maxScheduleRetries        = 10
 	maxLoadConfigRetries      = 10
+	// maintenanceWindowCheckInterval is the interval to check the boundaries
+	// of the maintenance windows.
+	maintenanceWindowCheckInterval = 10 * time.Second
+	// rollingRestartCheckInterval is the interval to check the progress of
+	// the rolling restart, which is the same as the store heartbeat interval.
+	rollingRestartCheckInterval = 10 * time.Second
+	// batchOfflineCheckInterval is the interval to check if the draining
+	// stores of the batch offline are drained.
+	batchOfflineCheckInterval = 10 * time.Second
+	// splitRangeCheckInterval is the interval to check if the regions are
+	// split at the pending keys of the split-range jobs.
+	splitRangeCheckInterval = 5 * time.Second
 
 	patrolScanRegionLimit = 128 // It takes about 14 minutes to iterate 1 million regions.
 	// PluginLoad means action for load plugin
@@ -53,6 +70,9 @@ var (
 	ErrSchedulerExisted = errors.New("scheduler existed")
 	// ErrSchedulerNotFound is error info for scheduler is not found.
 	ErrSchedulerNotFound = errors.New("scheduler not found")
+	// ErrClusterStopping is error info for the cluster is stopping or stopped,
+	// e.g. the PD leader is changing.
+	ErrClusterStopping = errors.New("cluster is stopping")
 )
 
 // coordinator is used to manage all schedulers and checkers to decide if the region needs to be scheduled.
@@ -64,27 +84,52 @@ type coordinator struct {
 	cancel          context.CancelFunc
 	cluster         *RaftCluster
 	checkers        *schedule.CheckerController
+	pinChecker      *leaderPinChecker
+	rejectChecker   *rejectLeaderChecker
 	regionScatterer *schedule.RegionScatterer
 	schedulers      map[string]*scheduleController
 	opController    *schedule.OperatorController
 	hbStreams       opt.HeartbeatStreams
-	pluginInterface *schedule.PluginInterface
+	pluginInterface schedule.PluginLoader
+	startTime       time.Time
+	// activeWindows are the maintenance windows active at the last check, to
+	// find out the boundaries of the windows.
+	activeWindows  map[string]*MaintenanceWindow
+	checkersPaused int32
+	// patrolRestart notifies the patrol to scan from the first region.
+	patrolRestart chan struct{}
+	repairFirst   *repairFirst
 }
 
 // newCoordinator creates a new coordinator.
 func newCoordinator(ctx context.Context, cluster *RaftCluster, hbStreams opt.HeartbeatStreams) *coordinator {
 	ctx, cancel := context.WithCancel(ctx)
 	opController := schedule.NewOperatorController(ctx, cluster, hbStreams)
+	opController.SetFinishObserver(cluster.reporter.ObserveOperator)
 	return &coordinator{
 		ctx:             ctx,
 		cancel:          cancel,
 		cluster:         cluster,
 		checkers:        schedule.NewCheckerController(ctx, cluster, cluster.ruleManager, opController),
+		pinChecker:      newLeaderPinChecker(),
+		rejectChecker:   newRejectLeaderChecker(),
 		regionScatterer: schedule.NewRegionScatterer(cluster),
 		schedulers:      make(map[string]*scheduleController),
 		opController:    opController,
 		hbStreams:       hbStreams,
 		pluginInterface: schedule.NewPluginInterface(),
+		startTime:       time.Now(),
+		activeWindows:   make(map[string]*MaintenanceWindow),
+		patrolRestart:   make(chan struct{}, 1),
+		repairFirst:     newRepairFirst(),
+	}
+}
+
+// restartPatrol makes the patrol scan the regions from the first one at once.
+func (c *coordinator) restartPatrol() {
+	select {
+	case c.patrolRestart <- struct{}{}:
+	default:
 	}
 }
 
@@ -104,21 +149,33 @@ func (c *coordinator) patrolRegions() {
 		select {
 		case <-timer.C:
 			timer.Reset(c.cluster.GetPatrolRegionInterval())
+		case <-c.patrolRestart:
+			log.Info("patrol regions restarts from the first region")
+			key = nil
+			start = time.Now()
+			c.repairFirst.reset()
 		case <-c.ctx.Done():
 			log.Info("patrol regions has been stopped")
 			return
 		}
+		if c.isCheckersPaused() {
+			continue
+		}
 
+		c.checkDeferredRegions()
 		regions := c.cluster.ScanRegions(key, nil, patrolScanRegionLimit)
 		if len(regions) == 0 {
 			// Resets the scan key.
 			key = nil
+			c.repairFirst.observe(0, true)
 			continue
 		}
 
+		var scanned int
 		for _, region := range regions {
 			// Skips the region if there is already a pending operator.
 			if c.opController.GetOperator(region.GetID()) != nil {
+				scanned++
 				continue
 			}
 
@@ -127,13 +184,19 @@ func (c *coordinator) patrolRegions() {
 				break
 			}
 
+			scanned++
 			key = region.GetEndKey()
+			c.rejectChecker.Check(c.cluster, region)
 			if ops != nil {
 				c.opController.AddWaitingOperator(ops...)
+			} else if op := c.pinChecker.Check(c.cluster, region); op != nil {
+				c.opController.AddWaitingOperator(op)
 			}
 		}
 		// Updates the label level isolation statistics.
 		c.cluster.updateRegionsLabelLevelStats(regions)
+		// The patrol passes the last region if it is checked.
+		c.repairFirst.observe(scanned, scanned > 0 && len(key) == 0)
 		if len(key) == 0 {
 			patrolCheckRegionsHistogram.Observe(time.Since(start).Seconds())
 			start = time.Now()
@@ -141,6 +204,26 @@ func (c *coordinator) patrolRegions() {
 	}
 }
 
+// checkDeferredRegions checks the regions with the down peers deferred by the
+// replica checker before the patrol goes on, the most urgent first, so that
+// they take the store limits before the others.
+func (c *coordinator) checkDeferredRegions() {
+	for _, regionID := range c.checkers.GetDeferredRegions(patrolScanRegionLimit) {
+		region := c.cluster.GetRegion(regionID)
+		if region == nil || c.opController.GetOperator(regionID) != nil {
+			c.checkers.ForgetDeferredRegion(regionID)
+			continue
+		}
+		checkerIsBusy, ops := c.checkers.CheckRegion(region)
+		if checkerIsBusy {
+			return
+		}
+		if ops != nil {
+			c.opController.AddWaitingOperator(ops...)
+		}
+	}
+}
+
 // drivePushOperator is used to push the unfinished operator to the excutor.
 func (c *coordinator) drivePushOperator() {
 	defer logutil.LogPanic()
@@ -257,54 +340,205 @@ func (c *coordinator) run() {
 		log.Error("cannot persist schedule config", zap.Error(err))
 	}
 
-	c.wg.Add(2)
+	if c.cluster.opt.IsRepairFirstEnabled() {
+		c.repairFirst.start(c.cluster.opt.GetRepairFirstTimeout())
+	}
+	c.wg.Add(6)
 	// Starts to patrol regions.
 	go c.patrolRegions()
 	go c.drivePushOperator()
+	go c.runMaintenanceWindows()
+	go c.driveRollingRestart()
+	go c.driveBatchOffline()
+	go c.driveSplitRange()
+}
+
+// driveRollingRestart moves the rolling restart forward periodically.
+func (c *coordinator) driveRollingRestart() {
+	defer logutil.LogPanic()
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(rollingRestartCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.cluster.restarts.advance(c)
+		case <-c.ctx.Done():
+			log.Info("rolling restart has been stopped")
+			return
+		}
+	}
+}
+
+// driveBatchOffline moves the batch offline forward periodically.
+func (c *coordinator) driveBatchOffline() {
+	defer logutil.LogPanic()
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(batchOfflineCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.cluster.batchOffline.advance(c.cluster)
+		case <-c.ctx.Done():
+			log.Info("batch offline has been stopped")
+			return
+		}
+	}
+}
+
+// driveSplitRange moves the split-range jobs forward periodically.
+func (c *coordinator) driveSplitRange() {
+	defer logutil.LogPanic()
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(splitRangeCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.cluster.splitRange.advance(c)
+		case <-c.ctx.Done():
+			log.Info("split range has been stopped")
+			return
+		}
+	}
+}
+
+// runMaintenanceWindows pauses and resumes the schedulers and the checkers
+// according to the maintenance windows.
+func (c *coordinator) runMaintenanceWindows() {
+	defer logutil.LogPanic()
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(maintenanceWindowCheckInterval)
+	defer ticker.Stop()
+	for {
+		c.applyMaintenanceWindows()
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			log.Info("maintenance windows have been stopped")
+			return
+		}
+	}
+}
+
+// applyMaintenanceWindows pauses or resumes the schedulers of the windows
+// entered or exited since the last check only, so that pausing or resuming a
+// scheduler manually overrides the windows until the next boundary.
+func (c *coordinator) applyMaintenanceWindows() {
+	active := make(map[string]*MaintenanceWindow)
+	for _, w := range c.cluster.GetMaintenanceWindows().GetActiveWindows() {
+		active[w.Name] = w
+	}
+	c.Lock()
+	defer c.Unlock()
+	// An updated window is regarded as exiting the old one and entering the new one.
+	affected := make(map[string]struct{})
+	for name, w := range active {
+		if c.activeWindows[name] != w {
+			for _, s := range w.Schedulers {
+				affected[s] = struct{}{}
+			}
+		}
+	}
+	for name, w := range c.activeWindows {
+		if active[name] != w {
+			for _, s := range w.Schedulers {
+				affected[s] = struct{}{}
+			}
+		}
+	}
+	c.activeWindows = active
+	paused := make(map[string]struct{})
+	var checkers bool
+	for _, w := range active {
+		for _, s := range w.Schedulers {
+			paused[s] = struct{}{}
+		}
+		checkers = checkers || w.IncludeCheckers
+	}
+
+	_, allAffected := affected[AllSchedulers]
+	_, allPaused := paused[AllSchedulers]
+	for name, s := range c.schedulers {
+		if _, ok := affected[name]; !ok && !allAffected {
+			continue
+		}
+		_, ok := paused[name]
+		s.setWindowPaused(allPaused || ok)
+		log.Info("scheduler is paused or resumed by maintenance window", zap.String("scheduler-name", name), zap.Bool("paused", allPaused || ok))
+	}
+	if checkers != c.isCheckersPaused() {
+		var v int32
+		if checkers {
+			v = 1
+		}
+		atomic.StoreInt32(&c.checkersPaused, v)
+		log.Info("checkers are paused or resumed by maintenance window", zap.Bool("paused", checkers))
+	}
+}
+
+func (c *coordinator) isCheckersPaused() bool {
+	return atomic.LoadInt32(&c.checkersPaused) == 1
 }
 
-// LoadPlugin load user plugin
-func (c *coordinator) LoadPlugin(pluginPath string, ch chan string) {
+// LoadPlugin loads user plugin and returns the name of the created scheduler.
+// The result of every action sent to ch is acknowledged over ack, and exited is
+// closed once nobody receives from ch any more.
+func (c *coordinator) LoadPlugin(pluginPath string, ch chan string, ack chan error, exited chan struct{}) (string, error) {
 	log.Info("load plugin", zap.String("plugin-path", pluginPath))
 	// get func: SchedulerType from plugin
 	SchedulerType, err := c.pluginInterface.GetFunction(pluginPath, "SchedulerType")
 	if err != nil {
 		log.Error("GetFunction SchedulerType error", zap.Error(err))
-		return
+		return "", err
+	}
+	schedulerType, ok := SchedulerType.(func() string)
+	if !ok {
+		return "", errors.Errorf("SchedulerType of plugin %s has wrong type %T", pluginPath, SchedulerType)
 	}
-	schedulerType := SchedulerType.(func() string)
 	// get func: SchedulerArgs from plugin
 	SchedulerArgs, err := c.pluginInterface.GetFunction(pluginPath, "SchedulerArgs")
 	if err != nil {
 		log.Error("GetFunction SchedulerArgs error", zap.Error(err))
-		return
+		return "", err
+	}
+	schedulerArgs, ok := SchedulerArgs.(func() []string)
+	if !ok {
+		return "", errors.Errorf("SchedulerArgs of plugin %s has wrong type %T", pluginPath, SchedulerArgs)
 	}
-	schedulerArgs := SchedulerArgs.(func() []string)
 	// create and add user scheduler
 	s, err := schedule.CreateScheduler(schedulerType(), c.opController, c.cluster.storage, schedule.ConfigSliceDecoder(schedulerType(), schedulerArgs()))
 	if err != nil {
 		log.Error("can not create scheduler", zap.String("scheduler-type", schedulerType()), zap.Error(err))
-		return
+		return "", err
 	}
 	log.Info("create scheduler", zap.String("scheduler-name", s.GetName()))
 	if err = c.addScheduler(s); err != nil {
 		log.Error("can't add scheduler", zap.String("scheduler-name", s.GetName()), zap.Error(err))
-		return
+		return "", err
 	}
 
 	c.wg.Add(1)
-	go c.waitPluginUnload(pluginPath, s.GetName(), ch)
+	go c.waitPluginUnload(pluginPath, s.GetName(), ch, ack, exited)
+	return s.GetName(), nil
 }
 
-func (c *coordinator) waitPluginUnload(pluginPath, schedulerName string, ch chan string) {
+func (c *coordinator) waitPluginUnload(pluginPath, schedulerName string, ch chan string, ack chan error, exited chan struct{}) {
 	defer logutil.LogPanic()
 	defer c.wg.Done()
+	defer close(exited)
 	// Get signal from channel which means user unload the plugin
 	for {
 		select {
 		case action := <-ch:
 			if action == PluginUnload {
 				err := c.removeScheduler(schedulerName)
+				ackPluginAction(ack, err)
 				if err != nil {
 					log.Error("can not remove scheduler", zap.String("scheduler-name", schedulerName), zap.Error(err))
 				} else {
@@ -312,6 +546,7 @@ func (c *coordinator) waitPluginUnload(pluginPath, schedulerName string, ch chan
 					return
 				}
 			} else {
+				ackPluginAction(ack, errors.Errorf("unknown action %s", action))
 				log.Error("unknown action", zap.String("action", action))
 			}
 		case <-c.ctx.Done():
@@ -321,6 +556,15 @@ func (c *coordinator) waitPluginUnload(pluginPath, schedulerName string, ch chan
 	}
 }
 
+// ackPluginAction sends the result without blocking, in case that nobody waits
+// for it any more.
+func ackPluginAction(ack chan error, err error) {
+	select {
+	case ack <- err:
+	default:
+	}
+}
+
 func (c *coordinator) stop() {
 	c.cancel()
 }
@@ -360,10 +604,16 @@ func (c *coordinator) getHotReadRegions() *statistics.StoreHotPeersInfos {
 	return nil
 }
 
+// getSchedulers returns a snapshot of the schedulers, which is safe to be
+// iterated while the schedulers are added or removed.
 func (c *coordinator) getSchedulers() map[string]*scheduleController {
 	c.RLock()
 	defer c.RUnlock()
-	return c.schedulers
+	schedulers := make(map[string]*scheduleController, len(c.schedulers))
+	for name, s := range c.schedulers {
+		schedulers[name] = s
+	}
+	return schedulers
 }
 
 func (c *coordinator) collectSchedulerMetrics() {
@@ -377,6 +627,7 @@ func (c *coordinator) collectSchedulerMetrics() {
 			allowScheduler = 1
 		}
 		schedulerStatusGauge.WithLabelValues(s.GetName(), "allow").Set(allowScheduler)
+		schedulerStatusGauge.WithLabelValues(s.GetName(), "backoff_level").Set(float64(s.backoff.status().Level))
 	}
 }
 
@@ -467,6 +718,11 @@ func (c *coordinator) addScheduler(scheduler schedule.Scheduler, args ...string)
 	if err := s.Prepare(c.cluster); err != nil {
 		return err
 	}
+	observe, err := c.cluster.opt.IsSchedulerObserved(c.ctx, s.GetName())
+	if err != nil {
+		log.Warn("can not load the observe flag of the scheduler", zap.String("scheduler-name", s.GetName()), zap.Error(err))
+	}
+	s.setObserving(observe)
 
 	c.wg.Add(1)
 	go c.runScheduler(s)
@@ -490,6 +746,7 @@ func (c *coordinator) removeScheduler(name string) error {
 
 	s.Stop()
 	schedulerStatusGauge.WithLabelValues(name, "allow").Set(0)
+	schedulerStatusGauge.WithLabelValues(name, "backoff_level").Set(0)
 	delete(c.schedulers, name)
 
 	var err error
@@ -509,6 +766,88 @@ func (c *coordinator) removeScheduler(name string) error {
 	return err
 }
 
+// evictLeader evicts the leaders from the store by the evict-leader-scheduler,
+// which is added if absent. It returns false if the store is evicted already.
+func (c *coordinator) evictLeader(storeID uint64) (bool, error) {
+	return c.addStoreToLeaderScheduler(schedulers.EvictLeaderType, schedulers.EvictLeaderName, storeID)
+}
+
+// unevictLeader stops evicting the leaders from the store, and removes the
+// evict-leader-scheduler if no store is evicted any more. It does nothing if
+// the store is not evicted.
+func (c *coordinator) unevictLeader(storeID uint64) error {
+	err := c.removeStoreFromLeaderScheduler(schedulers.EvictLeaderName, storeID)
+	if err == ErrSchedulerNotFound || err == schedulers.ErrScheduleConfigNotExist {
+		return nil
+	}
+	return err
+}
+
+// grantLeader grants the leaders to the store by the grant-leader-scheduler,
+// which is added if absent. It returns false if the store is granted already.
+func (c *coordinator) grantLeader(storeID uint64) (bool, error) {
+	return c.addStoreToLeaderScheduler(schedulers.GrantLeaderType, schedulers.GrantLeaderName, storeID)
+}
+
+// ungrantLeader stops granting the leaders to the store, and removes the
+// grant-leader-scheduler if no store is granted any more.
+func (c *coordinator) ungrantLeader(storeID uint64) error {
+	return c.removeStoreFromLeaderScheduler(schedulers.GrantLeaderName, storeID)
+}
+
+// addStoreToLeaderScheduler adds the store to the evict-leader-scheduler or
+// the grant-leader-scheduler, which is added with the store if absent.
+func (c *coordinator) addStoreToLeaderScheduler(tp, name string, storeID uint64) (bool, error) {
+	c.RLock()
+	s, ok := c.schedulers[name]
+	c.RUnlock()
+	if ok {
+		switch scheduler := s.Scheduler.(type) {
+		case schedulers.LeaderEvictor:
+			return scheduler.EvictLeader(storeID)
+		case schedulers.LeaderGranter:
+			return scheduler.GrantLeader(storeID)
+		default:
+			return false, errors.Errorf("scheduler %s cannot add the stores on demand", name)
+		}
+	}
+	args := []string{strconv.FormatUint(storeID, 10)}
+	scheduler, err := schedule.CreateScheduler(tp, c.opController, c.cluster.storage, schedule.ConfigSliceDecoder(tp, args))
+	if err != nil {
+		return false, err
+	}
+	if err := c.addScheduler(scheduler, args...); err != nil {
+		return false, err
+	}
+	return true, c.cluster.opt.Persist(c.cluster.storage)
+}
+
+// removeStoreFromLeaderScheduler removes the store from the
+// evict-leader-scheduler or the grant-leader-scheduler, and removes the
+// scheduler if no store is left.
+func (c *coordinator) removeStoreFromLeaderScheduler(name string, storeID uint64) error {
+	c.RLock()
+	s, ok := c.schedulers[name]
+	c.RUnlock()
+	if !ok {
+		return ErrSchedulerNotFound
+	}
+	var last bool
+	var err error
+	switch scheduler := s.Scheduler.(type) {
+	case schedulers.LeaderEvictor:
+		last, err = scheduler.UnevictLeader(storeID)
+	case schedulers.LeaderGranter:
+		last, err = scheduler.UngrantLeader(storeID)
+	default:
+		return errors.Errorf("scheduler %s cannot remove the stores on demand", name)
+	}
+	if err != nil || !last {
+		return err
+	}
+	return c.removeScheduler(name)
+}
+
 func (c *coordinator) pauseOrResumeScheduler(name string, t int64) error {
 	c.Lock()
 	defer c.Unlock()
@@ -532,12 +871,158 @@ func (c *coordinator) pauseOrResumeScheduler(name string, t int64) error {
 		var delayUntil int64 = 0
 		if t > 0 {
 			delayUntil = time.Now().Unix() + t
+		} else {
+			// Resuming overrides the maintenance windows.
+			atomic.StoreInt32(&sc.windowPaused, 0)
 		}
 		atomic.StoreInt64(&sc.delayUntil, delayUntil)
 	}
 	return err
 }
 
+// observeScheduler sets whether the scheduler only logs its operators instead
+// of adding them, and persists the flag.
+func (c *coordinator) observeScheduler(name string, observe bool) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.cluster == nil {
+		return ErrNotBootstrapped
+	}
+	s, ok := c.schedulers[name]
+	if !ok {
+		return ErrSchedulerNotFound
+	}
+	opt := c.cluster.opt
+	if err := opt.SetSchedulerObserved(s.Ctx(), name, observe); err != nil {
+		return err
+	}
+	if err := opt.Persist(c.cluster.storage); err != nil {
+		return err
+	}
+	s.setObserving(observe)
+	log.Info("scheduler observe flag is changed", zap.String("scheduler-name", name), zap.Bool("observe", observe))
+	c.cluster.schedulersCallback()
+	return nil
+}
+
+// getObservedOperators returns the recent operators logged by the scheduler
+// in the observe mode, the oldest first.
+func (c *coordinator) getObservedOperators(name string) ([]*ObservedOperator, error) {
+	c.RLock()
+	defer c.RUnlock()
+	s, ok := c.schedulers[name]
+	if !ok {
+		return nil, ErrSchedulerNotFound
+	}
+	return s.observed.list(), nil
+}
+
+// SchedulerDetail is the running status of a scheduler.
+type SchedulerDetail struct {
+	Name    string `json:"name"`
+	Paused  bool   `json:"paused"`
+	Allowed bool   `json:"allowed"`
+	// Observing means the operators of the scheduler are logged instead of
+	// being added.
+	Observing bool                   `json:"observing"`
+	Backoff   SchedulerBackoffStatus `json:"backoff"`
+}
+
+func (c *coordinator) getSchedulerDetail(name string) (*SchedulerDetail, error) {
+	c.RLock()
+	defer c.RUnlock()
+	s, ok := c.schedulers[name]
+	if !ok {
+		return nil, ErrSchedulerNotFound
+	}
+	return &SchedulerDetail{
+		Name:      name,
+		Paused:    s.IsPaused(),
+		Allowed:   s.AllowSchedule(),
+		Observing: s.isObserving(),
+		Backoff:   s.backoff.status(),
+	}, nil
+}
+
+// SchedulerStatus is the status and the config of a scheduler, which is
+// either running or disabled.
+type SchedulerStatus struct {
+	Name   string `json:"name"`
+	Paused bool   `json:"paused"`
+	// PausedUntil is when the scheduler resumes if it is paused by the API,
+	// which is absent if it is paused by the maintenance windows.
+	PausedUntil *time.Time `json:"paused_until,omitempty"`
+	// Disabled is true if it is a default scheduler removed by the API.
+	Disabled bool            `json:"disabled"`
+	Config   json.RawMessage `json:"config,omitempty"`
+}
+
+// getSchedulerStatuses returns the statuses of the running and the disabled
+// schedulers, sorted by the names.
+func (c *coordinator) getSchedulerStatuses() []*SchedulerStatus {
+	c.RLock()
+	defer c.RUnlock()
+	statuses := make([]*SchedulerStatus, 0, len(c.schedulers))
+	now := time.Now()
+	for name, s := range c.schedulers {
+		status := &SchedulerStatus{Name: name, Paused: s.IsPaused()}
+		if status.Paused {
+			if delayUntil := atomic.LoadInt64(&s.delayUntil); delayUntil > now.Unix() {
+				until := time.Unix(delayUntil, 0)
+				status.PausedUntil = &until
+			}
+		}
+		status.Config = encodeSchedulerConfig(s.Scheduler)
+		statuses = append(statuses, status)
+	}
+	for _, cfg := range c.cluster.opt.GetSchedulers() {
+		if !cfg.Disable {
+			continue
+		}
+		// To create a temporary scheduler is just used to get its name and
+		// config.
+		s, err := schedule.CreateScheduler(cfg.Type, schedule.NewOperatorController(c.ctx, nil, nil), core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(cfg.Type, cfg.Args))
+		if err != nil {
+			log.Warn("can not create the disabled scheduler", zap.String("scheduler-type", cfg.Type), zap.Error(err))
+			continue
+		}
+		statuses = append(statuses, &SchedulerStatus{Name: s.GetName(), Disabled: true, Config: encodeSchedulerConfig(s)})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func encodeSchedulerConfig(s schedule.Scheduler) json.RawMessage {
+	data, err := s.EncodeConfig()
+	if err != nil {
+		log.Warn("can not encode the scheduler config", zap.String("scheduler-name", s.GetName()), zap.Error(err))
+		return nil
+	}
+	return data
+}
+
+// SchedulerEffectiveConfig is the config items a scheduler uses at schedule
+// time.
+type SchedulerEffectiveConfig struct {
+	Name  string                          `json:"name"`
+	Type  string                          `json:"type"`
+	Items []*schedule.EffectiveConfigItem `json:"items"`
+}
+
+func (c *coordinator) getSchedulerEffectiveConfig(name string) (*SchedulerEffectiveConfig, error) {
+	c.RLock()
+	s, ok := c.schedulers[name]
+	c.RUnlock()
+	if !ok {
+		return nil, ErrSchedulerNotFound
+	}
+	return &SchedulerEffectiveConfig{
+		Name:  name,
+		Type:  s.GetType(),
+		Items: s.GetEffectiveConfig(c.cluster),
+	}, nil
+}
+
 func (c *coordinator) runScheduler(s *scheduleController) {
 	defer logutil.LogPanic()
 	defer c.wg.Done()
@@ -549,12 +1034,30 @@ func (c *coordinator) runScheduler(s *scheduleController) {
 	for {
 		select {
 		case <-timer.C:
+			s.updateBackoff()
 			timer.Reset(s.GetInterval())
 			if !s.AllowSchedule() {
 				continue
 			}
+			if c.isHeldByRepairFirst(s) {
+				// The hot status is shown by the API, so it is still updated.
+				if h, ok := s.Scheduler.(hotStatusUpdater); ok {
+					h.UpdateHotStatus(c.cluster)
+				}
+				continue
+			}
 			if op := s.Schedule(); op != nil {
+				for _, o := range op {
+					o.SetCreator(s.GetName())
+				}
+				if s.isObserving() {
+					s.observed.record(op...)
+					schedulerObservedOperatorCounter.WithLabelValues(s.GetName()).Add(float64(len(op)))
+					log.Debug("observe operator", zap.Int("total", len(op)), zap.String("scheduler", s.GetName()))
+					continue
+				}
 				added := c.opController.AddWaitingOperator(op...)
+				s.backoff.track(op[:added]...)
 				log.Debug("add operator", zap.Int("added", added), zap.Int("total", len(op)), zap.String("scheduler", s.GetName()))
 			}
 
@@ -573,9 +1076,13 @@ type scheduleController struct {
 	cluster      *RaftCluster
 	opController *schedule.OperatorController
 	nextInterval time.Duration
+	backoff      *schedulerBackoff
 	ctx          context.Context
 	cancel       context.CancelFunc
 	delayUntil   int64
+	windowPaused int32
+	observing    int32
+	observed     *observedLog
 }
 
 // newScheduleController creates a new scheduleController.
@@ -586,6 +1093,8 @@ func newScheduleController(c *coordinator, s schedule.Scheduler) *scheduleContro
 		cluster:      c.cluster,
 		opController: c.opController,
 		nextInterval: s.GetMinInterval(),
+		backoff:      newSchedulerBackoff(),
+		observed:     newObservedLog(),
 		ctx:          ctx,
 		cancel:       cancel,
 	}
@@ -613,7 +1122,21 @@ func (s *scheduleController) Schedule() []*operator.Operator {
 
 // GetInterval returns the interval of scheduling for a scheduler.
 func (s *scheduleController) GetInterval() time.Duration {
-	return s.nextInterval
+	return s.backoff.backoff(s.nextInterval)
+}
+
+// updateBackoff backs off or restores the scheduling interval by the success
+// rate of the operators created by the scheduler.
+func (s *scheduleController) updateBackoff() {
+	old := s.backoff.update(s.cluster.opt.GetSchedulerBackoffSuccessRate())
+	if status := s.backoff.status(); status.Level != old {
+		log.Info("scheduler backoff is changed",
+			zap.String("scheduler-name", s.GetName()),
+			zap.Int("old-level", old),
+			zap.Int("new-level", status.Level),
+			zap.Float64("success-rate", status.SuccessRate),
+			zap.Int("samples", status.Samples))
+	}
 }
 
 // AllowSchedule returns if a scheduler is allowed to schedule.
@@ -623,6 +1146,34 @@ func (s *scheduleController) AllowSchedule() bool {
 
 // isPaused returns if a schedueler is paused.
 func (s *scheduleController) IsPaused() bool {
+	if atomic.LoadInt32(&s.windowPaused) == 1 {
+		return true
+	}
 	delayUntil := atomic.LoadInt64(&s.delayUntil)
 	return time.Now().Unix() < delayUntil
 }
+
+// setWindowPaused pauses or resumes the scheduler on the boundaries of the
+// maintenance windows, which overrides the manual pausing.
+func (s *scheduleController) setWindowPaused(paused bool) {
+	if paused {
+		atomic.StoreInt32(&s.windowPaused, 1)
+		return
+	}
+	atomic.StoreInt32(&s.windowPaused, 0)
+	atomic.StoreInt64(&s.delayUntil, 0)
+}
+
+// isObserving returns if the operators of the scheduler are logged instead of
+// being added.
+func (s *scheduleController) isObserving() bool {
+	return atomic.LoadInt32(&s.observing) == 1
+}
+
+func (s *scheduleController) setObserving(observe bool) {
+	if observe {
+		atomic.StoreInt32(&s.observing, 1)
+		return
+	}
+	atomic.StoreInt32(&s.observing, 0)
+}
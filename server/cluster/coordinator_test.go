@@ -15,7 +15,10 @@ package cluster
 
 import (
 	"context"
+	"encoding/json"
 	"math/rand"
+	"plugin"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -35,6 +38,7 @@ import (
 	"github.com/pingcap/pd/v4/server/schedule/opt"
 	"github.com/pingcap/pd/v4/server/schedulers"
 	"github.com/pingcap/pd/v4/server/statistics"
+	"github.com/pkg/errors"
 )
 
 func newTestOperator(regionID uint64, regionEpoch *metapb.RegionEpoch, kind operator.OpKind, steps ...operator.OpStep) *operator.Operator {
@@ -356,6 +360,45 @@ func (s *testCoordinatorSuite) TestCheckRegion(c *C) {
 	s.checkRegion(c, tc, co, 1, false, 0)
 }
 
+func (s *testCoordinatorSuite) TestRegionTrace(c *C) {
+	tc, co, cleanup := prepare(nil, nil, func(co *coordinator) { co.run() }, c)
+	defer cleanup()
+
+	c.Assert(tc.addRegionStore(4, 4), IsNil)
+	c.Assert(tc.addRegionStore(3, 3), IsNil)
+	c.Assert(tc.addRegionStore(2, 2), IsNil)
+	c.Assert(tc.addRegionStore(1, 1), IsNil)
+	c.Assert(tc.addLeaderRegion(1, 2, 3), IsNil)
+	c.Assert(tc.addLeaderRegion(2, 2, 3), IsNil)
+	c.Assert(tc.GetRegionTracer().Enable(1, time.Minute), IsNil)
+
+	s.checkRegion(c, tc, co, 1, false, 1)
+	s.checkRegion(c, tc, co, 2, false, 1)
+	waitOperator(c, co, 1)
+	op := co.opController.GetOperator(1)
+	c.Assert(co.opController.RemoveOperator(op), IsTrue)
+
+	hasEvent := func(events []*core.TraceEvent, source, event string) bool {
+		for _, e := range events {
+			if e.Source == source && strings.Contains(e.Event, event) {
+				return true
+			}
+		}
+		return false
+	}
+	trace := tc.GetRegionTracer().GetTrace(1)
+	c.Assert(trace, NotNil)
+	c.Assert(hasEvent(trace.Events, "replica-checker", "store 2 is rejected as target by exclude-filter"), IsTrue)
+	c.Assert(hasEvent(trace.Events, "replica-checker", "make up replica with operator make-up-replica"), IsTrue)
+	c.Assert(hasEvent(trace.Events, "operator-controller", "is created"), IsTrue)
+	c.Assert(hasEvent(trace.Events, "operator-controller", "ends with status Canceled"), IsTrue)
+	for i := 1; i < len(trace.Events); i++ {
+		c.Assert(trace.Events[i].Time.Before(trace.Events[i-1].Time), IsFalse)
+	}
+	// The region which is not traced has no trace.
+	c.Assert(tc.GetRegionTracer().GetTrace(2), IsNil)
+}
+
 func (s *testCoordinatorSuite) TestCheckerIsBusy(c *C) {
 	tc, co, cleanup := prepare(func(cfg *config.ScheduleConfig) {
 		cfg.ReplicaScheduleLimit = 0 // ensure replica checker is busy
@@ -577,6 +620,93 @@ func (s *testCoordinatorSuite) TestShouldRunWithNonLeaderRegions(c *C) {
 	c.Assert(tc.GetRegion(10).GetLeader().GetStoreId(), Equals, uint64(0))
 }
 
+// mockPluginLoader is a stub of plugins that returns the functions directly.
+type mockPluginLoader map[string]interface{}
+
+func (l mockPluginLoader) GetFunction(path string, funcName string) (plugin.Symbol, error) {
+	f, ok := l[funcName]
+	if !ok {
+		return nil, errors.Errorf("symbol %s not found in plugin %s", funcName, path)
+	}
+	return f, nil
+}
+
+func (s *testCoordinatorSuite) TestLoadPlugin(c *C) {
+	_, co, cleanup := prepare(nil, nil, func(co *coordinator) { co.run() }, c)
+	defer cleanup()
+
+	ch, ack, exited := make(chan string), make(chan error, 1), make(chan struct{})
+	co.pluginInterface = mockPluginLoader{"SchedulerType": func() string { return schedulers.ShuffleLeaderType }}
+	_, err := co.LoadPlugin("./pd/plugin/shuffle.so", ch, ack, exited)
+	c.Assert(err, NotNil)
+	co.pluginInterface = mockPluginLoader{"SchedulerType": "shuffle-leader", "SchedulerArgs": func() []string { return nil }}
+	_, err = co.LoadPlugin("./pd/plugin/shuffle.so", ch, ack, exited)
+	c.Assert(err, NotNil)
+
+	co.pluginInterface = mockPluginLoader{
+		"SchedulerType": func() string { return schedulers.ShuffleLeaderType },
+		"SchedulerArgs": func() []string { return []string{"", ""} },
+	}
+	name, err := co.LoadPlugin("./pd/plugin/shuffle.so", ch, ack, exited)
+	c.Assert(err, IsNil)
+	c.Assert(co.getSchedulers(), HasKey, name)
+
+	ch <- "unknown"
+	c.Assert(<-ack, NotNil)
+	c.Assert(co.getSchedulers(), HasKey, name)
+
+	// The scheduler is removed before the unloading is acknowledged.
+	ch <- PluginUnload
+	c.Assert(<-ack, IsNil)
+	c.Assert(co.getSchedulers(), Not(HasKey), name)
+	<-exited
+
+	// Nobody receives the actions after the coordinator stops.
+	exited = make(chan struct{})
+	_, err = co.LoadPlugin("./pd/plugin/shuffle.so", ch, ack, exited)
+	c.Assert(err, IsNil)
+	co.stop()
+	<-exited
+}
+
+func (s *testCoordinatorSuite) TestRemoveTombStoneRecords(c *C) {
+	tc, co, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+	tc.RaftCluster.coordinator = co
+
+	for i := uint64(1); i <= 4; i++ {
+		c.Assert(tc.addRegionStore(i, 1), IsNil)
+	}
+	c.Assert(tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(tc.addLeaderRegion(2, 1, 2, 4), IsNil)
+	_, err := tc.GetStoreResidualRegions(3)
+	c.Assert(err, NotNil)
+	c.Assert(tc.BuryStore(3, true), IsNil)
+	regions, err := tc.GetStoreResidualRegions(3)
+	c.Assert(err, IsNil)
+	c.Assert(regions, HasLen, 1)
+	c.Assert(regions[0].GetID(), Equals, uint64(1))
+
+	// Refuse to remove the tombstone store with residual peers.
+	err = tc.RemoveTombStoneRecords(false)
+	c.Assert(err, FitsTypeOf, core.StoreResidualPeersErr{})
+	c.Assert(tc.GetStore(3), NotNil)
+
+	// The residual peers drain once the region reports that the peer is removed.
+	region := tc.GetRegion(1).Clone(core.WithRemoveStorePeer(3), core.WithIncConfVer())
+	c.Assert(tc.processRegionHeartbeat(region), IsNil)
+	regions, err = tc.GetStoreResidualRegions(3)
+	c.Assert(err, IsNil)
+	c.Assert(regions, HasLen, 0)
+
+	// Force to remove the tombstone store with residual peers.
+	c.Assert(tc.BuryStore(4, true), IsNil)
+	c.Assert(tc.RemoveTombStoneRecords(false), NotNil)
+	c.Assert(tc.RemoveTombStoneRecords(true), IsNil)
+	c.Assert(tc.GetStore(3), IsNil)
+	c.Assert(tc.GetStore(4), IsNil)
+}
+
 func (s *testCoordinatorSuite) TestAddScheduler(c *C) {
 	tc, co, cleanup := prepare(nil, nil, func(co *coordinator) { co.run() }, c)
 	defer cleanup()
@@ -774,6 +904,64 @@ func (s *testCoordinatorSuite) TestRemoveScheduler(c *C) {
 	co.wg.Wait()
 }
 
+func (s *testCoordinatorSuite) TestLeaderSchedulerStores(c *C) {
+	tc, co, cleanup := prepare(nil, nil, func(co *coordinator) { co.run() }, c)
+	defer cleanup()
+
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(tc.addLeaderStore(id, 1), IsNil)
+	}
+	storage := tc.RaftCluster.storage
+
+	for _, t := range []struct {
+		name   string
+		add    func(uint64) (bool, error)
+		remove func(uint64) error
+	}{
+		{schedulers.EvictLeaderName, co.evictLeader, func(id uint64) error {
+			return co.removeStoreFromLeaderScheduler(schedulers.EvictLeaderName, id)
+		}},
+		{schedulers.GrantLeaderName, co.grantLeader, co.ungrantLeader},
+	} {
+		c.Assert(t.remove(1), Equals, ErrSchedulerNotFound)
+
+		// The scheduler is added with the first store, and the other stores
+		// are added to it.
+		for id := uint64(1); id <= 2; id++ {
+			added, err := t.add(id)
+			c.Assert(err, IsNil)
+			c.Assert(added, IsTrue)
+		}
+		added, err := t.add(2)
+		c.Assert(err, IsNil)
+		c.Assert(added, IsFalse)
+		c.Assert(co.schedulers, HasKey, t.name)
+		data, err := storage.LoadScheduleConfig(t.name)
+		c.Assert(err, IsNil)
+		var conf struct {
+			StoreIDWithRanges map[uint64]interface{} `json:"store-id-ranges"`
+		}
+		c.Assert(json.Unmarshal([]byte(data), &conf), IsNil)
+		c.Assert(conf.StoreIDWithRanges, HasLen, 2)
+
+		// The scheduler removes itself after the last store is removed.
+		c.Assert(t.remove(3), Equals, schedulers.ErrScheduleConfigNotExist)
+		c.Assert(t.remove(1), IsNil)
+		c.Assert(co.schedulers, HasKey, t.name)
+		c.Assert(t.remove(2), IsNil)
+		c.Assert(co.schedulers, Not(HasKey), t.name)
+		data, err = storage.LoadScheduleConfig(t.name)
+		c.Assert(err, IsNil)
+		c.Assert(data, Equals, "")
+		for _, cfg := range tc.opt.GetSchedulers() {
+			c.Assert(cfg.Type, Not(Equals), strings.TrimSuffix(t.name, "-scheduler"))
+		}
+	}
+
+	// Unevicting a store not evicted is a no-op.
+	c.Assert(co.unevictLeader(1), IsNil)
+}
+
 func (s *testCoordinatorSuite) TestRestart(c *C) {
 	tc, co, cleanup := prepare(func(cfg *config.ScheduleConfig) {
 		// Turn off balance, we test add replica only.
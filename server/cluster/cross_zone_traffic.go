@@ -0,0 +1,138 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sort"
+
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/statistics"
+)
+
+// DefaultZoneLabel is the label key of the zones of the stores.
+const DefaultZoneLabel = "zone"
+
+// CrossZoneTraffic is the replication traffic from the leaders to the
+// followers crossing the zones, estimated by the write byte rates of the
+// regions.
+type CrossZoneTraffic struct {
+	ZoneLabel string `json:"zone-label"`
+	// Matrix is the byte rate per second from the zone of the leaders to the
+	// zone of the followers, indexed by the source zone first.
+	Matrix map[string]map[string]float64 `json:"matrix"`
+	// TotalByteRate is the byte rate per second of all the cross-zone
+	// replication traffic.
+	TotalByteRate float64 `json:"total-byte-rate"`
+	// UnlabeledPeers is the number of the peers skipped as the zones of their
+	// stores or the stores of their leaders are unknown.
+	UnlabeledPeers int `json:"unlabeled-peers"`
+	// TopRegions are the regions contributing the most cross-zone traffic.
+	TopRegions []*RegionCrossZoneTraffic `json:"top-regions"`
+}
+
+// RegionCrossZoneTraffic is the cross-zone replication traffic of a region.
+type RegionCrossZoneTraffic struct {
+	RegionID      uint64  `json:"region-id"`
+	LeaderZone    string  `json:"leader-zone"`
+	WriteByteRate float64 `json:"write-byte-rate"`
+	// CrossZoneByteRate is the write byte rate times the number of the
+	// followers in other zones.
+	CrossZoneByteRate float64 `json:"cross-zone-byte-rate"`
+}
+
+// GetCrossZoneTraffic estimates the replication traffic crossing the zones,
+// which are the values of the label of the stores. Each follower, including
+// the learners, receives the writes of the region from its leader. The write
+// byte rate of a region is the denoised one in the hot write statistics if it
+// is hot, or the one in its last heartbeat otherwise. The regions without the
+// flow data count as zero.
+func (c *RaftCluster) GetCrossZoneTraffic(zoneLabel string, top int) *CrossZoneTraffic {
+	hotRates := make(map[uint64]float64)
+	for storeID, stats := range c.RegionWriteStats() {
+		for _, stat := range stats {
+			// Only the leaders replicate the writes.
+			if region := c.GetRegion(stat.RegionID); region != nil && region.GetLeader().GetStoreId() == storeID {
+				hotRates[stat.RegionID] = stat.GetByteRate()
+			}
+		}
+	}
+
+	traffic := &CrossZoneTraffic{
+		ZoneLabel: zoneLabel,
+		Matrix:    make(map[string]map[string]float64),
+	}
+	zoneOf := func(storeID uint64) string {
+		if store := c.GetStore(storeID); store != nil {
+			return store.GetLabelValue(zoneLabel)
+		}
+		return ""
+	}
+	regions := make([]*RegionCrossZoneTraffic, 0)
+	for _, region := range c.GetRegions() {
+		leader := region.GetLeader()
+		if leader == nil {
+			continue
+		}
+		rate, ok := hotRates[region.GetID()]
+		if !ok {
+			rate = regionWriteByteRate(region)
+		}
+		leaderZone := zoneOf(leader.GetStoreId())
+		regionTraffic := &RegionCrossZoneTraffic{RegionID: region.GetID(), LeaderZone: leaderZone, WriteByteRate: rate}
+		for _, peer := range region.GetPeers() {
+			if peer.GetId() == leader.GetId() {
+				continue
+			}
+			zone := zoneOf(peer.GetStoreId())
+			if leaderZone == "" || zone == "" {
+				traffic.UnlabeledPeers++
+				continue
+			}
+			if zone == leaderZone || rate == 0 {
+				continue
+			}
+			if traffic.Matrix[leaderZone] == nil {
+				traffic.Matrix[leaderZone] = make(map[string]float64)
+			}
+			traffic.Matrix[leaderZone][zone] += rate
+			traffic.TotalByteRate += rate
+			regionTraffic.CrossZoneByteRate += rate
+		}
+		if regionTraffic.CrossZoneByteRate > 0 {
+			regions = append(regions, regionTraffic)
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].CrossZoneByteRate != regions[j].CrossZoneByteRate {
+			return regions[i].CrossZoneByteRate > regions[j].CrossZoneByteRate
+		}
+		return regions[i].RegionID < regions[j].RegionID
+	})
+	if len(regions) > top {
+		regions = regions[:top]
+	}
+	traffic.TopRegions = regions
+	return traffic
+}
+
+// regionWriteByteRate returns the write byte rate per second in the last
+// heartbeat of the region.
+func regionWriteByteRate(region *core.RegionInfo) float64 {
+	interval := region.GetInterval()
+	seconds := uint64(statistics.RegionHeartBeatReportInterval)
+	if interval.GetEndTimestamp() > interval.GetStartTimestamp() {
+		seconds = interval.GetEndTimestamp() - interval.GetStartTimestamp()
+	}
+	return float64(region.GetBytesWritten()) / float64(seconds)
+}
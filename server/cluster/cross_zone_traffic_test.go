@@ -0,0 +1,76 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+var _ = Suite(&testCrossZoneTrafficSuite{})
+
+type testCrossZoneTrafficSuite struct{}
+
+func (s *testCrossZoneTrafficSuite) TestGetCrossZoneTraffic(c *C) {
+	tc, _, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+
+	// Store 5 is not labeled.
+	for id, zone := range map[uint64]string{1: "z1", 2: "z1", 3: "z2", 4: "z3", 5: ""} {
+		c.Assert(tc.addRegionStore(id, 0), IsNil)
+		if zone != "" {
+			store := tc.GetStore(id).Clone(core.SetStoreLabels([]*metapb.StoreLabel{{Key: "zone", Value: zone}}))
+			c.Assert(tc.putStoreLocked(store), IsNil)
+		}
+	}
+	for _, r := range []struct {
+		id           uint64
+		stores       []uint64
+		writtenBytes uint64
+	}{
+		// 100 B/s from z1 to z2, as the follower on store 2 is in z1 too.
+		{1, []uint64{1, 2, 3}, 1000},
+		// 60 B/s from z2 to z1 and z3 each.
+		{2, []uint64{3, 1, 4}, 600},
+		// 10 B/s from z1 to z2, and the peer on store 5 is unlabeled.
+		{3, []uint64{1, 3, 5}, 100},
+		// No flow data.
+		{4, []uint64{4, 1, 3}, 0},
+	} {
+		c.Assert(tc.addLeaderRegion(r.id, r.stores[0], r.stores[1:]...), IsNil)
+		region := tc.GetRegion(r.id).Clone(core.SetWrittenBytes(r.writtenBytes), core.SetReportInterval(10))
+		c.Assert(tc.putRegion(region), IsNil)
+	}
+
+	traffic := tc.GetCrossZoneTraffic(DefaultZoneLabel, 2)
+	c.Assert(traffic.ZoneLabel, Equals, "zone")
+	c.Assert(traffic.Matrix, DeepEquals, map[string]map[string]float64{
+		"z1": {"z2": 110},
+		"z2": {"z1": 60, "z3": 60},
+	})
+	c.Assert(traffic.TotalByteRate, Equals, float64(230))
+	c.Assert(traffic.UnlabeledPeers, Equals, 1)
+	c.Assert(traffic.TopRegions, DeepEquals, []*RegionCrossZoneTraffic{
+		{RegionID: 2, LeaderZone: "z2", WriteByteRate: 60, CrossZoneByteRate: 120},
+		{RegionID: 1, LeaderZone: "z1", WriteByteRate: 100, CrossZoneByteRate: 100},
+	})
+
+	// No store has the label.
+	traffic = tc.GetCrossZoneTraffic("rack", 10)
+	c.Assert(traffic.Matrix, HasLen, 0)
+	c.Assert(traffic.TotalByteRate, Equals, float64(0))
+	c.Assert(traffic.UnlabeledPeers, Equals, 8)
+	c.Assert(traffic.TopRegions, HasLen, 0)
+}
@@ -0,0 +1,169 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/opt"
+	"go.uber.org/zap"
+)
+
+const (
+	rejectLeaderCheckerName = "reject-leader-checker"
+	// maxRejectLeaderWarnedRegions is the max number of the unsatisfiable
+	// regions remembered, beyond which they are forgotten and warned again.
+	maxRejectLeaderWarnedRegions = 10000
+)
+
+// LabelPropertyImpact is the impact of a configured label property on the
+// stores matching it.
+type LabelPropertyImpact struct {
+	Type  string `json:"type"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Stores are the IDs of the stores matching the label, except the
+	// tombstone ones.
+	Stores []uint64 `json:"stores"`
+	// LeaderCount is the number of the leaders held by the stores, which
+	// should trend to zero for reject-leader.
+	LeaderCount int `json:"leader-count"`
+}
+
+// LabelPropertyImpacts are the impacts of all the configured label properties.
+type LabelPropertyImpacts struct {
+	Properties []*LabelPropertyImpact `json:"properties"`
+	// UnsatisfiableRegions are the IDs of the regions whose healthy voters
+	// are all on the stores rejecting the leaders, at most the limit ones.
+	UnsatisfiableRegions []uint64 `json:"unsatisfiable-regions"`
+	// UnsatisfiableCount is the number of all the unsatisfiable regions.
+	UnsatisfiableCount int `json:"unsatisfiable-count"`
+}
+
+// GetLabelPropertyImpacts returns the impacts of the label properties, with at
+// most limit unsatisfiable regions sorted by the region ID.
+func (c *RaftCluster) GetLabelPropertyImpacts(limit int) *LabelPropertyImpacts {
+	impacts := &LabelPropertyImpacts{
+		Properties:           make([]*LabelPropertyImpact, 0),
+		UnsatisfiableRegions: make([]uint64, 0),
+	}
+	stores := c.GetStores()
+	sort.Slice(stores, func(i, j int) bool { return stores[i].GetID() < stores[j].GetID() })
+	for typ, labels := range c.opt.LoadLabelPropertyConfig() {
+		for _, label := range labels {
+			impact := &LabelPropertyImpact{Type: typ, Key: label.Key, Value: label.Value, Stores: make([]uint64, 0)}
+			for _, store := range stores {
+				if store.IsTombstone() || store.GetLabelValue(label.Key) != label.Value {
+					continue
+				}
+				impact.Stores = append(impact.Stores, store.GetID())
+				impact.LeaderCount += store.GetLeaderCount()
+			}
+			impacts.Properties = append(impacts.Properties, impact)
+		}
+	}
+	sort.Slice(impacts.Properties, func(i, j int) bool {
+		a, b := impacts.Properties[i], impacts.Properties[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Value < b.Value
+	})
+
+	if len(c.opt.LoadLabelPropertyConfig()[opt.RejectLeader]) == 0 {
+		return impacts
+	}
+	for _, region := range c.GetRegions() {
+		if c.isRejectLeaderUnsatisfiable(region) {
+			impacts.UnsatisfiableCount++
+			impacts.UnsatisfiableRegions = append(impacts.UnsatisfiableRegions, region.GetID())
+		}
+	}
+	sort.Slice(impacts.UnsatisfiableRegions, func(i, j int) bool {
+		return impacts.UnsatisfiableRegions[i] < impacts.UnsatisfiableRegions[j]
+	})
+	if len(impacts.UnsatisfiableRegions) > limit {
+		impacts.UnsatisfiableRegions = impacts.UnsatisfiableRegions[:limit]
+	}
+	return impacts
+}
+
+// isRejectLeaderUnsatisfiable returns true if the healthy voters of the region
+// are all on the stores rejecting the leaders, so that the leader cannot be
+// transferred out of them. The voters which are down or pending, or on the
+// stores which are not up or down, are not healthy.
+func (c *RaftCluster) isRejectLeaderUnsatisfiable(region *core.RegionInfo) bool {
+	var healthy int
+	for _, peer := range region.GetVoters() {
+		if region.GetDownPeer(peer.GetId()) != nil || region.GetPendingPeer(peer.GetId()) != nil {
+			continue
+		}
+		store := c.GetStore(peer.GetStoreId())
+		if store == nil || !store.IsUp() || store.DownTime() > c.GetMaxStoreDownTime() {
+			continue
+		}
+		if !c.CheckLabelProperty(opt.RejectLeader, store.GetLabels()) {
+			return false
+		}
+		healthy++
+	}
+	return healthy > 0
+}
+
+// rejectLeaderChecker warns about the regions which cannot satisfy the
+// reject-leader label property when they are checked. A region is warned once
+// until it becomes satisfiable. It is threadsafe.
+type rejectLeaderChecker struct {
+	sync.Mutex
+	warned map[uint64]struct{}
+}
+
+func newRejectLeaderChecker() *rejectLeaderChecker {
+	return &rejectLeaderChecker{warned: make(map[uint64]struct{})}
+}
+
+// Check returns true if the region is unsatisfiable.
+func (r *rejectLeaderChecker) Check(c *RaftCluster, region *core.RegionInfo) bool {
+	if len(c.opt.LoadLabelPropertyConfig()[opt.RejectLeader]) == 0 {
+		return false
+	}
+	unsatisfiable := c.isRejectLeaderUnsatisfiable(region)
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.warned[region.GetID()]; ok {
+		if !unsatisfiable {
+			delete(r.warned, region.GetID())
+		}
+		return unsatisfiable
+	}
+	if !unsatisfiable {
+		return false
+	}
+	if len(r.warned) >= maxRejectLeaderWarnedRegions {
+		r.warned = make(map[uint64]struct{})
+	}
+	r.warned[region.GetID()] = struct{}{}
+	regionEventCounter.WithLabelValues("reject_leader_unsatisfiable").Inc()
+	log.Warn("the healthy voters of the region are all on the stores rejecting the leaders",
+		zap.Uint64("region-id", region.GetID()),
+		zap.Uint64("leader-store-id", region.GetLeader().GetStoreId()))
+	c.GetRegionTracer().Record(region.GetID(), rejectLeaderCheckerName, "the healthy voters are all on the stores rejecting the leaders")
+	return true
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/opt"
+)
+
+var _ = Suite(&testLabelPropertyImpactSuite{})
+
+type testLabelPropertyImpactSuite struct{}
+
+func (s *testLabelPropertyImpactSuite) TestRejectLeader(c *C) {
+	_, cfg, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	tc := newTestCluster(cfg)
+	for id := uint64(1); id <= 3; id++ {
+		labels := map[string]string{"zone": "z1"}
+		if id == 3 {
+			labels["zone"] = "z2"
+		}
+		store := core.NewStoreInfoWithLabel(id, 1, labels).Clone(core.SetLastHeartbeatTS(time.Now()))
+		c.Assert(tc.putStoreLocked(store), IsNil)
+	}
+	c.Assert(tc.updateLeaderCount(1, 5), IsNil)
+	c.Assert(tc.updateLeaderCount(3, 2), IsNil)
+	c.Assert(tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	checker := newRejectLeaderChecker()
+
+	// Nothing is rejected without the property.
+	impacts := tc.GetLabelPropertyImpacts(10)
+	c.Assert(impacts.Properties, HasLen, 0)
+	c.Assert(impacts.UnsatisfiableRegions, HasLen, 0)
+	c.Assert(checker.Check(tc.RaftCluster, tc.GetRegion(1)), IsFalse)
+
+	cfg.SetLabelProperty(opt.RejectLeader, "zone", "z1")
+	impacts = tc.GetLabelPropertyImpacts(10)
+	c.Assert(impacts.Properties, HasLen, 1)
+	c.Assert(impacts.Properties[0].Type, Equals, opt.RejectLeader)
+	c.Assert(impacts.Properties[0].Stores, DeepEquals, []uint64{1, 2})
+	c.Assert(impacts.Properties[0].LeaderCount, Equals, 5)
+	c.Assert(impacts.UnsatisfiableCount, Equals, 0)
+	c.Assert(checker.Check(tc.RaftCluster, tc.GetRegion(1)), IsFalse)
+
+	// The voter on store 3 is down, so the leader cannot leave store 1 or 2.
+	region := tc.GetRegion(1)
+	down := region.GetStorePeer(3)
+	region = region.Clone(core.WithDownPeers([]*pdpb.PeerStats{{Peer: down, DownSeconds: 3600}}))
+	c.Assert(tc.putRegion(region), IsNil)
+	impacts = tc.GetLabelPropertyImpacts(10)
+	c.Assert(impacts.UnsatisfiableCount, Equals, 1)
+	c.Assert(impacts.UnsatisfiableRegions, DeepEquals, []uint64{1})
+	c.Assert(tc.GetLabelPropertyImpacts(1).UnsatisfiableRegions, HasLen, 1)
+
+	// It is warned once until it recovers.
+	c.Assert(tc.GetRegionTracer().Enable(1, time.Minute), IsNil)
+	c.Assert(checker.Check(tc.RaftCluster, region), IsTrue)
+	c.Assert(checker.Check(tc.RaftCluster, region), IsTrue)
+	c.Assert(tc.GetRegionTracer().GetTrace(1).Events, HasLen, 1)
+	c.Assert(checker.warned, HasLen, 1)
+	region = region.Clone(core.WithDownPeers(nil))
+	c.Assert(checker.Check(tc.RaftCluster, region), IsFalse)
+	c.Assert(checker.warned, HasLen, 0)
+
+	// The voter on the offline store is not healthy either.
+	c.Assert(tc.putRegion(region), IsNil)
+	c.Assert(tc.GetLabelPropertyImpacts(10).UnsatisfiableCount, Equals, 0)
+	c.Assert(tc.RemoveStore(3), IsNil)
+	c.Assert(tc.GetLabelPropertyImpacts(10).UnsatisfiableCount, Equals, 1)
+	c.Assert(checker.Check(tc.RaftCluster, region), IsTrue)
+	c.Assert(tc.GetRegionTracer().GetTrace(1).Events, HasLen, 2)
+}
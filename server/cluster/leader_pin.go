@@ -0,0 +1,199 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/filter"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"go.uber.org/zap"
+)
+
+const (
+	leaderPinName = "leader-pin"
+	// maxLeaderPinEvents is the most events kept for a pin, the oldest ones
+	// are dropped beyond it.
+	maxLeaderPinEvents = 16
+)
+
+// The types of the leader pin events.
+const (
+	LeaderPinCorrected = "corrected"
+	LeaderPinSuspended = "suspended"
+	LeaderPinResumed   = "resumed"
+)
+
+// LeaderPinEvent is a correction of the leader, or a change of the suspension
+// of a pin.
+type LeaderPinEvent struct {
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	Detail string    `json:"detail"`
+}
+
+// LeaderPinStatus is a pin along with how it is kept. A pin is suspended
+// while the stores to pin the leader on are all down, and resumed once any of
+// them is up. The problem is why the leader is not on the pinned stores.
+type LeaderPinStatus struct {
+	*core.LeaderPin
+	Suspended   bool              `json:"suspended"`
+	Problem     string            `json:"problem,omitempty"`
+	Corrections int               `json:"corrections"`
+	Events      []*LeaderPinEvent `json:"events"`
+}
+
+// leaderPinChecker moves the leaders of the pinned regions back to the pinned
+// stores when they drift. The status is not persisted, and starts over after
+// the leader of PD changes. It is threadsafe.
+type leaderPinChecker struct {
+	sync.Mutex
+	status map[uint64]*LeaderPinStatus
+	now    func() time.Time
+}
+
+func newLeaderPinChecker() *leaderPinChecker {
+	return &leaderPinChecker{
+		status: make(map[uint64]*LeaderPinStatus),
+		now:    time.Now,
+	}
+}
+
+// Check returns an operator to transfer the leader of the pinned region to a
+// pinned store which is healthy and holds a voter, if the leader drifts.
+func (p *leaderPinChecker) Check(c *RaftCluster, region *core.RegionInfo) *operator.Operator {
+	pin := c.GetLeaderPins().GetPin(region.GetID())
+	if pin == nil {
+		return nil
+	}
+	p.Lock()
+	defer p.Unlock()
+	status := p.getStatusLocked(pin)
+	leaderStoreID := region.GetLeader().GetStoreId()
+	if leader := c.GetStore(leaderStoreID); leader != nil && pin.MatchStore(leader) {
+		p.setProblemLocked(status, false, "")
+		return nil
+	}
+
+	var stores []*core.StoreInfo
+	if pin.StoreID != 0 {
+		if store := c.GetStore(pin.StoreID); store != nil {
+			stores = append(stores, store)
+		}
+	} else {
+		for _, store := range c.GetStores() {
+			if pin.MatchStore(store) {
+				stores = append(stores, store)
+			}
+		}
+		sort.Slice(stores, func(i, j int) bool { return stores[i].GetID() < stores[j].GetID() })
+	}
+	if len(stores) == 0 {
+		p.setProblemLocked(status, true, "no store to pin the leader on")
+		return nil
+	}
+	allDown := true
+	var rejected []uint64
+	stateFilter := filter.StoreStateFilter{ActionScope: leaderPinName, TransferLeader: true}
+	for _, store := range stores {
+		if store.IsTombstone() || store.DownTime() > c.GetMaxStoreDownTime() {
+			continue
+		}
+		allDown = false
+		if region.GetStoreVoter(store.GetID()) == nil || !stateFilter.Target(c, store) {
+			rejected = append(rejected, store.GetID())
+			continue
+		}
+		op, err := operator.CreateTransferLeaderOperator(leaderPinName, c, region, leaderStoreID, store.GetID(), operator.OpLeader)
+		if err != nil {
+			log.Debug("fail to create pin leader operator", zap.Uint64("region-id", region.GetID()), zap.Error(err))
+			p.setProblemLocked(status, false, err.Error())
+			return nil
+		}
+		op.SetCreator(leaderPinName)
+		p.setProblemLocked(status, false, "")
+		status.Corrections++
+		p.addEventLocked(status, LeaderPinCorrected, fmt.Sprintf("transfer leader from store %d to store %d", leaderStoreID, store.GetID()))
+		c.GetRegionTracer().Record(region.GetID(), leaderPinName, "leader drifts to store %d, transfer it back to store %d", leaderStoreID, store.GetID())
+		return op
+	}
+	if allDown {
+		p.setProblemLocked(status, true, "the stores to pin the leader on are down")
+	} else {
+		p.setProblemLocked(status, false, fmt.Sprintf("the stores %v hold no voter or can not take the leader", rejected))
+	}
+	return nil
+}
+
+func (p *leaderPinChecker) getStatusLocked(pin *core.LeaderPin) *LeaderPinStatus {
+	status, ok := p.status[pin.RegionID]
+	if !ok {
+		status = &LeaderPinStatus{Events: []*LeaderPinEvent{}}
+		p.status[pin.RegionID] = status
+	}
+	// The pin may be replaced since last checked.
+	status.LeaderPin = pin
+	return status
+}
+
+func (p *leaderPinChecker) setProblemLocked(status *LeaderPinStatus, suspended bool, problem string) {
+	if suspended != status.Suspended {
+		status.Suspended = suspended
+		if suspended {
+			p.addEventLocked(status, LeaderPinSuspended, problem)
+			log.Warn("leader pin is suspended", zap.Uint64("region-id", status.RegionID), zap.String("problem", problem))
+		} else {
+			p.addEventLocked(status, LeaderPinResumed, "")
+			log.Info("leader pin is resumed", zap.Uint64("region-id", status.RegionID))
+		}
+	}
+	status.Problem = problem
+}
+
+func (p *leaderPinChecker) addEventLocked(status *LeaderPinStatus, typ, detail string) {
+	status.Events = append(status.Events, &LeaderPinEvent{Type: typ, Time: p.now(), Detail: detail})
+	if len(status.Events) > maxLeaderPinEvents {
+		status.Events = append(status.Events[:0], status.Events[len(status.Events)-maxLeaderPinEvents:]...)
+	}
+	if typ == LeaderPinCorrected {
+		log.Info("leader pin corrects the leader", zap.Uint64("region-id", status.RegionID), zap.String("detail", detail))
+	}
+}
+
+// getStatus returns the status of the pins sorted by the region ID. The pins
+// not checked yet have no event.
+func (p *leaderPinChecker) getStatus(pins []*core.LeaderPin) []*LeaderPinStatus {
+	p.Lock()
+	defer p.Unlock()
+	statuses := make([]*LeaderPinStatus, 0, len(pins))
+	pinned := make(map[uint64]struct{}, len(pins))
+	for _, pin := range pins {
+		pinned[pin.RegionID] = struct{}{}
+		status := *p.getStatusLocked(pin)
+		status.Events = append([]*LeaderPinEvent(nil), status.Events...)
+		statuses = append(statuses, &status)
+	}
+	// The status of the removed pins is dropped.
+	for regionID := range p.status {
+		if _, ok := pinned[regionID]; !ok {
+			delete(p.status, regionID)
+		}
+	}
+	return statuses
+}
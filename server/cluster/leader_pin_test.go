@@ -0,0 +1,134 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+)
+
+var _ = Suite(&testLeaderPinSuite{})
+
+type testLeaderPinSuite struct{}
+
+func (s *testLeaderPinSuite) TestCorrectDrift(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	tc := newTestCluster(opt)
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(tc.addRegionStore(id, 1), IsNil)
+	}
+	c.Assert(tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	checker := newLeaderPinChecker()
+
+	// Not pinned.
+	c.Assert(checker.Check(tc.RaftCluster, tc.GetRegion(1)), IsNil)
+	// The store holds no peer of the region.
+	_, err = tc.PinRegionLeader(1, 4, nil)
+	c.Assert(err, IsNil)
+	c.Assert(checker.Check(tc.RaftCluster, tc.GetRegion(1)), IsNil)
+	status := checker.getStatus(tc.GetLeaderPins().GetPins())
+	c.Assert(status, HasLen, 1)
+	c.Assert(status[0].Suspended, IsFalse)
+	c.Assert(status[0].Problem, Not(Equals), "")
+
+	// The leader drifts away from the pinned store.
+	_, err = tc.PinRegionLeader(1, 2, nil)
+	c.Assert(err, IsNil)
+	op := checker.Check(tc.RaftCluster, tc.GetRegion(1))
+	c.Assert(op, NotNil)
+	c.Assert(op.Kind()&operator.OpLeader, Not(Equals), operator.OpKind(0))
+	c.Assert(op.Step(0), DeepEquals, operator.TransferLeader{FromStore: 1, ToStore: 2})
+
+	// The leader is moved back.
+	c.Assert(tc.addLeaderRegion(1, 2, 1, 3), IsNil)
+	c.Assert(checker.Check(tc.RaftCluster, tc.GetRegion(1)), IsNil)
+	status = checker.getStatus(tc.GetLeaderPins().GetPins())
+	c.Assert(status[0].Corrections, Equals, 1)
+	c.Assert(status[0].Problem, Equals, "")
+	c.Assert(status[0].Events, HasLen, 1)
+	c.Assert(status[0].Events[0].Type, Equals, LeaderPinCorrected)
+
+	// Pinned by the labels, the stores with the smaller IDs go first.
+	for _, id := range []uint64{3, 4} {
+		store := tc.GetStore(id).Clone(core.SetStoreLabels([]*metapb.StoreLabel{{Key: "zone", Value: "z1"}}))
+		tc.Lock()
+		c.Assert(tc.putStoreLocked(store), IsNil)
+		tc.Unlock()
+	}
+	_, err = tc.PinRegionLeader(1, 0, []*metapb.StoreLabel{{Key: "zone", Value: "z1"}})
+	c.Assert(err, IsNil)
+	op = checker.Check(tc.RaftCluster, tc.GetRegion(1))
+	c.Assert(op, NotNil)
+	c.Assert(op.Step(0), DeepEquals, operator.TransferLeader{FromStore: 2, ToStore: 3})
+
+	// Either the store or the labels.
+	_, err = tc.PinRegionLeader(1, 0, nil)
+	c.Assert(err, NotNil)
+	_, err = tc.PinRegionLeader(1, 3, []*metapb.StoreLabel{{Key: "zone", Value: "z1"}})
+	c.Assert(err, NotNil)
+	_, err = tc.PinRegionLeader(1, 5, nil)
+	c.Assert(err, NotNil)
+	_, err = tc.PinRegionLeader(2, 1, nil)
+	c.Assert(err, NotNil)
+}
+
+func (s *testLeaderPinSuite) TestSuspendTargetDown(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	tc := newTestCluster(opt)
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(tc.addRegionStore(id, 1), IsNil)
+	}
+	c.Assert(tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	checker := newLeaderPinChecker()
+	_, err = tc.PinRegionLeader(1, 2, nil)
+	c.Assert(err, IsNil)
+
+	// The pin is suspended while the pinned store is down.
+	c.Assert(tc.setStoreDown(2), IsNil)
+	c.Assert(checker.Check(tc.RaftCluster, tc.GetRegion(1)), IsNil)
+	status := checker.getStatus(tc.GetLeaderPins().GetPins())
+	c.Assert(status[0].Suspended, IsTrue)
+	c.Assert(status[0].Problem, Not(Equals), "")
+	c.Assert(status[0].Events[0].Type, Equals, LeaderPinSuspended)
+	// It stays suspended without any more event.
+	c.Assert(checker.Check(tc.RaftCluster, tc.GetRegion(1)), IsNil)
+	c.Assert(checker.getStatus(tc.GetLeaderPins().GetPins())[0].Events, HasLen, 1)
+
+	// The pin is resumed once the store is up.
+	c.Assert(tc.addRegionStore(2, 1), IsNil)
+	c.Assert(checker.Check(tc.RaftCluster, tc.GetRegion(1)), NotNil)
+	status = checker.getStatus(tc.GetLeaderPins().GetPins())
+	c.Assert(status[0].Suspended, IsFalse)
+	c.Assert(status[0].Corrections, Equals, 1)
+	c.Assert(status[0].Events, HasLen, 3)
+	c.Assert(status[0].Events[1].Type, Equals, LeaderPinResumed)
+	c.Assert(status[0].Events[2].Type, Equals, LeaderPinCorrected)
+
+	// The pins survive the failover.
+	pins := core.NewLeaderPins(tc.storage)
+	c.Assert(pins.Load(), IsNil)
+	c.Assert(pins.GetPin(1).StoreID, Equals, uint64(2))
+
+	// The status of the removed pin is dropped.
+	ok, err := tc.GetLeaderPins().RemovePin(1)
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsTrue)
+	c.Assert(checker.Check(tc.RaftCluster, tc.GetRegion(1)), IsNil)
+	c.Assert(checker.getStatus(tc.GetLeaderPins().GetPins()), HasLen, 0)
+	c.Assert(checker.status, HasLen, 0)
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/config"
+	"github.com/pingcap/pd/v4/server/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxLocationLabelsImpactSamples is the most regions sampled to estimate
+	// the impact of updating the location labels.
+	maxLocationLabelsImpactSamples = 1024
+	// replicaBaseScore is the base of the distinct score of the levels, the
+	// same as the one of core.DistinctScore.
+	replicaBaseScore = 100
+)
+
+// LocationLabelsImpact is the impact of updating the location labels, which
+// is estimated on a bounded sample of the regions.
+type LocationLabelsImpact struct {
+	OldLabels      []string `json:"old-labels"`
+	NewLabels      []string `json:"new-labels"`
+	TotalRegions   int      `json:"total-regions"`
+	SampledRegions int      `json:"sampled-regions"`
+	// ScoreChangedRegions is the number of the sampled regions whose distinct
+	// score of any peer changes.
+	ScoreChangedRegions int `json:"score-changed-regions"`
+	// MovingRegions is the number of the sampled regions which have a better
+	// location for a peer with the new labels but not with the old ones.
+	MovingRegions int `json:"moving-regions"`
+	// EstimatedMoves is the number of the peers estimated to be moved to a
+	// better location in all the regions.
+	EstimatedMoves int `json:"estimated-moves"`
+}
+
+// EstimateLocationLabelsImpact estimates the impact of updating the location
+// labels to the new ones before applying.
+func (c *RaftCluster) EstimateLocationLabelsImpact(labels []string) *LocationLabelsImpact {
+	oldLabels := c.opt.GetLocationLabels()
+	impact := &LocationLabelsImpact{OldLabels: oldLabels, NewLabels: labels}
+	regions := c.GetRegions()
+	impact.TotalRegions = len(regions)
+	if len(regions) == 0 {
+		return impact
+	}
+	step := (len(regions) + maxLocationLabelsImpactSamples - 1) / maxLocationLabelsImpactSamples
+	stores := c.GetStores()
+	for i := 0; i < len(regions); i += step {
+		region := regions[i]
+		impact.SampledRegions++
+		regionStores := c.GetRegionStores(region)
+		if len(regionStores) == 0 {
+			continue
+		}
+		for _, store := range regionStores {
+			if math.Abs(normalizedDistinctScore(oldLabels, regionStores, store)-normalizedDistinctScore(labels, regionStores, store)) > 1e-9 {
+				impact.ScoreChangedRegions++
+				break
+			}
+		}
+		if c.opt.IsLocationReplacementEnabled() && hasBetterLocation(labels, regionStores, stores) && !hasBetterLocation(oldLabels, regionStores, stores) {
+			impact.MovingRegions++
+		}
+	}
+	impact.EstimatedMoves = int(math.Round(float64(impact.MovingRegions) * float64(impact.TotalRegions) / float64(impact.SampledRegions)))
+	return impact
+}
+
+// normalizedDistinctScore is the distinct score in the unit of the top level
+// label, so that the scores with the labels appended are comparable.
+func normalizedDistinctScore(labels []string, stores []*core.StoreInfo, other *core.StoreInfo) float64 {
+	if len(labels) == 0 {
+		return 0
+	}
+	return core.DistinctScore(labels, stores, other) / math.Pow(replicaBaseScore, float64(len(labels)-1))
+}
+
+// hasBetterLocation returns true if the worst peer of the region could be
+// replaced by a store with a higher distinct score, like the replica checker.
+func hasBetterLocation(labels []string, regionStores, stores []*core.StoreInfo) bool {
+	if len(labels) == 0 {
+		return false
+	}
+	var (
+		worst      *core.StoreInfo
+		worstScore float64
+	)
+	for _, store := range regionStores {
+		if score := core.DistinctScore(labels, regionStores, store); worst == nil || score < worstScore {
+			worst, worstScore = store, score
+		}
+	}
+	others := make([]*core.StoreInfo, 0, len(regionStores)-1)
+	for _, store := range regionStores {
+		if store != worst {
+			others = append(others, store)
+		}
+	}
+L:
+	for _, store := range stores {
+		if !store.IsUp() || store.IsDisconnected() {
+			continue
+		}
+		for _, s := range regionStores {
+			if s.GetID() == store.GetID() {
+				continue L
+			}
+		}
+		if core.DistinctScore(labels, others, store) > worstScore {
+			return true
+		}
+	}
+	return false
+}
+
+// OnLocationLabelsUpdated records the update of the location labels, and
+// restarts the patrol of the regions to evaluate the new topology promptly.
+func (c *RaftCluster) OnLocationLabelsUpdated(oldLabels, newLabels []string) {
+	log.Info("location labels updated", zap.Strings("old", oldLabels), zap.Strings("new", newLabels))
+	configUpdateEventCounter.WithLabelValues("location-labels").Inc()
+	c.RLock()
+	co := c.coordinator
+	c.RUnlock()
+	if co != nil {
+		co.restartPatrol()
+	}
+}
+
+// CountDistinctLocations returns the number of the distinct locations of the
+// up stores by the labels, which bounds the replicas placed apart.
+func (c *RaftCluster) CountDistinctLocations(labels []string) int {
+	locations := make(map[string]struct{})
+	for _, store := range c.GetStores() {
+		if !store.IsUp() {
+			continue
+		}
+		values := make([]string, 0, len(labels))
+		for _, label := range labels {
+			values = append(values, store.GetLabelValue(label))
+		}
+		locations[strings.Join(values, "/")] = struct{}{}
+	}
+	return len(locations)
+}
+
+// OnConfigBatchUpdated records the update of a config batch as one event, and
+// restarts the patrol of the regions if the location labels are changed.
+func (c *RaftCluster) OnConfigBatchUpdated(changes []*config.Change) {
+	configUpdateEventCounter.WithLabelValues("batch").Inc()
+	for _, change := range changes {
+		if change.Item != "replication.location-labels" {
+			continue
+		}
+		c.RLock()
+		co := c.coordinator
+		c.RUnlock()
+		if co != nil {
+			co.restartPatrol()
+		}
+		return
+	}
+}
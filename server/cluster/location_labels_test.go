@@ -0,0 +1,81 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/pkg/typeutil"
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+var _ = Suite(&testLocationLabelsSuite{})
+
+type testLocationLabelsSuite struct{}
+
+func (s *testLocationLabelsSuite) TestEstimateLocationLabelsImpact(c *C) {
+	tc, co, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+	setLabels := func(labels ...string) {
+		cfg := *tc.opt.GetReplication().Load()
+		cfg.LocationLabels = typeutil.StringSlice(labels)
+		tc.opt.GetReplication().Store(&cfg)
+	}
+	// The racks are named within the zones, and store 4 is a candidate in the
+	// same zone as store 1.
+	for id, location := range map[uint64][2]string{1: {"z1", "r1"}, 2: {"z2", "r1"}, 3: {"z3", "r2"}, 4: {"z1", "r3"}} {
+		c.Assert(tc.addRegionStore(id, 0), IsNil)
+		store := tc.GetStore(id).Clone(core.SetStoreLabels([]*metapb.StoreLabel{
+			{Key: "zone", Value: location[0]},
+			{Key: "rack", Value: location[1]},
+		}))
+		c.Assert(tc.putStoreLocked(store), IsNil)
+	}
+	for id := uint64(1); id <= 10; id++ {
+		c.Assert(tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+	}
+
+	// Appending a label does not change the isolation of the regions whose
+	// peers are in different zones.
+	setLabels("zone")
+	impact := tc.EstimateLocationLabelsImpact([]string{"zone", "rack"})
+	c.Assert(impact.OldLabels, DeepEquals, []string{"zone"})
+	c.Assert(impact.TotalRegions, Equals, 10)
+	c.Assert(impact.SampledRegions, Equals, 10)
+	c.Assert(impact.ScoreChangedRegions, Equals, 0)
+	c.Assert(impact.MovingRegions, Equals, 0)
+	c.Assert(impact.EstimatedMoves, Equals, 0)
+
+	// Isolating by the racks first makes the peers on store 1 and 2 in the
+	// same location, and store 4 is a better location.
+	setLabels("zone", "rack")
+	impact = tc.EstimateLocationLabelsImpact([]string{"rack", "zone"})
+	c.Assert(impact.ScoreChangedRegions, Equals, 10)
+	c.Assert(impact.MovingRegions, Equals, 10)
+	c.Assert(impact.EstimatedMoves, Equals, 10)
+
+	// No move is estimated if the location replacement is disabled.
+	cfg := tc.opt.Load().Clone()
+	cfg.EnableLocationReplacement = false
+	tc.opt.Store(cfg)
+	impact = tc.EstimateLocationLabelsImpact([]string{"rack", "zone"})
+	c.Assert(impact.ScoreChangedRegions, Equals, 10)
+	c.Assert(impact.EstimatedMoves, Equals, 0)
+
+	// The update restarts the patrol.
+	tc.RaftCluster.coordinator = co
+	tc.OnLocationLabelsUpdated([]string{"zone", "rack"}, []string{"rack", "zone"})
+	tc.OnLocationLabelsUpdated([]string{"rack", "zone"}, []string{"zone", "rack"})
+	c.Assert(co.patrolRestart, HasLen, 1)
+}
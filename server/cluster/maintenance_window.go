@@ -0,0 +1,238 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// AllSchedulers means all the schedulers in a maintenance window.
+const AllSchedulers = "all"
+
+// ErrMaintenanceWindowOverlapped is error info for a maintenance window
+// overlapping with another one.
+var ErrMaintenanceWindowOverlapped = errors.New("maintenance window overlapped")
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// MaintenanceWindow pauses the schedulers, and optionally the checkers, in a
+// period of the day on the given weekdays, in the local time of PD. A window
+// across midnight should be split into two windows.
+type MaintenanceWindow struct {
+	Name string `json:"name"`
+	// Weekdays are the abbreviated names of the weekdays, such as "mon".
+	Weekdays []string `json:"weekdays"`
+	// Start and End are the time of the day like "09:00". End is exclusive,
+	// and "24:00" means the end of the day.
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Schedulers are the names of the paused schedulers, "all" means all the
+	// schedulers.
+	Schedulers      []string `json:"schedulers"`
+	IncludeCheckers bool     `json:"include-checkers"`
+
+	weekdays   [7]bool
+	start, end int
+}
+
+// Validate checks the window, and prepares it to be evaluated.
+func (w *MaintenanceWindow) Validate() error {
+	if w.Name == "" || strings.Contains(w.Name, "/") {
+		return errors.New("invalid name")
+	}
+	if len(w.Weekdays) == 0 {
+		return errors.New("weekdays should not be empty")
+	}
+	var weekdays [7]bool
+	for _, name := range w.Weekdays {
+		day, ok := weekdayNames[name]
+		if !ok {
+			return errors.Errorf("invalid weekday %q, should be like mon", name)
+		}
+		if weekdays[day] {
+			return errors.Errorf("duplicated weekday %q", name)
+		}
+		weekdays[day] = true
+	}
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return err
+	}
+	if start >= end {
+		return errors.New("start should be earlier than end")
+	}
+	if len(w.Schedulers) == 0 {
+		return errors.New("schedulers should not be empty")
+	}
+	w.weekdays, w.start, w.end = weekdays, start, end
+	return nil
+}
+
+// parseClock parses the time of the day to the minutes since midnight.
+func parseClock(s string) (int, error) {
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.Errorf("invalid time %q, should be like 09:00", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Overlaps returns true if both windows are active at some time.
+func (w *MaintenanceWindow) Overlaps(other *MaintenanceWindow) bool {
+	for day := range w.weekdays {
+		if w.weekdays[day] && other.weekdays[day] && w.start < other.end && other.start < w.end {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains returns true if the window is active at the given time.
+func (w *MaintenanceWindow) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	return w.weekdays[t.Weekday()] && w.start <= minute && minute < w.end
+}
+
+// MaintenanceWindows manages the maintenance windows. It is threadsafe.
+type MaintenanceWindows struct {
+	sync.RWMutex
+	storage *core.Storage
+	windows map[string]*MaintenanceWindow
+	now     func() time.Time
+}
+
+// NewMaintenanceWindows creates a MaintenanceWindows instance.
+func NewMaintenanceWindows(storage *core.Storage) *MaintenanceWindows {
+	return &MaintenanceWindows{
+		storage: storage,
+		windows: make(map[string]*MaintenanceWindow),
+		now:     time.Now,
+	}
+}
+
+// Load loads the windows from storage.
+func (m *MaintenanceWindows) Load() error {
+	m.Lock()
+	defer m.Unlock()
+	return m.storage.LoadMaintenanceWindows(func(k, v string) {
+		var window MaintenanceWindow
+		if err := json.Unmarshal([]byte(v), &window); err != nil {
+			log.Error("failed to unmarshal maintenance window", zap.String("window-key", k), zap.String("window-value", v))
+			return
+		}
+		if err := window.Validate(); err != nil {
+			log.Error("invalid maintenance window", zap.String("window-key", k), zap.String("window-value", v), zap.Error(err))
+			return
+		}
+		m.windows[window.Name] = &window
+	})
+}
+
+// SetWindow validates, persists and sets a window. The window with the same
+// name is replaced.
+func (m *MaintenanceWindows) SetWindow(window *MaintenanceWindow) error {
+	if err := window.Validate(); err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	for name, w := range m.windows {
+		if name != window.Name && w.Overlaps(window) {
+			return errors.Wrapf(ErrMaintenanceWindowOverlapped, "window %s overlaps with %s", window.Name, name)
+		}
+	}
+	if err := m.storage.SaveMaintenanceWindow(window.Name, window); err != nil {
+		return err
+	}
+	m.windows[window.Name] = window
+	log.Info("maintenance window updated", zap.String("window-name", window.Name),
+		zap.Strings("weekdays", window.Weekdays),
+		zap.String("start", window.Start),
+		zap.String("end", window.End),
+		zap.Strings("schedulers", window.Schedulers),
+		zap.Bool("include-checkers", window.IncludeCheckers))
+	return nil
+}
+
+// RemoveWindow removes a window. It returns false if the window does not exist.
+func (m *MaintenanceWindows) RemoveWindow(name string) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.windows[name]; !ok {
+		return false, nil
+	}
+	if err := m.storage.DeleteMaintenanceWindow(name); err != nil {
+		return false, err
+	}
+	delete(m.windows, name)
+	log.Info("maintenance window removed", zap.String("window-name", name))
+	return true, nil
+}
+
+// GetWindow returns the window with the name, or nil if it does not exist.
+func (m *MaintenanceWindows) GetWindow(name string) *MaintenanceWindow {
+	m.RLock()
+	defer m.RUnlock()
+	return m.windows[name]
+}
+
+// GetWindows returns all the windows sorted by name.
+func (m *MaintenanceWindows) GetWindows() []*MaintenanceWindow {
+	m.RLock()
+	defer m.RUnlock()
+	windows := make([]*MaintenanceWindow, 0, len(m.windows))
+	for _, w := range m.windows {
+		windows = append(windows, w)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].Name < windows[j].Name })
+	return windows
+}
+
+// GetActiveWindows returns the windows active now.
+func (m *MaintenanceWindows) GetActiveWindows() []*MaintenanceWindow {
+	m.RLock()
+	defer m.RUnlock()
+	now := m.now()
+	var windows []*MaintenanceWindow
+	for _, w := range m.windows {
+		if w.Contains(now) {
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/kv"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/schedulers"
+	"github.com/pkg/errors"
+)
+
+var _ = Suite(&testMaintenanceWindowSuite{})
+
+type testMaintenanceWindowSuite struct{}
+
+func (s *testMaintenanceWindowSuite) TestValidate(c *C) {
+	newWindow := func() *MaintenanceWindow {
+		return &MaintenanceWindow{Name: "w", Weekdays: []string{"mon"}, Start: "09:00", End: "24:00", Schedulers: []string{AllSchedulers}}
+	}
+	c.Assert(newWindow().Validate(), IsNil)
+	for _, f := range []func(w *MaintenanceWindow){
+		func(w *MaintenanceWindow) { w.Name = "" },
+		func(w *MaintenanceWindow) { w.Name = "a/b" },
+		func(w *MaintenanceWindow) { w.Weekdays = nil },
+		func(w *MaintenanceWindow) { w.Weekdays = []string{"monday"} },
+		func(w *MaintenanceWindow) { w.Weekdays = []string{"mon", "mon"} },
+		func(w *MaintenanceWindow) { w.Start = "9" },
+		func(w *MaintenanceWindow) { w.End = "24:01" },
+		func(w *MaintenanceWindow) { w.End = "09:00" },
+		func(w *MaintenanceWindow) { w.Schedulers = nil },
+	} {
+		w := newWindow()
+		f(w)
+		c.Assert(w.Validate(), NotNil)
+	}
+}
+
+func (s *testMaintenanceWindowSuite) TestMaintenanceWindows(c *C) {
+	storage := core.NewStorage(kv.NewMemoryKV())
+	m := NewMaintenanceWindows(storage)
+	// 2020-05-04 is a Monday.
+	now := time.Date(2020, 5, 4, 8, 59, 0, 0, time.Local)
+	m.now = func() time.Time { return now }
+
+	c.Assert(m.SetWindow(&MaintenanceWindow{Name: "day", Weekdays: []string{"mon", "tue"}, Start: "09:00", End: "18:00",
+		Schedulers: []string{schedulers.BalanceRegionName}, IncludeCheckers: true}), IsNil)
+	c.Assert(m.SetWindow(&MaintenanceWindow{Name: "evening", Weekdays: []string{"mon"}, Start: "18:00", End: "19:00",
+		Schedulers: []string{AllSchedulers}}), IsNil)
+	err := m.SetWindow(&MaintenanceWindow{Name: "overlap", Weekdays: []string{"sun", "tue"}, Start: "17:00", End: "18:30",
+		Schedulers: []string{AllSchedulers}})
+	c.Assert(errors.Cause(err), Equals, ErrMaintenanceWindowOverlapped)
+	// Updating a window does not overlap with itself.
+	c.Assert(m.SetWindow(&MaintenanceWindow{Name: "evening", Weekdays: []string{"mon", "tue"}, Start: "18:00", End: "19:00",
+		Schedulers: []string{AllSchedulers}}), IsNil)
+
+	activeNames := func() []string {
+		var names []string
+		for _, w := range m.GetActiveWindows() {
+			names = append(names, w.Name)
+		}
+		return names
+	}
+	c.Assert(activeNames(), HasLen, 0)
+	now = now.Add(time.Minute)
+	c.Assert(activeNames(), DeepEquals, []string{"day"})
+	now = now.Add(9 * time.Hour)
+	c.Assert(activeNames(), DeepEquals, []string{"evening"})
+	now = now.Add(time.Hour)
+	c.Assert(activeNames(), HasLen, 0)
+
+	// The windows are persisted.
+	m = NewMaintenanceWindows(storage)
+	c.Assert(m.Load(), IsNil)
+	c.Assert(m.GetWindows(), HasLen, 2)
+	c.Assert(m.GetWindow("evening").Weekdays, DeepEquals, []string{"mon", "tue"})
+	ok, err := m.RemoveWindow("day")
+	c.Assert(ok, IsTrue)
+	c.Assert(err, IsNil)
+	ok, err = m.RemoveWindow("day")
+	c.Assert(ok, IsFalse)
+	c.Assert(err, IsNil)
+	m = NewMaintenanceWindows(storage)
+	c.Assert(m.Load(), IsNil)
+	c.Assert(m.GetWindows(), HasLen, 1)
+}
+
+func (s *testMaintenanceWindowSuite) TestApplyMaintenanceWindows(c *C) {
+	tc, co, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+	for _, typ := range []string{schedulers.BalanceLeaderType, schedulers.BalanceRegionType} {
+		sc, err := schedule.CreateScheduler(typ, co.opController, tc.storage, schedule.ConfigSliceDecoder(typ, nil))
+		c.Assert(err, IsNil)
+		c.Assert(co.addScheduler(sc), IsNil)
+	}
+	isPaused := func(name string) bool {
+		detail, err := co.getSchedulerDetail(name)
+		c.Assert(err, IsNil)
+		return detail.Paused
+	}
+
+	windows := tc.GetMaintenanceWindows()
+	now := time.Date(2020, 5, 4, 8, 59, 0, 0, time.Local)
+	windows.now = func() time.Time { return now }
+	c.Assert(windows.SetWindow(&MaintenanceWindow{Name: "day", Weekdays: []string{"mon"}, Start: "09:00", End: "18:00",
+		Schedulers: []string{schedulers.BalanceRegionName}, IncludeCheckers: true}), IsNil)
+	c.Assert(windows.SetWindow(&MaintenanceWindow{Name: "evening", Weekdays: []string{"mon"}, Start: "18:00", End: "19:00",
+		Schedulers: []string{AllSchedulers}}), IsNil)
+	co.applyMaintenanceWindows()
+	c.Assert(isPaused(schedulers.BalanceRegionName), IsFalse)
+	c.Assert(co.isCheckersPaused(), IsFalse)
+
+	now = now.Add(time.Minute)
+	co.applyMaintenanceWindows()
+	c.Assert(isPaused(schedulers.BalanceRegionName), IsTrue)
+	c.Assert(isPaused(schedulers.BalanceLeaderName), IsFalse)
+	c.Assert(co.isCheckersPaused(), IsTrue)
+
+	// Resuming manually overrides the window until the next boundary.
+	c.Assert(co.pauseOrResumeScheduler(schedulers.BalanceRegionName, 0), IsNil)
+	c.Assert(isPaused(schedulers.BalanceRegionName), IsFalse)
+	now = now.Add(time.Hour)
+	co.applyMaintenanceWindows()
+	c.Assert(isPaused(schedulers.BalanceRegionName), IsFalse)
+
+	now = time.Date(2020, 5, 4, 18, 0, 0, 0, time.Local)
+	co.applyMaintenanceWindows()
+	c.Assert(isPaused(schedulers.BalanceRegionName), IsTrue)
+	c.Assert(isPaused(schedulers.BalanceLeaderName), IsTrue)
+	c.Assert(co.isCheckersPaused(), IsFalse)
+
+	// Pausing manually is overridden on the next boundary.
+	c.Assert(co.pauseOrResumeScheduler(schedulers.BalanceLeaderName, 3600), IsNil)
+	now = now.Add(time.Hour)
+	co.applyMaintenanceWindows()
+	c.Assert(isPaused(schedulers.BalanceRegionName), IsFalse)
+	c.Assert(isPaused(schedulers.BalanceLeaderName), IsFalse)
+
+	// Pausing manually out of the windows is kept.
+	c.Assert(co.pauseOrResumeScheduler(schedulers.BalanceLeaderName, 3600), IsNil)
+	now = now.Add(time.Hour)
+	co.applyMaintenanceWindows()
+	c.Assert(isPaused(schedulers.BalanceLeaderName), IsTrue)
+}
@@ -32,6 +32,30 @@ var (
 			Help:      "Counter of the region event",
 		}, []string{"event"})
 
+	regionCacheGapCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_cache_gap",
+			Help:      "Counter of the gaps in the region cache detected and repaired.",
+		}, []string{"event"})
+
+	storeRestartEventCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "store_restart_event",
+			Help:      "Counter of the store restart event",
+		}, []string{"event"})
+
+	configUpdateEventCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "config_update_event",
+			Help:      "Counter of the config update event",
+		}, []string{"item"})
+
 	schedulerStatusGauge = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "pd",
@@ -40,6 +64,14 @@ var (
 			Help:      "Status of the scheduler.",
 		}, []string{"kind", "type"})
 
+	schedulerObservedOperatorCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "observed_operators",
+			Help:      "Counter of the operators logged instead of being added by the observing schedulers.",
+		}, []string{"name"})
+
 	hotSpotStatusGauge = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "pd",
@@ -64,6 +96,22 @@ var (
 			Name:      "cluster_state_cpu_usage",
 			Help:      "CPU usage to determine the cluster state",
 		})
+	residualPeersGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "tombstone_residual_peers",
+			Help:      "Number of cached regions which still have peers on the tombstone store.",
+		}, []string{"store"})
+
+	balanceScoreGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "balance_score",
+			Help:      "Coefficient of variation of the leaders and the regions over the Up stores adjusted by the weights.",
+		}, []string{"type"})
+
 	clusterStateCurrent = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "pd",
@@ -75,10 +123,16 @@ var (
 
 func init() {
 	prometheus.MustRegister(regionEventCounter)
+	prometheus.MustRegister(regionCacheGapCounter)
+	prometheus.MustRegister(storeRestartEventCounter)
+	prometheus.MustRegister(configUpdateEventCounter)
 	prometheus.MustRegister(healthStatusGauge)
 	prometheus.MustRegister(schedulerStatusGauge)
+	prometheus.MustRegister(schedulerObservedOperatorCounter)
 	prometheus.MustRegister(hotSpotStatusGauge)
 	prometheus.MustRegister(patrolCheckRegionsHistogram)
 	prometheus.MustRegister(clusterStateCPUGuage)
 	prometheus.MustRegister(clusterStateCurrent)
+	prometheus.MustRegister(residualPeersGauge)
+	prometheus.MustRegister(balanceScoreGauge)
 }
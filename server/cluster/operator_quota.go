@@ -0,0 +1,192 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"go.uber.org/zap"
+)
+
+// DefaultOperatorConsumer is the consumer which the operators submitted by
+// unidentified consumers or consumers without a quota are counted to.
+const DefaultOperatorConsumer = "default"
+
+// OperatorQuota limits the operators a consumer can submit. Zero means no limit.
+type OperatorQuota struct {
+	MaxConcurrent int `json:"max-concurrent"`
+	MaxPerHour    int `json:"max-per-hour"`
+}
+
+// OperatorQuotaUsage is the operator usage of a consumer.
+type OperatorQuotaUsage struct {
+	Consumer   string        `json:"consumer"`
+	Quota      OperatorQuota `json:"quota"`
+	Concurrent int           `json:"concurrent"`
+	ThisHour   int           `json:"this-hour"`
+}
+
+type operatorQuotaBucket struct {
+	operators []*operator.Operator
+	hour      time.Time
+	hourCount int
+}
+
+// refresh drops the finished operators, and resets the count on the hour boundary.
+func (b *operatorQuotaBucket) refresh(now time.Time) {
+	running := b.operators[:0]
+	for _, op := range b.operators {
+		if !operator.IsEndStatus(op.Status()) {
+			running = append(running, op)
+		}
+	}
+	b.operators = running
+	if hour := now.Truncate(time.Hour); !hour.Equal(b.hour) {
+		b.hour = hour
+		b.hourCount = 0
+	}
+}
+
+// OperatorQuotas manages the operator quotas of the consumers submitting
+// operators via API. It is threadsafe.
+type OperatorQuotas struct {
+	sync.Mutex
+	storage *core.Storage
+	quotas  map[string]OperatorQuota
+	buckets map[string]*operatorQuotaBucket
+	now     func() time.Time
+}
+
+// NewOperatorQuotas creates an OperatorQuotas instance.
+func NewOperatorQuotas(storage *core.Storage) *OperatorQuotas {
+	return &OperatorQuotas{
+		storage: storage,
+		quotas:  make(map[string]OperatorQuota),
+		buckets: make(map[string]*operatorQuotaBucket),
+		now:     time.Now,
+	}
+}
+
+// Load loads the quotas from storage.
+func (q *OperatorQuotas) Load() error {
+	q.Lock()
+	defer q.Unlock()
+	return q.storage.LoadOperatorQuotas(func(k, v string) {
+		var quota OperatorQuota
+		if err := json.Unmarshal([]byte(v), &quota); err != nil {
+			log.Error("failed to unmarshal operator quota", zap.String("consumer", k), zap.String("quota", v))
+			return
+		}
+		q.quotas[k] = quota
+	})
+}
+
+// SetQuota persists and sets the quota of a consumer.
+func (q *OperatorQuotas) SetQuota(consumer string, quota OperatorQuota) error {
+	q.Lock()
+	defer q.Unlock()
+	if err := q.storage.SaveOperatorQuota(consumer, quota); err != nil {
+		return err
+	}
+	q.quotas[consumer] = quota
+	log.Info("operator quota updated", zap.String("consumer", consumer),
+		zap.Int("max-concurrent", quota.MaxConcurrent), zap.Int("max-per-hour", quota.MaxPerHour))
+	return nil
+}
+
+// RemoveQuota removes the quota of a consumer, so that its operators are
+// counted to the default consumer. It returns false if the quota does not exist.
+func (q *OperatorQuotas) RemoveQuota(consumer string) (bool, error) {
+	q.Lock()
+	defer q.Unlock()
+	if _, ok := q.quotas[consumer]; !ok {
+		return false, nil
+	}
+	if err := q.storage.DeleteOperatorQuota(consumer); err != nil {
+		return false, err
+	}
+	delete(q.quotas, consumer)
+	delete(q.buckets, consumer)
+	log.Info("operator quota removed", zap.String("consumer", consumer))
+	return true, nil
+}
+
+// GetUsages returns the usages of the consumers with quotas and the default
+// consumer, sorted by the consumer.
+func (q *OperatorQuotas) GetUsages() []*OperatorQuotaUsage {
+	q.Lock()
+	defer q.Unlock()
+	consumers := []string{DefaultOperatorConsumer}
+	for consumer := range q.quotas {
+		if consumer != DefaultOperatorConsumer {
+			consumers = append(consumers, consumer)
+		}
+	}
+	sort.Strings(consumers)
+	usages := make([]*OperatorQuotaUsage, 0, len(consumers))
+	for _, consumer := range consumers {
+		b := q.getBucket(consumer)
+		usages = append(usages, &OperatorQuotaUsage{
+			Consumer:   consumer,
+			Quota:      q.quotas[consumer],
+			Concurrent: len(b.operators),
+			ThisHour:   b.hourCount,
+		})
+	}
+	return usages
+}
+
+func (q *OperatorQuotas) getBucket(consumer string) *operatorQuotaBucket {
+	b, ok := q.buckets[consumer]
+	if !ok {
+		b = &operatorQuotaBucket{}
+		q.buckets[consumer] = b
+	}
+	b.refresh(q.now())
+	return b
+}
+
+// AddOperators checks the quota of the consumer, and calls add to add the
+// operators if the quota is not exceeded. The added operators are counted to
+// the consumer. It returns an OperatorQuotaExceededErr if the quota is exceeded.
+func (q *OperatorQuotas) AddOperators(consumer string, ops []*operator.Operator, add func() bool) (bool, error) {
+	q.Lock()
+	defer q.Unlock()
+	if _, ok := q.quotas[consumer]; !ok {
+		consumer = DefaultOperatorConsumer
+	}
+	quota, b := q.quotas[consumer], q.getBucket(consumer)
+	if (quota.MaxConcurrent > 0 && len(b.operators)+len(ops) > quota.MaxConcurrent) ||
+		(quota.MaxPerHour > 0 && b.hourCount+len(ops) > quota.MaxPerHour) {
+		return false, core.OperatorQuotaExceededErr{
+			Consumer:      consumer,
+			MaxConcurrent: quota.MaxConcurrent,
+			MaxPerHour:    quota.MaxPerHour,
+			Concurrent:    len(b.operators),
+			ThisHour:      b.hourCount,
+		}
+	}
+	if !add() {
+		return false, nil
+	}
+	b.operators = append(b.operators, ops...)
+	b.hourCount += len(ops)
+	return true, nil
+}
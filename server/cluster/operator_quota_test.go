@@ -0,0 +1,99 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/kv"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+)
+
+var _ = Suite(&testOperatorQuotaSuite{})
+
+type testOperatorQuotaSuite struct{}
+
+func newQuotaTestOperator() *operator.Operator {
+	return operator.NewOperator("test", "test", 1, &metapb.RegionEpoch{}, operator.OpAdmin)
+}
+
+func addQuotaTestOperators(q *OperatorQuotas, consumer string, ops ...*operator.Operator) error {
+	_, err := q.AddOperators(consumer, ops, func() bool { return true })
+	return err
+}
+
+func (s *testOperatorQuotaSuite) TestOperatorQuota(c *C) {
+	storage := core.NewStorage(kv.NewMemoryKV())
+	q := NewOperatorQuotas(storage)
+	now := time.Date(2020, 5, 1, 10, 30, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+
+	c.Assert(q.SetQuota("import", OperatorQuota{MaxConcurrent: 2, MaxPerHour: 3}), IsNil)
+	c.Assert(q.SetQuota(DefaultOperatorConsumer, OperatorQuota{MaxConcurrent: 1}), IsNil)
+
+	// Concurrent operators.
+	op1, op2 := newQuotaTestOperator(), newQuotaTestOperator()
+	c.Assert(addQuotaTestOperators(q, "import", op1, op2), IsNil)
+	err := addQuotaTestOperators(q, "import", newQuotaTestOperator())
+	c.Assert(err, DeepEquals, core.OperatorQuotaExceededErr{Consumer: "import", MaxConcurrent: 2, MaxPerHour: 3, Concurrent: 2, ThisHour: 2})
+	c.Assert(op1.Cancel(), IsTrue)
+	c.Assert(addQuotaTestOperators(q, "import", newQuotaTestOperator()), IsNil)
+
+	// Operators per hour.
+	c.Assert(op2.Cancel(), IsTrue)
+	err = addQuotaTestOperators(q, "import", newQuotaTestOperator())
+	c.Assert(err, DeepEquals, core.OperatorQuotaExceededErr{Consumer: "import", MaxConcurrent: 2, MaxPerHour: 3, Concurrent: 1, ThisHour: 3})
+	// The count is reset on the hour boundary.
+	now = time.Date(2020, 5, 1, 11, 0, 0, 0, time.UTC)
+	c.Assert(addQuotaTestOperators(q, "import", newQuotaTestOperator()), IsNil)
+
+	// Unidentified consumers and consumers without quota share the default quota.
+	c.Assert(addQuotaTestOperators(q, "", newQuotaTestOperator()), IsNil)
+	_, ok := addQuotaTestOperators(q, "unknown", newQuotaTestOperator()).(core.OperatorQuotaExceededErr)
+	c.Assert(ok, IsTrue)
+
+	// Failing to add the operators does not count.
+	c.Assert(q.SetQuota("backup", OperatorQuota{}), IsNil)
+	ok, err = q.AddOperators("backup", []*operator.Operator{newQuotaTestOperator()}, func() bool { return false })
+	c.Assert(ok, IsFalse)
+	c.Assert(err, IsNil)
+
+	c.Assert(q.GetUsages(), DeepEquals, []*OperatorQuotaUsage{
+		{Consumer: "backup", Quota: OperatorQuota{}},
+		{Consumer: DefaultOperatorConsumer, Quota: OperatorQuota{MaxConcurrent: 1}, Concurrent: 1, ThisHour: 1},
+		{Consumer: "import", Quota: OperatorQuota{MaxConcurrent: 2, MaxPerHour: 3}, Concurrent: 2, ThisHour: 1},
+	})
+
+	// The quotas are persisted.
+	q = NewOperatorQuotas(storage)
+	c.Assert(q.Load(), IsNil)
+	c.Assert(q.GetUsages(), HasLen, 3)
+	ok, err = q.RemoveQuota("backup")
+	c.Assert(ok, IsTrue)
+	c.Assert(err, IsNil)
+	ok, err = q.RemoveQuota("import")
+	c.Assert(ok, IsTrue)
+	c.Assert(err, IsNil)
+	ok, err = q.RemoveQuota("import")
+	c.Assert(ok, IsFalse)
+	c.Assert(err, IsNil)
+	q = NewOperatorQuotas(storage)
+	c.Assert(q.Load(), IsNil)
+	c.Assert(q.GetUsages(), DeepEquals, []*OperatorQuotaUsage{
+		{Consumer: DefaultOperatorConsumer, Quota: OperatorQuota{MaxConcurrent: 1}},
+	})
+}
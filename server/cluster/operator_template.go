@@ -0,0 +1,153 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// OperatorTemplate is a named operator request with the default arguments,
+// which are overridden when the template is instantiated.
+type OperatorTemplate struct {
+	Name string `json:"name"`
+	// Operator is the name of the operator, such as "transfer-region".
+	Operator string                     `json:"operator"`
+	Args     map[string]json.RawMessage `json:"args,omitempty"`
+	// ExcludeLabels excludes the stores matching any of the labels from the
+	// target stores of the operator.
+	ExcludeLabels []*metapb.StoreLabel `json:"exclude-labels,omitempty"`
+}
+
+// Validate checks the template except the arguments, which are checked
+// against the operator when it is created.
+func (t *OperatorTemplate) Validate() error {
+	if t.Name == "" || strings.Contains(t.Name, "/") {
+		return errors.New("invalid name")
+	}
+	if t.Operator == "" {
+		return errors.New("operator should not be empty")
+	}
+	for _, label := range t.ExcludeLabels {
+		if label.GetKey() == "" || label.GetValue() == "" {
+			return errors.New("the key and value of the excluded labels should not be empty")
+		}
+	}
+	return nil
+}
+
+// IsExcluded returns true if the store matches any of the excluded labels.
+func (t *OperatorTemplate) IsExcluded(store *core.StoreInfo) bool {
+	for _, label := range t.ExcludeLabels {
+		if store.GetLabelValue(label.GetKey()) == label.GetValue() {
+			return true
+		}
+	}
+	return false
+}
+
+// OperatorTemplates manages the operator templates. It is threadsafe.
+type OperatorTemplates struct {
+	sync.RWMutex
+	storage   *core.Storage
+	templates map[string]*OperatorTemplate
+}
+
+// NewOperatorTemplates creates an OperatorTemplates instance.
+func NewOperatorTemplates(storage *core.Storage) *OperatorTemplates {
+	return &OperatorTemplates{
+		storage:   storage,
+		templates: make(map[string]*OperatorTemplate),
+	}
+}
+
+// Load loads the templates from storage.
+func (m *OperatorTemplates) Load() error {
+	m.Lock()
+	defer m.Unlock()
+	return m.storage.LoadOperatorTemplates(func(k, v string) {
+		var template OperatorTemplate
+		if err := json.Unmarshal([]byte(v), &template); err != nil {
+			log.Error("failed to unmarshal operator template", zap.String("template-key", k), zap.String("template-value", v))
+			return
+		}
+		m.templates[template.Name] = &template
+	})
+}
+
+// SetTemplate validates, persists and sets a template on behalf of the
+// consumer. The template with the same name is replaced.
+func (m *OperatorTemplates) SetTemplate(template *OperatorTemplate, consumer string) error {
+	if err := template.Validate(); err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	if err := m.storage.SaveOperatorTemplate(template.Name, template); err != nil {
+		return err
+	}
+	old := m.templates[template.Name]
+	m.templates[template.Name] = template
+	log.Info("operator template updated", zap.String("template-name", template.Name),
+		zap.String("consumer", consumer),
+		zap.Reflect("old", old),
+		zap.Reflect("new", template))
+	return nil
+}
+
+// RemoveTemplate removes a template on behalf of the consumer. It returns
+// false if the template does not exist.
+func (m *OperatorTemplates) RemoveTemplate(name, consumer string) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	old, ok := m.templates[name]
+	if !ok {
+		return false, nil
+	}
+	if err := m.storage.DeleteOperatorTemplate(name); err != nil {
+		return false, err
+	}
+	delete(m.templates, name)
+	log.Info("operator template removed", zap.String("template-name", name),
+		zap.String("consumer", consumer),
+		zap.Reflect("old", old))
+	return true, nil
+}
+
+// GetTemplate returns the template with the name, or nil if it does not exist.
+func (m *OperatorTemplates) GetTemplate(name string) *OperatorTemplate {
+	m.RLock()
+	defer m.RUnlock()
+	return m.templates[name]
+}
+
+// GetTemplates returns all the templates sorted by name.
+func (m *OperatorTemplates) GetTemplates() []*OperatorTemplate {
+	m.RLock()
+	defer m.RUnlock()
+	templates := make([]*OperatorTemplate, 0, len(m.templates))
+	for _, t := range m.templates {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/kv"
+)
+
+var _ = Suite(&testOperatorTemplateSuite{})
+
+type testOperatorTemplateSuite struct{}
+
+func (s *testOperatorTemplateSuite) TestOperatorTemplates(c *C) {
+	storage := core.NewStorage(kv.NewMemoryKV())
+	m := NewOperatorTemplates(storage)
+
+	for _, t := range []*OperatorTemplate{
+		{Operator: "transfer-region"},
+		{Name: "a/b", Operator: "transfer-region"},
+		{Name: "t"},
+		{Name: "t", Operator: "transfer-region", ExcludeLabels: []*metapb.StoreLabel{{Key: "rack"}}},
+	} {
+		c.Assert(m.SetTemplate(t, ""), NotNil)
+	}
+	template := &OperatorTemplate{
+		Name:          "off-r7",
+		Operator:      "transfer-region",
+		Args:          map[string]json.RawMessage{"to_store_ids": json.RawMessage(`[1,2]`)},
+		ExcludeLabels: []*metapb.StoreLabel{{Key: "rack", Value: "r7"}},
+	}
+	c.Assert(m.SetTemplate(template, "sre"), IsNil)
+	c.Assert(m.SetTemplate(&OperatorTemplate{Name: "add", Operator: "add-peer"}, "sre"), IsNil)
+	c.Assert(m.GetTemplates(), HasLen, 2)
+	c.Assert(m.GetTemplates()[0].Name, Equals, "add")
+
+	store := core.NewStoreInfo(&metapb.Store{Id: 1, Labels: []*metapb.StoreLabel{{Key: "rack", Value: "r7"}}})
+	c.Assert(template.IsExcluded(store), IsTrue)
+	c.Assert(template.IsExcluded(core.NewStoreInfo(&metapb.Store{Id: 2})), IsFalse)
+
+	// The templates are loaded from storage.
+	loaded := NewOperatorTemplates(storage)
+	c.Assert(loaded.Load(), IsNil)
+	c.Assert(loaded.GetTemplate("off-r7"), DeepEquals, template)
+
+	ok, err := m.RemoveTemplate("add", "sre")
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsTrue)
+	ok, err = m.RemoveTemplate("add", "sre")
+	c.Assert(err, IsNil)
+	c.Assert(ok, IsFalse)
+	loaded = NewOperatorTemplates(storage)
+	c.Assert(loaded.Load(), IsNil)
+	c.Assert(loaded.GetTemplates(), HasLen, 1)
+}
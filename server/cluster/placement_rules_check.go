@@ -0,0 +1,115 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/placement"
+	"go.uber.org/zap"
+)
+
+// maxPlacementRulesCheckSamples is the most regions sampled to check the
+// placements before enabling or disabling the placement rules.
+const maxPlacementRulesCheckSamples = 1024
+
+// PlacementRulesCheck is the check before enabling or disabling the placement
+// rules, which is estimated on a bounded sample of the regions.
+type PlacementRulesCheck struct {
+	Enable bool `json:"enable"`
+	// Rules are the rules applied after enabling, or the ones in effect before
+	// disabling.
+	Rules          []*placement.Rule `json:"rules"`
+	TotalRegions   int               `json:"total-regions"`
+	SampledRegions int               `json:"sampled-regions"`
+	// UnsatisfiedRegions is the number of the sampled regions whose current
+	// placements do not satisfy the rules after enabling, or the replication
+	// config after disabling.
+	UnsatisfiedRegions int `json:"unsatisfied-regions"`
+	// EstimatedChanges is the number of the regions estimated to be changed
+	// by the checkers in all the regions.
+	EstimatedChanges int      `json:"estimated-changes"`
+	Warnings         []string `json:"warnings"`
+}
+
+// CheckEnablePlacementRules checks the rules converted from the replication
+// config, or the ones saved when enabled previously, against the current
+// placements. A faithful conversion changes no region.
+func (c *RaftCluster) CheckEnablePlacementRules() (*PlacementRulesCheck, error) {
+	rules, err := c.GetRuleManager().PreviewRules(c.opt.GetMaxReplicas(), c.opt.GetLocationLabels())
+	if err != nil {
+		return nil, err
+	}
+	check := &PlacementRulesCheck{Enable: true, Rules: rules, Warnings: []string{}}
+	if len(rules) != 1 || rules[0].GroupID != "pd" || rules[0].ID != "default" {
+		check.Warnings = append(check.Warnings, "the rules saved previously are applied instead of the ones converted from the replication config")
+	}
+	ruleSet := placement.NewRuleSet(rules)
+	c.checkPlacements(check, func(region *core.RegionInfo) bool {
+		return ruleSet.FitRegion(c, region).IsSatisfied()
+	})
+	return check, nil
+}
+
+// CheckDisablePlacementRules checks the current placements against the
+// replication config, which places the voters only and is the same for all
+// the regions.
+func (c *RaftCluster) CheckDisablePlacementRules() *PlacementRulesCheck {
+	rules := c.GetRuleManager().GetAllRules()
+	check := &PlacementRulesCheck{Rules: rules, Warnings: []string{}}
+	for _, rule := range rules {
+		if rule.Role == placement.Learner {
+			check.Warnings = append(check.Warnings, fmt.Sprintf("the learners placed by rule %s/%s are not supported by the replication config", rule.GroupID, rule.ID))
+		}
+		if len(rule.StartKey) > 0 || len(rule.EndKey) > 0 {
+			check.Warnings = append(check.Warnings, fmt.Sprintf("the key range of rule %s/%s is not supported by the replication config", rule.GroupID, rule.ID))
+		}
+	}
+	maxReplicas := c.opt.GetMaxReplicas()
+	c.checkPlacements(check, func(region *core.RegionInfo) bool {
+		return len(region.GetLearners()) == 0 && len(region.GetVoters()) == maxReplicas
+	})
+	return check
+}
+
+// checkPlacements samples the regions to estimate the changes, and warns if
+// any region is to be changed.
+func (c *RaftCluster) checkPlacements(check *PlacementRulesCheck, satisfied func(region *core.RegionInfo) bool) {
+	regions := c.GetRegions()
+	check.TotalRegions = len(regions)
+	if len(regions) == 0 {
+		return
+	}
+	step := (len(regions) + maxPlacementRulesCheckSamples - 1) / maxPlacementRulesCheckSamples
+	for i := 0; i < len(regions); i += step {
+		check.SampledRegions++
+		if !satisfied(regions[i]) {
+			check.UnsatisfiedRegions++
+		}
+	}
+	check.EstimatedChanges = int(math.Round(float64(check.UnsatisfiedRegions) * float64(check.TotalRegions) / float64(check.SampledRegions)))
+	if check.EstimatedChanges > 0 {
+		check.Warnings = append(check.Warnings, fmt.Sprintf("about %d of %d regions are estimated to be changed", check.EstimatedChanges, check.TotalRegions))
+	}
+}
+
+// OnPlacementRulesToggled records that the placement rules are enabled or
+// disabled.
+func (c *RaftCluster) OnPlacementRulesToggled(enabled bool) {
+	log.Info("placement rules toggled", zap.Bool("enabled", enabled))
+	configUpdateEventCounter.WithLabelValues("placement-rules").Inc()
+}
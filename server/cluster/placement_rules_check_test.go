@@ -0,0 +1,80 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/placement"
+)
+
+var _ = Suite(&testPlacementRulesCheckSuite{})
+
+type testPlacementRulesCheckSuite struct{}
+
+func (s *testPlacementRulesCheckSuite) TestPlacementRulesCheck(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	tc := newTestCluster(opt)
+	tc.ruleManager = placement.NewRuleManager(tc.storage)
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(tc.addRegionStore(id, 0), IsNil)
+	}
+	for id := uint64(1); id <= 10; id++ {
+		c.Assert(tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+	}
+
+	// The faithful conversion changes no region.
+	check, err := tc.CheckEnablePlacementRules()
+	c.Assert(err, IsNil)
+	c.Assert(check.Enable, IsTrue)
+	c.Assert(check.Rules, HasLen, 1)
+	c.Assert(check.Rules[0].Count, Equals, 3)
+	c.Assert(check.TotalRegions, Equals, 10)
+	c.Assert(check.SampledRegions, Equals, 10)
+	c.Assert(check.UnsatisfiedRegions, Equals, 0)
+	c.Assert(check.EstimatedChanges, Equals, 0)
+	c.Assert(check.Warnings, HasLen, 0)
+	// Nothing is saved by the check.
+	c.Assert(tc.ruleManager.GetAllRules(), HasLen, 0)
+
+	// The region lacking a replica is to be changed.
+	c.Assert(tc.addLeaderRegion(11, 1, 2), IsNil)
+	check, err = tc.CheckEnablePlacementRules()
+	c.Assert(err, IsNil)
+	c.Assert(check.UnsatisfiedRegions, Equals, 1)
+	c.Assert(check.EstimatedChanges, Equals, 1)
+	c.Assert(check.Warnings, HasLen, 1)
+
+	// The learners placed by the rules are not supported by the replication
+	// config.
+	c.Assert(tc.ruleManager.Initialize(3, nil), IsNil)
+	c.Assert(tc.ruleManager.SetRule(&placement.Rule{GroupID: "tiflash", ID: "learner", Role: placement.Learner, Count: 1}), IsNil)
+	region := newTestRegionMeta(12)
+	region.Peers = []*metapb.Peer{
+		{Id: 121, StoreId: 1},
+		{Id: 122, StoreId: 2},
+		{Id: 123, StoreId: 3},
+		{Id: 124, StoreId: 4, IsLearner: true},
+	}
+	c.Assert(tc.putRegion(core.NewRegionInfo(region, region.Peers[0])), IsNil)
+	check = tc.CheckDisablePlacementRules()
+	c.Assert(check.Enable, IsFalse)
+	c.Assert(check.Rules, HasLen, 2)
+	c.Assert(check.UnsatisfiedRegions, Equals, 2)
+	c.Assert(check.EstimatedChanges, Equals, 2)
+	c.Assert(check.Warnings, HasLen, 2)
+	c.Assert(check.Warnings[0], Matches, ".*learners placed by rule tiflash/learner.*")
+}
@@ -0,0 +1,180 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bytes"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxRegionGapRepairs is the max number of the gaps being repaired.
+	maxRegionGapRepairs = 1024
+	// regionGapRepairTTL is how long a gap is repaired, after which it should
+	// be found again to be repaired.
+	regionGapRepairTTL = 10 * time.Minute
+)
+
+// RegionCacheGap is a key range not covered by the regions in the cache.
+type RegionCacheGap struct {
+	// StartKey and EndKey are in hex format.
+	StartKey     string `json:"start_key"`
+	EndKey       string `json:"end_key"`
+	PrevRegionID uint64 `json:"prev_region_id"`
+	NextRegionID uint64 `json:"next_region_id"`
+	// RepairTime is when the gap is found to be repaired, or nil if it is not
+	// being repaired.
+	RepairTime *time.Time `json:"repair_time,omitempty"`
+}
+
+type regionGapRepairItem struct {
+	gap  *core.RegionGap
+	time time.Time
+}
+
+// regionGapRepair is the gaps being repaired. The heartbeats of the regions
+// overlapping a gap are put into the cache, even if nothing seems changed, as
+// the cache may still have the regions by their IDs while they are dropped from
+// the region tree. A gap is not repaired any more once a region overlapping it
+// is put, or it expires. It is threadsafe.
+type regionGapRepair struct {
+	sync.Mutex
+	items map[string]*regionGapRepairItem
+	now   func() time.Time
+}
+
+func newRegionGapRepair() *regionGapRepair {
+	return &regionGapRepair{
+		items: make(map[string]*regionGapRepairItem),
+		now:   time.Now,
+	}
+}
+
+// request starts to repair the gap. It returns false if the gap is being
+// repaired already or there are too many gaps being repaired.
+func (r *regionGapRepair) request(gap *core.RegionGap) bool {
+	r.Lock()
+	defer r.Unlock()
+	r.gcLocked()
+	key := string(gap.StartKey)
+	if item, ok := r.items[key]; ok && bytes.Equal(item.gap.EndKey, gap.EndKey) {
+		return false
+	}
+	if len(r.items) >= maxRegionGapRepairs {
+		return false
+	}
+	r.items[key] = &regionGapRepairItem{gap: gap, time: r.now()}
+	return true
+}
+
+func (r *regionGapRepair) gcLocked() {
+	now := r.now()
+	for key, item := range r.items {
+		if now.Sub(item.time) > regionGapRepairTTL {
+			delete(r.items, key)
+		}
+	}
+}
+
+// shouldRepair returns true if the region overlaps a gap being repaired.
+func (r *regionGapRepair) shouldRepair(region *core.RegionInfo) bool {
+	r.Lock()
+	defer r.Unlock()
+	for _, item := range r.items {
+		if overlapsGap(region, item.gap) {
+			return true
+		}
+	}
+	return false
+}
+
+// repaired stops repairing the gaps overlapped by the region, and returns the
+// number of them.
+func (r *regionGapRepair) repaired(region *core.RegionInfo) int {
+	r.Lock()
+	defer r.Unlock()
+	var n int
+	for key, item := range r.items {
+		if overlapsGap(region, item.gap) {
+			delete(r.items, key)
+			n++
+		}
+	}
+	return n
+}
+
+// requestTime returns when the gap is found to be repaired.
+func (r *regionGapRepair) requestTime(gap *core.RegionGap) (time.Time, bool) {
+	r.Lock()
+	defer r.Unlock()
+	item, ok := r.items[string(gap.StartKey)]
+	if !ok || !bytes.Equal(item.gap.EndKey, gap.EndKey) || r.now().Sub(item.time) > regionGapRepairTTL {
+		return time.Time{}, false
+	}
+	return item.time, true
+}
+
+func overlapsGap(region *core.RegionInfo, gap *core.RegionGap) bool {
+	return (len(gap.EndKey) == 0 || bytes.Compare(region.GetStartKey(), gap.EndKey) < 0) &&
+		(len(region.GetEndKey()) == 0 || bytes.Compare(gap.StartKey, region.GetEndKey()) < 0)
+}
+
+// RepairRegionGaps finds the gaps right before and after the region in the
+// cache, and repairs them by the next heartbeats of the regions covering them.
+// It returns the number of the gaps found.
+func (c *RaftCluster) RepairRegionGaps(region *core.RegionInfo) int {
+	prev, next := c.core.GetAdjacentRegionGaps(region)
+	var n int
+	for _, gap := range []*core.RegionGap{prev, next} {
+		if gap == nil {
+			continue
+		}
+		n++
+		if c.regionGaps.request(gap) {
+			regionCacheGapCounter.WithLabelValues("detected").Inc()
+			log.Warn("gap found in the region cache, repair it by the next heartbeats",
+				zap.String("start-key", core.HexRegionKeyStr(gap.StartKey)),
+				zap.String("end-key", core.HexRegionKeyStr(gap.EndKey)),
+				zap.Uint64("prev-region-id", gap.PrevRegionID),
+				zap.Uint64("next-region-id", gap.NextRegionID))
+		}
+	}
+	return n
+}
+
+// GetRegionCacheGaps returns the gaps in the key space covered by the regions
+// in the cache, at most limit ones if limit is positive.
+func (c *RaftCluster) GetRegionCacheGaps(limit int) []*RegionCacheGap {
+	gaps := c.core.GetRegionGaps(limit)
+	res := make([]*RegionCacheGap, 0, len(gaps))
+	for _, gap := range gaps {
+		cacheGap := &RegionCacheGap{
+			StartKey:     hex.EncodeToString(gap.StartKey),
+			EndKey:       hex.EncodeToString(gap.EndKey),
+			PrevRegionID: gap.PrevRegionID,
+			NextRegionID: gap.NextRegionID,
+		}
+		if t, ok := c.regionGaps.requestTime(gap); ok {
+			cacheGap.RepairTime = &t
+		}
+		res = append(res, cacheGap)
+	}
+	return res
+}
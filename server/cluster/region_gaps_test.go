@@ -0,0 +1,96 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+var _ = Suite(&testRegionGapsSuite{})
+
+type testRegionGapsSuite struct{}
+
+func (s *testRegionGapsSuite) newRegion(id uint64, start, end string) *core.RegionInfo {
+	peer := &metapb.Peer{Id: id + 100, StoreId: 1}
+	meta := &metapb.Region{
+		Id:          id,
+		Peers:       []*metapb.Peer{peer},
+		StartKey:    []byte(start),
+		EndKey:      []byte(end),
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}
+	return core.NewRegionInfo(meta, peer, core.SetApproximateSize(1))
+}
+
+func (s *testRegionGapsSuite) TestRepairRegionGaps(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	tc := newTestCluster(opt)
+	c.Assert(tc.addRegionStore(1, 3), IsNil)
+	now := time.Now()
+	tc.regionGaps.now = func() time.Time { return now }
+
+	r1, r2, r3 := s.newRegion(1, "", "b"), s.newRegion(2, "b", "c"), s.newRegion(3, "c", "")
+	for _, region := range []*core.RegionInfo{r1, r2, r3} {
+		c.Assert(tc.processRegionHeartbeat(region), IsNil)
+	}
+	c.Assert(tc.GetRegionCacheGaps(0), HasLen, 0)
+	c.Assert(tc.RepairRegionGaps(r1), Equals, 0)
+
+	// Dropping region 2 leaves a gap between region 1 and 3.
+	tc.DropCacheRegion(2)
+	c.Assert(tc.RepairRegionGaps(r1), Equals, 1)
+	c.Assert(tc.RepairRegionGaps(r3), Equals, 1)
+	c.Assert(tc.regionGaps.items, HasLen, 1)
+	gaps := tc.GetRegionCacheGaps(0)
+	c.Assert(gaps, HasLen, 1)
+	c.Assert(gaps[0].StartKey, Equals, "62")
+	c.Assert(gaps[0].EndKey, Equals, "63")
+	c.Assert(gaps[0].PrevRegionID, Equals, uint64(1))
+	c.Assert(gaps[0].NextRegionID, Equals, uint64(3))
+	c.Assert(gaps[0].RepairTime, NotNil)
+	c.Assert(gaps[0].RepairTime.Equal(now), IsTrue)
+
+	// The heartbeat of the region not overlapping the gap does not repair it.
+	c.Assert(tc.regionGaps.shouldRepair(r1), IsFalse)
+	c.Assert(tc.processRegionHeartbeat(r1), IsNil)
+	c.Assert(tc.regionGaps.items, HasLen, 1)
+
+	// The heartbeat of region 2 fills the gap.
+	c.Assert(tc.regionGaps.shouldRepair(r2), IsTrue)
+	c.Assert(tc.processRegionHeartbeat(r2), IsNil)
+	c.Assert(tc.GetRegionCacheGaps(0), HasLen, 0)
+	c.Assert(tc.regionGaps.items, HasLen, 0)
+
+	// The region is put even if nothing changes, in case the cache has it by
+	// its ID but not in the region tree.
+	c.Assert(tc.regionGaps.request(&core.RegionGap{StartKey: []byte("b"), EndKey: []byte("c")}), IsTrue)
+	r2 = r2.Clone()
+	c.Assert(tc.processRegionHeartbeat(r2), IsNil)
+	c.Assert(tc.GetRegion(2), Equals, r2)
+	c.Assert(tc.regionGaps.items, HasLen, 0)
+
+	// The repair expires.
+	tc.DropCacheRegion(2)
+	c.Assert(tc.RepairRegionGaps(r1), Equals, 1)
+	now = now.Add(regionGapRepairTTL + time.Second)
+	gaps = tc.GetRegionCacheGaps(0)
+	c.Assert(gaps, HasLen, 1)
+	c.Assert(gaps[0].RepairTime, IsNil)
+	c.Assert(tc.regionGaps.request(&core.RegionGap{StartKey: []byte("b"), EndKey: []byte("c")}), IsTrue)
+}
@@ -0,0 +1,221 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// RegionLineageRetention is how long the region lineage events are kept.
+	RegionLineageRetention = 30 * 24 * time.Hour
+	// MaxRegionLineageDepth is the max number of the events walked in a
+	// direction when the ancestry of a region is queried.
+	MaxRegionLineageDepth = 64
+	// maxRegionLineageEvents is the max number of the region lineage events
+	// kept, and the oldest ones are removed beyond it.
+	maxRegionLineageEvents = 100000
+)
+
+// The types of the region lineage events.
+const (
+	RegionLineageSplit = "split"
+	RegionLineageMerge = "merge"
+)
+
+// RegionLineageEvent is a split or merge which turns the parent regions into
+// the child regions. A region keeps its ID after a split or merge, so the ID
+// may be both a parent and a child of an event.
+type RegionLineageEvent struct {
+	ID       uint64    `json:"id"`
+	Type     string    `json:"type"`
+	Parents  []uint64  `json:"parents"`
+	Children []uint64  `json:"children"`
+	StartKey string    `json:"start-key"`
+	EndKey   string    `json:"end-key"`
+	Time     time.Time `json:"time"`
+}
+
+// RegionAncestry is the lineage of a region walked in both directions. The
+// events are in the order of the time.
+type RegionAncestry struct {
+	RegionID    uint64                `json:"region-id"`
+	Depth       int                   `json:"depth"`
+	Ancestors   []*RegionLineageEvent `json:"ancestors"`
+	Descendants []*RegionLineageEvent `json:"descendants"`
+}
+
+// RegionLineage records the splits and merges of the regions, so that a region
+// can be traced after its ID vanishes. It is threadsafe.
+type RegionLineage struct {
+	sync.RWMutex
+	storage *core.Storage
+	// events are in the order of the ID.
+	events []*RegionLineageEvent
+	nextID uint64
+	now    func() time.Time
+}
+
+// NewRegionLineage creates a RegionLineage instance.
+func NewRegionLineage(storage *core.Storage) *RegionLineage {
+	return &RegionLineage{
+		storage: storage,
+		nextID:  1,
+		now:     time.Now,
+	}
+}
+
+// Load loads the events from storage.
+func (l *RegionLineage) Load() error {
+	l.Lock()
+	defer l.Unlock()
+	l.events = l.events[:0]
+	err := l.storage.LoadRegionLineageEvents(func(k, v string) {
+		event := &RegionLineageEvent{}
+		if err := json.Unmarshal([]byte(v), event); err != nil {
+			log.Error("failed to unmarshal region lineage event", zap.String("id", k), zap.String("event", v))
+			return
+		}
+		l.events = append(l.events, event)
+		if event.ID >= l.nextID {
+			l.nextID = event.ID + 1
+		}
+	})
+	if err != nil {
+		return err
+	}
+	log.Info("load region lineage events", zap.Int("count", len(l.events)))
+	return nil
+}
+
+// RecordSplit records that the parent region is split into the children.
+func (l *RegionLineage) RecordSplit(parent uint64, children []uint64, startKey, endKey []byte) error {
+	return l.record(RegionLineageSplit, []uint64{parent}, children, startKey, endKey)
+}
+
+// RecordMerge records that the parents are merged into the child region.
+func (l *RegionLineage) RecordMerge(parents []uint64, child uint64, startKey, endKey []byte) error {
+	return l.record(RegionLineageMerge, parents, []uint64{child}, startKey, endKey)
+}
+
+func (l *RegionLineage) record(typ string, parents, children []uint64, startKey, endKey []byte) error {
+	l.Lock()
+	defer l.Unlock()
+	event := &RegionLineageEvent{
+		ID:       l.nextID,
+		Type:     typ,
+		Parents:  parents,
+		Children: children,
+		StartKey: core.HexRegionKeyStr(startKey),
+		EndKey:   core.HexRegionKeyStr(endKey),
+		Time:     l.now(),
+	}
+	if err := l.storage.SaveRegionLineageEvent(event.ID, event); err != nil {
+		return err
+	}
+	l.nextID++
+	l.events = append(l.events, event)
+	return l.gcLocked(event.Time)
+}
+
+// gcLocked removes the events out of the retention or beyond the max number.
+func (l *RegionLineage) gcLocked(now time.Time) error {
+	cutoff := now.Add(-RegionLineageRetention)
+	var n int
+	for n < len(l.events) && (len(l.events)-n > maxRegionLineageEvents || l.events[n].Time.Before(cutoff)) {
+		if err := l.storage.DeleteRegionLineageEvent(l.events[n].ID); err != nil {
+			l.events = l.events[n:]
+			return err
+		}
+		n++
+	}
+	l.events = l.events[n:]
+	return nil
+}
+
+// GetAncestry walks the lineage of the region up to the depth in both
+// directions. The ancestors are the events which led to the region, and the
+// descendants are the ones which the region went through.
+func (l *RegionLineage) GetAncestry(regionID uint64, depth int) *RegionAncestry {
+	l.RLock()
+	defer l.RUnlock()
+	return &RegionAncestry{
+		RegionID:    regionID,
+		Depth:       depth,
+		Ancestors:   l.walkLocked(regionID, depth, false),
+		Descendants: l.walkLocked(regionID, depth, true),
+	}
+}
+
+// walkLocked walks the events level by level. Forward, an event is reached
+// from a region which is its parent, and leads to its children. Backward, it
+// is the other way around. An event is only reached from the events before it
+// forward, or after it backward, as a region ID can be reused by the events.
+func (l *RegionLineage) walkLocked(regionID uint64, depth int, forward bool) []*RegionLineageEvent {
+	type frontier struct {
+		regionID uint64
+		// eventID is the ID of the event which led to the region.
+		eventID uint64
+	}
+	from := []frontier{{regionID: regionID}}
+	if !forward {
+		from[0].eventID = l.nextID
+	}
+	visited := make(map[uint64]struct{})
+	for level := 0; level < depth && len(from) > 0; level++ {
+		var next []frontier
+		for _, event := range l.events {
+			if _, ok := visited[event.ID]; ok {
+				continue
+			}
+			in, out := event.Parents, event.Children
+			if !forward {
+				in, out = out, in
+			}
+			for _, f := range from {
+				if (forward && event.ID <= f.eventID) || (!forward && event.ID >= f.eventID) || !containsID(in, f.regionID) {
+					continue
+				}
+				visited[event.ID] = struct{}{}
+				for _, id := range out {
+					next = append(next, frontier{regionID: id, eventID: event.ID})
+				}
+				break
+			}
+		}
+		from = next
+	}
+	events := make([]*RegionLineageEvent, 0, len(visited))
+	for _, event := range l.events {
+		if _, ok := visited[event.ID]; ok {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func containsID(ids []uint64, id uint64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
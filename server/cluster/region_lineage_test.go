@@ -0,0 +1,134 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/pkg/mock/mockid"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/kv"
+)
+
+var _ = Suite(&testRegionLineageSuite{})
+
+type testRegionLineageSuite struct{}
+
+func (s *testRegionLineageSuite) TestSplitAndMerge(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	storage := core.NewStorage(kv.NewMemoryKV())
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, storage, core.NewBasicCluster())
+	newRegion := func(id uint64, start, end string, version uint64) *core.RegionInfo {
+		peer := &metapb.Peer{Id: id * 10, StoreId: 1}
+		return core.NewRegionInfo(&metapb.Region{
+			Id:          id,
+			StartKey:    []byte(start),
+			EndKey:      []byte(end),
+			Peers:       []*metapb.Peer{peer},
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: version},
+		}, peer)
+	}
+	c.Assert(cluster.processRegionHeartbeat(newRegion(1, "a", "c", 1)), IsNil)
+
+	// Region 1 is split into regions 2 and 1.
+	_, err = cluster.HandleReportSplit(&pdpb.ReportSplitRequest{
+		Left:  newRegion(2, "a", "b", 2).GetMeta(),
+		Right: newRegion(1, "b", "c", 2).GetMeta(),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(cluster.processRegionHeartbeat(newRegion(2, "a", "b", 2)), IsNil)
+	c.Assert(cluster.processRegionHeartbeat(newRegion(1, "b", "c", 2)), IsNil)
+	// Region 1 is split into regions 3, 4 and 1 in a batch.
+	_, err = cluster.HandleBatchReportSplit(&pdpb.ReportBatchSplitRequest{Regions: []*metapb.Region{
+		newRegion(3, "b", "b1", 3).GetMeta(),
+		newRegion(4, "b1", "b2", 3).GetMeta(),
+		newRegion(1, "b2", "c", 3).GetMeta(),
+	}})
+	c.Assert(err, IsNil)
+	// Region 2 is merged into region 5, which neighbours it. Region 5 is
+	// new, so it is not a merge.
+	c.Assert(cluster.processRegionHeartbeat(newRegion(5, "", "a", 1)), IsNil)
+	c.Assert(cluster.processRegionHeartbeat(newRegion(5, "", "b", 2)), IsNil)
+
+	lineage := cluster.GetRegionLineage()
+	c.Assert(lineage.events, HasLen, 3)
+	c.Assert(lineage.events[0].Type, Equals, RegionLineageSplit)
+	c.Assert(lineage.events[0].Parents, DeepEquals, []uint64{1})
+	c.Assert(lineage.events[0].Children, DeepEquals, []uint64{2, 1})
+	c.Assert(lineage.events[0].StartKey, Equals, core.HexRegionKeyStr([]byte("a")))
+	c.Assert(lineage.events[0].EndKey, Equals, core.HexRegionKeyStr([]byte("c")))
+	c.Assert(lineage.events[1].Children, DeepEquals, []uint64{3, 4, 1})
+	c.Assert(lineage.events[2].Type, Equals, RegionLineageMerge)
+	c.Assert(lineage.events[2].Parents, DeepEquals, []uint64{5, 2})
+	c.Assert(lineage.events[2].Children, DeepEquals, []uint64{5})
+
+	// Region 2 comes from the first split of region 1, and is merged into
+	// region 5.
+	ancestry := lineage.GetAncestry(2, MaxRegionLineageDepth)
+	c.Assert(eventIDs(ancestry.Ancestors), DeepEquals, []uint64{1})
+	c.Assert(eventIDs(ancestry.Descendants), DeepEquals, []uint64{3})
+	// Region 5 is traced back to region 1 through region 2, but not to the
+	// later batch split. It goes through the merge as it keeps its ID.
+	ancestry = lineage.GetAncestry(5, MaxRegionLineageDepth)
+	c.Assert(eventIDs(ancestry.Ancestors), DeepEquals, []uint64{1, 3})
+	c.Assert(eventIDs(ancestry.Descendants), DeepEquals, []uint64{3})
+	ancestry = lineage.GetAncestry(5, 1)
+	c.Assert(eventIDs(ancestry.Ancestors), DeepEquals, []uint64{3})
+	// Region 1 goes through both splits, and region 2 split from it goes to
+	// region 5.
+	ancestry = lineage.GetAncestry(1, MaxRegionLineageDepth)
+	c.Assert(eventIDs(ancestry.Ancestors), DeepEquals, []uint64{1, 2})
+	c.Assert(eventIDs(ancestry.Descendants), DeepEquals, []uint64{1, 2, 3})
+	ancestry = lineage.GetAncestry(1, 1)
+	c.Assert(eventIDs(ancestry.Descendants), DeepEquals, []uint64{1, 2})
+
+	// The events are persisted.
+	lineage = NewRegionLineage(storage)
+	c.Assert(lineage.Load(), IsNil)
+	c.Assert(eventIDs(lineage.GetAncestry(5, MaxRegionLineageDepth).Ancestors), DeepEquals, []uint64{1, 3})
+	c.Assert(lineage.RecordSplit(5, []uint64{6, 5}, nil, []byte("b")), IsNil)
+	c.Assert(lineage.events[3].ID, Equals, uint64(4))
+}
+
+func (s *testRegionLineageSuite) TestRetention(c *C) {
+	storage := core.NewStorage(kv.NewMemoryKV())
+	now := time.Now()
+	lineage := NewRegionLineage(storage)
+	lineage.now = func() time.Time { return now }
+	c.Assert(lineage.RecordSplit(1, []uint64{2, 1}, nil, nil), IsNil)
+	now = now.Add(RegionLineageRetention / 2)
+	c.Assert(lineage.RecordMerge([]uint64{3, 1}, 3, nil, nil), IsNil)
+	c.Assert(eventIDs(lineage.GetAncestry(3, MaxRegionLineageDepth).Ancestors), DeepEquals, []uint64{1, 2})
+
+	// The split expires.
+	now = now.Add(RegionLineageRetention/2 + time.Minute)
+	c.Assert(lineage.RecordSplit(3, []uint64{4, 3}, nil, nil), IsNil)
+	c.Assert(eventIDs(lineage.GetAncestry(3, MaxRegionLineageDepth).Ancestors), DeepEquals, []uint64{2, 3})
+	lineage = NewRegionLineage(storage)
+	c.Assert(lineage.Load(), IsNil)
+	c.Assert(lineage.events, HasLen, 2)
+	c.Assert(lineage.events[0].ID, Equals, uint64(2))
+}
+
+func eventIDs(events []*RegionLineageEvent) []uint64 {
+	ids := make([]uint64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
@@ -0,0 +1,167 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/schedule/opt"
+	"github.com/pingcap/pd/v4/server/schedulers"
+	"go.uber.org/zap"
+)
+
+// repairFirstHeldTypes are the types of the schedulers held in the
+// repair-first phase. The others, such as evict-leader, are always allowed as
+// they are used to keep the cluster available.
+var repairFirstHeldTypes = map[string]struct{}{
+	schedulers.BalanceLeaderType: {},
+	schedulers.BalanceRegionType: {},
+	schedulers.HotRegionType:     {},
+}
+
+// hotStatusUpdater is implemented by the schedulers whose hot status can be
+// updated without scheduling, which is done when they are held in the
+// repair-first phase.
+type hotStatusUpdater interface {
+	UpdateHotStatus(cluster opt.Cluster)
+}
+
+// RepairFirstStatus is the progress of the repair-first phase.
+type RepairFirstStatus struct {
+	Active    bool      `json:"active"`
+	StartTime time.Time `json:"start_time"`
+	Deadline  time.Time `json:"deadline"`
+	// Scanned is the number of the regions checked by the patrol in the phase.
+	Scanned int `json:"scanned"`
+	// RegionCount is the number of the regions in the cache.
+	RegionCount int `json:"region_count"`
+	// Phase describes the phase, such as "repair-first phase, 42% scanned".
+	Phase string `json:"phase"`
+}
+
+// repairFirst is the phase after the coordinator starts, in which the balance
+// and hot region schedulers are held until the patrol checks all the regions
+// once or the deadline passes, so that the operators of the checkers, such as
+// replacing the down peers, are not competing with theirs for the store
+// limits. It is threadsafe.
+type repairFirst struct {
+	sync.Mutex
+	startTime time.Time
+	deadline  time.Time
+	scanned   int
+	active    bool
+	now       func() time.Time
+}
+
+func newRepairFirst() *repairFirst {
+	return &repairFirst{now: time.Now}
+}
+
+func (r *repairFirst) start(timeout time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.startTime = r.now()
+	r.deadline = r.startTime.Add(timeout)
+	r.scanned = 0
+	r.active = true
+	log.Info("repair-first phase starts, the balance and hot region schedulers are held",
+		zap.Duration("timeout", timeout), zap.Time("deadline", r.deadline))
+}
+
+// isActiveLocked returns true if the phase is neither finished nor expired.
+// The expiration is found and logged lazily.
+func (r *repairFirst) isActiveLocked() bool {
+	if r.active && !r.now().Before(r.deadline) {
+		r.active = false
+		log.Warn("repair-first phase expires before the patrol checks all the regions",
+			zap.Int("scanned", r.scanned))
+	}
+	return r.active
+}
+
+func (r *repairFirst) isActive() bool {
+	r.Lock()
+	defer r.Unlock()
+	return r.isActiveLocked()
+}
+
+// observe records the regions checked by the patrol, and finishes the phase
+// if the patrol reaches the last region.
+func (r *repairFirst) observe(scanned int, passed bool) {
+	r.Lock()
+	defer r.Unlock()
+	if !r.isActiveLocked() {
+		return
+	}
+	r.scanned += scanned
+	if passed {
+		r.active = false
+		log.Info("repair-first phase finishes, the schedulers are released",
+			zap.Int("scanned", r.scanned), zap.Duration("duration", r.now().Sub(r.startTime)))
+	}
+}
+
+// reset clears the regions checked as the patrol restarts from the first one.
+func (r *repairFirst) reset() {
+	r.Lock()
+	defer r.Unlock()
+	r.scanned = 0
+}
+
+func (r *repairFirst) status(regionCount int) *RepairFirstStatus {
+	r.Lock()
+	defer r.Unlock()
+	status := &RepairFirstStatus{
+		Active:      r.isActiveLocked(),
+		StartTime:   r.startTime,
+		Deadline:    r.deadline,
+		Scanned:     r.scanned,
+		RegionCount: regionCount,
+		Phase:       "normal",
+	}
+	if status.Active {
+		percent := 100
+		if regionCount > r.scanned {
+			percent = r.scanned * 100 / regionCount
+		}
+		status.Phase = fmt.Sprintf("repair-first phase, %d%% scanned", percent)
+	}
+	return status
+}
+
+// isHeldByRepairFirst returns true if the scheduler cannot create operators
+// in the repair-first phase.
+func (c *coordinator) isHeldByRepairFirst(s *scheduleController) bool {
+	if _, ok := repairFirstHeldTypes[s.GetType()]; !ok {
+		return false
+	}
+	return c.cluster.opt.IsRepairFirstEnabled() && c.repairFirst.isActive()
+}
+
+// repairFirstStatusLocked returns the progress of the repair-first phase, or
+// nil if the cluster is not running. The caller should hold the lock.
+func (c *RaftCluster) repairFirstStatusLocked() *RepairFirstStatus {
+	if !c.running || c.coordinator == nil {
+		return nil
+	}
+	status := c.coordinator.repairFirst.status(c.GetRegionCount())
+	if !c.opt.IsRepairFirstEnabled() {
+		status.Active = false
+		status.Phase = "normal"
+	}
+	return status
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/pkg/testutil"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/kv"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"github.com/pingcap/pd/v4/server/schedulers"
+)
+
+var _ = Suite(&testRepairFirstSuite{})
+
+type testRepairFirstSuite struct{}
+
+func (s *testRepairFirstSuite) TestRepairBeforeBalance(c *C) {
+	tc, co, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+	tc.coordinator, tc.running = co, true
+
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(tc.addRegionStore(id, 10), IsNil)
+	}
+	c.Assert(tc.setStoreDown(3), IsNil)
+	// All the leaders are on store 1, and the peers on store 3 are down.
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(tc.addLeaderRegion(id, 1, 2, 3), IsNil)
+		region := tc.GetRegion(id)
+		region = region.Clone(core.WithDownPeers([]*pdpb.PeerStats{{
+			Peer:        region.GetStorePeer(3),
+			DownSeconds: 24 * 60 * 60,
+		}}))
+		c.Assert(tc.putRegion(region), IsNil)
+	}
+	c.Assert(tc.addLeaderRegion(4, 1, 2, 4), IsNil)
+	c.Assert(tc.updateLeaderCount(1, 30), IsNil)
+	co.opController.SetAllStoresLimit(60, schedule.StoreLimitManual)
+
+	lb, err := schedule.CreateScheduler(schedulers.BalanceLeaderType, co.opController, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(schedulers.BalanceLeaderType, []string{"", ""}))
+	c.Assert(err, IsNil)
+	sc := newScheduleController(co, lb)
+	el, err := schedule.CreateScheduler(schedulers.EvictLeaderType, co.opController, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(schedulers.EvictLeaderType, []string{"2"}))
+	c.Assert(err, IsNil)
+
+	co.repairFirst.start(time.Minute)
+	c.Assert(co.isHeldByRepairFirst(sc), IsTrue)
+	c.Assert(co.isHeldByRepairFirst(newScheduleController(co, el)), IsFalse)
+	status := tc.repairFirstStatusLocked()
+	c.Assert(status.Active, IsTrue)
+	c.Assert(status.Phase, Equals, "repair-first phase, 0% scanned")
+
+	co.wg.Add(1)
+	go co.patrolRegions()
+	testutil.WaitUntil(c, func(c *C) bool {
+		return !co.repairFirst.isActive()
+	})
+	// The repairs are created in the phase.
+	for id := uint64(1); id <= 3; id++ {
+		op := co.opController.GetOperator(id)
+		c.Assert(op, NotNil)
+		c.Assert(op.Reason().Code, Equals, operator.ReasonDownPeer)
+	}
+	status = tc.repairFirstStatusLocked()
+	c.Assert(status.Active, IsFalse)
+	c.Assert(status.Scanned, Equals, 4)
+	c.Assert(status.Phase, Equals, "normal")
+
+	// The balance scheduler is released after the repairs.
+	c.Assert(co.isHeldByRepairFirst(sc), IsFalse)
+	ops := sc.Schedule()
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].RegionID(), Equals, uint64(4))
+	c.Assert(ops[0].Reason().Code, Equals, operator.ReasonScoreImbalance)
+}
+
+func (s *testRepairFirstSuite) TestPhase(c *C) {
+	tc, co, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+	tc.coordinator, tc.running = co, true
+	c.Assert(tc.addRegionStore(1, 5), IsNil)
+	for id := uint64(1); id <= 5; id++ {
+		c.Assert(tc.addLeaderRegion(id, 1), IsNil)
+	}
+	lb, err := schedule.CreateScheduler(schedulers.BalanceLeaderType, co.opController, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(schedulers.BalanceLeaderType, []string{"", ""}))
+	c.Assert(err, IsNil)
+	sc := newScheduleController(co, lb)
+
+	now := time.Now()
+	co.repairFirst.now = func() time.Time { return now }
+	co.repairFirst.start(time.Minute)
+	co.repairFirst.observe(2, false)
+	c.Assert(tc.repairFirstStatusLocked().Phase, Equals, "repair-first phase, 40% scanned")
+	co.repairFirst.reset()
+	c.Assert(tc.repairFirstStatusLocked().Scanned, Equals, 0)
+
+	// The phase can be disabled.
+	tc.opt.Load().EnableRepairFirst = false
+	c.Assert(co.isHeldByRepairFirst(sc), IsFalse)
+	c.Assert(tc.repairFirstStatusLocked().Active, IsFalse)
+	tc.opt.Load().EnableRepairFirst = true
+	c.Assert(co.isHeldByRepairFirst(sc), IsTrue)
+
+	// The hot region scheduler is held, but its hot status is updated.
+	hb, err := schedule.CreateScheduler(schedulers.HotRegionType, co.opController, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(schedulers.HotRegionType, nil))
+	c.Assert(err, IsNil)
+	c.Assert(co.isHeldByRepairFirst(newScheduleController(co, hb)), IsTrue)
+	c.Assert(hb.(hasHotStatus).GetHotWriteStatus().AsLeader, HasLen, 0)
+	hb.(hotStatusUpdater).UpdateHotStatus(tc)
+	c.Assert(hb.(hasHotStatus).GetHotWriteStatus().AsLeader, HasKey, uint64(1))
+
+	// The phase expires.
+	now = now.Add(time.Minute)
+	c.Assert(co.isHeldByRepairFirst(sc), IsFalse)
+	c.Assert(tc.repairFirstStatusLocked().Phase, Equals, "normal")
+}
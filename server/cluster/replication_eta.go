@@ -0,0 +1,197 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"github.com/pingcap/pd/v4/server/statistics"
+)
+
+const (
+	// replicationRateSampleInterval is the min interval between the samples of
+	// the replica operator completion rate.
+	replicationRateSampleInterval = time.Minute
+	// maxReplicationRateSamples is the number of the recent samples kept.
+	maxReplicationRateSamples = 30
+)
+
+// ReplicationETA is the estimated time to restore the full replication of the
+// regions, e.g. after a store fails.
+type ReplicationETA struct {
+	// UnderReplicatedRegions is the number of the regions missing peers, or
+	// having peers down or on the offline stores.
+	UnderReplicatedRegions int `json:"under_replicated_regions"`
+	// PendingPeers is the number of the peers to be re-replicated.
+	PendingPeers int `json:"pending_peers"`
+	// PendingSize is the approximate size in MiB of the peers to be
+	// re-replicated.
+	PendingSize int64 `json:"pending_size"`
+	// LimitRate is the peers per second allowed by the store limits of the
+	// stores that can receive the peers.
+	LimitRate float64 `json:"limit_rate"`
+	// ObservedRate is the mean of the recent completion rates of the replica
+	// operators in peers per second.
+	ObservedRate float64 `json:"observed_rate"`
+	RateSamples  int     `json:"rate_samples"`
+	// EffectiveRate is the observed rate capped by the limit rate, or the
+	// limit rate if no replica operator has finished recently.
+	EffectiveRate float64 `json:"effective_rate"`
+	// ETA and its bounds are in seconds. They are null if the rate is zero,
+	// and the upper bound is also null if there are not enough samples.
+	ETA        *float64  `json:"eta"`
+	ETALower   *float64  `json:"eta_lower"`
+	ETAUpper   *float64  `json:"eta_upper"`
+	UpdateTime time.Time `json:"update_time"`
+}
+
+// replicationRates is a ring of the recent completion rates of the replica
+// operators. It is threadsafe.
+type replicationRates struct {
+	sync.Mutex
+	samples    []float64
+	next       int
+	lastSample time.Time
+	now        func() time.Time
+}
+
+func newReplicationRates() *replicationRates {
+	return &replicationRates{now: time.Now}
+}
+
+// observe records the rate if the last sample is old enough.
+func (r *replicationRates) observe(rate float64) {
+	r.Lock()
+	defer r.Unlock()
+	now := r.now()
+	if now.Sub(r.lastSample) < replicationRateSampleInterval {
+		return
+	}
+	r.lastSample = now
+	if len(r.samples) < maxReplicationRateSamples {
+		r.samples = append(r.samples, rate)
+		return
+	}
+	r.samples[r.next] = rate
+	r.next = (r.next + 1) % maxReplicationRateSamples
+}
+
+func (r *replicationRates) list() []float64 {
+	r.Lock()
+	defer r.Unlock()
+	return append([]float64(nil), r.samples...)
+}
+
+// estimateReplicationETA fills the rates and the ETA of the pending peers. The
+// observed rate is the mean of the samples, and the bounds are 2 standard
+// deviations around it, all capped by the limit rate.
+func estimateReplicationETA(eta *ReplicationETA, samples []float64) {
+	eta.RateSamples = len(samples)
+	eta.EffectiveRate = eta.LimitRate
+	var mean, stddev float64
+	if len(samples) > 0 {
+		for _, rate := range samples {
+			mean += rate
+		}
+		mean /= float64(len(samples))
+		for _, rate := range samples {
+			stddev += (rate - mean) * (rate - mean)
+		}
+		stddev = math.Sqrt(stddev / float64(len(samples)))
+		eta.ObservedRate = mean
+		if mean > 0 {
+			eta.EffectiveRate = math.Min(mean, eta.LimitRate)
+		}
+	}
+
+	seconds := func(rate float64) *float64 {
+		if eta.PendingPeers == 0 {
+			return new(float64)
+		}
+		if rate <= 0 {
+			return nil
+		}
+		s := float64(eta.PendingPeers) / rate
+		return &s
+	}
+	eta.ETA = seconds(eta.EffectiveRate)
+	fastest, slowest := eta.LimitRate, 0.0
+	if mean > 0 {
+		fastest = math.Min(mean+2*stddev, eta.LimitRate)
+		slowest = math.Min(mean-2*stddev, eta.LimitRate)
+	}
+	eta.ETALower = seconds(fastest)
+	if len(samples) >= 2 || eta.PendingPeers == 0 {
+		eta.ETAUpper = seconds(slowest)
+	}
+}
+
+// observeReplicationRate samples the completion rate of the replica operators.
+func (c *RaftCluster) observeReplicationRate() {
+	c.replicaRates.observe(c.coordinator.opController.GetFinishedOperatorRate(operator.OpReplica))
+}
+
+// GetReplicationETA estimates the time to re-replicate the peers of the
+// under-replicated regions. The regions are found by the region statistics,
+// and each of them needs the missing peers with regard to max-replicas, plus
+// the peers down or on the offline stores. The peers are added at the recent
+// completion rate of the replica operators, which is capped by the sum of the
+// store limits of the up stores.
+func (c *RaftCluster) GetReplicationETA() *ReplicationETA {
+	eta := &ReplicationETA{UpdateTime: time.Now()}
+	regions := make(map[uint64]*core.RegionInfo)
+	for _, typ := range []statistics.RegionStatisticType{statistics.MissPeer, statistics.DownPeer, statistics.OfflinePeer} {
+		for _, region := range c.GetRegionStatsByType(typ) {
+			regions[region.GetID()] = region
+		}
+	}
+	maxReplicas := c.GetMaxReplicas()
+	for _, region := range regions {
+		peers := len(region.GetDownPeers())
+		if missing := maxReplicas - len(region.GetVoters()); missing > 0 {
+			peers += missing
+		}
+		for _, peer := range region.GetPeers() {
+			if store := c.GetStore(peer.GetStoreId()); store != nil && store.IsOffline() {
+				peers++
+			}
+		}
+		if peers == 0 {
+			continue
+		}
+		eta.UnderReplicatedRegions++
+		eta.PendingPeers += peers
+		eta.PendingSize += int64(peers) * region.GetApproximateSize()
+	}
+
+	limits := c.coordinator.opController.GetAllStoresLimit()
+	defaultRate := c.GetStoreBalanceRate() / schedule.StoreBalanceBaseTime
+	for _, store := range c.GetStores() {
+		if !store.IsUp() || store.DownTime() > c.GetMaxStoreDownTime() {
+			continue
+		}
+		if limit, ok := limits[store.GetID()]; ok {
+			eta.LimitRate += limit.Rate()
+		} else {
+			eta.LimitRate += defaultRate
+		}
+	}
+	estimateReplicationETA(eta, c.replicaRates.list())
+	return eta
+}
@@ -0,0 +1,168 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"math"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/pkg/mock/mockhbstream"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/statistics"
+)
+
+var _ = Suite(&testReplicationETASuite{})
+
+type testReplicationETASuite struct{}
+
+func (s *testReplicationETASuite) checkSeconds(c *C, seconds *float64, expected float64) {
+	c.Assert(seconds, NotNil)
+	c.Assert(math.Abs(*seconds-expected) < 1e-6, IsTrue, Commentf("%v != %v", *seconds, expected))
+}
+
+func (s *testReplicationETASuite) TestEstimate(c *C) {
+	// Nothing is observed, so the store limits are all we know.
+	eta := &ReplicationETA{PendingPeers: 120, LimitRate: 2}
+	estimateReplicationETA(eta, nil)
+	c.Assert(eta.EffectiveRate, Equals, 2.0)
+	s.checkSeconds(c, eta.ETA, 60)
+	s.checkSeconds(c, eta.ETALower, 60)
+	c.Assert(eta.ETAUpper, IsNil)
+
+	// The replica operators have not finished yet.
+	eta = &ReplicationETA{PendingPeers: 120, LimitRate: 2}
+	estimateReplicationETA(eta, []float64{0, 0, 0})
+	c.Assert(eta.EffectiveRate, Equals, 2.0)
+	s.checkSeconds(c, eta.ETA, 60)
+	c.Assert(eta.ETAUpper, IsNil)
+
+	// mean = 1, stddev = 0.5
+	eta = &ReplicationETA{PendingPeers: 120, LimitRate: 2}
+	estimateReplicationETA(eta, []float64{0.5, 1.5})
+	c.Assert(eta.ObservedRate, Equals, 1.0)
+	c.Assert(eta.RateSamples, Equals, 2)
+	c.Assert(eta.EffectiveRate, Equals, 1.0)
+	s.checkSeconds(c, eta.ETA, 120)
+	s.checkSeconds(c, eta.ETALower, 60)
+	c.Assert(eta.ETAUpper, IsNil)
+
+	// mean = 1, stddev = 0.1
+	eta = &ReplicationETA{PendingPeers: 120, LimitRate: 2}
+	estimateReplicationETA(eta, []float64{0.9, 1.1, 0.9, 1.1})
+	s.checkSeconds(c, eta.ETA, 120)
+	s.checkSeconds(c, eta.ETALower, 120/1.2)
+	s.checkSeconds(c, eta.ETAUpper, 120/0.8)
+
+	// The store limits are the ceiling of the observed rate.
+	eta = &ReplicationETA{PendingPeers: 120, LimitRate: 2}
+	estimateReplicationETA(eta, []float64{4, 4})
+	c.Assert(eta.ObservedRate, Equals, 4.0)
+	c.Assert(eta.EffectiveRate, Equals, 2.0)
+	s.checkSeconds(c, eta.ETA, 60)
+	s.checkSeconds(c, eta.ETALower, 60)
+	s.checkSeconds(c, eta.ETAUpper, 60)
+
+	// No store can receive the peers.
+	eta = &ReplicationETA{PendingPeers: 120}
+	estimateReplicationETA(eta, []float64{1, 1})
+	c.Assert(eta.ETA, IsNil)
+	c.Assert(eta.ETALower, IsNil)
+	c.Assert(eta.ETAUpper, IsNil)
+
+	// Fully replicated.
+	eta = &ReplicationETA{LimitRate: 2}
+	estimateReplicationETA(eta, nil)
+	s.checkSeconds(c, eta.ETA, 0)
+	s.checkSeconds(c, eta.ETALower, 0)
+	s.checkSeconds(c, eta.ETAUpper, 0)
+}
+
+func (s *testReplicationETASuite) TestReplicationRates(c *C) {
+	rates := newReplicationRates()
+	now := time.Now()
+	rates.now = func() time.Time { return now }
+	rates.observe(1)
+	// Sampled at most once per interval.
+	rates.observe(2)
+	c.Assert(rates.list(), DeepEquals, []float64{1})
+	for i := 2; i <= maxReplicationRateSamples+2; i++ {
+		now = now.Add(replicationRateSampleInterval)
+		rates.observe(float64(i))
+	}
+	samples := rates.list()
+	c.Assert(samples, HasLen, maxReplicationRateSamples)
+	// The oldest ones are overwritten.
+	c.Assert(samples[0], Equals, float64(maxReplicationRateSamples+1))
+	c.Assert(samples[1], Equals, float64(maxReplicationRateSamples+2))
+	c.Assert(samples[2], Equals, 3.0)
+}
+
+func (s *testReplicationETASuite) TestGetReplicationETA(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	tc := newTestCluster(opt)
+	tc.regionStats = statistics.NewRegionStatistics(tc.GetOpt())
+	hbStreams := mockhbstream.NewHeartbeatStreams(tc.getClusterID(), true /* no need to run */)
+	defer hbStreams.Close()
+	tc.coordinator = newCoordinator(ctx, tc.RaftCluster, hbStreams)
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(tc.addRegionStore(id, 1), IsNil)
+	}
+	// The down store cannot receive the peers.
+	c.Assert(tc.putStoreLocked(core.NewStoreInfo(&metapb.Store{Id: 4}, core.SetLastHeartbeatTS(time.Now().Add(-time.Hour)))), IsNil)
+	tc.coordinator.opController.SetStoreLimit(1, 0.5, schedule.StoreLimitManual)
+	defaultRate := tc.GetStoreBalanceRate() / schedule.StoreBalanceBaseTime
+
+	// Each region misses 2 peers after the failure.
+	for id := uint64(1); id <= 4; id++ {
+		peer := &metapb.Peer{Id: id + 100, StoreId: 1}
+		region := core.NewRegionInfo(&metapb.Region{
+			Id:          id,
+			Peers:       []*metapb.Peer{peer},
+			StartKey:    []byte{byte(id)},
+			EndKey:      []byte{byte(id + 1)},
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+		}, peer, core.SetApproximateSize(10))
+		c.Assert(tc.processRegionHeartbeat(region), IsNil)
+	}
+	tc.replicaRates.samples = []float64{0.1, 0.1}
+	eta := tc.GetReplicationETA()
+	c.Assert(eta.UnderReplicatedRegions, Equals, 4)
+	c.Assert(eta.PendingPeers, Equals, 8)
+	c.Assert(eta.PendingSize, Equals, int64(80))
+	c.Assert(math.Abs(eta.LimitRate-(0.5+2*defaultRate)) < 1e-9, IsTrue)
+	c.Assert(eta.EffectiveRate, Equals, 0.1)
+	s.checkSeconds(c, eta.ETA, 80)
+	s.checkSeconds(c, eta.ETAUpper, 80)
+
+	// The ETA shrinks as the repairs complete.
+	for id := uint64(1); id <= 2; id++ {
+		region := tc.GetRegion(id)
+		for _, storeID := range []uint64{2, 3} {
+			region = region.Clone(core.WithAddPeer(&metapb.Peer{Id: id*10 + storeID, StoreId: storeID}), core.WithIncConfVer())
+		}
+		c.Assert(tc.processRegionHeartbeat(region), IsNil)
+	}
+	eta = tc.GetReplicationETA()
+	c.Assert(eta.UnderReplicatedRegions, Equals, 2)
+	c.Assert(eta.PendingPeers, Equals, 4)
+	c.Assert(eta.PendingSize, Equals, int64(40))
+	s.checkSeconds(c, eta.ETA, 40)
+}
@@ -0,0 +1,364 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/pkg/typeutil"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const defaultLeaderDrainTimeout = 10 * time.Minute
+
+// The states of a rolling restart.
+const (
+	RollingRestartRunning  = "running"
+	RollingRestartPaused   = "paused"
+	RollingRestartAborted  = "aborted"
+	RollingRestartFinished = "finished"
+)
+
+// The phases of a store in a rolling restart.
+const (
+	// StoreRestartPending means the store waits for the previous stores.
+	StoreRestartPending = "pending"
+	// StoreRestartDraining means the leaders are being evicted from the store.
+	StoreRestartDraining = "draining"
+	// StoreRestartReady means the store has no leader and is ready to restart.
+	StoreRestartReady = "ready"
+	// StoreRestartCatchingUp means the store has restarted, and the pending
+	// peers are not back to the baseline yet.
+	StoreRestartCatchingUp = "catching-up"
+	// StoreRestartDone means the store has restarted and caught up.
+	StoreRestartDone = "done"
+	// StoreRestartSkipped means the store is removed or tombstone.
+	StoreRestartSkipped = "skipped"
+)
+
+var (
+	// ErrRollingRestartInProgress is error info for starting a rolling restart
+	// when another one is running or paused.
+	ErrRollingRestartInProgress = errors.New("rolling restart in progress")
+	// ErrRollingRestartNotFound is error info for no rolling restart.
+	ErrRollingRestartNotFound = errors.New("rolling restart not found")
+	// ErrRollingRestartStateMismatch is error info for pausing, resuming or
+	// aborting a rolling restart in the wrong state.
+	ErrRollingRestartStateMismatch = errors.New("rolling restart state mismatch")
+)
+
+// RollingRestartConfig is the stores to restart in order and the criteria to
+// move to the next store.
+type RollingRestartConfig struct {
+	StoreIDs []uint64 `json:"store-ids"`
+	// LeaderDrainTimeout is the time to wait for the leaders to be evicted
+	// from a store, after which the rolling restart is paused.
+	LeaderDrainTimeout typeutil.Duration `json:"leader-drain-timeout"`
+	// PendingPeerTolerance is the pending peers above the baseline tolerated
+	// when a restarted store is regarded as caught up.
+	PendingPeerTolerance int `json:"pending-peer-tolerance"`
+}
+
+// StoreRestart is the progress of a store in a rolling restart.
+type StoreRestart struct {
+	StoreID uint64 `json:"store-id"`
+	Phase   string `json:"phase"`
+	// PhaseStartTime is the time when the store enters the phase.
+	PhaseStartTime time.Time `json:"phase-start-time"`
+	// BaselinePendingPeers is the pending peers of the store before draining.
+	BaselinePendingPeers int `json:"baseline-pending-peers"`
+	// StartTimestamp is the start time of the store when it is ready to
+	// restart, by which the restart is detected.
+	StartTimestamp int64 `json:"start-timestamp"`
+	// Evicted is true if the leaders are evicted by the rolling restart rather
+	// than the evict-leader-scheduler added before.
+	Evicted bool `json:"evicted"`
+}
+
+// RollingRestart gates the restarts of the stores one by one. It evicts the
+// leaders from a store, reports the store is ready to restart once it has no
+// leader, and moves to the next store after the store restarts and catches
+// up. It never restarts any store itself.
+type RollingRestart struct {
+	RollingRestartConfig
+	State string `json:"state"`
+	// Message is the reason why the rolling restart is paused.
+	Message   string          `json:"message,omitempty"`
+	StartTime time.Time       `json:"start-time"`
+	Stores    []*StoreRestart `json:"stores"`
+}
+
+// Clone returns a deep copy of the rolling restart.
+func (r *RollingRestart) Clone() *RollingRestart {
+	restart := *r
+	restart.StoreIDs = append(r.StoreIDs[:0:0], r.StoreIDs...)
+	restart.Stores = make([]*StoreRestart, 0, len(r.Stores))
+	for _, s := range r.Stores {
+		store := *s
+		restart.Stores = append(restart.Stores, &store)
+	}
+	return &restart
+}
+
+// current returns the first store not done or skipped, or nil if all the
+// stores are.
+func (r *RollingRestart) current() *StoreRestart {
+	for _, s := range r.Stores {
+		if s.Phase != StoreRestartDone && s.Phase != StoreRestartSkipped {
+			return s
+		}
+	}
+	return nil
+}
+
+// RollingRestartController manages the rolling restart, which is persisted
+// so that it is resumed after the leader of PD changes. It is threadsafe.
+type RollingRestartController struct {
+	sync.RWMutex
+	storage *core.Storage
+	restart *RollingRestart
+	now     func() time.Time
+}
+
+// NewRollingRestartController creates a RollingRestartController instance.
+func NewRollingRestartController(storage *core.Storage) *RollingRestartController {
+	return &RollingRestartController{
+		storage: storage,
+		now:     time.Now,
+	}
+}
+
+// Load loads the rolling restart from storage.
+func (m *RollingRestartController) Load() error {
+	m.Lock()
+	defer m.Unlock()
+	var restart RollingRestart
+	ok, err := m.storage.LoadRollingRestart(&restart)
+	if err != nil || !ok {
+		return err
+	}
+	m.restart = &restart
+	return nil
+}
+
+// Get returns the rolling restart, or nil if there is none.
+func (m *RollingRestartController) Get() *RollingRestart {
+	m.RLock()
+	defer m.RUnlock()
+	if m.restart == nil {
+		return nil
+	}
+	return m.restart.Clone()
+}
+
+// Start persists and starts a rolling restart, which replaces the finished or
+// aborted one. The stores should be validated by the caller.
+func (m *RollingRestartController) Start(cfg *RollingRestartConfig) (*RollingRestart, error) {
+	m.Lock()
+	defer m.Unlock()
+	if m.restart != nil && (m.restart.State == RollingRestartRunning || m.restart.State == RollingRestartPaused) {
+		return nil, ErrRollingRestartInProgress
+	}
+	restart := &RollingRestart{
+		RollingRestartConfig: *cfg,
+		State:                RollingRestartRunning,
+		StartTime:            m.now(),
+	}
+	if restart.LeaderDrainTimeout.Duration <= 0 {
+		restart.LeaderDrainTimeout = typeutil.NewDuration(defaultLeaderDrainTimeout)
+	}
+	for _, id := range cfg.StoreIDs {
+		restart.Stores = append(restart.Stores, &StoreRestart{StoreID: id, Phase: StoreRestartPending})
+	}
+	if err := m.storage.SaveRollingRestart(restart); err != nil {
+		return nil, err
+	}
+	m.restart = restart
+	log.Info("rolling restart started", zap.Uint64s("store-ids", cfg.StoreIDs),
+		zap.Duration("leader-drain-timeout", restart.LeaderDrainTimeout.Duration),
+		zap.Int("pending-peer-tolerance", restart.PendingPeerTolerance))
+	return restart.Clone(), nil
+}
+
+// Pause pauses the running rolling restart. The leaders are kept evicted from
+// the current store.
+func (m *RollingRestartController) Pause() error {
+	return m.update(RollingRestartRunning, func(r *RollingRestart) {
+		r.State, r.Message = RollingRestartPaused, "paused manually"
+	})
+}
+
+// Resume resumes the paused rolling restart. The leader drain timeout of the
+// current store is restarted.
+func (m *RollingRestartController) Resume() error {
+	return m.update(RollingRestartPaused, func(r *RollingRestart) {
+		r.State, r.Message = RollingRestartRunning, ""
+		if s := r.current(); s != nil && s.Phase == StoreRestartDraining {
+			s.PhaseStartTime = m.now()
+		}
+	})
+}
+
+// Abort aborts the running or paused rolling restart, and stops evicting the
+// leaders from the current store.
+func (m *RollingRestartController) Abort(co *coordinator) error {
+	m.Lock()
+	defer m.Unlock()
+	r := m.restart
+	if r == nil {
+		return ErrRollingRestartNotFound
+	}
+	if r.State != RollingRestartRunning && r.State != RollingRestartPaused {
+		return errors.Wrapf(ErrRollingRestartStateMismatch, "the rolling restart is %s", r.State)
+	}
+	restart := r.Clone()
+	if s := restart.current(); s != nil && s.Evicted {
+		if err := co.unevictLeader(s.StoreID); err != nil {
+			return err
+		}
+		s.Evicted = false
+	}
+	restart.State, restart.Message = RollingRestartAborted, ""
+	if err := m.storage.SaveRollingRestart(restart); err != nil {
+		return err
+	}
+	m.restart = restart
+	log.Info("rolling restart aborted")
+	return nil
+}
+
+// update applies the change to a copy of the rolling restart in the state, and
+// persists it.
+func (m *RollingRestartController) update(state string, f func(r *RollingRestart)) error {
+	m.Lock()
+	defer m.Unlock()
+	if m.restart == nil {
+		return ErrRollingRestartNotFound
+	}
+	if m.restart.State != state {
+		return errors.Wrapf(ErrRollingRestartStateMismatch, "the rolling restart is %s", m.restart.State)
+	}
+	restart := m.restart.Clone()
+	f(restart)
+	if err := m.storage.SaveRollingRestart(restart); err != nil {
+		return err
+	}
+	m.restart = restart
+	log.Info("rolling restart updated", zap.String("state", restart.State))
+	return nil
+}
+
+// advance moves the running rolling restart forward by the current stores. It
+// is called periodically.
+func (m *RollingRestartController) advance(co *coordinator) {
+	m.Lock()
+	defer m.Unlock()
+	if m.restart == nil || m.restart.State != RollingRestartRunning {
+		return
+	}
+	restart := m.restart.Clone()
+	var changed bool
+	for {
+		s := restart.current()
+		if s == nil {
+			restart.State = RollingRestartFinished
+			changed = true
+			log.Info("rolling restart finished")
+			break
+		}
+		phase := s.Phase
+		m.advanceStore(co, restart, s)
+		if s.Phase != phase || restart.State != RollingRestartRunning {
+			changed = true
+		}
+		// Moves to the next store in the same round once the store is done.
+		if (s.Phase != StoreRestartDone && s.Phase != StoreRestartSkipped) || restart.State != RollingRestartRunning {
+			break
+		}
+	}
+	if !changed {
+		return
+	}
+	if err := m.storage.SaveRollingRestart(restart); err != nil {
+		log.Error("failed to persist rolling restart", zap.Error(err))
+		return
+	}
+	m.restart = restart
+}
+
+// advanceStore moves the store to the next phase if it is ready.
+func (m *RollingRestartController) advanceStore(co *coordinator, r *RollingRestart, s *StoreRestart) {
+	store := co.cluster.GetStore(s.StoreID)
+	if store == nil || store.IsTombstone() {
+		if s.Evicted {
+			if err := co.unevictLeader(s.StoreID); err != nil {
+				log.Error("failed to stop evicting leaders for rolling restart", zap.Uint64("store-id", s.StoreID), zap.Error(err))
+				return
+			}
+			s.Evicted = false
+		}
+		log.Warn("skip restarting the store which is removed", zap.Uint64("store-id", s.StoreID))
+		m.enterPhase(s, StoreRestartSkipped)
+		return
+	}
+	switch s.Phase {
+	case StoreRestartPending:
+		evicted, err := co.evictLeader(s.StoreID)
+		if err != nil {
+			log.Error("failed to evict leaders for rolling restart", zap.Uint64("store-id", s.StoreID), zap.Error(err))
+			return
+		}
+		s.Evicted = evicted
+		s.BaselinePendingPeers = store.GetPendingPeerCount()
+		m.enterPhase(s, StoreRestartDraining)
+	case StoreRestartDraining:
+		if store.GetLeaderCount() == 0 {
+			s.StartTimestamp = store.GetStartTime().Unix()
+			m.enterPhase(s, StoreRestartReady)
+			log.Info("store ready to restart", zap.Uint64("store-id", s.StoreID))
+			return
+		}
+		if m.now().Sub(s.PhaseStartTime) > r.LeaderDrainTimeout.Duration {
+			r.State = RollingRestartPaused
+			r.Message = fmt.Sprintf("the leaders of store %d are not drained in %s", s.StoreID, r.LeaderDrainTimeout.Duration)
+			log.Warn("rolling restart paused", zap.String("reason", r.Message))
+		}
+	case StoreRestartReady:
+		if store.GetStartTime().Unix() > s.StartTimestamp && !store.IsDisconnected() {
+			m.enterPhase(s, StoreRestartCatchingUp)
+		}
+	case StoreRestartCatchingUp:
+		if store.GetPendingPeerCount() > s.BaselinePendingPeers+r.PendingPeerTolerance {
+			return
+		}
+		if s.Evicted {
+			if err := co.unevictLeader(s.StoreID); err != nil {
+				log.Error("failed to stop evicting leaders for rolling restart", zap.Uint64("store-id", s.StoreID), zap.Error(err))
+				return
+			}
+			s.Evicted = false
+		}
+		m.enterPhase(s, StoreRestartDone)
+	}
+}
+
+func (m *RollingRestartController) enterPhase(s *StoreRestart, phase string) {
+	log.Info("store restart phase changed", zap.Uint64("store-id", s.StoreID), zap.String("from", s.Phase), zap.String("to", phase))
+	s.Phase, s.PhaseStartTime = phase, m.now()
+}
@@ -0,0 +1,127 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedulers"
+)
+
+var _ = Suite(&testRollingRestartSuite{})
+
+type testRollingRestartSuite struct{}
+
+func (s *testRollingRestartSuite) TestRollingRestart(c *C) {
+	tc, co, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(tc.addLeaderStore(id, 10), IsNil)
+	}
+	m := tc.restarts
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	phases := func() []string {
+		var phases []string
+		for _, store := range m.Get().Stores {
+			phases = append(phases, store.Phase)
+		}
+		return phases
+	}
+	evictorExists := func() bool {
+		co.RLock()
+		defer co.RUnlock()
+		_, ok := co.schedulers[schedulers.EvictLeaderName]
+		return ok
+	}
+
+	for _, ids := range [][]uint64{{}, {1, 1}, {1, 9}} {
+		_, err := tc.StartRollingRestart(&RollingRestartConfig{StoreIDs: ids})
+		c.Assert(err, NotNil)
+	}
+	restart, err := tc.StartRollingRestart(&RollingRestartConfig{StoreIDs: []uint64{1, 2}, PendingPeerTolerance: 1})
+	c.Assert(err, IsNil)
+	c.Assert(restart.LeaderDrainTimeout.Duration, Equals, defaultLeaderDrainTimeout)
+	c.Assert(phases(), DeepEquals, []string{StoreRestartPending, StoreRestartPending})
+	_, err = tc.StartRollingRestart(&RollingRestartConfig{StoreIDs: []uint64{3}})
+	c.Assert(err, Equals, ErrRollingRestartInProgress)
+
+	// The leaders are evicted from store 1 until it has no leader.
+	m.advance(co)
+	c.Assert(phases(), DeepEquals, []string{StoreRestartDraining, StoreRestartPending})
+	c.Assert(evictorExists(), IsTrue)
+	c.Assert(tc.GetStore(1).IsBlocked(), IsTrue)
+	m.advance(co)
+	c.Assert(phases()[0], Equals, StoreRestartDraining)
+	c.Assert(tc.updateLeaderCount(1, 0), IsNil)
+	m.advance(co)
+	c.Assert(phases()[0], Equals, StoreRestartReady)
+	m.advance(co)
+	c.Assert(phases()[0], Equals, StoreRestartReady)
+
+	// Store 1 restarts with more pending peers, and catches up later.
+	store := tc.GetStore(1).Clone(
+		core.SetStoreStartTime(now.Unix()),
+		core.SetPendingPeerCount(5),
+		core.SetLastHeartbeatTS(time.Now()),
+	)
+	c.Assert(tc.putStoreLocked(store), IsNil)
+	m.advance(co)
+	c.Assert(phases()[0], Equals, StoreRestartCatchingUp)
+	m.advance(co)
+	c.Assert(phases()[0], Equals, StoreRestartCatchingUp)
+	c.Assert(tc.putStoreLocked(tc.GetStore(1).Clone(core.SetPendingPeerCount(1))), IsNil)
+	m.advance(co)
+	c.Assert(phases(), DeepEquals, []string{StoreRestartDone, StoreRestartDraining})
+	c.Assert(tc.GetStore(1).IsBlocked(), IsFalse)
+	c.Assert(tc.GetStore(2).IsBlocked(), IsTrue)
+
+	// The rolling restart is resumed after PD restarts.
+	loaded := NewRollingRestartController(tc.storage)
+	c.Assert(loaded.Load(), IsNil)
+	c.Assert(loaded.Get().State, Equals, RollingRestartRunning)
+	c.Assert(loaded.Get().Stores[1].Phase, Equals, StoreRestartDraining)
+	c.Assert(loaded.Get().Stores[1].Evicted, IsTrue)
+
+	// It is paused if the leaders are not drained in time.
+	now = now.Add(defaultLeaderDrainTimeout + time.Second)
+	m.advance(co)
+	c.Assert(m.Get().State, Equals, RollingRestartPaused)
+	c.Assert(m.Get().Message, Not(Equals), "")
+	c.Assert(m.Pause(), NotNil)
+	c.Assert(m.Resume(), IsNil)
+	c.Assert(m.Get().Stores[1].PhaseStartTime.Equal(now), IsTrue)
+	m.advance(co)
+	c.Assert(m.Get().State, Equals, RollingRestartRunning)
+	c.Assert(m.Pause(), IsNil)
+	c.Assert(m.Get().State, Equals, RollingRestartPaused)
+
+	// Aborting stops evicting the leaders from the current store.
+	c.Assert(m.Abort(co), IsNil)
+	c.Assert(m.Get().State, Equals, RollingRestartAborted)
+	c.Assert(tc.GetStore(2).IsBlocked(), IsFalse)
+	c.Assert(evictorExists(), IsFalse)
+	c.Assert(m.Abort(co), NotNil)
+
+	// The store which becomes tombstone is skipped.
+	_, err = tc.StartRollingRestart(&RollingRestartConfig{StoreIDs: []uint64{3}})
+	c.Assert(err, IsNil)
+	c.Assert(tc.putStoreLocked(tc.GetStore(3).Clone(core.SetStoreState(metapb.StoreState_Tombstone))), IsNil)
+	m.advance(co)
+	c.Assert(phases(), DeepEquals, []string{StoreRestartSkipped})
+	c.Assert(m.Get().State, Equals, RollingRestartFinished)
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+)
+
+const (
+	// schedulerBackoffWindow is the sliding window to calculate the success rate.
+	schedulerBackoffWindow = 10 * time.Minute
+	// schedulerBackoffMinSamples is the min number of finished operators in
+	// the window to back off a scheduler.
+	schedulerBackoffMinSamples = 10
+	// maxSchedulerBackoffLevel limits the scheduling interval to be backed off
+	// by at most 1<<maxSchedulerBackoffLevel times.
+	maxSchedulerBackoffLevel = 6
+)
+
+// backoffOperatorKinds are the kinds of the operators created by balance
+// schedulers, whose success rate decides the backoff of the scheduler.
+const backoffOperatorKinds = operator.OpBalance | operator.OpHotRegion
+
+// SchedulerBackoffStatus is the backoff status of a scheduler.
+type SchedulerBackoffStatus struct {
+	// Level means the scheduling interval is backed off by 1<<Level times.
+	Level       int     `json:"level"`
+	SuccessRate float64 `json:"success_rate"`
+	// Samples is the number of finished operators in the window.
+	Samples int `json:"samples"`
+}
+
+type operatorResult struct {
+	finishedAt time.Time
+	success    bool
+}
+
+// schedulerBackoff tracks the success rate of the balance operators created
+// by a scheduler in a sliding window. The scheduling interval is exponentially
+// backed off when the rate drops below a threshold, and restored step by step
+// when the rate recovers.
+type schedulerBackoff struct {
+	sync.RWMutex
+	pending []*operator.Operator
+	results []operatorResult
+	level   int
+	now     func() time.Time
+}
+
+func newSchedulerBackoff() *schedulerBackoff {
+	return &schedulerBackoff{now: time.Now}
+}
+
+// track starts to track the balance operators.
+func (b *schedulerBackoff) track(ops ...*operator.Operator) {
+	b.Lock()
+	defer b.Unlock()
+	for _, op := range ops {
+		if op.Kind()&backoffOperatorKinds != 0 {
+			b.pending = append(b.pending, op)
+		}
+	}
+}
+
+// update collects the finished operators and adjusts the backoff level. The
+// level only grows when there are new timed out operators. It returns the
+// level before updating.
+func (b *schedulerBackoff) update(threshold float64) int {
+	b.Lock()
+	defer b.Unlock()
+	now := b.now()
+	var timedOut bool
+	pending := b.pending[:0]
+	for _, op := range b.pending {
+		switch op.Status() {
+		case operator.SUCCESS:
+			b.results = append(b.results, operatorResult{finishedAt: now, success: true})
+		case operator.TIMEOUT:
+			b.results = append(b.results, operatorResult{finishedAt: now})
+			timedOut = true
+		default:
+			// The operators neither succeeded nor timed out do not count.
+			if !op.IsEnd() && now.Sub(op.GetCreateTime()) < schedulerBackoffWindow {
+				pending = append(pending, op)
+			}
+		}
+	}
+	for i := len(pending); i < len(b.pending); i++ {
+		b.pending[i] = nil
+	}
+	b.pending = pending
+	i := 0
+	for i < len(b.results) && now.Sub(b.results[i].finishedAt) > schedulerBackoffWindow {
+		i++
+	}
+	b.results = b.results[i:]
+
+	old := b.level
+	if threshold <= 0 {
+		b.level = 0
+		return old
+	}
+	status := b.statusLocked()
+	if status.Samples >= schedulerBackoffMinSamples && status.SuccessRate < threshold {
+		if timedOut && b.level < maxSchedulerBackoffLevel {
+			b.level++
+		}
+	} else if b.level > 0 {
+		b.level--
+	}
+	return old
+}
+
+// backoff returns the backed off scheduling interval.
+func (b *schedulerBackoff) backoff(interval time.Duration) time.Duration {
+	b.RLock()
+	defer b.RUnlock()
+	return interval << uint(b.level)
+}
+
+func (b *schedulerBackoff) status() SchedulerBackoffStatus {
+	b.RLock()
+	defer b.RUnlock()
+	return b.statusLocked()
+}
+
+func (b *schedulerBackoff) statusLocked() SchedulerBackoffStatus {
+	status := SchedulerBackoffStatus{Level: b.level, SuccessRate: 1, Samples: len(b.results)}
+	if len(b.results) > 0 {
+		var success int
+		for _, r := range b.results {
+			if r.success {
+				success++
+			}
+		}
+		status.SuccessRate = float64(success) / float64(len(b.results))
+	}
+	return status
+}
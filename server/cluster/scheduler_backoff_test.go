@@ -0,0 +1,120 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+)
+
+var _ = Suite(&testSchedulerBackoffSuite{})
+
+type testSchedulerBackoffSuite struct{}
+
+func newBackoffTestOperators(n int, kind operator.OpKind) []*operator.Operator {
+	ops := make([]*operator.Operator, 0, n)
+	for i := 0; i < n; i++ {
+		op := operator.NewOperator("test", "test", uint64(i), &metapb.RegionEpoch{}, kind|operator.OpLeader,
+			operator.TransferLeader{FromStore: 1, ToStore: 2})
+		op.Start()
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+func finishBackoffTestOperators(c *C, ops []*operator.Operator, timeout bool) {
+	// The leader is transferred to store 2.
+	peers := []*metapb.Peer{{Id: 1, StoreId: 1}, {Id: 2, StoreId: 2}}
+	region := core.NewRegionInfo(&metapb.Region{Id: 1, Peers: peers}, peers[1])
+	for _, op := range ops {
+		if timeout {
+			operator.SetOperatorStatusReachTime(op, operator.STARTED, time.Now().Add(-time.Hour))
+			c.Assert(op.CheckTimeout(), IsTrue)
+		} else {
+			c.Assert(op.Check(region), IsNil)
+			c.Assert(op.CheckSuccess(), IsTrue)
+		}
+	}
+}
+
+func (s *testSchedulerBackoffSuite) TestSchedulerBackoff(c *C) {
+	b := newSchedulerBackoff()
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	interval := time.Second
+
+	// Too few samples do not back off the scheduler.
+	ops := newBackoffTestOperators(schedulerBackoffMinSamples-1, operator.OpBalance)
+	b.track(ops...)
+	finishBackoffTestOperators(c, ops, true)
+	c.Assert(b.update(0.5), Equals, 0)
+	c.Assert(b.backoff(interval), Equals, interval)
+
+	// Timed out operators back off the scheduler exponentially.
+	ops = newBackoffTestOperators(1, operator.OpBalance)
+	b.track(ops...)
+	finishBackoffTestOperators(c, ops, true)
+	b.update(0.5)
+	c.Assert(b.backoff(interval), Equals, 2*interval)
+	ops = newBackoffTestOperators(1, operator.OpHotRegion)
+	b.track(ops...)
+	finishBackoffTestOperators(c, ops, true)
+	b.update(0.5)
+	c.Assert(b.backoff(interval), Equals, 4*interval)
+	c.Assert(b.status(), DeepEquals, SchedulerBackoffStatus{Level: 2, SuccessRate: 0, Samples: 11})
+
+	// The level does not grow without new timed out operators.
+	b.update(0.5)
+	c.Assert(b.status().Level, Equals, 2)
+	// The operators not created by balance schedulers are ignored.
+	ops = newBackoffTestOperators(5, operator.OpAdmin)
+	b.track(ops...)
+	finishBackoffTestOperators(c, ops, true)
+	b.update(0.5)
+	c.Assert(b.status(), DeepEquals, SchedulerBackoffStatus{Level: 2, SuccessRate: 0, Samples: 11})
+
+	// The level is restored step by step when the success rate recovers.
+	ops = newBackoffTestOperators(11, operator.OpBalance)
+	b.track(ops...)
+	// Running operators do not count.
+	b.update(0.5)
+	c.Assert(b.status().Samples, Equals, 11)
+	finishBackoffTestOperators(c, ops, false)
+	c.Assert(b.update(0.5), Equals, 2)
+	c.Assert(b.status(), DeepEquals, SchedulerBackoffStatus{Level: 1, SuccessRate: 0.5, Samples: 22})
+	b.update(0.5)
+	c.Assert(b.backoff(interval), Equals, interval)
+
+	// The level is bounded.
+	for i := 0; i < 2*maxSchedulerBackoffLevel; i++ {
+		ops = newBackoffTestOperators(schedulerBackoffMinSamples, operator.OpBalance)
+		b.track(ops...)
+		finishBackoffTestOperators(c, ops, true)
+		b.update(0.5)
+	}
+	c.Assert(b.status().Level, Equals, maxSchedulerBackoffLevel)
+
+	// The results out of the window are discarded.
+	now = now.Add(schedulerBackoffWindow + time.Second)
+	b.update(0.5)
+	c.Assert(b.status(), DeepEquals, SchedulerBackoffStatus{Level: maxSchedulerBackoffLevel - 1, SuccessRate: 1, Samples: 0})
+
+	// Zero threshold disables the backoff.
+	b.update(0)
+	c.Assert(b.backoff(interval), Equals, interval)
+}
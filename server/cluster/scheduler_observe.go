@@ -0,0 +1,88 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+)
+
+// maxObservedOperators is the max number of the recent operators kept for an
+// observing scheduler.
+const maxObservedOperators = 256
+
+// ObservedOperator is an operator created by an observing scheduler, which is
+// logged instead of being added.
+type ObservedOperator struct {
+	Time     time.Time        `json:"time"`
+	RegionID uint64           `json:"region_id"`
+	Desc     string           `json:"desc"`
+	Kind     string           `json:"kind"`
+	Steps    []string         `json:"steps"`
+	Reason   *operator.Reason `json:"reason,omitempty"`
+}
+
+func newObservedOperator(op *operator.Operator, now time.Time) *ObservedOperator {
+	steps := make([]string, op.Len())
+	for i := range steps {
+		steps[i] = op.Step(i).String()
+	}
+	return &ObservedOperator{
+		Time:     now,
+		RegionID: op.RegionID(),
+		Desc:     op.Desc(),
+		Kind:     op.Kind().String(),
+		Steps:    steps,
+		Reason:   op.Reason(),
+	}
+}
+
+// observedLog is a ring of the recent operators of an observing scheduler.
+type observedLog struct {
+	sync.RWMutex
+	ops  []*ObservedOperator
+	next int
+	now  func() time.Time
+}
+
+func newObservedLog() *observedLog {
+	return &observedLog{now: time.Now}
+}
+
+// record logs the operators, overwriting the oldest ones if the log is full.
+func (l *observedLog) record(ops ...*operator.Operator) {
+	l.Lock()
+	defer l.Unlock()
+	now := l.now()
+	for _, op := range ops {
+		observed := newObservedOperator(op, now)
+		if len(l.ops) < maxObservedOperators {
+			l.ops = append(l.ops, observed)
+			continue
+		}
+		l.ops[l.next] = observed
+		l.next = (l.next + 1) % maxObservedOperators
+	}
+}
+
+// list returns the logged operators, the oldest first.
+func (l *observedLog) list() []*ObservedOperator {
+	l.RLock()
+	defer l.RUnlock()
+	res := make([]*ObservedOperator, 0, len(l.ops))
+	res = append(res, l.ops[l.next:]...)
+	return append(res, l.ops[:l.next]...)
+}
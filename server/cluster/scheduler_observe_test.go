@@ -0,0 +1,97 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/pkg/testutil"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/kv"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"github.com/pingcap/pd/v4/server/schedulers"
+)
+
+var _ = Suite(&testSchedulerObserveSuite{})
+
+type testSchedulerObserveSuite struct{}
+
+func (s *testSchedulerObserveSuite) TestObservedLog(c *C) {
+	l := newObservedLog()
+	for i := uint64(1); i <= maxObservedOperators+10; i++ {
+		op := operator.NewOperator("test", "test", i, &metapb.RegionEpoch{}, operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2})
+		op.SetReason(operator.NewReason(operator.ReasonCountImbalance, 1, 2))
+		l.record(op)
+	}
+	ops := l.list()
+	c.Assert(ops, HasLen, maxObservedOperators)
+	// The oldest ones are overwritten.
+	for i, op := range ops {
+		c.Assert(op.RegionID, Equals, uint64(i+11))
+	}
+	c.Assert(ops[0].Desc, Equals, "test")
+	c.Assert(ops[0].Steps, HasLen, 1)
+	c.Assert(ops[0].Reason.Code, Equals, operator.ReasonCountImbalance)
+}
+
+func (s *testSchedulerObserveSuite) TestObserveScheduler(c *C) {
+	tc, co, cleanup := prepare(nil, nil, func(co *coordinator) { co.run() }, c)
+	defer cleanup()
+
+	c.Assert(tc.addLeaderStore(1, 1), IsNil)
+	c.Assert(tc.addLeaderStore(2, 1), IsNil)
+	c.Assert(tc.addLeaderRegion(1, 1, 2), IsNil)
+	c.Assert(tc.addLeaderRegion(2, 2, 1), IsNil)
+
+	// The observe flag is loaded when the scheduler is added.
+	args := []string{"1"}
+	gls, err := schedule.CreateScheduler(schedulers.GrantLeaderType, co.opController, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(schedulers.GrantLeaderType, args))
+	c.Assert(err, IsNil)
+	tc.opt.AddSchedulerCfg(schedulers.GrantLeaderType, args)
+	c.Assert(tc.opt.SetSchedulerObserved(context.Background(), gls.GetName(), true), IsNil)
+	c.Assert(co.addScheduler(gls, args...), IsNil)
+	detail, err := co.getSchedulerDetail(gls.GetName())
+	c.Assert(err, IsNil)
+	c.Assert(detail.Observing, IsTrue)
+
+	// The operators are logged instead of being added.
+	testutil.WaitUntil(c, func(c *C) bool {
+		ops, err := co.getObservedOperators(gls.GetName())
+		c.Assert(err, IsNil)
+		return len(ops) >= 2
+	})
+	c.Assert(co.opController.GetOperator(2), IsNil)
+	ops, err := co.getObservedOperators(gls.GetName())
+	c.Assert(err, IsNil)
+	c.Assert(ops[0].RegionID, Equals, uint64(2))
+
+	// The operators are added after the flag is off, and the flag is
+	// persisted.
+	c.Assert(co.observeScheduler(gls.GetName(), false), IsNil)
+	waitOperator(c, co, 2)
+	observed, err := tc.opt.IsSchedulerObserved(context.Background(), gls.GetName())
+	c.Assert(err, IsNil)
+	c.Assert(observed, IsFalse)
+	c.Assert(co.observeScheduler(gls.GetName(), true), IsNil)
+	observed, err = tc.opt.IsSchedulerObserved(context.Background(), gls.GetName())
+	c.Assert(err, IsNil)
+	c.Assert(observed, IsTrue)
+
+	c.Assert(co.observeScheduler("no-such-scheduler", true), Equals, ErrSchedulerNotFound)
+	_, err = co.getObservedOperators("no-such-scheduler")
+	c.Assert(err, Equals, ErrSchedulerNotFound)
+}
@@ -0,0 +1,214 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"go.uber.org/zap"
+)
+
+const (
+	schedulingReportDateFormat = "2006-01-02"
+	// SchedulingReportRetentionDays is the number of the days whose scheduling
+	// reports are kept.
+	SchedulingReportRetentionDays = 90
+)
+
+// SchedulingReport is the rollup of the scheduling in a day, in the local
+// time of PD.
+type SchedulingReport struct {
+	Date                         string  `json:"date"`
+	UnderReplicatedRegionMinutes float64 `json:"under-replicated-region-minutes"`
+	ReplicaMoves                 int     `json:"replica-moves"`
+	ReplicaMoveBytes             uint64  `json:"replica-move-bytes"`
+	LeaderTransfers              int     `json:"leader-transfers"`
+	Merges                       int     `json:"merges"`
+	Splits                       int     `json:"splits"`
+}
+
+func (r *SchedulingReport) add(other *SchedulingReport) {
+	r.UnderReplicatedRegionMinutes += other.UnderReplicatedRegionMinutes
+	r.ReplicaMoves += other.ReplicaMoves
+	r.ReplicaMoveBytes += other.ReplicaMoveBytes
+	r.LeaderTransfers += other.LeaderTransfers
+	r.Merges += other.Merges
+	r.Splits += other.Splits
+}
+
+// SchedulingReportSummary is the scheduling reports of the recent days.
+type SchedulingReportSummary struct {
+	// Total is the sum of the reports, whose date is empty.
+	Total   SchedulingReport    `json:"total"`
+	Reports []*SchedulingReport `json:"reports"`
+}
+
+// SchedulingReporter rolls up the scheduling events incrementally into the
+// report of the day. The report of the day is checkpointed on every tick, so
+// that it continues after the leader changes. The events are attributed to the
+// days at the granularity of the tick. It is threadsafe.
+type SchedulingReporter struct {
+	sync.Mutex
+	storage  *core.Storage
+	current  *SchedulingReport
+	lastTick time.Time
+	now      func() time.Time
+}
+
+// NewSchedulingReporter creates a SchedulingReporter instance.
+func NewSchedulingReporter(storage *core.Storage) *SchedulingReporter {
+	now := time.Now()
+	return &SchedulingReporter{
+		storage:  storage,
+		current:  &SchedulingReport{Date: now.Format(schedulingReportDateFormat)},
+		lastTick: now,
+		now:      time.Now,
+	}
+}
+
+// Load loads the checkpoint of today. The time without a leader is not counted.
+func (r *SchedulingReporter) Load() error {
+	r.Lock()
+	defer r.Unlock()
+	now := r.now()
+	report := &SchedulingReport{Date: now.Format(schedulingReportDateFormat)}
+	if _, err := r.storage.LoadSchedulingReport(report.Date, report); err != nil {
+		return err
+	}
+	r.current, r.lastTick = report, now
+	return nil
+}
+
+// ObserveOperator counts the replica moves, the leader transfers and the
+// merges done by the operator finished successfully.
+func (r *SchedulingReporter) ObserveOperator(op *operator.Operator, region *core.RegionInfo) {
+	var moves, transfers, merges int
+	for i := 0; i < op.Len(); i++ {
+		switch s := op.Step(i).(type) {
+		case operator.AddPeer, operator.AddLearner, operator.AddLightPeer, operator.AddLightLearner:
+			moves++
+		case operator.TransferLeader:
+			transfers++
+		case operator.MergeRegion:
+			// Only counts the operator of the target region for a merge.
+			if s.IsPassive {
+				merges++
+			}
+		}
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.current.ReplicaMoves += moves
+	r.current.ReplicaMoveBytes += uint64(moves) * uint64(region.GetApproximateSize()) * (1 << 20)
+	r.current.LeaderTransfers += transfers
+	r.current.Merges += merges
+}
+
+// ObserveSplit counts the new regions generated by a split.
+func (r *SchedulingReporter) ObserveSplit(newRegions int) {
+	r.Lock()
+	defer r.Unlock()
+	r.current.Splits += newRegions
+}
+
+// Tick accumulates the under replicated region minutes since the last tick,
+// starts a new report on the day boundary, and checkpoints the report.
+func (r *SchedulingReporter) Tick(underReplicated int) error {
+	r.Lock()
+	defer r.Unlock()
+	now := r.now()
+	if date := now.Format(schedulingReportDateFormat); date != r.current.Date {
+		y, m, d := r.lastTick.Date()
+		midnight := time.Date(y, m, d+1, 0, 0, 0, 0, r.lastTick.Location())
+		if midnight.After(now) {
+			midnight = now
+		}
+		if midnight.After(r.lastTick) {
+			r.current.UnderReplicatedRegionMinutes += float64(underReplicated) * midnight.Sub(r.lastTick).Minutes()
+			r.lastTick = midnight
+		}
+		if err := r.storage.SaveSchedulingReport(r.current.Date, r.current); err != nil {
+			return err
+		}
+		r.current = &SchedulingReport{Date: date}
+		if err := r.gcLocked(now); err != nil {
+			return err
+		}
+	}
+	if now.After(r.lastTick) {
+		r.current.UnderReplicatedRegionMinutes += float64(underReplicated) * now.Sub(r.lastTick).Minutes()
+		r.lastTick = now
+	}
+	return r.storage.SaveSchedulingReport(r.current.Date, r.current)
+}
+
+// gcLocked removes the reports out of the retention.
+func (r *SchedulingReporter) gcLocked(now time.Time) error {
+	cutoff := now.AddDate(0, 0, -SchedulingReportRetentionDays).Format(schedulingReportDateFormat)
+	var expired []string
+	err := r.storage.LoadSchedulingReports("", func(k, v string) {
+		if k < cutoff {
+			expired = append(expired, k)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for _, date := range expired {
+		if err := r.storage.DeleteSchedulingReport(date); err != nil {
+			return err
+		}
+		log.Info("scheduling report expired", zap.String("date", date))
+	}
+	return nil
+}
+
+// GetSummary returns the reports of the recent days including today, sorted
+// by the date. The days without a report are omitted.
+func (r *SchedulingReporter) GetSummary(days int) (*SchedulingReportSummary, error) {
+	r.Lock()
+	defer r.Unlock()
+	from := r.now().AddDate(0, 0, 1-days).Format(schedulingReportDateFormat)
+	var reports []*SchedulingReport
+	err := r.storage.LoadSchedulingReports(from, func(k, v string) {
+		if k == r.current.Date {
+			return
+		}
+		var report SchedulingReport
+		if err := json.Unmarshal([]byte(v), &report); err != nil {
+			log.Error("failed to unmarshal scheduling report", zap.String("date", k), zap.String("report", v))
+			return
+		}
+		reports = append(reports, &report)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if r.current.Date >= from {
+		current := *r.current
+		reports = append(reports, &current)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Date < reports[j].Date })
+	summary := &SchedulingReportSummary{Reports: reports}
+	for _, report := range reports {
+		summary.Total.add(report)
+	}
+	return summary, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/kv"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+)
+
+var _ = Suite(&testSchedulingReportSuite{})
+
+type testSchedulingReportSuite struct{}
+
+func (s *testSchedulingReportSuite) TestSchedulingReport(c *C) {
+	storage := core.NewStorage(kv.NewMemoryKV())
+	now := time.Date(2020, 5, 4, 0, 0, 0, 0, time.Local)
+	newReporter := func() *SchedulingReporter {
+		r := NewSchedulingReporter(storage)
+		r.now = func() time.Time { return now }
+		c.Assert(r.Load(), IsNil)
+		return r
+	}
+	r := newReporter()
+	region := core.NewRegionInfo(&metapb.Region{Id: 1}, nil, core.SetApproximateSize(96))
+	newOperator := func(steps ...operator.OpStep) *operator.Operator {
+		return operator.NewOperator("test", "test", 1, &metapb.RegionEpoch{}, operator.OpAdmin, steps...)
+	}
+	moveRegion := newOperator(operator.AddLearner{ToStore: 2, PeerID: 2}, operator.PromoteLearner{ToStore: 2, PeerID: 2},
+		operator.TransferLeader{FromStore: 1, ToStore: 2}, operator.RemovePeer{FromStore: 1})
+	mergeRegion := []*operator.Operator{
+		newOperator(operator.MergeRegion{FromRegion: &metapb.Region{Id: 1}, ToRegion: &metapb.Region{Id: 2}}),
+		newOperator(operator.MergeRegion{FromRegion: &metapb.Region{Id: 1}, ToRegion: &metapb.Region{Id: 2}, IsPassive: true}),
+	}
+
+	// A simulated day, in which 2 regions are under replicated from 10:00 to
+	// 12:00, and 1 region is from 23:00 to the next day.
+	for minute := 1; minute <= 24*60; minute++ {
+		now = now.Add(time.Minute)
+		var underReplicated int
+		switch hour := now.Add(-time.Minute).Hour(); {
+		case hour >= 10 && hour < 12:
+			underReplicated = 2
+		case hour >= 23:
+			underReplicated = 1
+		}
+		if minute%60 == 0 {
+			r.ObserveOperator(moveRegion, region)
+		}
+		if minute == 12*60 {
+			// The leader changes, and the events since the last checkpoint
+			// are lost, including the move at 12:00 and the last minute of
+			// the under replicated regions.
+			r = newReporter()
+			for _, op := range mergeRegion {
+				r.ObserveOperator(op, region)
+			}
+			r.ObserveSplit(3)
+		}
+		if minute == 24*60 {
+			// The events are attributed at the granularity of the tick.
+			r.ObserveSplit(1)
+		}
+		c.Assert(r.Tick(underReplicated), IsNil)
+	}
+	day := &SchedulingReport{
+		Date:                         "2020-05-04",
+		UnderReplicatedRegionMinutes: 2*119 + 60,
+		ReplicaMoves:                 23,
+		ReplicaMoveBytes:             23 * 96 << 20,
+		LeaderTransfers:              23,
+		Merges:                       1,
+		Splits:                       4,
+	}
+	summary, err := r.GetSummary(7)
+	c.Assert(err, IsNil)
+	c.Assert(summary.Reports, HasLen, 2)
+	c.Assert(*summary.Reports[0], Equals, *day)
+	c.Assert(*summary.Reports[1], Equals, SchedulingReport{Date: "2020-05-05"})
+
+	// The ticks across the day boundary are split.
+	now = now.Add(time.Minute)
+	c.Assert(r.Tick(2), IsNil)
+	now = now.Add(24*time.Hour - 2*time.Minute)
+	c.Assert(r.Tick(1), IsNil)
+	now = now.Add(2 * time.Minute)
+	c.Assert(r.Tick(3), IsNil)
+	summary, err = r.GetSummary(2)
+	c.Assert(err, IsNil)
+	c.Assert(summary.Reports, HasLen, 2)
+	c.Assert(summary.Reports[0].Date, Equals, "2020-05-05")
+	c.Assert(summary.Reports[0].UnderReplicatedRegionMinutes, Equals, float64(2+1438+3))
+	c.Assert(summary.Reports[1].UnderReplicatedRegionMinutes, Equals, float64(3))
+	summary, err = r.GetSummary(3)
+	c.Assert(err, IsNil)
+	c.Assert(summary.Total.ReplicaMoves, Equals, 23)
+
+	// The reports out of the retention are removed.
+	now = now.AddDate(0, 0, SchedulingReportRetentionDays)
+	c.Assert(r.Tick(0), IsNil)
+	summary, err = r.GetSummary(SchedulingReportRetentionDays + 3)
+	c.Assert(err, IsNil)
+	c.Assert(summary.Reports, HasLen, 2)
+	c.Assert(summary.Reports[0].Date, Equals, "2020-05-06")
+}
@@ -0,0 +1,480 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// SplitRangeDesc is the description of the operators splitting the regions
+// for a split-range job.
+const SplitRangeDesc = "split-range"
+
+// The states of a split-range job.
+const (
+	SplitRangeRunning  = "running"
+	SplitRangeFinished = "finished"
+	SplitRangeFailed   = "failed"
+)
+
+const (
+	// maxSplitRangeKeys is the max number of the split keys of a job.
+	maxSplitRangeKeys = 10000
+	// defaultSplitRangeMaxConcurrent is the number of the regions split at
+	// the same time if it is not specified.
+	defaultSplitRangeMaxConcurrent = 4
+	// splitRangeJobTTL is how long a finished or failed job is kept.
+	splitRangeJobTTL = 24 * time.Hour
+)
+
+// splitRangeTimeout is how long a split-range job can go without any
+// progress before it fails.
+var splitRangeTimeout = 10 * time.Minute
+
+var (
+	// ErrSplitRangeNotFound is error info for no split-range job of the ID.
+	ErrSplitRangeNotFound = errors.New("split range job not found")
+	// ErrSplitRangeTooManyKeys is error info for a split-range job which
+	// splits the regions at too many keys.
+	ErrSplitRangeTooManyKeys = errors.Errorf("split range job has more than %d split keys", maxSplitRangeKeys)
+)
+
+// SplitRangeConfig is the key range to split the regions of, and where to
+// split them, either at the keys, or every stride bytes by the approximate
+// sizes of the regions. The keys are in hex format. The range start and end
+// keys are split at too.
+type SplitRangeConfig struct {
+	StartKey string   `json:"start-key"`
+	EndKey   string   `json:"end-key"`
+	Keys     []string `json:"keys,omitempty"`
+	// Stride is in bytes.
+	Stride        uint64 `json:"stride,omitempty"`
+	MaxConcurrent int    `json:"max-concurrent"`
+}
+
+// SplitRangeJob is the progress of splitting the regions of a key range,
+// which is persisted so that it is resumed after the leader of PD changes.
+type SplitRangeJob struct {
+	ID uint64 `json:"id"`
+	SplitRangeConfig
+	State string `json:"state"`
+	// PendingKeys are the split keys, in hex format, which are not the
+	// boundaries of the regions yet.
+	PendingKeys      []string  `json:"pending-keys"`
+	TotalKeys        int       `json:"total-keys"`
+	FinishedKeys     int       `json:"finished-keys"`
+	StartTime        time.Time `json:"start-time"`
+	LastProgressTime time.Time `json:"last-progress-time"`
+	EndTime          time.Time `json:"end-time"`
+	Error            string    `json:"error,omitempty"`
+}
+
+// Clone returns a deep copy of the job.
+func (j *SplitRangeJob) Clone() *SplitRangeJob {
+	job := *j
+	job.Keys = append(j.Keys[:0:0], j.Keys...)
+	job.PendingKeys = append(j.PendingKeys[:0:0], j.PendingKeys...)
+	return &job
+}
+
+// SplitRangeController manages the split-range jobs. It is threadsafe.
+type SplitRangeController struct {
+	sync.RWMutex
+	storage *core.Storage
+	jobs    map[uint64]*SplitRangeJob
+	now     func() time.Time
+}
+
+// NewSplitRangeController creates a SplitRangeController instance.
+func NewSplitRangeController(storage *core.Storage) *SplitRangeController {
+	return &SplitRangeController{
+		storage: storage,
+		jobs:    make(map[uint64]*SplitRangeJob),
+		now:     time.Now,
+	}
+}
+
+// Load loads the jobs from storage. The running jobs wait for the progress
+// since they are loaded rather than since they were persisted.
+func (m *SplitRangeController) Load() error {
+	m.Lock()
+	defer m.Unlock()
+	jobs := make(map[uint64]*SplitRangeJob)
+	var errs []error
+	err := m.storage.LoadSplitRangeJobs(func(k, v string) {
+		id, err := strconv.ParseUint(k, 10, 64)
+		if err != nil {
+			errs = append(errs, errors.WithStack(err))
+			return
+		}
+		job := &SplitRangeJob{}
+		if err := json.Unmarshal([]byte(v), job); err != nil {
+			errs = append(errs, errors.WithStack(err))
+			return
+		}
+		if job.State == SplitRangeRunning {
+			job.LastProgressTime = m.now()
+		}
+		jobs[id] = job
+	})
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	m.jobs = jobs
+	return nil
+}
+
+// Get returns the job of the ID, or nil if there is none.
+func (m *SplitRangeController) Get(id uint64) *SplitRangeJob {
+	m.RLock()
+	defer m.RUnlock()
+	if job, ok := m.jobs[id]; ok {
+		return job.Clone()
+	}
+	return nil
+}
+
+// Submit persists and starts a job to split the regions at the keys. If a
+// running job has the same config, it is returned instead, so that a retried
+// submission does not start the job twice. The config should be normalized
+// by the caller.
+func (m *SplitRangeController) Submit(c *RaftCluster, cfg *SplitRangeConfig) (*SplitRangeJob, error) {
+	m.Lock()
+	defer m.Unlock()
+	for _, job := range m.jobs {
+		if job.State == SplitRangeRunning && reflect.DeepEqual(job.SplitRangeConfig, *cfg) {
+			return job.Clone(), nil
+		}
+	}
+	keys, err := c.getSplitRangeKeys(cfg)
+	if err != nil {
+		return nil, err
+	}
+	id, err := c.AllocID()
+	if err != nil {
+		return nil, err
+	}
+	now := m.now()
+	job := &SplitRangeJob{
+		ID:               id,
+		SplitRangeConfig: *cfg,
+		State:            SplitRangeRunning,
+		TotalKeys:        len(keys),
+		StartTime:        now,
+		LastProgressTime: now,
+	}
+	for _, key := range keys {
+		job.PendingKeys = append(job.PendingKeys, hex.EncodeToString(key))
+	}
+	if len(keys) == 0 {
+		job.State, job.EndTime = SplitRangeFinished, now
+	}
+	if err := m.storage.SaveSplitRangeJob(id, job); err != nil {
+		return nil, err
+	}
+	m.jobs[id] = job
+	log.Info("split range job started", zap.Uint64("job-id", id), zap.String("start-key", cfg.StartKey),
+		zap.String("end-key", cfg.EndKey), zap.Int("keys", len(keys)), zap.Int("max-concurrent", cfg.MaxConcurrent))
+	return job.Clone(), nil
+}
+
+// advance checks the pending keys of the running jobs, and splits the regions
+// at them while there are free slots. The jobs ended long ago are removed. It
+// is called periodically.
+func (m *SplitRangeController) advance(co *coordinator) {
+	m.Lock()
+	defer m.Unlock()
+	now := m.now()
+	for id, job := range m.jobs {
+		if job.State != SplitRangeRunning {
+			if now.Sub(job.EndTime) > splitRangeJobTTL {
+				if err := m.storage.DeleteSplitRangeJob(id); err != nil {
+					log.Error("failed to remove split range job", zap.Uint64("job-id", id), zap.Error(err))
+					continue
+				}
+				delete(m.jobs, id)
+			}
+			continue
+		}
+		job = job.Clone()
+		if !m.advanceJob(co, job, now) {
+			continue
+		}
+		if err := m.storage.SaveSplitRangeJob(id, job); err != nil {
+			log.Error("failed to persist split range job", zap.Uint64("job-id", id), zap.Error(err))
+			continue
+		}
+		m.jobs[id] = job
+	}
+}
+
+// advanceJob moves the job forward, and returns true if it is changed.
+func (m *SplitRangeController) advanceJob(co *coordinator, job *SplitRangeJob, now time.Time) bool {
+	c := co.cluster
+	var changed bool
+	pending := job.PendingKeys[:0]
+	// The pending keys are grouped by the regions containing them, in order.
+	var regions []*core.RegionInfo
+	var regionKeys [][][]byte
+	for _, k := range job.PendingKeys {
+		key, _ := hex.DecodeString(k)
+		region := c.GetRegionInfoByKey(key)
+		if region != nil && bytes.Equal(region.GetStartKey(), key) {
+			changed = true
+			continue
+		}
+		pending = append(pending, k)
+		if region == nil {
+			continue
+		}
+		if n := len(regions); n > 0 && regions[n-1].GetID() == region.GetID() {
+			regionKeys[n-1] = append(regionKeys[n-1], key)
+		} else {
+			regions = append(regions, region)
+			regionKeys = append(regionKeys, [][]byte{key})
+		}
+	}
+	job.PendingKeys = pending
+	job.FinishedKeys = job.TotalKeys - len(pending)
+	if changed {
+		job.LastProgressTime = now
+	}
+
+	switch {
+	case len(pending) == 0:
+		job.State, job.EndTime = SplitRangeFinished, now
+		log.Info("split range job finished", zap.Uint64("job-id", job.ID), zap.Int("keys", job.TotalKeys))
+		return true
+	case now.Sub(job.LastProgressTime) > splitRangeTimeout:
+		job.State, job.EndTime = SplitRangeFailed, now
+		job.Error = "no region is split at the pending keys in " + splitRangeTimeout.String()
+		log.Error("split range job failed", zap.Uint64("job-id", job.ID), zap.String("error", job.Error),
+			zap.Int("pending-keys", len(pending)), zap.Strings("first-pending-keys", pending[:minInt(len(pending), 10)]))
+		return true
+	}
+
+	running := 0
+	for _, region := range regions {
+		if op := co.opController.GetOperator(region.GetID()); op != nil && op.Desc() == SplitRangeDesc {
+			running++
+		}
+	}
+	for i, region := range regions {
+		if running >= job.MaxConcurrent {
+			break
+		}
+		if op := co.opController.GetOperator(region.GetID()); op != nil && op.Desc() == SplitRangeDesc {
+			continue
+		}
+		op := operator.CreateSplitRegionOperator(SplitRangeDesc, region, operator.OpAdmin, pdpb.CheckPolicy_USEKEY, regionKeys[i])
+		op.SetCreator(operator.CreatorAdmin)
+		if co.opController.AddOperator(op) {
+			running++
+		}
+	}
+	return changed
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ValidateSplitRange checks and normalizes the config of a split-range job.
+func (c *RaftCluster) ValidateSplitRange(cfg *SplitRangeConfig) error {
+	startKey, err := hex.DecodeString(cfg.StartKey)
+	if err != nil {
+		return errors.New("start-key should be in hex format")
+	}
+	endKey, err := hex.DecodeString(cfg.EndKey)
+	if err != nil {
+		return errors.New("end-key should be in hex format")
+	}
+	if len(endKey) > 0 && bytes.Compare(endKey, startKey) <= 0 {
+		return errors.New("end-key should be greater than start-key")
+	}
+	if (len(cfg.Keys) > 0) == (cfg.Stride > 0) {
+		return errors.New("either keys or stride should be specified")
+	}
+	if len(cfg.Keys) > maxSplitRangeKeys {
+		return ErrSplitRangeTooManyKeys
+	}
+	if cfg.MaxConcurrent < 0 {
+		return errors.New("max-concurrent should not be negative")
+	}
+	if cfg.MaxConcurrent == 0 {
+		cfg.MaxConcurrent = defaultSplitRangeMaxConcurrent
+	}
+	keys := make([][]byte, 0, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		key, err := hex.DecodeString(k)
+		if err != nil {
+			return errors.Errorf("key %s should be in hex format", k)
+		}
+		if bytes.Compare(key, startKey) <= 0 || (len(endKey) > 0 && bytes.Compare(key, endKey) >= 0) {
+			return errors.Errorf("key %s should be in the range", k)
+		}
+		keys = append(keys, key)
+	}
+	cfg.StartKey, cfg.EndKey = hex.EncodeToString(startKey), hex.EncodeToString(endKey)
+	if len(keys) > 0 {
+		cfg.Keys = cfg.Keys[:0]
+		for _, key := range sortUniqueKeys(keys) {
+			cfg.Keys = append(cfg.Keys, hex.EncodeToString(key))
+		}
+	}
+	return nil
+}
+
+// SubmitSplitRange validates the config and submits a split-range job.
+func (c *RaftCluster) SubmitSplitRange(cfg *SplitRangeConfig) (*SplitRangeJob, error) {
+	if err := c.ValidateSplitRange(cfg); err != nil {
+		return nil, err
+	}
+	return c.splitRange.Submit(c, cfg)
+}
+
+// GetSplitRangeJob returns the split-range job of the ID, or nil if there is
+// none.
+func (c *RaftCluster) GetSplitRangeJob(id uint64) *SplitRangeJob {
+	return c.splitRange.Get(id)
+}
+
+// getSplitRangeKeys returns the keys to split the regions at for the config,
+// which are the range start and end keys, and the keys in the config, or the
+// keys every stride bytes of the regions intersecting the range. The keys are
+// sorted, and the ones already at the boundaries of the regions are skipped.
+func (c *RaftCluster) getSplitRangeKeys(cfg *SplitRangeConfig) ([][]byte, error) {
+	startKey, _ := hex.DecodeString(cfg.StartKey)
+	endKey, _ := hex.DecodeString(cfg.EndKey)
+	var keys [][]byte
+	for _, k := range cfg.Keys {
+		key, _ := hex.DecodeString(k)
+		keys = append(keys, key)
+	}
+	if cfg.Stride > 0 {
+		keys = getStrideSplitKeys(c.ScanRegions(startKey, endKey, -1), startKey, endKey, cfg.Stride)
+	}
+	if len(startKey) > 0 {
+		keys = append(keys, startKey)
+	}
+	if len(endKey) > 0 {
+		keys = append(keys, endKey)
+	}
+	keys = sortUniqueKeys(keys)
+	res := keys[:0]
+	for _, key := range keys {
+		if region := c.GetRegionInfoByKey(key); region != nil && bytes.Equal(region.GetStartKey(), key) {
+			continue
+		}
+		res = append(res, key)
+	}
+	if len(res) > maxSplitRangeKeys {
+		return nil, ErrSplitRangeTooManyKeys
+	}
+	return res, nil
+}
+
+// getStrideSplitKeys returns the keys splitting each region into the parts of
+// the stride bytes by its approximate size, which are inside the range. The
+// keys are interpolated between the start and end keys of the region, as if
+// the data is evenly distributed.
+func getStrideSplitKeys(regions []*core.RegionInfo, startKey, endKey []byte, stride uint64) [][]byte {
+	var keys [][]byte
+	for _, region := range regions {
+		size := uint64(region.GetApproximateSize()) << 20
+		parts := (size + stride - 1) / stride
+		if parts < 2 {
+			continue
+		}
+		if parts > maxSplitRangeKeys {
+			parts = maxSplitRangeKeys
+		}
+		for _, key := range interpolateKeys(region.GetStartKey(), region.GetEndKey(), int(parts)) {
+			if bytes.Compare(key, startKey) > 0 && (len(endKey) == 0 || bytes.Compare(key, endKey) < 0) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// interpolateKeys returns the keys dividing [startKey, endKey) into the parts
+// evenly. The end key is the end of the key space if it is empty. The keys
+// are compared as the numbers of the same length, which is a little longer
+// than the longer one of the start and end keys for the precision, and the
+// trailing zeros of the keys are trimmed.
+func interpolateKeys(startKey, endKey []byte, parts int) [][]byte {
+	n := len(startKey)
+	if len(endKey) > n {
+		n = len(endKey)
+	}
+	n += 2
+	pad := func(key []byte) *big.Int {
+		b := make([]byte, n)
+		copy(b, key)
+		return new(big.Int).SetBytes(b)
+	}
+	start, end := pad(startKey), pad(endKey)
+	if len(endKey) == 0 {
+		end = new(big.Int).Lsh(big.NewInt(1), uint(8*n))
+	}
+	diff := new(big.Int).Sub(end, start)
+	var keys [][]byte
+	for i := 1; i < parts; i++ {
+		v := new(big.Int).Mul(diff, big.NewInt(int64(i)))
+		v.Div(v, big.NewInt(int64(parts)))
+		v.Add(v, start)
+		b := v.Bytes()
+		key := make([]byte, n)
+		copy(key[n-len(b):], b)
+		key = bytes.TrimRight(key, "\x00")
+		if bytes.Compare(key, startKey) <= 0 || (len(keys) > 0 && bytes.Equal(keys[len(keys)-1], key)) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func sortUniqueKeys(keys [][]byte) [][]byte {
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	res := keys[:0]
+	for _, key := range keys {
+		if len(res) == 0 || !bytes.Equal(res[len(res)-1], key) {
+			res = append(res, key)
+		}
+	}
+	return res
+}
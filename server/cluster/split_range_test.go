@@ -0,0 +1,156 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+var _ = Suite(&testSplitRangeSuite{})
+
+type testSplitRangeSuite struct{}
+
+func (s *testSplitRangeSuite) newRegion(id uint64, start, end string, version uint64, size int64) *core.RegionInfo {
+	peer := &metapb.Peer{Id: id + 100, StoreId: 1}
+	meta := &metapb.Region{
+		Id:          id,
+		Peers:       []*metapb.Peer{peer},
+		StartKey:    []byte(start),
+		EndKey:      []byte(end),
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: version},
+	}
+	return core.NewRegionInfo(meta, peer, core.SetApproximateSize(size))
+}
+
+func (s *testSplitRangeSuite) TestStrideSplitKeys(c *C) {
+	toStrings := func(keys [][]byte) []string {
+		res := make([]string, 0, len(keys))
+		for _, key := range keys {
+			res = append(res, string(key))
+		}
+		return res
+	}
+	c.Assert(toStrings(interpolateKeys([]byte("a"), []byte("c"), 4)), DeepEquals, []string{"a\x80", "b", "b\x80"})
+	c.Assert(toStrings(interpolateKeys(nil, nil, 4)), DeepEquals, []string{"@", "\x80", "\xc0"})
+	c.Assert(toStrings(interpolateKeys([]byte("c"), nil, 2)), DeepEquals, []string{"\xb1\x80"})
+	// The keys are 2 bytes longer than the boundaries for the precision.
+	c.Assert(toStrings(interpolateKeys([]byte("a"), []byte("a\x01"), 4)), DeepEquals, []string{"a\x00@", "a\x00\x80", "a\x00\xc0"})
+
+	regions := []*core.RegionInfo{
+		s.newRegion(1, "", "a", 1, 1),
+		s.newRegion(2, "a", "c", 1, 64),
+		s.newRegion(3, "c", "", 1, 30),
+	}
+	// The 64MB region is split into 4 parts, the 30MB one into 2, and the
+	// 1MB one is kept.
+	c.Assert(toStrings(getStrideSplitKeys(regions, nil, nil, 16<<20)), DeepEquals, []string{"a\x80", "b", "b\x80", "\xb1\x80"})
+	c.Assert(toStrings(getStrideSplitKeys(regions, []byte("a\x80"), []byte("c"), 16<<20)), DeepEquals, []string{"b", "b\x80"})
+	c.Assert(getStrideSplitKeys(regions, nil, nil, 64<<20), HasLen, 0)
+}
+
+func (s *testSplitRangeSuite) TestSplitRange(c *C) {
+	tc, co, cleanup := prepare(nil, nil, nil, c)
+	defer cleanup()
+	c.Assert(tc.addRegionStore(1, 3), IsNil)
+	for _, region := range []*core.RegionInfo{
+		s.newRegion(1, "", "a", 1, 1),
+		s.newRegion(2, "a", "c", 1, 64),
+		s.newRegion(3, "c", "", 1, 30),
+	} {
+		c.Assert(tc.putRegion(region), IsNil)
+	}
+	now := time.Now()
+	m := tc.splitRange
+	m.now = func() time.Time { return now }
+
+	for _, cfg := range []*SplitRangeConfig{
+		{StartKey: "6", Stride: 1},
+		{StartKey: "62", EndKey: "61", Stride: 1},
+		{StartKey: "61"},
+		{StartKey: "61", Keys: []string{"62"}, Stride: 1},
+		{StartKey: "61", Keys: []string{"60"}},
+		{StartKey: "61", Stride: 1, MaxConcurrent: -1},
+	} {
+		c.Assert(tc.ValidateSplitRange(cfg), NotNil)
+	}
+
+	// The range start key is a boundary already, so it is skipped.
+	job, err := tc.SubmitSplitRange(&SplitRangeConfig{StartKey: "61", Stride: 16 << 20, MaxConcurrent: 1})
+	c.Assert(err, IsNil)
+	c.Assert(job.State, Equals, SplitRangeRunning)
+	c.Assert(job.PendingKeys, DeepEquals, []string{"6180", "62", "6280", "b180"})
+	// The same job is returned if it is submitted again.
+	again, err := tc.SubmitSplitRange(&SplitRangeConfig{StartKey: "61", Stride: 16 << 20, MaxConcurrent: 1})
+	c.Assert(err, IsNil)
+	c.Assert(again.ID, Equals, job.ID)
+	other, err := tc.SubmitSplitRange(&SplitRangeConfig{StartKey: "61", EndKey: "62", Keys: []string{"6180", "6180"}})
+	c.Assert(err, IsNil)
+	c.Assert(other.ID, Not(Equals), job.ID)
+	c.Assert(other.Keys, DeepEquals, []string{"6180"})
+	c.Assert(other.MaxConcurrent, Equals, defaultSplitRangeMaxConcurrent)
+	c.Assert(other.PendingKeys, DeepEquals, []string{"6180", "62"})
+
+	// Only 1 region of the job is split at a time.
+	m.advance(co)
+	op := co.opController.GetOperator(2)
+	c.Assert(op, NotNil)
+	c.Assert(op.Desc(), Equals, SplitRangeDesc)
+	c.Assert(co.opController.GetOperator(3), IsNil)
+	m.advance(co)
+	c.Assert(co.opController.GetOperator(3), IsNil)
+
+	// Region 2 is split, so region 3 is split next.
+	now = now.Add(time.Minute)
+	for _, region := range []*core.RegionInfo{
+		s.newRegion(4, "a", "a\x80", 2, 16),
+		s.newRegion(5, "a\x80", "b", 2, 16),
+		s.newRegion(6, "b", "b\x80", 2, 16),
+		s.newRegion(2, "b\x80", "c", 2, 16),
+	} {
+		c.Assert(tc.putRegion(region), IsNil)
+	}
+	m.advance(co)
+	job = m.Get(job.ID)
+	c.Assert(job.PendingKeys, DeepEquals, []string{"b180"})
+	c.Assert(job.FinishedKeys, Equals, 3)
+	c.Assert(job.LastProgressTime.Equal(now), IsTrue)
+	c.Assert(co.opController.GetOperator(3), NotNil)
+	c.Assert(m.Get(other.ID).State, Equals, SplitRangeFinished)
+
+	// The progress is resumed after the leader changes.
+	m = NewSplitRangeController(tc.storage)
+	m.now = func() time.Time { return now }
+	c.Assert(m.Load(), IsNil)
+	c.Assert(m.Get(job.ID).PendingKeys, DeepEquals, []string{"b180"})
+	c.Assert(m.Get(other.ID).State, Equals, SplitRangeFinished)
+
+	// The job fails if no region is split for a long time.
+	now = now.Add(splitRangeTimeout + time.Second)
+	m.advance(co)
+	job = m.Get(job.ID)
+	c.Assert(job.State, Equals, SplitRangeFailed)
+	c.Assert(job.Error, Not(Equals), "")
+
+	// The ended jobs are removed after a while.
+	now = now.Add(splitRangeJobTTL + time.Second)
+	m.advance(co)
+	c.Assert(m.Get(job.ID), IsNil)
+	c.Assert(m.Get(other.ID), IsNil)
+	c.Assert(m.Load(), IsNil)
+	c.Assert(m.jobs, HasLen, 0)
+}
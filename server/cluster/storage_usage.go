@@ -0,0 +1,76 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/juju/ratelimit"
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// storageUsageCacheTTL is how long the result of a scan is reused.
+	storageUsageCacheTTL = 5 * time.Minute
+	// storageUsageScanRate is the bytes loaded from the storage per second by
+	// a scan.
+	storageUsageScanRate = 4 << 20
+)
+
+// storageUsageCache scans the space used by the keys of PD, and keeps the
+// result for a while. Only one scan runs at a time, and the requests during
+// it share its result. It is threadsafe.
+type storageUsageCache struct {
+	sync.Mutex
+	storage *core.Storage
+	limit   *ratelimit.Bucket
+	usage   *core.StorageUsage
+	now     func() time.Time
+}
+
+func newStorageUsageCache(storage *core.Storage) *storageUsageCache {
+	return &storageUsageCache{
+		storage: storage,
+		limit:   ratelimit.NewBucketWithRate(storageUsageScanRate, storageUsageScanRate),
+		now:     time.Now,
+	}
+}
+
+func (c *storageUsageCache) get(ctx context.Context, refresh bool) (*core.StorageUsage, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.usage != nil && !refresh && c.now().Sub(c.usage.ScanTime) < storageUsageCacheTTL {
+		return c.usage, nil
+	}
+	usage, err := c.storage.ScanUsage(ctx, c.limit)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("storage usage scanned", zap.Int("keys", usage.Keys), zap.Int64("bytes", usage.Bytes), zap.String("duration", usage.ScanDuration))
+	c.usage = usage
+	return usage, nil
+}
+
+// GetStorageUsage returns the space used by the keys of PD in the storage. The
+// result scanned within a few minutes is returned unless refresh is true.
+func (c *RaftCluster) GetStorageUsage(ctx context.Context, refresh bool) (*core.StorageUsage, error) {
+	c.RLock()
+	cache := c.storageUsage
+	c.RUnlock()
+	return cache.get(ctx, refresh)
+}
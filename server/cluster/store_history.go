@@ -0,0 +1,235 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// StoreHistoryRetention is how long the records of the removed stores are
+	// kept.
+	StoreHistoryRetention = 365 * 24 * time.Hour
+	// maxRemovedStoreRecords is the max number of the records of the removed
+	// stores kept, and the ones removed earliest are dropped beyond it.
+	maxRemovedStoreRecords = 10000
+)
+
+// StoreStateRemoved is the state of a store whose tombstone record is removed.
+const StoreStateRemoved = "Removed"
+
+// StoreLifecycleRecord is the compact record of the lifecycle of a store, which
+// is kept after the store is removed for audit. The times of the transitions
+// are the last ones, and nil if the transitions never happen or happen before
+// the record is kept.
+type StoreLifecycleRecord struct {
+	StoreID       uint64               `json:"store-id"`
+	Address       string               `json:"address"`
+	Labels        []*metapb.StoreLabel `json:"labels,omitempty"`
+	State         string               `json:"state"`
+	JoinTime      *time.Time           `json:"join-time,omitempty"`
+	OfflineTime   *time.Time           `json:"offline-time,omitempty"`
+	TombstoneTime *time.Time           `json:"tombstone-time,omitempty"`
+	RemoveTime    *time.Time           `json:"remove-time,omitempty"`
+	// PeakUsedSize is the peak used size in bytes reported by the heartbeats,
+	// and PeakRegionCount is the peak number of the regions on the store.
+	PeakUsedSize    uint64 `json:"peak-used-size"`
+	PeakRegionCount int    `json:"peak-region-count"`
+	// Reason is supplied by the operator when the store is deleted.
+	Reason string `json:"reason,omitempty"`
+}
+
+// StoreHistory keeps the lifecycle records of the stores. The records are
+// persisted on the state transitions, and the peaks are persisted along with
+// the stores by the heartbeats. It is threadsafe.
+type StoreHistory struct {
+	sync.RWMutex
+	storage *core.Storage
+	records map[uint64]*StoreLifecycleRecord
+	// dirty is the stores whose peaks are not persisted yet.
+	dirty map[uint64]struct{}
+	now   func() time.Time
+}
+
+// NewStoreHistory creates a StoreHistory instance.
+func NewStoreHistory(storage *core.Storage) *StoreHistory {
+	return &StoreHistory{
+		storage: storage,
+		records: make(map[uint64]*StoreLifecycleRecord),
+		dirty:   make(map[uint64]struct{}),
+		now:     time.Now,
+	}
+}
+
+// Load loads the records from storage.
+func (h *StoreHistory) Load() error {
+	h.Lock()
+	defer h.Unlock()
+	h.records = make(map[uint64]*StoreLifecycleRecord)
+	h.dirty = make(map[uint64]struct{})
+	err := h.storage.LoadStoreHistories(func(k, v string) {
+		record := &StoreLifecycleRecord{}
+		if err := json.Unmarshal([]byte(v), record); err != nil {
+			log.Error("failed to unmarshal store lifecycle record", zap.String("store-id", k), zap.String("record", v))
+			return
+		}
+		h.records[record.StoreID] = record
+	})
+	if err != nil {
+		return err
+	}
+	log.Info("load store lifecycle records", zap.Int("count", len(h.records)))
+	return h.gcLocked(h.now())
+}
+
+// getOrCreateLocked returns the record of the store. A record is created
+// without the join time for the store joined before the records are kept.
+func (h *StoreHistory) getOrCreateLocked(storeID uint64) *StoreLifecycleRecord {
+	record, ok := h.records[storeID]
+	if !ok {
+		record = &StoreLifecycleRecord{StoreID: storeID}
+		h.records[storeID] = record
+	}
+	return record
+}
+
+// onPut records the store put, which joins the cluster if origin is nil, or
+// may change its state.
+func (h *StoreHistory) onPut(origin, store *core.StoreInfo) error {
+	h.Lock()
+	defer h.Unlock()
+	now := h.now()
+	record := h.getOrCreateLocked(store.GetID())
+	if origin == nil && record.JoinTime == nil {
+		record.JoinTime = &now
+	}
+	record.Address = store.GetAddress()
+	record.Labels = store.GetLabels()
+	state := store.GetState().String()
+	if state != record.State {
+		switch store.GetState() {
+		case metapb.StoreState_Offline:
+			record.OfflineTime = &now
+		case metapb.StoreState_Tombstone:
+			record.TombstoneTime = &now
+		}
+		record.State = state
+	}
+	return h.saveLocked(record)
+}
+
+// onRemove records that the tombstone record of the store is removed.
+func (h *StoreHistory) onRemove(store *core.StoreInfo) error {
+	h.Lock()
+	defer h.Unlock()
+	now := h.now()
+	record := h.getOrCreateLocked(store.GetID())
+	record.State = StoreStateRemoved
+	record.RemoveTime = &now
+	if err := h.saveLocked(record); err != nil {
+		return err
+	}
+	return h.gcLocked(now)
+}
+
+// observe updates the peaks of the store by its heartbeat.
+func (h *StoreHistory) observe(store *core.StoreInfo, regionCount int) {
+	h.Lock()
+	defer h.Unlock()
+	record := h.getOrCreateLocked(store.GetID())
+	if used := store.GetUsedSize(); used > record.PeakUsedSize {
+		record.PeakUsedSize = used
+		h.dirty[record.StoreID] = struct{}{}
+	}
+	if regionCount > record.PeakRegionCount {
+		record.PeakRegionCount = regionCount
+		h.dirty[record.StoreID] = struct{}{}
+	}
+}
+
+// flush persists the peaks of the store if they are changed.
+func (h *StoreHistory) flush(storeID uint64) error {
+	h.Lock()
+	defer h.Unlock()
+	if _, ok := h.dirty[storeID]; !ok {
+		return nil
+	}
+	return h.saveLocked(h.records[storeID])
+}
+
+// SetReason sets the reason why the store is deleted.
+func (h *StoreHistory) SetReason(storeID uint64, reason string) error {
+	h.Lock()
+	defer h.Unlock()
+	record := h.getOrCreateLocked(storeID)
+	record.Reason = reason
+	return h.saveLocked(record)
+}
+
+func (h *StoreHistory) saveLocked(record *StoreLifecycleRecord) error {
+	if err := h.storage.SaveStoreHistory(record.StoreID, record); err != nil {
+		return err
+	}
+	delete(h.dirty, record.StoreID)
+	return nil
+}
+
+// gcLocked removes the records of the stores removed out of the retention or
+// beyond the max number.
+func (h *StoreHistory) gcLocked(now time.Time) error {
+	var removed []*StoreLifecycleRecord
+	for _, record := range h.records {
+		if record.RemoveTime != nil {
+			removed = append(removed, record)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].RemoveTime.Before(*removed[j].RemoveTime) })
+	cutoff := now.Add(-StoreHistoryRetention)
+	for i, record := range removed {
+		if len(removed)-i <= maxRemovedStoreRecords && !record.RemoveTime.Before(cutoff) {
+			break
+		}
+		if err := h.storage.DeleteStoreHistory(record.StoreID); err != nil {
+			return err
+		}
+		delete(h.records, record.StoreID)
+		delete(h.dirty, record.StoreID)
+	}
+	return nil
+}
+
+// GetRecords returns the copies of the records in the order of the store ID,
+// including the ones of the removed stores if includeRemoved is true.
+func (h *StoreHistory) GetRecords(includeRemoved bool) []*StoreLifecycleRecord {
+	h.RLock()
+	defer h.RUnlock()
+	records := make([]*StoreLifecycleRecord, 0, len(h.records))
+	for _, record := range h.records {
+		if record.State == StoreStateRemoved && !includeRemoved {
+			continue
+		}
+		r := *record
+		records = append(records, &r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].StoreID < records[j].StoreID })
+	return records
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/pkg/mock/mockhbstream"
+	"github.com/pingcap/pd/v4/pkg/mock/mockid"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/kv"
+)
+
+var _ = Suite(&testStoreHistorySuite{})
+
+type testStoreHistorySuite struct{}
+
+func (s *testStoreHistorySuite) TestLifecycle(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	storage := core.NewStorage(kv.NewMemoryKV())
+	cluster := newTestRaftCluster(mockid.NewIDAllocator(), opt, storage, core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, mockhbstream.NewHeartbeatStreams(cluster.getClusterID(), true))
+	now := time.Now()
+	cluster.storeHistory.now = func() time.Time { return now }
+
+	c.Assert(cluster.PutStore(&metapb.Store{Id: 1, Address: "mock://tikv-1", Version: "2.0.0"}, false), IsNil)
+	joinTime := now
+	// The peak is kept when the used size drops.
+	for _, used := range []uint64{40, 60, 30} {
+		c.Assert(cluster.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: 1, Capacity: 100, Available: 100 - used, UsedSize: used}), IsNil)
+	}
+	records := cluster.GetStoreHistory().GetRecords(false)
+	c.Assert(records, HasLen, 1)
+	c.Assert(records[0].State, Equals, metapb.StoreState_Up.String())
+	c.Assert(records[0].PeakUsedSize, Equals, uint64(60))
+
+	now = now.Add(time.Hour)
+	offlineTime := now
+	c.Assert(cluster.RemoveStore(1), IsNil)
+	c.Assert(cluster.GetStoreHistory().SetReason(1, "hardware retired"), IsNil)
+	now = now.Add(time.Hour)
+	tombstoneTime := now
+	c.Assert(cluster.BuryStore(1, false), IsNil)
+	now = now.Add(time.Hour)
+	removeTime := now
+	c.Assert(cluster.RemoveTombStoneRecords(false), IsNil)
+	c.Assert(cluster.GetStore(1), IsNil)
+
+	check := func(history *StoreHistory) {
+		c.Assert(history.GetRecords(false), HasLen, 0)
+		records := history.GetRecords(true)
+		c.Assert(records, HasLen, 1)
+		record := records[0]
+		c.Assert(record.StoreID, Equals, uint64(1))
+		c.Assert(record.Address, Equals, "mock://tikv-1")
+		c.Assert(record.State, Equals, StoreStateRemoved)
+		c.Assert(record.JoinTime.Equal(joinTime), IsTrue)
+		c.Assert(record.OfflineTime.Equal(offlineTime), IsTrue)
+		c.Assert(record.TombstoneTime.Equal(tombstoneTime), IsTrue)
+		c.Assert(record.RemoveTime.Equal(removeTime), IsTrue)
+		c.Assert(record.PeakUsedSize, Equals, uint64(60))
+		c.Assert(record.Reason, Equals, "hardware retired")
+	}
+	check(cluster.GetStoreHistory())
+
+	// The record survives the restart, until it is out of the retention.
+	history := NewStoreHistory(storage)
+	c.Assert(history.Load(), IsNil)
+	check(history)
+	history.now = func() time.Time { return removeTime.Add(StoreHistoryRetention + time.Second) }
+	c.Assert(history.Load(), IsNil)
+	c.Assert(history.GetRecords(true), HasLen, 0)
+	var keys []string
+	c.Assert(storage.LoadStoreHistories(func(k, v string) { keys = append(keys, k) }), IsNil)
+	c.Assert(keys, HasLen, 0)
+}
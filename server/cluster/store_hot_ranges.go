@@ -0,0 +1,108 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/statistics"
+)
+
+// The rates by which the hot ranges are sorted.
+const (
+	HotRangeByByte = "byte"
+	HotRangeByKey  = "key"
+)
+
+// StoreHotRange is a contiguous key range made of the adjacent hot regions on
+// a store, along with their total rates.
+type StoreHotRange struct {
+	StartKey    string   `json:"start_key"`
+	EndKey      string   `json:"end_key"`
+	RegionCount int      `json:"region_count"`
+	RegionIDs   []uint64 `json:"region_ids"`
+	ByteRate    float64  `json:"byte_rate"`
+	KeyRate     float64  `json:"key_rate"`
+
+	startKey, endKey []byte
+}
+
+// GetStoreHotRanges merges the hot peers of the store into contiguous key
+// ranges, and returns the top ones by the byte or key rate. The hot peers are
+// the ones hot enough for the hot region scheduler. A top no more than 0
+// means all the ranges.
+func (c *RaftCluster) GetStoreHotRanges(storeID uint64, kind statistics.FlowKind, by string, top int) ([]*StoreHotRange, error) {
+	if c.GetStore(storeID) == nil {
+		return nil, core.NewStoreNotFoundErr(storeID)
+	}
+	minHotDegree := c.GetHotRegionCacheHitsThreshold()
+	var ranges []*StoreHotRange
+	for _, stat := range c.hotSpotCache.RegionStats(kind)[storeID] {
+		if stat.HotDegree < minHotDegree {
+			continue
+		}
+		region := c.GetRegion(stat.RegionID)
+		if region == nil {
+			continue
+		}
+		ranges = append(ranges, &StoreHotRange{
+			RegionCount: 1,
+			RegionIDs:   []uint64{stat.RegionID},
+			ByteRate:    stat.GetByteRate(),
+			KeyRate:     stat.GetKeyRate(),
+			startKey:    region.GetStartKey(),
+			endKey:      region.GetEndKey(),
+		})
+	}
+	ranges = mergeHotRanges(ranges)
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if by == HotRangeByKey {
+			return ranges[i].KeyRate > ranges[j].KeyRate
+		}
+		return ranges[i].ByteRate > ranges[j].ByteRate
+	})
+	if top > 0 && len(ranges) > top {
+		ranges = ranges[:top]
+	}
+	for _, r := range ranges {
+		r.StartKey, r.EndKey = core.HexRegionKeyStr(r.startKey), core.HexRegionKeyStr(r.endKey)
+	}
+	return ranges, nil
+}
+
+// mergeHotRanges merges the ranges of single regions into contiguous ones,
+// which are in the order of the start key.
+func mergeHotRanges(ranges []*StoreHotRange) []*StoreHotRange {
+	sort.Slice(ranges, func(i, j int) bool { return bytes.Compare(ranges[i].startKey, ranges[j].startKey) < 0 })
+	merged := make([]*StoreHotRange, 0, len(ranges))
+	for _, r := range ranges {
+		if n := len(merged); n > 0 {
+			last := merged[n-1]
+			// An empty end key is the end of the key space, which nothing
+			// follows.
+			if len(last.endKey) > 0 && bytes.Equal(last.endKey, r.startKey) {
+				last.endKey = r.endKey
+				last.RegionCount++
+				last.RegionIDs = append(last.RegionIDs, r.RegionIDs...)
+				last.ByteRate += r.ByteRate
+				last.KeyRate += r.KeyRate
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
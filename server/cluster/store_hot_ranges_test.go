@@ -0,0 +1,83 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/statistics"
+)
+
+var _ = Suite(&testStoreHotRangesSuite{})
+
+type testStoreHotRangesSuite struct{}
+
+func (s *testStoreHotRangesSuite) TestStoreHotRanges(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	tc := newTestCluster(opt)
+	c.Assert(tc.addRegionStore(1, 0), IsNil)
+	c.Assert(tc.addRegionStore(2, 0), IsNil)
+	hot := func(storeID, regionID uint64, byteRate, keyRate float64, hotDegree int) {
+		tc.hotSpotCache.Update(&statistics.HotPeerStat{
+			StoreID:   storeID,
+			RegionID:  regionID,
+			Kind:      statistics.WriteFlow,
+			ByteRate:  byteRate,
+			KeyRate:   keyRate,
+			HotDegree: hotDegree,
+		})
+	}
+	threshold := tc.GetHotRegionCacheHitsThreshold()
+	for id := uint64(1); id <= 8; id++ {
+		c.Assert(tc.addLeaderRegion(id, 1, 2), IsNil)
+	}
+	// The regions 1, 2 and 3 are adjacent, and so are the regions 5 and 6.
+	for _, id := range []uint64{1, 2, 3} {
+		hot(1, id, 100, 1, threshold)
+	}
+	hot(1, 5, 200, 1, threshold)
+	hot(1, 6, 200, 1, threshold)
+	hot(1, 8, 50, 10, threshold)
+	// Region 7 is not hot enough, and region 4 is only hot on store 2.
+	hot(1, 7, 1000, 1000, threshold-1)
+	hot(2, 4, 1000, 1000, threshold)
+	// Region 9 is hot but not in the cluster.
+	hot(1, 9, 1000, 1000, threshold)
+
+	ranges, err := tc.GetStoreHotRanges(1, statistics.WriteFlow, HotRangeByByte, 0)
+	c.Assert(err, IsNil)
+	c.Assert(ranges, HasLen, 3)
+	c.Assert(ranges[0].RegionIDs, DeepEquals, []uint64{5, 6})
+	c.Assert(ranges[0].StartKey, Equals, core.HexRegionKeyStr(tc.GetRegion(5).GetStartKey()))
+	c.Assert(ranges[0].EndKey, Equals, core.HexRegionKeyStr(tc.GetRegion(6).GetEndKey()))
+	c.Assert(ranges[0].ByteRate, Equals, 400.0)
+	c.Assert(ranges[0].KeyRate, Equals, 2.0)
+	c.Assert(ranges[1].RegionIDs, DeepEquals, []uint64{1, 2, 3})
+	c.Assert(ranges[1].RegionCount, Equals, 3)
+	c.Assert(ranges[1].ByteRate, Equals, 300.0)
+	c.Assert(ranges[2].RegionIDs, DeepEquals, []uint64{8})
+
+	ranges, err = tc.GetStoreHotRanges(1, statistics.WriteFlow, HotRangeByKey, 2)
+	c.Assert(err, IsNil)
+	c.Assert(ranges, HasLen, 2)
+	c.Assert(ranges[0].RegionIDs, DeepEquals, []uint64{8})
+	c.Assert(ranges[1].RegionIDs, DeepEquals, []uint64{1, 2, 3})
+
+	ranges, err = tc.GetStoreHotRanges(1, statistics.ReadFlow, HotRangeByByte, 0)
+	c.Assert(err, IsNil)
+	c.Assert(ranges, HasLen, 0)
+	_, err = tc.GetStoreHotRanges(3, statistics.WriteFlow, HotRangeByByte, 0)
+	c.Assert(err, NotNil)
+}
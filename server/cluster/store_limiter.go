@@ -49,25 +49,38 @@ func (s *StoreLimiter) Collect(stats *pdpb.StoreStats) {
 	log.Debug("collected statistics", zap.Reflect("stats", stats))
 	s.state.Collect((*StatEntry)(stats))
 
-	rate := float64(0)
 	state := s.state.State()
+	rate := s.sceneRate(state)
+	if rate > 0 {
+		s.oc.SetAllStoresLimitAuto(rate)
+		log.Info("change store limit for cluster", zap.Stringer("state", state), zap.Float64("rate", rate))
+		s.current = state
+		collectClusterStateCurrent(state)
+	}
+}
+
+// sceneRate returns the store limit rate of the scene of the load state, or 0
+// if the state is unknown.
+func (s *StoreLimiter) sceneRate(state LoadState) float64 {
 	switch state {
 	case LoadStateIdle:
-		rate = float64(s.scene.Idle) / schedule.StoreBalanceBaseTime
+		return float64(s.scene.Idle) / schedule.StoreBalanceBaseTime
 	case LoadStateLow:
-		rate = float64(s.scene.Low) / schedule.StoreBalanceBaseTime
+		return float64(s.scene.Low) / schedule.StoreBalanceBaseTime
 	case LoadStateNormal:
-		rate = float64(s.scene.Normal) / schedule.StoreBalanceBaseTime
+		return float64(s.scene.Normal) / schedule.StoreBalanceBaseTime
 	case LoadStateHigh:
-		rate = float64(s.scene.High) / schedule.StoreBalanceBaseTime
+		return float64(s.scene.High) / schedule.StoreBalanceBaseTime
 	}
+	return 0
+}
 
-	if rate > 0 {
-		s.oc.SetAllStoresLimitAuto(rate)
-		log.Info("change store limit for cluster", zap.Stringer("state", state), zap.Float64("rate", rate))
-		s.current = state
-		collectClusterStateCurrent(state)
-	}
+// CurrentRate returns the store limit rate of the scene of the current load
+// state, or 0 if the state is not collected yet.
+func (s *StoreLimiter) CurrentRate() float64 {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	return s.sceneRate(s.current)
 }
 
 func collectClusterStateCurrent(state LoadState) {
@@ -0,0 +1,158 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/checker"
+	"github.com/pingcap/pd/v4/server/schedule/filter"
+	"github.com/pingcap/pd/v4/server/schedule/placement"
+	"github.com/pkg/errors"
+)
+
+const offlineImpactName = "offline-impact"
+
+// RegionCountSize is the count and the total approximate size of regions.
+type RegionCountSize struct {
+	Count int   `json:"count"`
+	Size  int64 `json:"size"`
+}
+
+func (s *RegionCountSize) add(region *core.RegionInfo) {
+	s.Count++
+	s.Size += region.GetApproximateSize()
+}
+
+// StoreOfflineImpact is the work needed to take a store offline.
+type StoreOfflineImpact struct {
+	StoreID uint64 `json:"store_id"`
+	// Leaders are the regions whose leaders need to be transferred out of the
+	// store before their peers are replaced.
+	Leaders RegionCountSize `json:"leaders"`
+	// Voters are the regions whose non-leader voters need to be replaced.
+	Voters   RegionCountSize `json:"voters"`
+	Learners RegionCountSize `json:"learners"`
+	// Unplaceable are the regions having no store to place the replacement
+	// of the peer on the store currently.
+	Unplaceable        RegionCountSize `json:"unplaceable"`
+	UnplaceableRegions []uint64        `json:"unplaceable_regions"`
+}
+
+// GetStoreOfflineImpact estimates the regions to be moved if the store is
+// taken offline. The replacement stores are selected in the same way as the
+// replica checker, or the rule checker if the placement rules are enabled, but
+// no operator is created.
+func (c *RaftCluster) GetStoreOfflineImpact(storeID uint64) (*StoreOfflineImpact, error) {
+	if c.GetStore(storeID) == nil {
+		return nil, core.NewStoreNotFoundErr(storeID)
+	}
+
+	impact := &StoreOfflineImpact{StoreID: storeID, UnplaceableRegions: []uint64{}}
+	replicaChecker := checker.NewReplicaChecker(c, offlineImpactName)
+	for _, region := range c.core.ScanStoreRange(storeID, core.AnyRole, nil, nil, 0) {
+		peer := region.GetStorePeer(storeID)
+		switch {
+		case region.GetLeader().GetStoreId() == storeID:
+			impact.Leaders.add(region)
+		case region.GetStoreLearner(storeID) != nil:
+			impact.Learners.add(region)
+		default:
+			impact.Voters.add(region)
+		}
+		if !c.hasReplacementStore(replicaChecker, region, peer) {
+			impact.Unplaceable.add(region)
+			impact.UnplaceableRegions = append(impact.UnplaceableRegions, region.GetID())
+		}
+	}
+	return impact, nil
+}
+
+func (c *RaftCluster) hasReplacementStore(replicaChecker *checker.ReplicaChecker, region *core.RegionInfo, peer *metapb.Peer) bool {
+	if !c.IsPlacementRulesEnabled() {
+		target, _ := replicaChecker.SelectBestReplacementStore(region, peer, filter.NewStorageThresholdFilter(offlineImpactName))
+		return target != 0
+	}
+	fit := c.FitRegion(region)
+	rf := fit.GetRuleFit(peer.GetId())
+	if rf == nil {
+		// The orphan peer is removed rather than replaced.
+		return true
+	}
+	return checker.SelectStoreToReplacePeerByRule(offlineImpactName, c, region, fit, rf, peer) != nil
+}
+
+// checkStoresLeftUp is the preflight of taking the stores offline. It returns
+// an error if the stores left up cannot hold the max replicas, or the count of
+// a placement rule on the stores matching its constraints.
+func (c *RaftCluster) checkStoresLeftUp(removed map[uint64]struct{}) error {
+	var remaining []*core.StoreInfo
+	for _, store := range c.GetStores() {
+		if _, ok := removed[store.GetID()]; !ok && store.IsUp() {
+			remaining = append(remaining, store)
+		}
+	}
+	if !c.IsPlacementRulesEnabled() {
+		if len(remaining) < c.GetMaxReplicas() {
+			return errors.Errorf("only %d stores are left up, fewer than the max replicas %d", len(remaining), c.GetMaxReplicas())
+		}
+		return nil
+	}
+	for _, rule := range c.GetRuleManager().GetAllRules() {
+		var count int
+		for _, store := range remaining {
+			if placement.MatchLabelConstraints(store, rule.LabelConstraints) {
+				count++
+			}
+		}
+		if count < rule.Count {
+			return errors.Errorf("only %d stores matching rule %s/%s are left up, fewer than its count %d", count, rule.GroupID, rule.ID, rule.Count)
+		}
+	}
+	return nil
+}
+
+// StoreRemovalDryRun is what would happen if the store is removed.
+type StoreRemovalDryRun struct {
+	*StoreOfflineImpact
+	// Refused is true if taking the store offline is refused by the preflight
+	// of the batch offline, for the RefusedReason.
+	Refused       bool   `json:"refused"`
+	RefusedReason string `json:"refused_reason,omitempty"`
+}
+
+// DryRunRemoveStore reports the offline impact of the store and the preflight
+// result without changing any state. An offline store is not refused, as
+// removing it does nothing.
+func (c *RaftCluster) DryRunRemoveStore(storeID uint64) (*StoreRemovalDryRun, error) {
+	store := c.GetStore(storeID)
+	if store == nil {
+		return nil, core.NewStoreNotFoundErr(storeID)
+	}
+	if store.IsTombstone() {
+		return nil, core.StoreTombstonedErr{StoreID: storeID}
+	}
+	impact, err := c.GetStoreOfflineImpact(storeID)
+	if err != nil {
+		return nil, err
+	}
+	dryRun := &StoreRemovalDryRun{StoreOfflineImpact: impact}
+	if store.IsUp() {
+		if err := c.checkStoresLeftUp(map[uint64]struct{}{storeID: {}}); err != nil {
+			dryRun.Refused = true
+			dryRun.RefusedReason = err.Error()
+		}
+	}
+	return dryRun, nil
+}
@@ -0,0 +1,163 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/filter"
+	"github.com/pingcap/pd/v4/server/schedule/placement"
+)
+
+var _ = Suite(&testStoreOfflineImpactSuite{})
+
+type testStoreOfflineImpactSuite struct{}
+
+func (s *testStoreOfflineImpactSuite) TestStoreOfflineImpact(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	tc := newTestCluster(opt)
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(tc.addRegionStore(id, 0), IsNil)
+	}
+	c.Assert(tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(tc.addLeaderRegion(2, 2, 1, 4), IsNil)
+	c.Assert(tc.addLeaderRegion(3, 2, 3, 4), IsNil)
+	// Region 4 has a learner on store 1.
+	region := newTestRegionMeta(4)
+	leader, _ := tc.AllocPeer(2)
+	follower, _ := tc.AllocPeer(3)
+	learner, _ := tc.AllocPeer(1)
+	learner.IsLearner = true
+	region.Peers = []*metapb.Peer{leader, follower, learner}
+	c.Assert(tc.putRegion(core.NewRegionInfo(region, leader, core.SetApproximateSize(30))), IsNil)
+
+	impact, err := tc.GetStoreOfflineImpact(1)
+	c.Assert(err, IsNil)
+	c.Assert(impact, DeepEquals, &StoreOfflineImpact{
+		StoreID:            1,
+		Leaders:            RegionCountSize{Count: 1, Size: 10},
+		Voters:             RegionCountSize{Count: 1, Size: 10},
+		Learners:           RegionCountSize{Count: 1, Size: 30},
+		UnplaceableRegions: []uint64{},
+	})
+
+	// Store 4 is the only target for the regions 1 and 4.
+	store := tc.GetStore(4)
+	labels := []*metapb.StoreLabel{{Key: "specialUse", Value: filter.SpecialUseReserved}}
+	tc.Lock()
+	c.Assert(tc.putStoreLocked(store.Clone(core.SetStoreLabels(labels))), IsNil)
+	tc.Unlock()
+	impact, err = tc.GetStoreOfflineImpact(1)
+	c.Assert(err, IsNil)
+	c.Assert(impact.Unplaceable, Equals, RegionCountSize{Count: 2, Size: 40})
+	c.Assert(impact.UnplaceableRegions, DeepEquals, []uint64{1, 4})
+
+	_, err = tc.GetStoreOfflineImpact(5)
+	c.Assert(err, NotNil)
+}
+
+func (s *testStoreOfflineImpactSuite) TestDryRunRemoveStore(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	tc := newTestCluster(opt)
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(tc.addRegionStore(id, 0), IsNil)
+	}
+	c.Assert(tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(tc.addLeaderRegion(2, 2, 1, 3), IsNil)
+
+	// The regions have no store to move to, and the preflight refuses too.
+	dryRun, err := tc.DryRunRemoveStore(1)
+	c.Assert(err, IsNil)
+	c.Assert(dryRun.Leaders, Equals, RegionCountSize{Count: 1, Size: 10})
+	c.Assert(dryRun.Voters, Equals, RegionCountSize{Count: 1, Size: 10})
+	c.Assert(dryRun.UnplaceableRegions, DeepEquals, []uint64{1, 2})
+	c.Assert(dryRun.Refused, IsTrue)
+	c.Assert(tc.ValidateBatchOffline(&BatchOfflineConfig{StoreIDs: []uint64{1}}), ErrorMatches, dryRun.RefusedReason)
+	impact, err := tc.GetStoreOfflineImpact(1)
+	c.Assert(err, IsNil)
+	c.Assert(dryRun.StoreOfflineImpact, DeepEquals, impact)
+	c.Assert(tc.GetStore(1).IsUp(), IsTrue)
+
+	c.Assert(tc.addRegionStore(4, 0), IsNil)
+	dryRun, err = tc.DryRunRemoveStore(1)
+	c.Assert(err, IsNil)
+	c.Assert(dryRun.UnplaceableRegions, HasLen, 0)
+	c.Assert(dryRun.Refused, IsFalse)
+	c.Assert(tc.ValidateBatchOffline(&BatchOfflineConfig{StoreIDs: []uint64{1}}), IsNil)
+
+	// Removing an offline store does nothing.
+	c.Assert(tc.RemoveStore(2), IsNil)
+	c.Assert(tc.RemoveStore(3), IsNil)
+	dryRun, err = tc.DryRunRemoveStore(2)
+	c.Assert(err, IsNil)
+	c.Assert(dryRun.Refused, IsFalse)
+
+	tc.Lock()
+	c.Assert(tc.putStoreLocked(tc.GetStore(2).Clone(core.SetStoreState(metapb.StoreState_Tombstone))), IsNil)
+	tc.Unlock()
+	_, err = tc.DryRunRemoveStore(2)
+	c.Assert(err, NotNil)
+	_, err = tc.DryRunRemoveStore(5)
+	c.Assert(err, NotNil)
+}
+
+func (s *testStoreOfflineImpactSuite) TestDryRunRemoveStoreWithRules(c *C) {
+	_, opt, err := newTestScheduleConfig()
+	c.Assert(err, IsNil)
+	tc := newTestCluster(opt)
+	for id := uint64(1); id <= 4; id++ {
+		c.Assert(tc.addRegionStore(id, 0), IsNil)
+	}
+	// Stores 1, 2 and 3 are in zone z1, and all the voters are placed in z1.
+	labels := []*metapb.StoreLabel{{Key: "zone", Value: "z1"}}
+	tc.Lock()
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(tc.putStoreLocked(tc.GetStore(id).Clone(core.SetStoreLabels(labels))), IsNil)
+	}
+	tc.Unlock()
+	replication := *tc.opt.GetReplication().Load()
+	replication.EnablePlacementRules = true
+	tc.opt.GetReplication().Store(&replication)
+	tc.ruleManager = placement.NewRuleManager(tc.storage)
+	c.Assert(tc.ruleManager.Initialize(3, nil), IsNil)
+	c.Assert(tc.ruleManager.SetRule(&placement.Rule{
+		GroupID:          "pd",
+		ID:               "default",
+		Role:             placement.Voter,
+		Count:            3,
+		LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: placement.In, Values: []string{"z1"}}},
+	}), IsNil)
+	// Region 1 has no other store in z1 to move to, while region 2 can move
+	// to store 3. Store 4 is not a target though the replica checker picks it.
+	c.Assert(tc.addLeaderRegion(1, 1, 2, 3), IsNil)
+	c.Assert(tc.addLeaderRegion(2, 2, 1, 4), IsNil)
+
+	dryRun, err := tc.DryRunRemoveStore(1)
+	c.Assert(err, IsNil)
+	c.Assert(dryRun.Leaders, Equals, RegionCountSize{Count: 1, Size: 10})
+	c.Assert(dryRun.Voters, Equals, RegionCountSize{Count: 1, Size: 10})
+	c.Assert(dryRun.UnplaceableRegions, DeepEquals, []uint64{1})
+	c.Assert(dryRun.Refused, IsTrue)
+	c.Assert(tc.ValidateBatchOffline(&BatchOfflineConfig{StoreIDs: []uint64{1}}), ErrorMatches, dryRun.RefusedReason)
+
+	// The peer on store 4 is out of the rule, and is removed rather than replaced.
+	dryRun, err = tc.DryRunRemoveStore(4)
+	c.Assert(err, IsNil)
+	c.Assert(dryRun.Voters, Equals, RegionCountSize{Count: 1, Size: 10})
+	c.Assert(dryRun.UnplaceableRegions, HasLen, 0)
+	c.Assert(dryRun.Refused, IsFalse)
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/core"
+	"go.uber.org/zap"
+)
+
+// StoreWeight is the leader and region weights of a store.
+type StoreWeight struct {
+	StoreID uint64  `json:"store_id"`
+	Leader  float64 `json:"leader"`
+	Region  float64 `json:"region"`
+}
+
+// StoreWeightsError is the problems of a batch of store weights, none of
+// which is applied.
+type StoreWeightsError struct {
+	Problems []string `json:"problems"`
+}
+
+func (e *StoreWeightsError) Error() string {
+	return "invalid store weights: " + strings.Join(e.Problems, "; ")
+}
+
+// GetStoreWeights returns the weights of all the stores which are not
+// tombstone, sorted by the store ID.
+func (c *RaftCluster) GetStoreWeights() []*StoreWeight {
+	var weights []*StoreWeight
+	for _, store := range c.GetStores() {
+		if store.IsTombstone() {
+			continue
+		}
+		weights = append(weights, &StoreWeight{
+			StoreID: store.GetID(),
+			Leader:  store.GetLeaderWeight(),
+			Region:  store.GetRegionWeight(),
+		})
+	}
+	sort.Slice(weights, func(i, j int) bool { return weights[i].StoreID < weights[j].StoreID })
+	return weights
+}
+
+// checkWeight returns the problem of the weight in the i-th row if any.
+func checkWeight(i int, kind string, weight float64) []string {
+	switch {
+	case math.IsNaN(weight) || math.IsInf(weight, 0):
+		return []string{fmt.Sprintf("row %d: %s weight %v is not a finite number", i+1, kind, weight)}
+	case weight < 0:
+		return []string{fmt.Sprintf("row %d: negative %s weight %v", i+1, kind, weight)}
+	}
+	return nil
+}
+
+// SetStoreWeights sets the weights of the stores in bulk. The batch is
+// validated as a whole before any weight is persisted, and it is rejected
+// with all the problems found if any.
+func (c *RaftCluster) SetStoreWeights(weights []*StoreWeight) error {
+	c.Lock()
+	defer c.Unlock()
+
+	var problems []string
+	seen := make(map[uint64]struct{}, len(weights))
+	for i, w := range weights {
+		if w == nil {
+			problems = append(problems, fmt.Sprintf("row %d: empty row", i+1))
+			continue
+		}
+		if _, ok := seen[w.StoreID]; ok {
+			problems = append(problems, fmt.Sprintf("row %d: duplicated store %d", i+1, w.StoreID))
+			continue
+		}
+		seen[w.StoreID] = struct{}{}
+		if store := c.GetStore(w.StoreID); store == nil || store.IsTombstone() {
+			problems = append(problems, fmt.Sprintf("row %d: store %d not found", i+1, w.StoreID))
+		}
+		problems = append(problems, checkWeight(i, "leader", w.Leader)...)
+		problems = append(problems, checkWeight(i, "region", w.Region)...)
+	}
+	if len(problems) > 0 {
+		return &StoreWeightsError{Problems: problems}
+	}
+
+	for _, w := range weights {
+		if err := c.storage.SaveStoreWeight(w.StoreID, w.Leader, w.Region); err != nil {
+			return err
+		}
+		newStore := c.GetStore(w.StoreID).Clone(
+			core.SetLeaderWeight(w.Leader),
+			core.SetRegionWeight(w.Region),
+		)
+		if err := c.putStoreLocked(newStore); err != nil {
+			return err
+		}
+	}
+	log.Info("store weights are set in bulk", zap.Int("count", len(weights)), zap.Reflect("weights", weights))
+	return nil
+}
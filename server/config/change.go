@@ -0,0 +1,67 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// Change is an updated config item.
+type Change struct {
+	// Item is the name of the item prefixed by its section, such as
+	// "replication.max-replicas".
+	Item string      `json:"item"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// DiffSection returns the changed items of a config section, sorted by the
+// names. The items are compared in their JSON forms.
+func DiffSection(section string, old, new interface{}) ([]*Change, error) {
+	oldItems, err := sectionItems(old)
+	if err != nil {
+		return nil, err
+	}
+	newItems, err := sectionItems(new)
+	if err != nil {
+		return nil, err
+	}
+	var changes []*Change
+	for name, v := range newItems {
+		if !reflect.DeepEqual(oldItems[name], v) {
+			changes = append(changes, &Change{Item: section + "." + name, Old: oldItems[name], New: v})
+		}
+	}
+	for name, v := range oldItems {
+		if _, ok := newItems[name]; !ok {
+			changes = append(changes, &Change{Item: section + "." + name, Old: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Item < changes[j].Item })
+	return changes, nil
+}
+
+func sectionItems(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	items := make(map[string]interface{})
+	if err = json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -202,9 +202,10 @@ const (
 
 	defaultLeaderPriorityCheckInterval = time.Minute
 
-	defaultUseRegionStorage = true
-	defaultMaxResetTsGap    = 24 * time.Hour
-	defaultKeyType          = "table"
+	defaultUseRegionStorage   = true
+	defaultMaxResetTsGap      = 24 * time.Hour
+	defaultResetTSPauseWindow = time.Second
+	defaultKeyType            = "table"
 
 	defaultStrictlyMatchLabel  = false
 	defaultEnableGRPCGateway   = true
@@ -508,6 +509,38 @@ type ScheduleConfig struct {
 	EnableCrossTableMerge bool `toml:"enable-cross-table-merge" json:"enable-cross-table-merge,string"`
 	// PatrolRegionInterval is the interval for scanning region during patrol.
 	PatrolRegionInterval typeutil.Duration `toml:"patrol-region-interval" json:"patrol-region-interval"`
+	// EnableRepairFirst is the option to hold the balance and hot region schedulers after the
+	// coordinator starts, until the patrol checks all the regions once or RepairFirstTimeout
+	// elapses, so that the repairs of the checkers are not competing with them.
+	EnableRepairFirst  bool              `toml:"enable-repair-first" json:"enable-repair-first,string"`
+	RepairFirstTimeout typeutil.Duration `toml:"repair-first-timeout" json:"repair-first-timeout"`
+	// GrowthSplitRate is the growth rate of the approximate size in MB per minute above which
+	// PD splits the region before it becomes too large. 0 means never split by the growth.
+	GrowthSplitRate float64 `toml:"growth-split-rate" json:"growth-split-rate"`
+	// SchedulerBackoffSuccessRate is the success rate of the operators created by a balance scheduler
+	// below which the scheduling interval of the scheduler is backed off. 0 means never back off.
+	SchedulerBackoffSuccessRate float64 `toml:"scheduler-backoff-success-rate" json:"scheduler-backoff-success-rate"`
+	// StoreFlappingRestartLimit is the number of the restarts of a store within StoreFlappingWindow
+	// above which the store is flapping. A flapping store is not selected as the target of the
+	// schedules until it stays up for StoreFlappingCooldown. 0 means never flapping.
+	StoreFlappingRestartLimit uint64            `toml:"store-flapping-restart-limit" json:"store-flapping-restart-limit"`
+	StoreFlappingWindow       typeutil.Duration `toml:"store-flapping-window" json:"store-flapping-window"`
+	StoreFlappingCooldown     typeutil.Duration `toml:"store-flapping-cooldown" json:"store-flapping-cooldown"`
+	// OperatorPingPongLimit is the number of the operators finished for a region within
+	// OperatorPingPongWindow at which the new non-admin operators for the region are
+	// rejected or deferred according to OperatorPingPongAction. 0 means no limit.
+	OperatorPingPongLimit  uint64            `toml:"operator-pingpong-limit" json:"operator-pingpong-limit"`
+	OperatorPingPongWindow typeutil.Duration `toml:"operator-pingpong-window" json:"operator-pingpong-window"`
+	OperatorPingPongAction string            `toml:"operator-pingpong-action" json:"operator-pingpong-action"`
+	// ScatterGroupTTL is how long the stores selected for an idle scatter group are kept, after
+	// which the group is removed.
+	ScatterGroupTTL typeutil.Duration `toml:"scatter-group-ttl" json:"scatter-group-ttl"`
+	// ScatterStoreSelectionLimit is the number of times a store is selected in a scatter group
+	// before it is excluded from the candidates. The selections of the group are reset once no
+	// store is left.
+	ScatterStoreSelectionLimit uint64 `toml:"scatter-store-selection-limit" json:"scatter-store-selection-limit"`
+	// ScatterIncludeBusyStores is the option to select the busy stores when scattering regions.
+	ScatterIncludeBusyStores bool `toml:"scatter-include-busy-stores" json:"scatter-include-busy-stores,string"`
 	// MaxStoreDownTime is the max duration after which
 	// a store will be considered to be down if it hasn't reported heartbeats.
 	MaxStoreDownTime typeutil.Duration `toml:"max-store-down-time" json:"max-store-down-time"`
@@ -527,6 +560,14 @@ type ScheduleConfig struct {
 	// If the number of times a region hits the hot cache is greater than this
 	// threshold, it is considered a hot region.
 	HotRegionCacheHitsThreshold uint64 `toml:"hot-region-cache-hits-threshold" json:"hot-region-cache-hits-threshold"`
+	// HotWriteByteRateThreshold and HotWriteKeyRateThreshold are the min write
+	// rates of a hot peer. A peer is hot if it reaches either of them.
+	HotWriteByteRateThreshold float64 `toml:"hot-write-byte-rate-threshold" json:"hot-write-byte-rate-threshold"`
+	HotWriteKeyRateThreshold  float64 `toml:"hot-write-key-rate-threshold" json:"hot-write-key-rate-threshold"`
+	// HotReadByteRateThreshold and HotReadKeyRateThreshold are the min read
+	// rates of a hot leader. A leader is hot if it reaches either of them.
+	HotReadByteRateThreshold float64 `toml:"hot-read-byte-rate-threshold" json:"hot-read-byte-rate-threshold"`
+	HotReadKeyRateThreshold  float64 `toml:"hot-read-key-rate-threshold" json:"hot-read-key-rate-threshold"`
 	// StoreBalanceRate is the maximum of balance rate for each store.
 	StoreBalanceRate float64 `toml:"store-balance-rate" json:"store-balance-rate"`
 	// TolerantSizeRatio is the ratio of buffer size for balance scheduler.
@@ -608,6 +649,19 @@ func (c *ScheduleConfig) Clone() *ScheduleConfig {
 		MaxMergeRegionKeys:           c.MaxMergeRegionKeys,
 		SplitMergeInterval:           c.SplitMergeInterval,
 		PatrolRegionInterval:         c.PatrolRegionInterval,
+		EnableRepairFirst:            c.EnableRepairFirst,
+		RepairFirstTimeout:           c.RepairFirstTimeout,
+		GrowthSplitRate:              c.GrowthSplitRate,
+		SchedulerBackoffSuccessRate:  c.SchedulerBackoffSuccessRate,
+		StoreFlappingRestartLimit:    c.StoreFlappingRestartLimit,
+		StoreFlappingWindow:          c.StoreFlappingWindow,
+		StoreFlappingCooldown:        c.StoreFlappingCooldown,
+		OperatorPingPongLimit:        c.OperatorPingPongLimit,
+		OperatorPingPongWindow:       c.OperatorPingPongWindow,
+		OperatorPingPongAction:       c.OperatorPingPongAction,
+		ScatterGroupTTL:              c.ScatterGroupTTL,
+		ScatterStoreSelectionLimit:   c.ScatterStoreSelectionLimit,
+		ScatterIncludeBusyStores:     c.ScatterIncludeBusyStores,
 		MaxStoreDownTime:             c.MaxStoreDownTime,
 		LeaderScheduleLimit:          c.LeaderScheduleLimit,
 		LeaderSchedulePolicy:         c.LeaderSchedulePolicy,
@@ -618,6 +672,10 @@ func (c *ScheduleConfig) Clone() *ScheduleConfig {
 		EnableCrossTableMerge:        c.EnableCrossTableMerge,
 		HotRegionScheduleLimit:       c.HotRegionScheduleLimit,
 		HotRegionCacheHitsThreshold:  c.HotRegionCacheHitsThreshold,
+		HotWriteByteRateThreshold:    c.HotWriteByteRateThreshold,
+		HotWriteKeyRateThreshold:     c.HotWriteKeyRateThreshold,
+		HotReadByteRateThreshold:     c.HotReadByteRateThreshold,
+		HotReadKeyRateThreshold:      c.HotReadKeyRateThreshold,
 		StoreBalanceRate:             c.StoreBalanceRate,
 		TolerantSizeRatio:            c.TolerantSizeRatio,
 		LowSpaceRatio:                c.LowSpaceRatio,
@@ -649,6 +707,14 @@ const (
 	defaultSplitMergeInterval     = 1 * time.Hour
 	defaultPatrolRegionInterval   = 100 * time.Millisecond
 	defaultMaxStoreDownTime       = 30 * time.Minute
+	defaultRepairFirstTimeout     = 10 * time.Minute
+	defaultSchedulerBackoffRate   = 0.5
+	defaultStoreFlappingRestarts  = 3
+	defaultStoreFlappingWindow    = 10 * time.Minute
+	defaultStoreFlappingCooldown  = 30 * time.Minute
+	defaultOperatorPingPongWindow = 10 * time.Minute
+	defaultScatterGroupTTL        = 10 * time.Minute
+	defaultScatterStoreSelections = 1
 	defaultLeaderScheduleLimit    = 4
 	defaultRegionScheduleLimit    = 2048
 	defaultReplicaScheduleLimit   = 64
@@ -661,6 +727,10 @@ const (
 	// defaultHotRegionCacheHitsThreshold is the low hit number threshold of the
 	// hot region.
 	defaultHotRegionCacheHitsThreshold = 3
+	defaultHotWriteByteRateThreshold   = 1 * 1024
+	defaultHotWriteKeyRateThreshold    = 32
+	defaultHotReadByteRateThreshold    = 8 * 1024
+	defaultHotReadKeyRateThreshold     = 128
 	defaultSchedulerMaxWaitingOperator = 5
 	defaultLeaderSchedulePolicy        = "count"
 	defaultStoreLimitMode              = "manual"
@@ -681,7 +751,23 @@ func (c *ScheduleConfig) adjust(meta *configMetaData) error {
 	}
 	adjustDuration(&c.SplitMergeInterval, defaultSplitMergeInterval)
 	adjustDuration(&c.PatrolRegionInterval, defaultPatrolRegionInterval)
+	if !meta.IsDefined("enable-repair-first") {
+		c.EnableRepairFirst = true
+	}
+	adjustDuration(&c.RepairFirstTimeout, defaultRepairFirstTimeout)
 	adjustDuration(&c.MaxStoreDownTime, defaultMaxStoreDownTime)
+	if !meta.IsDefined("scheduler-backoff-success-rate") {
+		adjustFloat64(&c.SchedulerBackoffSuccessRate, defaultSchedulerBackoffRate)
+	}
+	if !meta.IsDefined("store-flapping-restart-limit") {
+		adjustUint64(&c.StoreFlappingRestartLimit, defaultStoreFlappingRestarts)
+	}
+	adjustDuration(&c.StoreFlappingWindow, defaultStoreFlappingWindow)
+	adjustDuration(&c.StoreFlappingCooldown, defaultStoreFlappingCooldown)
+	adjustDuration(&c.OperatorPingPongWindow, defaultOperatorPingPongWindow)
+	adjustString(&c.OperatorPingPongAction, schedule.PingPongReject)
+	adjustDuration(&c.ScatterGroupTTL, defaultScatterGroupTTL)
+	adjustUint64(&c.ScatterStoreSelectionLimit, defaultScatterStoreSelections)
 	if !meta.IsDefined("leader-schedule-limit") {
 		adjustUint64(&c.LeaderScheduleLimit, defaultLeaderScheduleLimit)
 	}
@@ -700,6 +786,18 @@ func (c *ScheduleConfig) adjust(meta *configMetaData) error {
 	if !meta.IsDefined("hot-region-cache-hits-threshold") {
 		adjustUint64(&c.HotRegionCacheHitsThreshold, defaultHotRegionCacheHitsThreshold)
 	}
+	if !meta.IsDefined("hot-write-byte-rate-threshold") {
+		adjustFloat64(&c.HotWriteByteRateThreshold, defaultHotWriteByteRateThreshold)
+	}
+	if !meta.IsDefined("hot-write-key-rate-threshold") {
+		adjustFloat64(&c.HotWriteKeyRateThreshold, defaultHotWriteKeyRateThreshold)
+	}
+	if !meta.IsDefined("hot-read-byte-rate-threshold") {
+		adjustFloat64(&c.HotReadByteRateThreshold, defaultHotReadByteRateThreshold)
+	}
+	if !meta.IsDefined("hot-read-key-rate-threshold") {
+		adjustFloat64(&c.HotReadKeyRateThreshold, defaultHotReadKeyRateThreshold)
+	}
 	if !meta.IsDefined("tolerant-size-ratio") {
 		adjustFloat64(&c.TolerantSizeRatio, defaultTolerantSizeRatio)
 	}
@@ -782,6 +880,19 @@ func (c *ScheduleConfig) Validate() error {
 	if c.LowSpaceRatio <= c.HighSpaceRatio {
 		return errors.New("low-space-ratio should be larger than high-space-ratio")
 	}
+	if c.GrowthSplitRate < 0 {
+		return errors.New("growth-split-rate should be nonnegative")
+	}
+	if c.SchedulerBackoffSuccessRate < 0 || c.SchedulerBackoffSuccessRate > 1 {
+		return errors.New("scheduler-backoff-success-rate should between 0 and 1")
+	}
+	if c.HotWriteByteRateThreshold < 0 || c.HotWriteKeyRateThreshold < 0 ||
+		c.HotReadByteRateThreshold < 0 || c.HotReadKeyRateThreshold < 0 {
+		return errors.New("the hot thresholds should be nonnegative")
+	}
+	if c.OperatorPingPongAction != schedule.PingPongReject && c.OperatorPingPongAction != schedule.PingPongDefer {
+		return errors.Errorf("operator-pingpong-action should be %s or %s", schedule.PingPongReject, schedule.PingPongDefer)
+	}
 	for _, scheduleConfig := range c.Schedulers {
 		if !schedule.IsSchedulerRegistered(scheduleConfig.Type) {
 			return errors.Errorf("create func of %v is not registered, maybe misspelled", scheduleConfig.Type)
@@ -790,6 +901,35 @@ func (c *ScheduleConfig) Validate() error {
 	return nil
 }
 
+// HotThresholds is the part of the schedule config which decides the hot peers.
+type HotThresholds struct {
+	CacheHitsThreshold uint64  `json:"hot-region-cache-hits-threshold"`
+	WriteByteRate      float64 `json:"hot-write-byte-rate-threshold"`
+	WriteKeyRate       float64 `json:"hot-write-key-rate-threshold"`
+	ReadByteRate       float64 `json:"hot-read-byte-rate-threshold"`
+	ReadKeyRate        float64 `json:"hot-read-key-rate-threshold"`
+}
+
+// GetHotThresholds returns the thresholds which decide the hot peers.
+func (c *ScheduleConfig) GetHotThresholds() *HotThresholds {
+	return &HotThresholds{
+		CacheHitsThreshold: c.HotRegionCacheHitsThreshold,
+		WriteByteRate:      c.HotWriteByteRateThreshold,
+		WriteKeyRate:       c.HotWriteKeyRateThreshold,
+		ReadByteRate:       c.HotReadByteRateThreshold,
+		ReadKeyRate:        c.HotReadKeyRateThreshold,
+	}
+}
+
+// SetHotThresholds sets the thresholds which decide the hot peers.
+func (c *ScheduleConfig) SetHotThresholds(t *HotThresholds) {
+	c.HotRegionCacheHitsThreshold = t.CacheHitsThreshold
+	c.HotWriteByteRateThreshold = t.WriteByteRate
+	c.HotWriteKeyRateThreshold = t.WriteKeyRate
+	c.HotReadByteRateThreshold = t.ReadByteRate
+	c.HotReadKeyRateThreshold = t.ReadKeyRate
+}
+
 // Deprecated is used to find if there is an option has been deprecated.
 func (c *ScheduleConfig) Deprecated() error {
 	if c.DisableLearner {
@@ -840,6 +980,9 @@ type SchedulerConfig struct {
 	Args        []string `toml:"args" json:"args"`
 	Disable     bool     `toml:"disable" json:"disable"`
 	ArgsPayload string   `toml:"args-payload" json:"args-payload"`
+	// Observe means the scheduler keeps running, but its operators are logged
+	// instead of being added.
+	Observe bool `toml:"observe" json:"observe"`
 }
 
 var defaultSchedulers = SchedulerConfigs{
@@ -915,6 +1058,9 @@ type PDServerConfig struct {
 	UseRegionStorage bool `toml:"use-region-storage" json:"use-region-storage,string"`
 	// MaxResetTSGap is the max gap to reset the tso.
 	MaxResetTSGap time.Duration `toml:"max-reset-ts-gap" json:"max-reset-ts-gap"`
+	// ResetTSPauseWindow is how long the tso is paused before it is reset in
+	// the safe mode, during which the clients get a retryable error.
+	ResetTSPauseWindow typeutil.Duration `toml:"reset-ts-pause-window" json:"reset-ts-pause-window"`
 	// KeyType is option to specify the type of keys.
 	// There are some types supported: ["table", "raw", "txn"], default: "table"
 	KeyType string `toml:"key-type" json:"key-type"`
@@ -923,6 +1069,9 @@ type PDServerConfig struct {
 	// MetricStorage is the cluster metric storage.
 	// Currently we use prometheus as metric storage, we may use PD/TiKV as metric storage later.
 	MetricStorage string `toml:"metric-storage" json:"metric-storage"`
+	// MinBootstrapVersion is the minimum version of the store which is allowed to bootstrap the cluster.
+	// Empty means only the minimum version supported by PD is required.
+	MinBootstrapVersion string `toml:"min-bootstrap-version" json:"min-bootstrap-version"`
 }
 
 func (c *PDServerConfig) adjust(meta *configMetaData) error {
@@ -932,12 +1081,23 @@ func (c *PDServerConfig) adjust(meta *configMetaData) error {
 	if !meta.IsDefined("max-reset-ts-gap") {
 		c.MaxResetTSGap = defaultMaxResetTsGap
 	}
+	adjustDuration(&c.ResetTSPauseWindow, defaultResetTSPauseWindow)
 	if !meta.IsDefined("key-type") {
 		c.KeyType = defaultKeyType
 	}
 	if !meta.IsDefined("runtime-services") {
 		c.RuntimeServices = defaultRuntimeServices
 	}
+	return c.Validate()
+}
+
+// Validate is used to validate if some pd server configurations are right.
+func (c *PDServerConfig) Validate() error {
+	if c.MinBootstrapVersion != "" {
+		if _, err := semver.NewVersion(strings.TrimPrefix(c.MinBootstrapVersion, "v")); err != nil {
+			return errors.Errorf("invalid min-bootstrap-version %q: %v", c.MinBootstrapVersion, err)
+		}
+	}
 	return nil
 }
 
@@ -99,6 +99,16 @@ func (s *testConfigSuite) TestValidation(c *C) {
 	c.Assert(cfg.Schedule.Validate(), IsNil)
 	cfg.Schedule.TolerantSizeRatio = -0.6
 	c.Assert(cfg.Schedule.Validate(), NotNil)
+	cfg.Schedule.TolerantSizeRatio = 0
+	cfg.Schedule.SchedulerBackoffSuccessRate = 1.5
+	c.Assert(cfg.Schedule.Validate(), NotNil)
+	cfg.Schedule.SchedulerBackoffSuccessRate = 0
+	c.Assert(cfg.Schedule.Validate(), IsNil)
+	c.Assert(cfg.Schedule.OperatorPingPongAction, Equals, "reject")
+	cfg.Schedule.OperatorPingPongAction = "skip"
+	c.Assert(cfg.Schedule.Validate(), NotNil)
+	cfg.Schedule.OperatorPingPongAction = "defer"
+	c.Assert(cfg.Schedule.Validate(), IsNil)
 	// check quota
 	c.Assert(cfg.QuotaBackendBytes, Equals, defaultQuotaBackendBytes)
 }
@@ -253,3 +263,20 @@ tidb-cert-path = "/path/client.pem"
 	c.Assert(cfg.Dashboard.TiDBKeyPath, Equals, "/path/client-key.pem")
 	c.Assert(cfg.Dashboard.TiDBCertPath, Equals, "/path/client.pem")
 }
+
+func (s *testConfigSuite) TestDiffSection(c *C) {
+	old := &ReplicationConfig{MaxReplicas: 3, LocationLabels: []string{"zone"}}
+	new := &ReplicationConfig{MaxReplicas: 5, LocationLabels: []string{"zone", "host"}}
+	changes, err := DiffSection("replication", old, new)
+	c.Assert(err, IsNil)
+	c.Assert(changes, DeepEquals, []*Change{
+		{Item: "replication.location-labels", Old: "zone", New: "zone,host"},
+		{Item: "replication.max-replicas", Old: float64(3), New: float64(5)},
+	})
+
+	changes, err = DiffSection("label-property", LabelPropertyConfig{"reject-leader": {{Key: "zone", Value: "z1"}}}, LabelPropertyConfig{})
+	c.Assert(err, IsNil)
+	c.Assert(changes, HasLen, 1)
+	c.Assert(changes[0].Item, Equals, "label-property.reject-leader")
+	c.Assert(changes[0].New, IsNil)
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/pingcap/pd/v4/server/core"
 	"github.com/pingcap/pd/v4/server/kv"
 	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pkg/errors"
 )
 
 // ScheduleOption is a wrapper to access the configuration safely.
@@ -151,6 +152,82 @@ func (o *ScheduleOption) GetPatrolRegionInterval() time.Duration {
 	return o.Load().PatrolRegionInterval.Duration
 }
 
+// GetGrowthSplitRate returns the growth rate of the approximate size in MB per minute
+// above which PD splits the region.
+func (o *ScheduleOption) GetGrowthSplitRate() float64 {
+	return o.Load().GrowthSplitRate
+}
+
+// GetSchedulerBackoffSuccessRate returns the success rate of the operators created by a
+// balance scheduler below which the scheduling interval of the scheduler is backed off.
+func (o *ScheduleOption) GetSchedulerBackoffSuccessRate() float64 {
+	return o.Load().SchedulerBackoffSuccessRate
+}
+
+// GetStoreFlappingRestartLimit returns the number of the restarts of a store within the
+// flapping window above which the store is flapping.
+func (o *ScheduleOption) GetStoreFlappingRestartLimit() uint64 {
+	return o.Load().StoreFlappingRestartLimit
+}
+
+// GetStoreFlappingWindow returns the window in which the restarts of a store are counted.
+func (o *ScheduleOption) GetStoreFlappingWindow() time.Duration {
+	return o.Load().StoreFlappingWindow.Duration
+}
+
+// GetOperatorPingPongLimit returns the number of the operators finished for a
+// region in the window at which the new operators for the region are guarded.
+func (o *ScheduleOption) GetOperatorPingPongLimit() uint64 {
+	return o.Load().OperatorPingPongLimit
+}
+
+// GetOperatorPingPongWindow returns the window in which the operators finished
+// for a region are counted.
+func (o *ScheduleOption) GetOperatorPingPongWindow() time.Duration {
+	return o.Load().OperatorPingPongWindow.Duration
+}
+
+// GetOperatorPingPongAction returns how the new operators for a region are
+// guarded, either rejected or deferred.
+func (o *ScheduleOption) GetOperatorPingPongAction() string {
+	return o.Load().OperatorPingPongAction
+}
+
+// IsRepairFirstEnabled returns if the balance and hot region schedulers are
+// held until the patrol checks all the regions once after the coordinator starts.
+func (o *ScheduleOption) IsRepairFirstEnabled() bool {
+	return o.Load().EnableRepairFirst
+}
+
+// GetRepairFirstTimeout returns how long the schedulers are held at most after
+// the coordinator starts.
+func (o *ScheduleOption) GetRepairFirstTimeout() time.Duration {
+	return o.Load().RepairFirstTimeout.Duration
+}
+
+// GetScatterGroupTTL returns how long the stores selected for an idle scatter
+// group are kept.
+func (o *ScheduleOption) GetScatterGroupTTL() time.Duration {
+	return o.Load().ScatterGroupTTL.Duration
+}
+
+// GetScatterStoreSelectionLimit returns the number of times a store is
+// selected in a scatter group before it is excluded from the candidates.
+func (o *ScheduleOption) GetScatterStoreSelectionLimit() uint64 {
+	return o.Load().ScatterStoreSelectionLimit
+}
+
+// IsScatterBusyStoresIncluded returns if the busy stores are selected when
+// scattering regions.
+func (o *ScheduleOption) IsScatterBusyStoresIncluded() bool {
+	return o.Load().ScatterIncludeBusyStores
+}
+
+// GetStoreFlappingCooldown returns how long a flapping store needs to stay up to recover.
+func (o *ScheduleOption) GetStoreFlappingCooldown() time.Duration {
+	return o.Load().StoreFlappingCooldown.Duration
+}
+
 // GetMaxStoreDownTime returns the max down time of a store.
 func (o *ScheduleOption) GetMaxStoreDownTime() time.Duration {
 	return o.Load().MaxStoreDownTime.Duration
@@ -259,11 +336,11 @@ func (o *ScheduleOption) AddSchedulerCfg(tp string, args []string) {
 		// comparing args is to cover the case that there are schedulers in same type but not with same name
 		// such as two schedulers of type "evict-leader",
 		// one name is "evict-leader-scheduler-1" and the other is "evict-leader-scheduler-2"
-		if reflect.DeepEqual(schedulerCfg, SchedulerConfig{Type: tp, Args: args, Disable: false}) {
+		if reflect.DeepEqual(schedulerCfg, SchedulerConfig{Type: tp, Args: args, Disable: false, Observe: schedulerCfg.Observe}) {
 			return
 		}
 
-		if reflect.DeepEqual(schedulerCfg, SchedulerConfig{Type: tp, Args: args, Disable: true}) {
+		if reflect.DeepEqual(schedulerCfg, SchedulerConfig{Type: tp, Args: args, Disable: true, Observe: schedulerCfg.Observe}) {
 			schedulerCfg.Disable = false
 			v.Schedulers[i] = schedulerCfg
 			o.Store(v)
@@ -276,27 +353,60 @@ func (o *ScheduleOption) AddSchedulerCfg(tp string, args []string) {
 
 // RemoveSchedulerCfg removes the scheduler configurations.
 func (o *ScheduleOption) RemoveSchedulerCfg(ctx context.Context, name string) error {
-	c := o.Load()
-	v := c.Clone()
-	for i, schedulerCfg := range v.Schedulers {
+	v := o.Load().Clone()
+	i, err := findSchedulerCfg(ctx, v.Schedulers, name)
+	if err != nil || i < 0 {
+		return err
+	}
+	if IsDefaultScheduler(v.Schedulers[i].Type) {
+		v.Schedulers[i].Disable = true
+	} else {
+		v.Schedulers = append(v.Schedulers[:i], v.Schedulers[i+1:]...)
+	}
+	o.Store(v)
+	return nil
+}
+
+// IsSchedulerObserved returns if the scheduler only logs its operators.
+func (o *ScheduleOption) IsSchedulerObserved(ctx context.Context, name string) (bool, error) {
+	schedulers := o.Load().Schedulers
+	i, err := findSchedulerCfg(ctx, schedulers, name)
+	if err != nil || i < 0 {
+		return false, err
+	}
+	return schedulers[i].Observe, nil
+}
+
+// SetSchedulerObserved sets whether the scheduler only logs its operators.
+func (o *ScheduleOption) SetSchedulerObserved(ctx context.Context, name string, observe bool) error {
+	v := o.Load().Clone()
+	i, err := findSchedulerCfg(ctx, v.Schedulers, name)
+	if err != nil {
+		return err
+	}
+	if i < 0 {
+		return errors.Errorf("the config of scheduler %s is not found", name)
+	}
+	v.Schedulers[i].Observe = observe
+	o.Store(v)
+	return nil
+}
+
+// findSchedulerCfg returns the index of the config of the scheduler, or -1 if
+// it is not found.
+func findSchedulerCfg(ctx context.Context, schedulers SchedulerConfigs, name string) (int, error) {
+	for i, schedulerCfg := range schedulers {
 		// To create a temporary scheduler is just used to get scheduler's name
 		decoder := schedule.ConfigSliceDecoder(schedulerCfg.Type, schedulerCfg.Args)
 		tmp, err := schedule.CreateScheduler(schedulerCfg.Type, schedule.NewOperatorController(ctx, nil, nil), core.NewStorage(kv.NewMemoryKV()), decoder)
 		if err != nil {
-			return err
+			return -1, err
 		}
 		if tmp.GetName() == name {
-			if IsDefaultScheduler(tmp.GetType()) {
-				schedulerCfg.Disable = true
-				v.Schedulers[i] = schedulerCfg
-			} else {
-				v.Schedulers = append(v.Schedulers[:i], v.Schedulers[i+1:]...)
-			}
-			o.Store(v)
-			return nil
+			return i, nil
 		}
 	}
-	return nil
+	return -1, nil
 }
 
 // SetLabelProperty sets the label property.
@@ -409,6 +519,7 @@ func (o *ScheduleOption) adjustScheduleCfg(persistentCfg *Config) {
 		for _, ps := range persistentCfg.Schedule.Schedulers {
 			if s.Type == ps.Type && reflect.DeepEqual(s.Args, ps.Args) {
 				scheduleCfg.Schedulers[i].Disable = ps.Disable
+				scheduleCfg.Schedulers[i].Observe = ps.Observe
 				break
 			}
 		}
@@ -437,6 +548,18 @@ func (o *ScheduleOption) GetHotRegionCacheHitsThreshold() int {
 	return int(o.Load().HotRegionCacheHitsThreshold)
 }
 
+// GetHotWriteThresholds returns the min write byte rate and key rate of a hot peer.
+func (o *ScheduleOption) GetHotWriteThresholds() (float64, float64) {
+	cfg := o.Load()
+	return cfg.HotWriteByteRateThreshold, cfg.HotWriteKeyRateThreshold
+}
+
+// GetHotReadThresholds returns the min read byte rate and key rate of a hot leader.
+func (o *ScheduleOption) GetHotReadThresholds() (float64, float64) {
+	cfg := o.Load()
+	return cfg.HotReadByteRateThreshold, cfg.HotReadKeyRateThreshold
+}
+
 // CheckLabelProperty checks the label property.
 func (o *ScheduleOption) CheckLabelProperty(typ string, labels []*metapb.StoreLabel) bool {
 	pc := o.labelProperty.Load().(LabelPropertyConfig)
@@ -0,0 +1,151 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/config"
+	"github.com/pingcap/pd/v4/server/schedule/opt"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ConfigBatch is the updates of the config sections applied at once, any of
+// which can be nil to keep the section.
+type ConfigBatch struct {
+	Schedule    *config.ScheduleConfig
+	Replication *config.ReplicationConfig
+	// LabelProperty replaces the whole label property config.
+	LabelProperty config.LabelPropertyConfig
+}
+
+// ConfigBatchError is the error of an invalid section of a config batch.
+type ConfigBatchError struct {
+	Section string
+	Err     error
+}
+
+func (e *ConfigBatchError) Error() string {
+	return e.Section + ": " + e.Err.Error()
+}
+
+// validateConfigBatch validates the sections of the batch, and the
+// constraints across them against the current config.
+func (s *Server) validateConfigBatch(batch *ConfigBatch) error {
+	if batch.Schedule != nil {
+		if err := batch.Schedule.Validate(); err != nil {
+			return &ConfigBatchError{Section: "schedule", Err: err}
+		}
+		if err := batch.Schedule.Deprecated(); err != nil {
+			return &ConfigBatchError{Section: "schedule", Err: err}
+		}
+	}
+	replication := s.scheduleOpt.GetReplication().Load()
+	if batch.Replication != nil {
+		if err := batch.Replication.Validate(); err != nil {
+			return &ConfigBatchError{Section: "replication", Err: err}
+		}
+		if batch.Replication.MaxReplicas == 0 {
+			return &ConfigBatchError{Section: "replication", Err: errors.New("max-replicas should be positive")}
+		}
+		// Toggling the placement rules checks the current placements, which
+		// is done by its own API.
+		if batch.Replication.EnablePlacementRules != replication.EnablePlacementRules {
+			return &ConfigBatchError{Section: "replication", Err: errors.New("enable-placement-rules cannot be updated in batch")}
+		}
+		replication = batch.Replication
+	}
+	for typ, labels := range batch.LabelProperty {
+		if typ != opt.RejectLeader {
+			return &ConfigBatchError{Section: "label-property", Err: errors.Errorf("unknown label property type %s", typ)}
+		}
+		for _, l := range labels {
+			if l.Key == "" {
+				return &ConfigBatchError{Section: "label-property", Err: errors.Errorf("the label key of %s should not be empty", typ)}
+			}
+		}
+	}
+	// The replicas cannot be placed apart in fewer locations. It is not
+	// checked with the placement rules, by which the max-replicas is not used.
+	if batch.Replication != nil && !replication.EnablePlacementRules && len(replication.LocationLabels) > 0 {
+		if rc := s.GetRaftCluster(); rc != nil {
+			if locations := rc.CountDistinctLocations(replication.LocationLabels); locations > 0 && uint64(locations) < replication.MaxReplicas {
+				return &ConfigBatchError{Section: "replication", Err: errors.Errorf("max-replicas %d is more than the %d distinct locations by the location labels %v",
+					replication.MaxReplicas, locations, []string(replication.LocationLabels))}
+			}
+		}
+	}
+	return nil
+}
+
+// SetConfigBatch validates all the sections of the batch, and applies them
+// under the config lock and persists them at once, so that either all or none
+// of them are applied. It returns the changed items.
+func (s *Server) SetConfigBatch(batch *ConfigBatch) ([]*config.Change, error) {
+	s.configLock.Lock()
+	defer s.configLock.Unlock()
+	if err := s.validateConfigBatch(batch); err != nil {
+		return nil, err
+	}
+
+	oldSchedule := s.scheduleOpt.Load()
+	oldReplication := s.scheduleOpt.GetReplication().Load()
+	oldLabelProperty := s.scheduleOpt.LoadLabelPropertyConfig()
+	var changes []*config.Change
+	diff := func(section string, old, new interface{}) error {
+		c, err := config.DiffSection(section, old, new)
+		changes = append(changes, c...)
+		return err
+	}
+	if batch.Schedule != nil {
+		if err := diff("schedule", oldSchedule, batch.Schedule); err != nil {
+			return nil, err
+		}
+	}
+	if batch.Replication != nil {
+		if err := diff("replication", oldReplication, batch.Replication); err != nil {
+			return nil, err
+		}
+	}
+	if batch.LabelProperty != nil {
+		if err := diff("label-property", oldLabelProperty, batch.LabelProperty); err != nil {
+			return nil, err
+		}
+	}
+	if len(changes) == 0 {
+		return changes, nil
+	}
+
+	if batch.Schedule != nil {
+		s.scheduleOpt.Store(batch.Schedule)
+	}
+	if batch.Replication != nil {
+		s.scheduleOpt.GetReplication().Store(batch.Replication)
+	}
+	if batch.LabelProperty != nil {
+		s.scheduleOpt.SetLabelPropertyConfig(batch.LabelProperty.Clone())
+	}
+	if err := s.scheduleOpt.Persist(s.storage); err != nil {
+		s.scheduleOpt.Store(oldSchedule)
+		s.scheduleOpt.GetReplication().Store(oldReplication)
+		s.scheduleOpt.SetLabelPropertyConfig(oldLabelProperty)
+		log.Error("failed to update config in batch", zap.Reflect("changes", changes), zap.Error(err))
+		return nil, err
+	}
+	log.Info("config is updated in batch", zap.Reflect("changes", changes))
+	if rc := s.GetRaftCluster(); rc != nil {
+		rc.OnConfigBatchUpdated(changes)
+	}
+	return changes, nil
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/log"
 	"github.com/pingcap/pd/v4/pkg/slice"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
 
@@ -86,6 +87,22 @@ func (bc *BasicCluster) GetStoreRegions(storeID uint64) []*RegionInfo {
 	return bc.Regions.GetStoreRegions(storeID)
 }
 
+// GetStoreRegionsByRole gets the regions which have a peer of the role on the
+// store.
+func (bc *BasicCluster) GetStoreRegionsByRole(storeID uint64, role PeerRole) []*RegionInfo {
+	bc.RLock()
+	defer bc.RUnlock()
+	return bc.Regions.GetStoreRegionsByRole(storeID, role)
+}
+
+// GetStoreRegionCountByRole gets the count of the regions which have a peer of
+// the role on the store.
+func (bc *BasicCluster) GetStoreRegionCountByRole(storeID uint64, role PeerRole) int {
+	bc.RLock()
+	defer bc.RUnlock()
+	return bc.Regions.GetStoreRegionCountByRole(storeID, role)
+}
+
 // GetRegionStores returns all Stores that contains the region's peer.
 func (bc *BasicCluster) GetRegionStores(region *RegionInfo) []*StoreInfo {
 	bc.RLock()
@@ -126,6 +143,20 @@ func (bc *BasicCluster) GetAdjacentRegions(region *RegionInfo) (*RegionInfo, *Re
 	return bc.Regions.GetAdjacentRegions(region)
 }
 
+// GetAdjacentRegionGaps returns the gaps right before and after the region.
+func (bc *BasicCluster) GetAdjacentRegionGaps(region *RegionInfo) (*RegionGap, *RegionGap) {
+	bc.RLock()
+	defer bc.RUnlock()
+	return bc.Regions.GetAdjacentRegionGaps(region)
+}
+
+// GetRegionGaps returns the gaps in the key space covered by the regions.
+func (bc *BasicCluster) GetRegionGaps(limit int) []*RegionGap {
+	bc.RLock()
+	defer bc.RUnlock()
+	return bc.Regions.GetRegionGaps(limit)
+}
+
 // BlockStore stops balancer from selecting the store.
 func (bc *BasicCluster) BlockStore(storeID uint64) error {
 	bc.Lock()
@@ -304,6 +335,12 @@ func (bc *BasicCluster) PreCheckPutRegion(region *RegionInfo) (*RegionInfo, erro
 	if r.GetVersion() < o.GetVersion() || r.GetConfVer() < o.GetConfVer() {
 		return origin, ErrRegionIsStale(region.GetMeta(), origin.GetMeta())
 	}
+	// A stale leader partitioned away keeps reporting the same epoch, which
+	// should not overwrite the leader elected in a higher term.
+	if r.GetVersion() == o.GetVersion() && r.GetConfVer() == o.GetConfVer() &&
+		region.GetTerm() != 0 && region.GetTerm() < origin.GetTerm() {
+		return origin, errors.Wrapf(ErrRegionTermIsStale, "region %d term %d, origin term %d", region.GetID(), region.GetTerm(), origin.GetTerm())
+	}
 	return origin, nil
 }
 
@@ -314,6 +351,14 @@ func (bc *BasicCluster) PutRegion(region *RegionInfo) []*RegionInfo {
 	return bc.Regions.SetRegion(region)
 }
 
+// UpdateRegionFlow replaces the cached origin with the region which only
+// differs from it in the flow stats.
+func (bc *BasicCluster) UpdateRegionFlow(origin, region *RegionInfo) bool {
+	bc.Lock()
+	defer bc.Unlock()
+	return bc.Regions.UpdateRegionFlow(origin, region)
+}
+
 // CheckAndPutRegion checks if the region is valid to put,if valid then put.
 func (bc *BasicCluster) CheckAndPutRegion(region *RegionInfo) []*RegionInfo {
 	origin, err := bc.PreCheckPutRegion(region)
@@ -332,6 +377,19 @@ func (bc *BasicCluster) RemoveRegion(region *RegionInfo) {
 	bc.Regions.RemoveRegion(region)
 }
 
+// SearchRegionWithAdjacent searches the RegionInfo covering the key and its
+// adjacent regions in one read, so that they are consistent.
+func (bc *BasicCluster) SearchRegionWithAdjacent(regionKey []byte) (region, prev, next *RegionInfo) {
+	bc.RLock()
+	defer bc.RUnlock()
+	region = bc.Regions.SearchRegion(regionKey)
+	if region == nil {
+		return nil, nil, nil
+	}
+	prev, next = bc.Regions.GetAdjacentRegions(region)
+	return region, prev, next
+}
+
 // SearchRegion searches RegionInfo from regionTree.
 func (bc *BasicCluster) SearchRegion(regionKey []byte) *RegionInfo {
 	bc.RLock()
@@ -354,6 +412,14 @@ func (bc *BasicCluster) ScanRange(startKey, endKey []byte, limit int) []*RegionI
 	return bc.Regions.ScanRange(startKey, endKey, limit)
 }
 
+// ScanStoreRange scans regions intersecting [start key, end key) which have a
+// peer of the role on the store, returns at most `limit` regions.
+func (bc *BasicCluster) ScanStoreRange(storeID uint64, role PeerRole, startKey, endKey []byte, limit int) []*RegionInfo {
+	bc.RLock()
+	defer bc.RUnlock()
+	return bc.Regions.ScanStoreRange(storeID, role, startKey, endKey, limit)
+}
+
 // GetOverlaps returns the regions which are overlapped with the specified region range.
 func (bc *BasicCluster) GetOverlaps(region *RegionInfo) []*RegionInfo {
 	bc.RLock()
@@ -34,10 +34,22 @@ var (
 
 	// StoreTombstonedCode is an invalid operation was attempted on a store which is in a removed state.
 	StoreTombstonedCode = storeStateCode.Child("state.store.tombstoned").SetHTTP(http.StatusGone)
+
+	// StoreResidualPeersCode is an error due to removing a store which is still referenced by the cached regions.
+	StoreResidualPeersCode = storeStateCode.Child("state.store.residual_peers").SetHTTP(http.StatusConflict)
+
+	// StoreAddressConflictCode is an error due to resolving an address shared by several stores.
+	StoreAddressConflictCode = storeStateCode.Child("state.store.address_conflict").SetHTTP(http.StatusConflict)
+
+	// OperatorQuotaExceededCode is an error due to a consumer submitting more operators than its quota.
+	OperatorQuotaExceededCode = errcode.StateCode.Child("state.operator_quota_exceeded").SetHTTP(http.StatusTooManyRequests)
 )
 
-var _ errcode.ErrorCode = (*StoreTombstonedErr)(nil) // assert implements interface
-var _ errcode.ErrorCode = (*StoreBlockedErr)(nil)    // assert implements interface
+var _ errcode.ErrorCode = (*StoreTombstonedErr)(nil)       // assert implements interface
+var _ errcode.ErrorCode = (*StoreBlockedErr)(nil)          // assert implements interface
+var _ errcode.ErrorCode = (*StoreResidualPeersErr)(nil)    // assert implements interface
+var _ errcode.ErrorCode = (*StoreAddressConflictErr)(nil)  // assert implements interface
+var _ errcode.ErrorCode = (*OperatorQuotaExceededErr)(nil) // assert implements interface
 
 // StoreErr can be newtyped or embedded in your own error
 type StoreErr struct {
@@ -64,6 +76,53 @@ func (e StoreBlockedErr) Error() string {
 // Code returns StoreBlockedCode
 func (e StoreBlockedErr) Code() errcode.Code { return StoreBlockedCode }
 
+// StoreResidualPeersErr has a Code() of StoreResidualPeersCode
+type StoreResidualPeersErr struct {
+	StoreID uint64 `json:"storeId"`
+	Count   int    `json:"count"`
+}
+
+func (e StoreResidualPeersErr) Error() string {
+	return fmt.Sprintf("store %v still has %v residual peers", e.StoreID, e.Count)
+}
+
+// Code returns StoreResidualPeersCode
+func (e StoreResidualPeersErr) Code() errcode.Code { return StoreResidualPeersCode }
+
+// StoreAddressConflictErr has a Code() of StoreAddressConflictCode
+type StoreAddressConflictErr struct {
+	Address    string   `json:"address"`
+	Candidates []uint64 `json:"candidates"`
+}
+
+func (e StoreAddressConflictErr) Error() string {
+	return fmt.Sprintf("address %s is shared by stores %v", e.Address, e.Candidates)
+}
+
+// Code returns StoreAddressConflictCode
+func (e StoreAddressConflictErr) Code() errcode.Code { return StoreAddressConflictCode }
+
+// OperatorQuotaExceededErr has a Code() of OperatorQuotaExceededCode
+type OperatorQuotaExceededErr struct {
+	Consumer      string `json:"consumer"`
+	MaxConcurrent int    `json:"maxConcurrent"`
+	MaxPerHour    int    `json:"maxPerHour"`
+	Concurrent    int    `json:"concurrent"`
+	ThisHour      int    `json:"thisHour"`
+}
+
+func (e OperatorQuotaExceededErr) Error() string {
+	return fmt.Sprintf("consumer %s exceeds the operator quota: %v concurrent operators of max %v, %v operators this hour of max %v",
+		e.Consumer, e.Concurrent, e.MaxConcurrent, e.ThisHour, e.MaxPerHour)
+}
+
+// Code returns OperatorQuotaExceededCode
+func (e OperatorQuotaExceededErr) Code() errcode.Code { return OperatorQuotaExceededCode }
+
+// ErrRegionTermIsStale is error info for the region whose epoch is the same as
+// the cached one but the term is lower, which is reported by a stale leader.
+var ErrRegionTermIsStale = errors.New("region term is stale")
+
 // ErrRegionIsStale is error info for region is stale.
 var ErrRegionIsStale = func(region *metapb.Region, origin *metapb.Region) error {
 	return errors.Errorf("region is stale: region %v origin %v", region, origin)
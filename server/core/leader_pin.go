@@ -0,0 +1,131 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// LeaderPin keeps the leader of a region on the store, or on any store
+// matching all the labels, e.g. to keep the meta regions in the primary DC.
+type LeaderPin struct {
+	RegionID   uint64               `json:"region_id"`
+	StoreID    uint64               `json:"store_id,omitempty"`
+	Labels     []*metapb.StoreLabel `json:"labels,omitempty"`
+	CreateTime time.Time            `json:"create_time"`
+}
+
+// MatchStore returns true if the leader can be pinned on the store.
+func (p *LeaderPin) MatchStore(store *StoreInfo) bool {
+	if p.StoreID != 0 {
+		return store.GetID() == p.StoreID
+	}
+	for _, label := range p.Labels {
+		if store.GetLabelValue(label.GetKey()) != label.GetValue() {
+			return false
+		}
+	}
+	return len(p.Labels) > 0
+}
+
+// LeaderPins manages the leader pins of the regions. It is threadsafe.
+type LeaderPins struct {
+	sync.RWMutex
+	storage *Storage
+	pins    map[uint64]*LeaderPin
+}
+
+// NewLeaderPins creates a LeaderPins instance.
+func NewLeaderPins(storage *Storage) *LeaderPins {
+	return &LeaderPins{
+		storage: storage,
+		pins:    make(map[uint64]*LeaderPin),
+	}
+}
+
+// Load loads the pins from storage.
+func (p *LeaderPins) Load() error {
+	p.Lock()
+	defer p.Unlock()
+	return p.storage.LoadLeaderPins(func(k, v string) {
+		var pin LeaderPin
+		if err := json.Unmarshal([]byte(v), &pin); err != nil {
+			log.Error("failed to unmarshal leader pin", zap.String("pin-key", k), zap.String("pin-value", v))
+			return
+		}
+		p.pins[pin.RegionID] = &pin
+	})
+}
+
+// SetPin persists and sets the pin, which replaces the one of the region.
+func (p *LeaderPins) SetPin(pin *LeaderPin) error {
+	p.Lock()
+	defer p.Unlock()
+	if err := p.storage.SaveLeaderPin(pin.RegionID, pin); err != nil {
+		return err
+	}
+	p.pins[pin.RegionID] = pin
+	log.Info("leader pin set", zap.Uint64("region-id", pin.RegionID), zap.Uint64("store-id", pin.StoreID), zap.Reflect("labels", pin.Labels))
+	return nil
+}
+
+// RemovePin removes the pin of the region. It returns false if the region is
+// not pinned.
+func (p *LeaderPins) RemovePin(regionID uint64) (bool, error) {
+	p.Lock()
+	defer p.Unlock()
+	if _, ok := p.pins[regionID]; !ok {
+		return false, nil
+	}
+	if err := p.storage.DeleteLeaderPin(regionID); err != nil {
+		return false, err
+	}
+	delete(p.pins, regionID)
+	log.Info("leader pin removed", zap.Uint64("region-id", regionID))
+	return true, nil
+}
+
+// GetPin returns the pin of the region, or nil if it is not pinned.
+func (p *LeaderPins) GetPin(regionID uint64) *LeaderPin {
+	p.RLock()
+	defer p.RUnlock()
+	return p.pins[regionID]
+}
+
+// GetPins returns all the pins sorted by the region ID.
+func (p *LeaderPins) GetPins() []*LeaderPin {
+	p.RLock()
+	defer p.RUnlock()
+	pins := make([]*LeaderPin, 0, len(p.pins))
+	for _, pin := range p.pins {
+		pins = append(pins, pin)
+	}
+	sort.Slice(pins, func(i, j int) bool { return pins[i].RegionID < pins[j].RegionID })
+	return pins
+}
+
+// IsRegionPinned returns true if the leader of the region is pinned.
+func (p *LeaderPins) IsRegionPinned(regionID uint64) bool {
+	p.RLock()
+	defer p.RUnlock()
+	_, ok := p.pins[regionID]
+	return ok
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pingcap/kvproto/pkg/metapb"
+)
+
+// RankedPeer is a peer with the score of how close its store is to a location.
+type RankedPeer struct {
+	Peer *metapb.Peer
+	// Score is the number of the leading location levels the store shares with
+	// the location, and a higher score means the peer is closer.
+	Score int
+}
+
+// LocationScore returns the number of the leading levels of the labels at
+// which the store is at the same location as the given one. Unlike
+// CompareLocation, a level missing in either side stops the match, since
+// nothing is known about the distance from there on.
+func (s *StoreInfo) LocationScore(labels []string, location []*metapb.StoreLabel) int {
+	for i, key := range labels {
+		v1, v2 := s.GetLabelValue(key), getLabelValue(location, key)
+		if v1 == "" || v2 == "" || !strings.EqualFold(v1, v2) {
+			return i
+		}
+	}
+	return len(labels)
+}
+
+func getLabelValue(labels []*metapb.StoreLabel, key string) string {
+	for _, label := range labels {
+		if strings.EqualFold(label.GetKey(), key) {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
+
+// RankPeersByLocation ranks the live peers of the region by how close their
+// stores are to the location, where the levels are in the order of the
+// labels, or of the location itself if no labels are given. The down and
+// pending peers and the ones on the unknown or tombstone stores are excluded,
+// and the peers with the same score are ordered by the store ID.
+func RankPeersByLocation(region *RegionInfo, getStore func(uint64) *StoreInfo, labels []string, location []*metapb.StoreLabel) []*RankedPeer {
+	if len(labels) == 0 {
+		labels = make([]string, 0, len(location))
+		for _, label := range location {
+			labels = append(labels, label.GetKey())
+		}
+	}
+	ranked := make([]*RankedPeer, 0, len(region.GetPeers()))
+	for _, peer := range region.GetPeers() {
+		if region.GetDownPeer(peer.GetId()) != nil || region.GetPendingPeer(peer.GetId()) != nil {
+			continue
+		}
+		store := getStore(peer.GetStoreId())
+		if store == nil || store.IsTombstone() {
+			continue
+		}
+		ranked = append(ranked, &RankedPeer{Peer: peer, Score: store.LocationScore(labels, location)})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Peer.GetStoreId() < ranked[j].Peer.GetStoreId()
+	})
+	return ranked
+}
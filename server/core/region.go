@@ -18,6 +18,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"unsafe"
 
@@ -42,6 +43,9 @@ type RegionInfo struct {
 	approximateSize int64
 	approximateKeys int64
 	interval        *pdpb.TimeInterval
+	// term is the raft term of the leader reported in the heartbeat, and 0
+	// means unknown.
+	term uint64
 }
 
 // NewRegionInfo creates RegionInfo with region's meta and leader peer.
@@ -98,6 +102,7 @@ func RegionFromHeartbeat(heartbeat *pdpb.RegionHeartbeatRequest) *RegionInfo {
 		approximateSize: int64(regionSize),
 		approximateKeys: int64(heartbeat.GetApproximateKeys()),
 		interval:        heartbeat.GetInterval(),
+		term:            heartbeat.GetTerm(),
 	}
 
 	classifyVoterAndLearner(region)
@@ -127,6 +132,7 @@ func (r *RegionInfo) Clone(opts ...RegionCreateOption) *RegionInfo {
 		approximateSize: r.approximateSize,
 		approximateKeys: r.approximateKeys,
 		interval:        proto.Clone(r.interval).(*pdpb.TimeInterval),
+		term:            r.term,
 	}
 
 	for _, opt := range opts {
@@ -338,6 +344,11 @@ func (r *RegionInfo) GetInterval() *pdpb.TimeInterval {
 	return r.interval
 }
 
+// GetTerm returns the raft term of the leader, or 0 if it is unknown.
+func (r *RegionInfo) GetTerm() uint64 {
+	return r.term
+}
+
 // GetDownPeers returns the down peers of the region.
 func (r *RegionInfo) GetDownPeers() []*pdpb.PeerStats {
 	return r.downPeers
@@ -575,6 +586,28 @@ func (r *RegionsInfo) SetRegion(region *RegionInfo) []*RegionInfo {
 	return r.AddRegion(region)
 }
 
+// UpdateRegionFlow replaces the cached origin with the region in place without
+// updating the structure of the region trees. The region should only differ
+// from origin in the flow stats, see IsOnlyFlowChanged. It returns false if
+// origin is not cached anymore, then the region should be set by SetRegion.
+func (r *RegionsInfo) UpdateRegionFlow(origin, region *RegionInfo) bool {
+	if r.regions.Get(region.GetID()) != origin || !r.tree.replace(region) {
+		return false
+	}
+	r.regions.Put(region)
+	for _, peer := range region.GetVoters() {
+		if peer.GetId() == region.leader.GetId() {
+			r.leaders[peer.GetStoreId()].update(region)
+		} else {
+			r.followers[peer.GetStoreId()].update(region)
+		}
+	}
+	for _, peer := range region.GetLearners() {
+		r.learners[peer.GetStoreId()].update(region)
+	}
+	return true
+}
+
 // Length returns the RegionsInfo length
 func (r *RegionsInfo) Length() int {
 	return r.regions.Len()
@@ -826,6 +859,140 @@ func (r *RegionsInfo) ScanRange(startKey, endKey []byte, limit int) []*RegionInf
 	return res
 }
 
+// PeerRole is the role of a peer used to filter the regions of a store.
+type PeerRole string
+
+// Peer roles used to filter the regions of a store. AnyRole matches peers of
+// all roles.
+const (
+	AnyRole      PeerRole = ""
+	LeaderRole   PeerRole = "leader"
+	FollowerRole PeerRole = "follower"
+	LearnerRole  PeerRole = "learner"
+)
+
+// IsValidPeerRole checks if the role is a known peer role.
+func IsValidPeerRole(role PeerRole) bool {
+	switch role {
+	case AnyRole, LeaderRole, FollowerRole, LearnerRole:
+		return true
+	}
+	return false
+}
+
+// ScanStoreRange scans regions intersecting [start key, end key) which have a
+// peer of the role on the store, returns at most `limit` regions. limit <= 0
+// means no limit. It walks either the key range or the store's subtrees,
+// depending on which one has fewer regions.
+func (r *RegionsInfo) ScanStoreRange(storeID uint64, role PeerRole, startKey, endKey []byte, limit int) []*RegionInfo {
+	subTrees := r.getStoreSubTrees(storeID, role)
+	var storeCount int
+	for _, subTree := range subTrees {
+		storeCount += subTree.length()
+	}
+	if storeCount == 0 {
+		return nil
+	}
+
+	var res []*RegionInfo
+	if storeCount >= r.tree.length() {
+		r.tree.scanRange(startKey, func(region *RegionInfo) bool {
+			if len(endKey) > 0 && bytes.Compare(region.GetStartKey(), endKey) >= 0 {
+				return false
+			}
+			if limit > 0 && len(res) >= limit {
+				return false
+			}
+			region = r.GetRegion(region.GetID())
+			if matchPeerRole(region, storeID, role) {
+				res = append(res, region)
+			}
+			return true
+		})
+		return res
+	}
+
+	for _, subTree := range subTrees {
+		var count int
+		subTree.scanRange(startKey, func(region *RegionInfo) bool {
+			if len(endKey) > 0 && bytes.Compare(region.GetStartKey(), endKey) >= 0 {
+				return false
+			}
+			if limit > 0 && count >= limit {
+				return false
+			}
+			res = append(res, r.GetRegion(region.GetID()))
+			count++
+			return true
+		})
+	}
+	// A region has at most one peer on a store, so the results of the subtrees
+	// never overlap and only need to be merged by the start key.
+	if len(subTrees) > 1 {
+		sort.Slice(res, func(i, j int) bool {
+			return bytes.Compare(res[i].GetStartKey(), res[j].GetStartKey()) < 0
+		})
+	}
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res
+}
+
+// GetStoreRegionsByRole gets the regions which have a peer of the role on the
+// store from the store's subtrees.
+func (r *RegionsInfo) GetStoreRegionsByRole(storeID uint64, role PeerRole) []*RegionInfo {
+	var regions []*RegionInfo
+	for _, subTree := range r.getStoreSubTrees(storeID, role) {
+		regions = append(regions, subTree.scanRanges()...)
+	}
+	return regions
+}
+
+// GetStoreRegionCountByRole gets the count of the regions which have a peer of
+// the role on the store.
+func (r *RegionsInfo) GetStoreRegionCountByRole(storeID uint64, role PeerRole) int {
+	var count int
+	for _, subTree := range r.getStoreSubTrees(storeID, role) {
+		count += subTree.length()
+	}
+	return count
+}
+
+func (r *RegionsInfo) getStoreSubTrees(storeID uint64, role PeerRole) []*regionSubTree {
+	var subTrees []*regionSubTree
+	if role == AnyRole || role == LeaderRole {
+		subTrees = append(subTrees, r.leaders[storeID])
+	}
+	if role == AnyRole || role == FollowerRole {
+		subTrees = append(subTrees, r.followers[storeID])
+	}
+	if role == AnyRole || role == LearnerRole {
+		subTrees = append(subTrees, r.learners[storeID])
+	}
+	res := subTrees[:0]
+	for _, subTree := range subTrees {
+		if subTree.length() > 0 {
+			res = append(res, subTree)
+		}
+	}
+	return res
+}
+
+func matchPeerRole(region *RegionInfo, storeID uint64, role PeerRole) bool {
+	switch role {
+	case LeaderRole:
+		return region.GetLeader().GetStoreId() == storeID
+	case FollowerRole:
+		voter := region.GetStoreVoter(storeID)
+		return voter != nil && voter.GetId() != region.GetLeader().GetId()
+	case LearnerRole:
+		return region.GetStoreLearner(storeID) != nil
+	default:
+		return region.GetStorePeer(storeID) != nil
+	}
+}
+
 // ScanRangeWithIterator scans from the first region containing or behind start key,
 // until iterator returns false.
 func (r *RegionsInfo) ScanRangeWithIterator(startKey []byte, iterator func(region *RegionInfo) bool) {
@@ -846,6 +1013,66 @@ func (r *RegionsInfo) GetAdjacentRegions(region *RegionInfo) (*RegionInfo, *Regi
 	return prev, next
 }
 
+// RegionGap is a key range not covered by any region in the cache, which is
+// left when a region is dropped from the cache before the regions replacing
+// it send heartbeats.
+type RegionGap struct {
+	StartKey []byte
+	// EndKey is empty if the gap reaches the end of the key space.
+	EndKey []byte
+	// PrevRegionID and NextRegionID are the regions around the gap, or 0 if
+	// the gap is at the start or the end of the key space.
+	PrevRegionID uint64
+	NextRegionID uint64
+}
+
+// GetAdjacentRegionGaps returns the gaps right before and after the region,
+// or nil if the region is adjacent to another region or the boundary of the
+// key space on that side.
+func (r *RegionsInfo) GetAdjacentRegionGaps(region *RegionInfo) (*RegionGap, *RegionGap) {
+	p, n := r.tree.getAdjacentRegions(region)
+	var prev, next *RegionGap
+	if len(region.GetStartKey()) > 0 && (p == nil || !bytes.Equal(p.region.GetEndKey(), region.GetStartKey())) {
+		prev = &RegionGap{StartKey: []byte{}, EndKey: region.GetStartKey(), NextRegionID: region.GetID()}
+		if p != nil {
+			prev.StartKey, prev.PrevRegionID = p.region.GetEndKey(), p.region.GetID()
+		}
+	}
+	if len(region.GetEndKey()) > 0 && (n == nil || !bytes.Equal(region.GetEndKey(), n.region.GetStartKey())) {
+		next = &RegionGap{StartKey: region.GetEndKey(), EndKey: []byte{}, PrevRegionID: region.GetID()}
+		if n != nil {
+			next.EndKey, next.NextRegionID = n.region.GetStartKey(), n.region.GetID()
+		}
+	}
+	return prev, next
+}
+
+// GetRegionGaps returns the gaps in the key space covered by the regions in
+// the cache from the start, at most limit ones if limit is positive. There is
+// no gap if there is no region.
+func (r *RegionsInfo) GetRegionGaps(limit int) []*RegionGap {
+	var (
+		gaps []*RegionGap
+		prev *RegionInfo
+	)
+	r.tree.scanRange([]byte{}, func(region *RegionInfo) bool {
+		if limit > 0 && len(gaps) >= limit {
+			return false
+		}
+		if prev == nil && len(region.GetStartKey()) > 0 {
+			gaps = append(gaps, &RegionGap{StartKey: []byte{}, EndKey: region.GetStartKey(), NextRegionID: region.GetID()})
+		} else if prev != nil && !bytes.Equal(prev.GetEndKey(), region.GetStartKey()) {
+			gaps = append(gaps, &RegionGap{StartKey: prev.GetEndKey(), EndKey: region.GetStartKey(), PrevRegionID: prev.GetID(), NextRegionID: region.GetID()})
+		}
+		prev = region
+		return true
+	})
+	if prev != nil && len(prev.GetEndKey()) > 0 && (limit <= 0 || len(gaps) < limit) {
+		gaps = append(gaps, &RegionGap{StartKey: prev.GetEndKey(), EndKey: []byte{}, PrevRegionID: prev.GetID()})
+	}
+	return gaps
+}
+
 // GetAverageRegionSize returns the average region approximate size.
 func (r *RegionsInfo) GetAverageRegionSize() int64 {
 	if r.regions.Len() == 0 {
@@ -884,6 +1111,38 @@ func DiffRegionPeersInfo(origin *RegionInfo, other *RegionInfo) string {
 	return strings.Join(ret, ",")
 }
 
+// IsOnlyFlowChanged returns true if the region only differs from origin in the
+// flow stats, which means the meta, the leader, the approximate size and keys
+// are the same, and neither of them has down or pending peers.
+func IsOnlyFlowChanged(origin, region *RegionInfo) bool {
+	if len(origin.downPeers) > 0 || len(origin.pendingPeers) > 0 ||
+		len(region.downPeers) > 0 || len(region.pendingPeers) > 0 {
+		return false
+	}
+	if origin.GetLeader().GetId() != region.GetLeader().GetId() ||
+		origin.GetLeader().GetStoreId() != region.GetLeader().GetStoreId() ||
+		origin.approximateSize != region.approximateSize ||
+		origin.approximateKeys != region.approximateKeys {
+		return false
+	}
+	o, r := origin.GetMeta(), region.GetMeta()
+	if o.GetId() != r.GetId() ||
+		!bytes.Equal(o.GetStartKey(), r.GetStartKey()) ||
+		!bytes.Equal(o.GetEndKey(), r.GetEndKey()) ||
+		o.GetRegionEpoch().GetVersion() != r.GetRegionEpoch().GetVersion() ||
+		o.GetRegionEpoch().GetConfVer() != r.GetRegionEpoch().GetConfVer() ||
+		len(o.GetPeers()) != len(r.GetPeers()) {
+		return false
+	}
+	for i, p := range o.GetPeers() {
+		q := r.GetPeers()[i]
+		if p.GetId() != q.GetId() || p.GetStoreId() != q.GetStoreId() || p.GetIsLearner() != q.GetIsLearner() {
+			return false
+		}
+	}
+	return true
+}
+
 // DiffRegionKeyInfo return the difference of key info between two RegionInfo
 func DiffRegionKeyInfo(origin *RegionInfo, other *RegionInfo) string {
 	var ret []string
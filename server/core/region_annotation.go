@@ -0,0 +1,146 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pingcap/pd/v4/pkg/cache"
+)
+
+const (
+	// RegionAnnotationTTL is how long the annotation of a region is kept
+	// since it is updated.
+	RegionAnnotationTTL = 24 * time.Hour
+	// maxAnnotatedRegions is the max number of the annotated regions, beyond
+	// which the least recently updated ones are evicted.
+	maxAnnotatedRegions = 100000
+)
+
+// FinishedOperatorAnnotation is the last operator finished on a region.
+type FinishedOperatorAnnotation struct {
+	Desc       string    `json:"desc"`
+	Creator    string    `json:"creator"`
+	Result     string    `json:"result"`
+	FinishTime time.Time `json:"finish_time"`
+}
+
+// RejectedOperatorAnnotation is the last operator failed to be added on a
+// region.
+type RejectedOperatorAnnotation struct {
+	Desc    string    `json:"desc"`
+	Creator string    `json:"creator"`
+	Reason  string    `json:"reason"`
+	Time    time.Time `json:"time"`
+}
+
+// RegionAnnotation is what PD did to a region lately.
+type RegionAnnotation struct {
+	LastFinished *FinishedOperatorAnnotation `json:"last_finished,omitempty"`
+	LastRejected *RejectedOperatorAnnotation `json:"last_rejected,omitempty"`
+	updateTime   time.Time
+}
+
+func (a *RegionAnnotation) isExpired(now time.Time) bool {
+	return now.Sub(a.updateTime) > RegionAnnotationTTL
+}
+
+// RegionAnnotations keeps the annotations of the regions. The annotation of a
+// region is evicted after RegionAnnotationTTL, once the region is removed, or
+// once it is the least recently updated one of too many. It is threadsafe.
+type RegionAnnotations struct {
+	sync.RWMutex
+	annotations cache.Cache
+	now         func() time.Time
+}
+
+// NewRegionAnnotations creates a RegionAnnotations.
+func NewRegionAnnotations() *RegionAnnotations {
+	return &RegionAnnotations{
+		annotations: cache.NewCache(maxAnnotatedRegions, cache.LRUCache),
+		now:         time.Now,
+	}
+}
+
+// AnnotateFinished records the operator finished on the region.
+func (a *RegionAnnotations) AnnotateFinished(regionID uint64, desc, creator, result string) {
+	a.Lock()
+	defer a.Unlock()
+	annotation := a.updateLocked(regionID)
+	annotation.LastFinished = &FinishedOperatorAnnotation{
+		Desc:       desc,
+		Creator:    creator,
+		Result:     result,
+		FinishTime: annotation.updateTime,
+	}
+}
+
+// AnnotateRejected records the operator failed to be added on the region.
+func (a *RegionAnnotations) AnnotateRejected(regionID uint64, desc, creator, reason string) {
+	a.Lock()
+	defer a.Unlock()
+	annotation := a.updateLocked(regionID)
+	annotation.LastRejected = &RejectedOperatorAnnotation{
+		Desc:    desc,
+		Creator: creator,
+		Reason:  reason,
+		Time:    annotation.updateTime,
+	}
+}
+
+// updateLocked returns the annotation of the region to update, and makes it
+// the most recently updated one.
+func (a *RegionAnnotations) updateLocked(regionID uint64) *RegionAnnotation {
+	annotation := &RegionAnnotation{}
+	if v, ok := a.annotations.Peek(regionID); ok {
+		annotation = v.(*RegionAnnotation)
+	}
+	annotation.updateTime = a.now()
+	a.annotations.Put(regionID, annotation)
+	return annotation
+}
+
+// Get returns a copy of the annotation of the region, or nil if there is
+// none or it has expired.
+func (a *RegionAnnotations) Get(regionID uint64) *RegionAnnotation {
+	a.RLock()
+	defer a.RUnlock()
+	v, ok := a.annotations.Peek(regionID)
+	if !ok || v.(*RegionAnnotation).isExpired(a.now()) {
+		return nil
+	}
+	cp := *v.(*RegionAnnotation)
+	return &cp
+}
+
+// Remove drops the annotation of the region, which is called once the region
+// is removed, e.g. merged into another one.
+func (a *RegionAnnotations) Remove(regionID uint64) {
+	a.Lock()
+	defer a.Unlock()
+	a.annotations.Remove(regionID)
+}
+
+// GC evicts the expired annotations.
+func (a *RegionAnnotations) GC() {
+	a.Lock()
+	defer a.Unlock()
+	now := a.now()
+	for _, item := range a.annotations.Elems() {
+		if item.Value.(*RegionAnnotation).isExpired(now) {
+			a.annotations.Remove(item.Key)
+		}
+	}
+}
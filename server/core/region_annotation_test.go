@@ -0,0 +1,73 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testRegionAnnotationsSuite{})
+
+type testRegionAnnotationsSuite struct{}
+
+func (s *testRegionAnnotationsSuite) TestAnnotate(c *C) {
+	annotations := NewRegionAnnotations()
+	now := time.Now()
+	annotations.now = func() time.Time { return now }
+	c.Assert(annotations.Get(1), IsNil)
+
+	annotations.AnnotateFinished(1, "transfer-leader", "balance-leader-scheduler", "succeeded")
+	now = now.Add(time.Minute)
+	annotations.AnnotateRejected(1, "move-region", "admin", "the region epoch does not match")
+	annotation := annotations.Get(1)
+	c.Assert(annotation.LastFinished.Desc, Equals, "transfer-leader")
+	c.Assert(annotation.LastFinished.FinishTime.Equal(now.Add(-time.Minute)), IsTrue)
+	c.Assert(annotation.LastRejected.Reason, Equals, "the region epoch does not match")
+	c.Assert(annotation.LastRejected.Time.Equal(now), IsTrue)
+	annotations.AnnotateFinished(1, "merge-region", "merge-checker", "timeout")
+	c.Assert(annotations.Get(1).LastFinished.Desc, Equals, "merge-region")
+	c.Assert(annotations.Get(1).LastRejected, NotNil)
+
+	annotations.Remove(1)
+	c.Assert(annotations.Get(1), IsNil)
+}
+
+func (s *testRegionAnnotationsSuite) TestEvict(c *C) {
+	annotations := NewRegionAnnotations()
+	now := time.Now()
+	annotations.now = func() time.Time { return now }
+	annotations.AnnotateFinished(1, "transfer-leader", "admin", "succeeded")
+	now = now.Add(RegionAnnotationTTL)
+	annotations.AnnotateFinished(2, "transfer-leader", "admin", "succeeded")
+	c.Assert(annotations.Get(1), NotNil)
+
+	// The expired annotation is not readable, and is evicted by GC.
+	now = now.Add(time.Second)
+	c.Assert(annotations.Get(1), IsNil)
+	c.Assert(annotations.Get(2), NotNil)
+	annotations.GC()
+	c.Assert(annotations.annotations.Len(), Equals, 1)
+
+	// The least recently updated one is evicted once it is full.
+	for i := uint64(3); i < maxAnnotatedRegions+3; i++ {
+		now = now.Add(time.Millisecond)
+		annotations.AnnotateRejected(i, "add-peer", "admin", "exceed the max number of the waiting operators")
+	}
+	c.Assert(annotations.annotations.Len(), Equals, maxAnnotatedRegions)
+	c.Assert(annotations.Get(2), IsNil)
+	c.Assert(annotations.Get(3), NotNil)
+	c.Assert(annotations.Get(maxAnnotatedRegions+2), NotNil)
+}
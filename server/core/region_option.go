@@ -195,6 +195,13 @@ func SetReportInterval(v uint64) RegionCreateOption {
 	}
 }
 
+// SetTerm sets the raft term of the leader for the region.
+func SetTerm(term uint64) RegionCreateOption {
+	return func(region *RegionInfo) {
+		region.term = term
+	}
+}
+
 // SetRegionConfVer sets the config version for the reigon.
 func SetRegionConfVer(confVer uint64) RegionCreateOption {
 	return func(region *RegionInfo) {
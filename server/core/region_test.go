@@ -149,6 +149,171 @@ func BenchmarkRandomRegion(b *testing.B) {
 	}
 }
 
+var _ = Suite(&testRegionsInfoSuite{})
+
+type testRegionsInfoSuite struct{}
+
+func (s *testRegionsInfoSuite) TestUpdateRegionFlow(c *C) {
+	regions := NewRegionsInfo()
+	peers := []*metapb.Peer{
+		{Id: 11, StoreId: 1},
+		{Id: 12, StoreId: 2},
+		{Id: 13, StoreId: 3, IsLearner: true},
+	}
+	origin := NewRegionInfo(&metapb.Region{
+		Id:          1,
+		StartKey:    []byte("a"),
+		EndKey:      []byte("b"),
+		Peers:       peers,
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}, peers[0], SetApproximateSize(10), SetApproximateKeys(100))
+	regions.SetRegion(origin)
+
+	region := origin.Clone(SetWrittenBytes(1024), SetReadKeys(10))
+	c.Assert(IsOnlyFlowChanged(origin, region), IsTrue)
+	for _, other := range []*RegionInfo{
+		origin.Clone(SetApproximateSize(20)),
+		origin.Clone(SetApproximateKeys(200)),
+		origin.Clone(WithLeader(peers[1])),
+		origin.Clone(WithIncVersion()),
+		origin.Clone(WithEndKey([]byte("c"))),
+		origin.Clone(SetPeers(peers[:2])),
+		origin.Clone(WithPendingPeers(peers[1:2])),
+	} {
+		c.Assert(IsOnlyFlowChanged(origin, other), IsFalse)
+	}
+
+	c.Assert(regions.UpdateRegionFlow(origin, region), IsTrue)
+	c.Assert(regions.GetRegion(1), Equals, region)
+	c.Assert(regions.SearchRegion([]byte("a")), Equals, region)
+	c.Assert(regions.GetLeader(1, region), Equals, region)
+	c.Assert(regions.GetFollower(2, region), Equals, region)
+	c.Assert(regions.RandLearnerRegion(3, []KeyRange{NewKeyRange("", "")}), Equals, region)
+	c.Assert(regions.GetRegionCount(), Equals, 1)
+	c.Assert(regions.GetStoreRegionSize(1), Equals, int64(10))
+	c.Assert(regions.GetStoreLearnerRegionSize(3), Equals, int64(10))
+
+	// The origin is not cached anymore.
+	c.Assert(regions.UpdateRegionFlow(origin, region.Clone(SetWrittenBytes(2048))), IsFalse)
+	c.Assert(regions.GetRegion(1), Equals, region)
+}
+
+func (s *testRegionsInfoSuite) TestScanStoreRange(c *C) {
+	rand.Seed(1)
+	regions := NewRegionsInfo()
+	// Store 1 has a voter in every region, the peers of the other regions are
+	// interleaved among store 2-6. Every region has a learner.
+	for i := uint64(0); i < 200; i++ {
+		stores := rand.Perm(5)
+		peers := []*metapb.Peer{
+			{Id: i*10 + 1, StoreId: 1},
+			{Id: i*10 + 2, StoreId: uint64(stores[0]) + 2},
+			{Id: i*10 + 3, StoreId: uint64(stores[1]) + 2},
+			{Id: i*10 + 4, StoreId: uint64(stores[2]) + 2, IsLearner: true},
+		}
+		region := NewRegionInfo(&metapb.Region{
+			Id:       i + 1,
+			StartKey: []byte(fmt.Sprintf("%04d", i)),
+			EndKey:   []byte(fmt.Sprintf("%04d", i+1)),
+			Peers:    peers,
+		}, peers[rand.Intn(3)])
+		regions.SetRegion(region)
+	}
+
+	roles := []PeerRole{AnyRole, LeaderRole, FollowerRole, LearnerRole}
+	for i := 0; i < 500; i++ {
+		storeID := uint64(rand.Intn(7) + 1)
+		role := roles[rand.Intn(len(roles))]
+		startKey := []byte(fmt.Sprintf("%04d", rand.Intn(220)))
+		var endKey []byte
+		if rand.Intn(4) > 0 {
+			endKey = []byte(fmt.Sprintf("%04d", rand.Intn(220)))
+		}
+		limit := rand.Intn(50) - 10
+
+		var expected []*RegionInfo
+		for _, region := range regions.ScanRange(startKey, endKey, -1) {
+			if limit > 0 && len(expected) >= limit {
+				break
+			}
+			for _, peer := range region.GetPeers() {
+				if peer.GetStoreId() != storeID {
+					continue
+				}
+				isLeader := peer.GetId() == region.GetLeader().GetId()
+				if role == AnyRole ||
+					(role == LeaderRole && isLeader) ||
+					(role == FollowerRole && !isLeader && !peer.GetIsLearner()) ||
+					(role == LearnerRole && peer.GetIsLearner()) {
+					expected = append(expected, region)
+				}
+			}
+		}
+		res := regions.ScanStoreRange(storeID, role, startKey, endKey, limit)
+		c.Assert(res, HasLen, len(expected), Commentf("store %d role %s", storeID, role))
+		for j := range res {
+			c.Assert(res[j].GetID(), Equals, expected[j].GetID())
+		}
+	}
+
+	// The store's subtrees agree with the full scan.
+	for storeID := uint64(1); storeID <= 7; storeID++ {
+		for _, role := range roles {
+			expected := make(map[uint64]struct{})
+			for _, region := range regions.ScanStoreRange(storeID, role, nil, nil, -1) {
+				expected[region.GetID()] = struct{}{}
+			}
+			res := regions.GetStoreRegionsByRole(storeID, role)
+			c.Assert(res, HasLen, len(expected), Commentf("store %d role %s", storeID, role))
+			c.Assert(regions.GetStoreRegionCountByRole(storeID, role), Equals, len(expected))
+			for _, region := range res {
+				_, ok := expected[region.GetID()]
+				c.Assert(ok, IsTrue)
+			}
+		}
+	}
+}
+
+func (s *testRegionsInfoSuite) TestRegionGaps(c *C) {
+	regions := NewRegionsInfo()
+	newRegion := func(id uint64, start, end string) *RegionInfo {
+		return NewRegionInfo(&metapb.Region{Id: id, StartKey: []byte(start), EndKey: []byte(end)}, nil)
+	}
+	c.Assert(regions.GetRegionGaps(0), HasLen, 0)
+
+	// [a, b) [b, c) _ [d, e) _
+	r1, r2, r3 := newRegion(1, "a", "b"), newRegion(2, "b", "c"), newRegion(3, "d", "e")
+	for _, region := range []*RegionInfo{r1, r2, r3} {
+		regions.SetRegion(region)
+	}
+	gaps := regions.GetRegionGaps(0)
+	c.Assert(gaps, DeepEquals, []*RegionGap{
+		{StartKey: []byte{}, EndKey: []byte("a"), NextRegionID: 1},
+		{StartKey: []byte("c"), EndKey: []byte("d"), PrevRegionID: 2, NextRegionID: 3},
+		{StartKey: []byte("e"), EndKey: []byte{}, PrevRegionID: 3},
+	})
+	c.Assert(regions.GetRegionGaps(2), DeepEquals, gaps[:2])
+
+	prev, next := regions.GetAdjacentRegionGaps(r1)
+	c.Assert(prev, DeepEquals, gaps[0])
+	c.Assert(next, IsNil)
+	prev, next = regions.GetAdjacentRegionGaps(r2)
+	c.Assert(prev, IsNil)
+	c.Assert(next, DeepEquals, gaps[1])
+	prev, next = regions.GetAdjacentRegionGaps(r3)
+	c.Assert(prev, DeepEquals, gaps[1])
+	c.Assert(next, DeepEquals, gaps[2])
+
+	// Fill the gaps.
+	regions.SetRegion(newRegion(4, "", "a"))
+	regions.SetRegion(newRegion(5, "c", "d"))
+	regions.SetRegion(newRegion(6, "e", ""))
+	c.Assert(regions.GetRegionGaps(0), HasLen, 0)
+	prev, next = regions.GetAdjacentRegionGaps(r3)
+	c.Assert(prev, IsNil)
+	c.Assert(next, IsNil)
+}
+
 const keyLength = 100
 
 func randomBytes(n int) []byte {
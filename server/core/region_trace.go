@@ -0,0 +1,140 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxTracedRegions is the max number of regions traced at the same time.
+	maxTracedRegions = 8
+	// maxTraceEvents is the max number of events kept for a region, the
+	// oldest events are dropped when it is exceeded.
+	maxTraceEvents = 256
+)
+
+// TraceEvent is a scheduling decision made on a traced region.
+type TraceEvent struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Event  string    `json:"event"`
+}
+
+// RegionTrace is the collected scheduling decisions of a region.
+type RegionTrace struct {
+	RegionID   uint64        `json:"region_id"`
+	ExpireTime time.Time     `json:"expire_time"`
+	Events     []*TraceEvent `json:"events"`
+}
+
+func (t *RegionTrace) isExpired(now time.Time) bool {
+	return !now.Before(t.ExpireTime)
+}
+
+// RegionTracer collects the scheduling decisions of a few regions for
+// debugging. It is threadsafe and costs little when no region is traced.
+type RegionTracer struct {
+	sync.RWMutex
+	// count is the number of traces, it is used to skip recording quickly.
+	count  int32
+	traces map[uint64]*RegionTrace
+}
+
+// NewRegionTracer creates a RegionTracer.
+func NewRegionTracer() *RegionTracer {
+	return &RegionTracer{traces: make(map[uint64]*RegionTrace)}
+}
+
+// Enable starts tracing the region until the ttl expires. The events
+// collected before are dropped.
+func (t *RegionTracer) Enable(regionID uint64, ttl time.Duration) error {
+	t.Lock()
+	defer t.Unlock()
+	now := time.Now()
+	if _, ok := t.traces[regionID]; !ok && len(t.traces) >= maxTracedRegions {
+		// Evict the expired traces to make room.
+		for id, trace := range t.traces {
+			if trace.isExpired(now) {
+				delete(t.traces, id)
+			}
+		}
+		if len(t.traces) >= maxTracedRegions {
+			return errors.Errorf("too many traced regions, at most %d", maxTracedRegions)
+		}
+	}
+	t.traces[regionID] = &RegionTrace{RegionID: regionID, ExpireTime: now.Add(ttl)}
+	atomic.StoreInt32(&t.count, int32(len(t.traces)))
+	return nil
+}
+
+// Disable stops tracing the region and drops the collected events.
+func (t *RegionTracer) Disable(regionID uint64) {
+	t.Lock()
+	defer t.Unlock()
+	delete(t.traces, regionID)
+	atomic.StoreInt32(&t.count, int32(len(t.traces)))
+}
+
+// IsTracing returns true if the region is being traced.
+func (t *RegionTracer) IsTracing(regionID uint64) bool {
+	if t == nil || atomic.LoadInt32(&t.count) == 0 {
+		return false
+	}
+	t.RLock()
+	defer t.RUnlock()
+	trace, ok := t.traces[regionID]
+	return ok && !trace.isExpired(time.Now())
+}
+
+// Record appends an event to the trace of the region if it is being traced.
+func (t *RegionTracer) Record(regionID uint64, source string, format string, args ...interface{}) {
+	if t == nil || atomic.LoadInt32(&t.count) == 0 {
+		return
+	}
+	t.Lock()
+	defer t.Unlock()
+	trace, ok := t.traces[regionID]
+	now := time.Now()
+	if !ok || trace.isExpired(now) {
+		return
+	}
+	if len(trace.Events) >= maxTraceEvents {
+		trace.Events = trace.Events[1:]
+	}
+	trace.Events = append(trace.Events, &TraceEvent{
+		Time:   now,
+		Source: source,
+		Event:  fmt.Sprintf(format, args...),
+	})
+}
+
+// GetTrace returns a copy of the region's trace in chronological order. The
+// trace is still available after it expires until it is evicted.
+func (t *RegionTracer) GetTrace(regionID uint64) *RegionTrace {
+	t.RLock()
+	defer t.RUnlock()
+	trace, ok := t.traces[regionID]
+	if !ok {
+		return nil
+	}
+	events := make([]*TraceEvent, len(trace.Events))
+	copy(events, trace.Events)
+	return &RegionTrace{RegionID: trace.RegionID, ExpireTime: trace.ExpireTime, Events: events}
+}
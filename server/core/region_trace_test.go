@@ -0,0 +1,66 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testRegionTracerSuite{})
+
+type testRegionTracerSuite struct{}
+
+func (s *testRegionTracerSuite) TestRecord(c *C) {
+	tracer := NewRegionTracer()
+	tracer.Record(1, "test", "not traced")
+	c.Assert(tracer.GetTrace(1), IsNil)
+
+	c.Assert(tracer.Enable(1, time.Minute), IsNil)
+	c.Assert(tracer.IsTracing(1), IsTrue)
+	c.Assert(tracer.IsTracing(2), IsFalse)
+	for i := 0; i < maxTraceEvents+10; i++ {
+		tracer.Record(1, "test", "event %d", i)
+	}
+	tracer.Record(2, "test", "not traced")
+	trace := tracer.GetTrace(1)
+	c.Assert(trace.Events, HasLen, maxTraceEvents)
+	c.Assert(trace.Events[0].Event, Equals, "event 10")
+	c.Assert(trace.Events[maxTraceEvents-1].Source, Equals, "test")
+	c.Assert(tracer.GetTrace(2), IsNil)
+
+	tracer.Disable(1)
+	c.Assert(tracer.IsTracing(1), IsFalse)
+	c.Assert(tracer.GetTrace(1), IsNil)
+}
+
+func (s *testRegionTracerSuite) TestExpire(c *C) {
+	tracer := NewRegionTracer()
+	for i := 0; i < maxTracedRegions; i++ {
+		c.Assert(tracer.Enable(uint64(i), time.Minute), IsNil)
+	}
+	c.Assert(tracer.Enable(maxTracedRegions, time.Minute), NotNil)
+	// Enabling a traced region again is allowed.
+	c.Assert(tracer.Enable(0, 0), IsNil)
+
+	// The expired trace stops collecting but is still readable.
+	tracer.Record(0, "test", "expired")
+	c.Assert(tracer.IsTracing(0), IsFalse)
+	c.Assert(tracer.GetTrace(0).Events, HasLen, 0)
+
+	// The expired trace is evicted for a new one.
+	c.Assert(tracer.Enable(maxTracedRegions, time.Minute), IsNil)
+	c.Assert(tracer.GetTrace(0), IsNil)
+}
@@ -104,6 +104,19 @@ func (t *regionTree) update(region *RegionInfo) []*RegionInfo {
 	return overlaps
 }
 
+// replace replaces the region in the tree in place. It does nothing and
+// returns false if the tree does not have a region with the same ID and range.
+func (t *regionTree) replace(region *RegionInfo) bool {
+	result := t.find(region)
+	if result == nil || result.region.GetID() != region.GetID() ||
+		!bytes.Equal(result.region.GetStartKey(), region.GetStartKey()) ||
+		!bytes.Equal(result.region.GetEndKey(), region.GetEndKey()) {
+		return false
+	}
+	result.region = region
+	return true
+}
+
 // remove removes a region if the region is in the tree.
 // It will do nothing if it cannot find the region or the found region
 // is not the same with the region.
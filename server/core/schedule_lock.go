@@ -0,0 +1,179 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"go.uber.org/zap"
+)
+
+// ScheduleLock protects a key range from being merged or balanced for a while,
+// e.g. during the split, scatter and ingest pipeline of importing data.
+type ScheduleLock struct {
+	ID          uint64    `json:"id"`
+	StartKey    []byte    `json:"-"`
+	StartKeyHex string    `json:"start_key"`
+	EndKey      []byte    `json:"-"`
+	EndKeyHex   string    `json:"end_key"`
+	ExpireTime  time.Time `json:"expire_time"`
+}
+
+// IsExpired returns true if the lock is expired at the given time.
+func (l *ScheduleLock) IsExpired(now time.Time) bool {
+	return !now.Before(l.ExpireTime)
+}
+
+// Overlaps returns true if the lock overlaps with the range [startKey, endKey).
+// An empty end key means the range is unbounded.
+func (l *ScheduleLock) Overlaps(startKey, endKey []byte) bool {
+	return (len(endKey) == 0 || bytes.Compare(l.StartKey, endKey) < 0) &&
+		(len(l.EndKey) == 0 || bytes.Compare(startKey, l.EndKey) < 0)
+}
+
+// ScheduleLocks manages the key range schedule locks. It is threadsafe.
+type ScheduleLocks struct {
+	sync.RWMutex
+	storage *Storage
+	locks   map[uint64]*ScheduleLock
+}
+
+// NewScheduleLocks creates a ScheduleLocks instance.
+func NewScheduleLocks(storage *Storage) *ScheduleLocks {
+	return &ScheduleLocks{
+		storage: storage,
+		locks:   make(map[uint64]*ScheduleLock),
+	}
+}
+
+// Load loads the locks from storage. Expired locks are dropped.
+func (s *ScheduleLocks) Load() error {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now()
+	var expired []uint64
+	err := s.storage.LoadScheduleLocks(func(k, v string) {
+		var lock ScheduleLock
+		var err error
+		if err = json.Unmarshal([]byte(v), &lock); err != nil {
+			log.Error("failed to unmarshal schedule lock", zap.String("lock-key", k), zap.String("lock-value", v))
+			return
+		}
+		if lock.StartKey, err = hex.DecodeString(lock.StartKeyHex); err != nil {
+			log.Error("failed to decode schedule lock start key", zap.String("lock-key", k), zap.String("lock-value", v))
+			return
+		}
+		if lock.EndKey, err = hex.DecodeString(lock.EndKeyHex); err != nil {
+			log.Error("failed to decode schedule lock end key", zap.String("lock-key", k), zap.String("lock-value", v))
+			return
+		}
+		if lock.IsExpired(now) {
+			expired = append(expired, lock.ID)
+			return
+		}
+		s.locks[lock.ID] = &lock
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range expired {
+		if err := s.storage.DeleteScheduleLock(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddLock persists and adds a lock.
+func (s *ScheduleLocks) AddLock(lock *ScheduleLock) error {
+	s.Lock()
+	defer s.Unlock()
+	lock.StartKeyHex, lock.EndKeyHex = hex.EncodeToString(lock.StartKey), hex.EncodeToString(lock.EndKey)
+	if err := s.storage.SaveScheduleLock(lock.ID, lock); err != nil {
+		return err
+	}
+	s.locks[lock.ID] = lock
+	log.Info("schedule lock added", zap.Uint64("lock-id", lock.ID),
+		zap.String("start-key", HexRegionKeyStr(lock.StartKey)),
+		zap.String("end-key", HexRegionKeyStr(lock.EndKey)),
+		zap.Time("expire-time", lock.ExpireTime))
+	return nil
+}
+
+// RemoveLock releases a lock. It returns false if the lock does not exist.
+func (s *ScheduleLocks) RemoveLock(id uint64) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.locks[id]; !ok {
+		return false, nil
+	}
+	if err := s.storage.DeleteScheduleLock(id); err != nil {
+		return false, err
+	}
+	delete(s.locks, id)
+	log.Info("schedule lock removed", zap.Uint64("lock-id", id))
+	return true, nil
+}
+
+// GetLocks returns all the unexpired locks sorted by ID.
+func (s *ScheduleLocks) GetLocks() []*ScheduleLock {
+	s.RLock()
+	defer s.RUnlock()
+	now := time.Now()
+	locks := make([]*ScheduleLock, 0, len(s.locks))
+	for _, lock := range s.locks {
+		if !lock.IsExpired(now) {
+			locks = append(locks, lock)
+		}
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].ID < locks[j].ID })
+	return locks
+}
+
+// IsRegionLocked returns true if the region overlaps with any unexpired lock.
+func (s *ScheduleLocks) IsRegionLocked(region *RegionInfo) bool {
+	s.RLock()
+	defer s.RUnlock()
+	now := time.Now()
+	for _, lock := range s.locks {
+		if !lock.IsExpired(now) && lock.Overlaps(region.GetStartKey(), region.GetEndKey()) {
+			return true
+		}
+	}
+	return false
+}
+
+// GCExpiredLocks removes the expired locks from memory and storage.
+func (s *ScheduleLocks) GCExpiredLocks() error {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now()
+	for id, lock := range s.locks {
+		if !lock.IsExpired(now) {
+			continue
+		}
+		if err := s.storage.DeleteScheduleLock(id); err != nil {
+			return err
+		}
+		delete(s.locks, id)
+		log.Info("schedule lock expired", zap.Uint64("lock-id", id))
+	}
+	return nil
+}
@@ -17,6 +17,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"path"
 	"strconv"
@@ -38,6 +39,20 @@ const (
 	schedulePath = "schedule"
 	gcPath       = "gc"
 	rulesPath    = "rules"
+	lockPath     = "schedule_lock"
+	quotaPath    = "operator_quota"
+	windowPath   = "maintenance_window"
+	reportPath   = "scheduling_report"
+	capacityPath = "capacity_sample"
+	restartPath  = "rolling_restart"
+	templatePath = "operator_template"
+	tsoResetPath = "tso_reset_history"
+	limitPath    = "store_limit"
+	lineagePath  = "region_lineage"
+	offlinePath  = "batch_offline"
+	pinPath      = "leader_pin"
+	historyPath  = "store_history"
+	splitPath    = "split_range"
 
 	customScheduleConfigPath = "scheduler_config"
 	componentsConfigPath     = "components_config"
@@ -106,6 +121,14 @@ func (s *Storage) storeRegionWeightPath(storeID uint64) string {
 	return path.Join(schedulePath, "store_weight", fmt.Sprintf("%020d", storeID), "region")
 }
 
+func (s *Storage) storeAnnotationPath(storeID uint64) string {
+	return path.Join(clusterPath, "store_annotation", fmt.Sprintf("%020d", storeID))
+}
+
+func (s *Storage) storeSpaceFloorPath(storeID uint64) string {
+	return path.Join(clusterPath, "store_space_floor", fmt.Sprintf("%020d", storeID))
+}
+
 // SaveScheduleConfig saves the config of scheduler.
 func (s *Storage) SaveScheduleConfig(scheduleName string, data []byte) error {
 	configPath := path.Join(customScheduleConfigPath, scheduleName)
@@ -124,6 +147,52 @@ func (s *Storage) LoadScheduleConfig(scheduleName string) (string, error) {
 	return s.Load(configPath)
 }
 
+// LoadScheduleConfigRevision returns the revision of the persisted config of
+// the scheduler.
+func (s *Storage) LoadScheduleConfigRevision(scheduleName string) (string, error) {
+	return s.loadRevision(path.Join(customScheduleConfigPath, scheduleName))
+}
+
+// LoadConfigRevision returns the revision of the persisted config.
+func (s *Storage) LoadConfigRevision() (string, error) {
+	return s.loadRevision(configPath)
+}
+
+// LoadRulesRevision returns the revision of all the persisted placement rules.
+func (s *Storage) LoadRulesRevision() (string, error) {
+	h := fnv.New64a()
+	found := false
+	if _, err := s.LoadRules(func(k, v string) {
+		found = true
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}); err != nil {
+		return "", err
+	}
+	if !found {
+		return "0", nil
+	}
+	return strconv.FormatUint(h.Sum64(), 16), nil
+}
+
+// loadRevision returns the revision of the value persisted at the key, which is
+// the hash of the value, so it changes on every write changing the value and
+// is the same on all the PD servers. The revision of an absent value is "0".
+func (s *Storage) loadRevision(key string) (string, error) {
+	value, err := s.Load(key)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "0", nil
+	}
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	return strconv.FormatUint(h.Sum64(), 16), nil
+}
+
 // LoadMeta loads cluster meta from storage.
 func (s *Storage) LoadMeta(meta *metapb.Cluster) (bool, error) {
 	return loadProto(s.Base, clusterPath, meta)
@@ -146,6 +215,12 @@ func (s *Storage) SaveStore(store *metapb.Store) error {
 
 // DeleteStore deletes one store from storage.
 func (s *Storage) DeleteStore(store *metapb.Store) error {
+	if err := s.Remove(s.storeAnnotationPath(store.GetId())); err != nil {
+		return err
+	}
+	if err := s.Remove(s.storeSpaceFloorPath(store.GetId())); err != nil {
+		return err
+	}
 	return s.Remove(s.storePath(store.GetId()))
 }
 
@@ -260,6 +335,453 @@ func (s *Storage) LoadRules(f func(k, v string)) (bool, error) {
 	}
 }
 
+// SaveScheduleLock stores a schedule lock to the lockPath.
+func (s *Storage) SaveScheduleLock(id uint64, lock interface{}) error {
+	value, err := json.Marshal(lock)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(path.Join(lockPath, fmt.Sprintf("%020d", id)), string(value))
+}
+
+// DeleteScheduleLock removes a schedule lock from storage.
+func (s *Storage) DeleteScheduleLock(id uint64) error {
+	return s.Base.Remove(path.Join(lockPath, fmt.Sprintf("%020d", id)))
+}
+
+// LoadScheduleLocks loads schedule locks from storage.
+func (s *Storage) LoadScheduleLocks(f func(k, v string)) error {
+	nextKey := path.Join(lockPath, "\x00")
+	endKey := lockPath + "0"
+	for {
+		keys, values, err := s.LoadRange(nextKey, endKey, minKVRangeLimit)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			f(strings.TrimPrefix(keys[i], lockPath+"/"), values[i])
+		}
+		if len(keys) < minKVRangeLimit {
+			return nil
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
+	}
+}
+
+// SaveOperatorQuota stores the operator quota of a consumer to the quotaPath.
+func (s *Storage) SaveOperatorQuota(consumer string, quota interface{}) error {
+	value, err := json.Marshal(quota)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(path.Join(quotaPath, consumer), string(value))
+}
+
+// DeleteOperatorQuota removes the operator quota of a consumer from storage.
+func (s *Storage) DeleteOperatorQuota(consumer string) error {
+	return s.Base.Remove(path.Join(quotaPath, consumer))
+}
+
+// LoadOperatorQuotas loads the operator quotas from storage.
+func (s *Storage) LoadOperatorQuotas(f func(k, v string)) error {
+	nextKey := path.Join(quotaPath, "\x00")
+	endKey := quotaPath + "0"
+	for {
+		keys, values, err := s.LoadRange(nextKey, endKey, minKVRangeLimit)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			f(strings.TrimPrefix(keys[i], quotaPath+"/"), values[i])
+		}
+		if len(keys) < minKVRangeLimit {
+			return nil
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
+	}
+}
+
+// SaveMaintenanceWindow stores a maintenance window to the windowPath.
+func (s *Storage) SaveMaintenanceWindow(name string, window interface{}) error {
+	value, err := json.Marshal(window)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(path.Join(windowPath, name), string(value))
+}
+
+// DeleteMaintenanceWindow removes a maintenance window from storage.
+func (s *Storage) DeleteMaintenanceWindow(name string) error {
+	return s.Base.Remove(path.Join(windowPath, name))
+}
+
+// LoadMaintenanceWindows loads the maintenance windows from storage.
+func (s *Storage) LoadMaintenanceWindows(f func(k, v string)) error {
+	nextKey := path.Join(windowPath, "\x00")
+	endKey := windowPath + "0"
+	for {
+		keys, values, err := s.LoadRange(nextKey, endKey, minKVRangeLimit)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			f(strings.TrimPrefix(keys[i], windowPath+"/"), values[i])
+		}
+		if len(keys) < minKVRangeLimit {
+			return nil
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
+	}
+}
+
+// SaveSchedulingReport stores the scheduling report of a day to the reportPath.
+func (s *Storage) SaveSchedulingReport(date string, report interface{}) error {
+	value, err := json.Marshal(report)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(path.Join(reportPath, date), string(value))
+}
+
+// LoadSchedulingReport loads the scheduling report of a day from storage.
+func (s *Storage) LoadSchedulingReport(date string, report interface{}) (bool, error) {
+	value, err := s.Load(path.Join(reportPath, date))
+	if err != nil {
+		return false, err
+	}
+	if value == "" {
+		return false, nil
+	}
+	err = json.Unmarshal([]byte(value), report)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+// DeleteSchedulingReport removes the scheduling report of a day from storage.
+func (s *Storage) DeleteSchedulingReport(date string) error {
+	return s.Base.Remove(path.Join(reportPath, date))
+}
+
+// LoadSchedulingReports loads the scheduling reports from the date on.
+func (s *Storage) LoadSchedulingReports(fromDate string, f func(k, v string)) error {
+	return s.loadDated(reportPath, fromDate, f)
+}
+
+// SaveCapacitySample stores the capacity sample of a day to the capacityPath.
+func (s *Storage) SaveCapacitySample(date string, sample interface{}) error {
+	value, err := json.Marshal(sample)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(path.Join(capacityPath, date), string(value))
+}
+
+// DeleteCapacitySample removes the capacity sample of a day from storage.
+func (s *Storage) DeleteCapacitySample(date string) error {
+	return s.Base.Remove(path.Join(capacityPath, date))
+}
+
+// LoadCapacitySamples loads the capacity samples from the date on.
+func (s *Storage) LoadCapacitySamples(fromDate string, f func(k, v string)) error {
+	return s.loadDated(capacityPath, fromDate, f)
+}
+
+// SaveStoreLimit stores the manual limit of a store to the limitPath.
+func (s *Storage) SaveStoreLimit(storeID uint64, limit interface{}) error {
+	value, err := json.Marshal(limit)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(path.Join(limitPath, fmt.Sprintf("%020d", storeID)), string(value))
+}
+
+// DeleteStoreLimit removes the manual limit of a store from storage.
+func (s *Storage) DeleteStoreLimit(storeID uint64) error {
+	return s.Base.Remove(path.Join(limitPath, fmt.Sprintf("%020d", storeID)))
+}
+
+// LoadStoreLimits loads the manual store limits from storage.
+func (s *Storage) LoadStoreLimits(f func(k, v string)) error {
+	nextKey := path.Join(limitPath, "\x00")
+	endKey := limitPath + "0"
+	for {
+		keys, values, err := s.LoadRange(nextKey, endKey, minKVRangeLimit)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			f(strings.TrimPrefix(keys[i], limitPath+"/"), values[i])
+		}
+		if len(keys) < minKVRangeLimit {
+			return nil
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
+	}
+}
+
+// SaveRegionLineageEvent stores a region lineage event to the lineagePath.
+func (s *Storage) SaveRegionLineageEvent(id uint64, event interface{}) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(path.Join(lineagePath, fmt.Sprintf("%020d", id)), string(value))
+}
+
+// DeleteRegionLineageEvent removes a region lineage event from storage.
+func (s *Storage) DeleteRegionLineageEvent(id uint64) error {
+	return s.Base.Remove(path.Join(lineagePath, fmt.Sprintf("%020d", id)))
+}
+
+// LoadRegionLineageEvents loads the region lineage events from storage, in
+// the order of the ID.
+func (s *Storage) LoadRegionLineageEvents(f func(k, v string)) error {
+	nextKey := path.Join(lineagePath, "\x00")
+	endKey := lineagePath + "0"
+	for {
+		keys, values, err := s.LoadRange(nextKey, endKey, minKVRangeLimit)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			f(strings.TrimPrefix(keys[i], lineagePath+"/"), values[i])
+		}
+		if len(keys) < minKVRangeLimit {
+			return nil
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
+	}
+}
+
+// SaveOperatorTemplate stores an operator template to the templatePath.
+func (s *Storage) SaveOperatorTemplate(name string, template interface{}) error {
+	value, err := json.Marshal(template)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(path.Join(templatePath, name), string(value))
+}
+
+// DeleteOperatorTemplate removes an operator template from storage.
+func (s *Storage) DeleteOperatorTemplate(name string) error {
+	return s.Base.Remove(path.Join(templatePath, name))
+}
+
+// LoadOperatorTemplates loads the operator templates from storage.
+func (s *Storage) LoadOperatorTemplates(f func(k, v string)) error {
+	nextKey := path.Join(templatePath, "\x00")
+	endKey := templatePath + "0"
+	for {
+		keys, values, err := s.LoadRange(nextKey, endKey, minKVRangeLimit)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			f(strings.TrimPrefix(keys[i], templatePath+"/"), values[i])
+		}
+		if len(keys) < minKVRangeLimit {
+			return nil
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
+	}
+}
+
+// SaveRollingRestart stores the rolling restart to the restartPath.
+func (s *Storage) SaveRollingRestart(restart interface{}) error {
+	value, err := json.Marshal(restart)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(restartPath, string(value))
+}
+
+// LoadRollingRestart loads the rolling restart from storage.
+func (s *Storage) LoadRollingRestart(restart interface{}) (bool, error) {
+	value, err := s.Load(restartPath)
+	if err != nil {
+		return false, err
+	}
+	if value == "" {
+		return false, nil
+	}
+	err = json.Unmarshal([]byte(value), restart)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+// SaveBatchOffline stores the batch offline to the offlinePath.
+func (s *Storage) SaveBatchOffline(offline interface{}) error {
+	value, err := json.Marshal(offline)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(offlinePath, string(value))
+}
+
+// LoadBatchOffline loads the batch offline from storage.
+func (s *Storage) LoadBatchOffline(offline interface{}) (bool, error) {
+	value, err := s.Load(offlinePath)
+	if err != nil {
+		return false, err
+	}
+	if value == "" {
+		return false, nil
+	}
+	err = json.Unmarshal([]byte(value), offline)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+// SaveLeaderPin stores the leader pin of a region to the pinPath.
+func (s *Storage) SaveLeaderPin(regionID uint64, pin interface{}) error {
+	value, err := json.Marshal(pin)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(path.Join(pinPath, fmt.Sprintf("%020d", regionID)), string(value))
+}
+
+// DeleteLeaderPin removes the leader pin of a region from storage.
+func (s *Storage) DeleteLeaderPin(regionID uint64) error {
+	return s.Base.Remove(path.Join(pinPath, fmt.Sprintf("%020d", regionID)))
+}
+
+// LoadLeaderPins loads the leader pins from storage.
+func (s *Storage) LoadLeaderPins(f func(k, v string)) error {
+	nextKey := path.Join(pinPath, "\x00")
+	endKey := pinPath + "0"
+	for {
+		keys, values, err := s.LoadRange(nextKey, endKey, minKVRangeLimit)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			f(strings.TrimPrefix(keys[i], pinPath+"/"), values[i])
+		}
+		if len(keys) < minKVRangeLimit {
+			return nil
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
+	}
+}
+
+// SaveStoreHistory stores the lifecycle record of a store to the historyPath.
+func (s *Storage) SaveStoreHistory(storeID uint64, record interface{}) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(path.Join(historyPath, fmt.Sprintf("%020d", storeID)), string(value))
+}
+
+// DeleteStoreHistory removes the lifecycle record of a store from storage.
+func (s *Storage) DeleteStoreHistory(storeID uint64) error {
+	return s.Base.Remove(path.Join(historyPath, fmt.Sprintf("%020d", storeID)))
+}
+
+// LoadStoreHistories loads the lifecycle records of the stores from storage.
+func (s *Storage) LoadStoreHistories(f func(k, v string)) error {
+	nextKey := path.Join(historyPath, "\x00")
+	endKey := historyPath + "0"
+	for {
+		keys, values, err := s.LoadRange(nextKey, endKey, minKVRangeLimit)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			f(strings.TrimPrefix(keys[i], historyPath+"/"), values[i])
+		}
+		if len(keys) < minKVRangeLimit {
+			return nil
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
+	}
+}
+
+// SaveSplitRangeJob stores a split-range job to the splitPath.
+func (s *Storage) SaveSplitRangeJob(id uint64, job interface{}) error {
+	value, err := json.Marshal(job)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(path.Join(splitPath, fmt.Sprintf("%020d", id)), string(value))
+}
+
+// DeleteSplitRangeJob removes a split-range job from storage.
+func (s *Storage) DeleteSplitRangeJob(id uint64) error {
+	return s.Base.Remove(path.Join(splitPath, fmt.Sprintf("%020d", id)))
+}
+
+// LoadSplitRangeJobs loads the split-range jobs from storage.
+func (s *Storage) LoadSplitRangeJobs(f func(k, v string)) error {
+	nextKey := path.Join(splitPath, "\x00")
+	endKey := splitPath + "0"
+	for {
+		keys, values, err := s.LoadRange(nextKey, endKey, minKVRangeLimit)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			f(strings.TrimPrefix(keys[i], splitPath+"/"), values[i])
+		}
+		if len(keys) < minKVRangeLimit {
+			return nil
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
+	}
+}
+
+// SaveTSOResetHistory stores the TSO reset history to the tsoResetPath.
+func (s *Storage) SaveTSOResetHistory(history interface{}) error {
+	value, err := json.Marshal(history)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(tsoResetPath, string(value))
+}
+
+// LoadTSOResetHistory loads the TSO reset history from storage.
+func (s *Storage) LoadTSOResetHistory(history interface{}) (bool, error) {
+	value, err := s.Load(tsoResetPath)
+	if err != nil {
+		return false, err
+	}
+	if value == "" {
+		return false, nil
+	}
+	err = json.Unmarshal([]byte(value), history)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+// loadDated loads the values keyed by the date under the prefix from the date
+// on, in the order of the date.
+func (s *Storage) loadDated(prefix, fromDate string, f func(k, v string)) error {
+	nextKey := path.Join(prefix, fromDate)
+	endKey := prefix + "0"
+	for {
+		keys, values, err := s.LoadRange(nextKey, endKey, minKVRangeLimit)
+		if err != nil {
+			return err
+		}
+		for i := range keys {
+			f(strings.TrimPrefix(keys[i], prefix+"/"), values[i])
+		}
+		if len(keys) < minKVRangeLimit {
+			return nil
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
+	}
+}
+
 // LoadStores loads all stores from storage to StoresInfo.
 func (s *Storage) LoadStores(f func(store *StoreInfo)) error {
 	nextID := uint64(0)
@@ -283,7 +805,16 @@ func (s *Storage) LoadStores(f func(store *StoreInfo)) error {
 			if err != nil {
 				return err
 			}
-			newStoreInfo := NewStoreInfo(store, SetLeaderWeight(leaderWeight), SetRegionWeight(regionWeight))
+			annotation, err := s.loadStoreAnnotation(store.GetId())
+			if err != nil {
+				return err
+			}
+			spaceFloor, err := s.loadStoreSpaceFloor(store.GetId())
+			if err != nil {
+				return err
+			}
+			newStoreInfo := NewStoreInfo(store, SetLeaderWeight(leaderWeight), SetRegionWeight(regionWeight),
+				SetStoreAnnotation(annotation), SetStoreSpaceFloor(spaceFloor))
 
 			nextID = store.GetId() + 1
 			f(newStoreInfo)
@@ -304,6 +835,56 @@ func (s *Storage) SaveStoreWeight(storeID uint64, leader, region float64) error
 	return s.Save(s.storeRegionWeightPath(storeID), regionValue)
 }
 
+// SaveStoreAnnotation saves a store's annotation to storage. An empty
+// annotation removes the saved one.
+func (s *Storage) SaveStoreAnnotation(storeID uint64, annotation *StoreAnnotation) error {
+	if annotation.IsEmpty() {
+		return s.Remove(s.storeAnnotationPath(storeID))
+	}
+	value, err := json.Marshal(annotation)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(s.storeAnnotationPath(storeID), string(value))
+}
+
+func (s *Storage) loadStoreAnnotation(storeID uint64) (*StoreAnnotation, error) {
+	value, err := s.Load(s.storeAnnotationPath(storeID))
+	if err != nil || value == "" {
+		return nil, err
+	}
+	annotation := &StoreAnnotation{}
+	if err := json.Unmarshal([]byte(value), annotation); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return annotation, nil
+}
+
+// SaveStoreSpaceFloor saves the minimum free space reserved on a store to
+// storage. An empty floor removes the saved one.
+func (s *Storage) SaveStoreSpaceFloor(storeID uint64, floor *StoreSpaceFloor) error {
+	if floor.IsEmpty() {
+		return s.Remove(s.storeSpaceFloorPath(storeID))
+	}
+	value, err := json.Marshal(floor)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.Save(s.storeSpaceFloorPath(storeID), string(value))
+}
+
+func (s *Storage) loadStoreSpaceFloor(storeID uint64) (*StoreSpaceFloor, error) {
+	value, err := s.Load(s.storeSpaceFloorPath(storeID))
+	if err != nil || value == "" {
+		return nil, err
+	}
+	floor := &StoreSpaceFloor{}
+	if err := json.Unmarshal([]byte(value), floor); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return floor, nil
+}
+
 func (s *Storage) loadFloatWithDefaultValue(path string, def float64) (float64, error) {
 	res, err := s.Load(path)
 	if err != nil {
@@ -359,13 +940,25 @@ func (s *Storage) LoadGCSafePoint() (uint64, error) {
 	return safePoint, nil
 }
 
-// LoadAllScheduleConfig loads all schedulers' config.
+// LoadAllScheduleConfig loads all schedulers' config, including the configs
+// which fail to be decoded by the schedulers.
 func (s *Storage) LoadAllScheduleConfig() ([]string, []string, error) {
-	keys, values, err := s.LoadRange(customScheduleConfigPath, clientv3.GetPrefixRangeEnd(customScheduleConfigPath), 1000)
-	for i, key := range keys {
-		keys[i] = strings.TrimPrefix(key, customScheduleConfigPath+"/")
+	nextKey, endKey := customScheduleConfigPath, clientv3.GetPrefixRangeEnd(customScheduleConfigPath)
+	var names, configs []string
+	for {
+		keys, values, err := s.LoadRange(nextKey, endKey, minKVRangeLimit)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range keys {
+			names = append(names, strings.TrimPrefix(keys[i], customScheduleConfigPath+"/"))
+			configs = append(configs, values[i])
+		}
+		if len(keys) < minKVRangeLimit {
+			return names, configs, nil
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
 	}
-	return keys, values, err
 }
 
 // SaveComponentsConfig stores marshalable cfg to the componentsConfigPath.
@@ -14,9 +14,14 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/juju/ratelimit"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/pd/v4/server/kv"
@@ -118,6 +123,70 @@ func (s *testKVSuite) TestStoreWeight(c *C) {
 	}
 }
 
+func (s *testKVSuite) TestStoreAnnotation(c *C) {
+	storage := NewStorage(kv.NewMemoryKV())
+	const n = 3
+
+	stores := mustSaveStores(c, storage, n)
+	annotation := &StoreAnnotation{Nickname: "tikv-rack3-slot2", Metadata: map[string]string{"owner": "infra"}}
+	c.Assert(storage.SaveStoreAnnotation(1, annotation), IsNil)
+	cache := NewStoresInfo()
+	c.Assert(storage.LoadStores(cache.SetStore), IsNil)
+	c.Assert(cache.GetStore(0).GetAnnotation(), IsNil)
+	c.Assert(cache.GetStore(1).GetAnnotation(), DeepEquals, annotation)
+
+	// The annotation is removed together with the store.
+	c.Assert(storage.DeleteStore(stores[1]), IsNil)
+	c.Assert(storage.SaveStore(stores[1]), IsNil)
+	cache = NewStoresInfo()
+	c.Assert(storage.LoadStores(cache.SetStore), IsNil)
+	c.Assert(cache.GetStore(1).GetAnnotation(), IsNil)
+}
+
+func (s *testKVSuite) TestValidateStoreAnnotation(c *C) {
+	c.Assert((&StoreAnnotation{Nickname: "机架-3"}).Validate(), IsNil)
+	c.Assert((&StoreAnnotation{Nickname: strings.Repeat("a", maxStoreNicknameLength+1)}).Validate(), NotNil)
+	c.Assert((&StoreAnnotation{Nickname: "\xff"}).Validate(), NotNil)
+	c.Assert((&StoreAnnotation{Metadata: map[string]string{"": "a"}}).Validate(), NotNil)
+	c.Assert((&StoreAnnotation{Metadata: map[string]string{"sn": strings.Repeat("a", maxStoreMetadataValLength+1)}}).Validate(), NotNil)
+}
+
+func (s *testKVSuite) TestScheduleLocks(c *C) {
+	storage := NewStorage(kv.NewMemoryKV())
+	locks := NewScheduleLocks(storage)
+	c.Assert(locks.AddLock(&ScheduleLock{ID: 1, StartKey: []byte("a"), EndKey: []byte("c"), ExpireTime: time.Now().Add(time.Hour)}), IsNil)
+	c.Assert(locks.AddLock(&ScheduleLock{ID: 2, StartKey: []byte("x"), ExpireTime: time.Now().Add(time.Hour)}), IsNil)
+	c.Assert(locks.AddLock(&ScheduleLock{ID: 3, StartKey: []byte("m"), EndKey: []byte("n"), ExpireTime: time.Now().Add(-time.Second)}), IsNil)
+	c.Assert(locks.GetLocks(), HasLen, 2)
+
+	newRegion := func(start, end string) *RegionInfo {
+		return NewRegionInfo(&metapb.Region{StartKey: []byte(start), EndKey: []byte(end)}, nil)
+	}
+	c.Assert(locks.IsRegionLocked(newRegion("b", "d")), IsTrue)
+	c.Assert(locks.IsRegionLocked(newRegion("c", "d")), IsFalse)
+	c.Assert(locks.IsRegionLocked(newRegion("", "a")), IsFalse)
+	c.Assert(locks.IsRegionLocked(newRegion("y", "")), IsTrue)
+	c.Assert(locks.IsRegionLocked(newRegion("m", "n")), IsFalse)
+
+	// The locks survive a reload and the expired one is dropped.
+	locks = NewScheduleLocks(storage)
+	c.Assert(locks.Load(), IsNil)
+	c.Assert(locks.GetLocks(), HasLen, 2)
+	c.Assert(locks.GetLocks()[0].StartKey, DeepEquals, []byte("a"))
+	c.Assert(locks.IsRegionLocked(newRegion("b", "d")), IsTrue)
+
+	ok, err := locks.RemoveLock(1)
+	c.Assert(ok, IsTrue)
+	c.Assert(err, IsNil)
+	ok, err = locks.RemoveLock(1)
+	c.Assert(ok, IsFalse)
+	c.Assert(err, IsNil)
+	locks = NewScheduleLocks(storage)
+	c.Assert(locks.Load(), IsNil)
+	c.Assert(locks.GetLocks(), HasLen, 1)
+	c.Assert(locks.IsRegionLocked(newRegion("b", "d")), IsFalse)
+}
+
 func mustSaveRegions(c *C, s *Storage, n int) []*metapb.Region {
 	regions := make([]*metapb.Region, 0, n)
 	for i := 0; i < n; i++ {
@@ -178,6 +247,25 @@ func (s *testKVSuite) TestLoadRegionsExceedRangeLimit(c *C) {
 	}
 }
 
+func (s *testKVSuite) TestLoadAllScheduleConfig(c *C) {
+	storage := NewStorage(kv.NewMemoryKV())
+	n := minKVRangeLimit*2 + 10
+	for i := 0; i < n; i++ {
+		c.Assert(storage.SaveScheduleConfig(fmt.Sprintf("scheduler-%03d", i), []byte(strconv.Itoa(i))), IsNil)
+	}
+	// The configs are not decoded, so the broken ones are loaded too.
+	c.Assert(storage.SaveScheduleConfig("scheduler-broken", []byte("{")), IsNil)
+	names, configs, err := storage.LoadAllScheduleConfig()
+	c.Assert(err, IsNil)
+	c.Assert(names, HasLen, n+1)
+	for i := 0; i < n; i++ {
+		c.Assert(names[i], Equals, fmt.Sprintf("scheduler-%03d", i))
+		c.Assert(configs[i], Equals, strconv.Itoa(i))
+	}
+	c.Assert(names[n], Equals, "scheduler-broken")
+	c.Assert(configs[n], Equals, "{")
+}
+
 func (s *testKVSuite) TestLoadGCSafePoint(c *C) {
 	storage := NewStorage(kv.NewMemoryKV())
 	testData := []uint64{0, 1, 2, 233, 2333, 23333333333, math.MaxUint64}
@@ -194,6 +282,82 @@ func (s *testKVSuite) TestLoadGCSafePoint(c *C) {
 	}
 }
 
+func (s *testKVSuite) TestScanUsage(c *C) {
+	base := kv.NewMemoryKV()
+	storage := NewStorage(base)
+	n := minKVRangeLimit*2 + 10
+	regions := mustSaveRegions(c, storage, n)
+	for id := uint64(1); id <= 3; id++ {
+		c.Assert(storage.SaveStore(&metapb.Store{Id: id}), IsNil)
+	}
+	c.Assert(storage.SaveStoreWeight(1, 2, 3), IsNil)
+	c.Assert(storage.SaveScheduleConfig("balance-leader-scheduler", []byte("{}")), IsNil)
+	c.Assert(storage.SaveGCSafePoint(100), IsNil)
+	c.Assert(storage.SaveRule("pd/default", map[string]string{"id": "default"}), IsNil)
+	c.Assert(base.Save("raft", "meta"), IsNil)
+	c.Assert(base.Save("unknown/key", "value"), IsNil)
+	c.Assert(base.Save("schedule_locked", "value"), IsNil)
+
+	// Sums up the keys by the prefix they are expected under.
+	expected := make(map[string]*StoragePrefixUsage)
+	var total int64
+	keys, values, err := base.LoadRange("\x00", "\xff", maxKVRangeLimit)
+	c.Assert(err, IsNil)
+	for i, key := range keys {
+		var prefix string
+		switch {
+		case strings.HasPrefix(key, "raft/r/"):
+			prefix = "raft/r"
+		case strings.HasPrefix(key, "raft/s/"):
+			prefix = "raft/s"
+		case strings.HasPrefix(key, "schedule/store_weight/"):
+			prefix = "schedule/store_weight"
+		case strings.HasPrefix(key, "scheduler_config/"):
+			prefix = "scheduler_config"
+		case key == "raft", key == "gc/safe_point", strings.HasPrefix(key, "rules/"):
+			prefix = strings.Split(key, "/")[0]
+		default:
+			prefix = StorageUsageOther
+		}
+		if expected[prefix] == nil {
+			expected[prefix] = &StoragePrefixUsage{Prefix: prefix}
+		}
+		expected[prefix].Keys++
+		expected[prefix].Bytes += int64(len(key) + len(values[i]))
+		total += int64(len(key) + len(values[i]))
+	}
+
+	usage, err := storage.ScanUsage(context.Background(), ratelimit.NewBucketWithRate(1<<30, 1<<30))
+	c.Assert(err, IsNil)
+	c.Assert(usage.Keys, Equals, len(keys))
+	c.Assert(usage.Bytes, Equals, total)
+	c.Assert(usage.Prefixes, HasLen, len(expected))
+	for i, u := range usage.Prefixes {
+		if i > 0 {
+			c.Assert(usage.Prefixes[i-1].Bytes >= u.Bytes, IsTrue)
+		}
+		c.Assert(u.Keys, Equals, expected[u.Prefix].Keys, Commentf(u.Prefix))
+		c.Assert(u.Bytes, Equals, expected[u.Prefix].Bytes, Commentf(u.Prefix))
+		if u.Keys < storageUsageTopKeys {
+			c.Assert(u.LargestKeys, HasLen, u.Keys)
+		} else {
+			c.Assert(u.LargestKeys, HasLen, storageUsageTopKeys)
+		}
+		for j := 1; j < len(u.LargestKeys); j++ {
+			c.Assert(u.LargestKeys[j-1].Bytes >= u.LargestKeys[j].Bytes, IsTrue)
+		}
+	}
+	c.Assert(usage.Prefixes[0].Prefix, Equals, "raft/r")
+	c.Assert(usage.Prefixes[0].Keys, Equals, len(regions))
+	c.Assert(expected[StorageUsageOther].Keys, Equals, 2)
+
+	// The scan is aborted while it waits for the limit.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = storage.ScanUsage(ctx, ratelimit.NewBucketWithRate(1, 1))
+	c.Assert(err, Equals, context.Canceled)
+}
+
 type KVWithMaxRangeLimit struct {
 	kv.Base
 	rangeLimit int
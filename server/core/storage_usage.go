@@ -0,0 +1,172 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juju/ratelimit"
+)
+
+const (
+	// storageUsagePageSize is the number of keys loaded at a time.
+	storageUsagePageSize = minKVRangeLimit
+	// storageUsageTopKeys is the number of the largest keys kept per prefix.
+	storageUsageTopKeys = 5
+	// StorageUsageOther is the prefix of the keys not under any known prefix.
+	StorageUsageOther = "other"
+)
+
+// storageUsagePrefixes are the prefixes of the keys of PD. A key is counted
+// by the longest prefix it is under.
+var storageUsagePrefixes = []string{
+	clusterPath,
+	path.Join(clusterPath, "s"),
+	path.Join(clusterPath, "r"),
+	path.Join(clusterPath, "status"),
+	path.Join(clusterPath, "store_annotation"),
+	path.Join(clusterPath, "store_space_floor"),
+	configPath,
+	schedulePath,
+	path.Join(schedulePath, "store_weight"),
+	customScheduleConfigPath,
+	componentsConfigPath,
+	gcPath,
+	rulesPath,
+	lockPath,
+	quotaPath,
+	windowPath,
+	reportPath,
+	capacityPath,
+	restartPath,
+	templatePath,
+	tsoResetPath,
+	limitPath,
+	lineagePath,
+	offlinePath,
+	pinPath,
+	"timestamp",
+	"leader",
+	"alloc_id",
+	"member",
+}
+
+// StorageKeyUsage is the space used by a key.
+type StorageKeyUsage struct {
+	Key   string `json:"key"`
+	Bytes int64  `json:"bytes"`
+}
+
+// StoragePrefixUsage is the space used by the keys under a prefix. The bytes
+// are the total size of the keys and the values.
+type StoragePrefixUsage struct {
+	Prefix      string             `json:"prefix"`
+	Keys        int                `json:"keys"`
+	Bytes       int64              `json:"bytes"`
+	LargestKeys []*StorageKeyUsage `json:"largest_keys"`
+}
+
+func (u *StoragePrefixUsage) add(key string, bytes int64) {
+	u.Keys++
+	u.Bytes += bytes
+	i := sort.Search(len(u.LargestKeys), func(i int) bool { return u.LargestKeys[i].Bytes < bytes })
+	if i >= storageUsageTopKeys {
+		return
+	}
+	u.LargestKeys = append(u.LargestKeys, nil)
+	copy(u.LargestKeys[i+1:], u.LargestKeys[i:])
+	u.LargestKeys[i] = &StorageKeyUsage{Key: key, Bytes: bytes}
+	if len(u.LargestKeys) > storageUsageTopKeys {
+		u.LargestKeys = u.LargestKeys[:storageUsageTopKeys]
+	}
+}
+
+// StorageUsage is the space used by the keys of PD, broken down by the
+// prefixes. The prefixes without any key are omitted.
+type StorageUsage struct {
+	ScanTime     time.Time             `json:"scan_time"`
+	ScanDuration string                `json:"scan_duration"`
+	Keys         int                   `json:"keys"`
+	Bytes        int64                 `json:"bytes"`
+	Prefixes     []*StoragePrefixUsage `json:"prefixes"`
+}
+
+func storageUsagePrefix(key string) string {
+	prefix := StorageUsageOther
+	for _, p := range storageUsagePrefixes {
+		if (key == p || strings.HasPrefix(key, p+"/")) && (prefix == StorageUsageOther || len(p) > len(prefix)) {
+			prefix = p
+		}
+	}
+	return prefix
+}
+
+// ScanUsage walks all the keys of PD in the kv base, and counts the space they
+// use by the prefixes. The scan takes the loaded bytes from the limit, so
+// that it does not put too much load on the kv base. The regions saved in the
+// region storage are not counted.
+func (s *Storage) ScanUsage(ctx context.Context, limit *ratelimit.Bucket) (*StorageUsage, error) {
+	start := time.Now()
+	prefixes := make(map[string]*StoragePrefixUsage)
+	usage := &StorageUsage{ScanTime: start}
+	nextKey, endKey := "\x00", "\xff"
+	for {
+		keys, values, err := s.LoadRange(nextKey, endKey, storageUsagePageSize)
+		if err != nil {
+			return nil, err
+		}
+		var loaded int64
+		for i := range keys {
+			bytes := int64(len(keys[i]) + len(values[i]))
+			loaded += bytes
+			prefix := storageUsagePrefix(keys[i])
+			u, ok := prefixes[prefix]
+			if !ok {
+				u = &StoragePrefixUsage{Prefix: prefix}
+				prefixes[prefix] = u
+			}
+			u.add(keys[i], bytes)
+			usage.Keys++
+			usage.Bytes += bytes
+		}
+		if len(keys) < storageUsagePageSize {
+			break
+		}
+		nextKey = keys[len(keys)-1] + "\x00"
+		if limit == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(limit.Take(loaded)):
+		}
+	}
+	usage.Prefixes = make([]*StoragePrefixUsage, 0, len(prefixes))
+	for _, u := range prefixes {
+		usage.Prefixes = append(usage.Prefixes, u)
+	}
+	sort.Slice(usage.Prefixes, func(i, j int) bool {
+		if usage.Prefixes[i].Bytes != usage.Prefixes[j].Bytes {
+			return usage.Prefixes[i].Bytes > usage.Prefixes[j].Bytes
+		}
+		return usage.Prefixes[i].Prefix < usage.Prefixes[j].Prefix
+	})
+	usage.ScanDuration = time.Since(start).String()
+	return usage, nil
+}
@@ -24,12 +24,17 @@ import (
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/pkg/typeutil"
 	"go.uber.org/zap"
 )
 
 // Interval to save store meta (including heartbeat ts) to etcd.
 const storePersistInterval = 5 * time.Minute
 
+// IOCapacityLabel is the label of a store whose value is the disk throughput
+// of the store in bytes per second, such as "500MiB".
+const IOCapacityLabel = "io-capacity"
+
 // StoreInfo contains information about a store.
 type StoreInfo struct {
 	meta  *metapb.Store
@@ -44,6 +49,9 @@ type StoreInfo struct {
 	lastPersistTime  time.Time
 	leaderWeight     float64
 	regionWeight     float64
+	annotation       *StoreAnnotation
+	spaceFloor       *StoreSpaceFloor
+	restarts         *StoreRestarts
 	available        func() bool
 }
 
@@ -76,6 +84,9 @@ func (s *StoreInfo) Clone(opts ...StoreCreateOption) *StoreInfo {
 		lastPersistTime:  s.lastPersistTime,
 		leaderWeight:     s.leaderWeight,
 		regionWeight:     s.regionWeight,
+		annotation:       s.annotation,
+		spaceFloor:       s.spaceFloor,
+		restarts:         s.restarts,
 		available:        s.available,
 	}
 
@@ -123,6 +134,27 @@ func (s *StoreInfo) GetMeta() *metapb.Store {
 	return s.meta
 }
 
+// GetAnnotation returns the human-friendly annotation of the store.
+func (s *StoreInfo) GetAnnotation() *StoreAnnotation {
+	return s.annotation
+}
+
+// GetSpaceFloor returns the minimum free space reserved on the store, or nil
+// if there is none.
+func (s *StoreInfo) GetSpaceFloor() *StoreSpaceFloor {
+	return s.spaceFloor
+}
+
+// GetRestarts returns the restarts of the store, or nil if there is none.
+func (s *StoreInfo) GetRestarts() *StoreRestarts {
+	return s.restarts
+}
+
+// IsFlapping returns true if the store restarts too frequently recently.
+func (s *StoreInfo) IsFlapping() bool {
+	return s.restarts.IsFlapping(time.Now())
+}
+
 // GetState returns the state of the store.
 func (s *StoreInfo) GetState() metapb.StoreState {
 	return s.meta.GetState()
@@ -332,6 +364,15 @@ func (s *StoreInfo) IsLowSpace(lowSpaceRatio float64) bool {
 	return s.GetStoreStats() != nil && s.AvailableRatio() < 1-lowSpaceRatio
 }
 
+// IsBelowSpaceFloor checks if the free space of the store is below the space
+// reserved on it.
+func (s *StoreInfo) IsBelowSpaceFloor() bool {
+	if s.spaceFloor.IsEmpty() || s.GetStoreStats() == nil || s.GetCapacity() == 0 {
+		return false
+	}
+	return s.GetAvailable() < s.spaceFloor.Bytes(s.GetCapacity())
+}
+
 // ResourceCount returns count of leader/region in the store.
 func (s *StoreInfo) ResourceCount(kind ResourceKind) uint64 {
 	switch kind {
@@ -432,6 +473,42 @@ func (s *StoreInfo) GetLabelValue(key string) string {
 	return ""
 }
 
+// GetIOCapacity returns the disk throughput of the store in bytes per second
+// by the label, or 0 if it is not labeled or the value is invalid.
+func (s *StoreInfo) GetIOCapacity() uint64 {
+	value := s.GetLabelValue(IOCapacityLabel)
+	if value == "" {
+		return 0
+	}
+	var capacity typeutil.ByteSize
+	if err := capacity.UnmarshalText([]byte(value)); err != nil {
+		return 0
+	}
+	return uint64(capacity)
+}
+
+// IOCapacityFactors returns the ratios of the IO capacities of the stores to
+// the average of the labeled ones. The stores not labeled are regarded as the
+// average ones, so the ratios are all 1 if none is labeled.
+func IOCapacityFactors(stores []*StoreInfo) map[uint64]float64 {
+	var sum float64
+	var count int
+	for _, store := range stores {
+		if capacity := store.GetIOCapacity(); capacity > 0 {
+			sum += float64(capacity)
+			count++
+		}
+	}
+	factors := make(map[uint64]float64, len(stores))
+	for _, store := range stores {
+		factors[store.GetID()] = 1
+		if capacity := store.GetIOCapacity(); capacity > 0 {
+			factors[store.GetID()] = float64(capacity) * float64(count) / sum
+		}
+	}
+	return factors
+}
+
 // CompareLocation compares 2 stores' labels and returns at which level their
 // locations are different. It returns -1 if they are at the same location.
 func (s *StoreInfo) CompareLocation(other *StoreInfo, labels []string) int {
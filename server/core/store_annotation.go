@@ -0,0 +1,71 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	maxStoreNicknameLength    = 64
+	maxStoreMetadataCount     = 16
+	maxStoreMetadataKeyLength = 64
+	maxStoreMetadataValLength = 256
+)
+
+// StoreAnnotation is the human-friendly information attached to a store. It
+// is only used for display and is ignored by the schedulers.
+type StoreAnnotation struct {
+	Nickname string            `json:"nickname,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// IsEmpty returns true if there is nothing in the annotation.
+func (a *StoreAnnotation) IsEmpty() bool {
+	return a == nil || (a.Nickname == "" && len(a.Metadata) == 0)
+}
+
+// Validate checks if the annotation is valid UTF-8 and within the length limits.
+func (a *StoreAnnotation) Validate() error {
+	if err := validateAnnotationString("nickname", a.Nickname, maxStoreNicknameLength); err != nil {
+		return err
+	}
+	if len(a.Metadata) > maxStoreMetadataCount {
+		return errors.Errorf("too many metadata entries, at most %d", maxStoreMetadataCount)
+	}
+	for k, v := range a.Metadata {
+		if k == "" {
+			return errors.New("metadata key should not be empty")
+		}
+		if err := validateAnnotationString("metadata key", k, maxStoreMetadataKeyLength); err != nil {
+			return err
+		}
+		if err := validateAnnotationString("metadata value", v, maxStoreMetadataValLength); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateAnnotationString(name, s string, maxLength int) error {
+	if !utf8.ValidString(s) {
+		return errors.Errorf("%s %q is not valid UTF-8", name, s)
+	}
+	if utf8.RuneCountInString(s) > maxLength {
+		return errors.Errorf("%s %q is too long, at most %d characters", name, s, maxLength)
+	}
+	return nil
+}
@@ -162,3 +162,24 @@ func SetAvailableFunc(f func() bool) StoreCreateOption {
 		store.available = f
 	}
 }
+
+// SetStoreRestarts sets the restarts of the store.
+func SetStoreRestarts(restarts *StoreRestarts) StoreCreateOption {
+	return func(store *StoreInfo) {
+		store.restarts = restarts
+	}
+}
+
+// SetStoreAnnotation sets the human-friendly annotation for the store.
+func SetStoreAnnotation(annotation *StoreAnnotation) StoreCreateOption {
+	return func(store *StoreInfo) {
+		store.annotation = annotation
+	}
+}
+
+// SetStoreSpaceFloor sets the minimum free space reserved on the store.
+func SetStoreSpaceFloor(floor *StoreSpaceFloor) StoreCreateOption {
+	return func(store *StoreInfo) {
+		store.spaceFloor = floor
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import "time"
+
+// maxStoreRestartHistory is the max number of the recent restarts kept for a
+// store, unless more are needed to detect the flapping.
+const maxStoreRestartHistory = 16
+
+// StoreRestarts is the restarts of a store observed since PD became the
+// leader. It is immutable.
+type StoreRestarts struct {
+	// Count is the number of all the observed restarts.
+	Count int
+	// History is the time of the recent restarts, the oldest first.
+	History []time.Time
+	// FlappingUntil is the time until which the store is flapping.
+	FlappingUntil time.Time
+}
+
+// Record returns the restarts with a new restart at the given time. The store
+// is flapping if it restarts more than limit times within the window, until
+// it stays up for the cooldown. 0 limit means never flapping.
+func (r *StoreRestarts) Record(t time.Time, limit uint64, window, cooldown time.Duration) *StoreRestarts {
+	restarts := &StoreRestarts{Count: 1}
+	if r != nil {
+		restarts.Count = r.Count + 1
+		restarts.FlappingUntil = r.FlappingUntil
+		restarts.History = append(restarts.History, r.History...)
+	}
+	restarts.History = append(restarts.History, t)
+	maxHistory := maxStoreRestartHistory
+	if int(limit)+1 > maxHistory {
+		maxHistory = int(limit) + 1
+	}
+	if len(restarts.History) > maxHistory {
+		restarts.History = restarts.History[len(restarts.History)-maxHistory:]
+	}
+	if limit == 0 {
+		return restarts
+	}
+	var recent uint64
+	for _, restart := range restarts.History {
+		if t.Sub(restart) < window {
+			recent++
+		}
+	}
+	// A flapping store restarting again needs to stay up for another cooldown.
+	if recent > limit || t.Before(restarts.FlappingUntil) {
+		restarts.FlappingUntil = t.Add(cooldown)
+	}
+	return restarts
+}
+
+// GetLastRestart returns the time of the last restart, or the zero time if
+// there is no restart.
+func (r *StoreRestarts) GetLastRestart() time.Time {
+	if r == nil || len(r.History) == 0 {
+		return time.Time{}
+	}
+	return r.History[len(r.History)-1]
+}
+
+// IsFlapping returns true if the store is flapping at the given time.
+func (r *StoreRestarts) IsFlapping(t time.Time) bool {
+	return r != nil && t.Before(r.FlappingUntil)
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import "github.com/pkg/errors"
+
+// StoreSpaceFloor is the minimum free space reserved on a store, below which
+// the store is never a target of the new replicas, regardless of the global
+// low-space-ratio. The larger one of the bytes and the percent applies.
+type StoreSpaceFloor struct {
+	MinFreeBytes uint64 `json:"min-free-bytes,omitempty"`
+	// MinFreePercent is the percent of the capacity, in [0, 100].
+	MinFreePercent float64 `json:"min-free-percent,omitempty"`
+}
+
+// IsEmpty returns true if no space is reserved.
+func (f *StoreSpaceFloor) IsEmpty() bool {
+	return f == nil || (f.MinFreeBytes == 0 && f.MinFreePercent == 0)
+}
+
+// Validate checks if the percent is in range.
+func (f *StoreSpaceFloor) Validate() error {
+	if f.MinFreePercent < 0 || f.MinFreePercent > 100 {
+		return errors.Errorf("min-free-percent %v should be in [0, 100]", f.MinFreePercent)
+	}
+	return nil
+}
+
+// Bytes returns the minimum free bytes of the store with the capacity.
+func (f *StoreSpaceFloor) Bytes(capacity uint64) uint64 {
+	if f.IsEmpty() {
+		return 0
+	}
+	floor := uint64(float64(capacity) * f.MinFreePercent / 100)
+	if f.MinFreeBytes > floor {
+		return f.MinFreeBytes
+	}
+	return floor
+}
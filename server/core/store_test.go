@@ -19,6 +19,7 @@ import (
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
 )
 
 var _ = Suite(&testDistinctScoreSuite{})
@@ -92,3 +93,137 @@ func (s *testConcurrencySuite) TestCloneStore(c *C) {
 	}()
 	wg.Wait()
 }
+
+var _ = Suite(&testStoreRestartsSuite{})
+
+type testStoreRestartsSuite struct{}
+
+func (s *testStoreRestartsSuite) TestRecord(c *C) {
+	var restarts *StoreRestarts
+	start := time.Now()
+	c.Assert(restarts.GetLastRestart().IsZero(), IsTrue)
+	c.Assert(restarts.IsFlapping(start), IsFalse)
+
+	// 3 restarts within the window do not exceed the limit.
+	for i := 0; i < 3; i++ {
+		restarts = restarts.Record(start.Add(time.Duration(i)*4*time.Minute), 3, 10*time.Minute, 30*time.Minute)
+		c.Assert(restarts.IsFlapping(start.Add(time.Duration(i)*4*time.Minute)), IsFalse)
+	}
+	// The first restart is out of the window.
+	t := start.Add(12 * time.Minute)
+	restarts = restarts.Record(t, 3, 10*time.Minute, 30*time.Minute)
+	c.Assert(restarts.IsFlapping(t), IsFalse)
+	t = t.Add(time.Minute)
+	restarts = restarts.Record(t, 3, 10*time.Minute, 30*time.Minute)
+	c.Assert(restarts.Count, Equals, 5)
+	c.Assert(restarts.GetLastRestart(), Equals, t)
+	c.Assert(restarts.IsFlapping(t), IsTrue)
+	c.Assert(restarts.FlappingUntil, Equals, t.Add(30*time.Minute))
+
+	// Restarting again during the cooldown restarts the cooldown.
+	t = t.Add(20 * time.Minute)
+	restarts = restarts.Record(t, 3, 10*time.Minute, 30*time.Minute)
+	c.Assert(restarts.FlappingUntil, Equals, t.Add(30*time.Minute))
+	c.Assert(restarts.IsFlapping(t.Add(29*time.Minute)), IsTrue)
+	c.Assert(restarts.IsFlapping(t.Add(30*time.Minute)), IsFalse)
+
+	// The history is bounded.
+	for i := 0; i < maxStoreRestartHistory*2; i++ {
+		restarts = restarts.Record(t.Add(time.Hour), 0, 0, 0)
+	}
+	c.Assert(restarts.History, HasLen, maxStoreRestartHistory)
+	c.Assert(restarts.Count, Equals, 6+maxStoreRestartHistory*2)
+}
+
+var _ = Suite(&testPeerLocationSuite{})
+
+type testPeerLocationSuite struct{}
+
+func (s *testPeerLocationSuite) TestLocationScore(c *C) {
+	labels := []string{"zone", "rack", "host"}
+	location := []*metapb.StoreLabel{{Key: "zone", Value: "z1"}, {Key: "rack", Value: "r1"}, {Key: "host", Value: "h1"}}
+	testCases := []struct {
+		storeLabels map[string]string
+		score       int
+	}{
+		{map[string]string{"zone": "z1", "rack": "r1", "host": "h1"}, 3},
+		{map[string]string{"zone": "Z1", "rack": "r1", "host": "h2"}, 2},
+		{map[string]string{"zone": "z1", "rack": "r2", "host": "h1"}, 1},
+		{map[string]string{"zone": "z2", "rack": "r1", "host": "h1"}, 0},
+		// A missing level stops the match.
+		{map[string]string{"zone": "z1", "host": "h1"}, 1},
+		{map[string]string{"rack": "r1", "host": "h1"}, 0},
+		{nil, 0},
+	}
+	for _, t := range testCases {
+		store := NewStoreInfoWithLabel(1, 1, t.storeLabels)
+		c.Assert(store.LocationScore(labels, location), Equals, t.score)
+	}
+	store := NewStoreInfoWithLabel(1, 1, map[string]string{"zone": "z1", "rack": "r1", "host": "h1"})
+	c.Assert(store.LocationScore(labels, location[:1]), Equals, 1)
+	c.Assert(store.LocationScore(labels, location[1:]), Equals, 0)
+}
+
+func (s *testPeerLocationSuite) TestRankPeersByLocation(c *C) {
+	stores := map[uint64]*StoreInfo{
+		1: NewStoreInfoWithLabel(1, 1, map[string]string{"zone": "z1", "rack": "r1", "host": "h1"}),
+		2: NewStoreInfoWithLabel(2, 1, map[string]string{"zone": "z1", "rack": "r2", "host": "h2"}),
+		3: NewStoreInfoWithLabel(3, 1, map[string]string{"zone": "z2", "rack": "r1", "host": "h3"}),
+		4: NewStoreInfoWithLabel(4, 1, map[string]string{"zone": "z1", "rack": "r1", "host": "h4"}),
+		5: NewStoreInfoWithLabel(5, 1, map[string]string{"zone": "z1", "rack": "r1", "host": "h5"}),
+		6: NewStoreInfoWithLabel(6, 1, map[string]string{"zone": "z1", "rack": "r1", "host": "h6"}),
+	}
+	stores[6] = stores[6].Clone(SetStoreState(metapb.StoreState_Tombstone))
+	getStore := func(id uint64) *StoreInfo { return stores[id] }
+	var peers []*metapb.Peer
+	for id := uint64(1); id <= 7; id++ {
+		peers = append(peers, &metapb.Peer{Id: id * 10, StoreId: id})
+	}
+	region := NewRegionInfo(&metapb.Region{Id: 1, Peers: peers}, peers[0],
+		WithDownPeers([]*pdpb.PeerStats{{Peer: peers[3]}}),
+		WithPendingPeers([]*metapb.Peer{peers[4]}))
+
+	labels := []string{"zone", "rack", "host"}
+	location := []*metapb.StoreLabel{{Key: "zone", Value: "z1"}, {Key: "rack", Value: "r2"}}
+	ranked := RankPeersByLocation(region, getStore, labels, location)
+	// The down, pending, tombstone and unknown ones are excluded.
+	c.Assert(ranked, HasLen, 3)
+	c.Assert(ranked[0].Peer.GetStoreId(), Equals, uint64(2))
+	c.Assert(ranked[0].Score, Equals, 2)
+	c.Assert(ranked[1].Peer.GetStoreId(), Equals, uint64(1))
+	c.Assert(ranked[1].Score, Equals, 1)
+	c.Assert(ranked[2].Peer.GetStoreId(), Equals, uint64(3))
+	c.Assert(ranked[2].Score, Equals, 0)
+
+	// Without the labels, the levels follow the location.
+	location = []*metapb.StoreLabel{{Key: "rack", Value: "r1"}, {Key: "zone", Value: "z2"}}
+	ranked = RankPeersByLocation(region, getStore, nil, location)
+	c.Assert(ranked, HasLen, 3)
+	c.Assert(ranked[0].Peer.GetStoreId(), Equals, uint64(3))
+	c.Assert(ranked[0].Score, Equals, 2)
+	c.Assert(ranked[1].Peer.GetStoreId(), Equals, uint64(1))
+	c.Assert(ranked[1].Score, Equals, 1)
+	c.Assert(ranked[2].Peer.GetStoreId(), Equals, uint64(2))
+	c.Assert(ranked[2].Score, Equals, 0)
+}
+
+var _ = Suite(&testIOCapacitySuite{})
+
+type testIOCapacitySuite struct{}
+
+func (s *testIOCapacitySuite) TestIOCapacityFactors(c *C) {
+	stores := []*StoreInfo{
+		NewStoreInfoWithLabel(1, 1, map[string]string{IOCapacityLabel: "400MiB"}),
+		NewStoreInfoWithLabel(2, 1, map[string]string{IOCapacityLabel: "200MiB"}),
+		NewStoreInfoWithLabel(3, 1, nil),
+		NewStoreInfoWithLabel(4, 1, map[string]string{IOCapacityLabel: "fast"}),
+	}
+	c.Assert(stores[0].GetIOCapacity(), Equals, uint64(400<<20))
+	c.Assert(stores[2].GetIOCapacity(), Equals, uint64(0))
+	c.Assert(stores[3].GetIOCapacity(), Equals, uint64(0))
+
+	factors := IOCapacityFactors(stores)
+	c.Assert(factors, DeepEquals, map[uint64]float64{1: 4.0 / 3, 2: 2.0 / 3, 3: 1, 4: 1})
+	factors = IOCapacityFactors(stores[2:])
+	c.Assert(factors, DeepEquals, map[uint64]float64{3: 1, 4: 1})
+}
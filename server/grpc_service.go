@@ -24,10 +24,14 @@ import (
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/pkg/grpcutil"
 	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"github.com/pingcap/pd/v4/server/tso"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -90,8 +94,12 @@ func (s *Server) Tso(stream pdpb.PD_TsoServer) error {
 		count := request.GetCount()
 		ts, err := s.tso.GetRespTS(count)
 		if err != nil {
+			if errors.Cause(err) == tso.ErrPaused {
+				return status.Errorf(codes.Unavailable, err.Error())
+			}
 			return status.Errorf(codes.Unknown, err.Error())
 		}
+		s.tsoRequests.Add(1)
 
 		elapsed := time.Since(start)
 		if elapsed > slowThreshold {
@@ -125,6 +133,15 @@ func (s *Server) Bootstrap(ctx context.Context, request *pdpb.BootstrapRequest)
 			Header: s.errorHeader(err),
 		}, nil
 	}
+	if err := checkBootstrapVersion(request.GetStore(), s.scheduleOpt.LoadPDServerConfig().MinBootstrapVersion); err != nil {
+		log.Warn("reject bootstrapping the cluster", zap.Error(err))
+		return &pdpb.BootstrapResponse{
+			Header: s.errorHeader(&pdpb.Error{
+				Type:    pdpb.ErrorType_INCOMPATIBLE_VERSION,
+				Message: err.Error(),
+			}),
+		}, nil
+	}
 	if _, err := s.bootstrapCluster(request); err != nil {
 		return nil, status.Errorf(codes.Unknown, err.Error())
 	}
@@ -419,12 +436,7 @@ func (s *Server) GetRegion(ctx context.Context, request *pdpb.GetRegionRequest)
 	if rc == nil {
 		return &pdpb.GetRegionResponse{Header: s.notBootstrappedHeader()}, nil
 	}
-	region, leader := rc.GetRegionByKey(request.GetRegionKey())
-	return &pdpb.GetRegionResponse{
-		Header: s.header(),
-		Region: region,
-		Leader: leader,
-	}, nil
+	return s.newGetRegionResponse(ctx, rc.GetRegionInfoByKey(request.GetRegionKey()))
 }
 
 // GetPrevRegion implements gRPC PDServer
@@ -438,12 +450,7 @@ func (s *Server) GetPrevRegion(ctx context.Context, request *pdpb.GetRegionReque
 		return &pdpb.GetRegionResponse{Header: s.notBootstrappedHeader()}, nil
 	}
 
-	region, leader := rc.GetPrevRegionByKey(request.GetRegionKey())
-	return &pdpb.GetRegionResponse{
-		Header: s.header(),
-		Region: region,
-		Leader: leader,
-	}, nil
+	return s.newGetRegionResponse(ctx, rc.GetPrevRegionInfoByKey(request.GetRegionKey()))
 }
 
 // GetRegionByID implements gRPC PDServer.
@@ -456,13 +463,28 @@ func (s *Server) GetRegionByID(ctx context.Context, request *pdpb.GetRegionByIDR
 	if rc == nil {
 		return &pdpb.GetRegionResponse{Header: s.notBootstrappedHeader()}, nil
 	}
-	id := request.GetRegionId()
-	region, leader := rc.GetRegionByID(id)
-	return &pdpb.GetRegionResponse{
-		Header: s.header(),
-		Region: region,
-		Leader: leader,
-	}, nil
+	return s.newGetRegionResponse(ctx, rc.GetRegion(request.GetRegionId()))
+}
+
+// newGetRegionResponse returns the response of the GetRegion requests. The
+// down and pending peers of the region are sent in the header if they are
+// requested by the metadata, since the response has no field for them.
+func (s *Server) newGetRegionResponse(ctx context.Context, region *core.RegionInfo) (*pdpb.GetRegionResponse, error) {
+	resp := &pdpb.GetRegionResponse{Header: s.header()}
+	if region == nil {
+		return resp, nil
+	}
+	resp.Region, resp.Leader = region.GetMeta(), region.GetLeader()
+	if grpcutil.IsPeerStatusRequested(ctx) {
+		md, err := grpcutil.NewPeerStatusHeader(region.GetDownPeers(), region.GetPendingPeers())
+		if err != nil {
+			return nil, err
+		}
+		if err := grpc.SetHeader(ctx, md); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
 }
 
 // ScanRegions implements gRPC PDServer.
@@ -650,11 +672,12 @@ func (s *Server) ScatterRegion(ctx context.Context, request *pdpb.ScatterRegionR
 		return nil, errors.Errorf("region %d is a hot region", region.GetID())
 	}
 
-	op, err := rc.GetRegionScatter().Scatter(region)
+	op, err := rc.GetRegionScatter().Scatter(region, grpcutil.GetScatterGroup(ctx))
 	if err != nil {
 		return nil, err
 	}
 	if op != nil {
+		op.SetCreator(operator.CreatorClient)
 		rc.GetOperatorController().AddOperator(op)
 	}
 
@@ -0,0 +1,222 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/pingcap/kvproto/pkg/configpb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	configmanager "github.com/pingcap/pd/v4/server/config_manager"
+	"github.com/pingcap/pd/v4/server/schedule"
+)
+
+// StoreLimitComponent is the component of the configpb Config service, by
+// which the store limits are read and written. pdpb has no messages for the
+// store limits yet, so they are served as the global config of the component:
+// Get returns the limits of all the stores in JSON, and Update sets the limit
+// of the store named by each entry to the rate in its value.
+const StoreLimitComponent = "store-limit"
+
+// configService is the configpb Config service of the server. The requests of
+// StoreLimitComponent are served by the store limit RPCs below, and the others
+// by the config manager.
+type configService struct {
+	*configmanager.ConfigManager
+	s *Server
+}
+
+// Get implements gRPC ConfigServer.
+func (c *configService) Get(ctx context.Context, request *configpb.GetRequest) (*configpb.GetResponse, error) {
+	if request.GetComponent() != StoreLimitComponent {
+		return c.ConfigManager.Get(ctx, request)
+	}
+	resp, err := c.s.GetAllStoresLimit(ctx, &GetAllStoresLimitRequest{Header: toRequestHeader(request.GetHeader())})
+	if err != nil {
+		return nil, err
+	}
+	if pberr := resp.Header.GetError(); pberr != nil {
+		return &configpb.GetResponse{Header: c.s.configHeader(), Status: toConfigStatus(pberr)}, nil
+	}
+	config, err := json.Marshal(resp.Limits)
+	if err != nil {
+		return nil, err
+	}
+	return &configpb.GetResponse{
+		Header: c.s.configHeader(),
+		Status: &configpb.Status{Code: configpb.StatusCode_OK},
+		Config: string(config),
+	}, nil
+}
+
+// Update implements gRPC ConfigServer.
+func (c *configService) Update(ctx context.Context, request *configpb.UpdateRequest) (*configpb.UpdateResponse, error) {
+	if request.GetKind().GetGlobal().GetComponent() != StoreLimitComponent {
+		return c.ConfigManager.Update(ctx, request)
+	}
+	header := toRequestHeader(request.GetHeader())
+	for _, entry := range request.GetEntries() {
+		storeID, err := strconv.ParseUint(entry.GetName(), 10, 64)
+		if err != nil {
+			return &configpb.UpdateResponse{Header: c.s.configHeader(), Status: &configpb.Status{
+				Code:    configpb.StatusCode_UNKNOWN,
+				Message: fmt.Sprintf("invalid store id %q", entry.GetName()),
+			}}, nil
+		}
+		rate, err := strconv.ParseFloat(entry.GetValue(), 64)
+		if err != nil {
+			return &configpb.UpdateResponse{Header: c.s.configHeader(), Status: &configpb.Status{
+				Code:    configpb.StatusCode_UNKNOWN,
+				Message: fmt.Sprintf("invalid rate %q", entry.GetValue()),
+			}}, nil
+		}
+		resp, err := c.s.SetStoreLimit(ctx, &SetStoreLimitRequest{Header: header, StoreID: storeID, Rate: rate})
+		if err != nil {
+			return nil, err
+		}
+		if pberr := resp.Header.GetError(); pberr != nil {
+			return &configpb.UpdateResponse{Header: c.s.configHeader(), Status: toConfigStatus(pberr)}, nil
+		}
+	}
+	return &configpb.UpdateResponse{
+		Header: c.s.configHeader(),
+		Status: &configpb.Status{Code: configpb.StatusCode_OK},
+	}, nil
+}
+
+func (s *Server) configHeader() *configpb.Header {
+	return &configpb.Header{ClusterId: s.clusterID}
+}
+
+func toRequestHeader(header *configpb.Header) *pdpb.RequestHeader {
+	return &pdpb.RequestHeader{ClusterId: header.GetClusterId()}
+}
+
+func toConfigStatus(pberr *pdpb.Error) *configpb.Status {
+	return &configpb.Status{
+		Code:    configpb.StatusCode_UNKNOWN,
+		Message: fmt.Sprintf("%s: %s", pberr.GetType(), pberr.GetMessage()),
+	}
+}
+
+// StoreLimit is the balance rate limit of a store.
+type StoreLimit struct {
+	StoreID uint64 `json:"store-id"`
+	// Rate is the number of the operators per minute, as in the HTTP API.
+	Rate float64 `json:"rate"`
+	// Mode is "manual" if the limit is set by the user, or "auto" otherwise.
+	Mode string `json:"mode"`
+}
+
+// GetAllStoresLimitRequest is the request to get the limits of all stores.
+type GetAllStoresLimitRequest struct {
+	Header *pdpb.RequestHeader
+}
+
+// GetHeader returns the header of the request.
+func (r *GetAllStoresLimitRequest) GetHeader() *pdpb.RequestHeader {
+	if r != nil {
+		return r.Header
+	}
+	return nil
+}
+
+// GetAllStoresLimitResponse is the limits of all stores except the tombstone
+// ones, sorted by the store IDs.
+type GetAllStoresLimitResponse struct {
+	Header *pdpb.ResponseHeader
+	Limits []*StoreLimit
+}
+
+// SetStoreLimitRequest is the request to set the manual limit of a store.
+type SetStoreLimitRequest struct {
+	Header  *pdpb.RequestHeader
+	StoreID uint64
+	// Rate is the number of the operators per minute, as in the HTTP API.
+	Rate float64
+}
+
+// GetHeader returns the header of the request.
+func (r *SetStoreLimitRequest) GetHeader() *pdpb.RequestHeader {
+	if r != nil {
+		return r.Header
+	}
+	return nil
+}
+
+// SetStoreLimitResponse is the response of setting the limit of a store.
+type SetStoreLimitResponse struct {
+	Header *pdpb.ResponseHeader
+}
+
+// GetAllStoresLimit implements the store limit RPC.
+func (s *Server) GetAllStoresLimit(ctx context.Context, request *GetAllStoresLimitRequest) (*GetAllStoresLimitResponse, error) {
+	if err := s.validateRequest(request.GetHeader()); err != nil {
+		return nil, err
+	}
+
+	rc := s.GetRaftCluster()
+	if rc == nil {
+		return &GetAllStoresLimitResponse{Header: s.notBootstrappedHeader()}, nil
+	}
+
+	limits := rc.GetOperatorController().GetAllStoresLimit()
+	resp := &GetAllStoresLimitResponse{
+		Header: s.header(),
+		Limits: make([]*StoreLimit, 0, len(limits)),
+	}
+	for storeID, limit := range limits {
+		resp.Limits = append(resp.Limits, &StoreLimit{
+			StoreID: storeID,
+			Rate:    limit.Rate() * schedule.StoreBalanceBaseTime,
+			Mode:    limit.Mode().String(),
+		})
+	}
+	sort.Slice(resp.Limits, func(i, j int) bool { return resp.Limits[i].StoreID < resp.Limits[j].StoreID })
+	return resp, nil
+}
+
+// SetStoreLimit implements the store limit RPC. The limit is persisted as the
+// one set by the HTTP API.
+func (s *Server) SetStoreLimit(ctx context.Context, request *SetStoreLimitRequest) (*SetStoreLimitResponse, error) {
+	if err := s.validateRequest(request.GetHeader()); err != nil {
+		return nil, err
+	}
+
+	rc := s.GetRaftCluster()
+	if rc == nil {
+		return &SetStoreLimitResponse{Header: s.notBootstrappedHeader()}, nil
+	}
+
+	if request.Rate < 0 {
+		return &SetStoreLimitResponse{Header: s.errorHeader(&pdpb.Error{
+			Type:    pdpb.ErrorType_UNKNOWN,
+			Message: fmt.Sprintf("invalid rate %v", request.Rate),
+		})}, nil
+	}
+	if pberr := checkStore(rc, request.StoreID); pberr != nil {
+		return &SetStoreLimitResponse{Header: s.errorHeader(pberr)}, nil
+	}
+	if err := rc.SetStoreLimit(request.StoreID, request.Rate/schedule.StoreBalanceBaseTime); err != nil {
+		return &SetStoreLimitResponse{Header: s.errorHeader(&pdpb.Error{
+			Type:    pdpb.ErrorType_UNKNOWN,
+			Message: err.Error(),
+		})}, nil
+	}
+	return &SetStoreLimitResponse{Header: s.header()}, nil
+}
@@ -15,18 +15,23 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"net/http"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pingcap/errcode"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/pkg/etcdutil"
+	"github.com/pingcap/pd/v4/pkg/typeutil"
 	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/pingcap/pd/v4/server/config"
 	"github.com/pingcap/pd/v4/server/core"
@@ -35,7 +40,10 @@ import (
 	"github.com/pingcap/pd/v4/server/schedule/opt"
 	"github.com/pingcap/pd/v4/server/schedulers"
 	"github.com/pingcap/pd/v4/server/statistics"
+	"github.com/pingcap/pd/v4/server/tso"
 	"github.com/pkg/errors"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/etcdserver"
 	"go.uber.org/zap"
 )
 
@@ -55,6 +63,14 @@ var (
 	ErrRegionNotFound = func(regionID uint64) error {
 		return errors.Errorf("region %v not found", regionID)
 	}
+	// ErrRegionNotFoundByKey is error info for no region covering the key.
+	ErrRegionNotFoundByKey = func(key []byte) error {
+		return errors.Errorf("region covering key %s not found", core.HexRegionKeyStr(key))
+	}
+	// ErrRegionNoAdjacent is error info for region has no adjacent region in the direction.
+	ErrRegionNoAdjacent = func(regionID uint64, direction string) error {
+		return errors.Errorf("region %v has no %s region", regionID, direction)
+	}
 	// ErrRegionAbnormalPeer is error info for region has abonormal peer.
 	ErrRegionAbnormalPeer = func(regionID uint64) error {
 		return errors.Errorf("region %v has abnormal peer", regionID)
@@ -67,18 +83,53 @@ var (
 	ErrPluginNotFound = func(pluginPath string) error {
 		return errors.Errorf("plugin is not found: %s", pluginPath)
 	}
+	// ErrPluginAlreadyLoaded is error info for plugin already loaded.
+	ErrPluginAlreadyLoaded = func(pluginPath string) error {
+		return errors.Errorf("plugin is already loaded: %s", pluginPath)
+	}
+	// ErrEtcdMaintaining is error info for etcd is already being compacted and defragmented.
+	ErrEtcdMaintaining = errors.New("etcd is already being compacted and defragmented")
+	// ErrSchedulerConfigNotFound is error info for scheduler config not found in the storage.
+	ErrSchedulerConfigNotFound = errors.New("scheduler config not found")
+	// ErrSchedulerConfigInUse is error info for removing the config of a running scheduler.
+	ErrSchedulerConfigInUse = errors.New("scheduler config is used by a running scheduler")
 )
 
+// pluginUnloadTimeout is the max time to wait for the coordinator to unload a plugin.
+const pluginUnloadTimeout = 5 * time.Second
+
+// PluginStatus is the status of a loaded plugin.
+type PluginStatus struct {
+	Path          string    `json:"path"`
+	SchedulerName string    `json:"scheduler_name"`
+	LoadTime      time.Time `json:"load_time"`
+	// Running is false if the scheduler of the plugin is not in the
+	// coordinator any more, e.g. the PD leader has changed.
+	Running bool `json:"running"`
+}
+
+type loadedPlugin struct {
+	ch  chan string
+	ack chan error
+	// exited is closed when the coordinator stops receiving from ch.
+	exited        chan struct{}
+	schedulerName string
+	loadTime      time.Time
+}
+
 // Handler is a helper to export methods to handle API/RPC requests.
 type Handler struct {
 	s               *Server
 	opt             *config.ScheduleOption
-	pluginChMap     map[string]chan string
+	pluginChMap     map[string]*loadedPlugin
 	pluginChMapLock sync.RWMutex
+	// etcdMaintaining is set to 1 while the embedded etcd is being compacted
+	// and defragmented.
+	etcdMaintaining int32
 }
 
 func newHandler(s *Server) *Handler {
-	return &Handler{s: s, opt: s.scheduleOpt, pluginChMap: make(map[string]chan string), pluginChMapLock: sync.RWMutex{}}
+	return &Handler{s: s, opt: s.scheduleOpt, pluginChMap: make(map[string]*loadedPlugin), pluginChMapLock: sync.RWMutex{}}
 }
 
 // GetRaftCluster returns RaftCluster.
@@ -117,19 +168,132 @@ func (h *Handler) GetScheduleConfig() *config.ScheduleConfig {
 	return h.s.GetScheduleConfig()
 }
 
+// GetSchedulerEffectiveConfig returns the config items the scheduler uses at
+// schedule time. The name can omit the "-scheduler" suffix.
+func (h *Handler) GetSchedulerEffectiveConfig(name string) (*cluster.SchedulerEffectiveConfig, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	conf, err := c.GetSchedulerEffectiveConfig(name)
+	if err == cluster.ErrSchedulerNotFound && !strings.HasSuffix(name, "-scheduler") {
+		return c.GetSchedulerEffectiveConfig(name + "-scheduler")
+	}
+	return conf, err
+}
+
 // GetSchedulers returns all names of schedulers.
 func (h *Handler) GetSchedulers() ([]string, error) {
 	c, err := h.GetRaftCluster()
 	if err != nil {
 		return nil, err
 	}
-	names := make([]string, 0, len(c.GetSchedulers()))
-	for name := range c.GetSchedulers() {
+	schedulers := c.GetSchedulers()
+	if schedulers == nil {
+		return nil, errors.WithStack(cluster.ErrClusterStopping)
+	}
+	names := make([]string, 0, len(schedulers))
+	for name := range schedulers {
 		names = append(names, name)
 	}
 	return names, nil
 }
 
+// GetSchedulerStatuses returns the statuses and the configs of the running and
+// the disabled schedulers, so that the paused ones can be found in one call.
+func (h *Handler) GetSchedulerStatuses() ([]*cluster.SchedulerStatus, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return c.GetSchedulerStatuses()
+}
+
+// GetSchedulerDetail returns the running status of a scheduler.
+func (h *Handler) GetSchedulerDetail(name string) (*cluster.SchedulerDetail, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return c.GetSchedulerDetail(name)
+}
+
+// ObserveScheduler sets whether a scheduler keeps running but logs its
+// operators instead of adding them.
+func (h *Handler) ObserveScheduler(name string, observe bool) error {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return err
+	}
+	if err = c.ObserveScheduler(name, observe); err != nil {
+		log.Error("can not set the observe flag of scheduler", zap.String("scheduler-name", name), zap.Bool("observe", observe), zap.Error(err))
+	}
+	return err
+}
+
+// GetObservedOperators returns the recent operators logged by a scheduler in
+// the observe mode.
+func (h *Handler) GetObservedOperators(name string) ([]*cluster.ObservedOperator, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return c.GetObservedOperators(name)
+}
+
+// PersistedSchedulerConfig is the raw scheduler config persisted in the storage.
+type PersistedSchedulerConfig struct {
+	Name string `json:"name"`
+	// Config is kept as a string since it may fail to be decoded.
+	Config string `json:"config"`
+	// Loaded means the scheduler is running in the cluster.
+	Loaded bool `json:"loaded"`
+}
+
+// GetPersistedSchedulerConfigs returns all scheduler configs in the storage,
+// including the ones of the schedulers failed to be loaded.
+func (h *Handler) GetPersistedSchedulerConfigs() ([]PersistedSchedulerConfig, error) {
+	names, configs, err := h.s.GetStorage().LoadAllScheduleConfig()
+	if err != nil {
+		return nil, err
+	}
+	loaded := make(map[string]bool)
+	if c := h.s.GetRaftCluster(); c != nil {
+		for name := range c.GetSchedulers() {
+			loaded[name] = true
+		}
+	}
+	res := make([]PersistedSchedulerConfig, 0, len(names))
+	for i, name := range names {
+		res = append(res, PersistedSchedulerConfig{Name: name, Config: configs[i], Loaded: loaded[name]})
+	}
+	return res, nil
+}
+
+// RemovePersistedSchedulerConfig removes a stale scheduler config from the
+// storage. The config of a running scheduler should be removed along with the
+// scheduler instead.
+func (h *Handler) RemovePersistedSchedulerConfig(name string) error {
+	storage := h.s.GetStorage()
+	data, err := storage.LoadScheduleConfig(name)
+	if err != nil {
+		return err
+	}
+	if data == "" {
+		return errors.WithStack(ErrSchedulerConfigNotFound)
+	}
+	if c := h.s.GetRaftCluster(); c != nil {
+		if _, ok := c.GetSchedulers()[name]; ok {
+			return errors.WithStack(ErrSchedulerConfigInUse)
+		}
+	}
+	if err = storage.RemoveScheduleConfig(name); err != nil {
+		return err
+	}
+	log.Info("persisted scheduler config is removed", zap.String("scheduler-name", name))
+	return nil
+}
+
 // GetStores returns all stores in the cluster.
 func (h *Handler) GetStores() ([]*core.StoreInfo, error) {
 	rc := h.s.GetRaftCluster()
@@ -149,6 +313,37 @@ func (h *Handler) GetStores() ([]*core.StoreInfo, error) {
 	return stores, nil
 }
 
+// StatsWarmUpDuration is how long the statistics are warming up after the
+// coordinator starts. The hot regions are not reported until it passes.
+var StatsWarmUpDuration = statistics.RegionHeartBeatReportInterval * time.Second
+
+// StatsAnnotation describes the statistics collected since the current PD
+// leader takes office, so that clients can detect the reset of the statistics
+// caused by leader changes.
+type StatsAnnotation struct {
+	LeaderTerm           int64             `json:"leader_term"`
+	CoordinatorStartTime time.Time         `json:"coordinator_start_time"`
+	DataAge              typeutil.Duration `json:"data_age"`
+	WarmingUp            bool              `json:"warming_up"`
+}
+
+// GetStatsAnnotation returns the annotation of the operator history and the
+// hot region statistics.
+func (h *Handler) GetStatsAnnotation() (*StatsAnnotation, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	startTime := c.GetCoordinatorStartTime()
+	age := time.Since(startTime)
+	return &StatsAnnotation{
+		LeaderTerm:           h.s.GetMember().GetLeaderTerm(),
+		CoordinatorStartTime: startTime,
+		DataAge:              typeutil.NewDuration(age),
+		WarmingUp:            age < StatsWarmUpDuration,
+	}, nil
+}
+
 // GetHotWriteRegions gets all hot write regions stats.
 func (h *Handler) GetHotWriteRegions() *statistics.StoreHotPeersInfos {
 	c, err := h.GetRaftCluster()
@@ -203,6 +398,24 @@ func (h *Handler) GetHotKeysReadStores() map[uint64]float64 {
 	return rc.GetStoresKeysReadStat()
 }
 
+// GetStoresIOCapacity gets the IO capacities of the labeled stores, and the
+// ratios of the IO capacities of all the stores to the average, by which the
+// hot region scheduler normalizes the byte rates.
+func (h *Handler) GetStoresIOCapacity() (map[uint64]uint64, map[uint64]float64) {
+	rc := h.s.GetRaftCluster()
+	if rc == nil {
+		return nil, nil
+	}
+	stores := rc.GetStores()
+	capacities := make(map[uint64]uint64)
+	for _, store := range stores {
+		if capacity := store.GetIOCapacity(); capacity > 0 {
+			capacities[store.GetID()] = capacity
+		}
+	}
+	return capacities, core.IOCapacityFactors(stores)
+}
+
 // AddScheduler adds a scheduler.
 func (h *Handler) AddScheduler(name string, args ...string) error {
 	c, err := h.GetRaftCluster()
@@ -293,6 +506,18 @@ func (h *Handler) AddEvictLeaderScheduler(storeID uint64) error {
 	return h.AddScheduler(schedulers.EvictLeaderType, strconv.FormatUint(storeID, 10))
 }
 
+// AddEvictLeaderByLabel adds an evict-leader-scheduler which evicts leaders
+// from the stores matching the label selector, such as "zone=z1". The new
+// stores matching the selector are evicted too.
+func (h *Handler) AddEvictLeaderByLabel(selector string) error {
+	return h.AddScheduler(schedulers.EvictLeaderType, selector)
+}
+
+// AddEvictSlowStoreScheduler adds an evict-slow-store-scheduler.
+func (h *Handler) AddEvictSlowStoreScheduler() error {
+	return h.AddScheduler(schedulers.EvictSlowStoreType)
+}
+
 // AddShuffleLeaderScheduler adds a shuffle-leader-scheduler.
 func (h *Handler) AddShuffleLeaderScheduler() error {
 	return h.AddScheduler(schedulers.ShuffleLeaderType)
@@ -343,6 +568,15 @@ func (h *Handler) GetOperatorStatus(regionID uint64) (*schedule.OperatorWithStat
 	return op, nil
 }
 
+// GetSplitIntoStatus returns the progress of splitting the region into parts.
+func (h *Handler) GetSplitIntoStatus(regionID uint64) (*schedule.SplitIntoStatus, error) {
+	c, err := h.GetOperatorController()
+	if err != nil {
+		return nil, err
+	}
+	return c.GetSplitIntoStatus(regionID), nil
+}
+
 // RemoveOperator removes the region operator.
 func (h *Handler) RemoveOperator(regionID uint64) error {
 	c, err := h.GetOperatorController()
@@ -418,12 +652,11 @@ func (h *Handler) GetHistory(start time.Time) ([]operator.OpHistory, error) {
 
 // SetAllStoresLimit is used to set limit of all stores.
 func (h *Handler) SetAllStoresLimit(rate float64) error {
-	c, err := h.GetOperatorController()
+	c, err := h.GetRaftCluster()
 	if err != nil {
 		return err
 	}
-	c.SetAllStoresLimit(rate, schedule.StoreLimitManual)
-	return nil
+	return c.SetAllStoresLimit(rate)
 }
 
 // GetAllStoresLimit is used to get limit of all stores.
@@ -435,73 +668,122 @@ func (h *Handler) GetAllStoresLimit() (map[uint64]*schedule.StoreLimit, error) {
 	return c.GetAllStoresLimit(), nil
 }
 
+// GetStoreBudgets returns the forecast of the store limits, sorted by the
+// store IDs.
+func (h *Handler) GetStoreBudgets() ([]*schedule.StoreBudget, error) {
+	c, err := h.GetOperatorController()
+	if err != nil {
+		return nil, err
+	}
+	budgets := make([]*schedule.StoreBudget, 0)
+	for _, budget := range c.GetStoreBudgets() {
+		budgets = append(budgets, budget)
+	}
+	sort.Slice(budgets, func(i, j int) bool { return budgets[i].StoreID < budgets[j].StoreID })
+	return budgets, nil
+}
+
 // SetStoreLimit is used to set the limit of a store.
 func (h *Handler) SetStoreLimit(storeID uint64, rate float64) error {
-	c, err := h.GetOperatorController()
+	c, err := h.GetRaftCluster()
 	if err != nil {
 		return err
 	}
-	c.SetStoreLimit(storeID, rate, schedule.StoreLimitManual)
-	return nil
+	return c.SetStoreLimit(storeID, rate)
 }
 
-// AddTransferLeaderOperator adds an operator to transfer leader to the store.
-func (h *Handler) AddTransferLeaderOperator(regionID uint64, storeID uint64) error {
+// RemoveStoreLimit is used to remove the manual limit of a store.
+func (h *Handler) RemoveStoreLimit(storeID uint64) error {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return err
+	}
+	return c.RemoveStoreLimit(storeID)
+}
+
+// RemoveAllStoresLimit is used to remove the manual limits of all stores.
+func (h *Handler) RemoveAllStoresLimit() error {
 	c, err := h.GetRaftCluster()
 	if err != nil {
 		return err
 	}
+	return c.RemoveAllStoresLimit()
+}
+
+// AddTransferLeaderOperator adds an operator to transfer leader to the store.
+func (h *Handler) AddTransferLeaderOperator(consumer string, regionID uint64, storeID uint64) ([]*operator.Operator, error) {
+	ops, err := h.BuildTransferLeaderOperator(regionID, storeID)
+	if err != nil {
+		return nil, err
+	}
+	return h.addOperators(consumer, ops...)
+}
+
+// BuildTransferLeaderOperator builds an operator to transfer leader to the
+// store without adding it.
+func (h *Handler) BuildTransferLeaderOperator(regionID uint64, storeID uint64) ([]*operator.Operator, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
 
 	region := c.GetRegion(regionID)
 	if region == nil {
-		return ErrRegionNotFound(regionID)
+		return nil, ErrRegionNotFound(regionID)
 	}
 
 	newLeader := region.GetStoreVoter(storeID)
 	if newLeader == nil {
-		return errors.Errorf("region has no voter in store %v", storeID)
+		return nil, errors.Errorf("region has no voter in store %v", storeID)
 	}
 
 	op, err := operator.CreateTransferLeaderOperator("admin-transfer-leader", c, region, region.GetLeader().GetStoreId(), newLeader.GetStoreId(), operator.OpAdmin)
 	if err != nil {
 		log.Debug("fail to create transfer leader operator", zap.Error(err))
-		return err
-	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
-		return errors.WithStack(ErrAddOperator)
+		return nil, err
 	}
-	return nil
+	return []*operator.Operator{op}, nil
 }
 
 // AddTransferRegionOperator adds an operator to transfer region to the stores.
-func (h *Handler) AddTransferRegionOperator(regionID uint64, storeIDs map[uint64]struct{}) error {
+func (h *Handler) AddTransferRegionOperator(consumer string, regionID uint64, storeIDs map[uint64]struct{}) ([]*operator.Operator, error) {
+	ops, err := h.BuildTransferRegionOperator(regionID, storeIDs)
+	if err != nil {
+		return nil, err
+	}
+	return h.addOperators(consumer, ops...)
+}
+
+// BuildTransferRegionOperator builds an operator to transfer region to the
+// stores without adding it.
+func (h *Handler) BuildTransferRegionOperator(regionID uint64, storeIDs map[uint64]struct{}) ([]*operator.Operator, error) {
 	c, err := h.GetRaftCluster()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if c.IsPlacementRulesEnabled() {
 		// Cannot determine role when placement rules enabled. Not supported now.
-		return errors.New("transfer region is not supported when placement rules enabled")
+		return nil, errors.New("transfer region is not supported when placement rules enabled")
 	}
 
 	region := c.GetRegion(regionID)
 	if region == nil {
-		return ErrRegionNotFound(regionID)
+		return nil, ErrRegionNotFound(regionID)
 	}
 
 	if len(storeIDs) > c.GetMaxReplicas() {
-		return errors.Errorf("the number of stores is %v, beyond the max replicas", len(storeIDs))
+		return nil, errors.Errorf("the number of stores is %v, beyond the max replicas", len(storeIDs))
 	}
 
 	var store *core.StoreInfo
 	for id := range storeIDs {
 		store = c.GetStore(id)
 		if store == nil {
-			return core.NewStoreNotFoundErr(id)
+			return nil, core.NewStoreNotFoundErr(id)
 		}
 		if store.IsTombstone() {
-			return errcode.Op("operator.add").AddTo(core.StoreTombstonedErr{StoreID: id})
+			return nil, errcode.Op("operator.add").AddTo(core.StoreTombstonedErr{StoreID: id})
 		}
 	}
 
@@ -513,49 +795,53 @@ func (h *Handler) AddTransferRegionOperator(regionID uint64, storeIDs map[uint64
 	op, err := operator.CreateMoveRegionOperator("admin-move-region", c, region, operator.OpAdmin, peers)
 	if err != nil {
 		log.Debug("fail to create move region operator", zap.Error(err))
-		return err
-	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
-		return errors.WithStack(ErrAddOperator)
+		return nil, err
 	}
-	return nil
+	return []*operator.Operator{op}, nil
 }
 
 // AddTransferPeerOperator adds an operator to transfer peer.
-func (h *Handler) AddTransferPeerOperator(regionID uint64, fromStoreID, toStoreID uint64) error {
+func (h *Handler) AddTransferPeerOperator(consumer string, regionID uint64, fromStoreID, toStoreID uint64) ([]*operator.Operator, error) {
+	ops, err := h.BuildTransferPeerOperator(regionID, fromStoreID, toStoreID)
+	if err != nil {
+		return nil, err
+	}
+	return h.addOperators(consumer, ops...)
+}
+
+// BuildTransferPeerOperator builds an operator to transfer peer without
+// adding it.
+func (h *Handler) BuildTransferPeerOperator(regionID uint64, fromStoreID, toStoreID uint64) ([]*operator.Operator, error) {
 	c, err := h.GetRaftCluster()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	region := c.GetRegion(regionID)
 	if region == nil {
-		return ErrRegionNotFound(regionID)
+		return nil, ErrRegionNotFound(regionID)
 	}
 
 	oldPeer := region.GetStorePeer(fromStoreID)
 	if oldPeer == nil {
-		return errors.Errorf("region has no peer in store %v", fromStoreID)
+		return nil, errors.Errorf("region has no peer in store %v", fromStoreID)
 	}
 
 	toStore := c.GetStore(toStoreID)
 	if toStore == nil {
-		return core.NewStoreNotFoundErr(toStoreID)
+		return nil, core.NewStoreNotFoundErr(toStoreID)
 	}
 	if toStore.IsTombstone() {
-		return errcode.Op("operator.add").AddTo(core.StoreTombstonedErr{StoreID: toStoreID})
+		return nil, errcode.Op("operator.add").AddTo(core.StoreTombstonedErr{StoreID: toStoreID})
 	}
 
 	newPeer := &metapb.Peer{StoreId: toStoreID, IsLearner: oldPeer.GetIsLearner()}
 	op, err := operator.CreateMovePeerOperator("admin-move-peer", c, region, operator.OpAdmin, fromStoreID, newPeer)
 	if err != nil {
 		log.Debug("fail to create move peer operator", zap.Error(err))
-		return err
-	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
-		return errors.WithStack(ErrAddOperator)
+		return nil, err
 	}
-	return nil
+	return []*operator.Operator{op}, nil
 }
 
 // checkAdminAddPeerOperator checks adminAddPeer operator with given region ID and store ID.
@@ -586,29 +872,44 @@ func (h *Handler) checkAdminAddPeerOperator(regionID uint64, toStoreID uint64) (
 }
 
 // AddAddPeerOperator adds an operator to add peer.
-func (h *Handler) AddAddPeerOperator(regionID uint64, toStoreID uint64) error {
+func (h *Handler) AddAddPeerOperator(consumer string, regionID uint64, toStoreID uint64) ([]*operator.Operator, error) {
+	ops, err := h.BuildAddPeerOperator(regionID, toStoreID)
+	if err != nil {
+		return nil, err
+	}
+	return h.addOperators(consumer, ops...)
+}
+
+// BuildAddPeerOperator builds an operator to add peer without adding it.
+func (h *Handler) BuildAddPeerOperator(regionID uint64, toStoreID uint64) ([]*operator.Operator, error) {
 	c, region, err := h.checkAdminAddPeerOperator(regionID, toStoreID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	newPeer := &metapb.Peer{StoreId: toStoreID}
 	op, err := operator.CreateAddPeerOperator("admin-add-peer", c, region, newPeer, operator.OpAdmin)
 	if err != nil {
 		log.Debug("fail to create add peer operator", zap.Error(err))
-		return err
-	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
-		return errors.WithStack(ErrAddOperator)
+		return nil, err
 	}
-	return nil
+	return []*operator.Operator{op}, nil
 }
 
 // AddAddLearnerOperator adds an operator to add learner.
-func (h *Handler) AddAddLearnerOperator(regionID uint64, toStoreID uint64) error {
+func (h *Handler) AddAddLearnerOperator(consumer string, regionID uint64, toStoreID uint64) ([]*operator.Operator, error) {
+	ops, err := h.BuildAddLearnerOperator(regionID, toStoreID)
+	if err != nil {
+		return nil, err
+	}
+	return h.addOperators(consumer, ops...)
+}
+
+// BuildAddLearnerOperator builds an operator to add learner without adding it.
+func (h *Handler) BuildAddLearnerOperator(regionID uint64, toStoreID uint64) ([]*operator.Operator, error) {
 	c, region, err := h.checkAdminAddPeerOperator(regionID, toStoreID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	newPeer := &metapb.Peer{
@@ -619,98 +920,156 @@ func (h *Handler) AddAddLearnerOperator(regionID uint64, toStoreID uint64) error
 	op, err := operator.CreateAddPeerOperator("admin-add-learner", c, region, newPeer, operator.OpAdmin)
 	if err != nil {
 		log.Debug("fail to create add learner operator", zap.Error(err))
-		return err
-	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
-		return errors.WithStack(ErrAddOperator)
+		return nil, err
 	}
-	return nil
+	return []*operator.Operator{op}, nil
 }
 
 // AddRemovePeerOperator adds an operator to remove peer.
-func (h *Handler) AddRemovePeerOperator(regionID uint64, fromStoreID uint64) error {
+func (h *Handler) AddRemovePeerOperator(consumer string, regionID uint64, fromStoreID uint64) ([]*operator.Operator, error) {
+	ops, err := h.BuildRemovePeerOperator(regionID, fromStoreID)
+	if err != nil {
+		return nil, err
+	}
+	return h.addOperators(consumer, ops...)
+}
+
+// BuildRemovePeerOperator builds an operator to remove peer without adding it.
+func (h *Handler) BuildRemovePeerOperator(regionID uint64, fromStoreID uint64) ([]*operator.Operator, error) {
 	c, err := h.GetRaftCluster()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	region := c.GetRegion(regionID)
 	if region == nil {
-		return ErrRegionNotFound(regionID)
+		return nil, ErrRegionNotFound(regionID)
 	}
 
 	if region.GetStorePeer(fromStoreID) == nil {
-		return errors.Errorf("region has no peer in store %v", fromStoreID)
+		return nil, errors.Errorf("region has no peer in store %v", fromStoreID)
 	}
 
 	op, err := operator.CreateRemovePeerOperator("admin-remove-peer", c, operator.OpAdmin, region, fromStoreID)
 	if err != nil {
 		log.Debug("fail to create move peer operator", zap.Error(err))
-		return err
-	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
-		return errors.WithStack(ErrAddOperator)
+		return nil, err
 	}
-	return nil
+	return []*operator.Operator{op}, nil
 }
 
 // AddMergeRegionOperator adds an operator to merge region.
-func (h *Handler) AddMergeRegionOperator(regionID uint64, targetID uint64) error {
+func (h *Handler) AddMergeRegionOperator(consumer string, regionID uint64, targetID uint64) ([]*operator.Operator, error) {
+	ops, err := h.BuildMergeRegionOperator(regionID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return h.addOperators(consumer, ops...)
+}
+
+// BuildMergeRegionOperator builds the operators to merge region without
+// adding them.
+func (h *Handler) BuildMergeRegionOperator(regionID uint64, targetID uint64) ([]*operator.Operator, error) {
 	c, err := h.GetRaftCluster()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	region := c.GetRegion(regionID)
 	if region == nil {
-		return ErrRegionNotFound(regionID)
+		return nil, ErrRegionNotFound(regionID)
 	}
 
 	target := c.GetRegion(targetID)
 	if target == nil {
-		return ErrRegionNotFound(targetID)
+		return nil, ErrRegionNotFound(targetID)
 	}
+	return h.buildMergeRegionOperator(c, region, target)
+}
+
+// AddMergeRegionByKeyOperator adds an operator to merge the region covering
+// the key into its adjacent region in the direction, which is "next" or
+// "prev". The regions are resolved when the operator is created, as the
+// region IDs change when the regions split or merge.
+func (h *Handler) AddMergeRegionByKeyOperator(consumer string, key []byte, direction string) ([]*operator.Operator, error) {
+	ops, err := h.BuildMergeRegionByKeyOperator(key, direction)
+	if err != nil {
+		return nil, err
+	}
+	return h.addOperators(consumer, ops...)
+}
 
+// BuildMergeRegionByKeyOperator builds the operators to merge the region
+// covering the key into its adjacent region without adding them.
+func (h *Handler) BuildMergeRegionByKeyOperator(key []byte, direction string) ([]*operator.Operator, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+
+	region, prev, next := c.GetRegionWithAdjacentByKey(key)
+	if region == nil {
+		return nil, ErrRegionNotFoundByKey(key)
+	}
+	target := next
+	if direction == "prev" {
+		target = prev
+	}
+	if target == nil {
+		return nil, ErrRegionNoAdjacent(region.GetID(), direction)
+	}
+	return h.buildMergeRegionOperator(c, region, target)
+}
+
+func (h *Handler) buildMergeRegionOperator(c *cluster.RaftCluster, region, target *core.RegionInfo) ([]*operator.Operator, error) {
+	regionID, targetID := region.GetID(), target.GetID()
 	if !opt.IsRegionHealthy(c, region) || !opt.IsRegionReplicated(c, region) {
-		return ErrRegionAbnormalPeer(regionID)
+		return nil, ErrRegionAbnormalPeer(regionID)
 	}
 
 	if !opt.IsRegionHealthy(c, target) || !opt.IsRegionReplicated(c, target) {
-		return ErrRegionAbnormalPeer(targetID)
+		return nil, ErrRegionAbnormalPeer(targetID)
 	}
 
 	// for the case first region (start key is nil) with the last region (end key is nil) but not adjacent
 	if (!bytes.Equal(region.GetStartKey(), target.GetEndKey()) || len(region.GetStartKey()) == 0) &&
 		(!bytes.Equal(region.GetEndKey(), target.GetStartKey()) || len(region.GetEndKey()) == 0) {
-		return ErrRegionNotAdjacent
+		return nil, ErrRegionNotAdjacent
 	}
 
 	ops, err := operator.CreateMergeRegionOperator("admin-merge-region", c, region, target, operator.OpAdmin)
 	if err != nil {
 		log.Debug("fail to create merge region operator", zap.Error(err))
-		return err
-	}
-	if ok := c.GetOperatorController().AddOperator(ops...); !ok {
-		return errors.WithStack(ErrAddOperator)
+		return nil, err
 	}
-	return nil
+	return ops, nil
 }
 
 // AddSplitRegionOperator adds an operator to split a region.
-func (h *Handler) AddSplitRegionOperator(regionID uint64, policyStr string, keys []string) error {
+func (h *Handler) AddSplitRegionOperator(consumer string, regionID uint64, policyStr string, keys []string) ([]*operator.Operator, error) {
+	ops, err := h.BuildSplitRegionOperator(regionID, policyStr, keys)
+	if err != nil {
+		return nil, err
+	}
+	return h.addOperators(consumer, ops...)
+}
+
+// BuildSplitRegionOperator builds an operator to split a region without
+// adding it.
+func (h *Handler) BuildSplitRegionOperator(regionID uint64, policyStr string, keys []string) ([]*operator.Operator, error) {
 	c, err := h.GetRaftCluster()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	region := c.GetRegion(regionID)
 	if region == nil {
-		return ErrRegionNotFound(regionID)
+		return nil, ErrRegionNotFound(regionID)
 	}
 
 	policy, ok := pdpb.CheckPolicy_value[strings.ToUpper(policyStr)]
 	if !ok {
-		return errors.Errorf("check policy %s is not supported", policyStr)
+		return nil, errors.Errorf("check policy %s is not supported", policyStr)
 	}
 
 	var splitKeys [][]byte
@@ -718,47 +1077,228 @@ func (h *Handler) AddSplitRegionOperator(regionID uint64, policyStr string, keys
 		for i := range keys {
 			k, err := hex.DecodeString(keys[i])
 			if err != nil {
-				return errors.Errorf("split key %s is not in hex format", keys[i])
+				return nil, errors.Errorf("split key %s is not in hex format", keys[i])
 			}
 			splitKeys = append(splitKeys, k)
 		}
 	}
 
 	op := operator.CreateSplitRegionOperator("admin-split-region", region, operator.OpAdmin, pdpb.CheckPolicy(policy), splitKeys)
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
-		return errors.WithStack(ErrAddOperator)
+	return []*operator.Operator{op}, nil
+}
+
+// AddSplitRegionIntoOperator adds an operator to split a region into parts of
+// approximately even size.
+func (h *Handler) AddSplitRegionIntoOperator(consumer string, regionID uint64, parts int) ([]*operator.Operator, error) {
+	ops, err := h.BuildSplitRegionIntoOperator(regionID, parts)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	region := c.GetRegion(regionID)
+	if region == nil {
+		return nil, ErrRegionNotFound(regionID)
+	}
+	ok, err := c.GetOperatorQuotas().AddOperators(consumer, ops, func() bool {
+		return c.GetOperatorController().AddSplitIntoOperator(region, ops[0], parts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.WithStack(ErrAddOperator)
+	}
+	return ops, nil
 }
 
-// AddScatterRegionOperator adds an operator to scatter a region.
-func (h *Handler) AddScatterRegionOperator(regionID uint64) error {
+// BuildSplitRegionIntoOperator builds an operator to split a region into
+// parts without adding it.
+func (h *Handler) BuildSplitRegionIntoOperator(regionID uint64, parts int) ([]*operator.Operator, error) {
 	c, err := h.GetRaftCluster()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	region := c.GetRegion(regionID)
 	if region == nil {
-		return ErrRegionNotFound(regionID)
+		return nil, ErrRegionNotFound(regionID)
 	}
 
-	if c.IsRegionHot(region) {
-		return errors.Errorf("region %d is a hot region", regionID)
+	if parts < schedule.MinSplitIntoParts || parts > schedule.MaxSplitIntoParts {
+		return nil, errors.Errorf("parts %d should be in [%d, %d]", parts, schedule.MinSplitIntoParts, schedule.MaxSplitIntoParts)
 	}
 
-	op, err := c.GetRegionScatter().Scatter(region)
+	return []*operator.Operator{schedule.CreateSplitIntoOperator(region)}, nil
+}
+
+// addOperators adds the operators submitted by the consumer if the operator
+// quota of the consumer is not exceeded. It returns the added operators.
+func (h *Handler) addOperators(consumer string, ops ...*operator.Operator) ([]*operator.Operator, error) {
+	c, err := h.GetRaftCluster()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for _, op := range ops {
+		op.SetCreator(operator.CreatorAdmin)
+	}
+	ok, err := c.GetOperatorQuotas().AddOperators(consumer, ops, func() bool {
+		return c.GetOperatorController().AddOperator(ops...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.WithStack(ErrAddOperator)
+	}
+	return ops, nil
+}
+
+// AddChainedOperators adds the operators built by one of the Build*Operator
+// methods, whose error is passed as buildErr, after the prerequisite operator
+// finishes successfully. They are held until then, and counted to the
+// operator quota of the consumer. They are built again by rebuild when they
+// are released, as the prerequisite may change the region.
+func (h *Handler) AddChainedOperators(consumer string, after operator.Dependency, rebuild schedule.RebuildFunc, ops []*operator.Operator, buildErr error) ([]*operator.Operator, error) {
+	if buildErr != nil {
+		return nil, buildErr
+	}
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		op.SetCreator(operator.CreatorAdmin)
+	}
+	var chainErr error
+	ok, err := c.GetOperatorQuotas().AddOperators(consumer, ops, func() bool {
+		var added bool
+		added, chainErr = c.GetOperatorController().AddChainedOperators(after, rebuild, ops...)
+		return added
+	})
+	if err != nil {
+		return nil, err
+	}
+	if chainErr != nil {
+		return nil, chainErr
+	}
+	if !ok {
+		return nil, errors.WithStack(ErrAddOperator)
+	}
+	return ops, nil
+}
+
+// AddScatterRegionOperator adds an operator to scatter a region among the
+// regions of the scatter group.
+func (h *Handler) AddScatterRegionOperator(consumer string, regionID uint64, group string) ([]*operator.Operator, error) {
+	c, region, err := h.checkScatterRegion(regionID)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := c.GetRegionScatter().Scatter(region, group)
+	if err != nil {
+		return nil, err
 	}
 
 	if op == nil {
-		return nil
+		return nil, nil
+	}
+	return h.addOperators(consumer, op)
+}
+
+// ScatterRegionsResult is the result of scattering the regions in a key range.
+type ScatterRegionsResult struct {
+	// Scattered is the number of the regions whose operators are added.
+	Scattered int
+	// Hot is the number of the regions skipped as they are hot.
+	Hot int
+	// Failed is the number of the regions which cannot be scattered, or whose
+	// operators fail to be added.
+	Failed int
+	// NoOperator is the number of the regions for which no operator can be
+	// built from the stores selected.
+	NoOperator int
+	// NextKey is the start key of the regions not handled yet, as the limit
+	// is reached or the store limits or the operator quota are exhausted. It
+	// is nil if all the regions in the range are handled.
+	NextKey []byte
+}
+
+// ScatterRegions scatters at most limit regions in [startKey, endKey) among
+// the regions of the scatter group. Unlike scattering a single region, the
+// peers added take the tokens of the store limits, and it stops once an
+// operator would exceed the store limits or the operator quota of the
+// consumer, so that the stores are not flooded by the snapshots. The rest are
+// scattered by the later calls starting from NextKey.
+func (h *Handler) ScatterRegions(consumer string, startKey, endKey []byte, group string, limit int) (*ScatterRegionsResult, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ScatterRegionsResult{}
+	regions := c.ScanRegions(startKey, endKey, limit)
+	for _, region := range regions {
+		if c.IsRegionHot(region) {
+			res.Hot++
+			continue
+		}
+		op, err := c.GetRegionScatter().ScatterWithStoreLimit(region, group, func(op *operator.Operator) bool {
+			return c.GetOperatorController().CheckStoreLimit(op) == 0
+		})
+		if err == schedule.ErrScatterStoreLimitExceeded {
+			res.NextKey = region.GetStartKey()
+			return res, nil
+		}
+		if err != nil {
+			res.Failed++
+			continue
+		}
+		if op == nil {
+			res.NoOperator++
+			continue
+		}
+		if _, err := h.addOperators(consumer, op); err != nil {
+			if _, ok := errors.Cause(err).(core.OperatorQuotaExceededErr); ok {
+				res.NextKey = region.GetStartKey()
+				return res, nil
+			}
+			res.Failed++
+			continue
+		}
+		res.Scattered++
 	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
-		return errors.WithStack(ErrAddOperator)
+
+	if len(regions) == limit {
+		last := regions[len(regions)-1].GetEndKey()
+		if len(last) > 0 && (len(endKey) == 0 || bytes.Compare(last, endKey) < 0) {
+			res.NextKey = last
+		}
 	}
-	return nil
+	return res, nil
+}
+
+// checkScatterRegion checks if the region can be scattered. The operator to
+// scatter a region is not built in advance, as the scatterer records the
+// stores it selects.
+func (h *Handler) checkScatterRegion(regionID uint64) (*cluster.RaftCluster, *core.RegionInfo, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	region := c.GetRegion(regionID)
+	if region == nil {
+		return nil, nil, ErrRegionNotFound(regionID)
+	}
+
+	if c.IsRegionHot(region) {
+		return nil, nil, errors.Errorf("region %d is a hot region", regionID)
+	}
+	return c, region, nil
 }
 
 // GetDownPeerRegions gets the region with down peer.
@@ -832,11 +1372,30 @@ func (h *Handler) GetEmptyRegion() ([]*core.RegionInfo, error) {
 
 // ResetTS resets the ts with specified tso.
 func (h *Handler) ResetTS(ts uint64) error {
+	return h.ResetTSWithOptions(ts, ResetTSOptions{})
+}
+
+// ResetTSWithOptions resets the ts with specified tso and records it in the
+// reset history.
+func (h *Handler) ResetTSWithOptions(ts uint64, opts ResetTSOptions) error {
+	if h.s.tso == nil {
+		return ErrServerNotStarted
+	}
+	return h.s.resetTS(ts, opts)
+}
+
+// GetTSOResetHistory returns the TSO resets from the oldest to the latest.
+func (h *Handler) GetTSOResetHistory() ([]*TSOResetRecord, error) {
+	return h.s.loadTSOResetHistory()
+}
+
+// GetTSOStatus returns the status of the timestamp oracle.
+func (h *Handler) GetTSOStatus() (*tso.Status, error) {
 	tsoServer := h.s.tso
 	if tsoServer == nil {
-		return ErrServerNotStarted
+		return nil, ErrServerNotStarted
 	}
-	return tsoServer.ResetUserTimestamp(ts)
+	return tsoServer.GetStatus()
 }
 
 // SetStoreLimitScene sets the limit values for differents scenes
@@ -855,26 +1414,287 @@ func (h *Handler) GetStoreLimitScene() *schedule.StoreLimitScene {
 func (h *Handler) PluginLoad(pluginPath string) error {
 	h.pluginChMapLock.Lock()
 	defer h.pluginChMapLock.Unlock()
-	cluster, err := h.GetRaftCluster()
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return err
+	}
+	if p, ok := h.pluginChMap[pluginPath]; ok {
+		if isPluginRunning(rc, p) {
+			return ErrPluginAlreadyLoaded(pluginPath)
+		}
+		// The plugin is gone with the previous coordinator, load it again.
+		delete(h.pluginChMap, pluginPath)
+	}
+	ch, ack, exited := make(chan string), make(chan error, 1), make(chan struct{})
+	schedulerName, err := rc.LoadPlugin(pluginPath, ch, ack, exited)
 	if err != nil {
 		return err
 	}
-	c := cluster.GetCoordinator()
-	ch := make(chan string)
-	h.pluginChMap[pluginPath] = ch
-	c.LoadPlugin(pluginPath, ch)
+	h.pluginChMap[pluginPath] = &loadedPlugin{
+		ch:            ch,
+		ack:           ack,
+		exited:        exited,
+		schedulerName: schedulerName,
+		loadTime:      time.Now(),
+	}
 	return nil
 }
 
-// PluginUnload unloads the plugin referenced by the pluginPath
+// PluginUnload unloads the plugin referenced by the pluginPath. It waits
+// until the coordinator stops the plugin.
 func (h *Handler) PluginUnload(pluginPath string) error {
 	h.pluginChMapLock.Lock()
 	defer h.pluginChMapLock.Unlock()
-	if ch, ok := h.pluginChMap[pluginPath]; ok {
-		ch <- cluster.PluginUnload
+	p, ok := h.pluginChMap[pluginPath]
+	if !ok {
+		return ErrPluginNotFound(pluginPath)
+	}
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return err
+	}
+	if !isPluginRunning(rc, p) {
+		delete(h.pluginChMap, pluginPath)
+		return nil
+	}
+	timer := time.NewTimer(pluginUnloadTimeout)
+	defer timer.Stop()
+	select {
+	case p.ch <- cluster.PluginUnload:
+	case <-p.exited:
+		// The plugin is gone with the coordinator stopped meanwhile.
+		delete(h.pluginChMap, pluginPath)
 		return nil
+	case <-timer.C:
+		return errors.Errorf("unload plugin %s timeout", pluginPath)
+	}
+	select {
+	case err := <-p.ack:
+		if err != nil {
+			return err
+		}
+	case <-timer.C:
+		return errors.Errorf("unload plugin %s timeout", pluginPath)
+	}
+	delete(h.pluginChMap, pluginPath)
+	return nil
+}
+
+// GetLoadedPlugins returns the status of the loaded plugins.
+func (h *Handler) GetLoadedPlugins() ([]*PluginStatus, error) {
+	h.pluginChMapLock.RLock()
+	defer h.pluginChMapLock.RUnlock()
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	plugins := make([]*PluginStatus, 0, len(h.pluginChMap))
+	for path, p := range h.pluginChMap {
+		plugins = append(plugins, &PluginStatus{
+			Path:          path,
+			SchedulerName: p.schedulerName,
+			LoadTime:      p.loadTime,
+			Running:       isPluginRunning(rc, p),
+		})
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Path < plugins[j].Path })
+	return plugins, nil
+}
+
+func isPluginRunning(rc *cluster.RaftCluster, p *loadedPlugin) bool {
+	select {
+	case <-p.exited:
+		return false
+	default:
+	}
+	_, ok := rc.GetSchedulers()[p.schedulerName]
+	return ok
+}
+
+// EtcdStatus is the status of the embedded etcd of the server.
+type EtcdStatus struct {
+	DBSize            int64  `json:"db_size"`
+	DBSizeInUse       int64  `json:"db_size_in_use"`
+	Quota             int64  `json:"quota"`
+	Revision          int64  `json:"revision"`
+	CompactedRevision int64  `json:"compacted_revision"`
+	RaftTerm          uint64 `json:"raft_term"`
+	Leader            uint64 `json:"leader"`
+}
+
+// EtcdDefragResult is the result of compacting and defragmenting etcd.
+type EtcdDefragResult struct {
+	CompactedRevision int64    `json:"compacted_revision"`
+	Defragmented      []string `json:"defragmented"`
+}
+
+// GetEtcdStatus returns the status of the embedded etcd.
+func (h *Handler) GetEtcdStatus() (*EtcdStatus, error) {
+	etcd := h.s.GetMember().Etcd()
+	resp, err := etcdutil.GetEtcdStatus(h.s.GetClient(), h.s.GetMember().Member().GetClientUrls()[0])
+	if err != nil {
+		return nil, err
+	}
+	quota := etcd.Server.Cfg.QuotaBackendBytes
+	if quota == 0 {
+		quota = etcdserver.DefaultQuotaBytes
+	}
+	compactedRev := etcd.Server.KV().FirstRev()
+	if compactedRev < 0 {
+		// Never compacted.
+		compactedRev = 0
+	}
+	return &EtcdStatus{
+		DBSize:            resp.DbSize,
+		DBSizeInUse:       resp.DbSizeInUse,
+		Quota:             quota,
+		Revision:          resp.Header.GetRevision(),
+		CompactedRevision: compactedRev,
+		RaftTerm:          resp.RaftTerm,
+		Leader:            resp.Leader,
+	}, nil
+}
+
+// DefragAndCompactEtcd compacts etcd to the current revision, then defragments
+// the members one at a time. The members are checked to be healthy before
+// each defragmentation, and it stops at the first failure.
+func (h *Handler) DefragAndCompactEtcd() (*EtcdDefragResult, error) {
+	if !h.s.GetMember().IsLeader() {
+		return nil, errors.WithStack(ErrNotLeader)
+	}
+	if !atomic.CompareAndSwapInt32(&h.etcdMaintaining, 0, 1) {
+		return nil, ErrEtcdMaintaining
+	}
+	defer atomic.StoreInt32(&h.etcdMaintaining, 0)
+
+	status, err := h.GetEtcdStatus()
+	if err != nil {
+		return nil, err
+	}
+	client := h.s.GetClient()
+	if status.CompactedRevision < status.Revision {
+		if err = etcdutil.CompactEtcd(client, status.Revision); err != nil {
+			return nil, err
+		}
+		log.Info("etcd is compacted", zap.Int64("revision", status.Revision))
+	}
+	result := &EtcdDefragResult{CompactedRevision: status.Revision, Defragmented: []string{}}
+
+	members, err := cluster.GetMembers(client)
+	if err != nil {
+		return result, err
+	}
+	for _, m := range members {
+		if err = checkEtcdHealth(client, members); err != nil {
+			return result, errors.WithMessagef(err, "stop before defragmenting %s", m.GetName())
+		}
+		if len(m.GetClientUrls()) == 0 {
+			return result, errors.Errorf("member %s has no client urls", m.GetName())
+		}
+		if err = etcdutil.DefragmentEtcd(client, m.GetClientUrls()[0]); err != nil {
+			return result, errors.WithMessagef(err, "failed to defragment %s", m.GetName())
+		}
+		log.Info("etcd member is defragmented", zap.String("name", m.GetName()))
+		result.Defragmented = append(result.Defragmented, m.GetName())
+	}
+	if err = checkEtcdHealth(client, members); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// checkEtcdHealth checks that all the etcd members can serve the status requests.
+func checkEtcdHealth(client *clientv3.Client, members []*pdpb.Member) error {
+	for _, m := range members {
+		var err error
+		for _, u := range m.GetClientUrls() {
+			if _, err = etcdutil.GetEtcdStatus(client, u); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return errors.WithMessagef(err, "etcd member %s is unhealthy", m.GetName())
+		}
+	}
+	return nil
+}
+
+// memberEtcdStatusTimeout is the timeout of gathering the etcd status of a
+// member, so that an unreachable member does not slow down the others.
+const memberEtcdStatusTimeout = 3 * time.Second
+
+// MemberEtcdStatus is the etcd status of a PD member.
+type MemberEtcdStatus struct {
+	// Alarms are the active etcd alarms of the member, such as NOSPACE.
+	Alarms       []string `json:"alarms"`
+	LeaseCount   int      `json:"lease_count"`
+	DBSize       int64    `json:"db_size"`
+	IsEtcdLeader bool     `json:"is_etcd_leader"`
+	// Partial means some of the status cannot be gathered, Errors tells why.
+	Partial bool     `json:"partial"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+func (s *MemberEtcdStatus) addError(err error) {
+	s.Partial = true
+	s.Errors = append(s.Errors, err.Error())
+}
+
+// GetMembersEtcdStatus gathers the etcd status of the members concurrently,
+// keyed by the member ID. The members that cannot be reached in time are
+// marked as partial instead of failing the whole request.
+func (h *Handler) GetMembersEtcdStatus(members []*pdpb.Member) map[uint64]*MemberEtcdStatus {
+	client := h.s.GetClient()
+	ctx, cancel := context.WithTimeout(client.Ctx(), memberEtcdStatusTimeout)
+	defer cancel()
+
+	etcdLeader := h.s.GetMember().GetEtcdLeader()
+	statuses := make(map[uint64]*MemberEtcdStatus, len(members))
+	var wg sync.WaitGroup
+	for _, m := range members {
+		status := &MemberEtcdStatus{Alarms: []string{}, IsEtcdLeader: m.GetMemberId() == etcdLeader}
+		statuses[m.GetMemberId()] = status
+		wg.Add(1)
+		go func(m *pdpb.Member) {
+			defer wg.Done()
+			getMemberEtcdStatus(ctx, client, m, status)
+		}(m)
+	}
+	// The alarms are stored in the raft log, so they are listed once for all members.
+	alarms, err := client.AlarmList(ctx)
+	wg.Wait()
+	if err != nil {
+		for _, status := range statuses {
+			status.addError(errors.WithMessage(err, "failed to list alarms"))
+		}
+		return statuses
+	}
+	for _, alarm := range alarms.Alarms {
+		if status, ok := statuses[alarm.GetMemberID()]; ok {
+			status.Alarms = append(status.Alarms, alarm.GetAlarm().String())
+		}
+	}
+	return statuses
+}
+
+func getMemberEtcdStatus(ctx context.Context, client *clientv3.Client, m *pdpb.Member, status *MemberEtcdStatus) {
+	if len(m.GetClientUrls()) == 0 {
+		status.addError(errors.New("no client urls"))
+		return
+	}
+	endpoint := m.GetClientUrls()[0]
+	resp, err := client.Status(ctx, endpoint)
+	if err != nil {
+		status.addError(errors.WithMessage(err, "failed to get status"))
+	} else {
+		status.DBSize = resp.DbSize
+	}
+	count, err := etcdutil.CountEtcdLeases(ctx, client, endpoint)
+	if err != nil {
+		status.addError(errors.WithMessage(err, "failed to count leases"))
+	} else {
+		status.LeaseCount = count
 	}
-	return ErrPluginNotFound(pluginPath)
 }
 
 // GetAddr returns the server urls for clients.
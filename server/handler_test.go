@@ -0,0 +1,101 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/pkg/testutil"
+	"github.com/pingcap/pd/v4/server/schedulers"
+)
+
+var _ = Suite(&testHandlerSuite{})
+
+type testHandlerSuite struct{}
+
+// TestLeaderSwitch restarts the raft cluster repeatedly, as what the leader
+// switch does, while the scheduler and plugin APIs are being called.
+func (s *testHandlerSuite) TestLeaderSwitch(c *C) {
+	svr, cleanup, err := NewTestServer(c)
+	defer cleanup()
+	c.Assert(err, IsNil)
+	mustWaitLeader(c, []*Server{svr})
+	grpcPDClient := testutil.MustNewGrpcClient(c, svr.GetAddr())
+	req := &pdpb.BootstrapRequest{
+		Header: testutil.NewRequestHeader(svr.clusterID),
+		Store:  &metapb.Store{Id: 1, Address: "127.0.0.1:0", Version: "v4.0.0"},
+		Region: &metapb.Region{Id: 2, Peers: []*metapb.Peer{{Id: 3, StoreId: 1}}},
+	}
+	resp, err := grpcPDClient.Bootstrap(context.Background(), req)
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetHeader().GetError(), IsNil)
+
+	h := svr.GetHandler()
+	// The plugin whose coordinator has been stopped is unloaded at once.
+	h.pluginChMap["gone.so"] = &loadedPlugin{ch: make(chan string), ack: make(chan error, 1), exited: make(chan struct{})}
+	close(h.pluginChMap["gone.so"].exited)
+	start := time.Now()
+	c.Assert(h.PluginUnload("gone.so"), IsNil)
+	c.Assert(time.Since(start), Less, pluginUnloadTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	hammer := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				f()
+			}
+		}()
+	}
+	hammer(func() {
+		h.AddShuffleLeaderScheduler()
+		h.RemoveScheduler(schedulers.ShuffleLeaderName)
+	})
+	hammer(func() {
+		h.GetSchedulers()
+		h.GetSchedulerDetail(schedulers.BalanceRegionName)
+		h.IsSchedulerPaused(schedulers.BalanceLeaderName)
+		h.PauseOrResumeScheduler(schedulers.BalanceLeaderName, 0)
+		h.GetSchedulerConfigHandler()
+	})
+	hammer(func() {
+		h.PluginLoad("not-exist.so")
+		h.PluginUnload("not-exist.so")
+		h.GetLoadedPlugins()
+	})
+
+	for i := 0; i < 20; i++ {
+		svr.stopRaftCluster()
+		time.Sleep(5 * time.Millisecond)
+		c.Assert(svr.createRaftCluster(), IsNil)
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+
+	svr.stopRaftCluster()
+	c.Assert(h.AddShuffleLeaderScheduler(), NotNil)
+	c.Assert(svr.createRaftCluster(), IsNil)
+	c.Assert(h.AddShuffleLeaderScheduler(), IsNil)
+	names, err := h.GetSchedulers()
+	c.Assert(err, IsNil)
+	c.Assert(names, Not(HasLen), 0)
+}
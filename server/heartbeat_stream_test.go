@@ -63,7 +63,7 @@ func (s *testHeartbeatStreamSuite) TestActivity(c *C) {
 	c.Assert(err, IsNil)
 	err = s.svr.GetRaftCluster().HandleRegionHeartbeat(core.NewRegionInfo(s.region, s.region.GetPeers()[0]))
 	c.Assert(err, IsNil)
-	err = newHandler(s.svr).AddAddPeerOperator(s.region.GetId(), 2)
+	_, err = newHandler(s.svr).AddAddPeerOperator("", s.region.GetId(), 2)
 	c.Assert(err, IsNil)
 
 	stream1, stream2 := newRegionheartbeatClient(c, s.grpcPDClient), newRegionheartbeatClient(c, s.grpcPDClient)
@@ -58,6 +58,8 @@ type Member struct {
 	// etcd leader key when the PD node is successfully elected as the leader
 	// of the cluster. Every write will use it to check leadership.
 	memberValue string
+	// leaderTerm is the etcd revision at which the PD node is elected as the leader.
+	leaderTerm int64
 }
 
 // NewMember create a new Member.
@@ -253,9 +255,17 @@ func (m *Member) CampaignLeader(lease *LeaderLease, leaseTimeout int64) error {
 	if !resp.Succeeded {
 		return errors.New("failed to campaign leader, other server may campaign ok")
 	}
+	atomic.StoreInt64(&m.leaderTerm, resp.Header.GetRevision())
 	return nil
 }
 
+// GetLeaderTerm returns the term of the last time the PD node is elected as the
+// leader. It is the etcd revision at which the leader key is created, so it
+// increases monotonically across the leader changes of the PD cluster.
+func (m *Member) GetLeaderTerm() int64 {
+	return atomic.LoadInt64(&m.leaderTerm)
+}
+
 // ResignLeader resigns current PD's leadership. If nextLeader is empty, all
 // other pd-servers can campaign.
 func (m *Member) ResignLeader(ctx context.Context, from string, nextLeader string) error {
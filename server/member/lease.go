@@ -79,6 +79,16 @@ func (l *LeaderLease) IsExpired() bool {
 	return time.Now().After(l.expireTime.Load().(time.Time))
 }
 
+// GetRemainingTime returns the remaining time before the lease expires. It
+// returns 0 if the lease is expired.
+func (l *LeaderLease) GetRemainingTime() time.Duration {
+	remaining := time.Until(l.expireTime.Load().(time.Time))
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
 // KeepAlive auto renews the lease and update expireTime.
 func (l *LeaderLease) KeepAlive(ctx context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
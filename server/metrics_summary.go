@@ -0,0 +1,96 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	"github.com/pingcap/pd/v4/pkg/typeutil"
+	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/statistics"
+)
+
+const (
+	// TSORateWindow is the window in which the TSO requests are counted, and
+	// tsoRateBuckets divide it.
+	TSORateWindow  = time.Minute
+	tsoRateBuckets = 60
+)
+
+// RateSummary is the events per second in the recent window.
+type RateSummary struct {
+	Window typeutil.Duration `json:"window"`
+	Rate   float64           `json:"rate"`
+}
+
+// CountSummary is the events by the kind in the recent window.
+type CountSummary struct {
+	Window typeutil.Duration `json:"window"`
+	Counts map[string]uint64 `json:"counts"`
+}
+
+// MetricsSummary is a snapshot of the key metrics, which is assembled from
+// the in-process counters for the deployments without Prometheus. The values
+// without a window are the current ones.
+type MetricsSummary struct {
+	StoreHeartbeats   RateSummary    `json:"store_heartbeats"`
+	RegionHeartbeats  RateSummary    `json:"region_heartbeats"`
+	TSORequests       RateSummary    `json:"tso_requests"`
+	OperatorsCreated  CountSummary   `json:"operators_created"`
+	OperatorsFinished CountSummary   `json:"operators_finished"`
+	RunningOperators  int            `json:"running_operators"`
+	WaitingOperators  int            `json:"waiting_operators"`
+	RegionCount       int            `json:"region_count"`
+	LeaderCount       int            `json:"leader_count"`
+	AbnormalRegions   map[string]int `json:"abnormal_regions"`
+}
+
+var abnormalRegionTypes = map[string]statistics.RegionStatisticType{
+	"miss-peer":    statistics.MissPeer,
+	"extra-peer":   statistics.ExtraPeer,
+	"down-peer":    statistics.DownPeer,
+	"pending-peer": statistics.PendingPeer,
+	"offline-peer": statistics.OfflinePeer,
+	"learner-peer": statistics.LearnerPeer,
+	"empty-region": statistics.EmptyRegion,
+}
+
+// GetMetricsSummary returns the snapshot of the key metrics.
+func (h *Handler) GetMetricsSummary() (*MetricsSummary, error) {
+	c := h.s.GetRaftCluster()
+	if c == nil {
+		return nil, cluster.ErrNotBootstrapped
+	}
+	summary := &MetricsSummary{
+		StoreHeartbeats:   RateSummary{Window: typeutil.NewDuration(cluster.HeartbeatRateWindow)},
+		RegionHeartbeats:  RateSummary{Window: typeutil.NewDuration(cluster.HeartbeatRateWindow)},
+		TSORequests:       RateSummary{Window: typeutil.NewDuration(TSORateWindow), Rate: h.s.tsoRequests.Rate()},
+		OperatorsCreated:  CountSummary{Window: typeutil.NewDuration(schedule.OperatorCountWindow)},
+		OperatorsFinished: CountSummary{Window: typeutil.NewDuration(schedule.OperatorCountWindow)},
+		RegionCount:       c.GetRegionCount(),
+		AbnormalRegions:   make(map[string]int, len(abnormalRegionTypes)),
+	}
+	summary.StoreHeartbeats.Rate, summary.RegionHeartbeats.Rate = c.GetHeartbeatRates()
+	oc := c.GetOperatorController()
+	summary.OperatorsCreated.Counts, summary.OperatorsFinished.Counts = oc.GetOperatorCounts()
+	summary.RunningOperators, summary.WaitingOperators = oc.GetOperatorQueueDepth()
+	for _, store := range c.GetStores() {
+		summary.LeaderCount += store.GetLeaderCount()
+	}
+	for name, typ := range abnormalRegionTypes {
+		summary.AbnormalRegions[name] = c.GetRegionStatsCount(typ)
+	}
+	return summary, nil
+}
@@ -0,0 +1,241 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/pd/v4/server/cluster"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/filter"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"github.com/pingcap/pd/v4/server/schedule/opt"
+)
+
+const precheckScope = "admin-precheck"
+
+// The results of an operator check.
+const (
+	CheckPassed  = "pass"
+	CheckFailed  = "fail"
+	CheckSkipped = "skip"
+)
+
+// OperatorCheck is the result of a check of the operator precheck.
+type OperatorCheck struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// OperatorPrecheck is the verdict on whether an operator would be rejected.
+type OperatorPrecheck struct {
+	Passed bool             `json:"passed"`
+	Checks []*OperatorCheck `json:"checks"`
+}
+
+func (p *OperatorPrecheck) add(name string, failures []string) {
+	check := &OperatorCheck{Name: name, Result: CheckPassed}
+	if len(failures) > 0 {
+		check.Result = CheckFailed
+		check.Detail = strings.Join(failures, "; ")
+		p.Passed = false
+	}
+	p.Checks = append(p.Checks, check)
+}
+
+func (p *OperatorPrecheck) skip(name, reason string) {
+	p.Checks = append(p.Checks, &OperatorCheck{Name: name, Result: CheckSkipped, Detail: reason})
+}
+
+// PrecheckOperators reports which checks would reject the operators built by
+// one of the Build*Operator methods, whose error is passed as buildErr. The
+// operators are not added.
+func (h *Handler) PrecheckOperators(ops []*operator.Operator, buildErr error) (*OperatorPrecheck, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	precheck := &OperatorPrecheck{Passed: true}
+	if buildErr != nil {
+		precheck.add("arguments", []string{buildErr.Error()})
+		return precheck, nil
+	}
+	precheck.add("arguments", nil)
+	h.precheckRegions(c, precheck, ops)
+	h.precheckStores(c, precheck, ops)
+	return precheck, nil
+}
+
+// PrecheckScatterRegionOperator reports which checks would reject the
+// operator to scatter the region. Only the region is checked, as the target
+// stores are chosen when the region is scattered.
+func (h *Handler) PrecheckScatterRegionOperator(regionID uint64) (*OperatorPrecheck, error) {
+	c, region, err := h.checkScatterRegion(regionID)
+	if err != nil {
+		// The cluster which is not bootstrapped is reported as an error by
+		// PrecheckOperators too.
+		return h.PrecheckOperators(nil, err)
+	}
+	precheck := &OperatorPrecheck{Passed: true}
+	precheck.add("arguments", nil)
+	var failures []string
+	if !opt.IsRegionHealthy(c, region) {
+		failures = append(failures, fmt.Sprintf("region %d has down or pending peers", regionID))
+	}
+	precheck.add("region-health", failures)
+	return precheck, nil
+}
+
+func (h *Handler) precheckRegions(c *cluster.RaftCluster, precheck *OperatorPrecheck, ops []*operator.Operator) {
+	var unhealthy, conflicts []string
+	for _, op := range ops {
+		region := c.GetRegion(op.RegionID())
+		if region == nil {
+			unhealthy = append(unhealthy, fmt.Sprintf("region %d is not found", op.RegionID()))
+		} else if !opt.IsRegionHealthy(c, region) {
+			unhealthy = append(unhealthy, fmt.Sprintf("region %d has down or pending peers", op.RegionID()))
+		}
+		if old := c.GetOperatorController().GetConflictingOperator(op); old != nil {
+			conflicts = append(conflicts, fmt.Sprintf("region %d has a running operator %s", op.RegionID(), old.Desc()))
+		}
+	}
+	precheck.add("region-health", unhealthy)
+	precheck.add("operator-conflict", conflicts)
+}
+
+// peerTarget is a store which gains a peer, along with the store the peer
+// replaces, which is 0 if the peer is added only.
+type peerTarget struct {
+	region   *core.RegionInfo
+	store    uint64
+	oldStore uint64
+}
+
+// getOperatorTargets returns the stores which gain the peers and the leaders
+// by the operators.
+func getOperatorTargets(c *cluster.RaftCluster, ops []*operator.Operator) ([]peerTarget, []uint64) {
+	var peerTargets []peerTarget
+	var leaderTargets []uint64
+	for _, op := range ops {
+		region := c.GetRegion(op.RegionID())
+		var adds, removes []uint64
+		for i := 0; i < op.Len(); i++ {
+			switch step := op.Step(i).(type) {
+			case operator.AddPeer:
+				adds = append(adds, step.ToStore)
+			case operator.AddLearner:
+				adds = append(adds, step.ToStore)
+			case operator.AddLightPeer:
+				adds = append(adds, step.ToStore)
+			case operator.AddLightLearner:
+				adds = append(adds, step.ToStore)
+			case operator.RemovePeer:
+				removes = append(removes, step.FromStore)
+			case operator.TransferLeader:
+				leaderTargets = append(leaderTargets, step.ToStore)
+			}
+		}
+		for i, store := range adds {
+			target := peerTarget{region: region, store: store}
+			if i < len(removes) {
+				target.oldStore = removes[i]
+			}
+			peerTargets = append(peerTargets, target)
+		}
+	}
+	return peerTargets, leaderTargets
+}
+
+func (h *Handler) precheckStores(c *cluster.RaftCluster, precheck *OperatorPrecheck, ops []*operator.Operator) {
+	peerTargets, leaderTargets := getOperatorTargets(c, ops)
+	rejectedBy := func(storeID uint64, filters ...filter.Filter) string {
+		store := c.GetStore(storeID)
+		if store == nil {
+			return fmt.Sprintf("store %d is not found", storeID)
+		}
+		if f := filter.TargetRejectedBy(c, store, filters); f != nil {
+			return fmt.Sprintf("store %d is rejected by %s", storeID, f.Type())
+		}
+		return ""
+	}
+	stores := make([]uint64, 0, len(peerTargets))
+	for _, target := range peerTargets {
+		stores = append(stores, target.store)
+	}
+	if len(stores) == 0 && len(leaderTargets) == 0 {
+		precheck.skip("store-state", "no store is targeted")
+	} else {
+		var failures []string
+		for _, id := range stores {
+			if failure := rejectedBy(id, filter.NewStateFilter(precheckScope), filter.NewHealthFilter(precheckScope)); failure != "" {
+				failures = append(failures, failure)
+			}
+		}
+		for _, id := range leaderTargets {
+			if failure := rejectedBy(id, filter.StoreStateFilter{ActionScope: precheckScope, TransferLeader: true}); failure != "" {
+				failures = append(failures, failure)
+			}
+		}
+		precheck.add("store-state", failures)
+	}
+	if len(stores) == 0 {
+		precheck.skip("snapshot", "no peer is added")
+	} else {
+		var failures []string
+		for _, id := range stores {
+			if failure := rejectedBy(id, filter.NewSnapshotCountFilter(precheckScope), filter.NewPendingPeerCountFilter(precheckScope)); failure != "" {
+				failures = append(failures, failure)
+			}
+		}
+		precheck.add("snapshot", failures)
+	}
+
+	var failures []string
+	if storeID := c.GetOperatorController().CheckStoreLimit(ops...); storeID != 0 {
+		failures = append(failures, fmt.Sprintf("store %d exceeds the store limit", storeID))
+	}
+	precheck.add("store-limit", failures)
+
+	h.precheckPlacement(c, precheck, peerTargets, rejectedBy)
+}
+
+// precheckPlacement checks if the isolation of the regions decreases, against
+// the placement rules if they are enabled, or the location labels otherwise.
+func (h *Handler) precheckPlacement(c *cluster.RaftCluster, precheck *OperatorPrecheck, targets []peerTarget, rejectedBy func(uint64, ...filter.Filter) string) {
+	if len(targets) == 0 {
+		precheck.skip("placement", "no peer is added")
+		return
+	}
+	var failures []string
+	for _, target := range targets {
+		if target.region == nil {
+			continue
+		}
+		var f filter.Filter
+		if c.IsPlacementRulesEnabled() {
+			f = filter.NewRuleFitFilter(precheckScope, c, target.region, target.oldStore)
+		} else if oldStore := c.GetStore(target.oldStore); oldStore != nil {
+			f = filter.NewDistinctScoreFilter(precheckScope, c.GetLocationLabels(), c.GetRegionStores(target.region), oldStore)
+		} else {
+			// The isolation is only compared when a peer is replaced.
+			continue
+		}
+		if failure := rejectedBy(target.store, f); failure != "" {
+			failures = append(failures, failure)
+		}
+	}
+	precheck.add("placement", failures)
+}
@@ -35,16 +35,38 @@ const (
 	keepaliveTimeout = 3 * time.Second
 )
 
+// SyncStatus is the status of syncing with the leader on a follower.
+type SyncStatus struct {
+	// Leader is the name of the leader synced with, which is empty if it is
+	// not syncing.
+	Leader string `json:"leader"`
+	// AppliedIndex is the index next to the last record applied.
+	AppliedIndex uint64    `json:"applied_index"`
+	LastSyncTime time.Time `json:"last_sync_time"`
+}
+
 // StopSyncWithLeader stop to sync the region with leader.
 func (s *RegionSyncer) StopSyncWithLeader() {
 	s.reset()
 	s.Lock()
 	close(s.closed)
 	s.closed = make(chan struct{})
+	s.syncLeader = ""
 	s.Unlock()
 	s.wg.Wait()
 }
 
+// GetSyncStatus returns the status of syncing with the leader.
+func (s *RegionSyncer) GetSyncStatus() *SyncStatus {
+	s.RLock()
+	defer s.RUnlock()
+	return &SyncStatus{
+		Leader:       s.syncLeader,
+		AppliedIndex: s.history.GetNextIndex(),
+		LastSyncTime: s.lastSyncTime,
+	}
+}
+
 func (s *RegionSyncer) reset() {
 	s.Lock()
 	defer s.Unlock()
@@ -165,6 +187,9 @@ func (s *RegionSyncer) StartSyncWithLeader(addr string) {
 				continue
 			}
 			log.Info("server starts to synchronize with leader", zap.String("server", s.server.Name()), zap.String("leader", s.server.GetLeader().GetName()), zap.Uint64("request-index", s.history.GetNextIndex()))
+			s.Lock()
+			s.syncLeader = s.server.GetLeader().GetName()
+			s.Unlock()
 			for {
 				resp, err := stream.Recv()
 				if err != nil {
@@ -206,6 +231,9 @@ func (s *RegionSyncer) StartSyncWithLeader(addr string) {
 						s.history.Record(region)
 					}
 				}
+				s.Lock()
+				s.lastSyncTime = time.Now()
+				s.Unlock()
 			}
 		}
 	}()
@@ -23,6 +23,15 @@ var regionSyncerStatus = prometheus.NewGaugeVec(
 		Help:      "Inner status of the region syncer.",
 	}, []string{"type"})
 
+var followerLag = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "pd",
+		Subsystem: "region_syncer",
+		Name:      "follower_lag",
+		Help:      "The lag of the followers in records and seconds.",
+	}, []string{"follower", "type"})
+
 func init() {
 	prometheus.MustRegister(regionSyncerStatus)
+	prometheus.MustRegister(followerLag)
 }
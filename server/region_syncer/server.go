@@ -64,10 +64,60 @@ type Server interface {
 	GetBasicCluster() *core.BasicCluster
 }
 
+var (
+	// ErrFollowerNotFound is error info for no sync stream to the follower.
+	ErrFollowerNotFound = errors.New("no region sync stream to the follower")
+	// ErrResyncInProgress is error info for resyncing a follower which is
+	// being resynced.
+	ErrResyncInProgress = errors.New("resync in progress")
+)
+
+// syncStream is the established stream to a follower. The sends are
+// serialized by the lock.
+type syncStream struct {
+	sync.Mutex
+	ServerStream
+	// sentIndex is the index next to the last record sent successfully.
+	sentIndex uint64
+	sentTime  time.Time
+	// resyncing means the snapshot is being sent to the stream without the
+	// lock, so the broadcasts skip it, and the records skipped are sent after
+	// the snapshot.
+	resyncing bool
+}
+
+func (s *syncStream) send(resp *pdpb.SyncRegionResponse) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.resyncing {
+		return nil
+	}
+	if err := s.Send(resp); err != nil {
+		return err
+	}
+	s.sentIndex = resp.GetStartIndex() + uint64(len(resp.GetRegions()))
+	s.sentTime = time.Now()
+	return nil
+}
+
+// FollowerStatus is the status of the sync stream to a follower on the leader.
+type FollowerStatus struct {
+	Name string `json:"name"`
+	// SentIndex is the index next to the last record sent to the follower
+	// successfully. The follower acks nothing, so it is the closest to the
+	// acked index, and is comparable with the applied index of the follower.
+	SentIndex   uint64 `json:"sent_index"`
+	LeaderIndex uint64 `json:"leader_index"`
+	LagRecords  uint64 `json:"lag_records"`
+	// LagSeconds is the time since the last send if the follower lags.
+	LagSeconds   float64   `json:"lag_seconds"`
+	LastSentTime time.Time `json:"last_sent_time"`
+}
+
 // RegionSyncer is used to sync the region information without raft.
 type RegionSyncer struct {
 	sync.RWMutex
-	streams            map[string]ServerStream
+	streams            map[string]*syncStream
 	regionSyncerCtx    context.Context
 	regionSyncerCancel context.CancelFunc
 	server             Server
@@ -76,6 +126,10 @@ type RegionSyncer struct {
 	history            *historyBuffer
 	limit              *ratelimit.Bucket
 	securityConfig     *grpcutil.SecurityConfig
+	// syncLeader and lastSyncTime are the status of syncing with the leader
+	// on a follower.
+	syncLeader   string
+	lastSyncTime time.Time
 }
 
 // NewRegionSyncer returns a region syncer.
@@ -85,7 +139,7 @@ type RegionSyncer struct {
 // no longer etcd but go-leveldb.
 func NewRegionSyncer(s Server) *RegionSyncer {
 	return &RegionSyncer{
-		streams:        make(map[string]ServerStream),
+		streams:        make(map[string]*syncStream),
 		server:         s,
 		closed:         make(chan struct{}),
 		history:        newHistoryBuffer(defaultHistoryBufferSize, s.GetStorage().GetRegionStorage()),
@@ -154,11 +208,12 @@ func (s *RegionSyncer) Sync(stream pdpb.PD_SyncRegionsServer) error {
 			zap.String("requested-server", request.GetMember().GetName()),
 			zap.String("url", request.GetMember().GetClientUrls()[0]))
 
+		nextIndex := s.history.GetNextIndex()
 		err = s.syncHistoryRegion(request, stream)
 		if err != nil {
 			return err
 		}
-		s.bindStream(request.GetMember().GetName(), stream)
+		s.bindStream(request.GetMember().GetName(), stream, nextIndex)
 	}
 }
 
@@ -174,33 +229,7 @@ func (s *RegionSyncer) syncHistoryRegion(request *pdpb.SyncRegionRequest, stream
 		}
 		// do full synchronization
 		if startIndex == 0 {
-			regions := s.server.GetRegions()
-			lastIndex := 0
-			start := time.Now()
-			metas := make([]*metapb.Region, 0, maxSyncRegionBatchSize)
-			stats := make([]*pdpb.RegionStat, 0, maxSyncRegionBatchSize)
-			for syncedIndex, r := range regions {
-				metas = append(metas, r.GetMeta())
-				stats = append(stats, r.GetStat())
-				if len(metas) < maxSyncRegionBatchSize && syncedIndex < len(regions)-1 {
-					continue
-				}
-				resp := &pdpb.SyncRegionResponse{
-					Header:      &pdpb.ResponseHeader{ClusterId: s.server.ClusterID()},
-					Regions:     metas,
-					StartIndex:  uint64(lastIndex),
-					RegionStats: stats,
-				}
-				s.limit.Wait(int64(resp.Size()))
-				lastIndex += len(metas)
-				if err := stream.Send(resp); err != nil {
-					log.Error("failed to send sync region response", zap.Error(err))
-				}
-				metas = metas[:0]
-				stats = stats[:0]
-			}
-			log.Info("requested server has completed full synchronization with server",
-				zap.String("requested-server", name), zap.String("server", s.server.Name()), zap.Duration("cost", time.Since(start)))
+			s.syncAllRegions(name, stream)
 			return nil
 		}
 		log.Warn("no history regions from index, the leader may be restarted", zap.Uint64("index", startIndex))
@@ -226,30 +255,162 @@ func (s *RegionSyncer) syncHistoryRegion(request *pdpb.SyncRegionRequest, stream
 	return stream.Send(resp)
 }
 
-// bindStream binds the established server stream.
-func (s *RegionSyncer) bindStream(name string, stream ServerStream) {
+// syncAllRegions sends all the regions to the stream, which resets the index
+// of the follower to 0.
+func (s *RegionSyncer) syncAllRegions(name string, stream ServerStream) {
+	regions := s.server.GetRegions()
+	lastIndex := 0
+	start := time.Now()
+	metas := make([]*metapb.Region, 0, maxSyncRegionBatchSize)
+	stats := make([]*pdpb.RegionStat, 0, maxSyncRegionBatchSize)
+	for syncedIndex, r := range regions {
+		metas = append(metas, r.GetMeta())
+		stats = append(stats, r.GetStat())
+		if len(metas) < maxSyncRegionBatchSize && syncedIndex < len(regions)-1 {
+			continue
+		}
+		resp := &pdpb.SyncRegionResponse{
+			Header:      &pdpb.ResponseHeader{ClusterId: s.server.ClusterID()},
+			Regions:     metas,
+			StartIndex:  uint64(lastIndex),
+			RegionStats: stats,
+		}
+		s.limit.Wait(int64(resp.Size()))
+		lastIndex += len(metas)
+		if err := stream.Send(resp); err != nil {
+			log.Error("failed to send sync region response", zap.Error(err))
+		}
+		metas = metas[:0]
+		stats = stats[:0]
+	}
+	log.Info("requested server has completed full synchronization with server",
+		zap.String("requested-server", name), zap.String("server", s.server.Name()), zap.Duration("cost", time.Since(start)))
+}
+
+// bindStream binds the established server stream, which has been sent the
+// records before the next index.
+func (s *RegionSyncer) bindStream(name string, stream ServerStream, nextIndex uint64) {
 	s.Lock()
 	defer s.Unlock()
-	s.streams[name] = stream
+	s.streams[name] = &syncStream{ServerStream: stream, sentIndex: nextIndex, sentTime: time.Now()}
 }
 
 func (s *RegionSyncer) broadcast(regions *pdpb.SyncRegionResponse) {
-	var failed []string
+	// The streams are sent without the lock, as a stream may be resyncing.
 	s.RLock()
-	for name, sender := range s.streams {
-		err := sender.Send(regions)
+	streams := make(map[string]*syncStream, len(s.streams))
+	for name, stream := range s.streams {
+		streams[name] = stream
+	}
+	s.RUnlock()
+	var failed []string
+	for name, stream := range streams {
+		err := stream.send(regions)
 		if err != nil {
 			log.Error("region syncer send data meet error", zap.Error(err))
 			failed = append(failed, name)
+			continue
 		}
+		status := s.getFollowerStatus(name, stream)
+		followerLag.WithLabelValues(name, "records").Set(float64(status.LagRecords))
+		followerLag.WithLabelValues(name, "seconds").Set(status.LagSeconds)
 	}
-	s.RUnlock()
 	if len(failed) > 0 {
 		s.Lock()
 		for _, name := range failed {
+			// The stream may be established again.
+			if s.streams[name] != streams[name] {
+				continue
+			}
 			delete(s.streams, name)
+			followerLag.DeleteLabelValues(name, "records")
+			followerLag.DeleteLabelValues(name, "seconds")
 			log.Info("region syncer delete the stream", zap.String("stream", name))
 		}
 		s.Unlock()
 	}
 }
+
+// GetFollowerStatus returns the status of the sync stream to the follower on
+// the leader, or nil if there is no stream to it.
+func (s *RegionSyncer) GetFollowerStatus(name string) *FollowerStatus {
+	s.RLock()
+	defer s.RUnlock()
+	stream, ok := s.streams[name]
+	if !ok {
+		return nil
+	}
+	return s.getFollowerStatus(name, stream)
+}
+
+func (s *RegionSyncer) getFollowerStatus(name string, stream *syncStream) *FollowerStatus {
+	stream.Lock()
+	defer stream.Unlock()
+	status := &FollowerStatus{
+		Name:         name,
+		SentIndex:    stream.sentIndex,
+		LeaderIndex:  s.history.GetNextIndex(),
+		LastSentTime: stream.sentTime,
+	}
+	if status.LeaderIndex > status.SentIndex {
+		status.LagRecords = status.LeaderIndex - status.SentIndex
+		status.LagSeconds = time.Since(stream.sentTime).Seconds()
+	}
+	return status
+}
+
+// Resync sends all the regions to the follower as a snapshot, which rebuilds
+// the regions of the follower, and returns after the snapshot is sent. The
+// snapshot is sent without the lock of the stream, so the broadcasts to the
+// other followers are not blocked by the rate limit, and the records broadcast
+// meanwhile are sent to the follower after the snapshot.
+func (s *RegionSyncer) Resync(name string) error {
+	s.RLock()
+	stream, ok := s.streams[name]
+	s.RUnlock()
+	if !ok {
+		return errors.WithStack(ErrFollowerNotFound)
+	}
+	stream.Lock()
+	if stream.resyncing {
+		stream.Unlock()
+		return errors.WithStack(ErrResyncInProgress)
+	}
+	stream.resyncing = true
+	nextIndex := s.history.GetNextIndex()
+	stream.Unlock()
+
+	log.Info("resync all the regions with server", zap.String("requested-server", name))
+	s.syncAllRegions(name, stream.ServerStream)
+
+	stream.Lock()
+	defer stream.Unlock()
+	stream.resyncing = false
+	// The records after the snapshot reset the index of the follower back to
+	// the one of the leader.
+	records := s.history.RecordsFrom(nextIndex)
+	if len(records) == 0 {
+		stream.sentIndex, stream.sentTime = nextIndex, time.Now()
+		if s.history.GetNextIndex() != nextIndex {
+			return errors.Errorf("the records since index %d are evicted during the resync", nextIndex)
+		}
+		return nil
+	}
+	regions := make([]*metapb.Region, len(records))
+	stats := make([]*pdpb.RegionStat, len(records))
+	for i, r := range records {
+		regions[i] = r.GetMeta()
+		stats[i] = r.GetStat()
+	}
+	resp := &pdpb.SyncRegionResponse{
+		Header:      &pdpb.ResponseHeader{ClusterId: s.server.ClusterID()},
+		Regions:     regions,
+		StartIndex:  nextIndex,
+		RegionStats: stats,
+	}
+	if err := stream.Send(resp); err != nil {
+		return errors.WithStack(err)
+	}
+	stream.sentIndex, stream.sentTime = nextIndex+uint64(len(records)), time.Now()
+	return nil
+}
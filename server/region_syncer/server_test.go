@@ -0,0 +1,118 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/ratelimit"
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/kv"
+	"github.com/pkg/errors"
+)
+
+var _ = Suite(&testServerSuite{})
+
+type testServerSuite struct{}
+
+type mockServer struct {
+	Server
+	regions []*core.RegionInfo
+}
+
+func (s *mockServer) ClusterID() uint64              { return 1 }
+func (s *mockServer) Name() string                   { return "leader" }
+func (s *mockServer) GetRegions() []*core.RegionInfo { return s.regions }
+
+// mockServerStream blocks the sends of the snapshot until it is unblocked.
+type mockServerStream struct {
+	sync.Mutex
+	sent      []*pdpb.SyncRegionResponse
+	snapshot  chan struct{}
+	unblocked chan struct{}
+}
+
+func (s *mockServerStream) Send(resp *pdpb.SyncRegionResponse) error {
+	if resp.GetStartIndex() == 0 && len(resp.GetRegions()) > 0 {
+		s.snapshot <- struct{}{}
+		<-s.unblocked
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.sent = append(s.sent, resp)
+	return nil
+}
+
+func (s *mockServerStream) getSent() []*pdpb.SyncRegionResponse {
+	s.Lock()
+	defer s.Unlock()
+	return append(s.sent[:0:0], s.sent...)
+}
+
+func (t *testServerSuite) TestResync(c *C) {
+	newRegion := func(id uint64) *core.RegionInfo {
+		return core.NewRegionInfo(&metapb.Region{Id: id}, nil)
+	}
+	s := &RegionSyncer{
+		streams: make(map[string]*syncStream),
+		server:  &mockServer{regions: []*core.RegionInfo{newRegion(1), newRegion(2)}},
+		history: newHistoryBuffer(100, kv.NewMemoryKV()),
+		limit:   ratelimit.NewBucketWithRate(defaultBucketRate, defaultBucketCapacity),
+	}
+	for id := uint64(1); id <= 2; id++ {
+		s.history.Record(newRegion(id))
+	}
+	stream := &mockServerStream{snapshot: make(chan struct{}), unblocked: make(chan struct{})}
+	s.bindStream("follower", stream, s.history.GetNextIndex())
+	c.Assert(errors.Cause(s.Resync("unknown")), Equals, ErrFollowerNotFound)
+
+	done := make(chan error)
+	go func() { done <- s.Resync("follower") }()
+	<-stream.snapshot
+	c.Assert(errors.Cause(s.Resync("follower")), Equals, ErrResyncInProgress)
+	// The broadcast is not blocked by the snapshot being sent, and the record
+	// is sent after the snapshot.
+	s.history.Record(newRegion(3))
+	broadcasted := make(chan struct{})
+	go func() {
+		s.broadcast(&pdpb.SyncRegionResponse{StartIndex: 2, Regions: []*metapb.Region{{Id: 3}}})
+		close(broadcasted)
+	}()
+	select {
+	case <-broadcasted:
+	case <-time.After(5 * time.Second):
+		c.Fatal("the broadcast is blocked by the resync")
+	}
+	c.Assert(stream.getSent(), HasLen, 0)
+	close(stream.unblocked)
+	c.Assert(<-done, IsNil)
+
+	sent := stream.getSent()
+	c.Assert(sent, HasLen, 2)
+	c.Assert(sent[0].GetStartIndex(), Equals, uint64(0))
+	c.Assert(sent[0].GetRegions(), HasLen, 2)
+	c.Assert(sent[1].GetStartIndex(), Equals, uint64(2))
+	c.Assert(sent[1].GetRegions(), DeepEquals, []*metapb.Region{{Id: 3}})
+	c.Assert(s.GetFollowerStatus("follower").LagRecords, Equals, uint64(0))
+
+	// The follower is synced as usual after the resync.
+	s.history.Record(newRegion(4))
+	s.broadcast(&pdpb.SyncRegionResponse{StartIndex: 3, Regions: []*metapb.Region{{Id: 4}}})
+	c.Assert(stream.getSent(), HasLen, 3)
+	c.Assert(s.GetFollowerStatus("follower").SentIndex, Equals, uint64(4))
+}
@@ -0,0 +1,229 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+const (
+	// downPeerRetryBackoff is the backoff before retrying a region failed to
+	// be fixed, which is doubled by each failure up to maxDownPeerRetryBackoff.
+	downPeerRetryBackoff    = time.Second
+	maxDownPeerRetryBackoff = time.Minute
+	// maxDownPeerHoldRetries is the number of the failures after which a
+	// region no longer holds back the less urgent ones, so that a region which
+	// cannot be fixed, such as for no store matching the location labels, does
+	// not starve the others.
+	maxDownPeerHoldRetries = 5
+)
+
+// downPeerCandidate is a region with a down peer to be fixed.
+type downPeerCandidate struct {
+	regionID uint64
+	// healthy is the number of the voters which are neither down nor pending.
+	healthy     int
+	downSeconds uint64
+	retries     int
+	nextRetry   time.Time
+	// ready is true if the candidate is in the ready heap, otherwise it is in
+	// the backoff heap.
+	ready bool
+	index int
+}
+
+// moreUrgent returns true if the candidate should be fixed before the other,
+// as it has fewer healthy voters, or its peer has been down longer.
+func (c *downPeerCandidate) moreUrgent(o *downPeerCandidate) bool {
+	if c.healthy != o.healthy {
+		return c.healthy < o.healthy
+	}
+	if c.downSeconds != o.downSeconds {
+		return c.downSeconds > o.downSeconds
+	}
+	return c.regionID < o.regionID
+}
+
+func (c *downPeerCandidate) holds() bool {
+	return c.retries < maxDownPeerHoldRetries
+}
+
+type candidateHeap struct {
+	items []*downPeerCandidate
+	less  func(a, b *downPeerCandidate) bool
+}
+
+func (h *candidateHeap) Len() int { return len(h.items) }
+
+func (h *candidateHeap) Less(i, j int) bool { return h.less(h.items[i], h.items[j]) }
+
+func (h *candidateHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *candidateHeap) Push(x interface{}) {
+	item := x.(*downPeerCandidate)
+	item.index = len(h.items)
+	h.items = append(h.items, item)
+}
+
+func (h *candidateHeap) Pop() interface{} {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items = h.items[:n-1]
+	return item
+}
+
+// downPeerQueue tracks the regions with the down peers which are seen but
+// deferred by the replica checker, either because they fail to be fixed, such
+// as for the store limits, or because there are more urgent ones. The ready
+// ones are checked again in the order of the urgency, and the failed ones are
+// retried after the backoff. It is threadsafe.
+type downPeerQueue struct {
+	sync.Mutex
+	candidates map[uint64]*downPeerCandidate
+	ready      *candidateHeap
+	backoff    *candidateHeap
+	// holding is the number of the candidates holding back the less urgent
+	// ones by the number of their healthy voters.
+	holding map[int]int
+	now     func() time.Time
+}
+
+func newDownPeerQueue() *downPeerQueue {
+	return &downPeerQueue{
+		candidates: make(map[uint64]*downPeerCandidate),
+		ready:      &candidateHeap{less: (*downPeerCandidate).moreUrgent},
+		backoff: &candidateHeap{less: func(a, b *downPeerCandidate) bool {
+			return a.nextRetry.Before(b.nextRetry)
+		}},
+		holding: make(map[int]int),
+		now:     time.Now,
+	}
+}
+
+// isBackingOff returns true if the region is waiting to be retried.
+func (q *downPeerQueue) isBackingOff(regionID uint64) bool {
+	q.Lock()
+	defer q.Unlock()
+	c, ok := q.candidates[regionID]
+	return ok && !c.ready && q.now().Before(c.nextRetry)
+}
+
+// holdBack returns true if there are more urgent regions with fewer healthy
+// voters, which should take the store limits first.
+func (q *downPeerQueue) holdBack(healthy int) bool {
+	q.Lock()
+	defer q.Unlock()
+	for h, count := range q.holding {
+		if h < healthy && count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// push defers the region. A failed one is retried after the backoff, and the
+// others are ready to be checked again.
+func (q *downPeerQueue) push(regionID uint64, healthy int, downSeconds uint64, failed bool) {
+	q.Lock()
+	defer q.Unlock()
+	c, ok := q.candidates[regionID]
+	if ok {
+		q.removeLocked(c)
+	} else {
+		c = &downPeerCandidate{regionID: regionID}
+	}
+	c.healthy, c.downSeconds = healthy, downSeconds
+	switch {
+	case failed:
+		c.retries++
+		backoff := downPeerRetryBackoff
+		for i := 1; i < c.retries && backoff < maxDownPeerRetryBackoff; i++ {
+			backoff *= 2
+		}
+		if backoff > maxDownPeerRetryBackoff {
+			backoff = maxDownPeerRetryBackoff
+		}
+		c.nextRetry = q.now().Add(backoff)
+		c.ready = false
+		heap.Push(q.backoff, c)
+	case ok && !c.ready && q.now().Before(c.nextRetry):
+		// It keeps waiting for the retry.
+		heap.Push(q.backoff, c)
+	default:
+		c.ready = true
+		heap.Push(q.ready, c)
+	}
+	q.candidates[regionID] = c
+	if c.holds() {
+		q.holding[c.healthy]++
+	}
+}
+
+// remove forgets the region, which is fixed, or no longer has down peers.
+func (q *downPeerQueue) remove(regionID uint64) {
+	q.Lock()
+	defer q.Unlock()
+	if c, ok := q.candidates[regionID]; ok {
+		q.removeLocked(c)
+		delete(q.candidates, regionID)
+	}
+}
+
+func (q *downPeerQueue) removeLocked(c *downPeerCandidate) {
+	if c.ready {
+		heap.Remove(q.ready, c.index)
+	} else {
+		heap.Remove(q.backoff, c.index)
+	}
+	if c.holds() {
+		if q.holding[c.healthy]--; q.holding[c.healthy] == 0 {
+			delete(q.holding, c.healthy)
+		}
+	}
+}
+
+// getReady returns at most limit regions ready to be checked again, the most
+// urgent first. The regions are kept until they are pushed or removed.
+func (q *downPeerQueue) getReady(limit int) []uint64 {
+	q.Lock()
+	defer q.Unlock()
+	now := q.now()
+	for q.backoff.Len() > 0 && !now.Before(q.backoff.items[0].nextRetry) {
+		c := heap.Pop(q.backoff).(*downPeerCandidate)
+		c.ready = true
+		heap.Push(q.ready, c)
+	}
+	var popped []*downPeerCandidate
+	for q.ready.Len() > 0 && len(popped) < limit {
+		popped = append(popped, heap.Pop(q.ready).(*downPeerCandidate))
+	}
+	regionIDs := make([]uint64, 0, len(popped))
+	for _, c := range popped {
+		regionIDs = append(regionIDs, c.regionID)
+		heap.Push(q.ready, c)
+	}
+	return regionIDs
+}
+
+func (q *downPeerQueue) len() int {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.candidates)
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"fmt"
+
+	"github.com/pingcap/pd/v4/server/schedule/filter"
+	"github.com/pingcap/pd/v4/server/schedule/opt"
+)
+
+// Explanation is how a checker handles a region, which is collected by
+// checking the region in the explain mode.
+type Explanation struct {
+	Checker string `json:"checker"`
+	// Results are the results of the branches taken by the checker, which are
+	// the labels of the checker metrics, such as "no-store-down".
+	Results []string `json:"results"`
+	// Events are the decisions made on the region, which are the same as the
+	// events of the region trace.
+	Events []string `json:"events"`
+	// RejectedStores are the stores rejected as the targets, by the types of
+	// the filters rejecting them.
+	RejectedStores map[string][]uint64 `json:"rejected_stores,omitempty"`
+	// Operator is the operator the checker creates, which is not added.
+	Operator string `json:"operator,omitempty"`
+	// Notes are why the operator would not be created or added by the patrol
+	// besides the checker, such as the replica schedule limit is reached.
+	Notes []string `json:"notes,omitempty"`
+}
+
+func newExplanation(checker string) *Explanation {
+	return &Explanation{Checker: checker, Results: []string{}, Events: []string{}}
+}
+
+func (e *Explanation) addEvent(format string, args ...interface{}) {
+	e.Events = append(e.Events, fmt.Sprintf(format, args...))
+}
+
+// rejectStores records the stores of the cluster rejected as the targets by
+// the filters. A store is recorded once for a filter type even if the targets
+// are selected more than once.
+func (e *Explanation) rejectStores(cluster opt.Cluster, filters []filter.Filter) {
+	for _, store := range cluster.GetStores() {
+		f := filter.TargetRejectedBy(cluster, store, filters)
+		if f == nil {
+			continue
+		}
+		if e.RejectedStores == nil {
+			e.RejectedStores = make(map[string][]uint64)
+		}
+		if !containsStore(e.RejectedStores[f.Type()], store.GetID()) {
+			e.RejectedStores[f.Type()] = append(e.RejectedStores[f.Type()], store.GetID())
+		}
+	}
+}
+
+func containsStore(storeIDs []uint64, storeID uint64) bool {
+	for _, id := range storeIDs {
+		if id == storeID {
+			return true
+		}
+	}
+	return false
+}
@@ -58,11 +58,19 @@ func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
 	expireTime := m.startTime.Add(m.cluster.GetSplitMergeInterval())
 	if time.Now().Before(expireTime) {
 		checkerCounter.WithLabelValues("merge_checker", "recently-start").Inc()
+		m.trace(region, "skip because PD started recently")
 		return nil
 	}
 
 	if m.splitCache.Exists(region.GetID()) {
 		checkerCounter.WithLabelValues("merge_checker", "recently-split").Inc()
+		m.trace(region, "skip because the region is split recently")
+		return nil
+	}
+
+	if m.cluster.IsRegionScheduleLocked(region) {
+		checkerCounter.WithLabelValues("merge_checker", "schedule-locked").Inc()
+		m.trace(region, "skip because the region is schedule locked")
 		return nil
 	}
 
@@ -74,6 +82,7 @@ func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
 	// thus here when size is 0, just skip.
 	if region.GetApproximateSize() == 0 {
 		checkerCounter.WithLabelValues("merge_checker", "skip").Inc()
+		m.trace(region, "skip because the region size is unknown")
 		return nil
 	}
 
@@ -81,23 +90,27 @@ func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
 	if region.GetApproximateSize() > int64(m.cluster.GetMaxMergeRegionSize()) ||
 		region.GetApproximateKeys() > int64(m.cluster.GetMaxMergeRegionKeys()) {
 		checkerCounter.WithLabelValues("merge_checker", "no-need").Inc()
+		m.trace(region, "skip because the region is not small enough")
 		return nil
 	}
 
 	// skip region has down peers or pending peers or learner peers
 	if !opt.IsRegionHealthy(m.cluster, region) {
 		checkerCounter.WithLabelValues("merge_checker", "special-peer").Inc()
+		m.trace(region, "skip because the region is unhealthy")
 		return nil
 	}
 
 	if !opt.IsRegionReplicated(m.cluster, region) {
 		checkerCounter.WithLabelValues("merge_checker", "abnormal-replica").Inc()
+		m.trace(region, "skip because the region is not fully replicated")
 		return nil
 	}
 
 	// skip hot region
 	if m.cluster.IsRegionHot(region) {
 		checkerCounter.WithLabelValues("merge_checker", "hot-region").Inc()
+		m.trace(region, "skip because the region is hot")
 		return nil
 	}
 
@@ -114,7 +127,18 @@ func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
 	}
 
 	if target == nil {
+		// The region is not adjacent to any region on a side in the cache,
+		// though it must be in TiKV, so the cache has a gap there.
+		if (prev == nil && len(region.GetStartKey()) > 0) || (next == nil && len(region.GetEndKey()) > 0) {
+			checkerCounter.WithLabelValues("merge_checker", "cache-gap").Inc()
+			if r, ok := m.cluster.(regionGapRepairer); ok {
+				r.RepairRegionGaps(region)
+			}
+			m.trace(region, "no adjacent region to merge with, wait for the heartbeats to fill the gap in the cache")
+			return nil
+		}
 		checkerCounter.WithLabelValues("merge_checker", "no-target").Inc()
+		m.trace(region, "no adjacent region to merge with")
 		return nil
 	}
 
@@ -122,9 +146,11 @@ func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
 	ops, err := operator.CreateMergeRegionOperator("merge-region", m.cluster, region, target, operator.OpMerge)
 	if err != nil {
 		log.Warn("create merge region operator failed", zap.Error(err))
+		m.trace(region, "failed to create merge operator: %v", err)
 		return nil
 	}
 	checkerCounter.WithLabelValues("merge_checker", "new-operator").Inc()
+	m.trace(region, "merge with region %d by operator %s", target.GetID(), ops[0])
 	if region.GetApproximateSize() > target.GetApproximateSize() ||
 		region.GetApproximateKeys() > target.GetApproximateKeys() {
 		checkerCounter.WithLabelValues("merge_checker", "larger-source").Inc()
@@ -132,8 +158,18 @@ func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
 	return ops
 }
 
+// regionGapRepairer repairs the gaps around a region in the cache. It is
+// implemented by the cluster, but not the mock one.
+type regionGapRepairer interface {
+	RepairRegionGaps(region *core.RegionInfo) int
+}
+
+func (m *MergeChecker) trace(region *core.RegionInfo, format string, args ...interface{}) {
+	m.cluster.GetRegionTracer().Record(region.GetID(), "merge-checker", format, args...)
+}
+
 func (m *MergeChecker) checkTarget(region, adjacent *core.RegionInfo) bool {
-	return adjacent != nil && !m.cluster.IsRegionHot(adjacent) && AllowMerge(m.cluster, region, adjacent) &&
+	return adjacent != nil && !m.cluster.IsRegionHot(adjacent) && !m.cluster.IsRegionScheduleLocked(adjacent) && AllowMerge(m.cluster, region, adjacent) &&
 		opt.IsRegionHealthy(m.cluster, adjacent) && opt.IsRegionReplicated(m.cluster, adjacent)
 }
 
@@ -194,6 +194,68 @@ func (s *testMergeCheckerSuite) TestBasic(c *C) {
 	c.Assert(ops, IsNil)
 }
 
+func (s *testMergeCheckerSuite) TestScheduleLock(c *C) {
+	s.cluster.ScheduleOptions.SplitMergeInterval = 0
+
+	// A lock outside the regions does not affect merging.
+	c.Assert(s.cluster.AddLock(&core.ScheduleLock{ID: 1, StartKey: []byte("y"), EndKey: []byte("z"), ExpireTime: time.Now().Add(time.Hour)}), IsNil)
+	ops := s.mc.Check(s.regions[2])
+	c.Assert(ops, NotNil)
+	c.Assert(ops[0].RegionID(), Equals, s.regions[2].GetID())
+	c.Assert(ops[1].RegionID(), Equals, s.regions[1].GetID())
+
+	// Skip the region inside a locked range.
+	c.Assert(s.cluster.AddLock(&core.ScheduleLock{ID: 2, StartKey: []byte("u"), EndKey: []byte("v"), ExpireTime: time.Now().Add(time.Hour)}), IsNil)
+	c.Assert(s.mc.Check(s.regions[2]), IsNil)
+	ok, err := s.cluster.RemoveLock(2)
+	c.Assert(ok, IsTrue)
+	c.Assert(err, IsNil)
+
+	// The locked adjacent region cannot be the merge target.
+	c.Assert(s.cluster.AddLock(&core.ScheduleLock{ID: 3, StartKey: []byte("b"), EndKey: []byte("c"), ExpireTime: time.Now().Add(time.Hour)}), IsNil)
+	c.Assert(s.mc.Check(s.regions[2]), IsNil)
+	ok, err = s.cluster.RemoveLock(3)
+	c.Assert(ok, IsTrue)
+	c.Assert(err, IsNil)
+
+	// The lock does not work after expiry.
+	c.Assert(s.cluster.AddLock(&core.ScheduleLock{ID: 4, StartKey: []byte("t"), EndKey: []byte("x"), ExpireTime: time.Now().Add(-time.Second)}), IsNil)
+	ops = s.mc.Check(s.regions[2])
+	c.Assert(ops, NotNil)
+	c.Assert(ops[0].RegionID(), Equals, s.regions[2].GetID())
+	c.Assert(ops[1].RegionID(), Equals, s.regions[1].GetID())
+}
+
+type mockGapRepairCluster struct {
+	*mockcluster.Cluster
+	repaired []uint64
+}
+
+func (c *mockGapRepairCluster) RepairRegionGaps(region *core.RegionInfo) int {
+	c.repaired = append(c.repaired, region.GetID())
+	return 1
+}
+
+func (s *testMergeCheckerSuite) TestCacheGap(c *C) {
+	s.cluster.ScheduleOptions.SplitMergeInterval = 0
+	cluster := &mockGapRepairCluster{Cluster: s.cluster}
+	mc := NewMergeChecker(s.ctx, cluster)
+
+	// Drop region 2 from the cache, then region 3 is not adjacent to any
+	// region, as region 4 is not replicated.
+	s.cluster.RemoveRegion(s.regions[1])
+	c.Assert(mc.Check(s.regions[2]), IsNil)
+	c.Assert(cluster.repaired, DeepEquals, []uint64{3})
+
+	// It merges once the gap is filled by the heartbeat.
+	s.cluster.PutRegion(s.regions[1])
+	ops := mc.Check(s.regions[2])
+	c.Assert(ops, NotNil)
+	c.Assert(ops[0].RegionID(), Equals, s.regions[2].GetID())
+	c.Assert(ops[1].RegionID(), Equals, s.regions[1].GetID())
+	c.Assert(cluster.repaired, HasLen, 1)
+}
+
 func (s *testMergeCheckerSuite) checkSteps(c *C, op *operator.Operator, steps []operator.OpStep) {
 	c.Assert(op.Kind()&operator.OpMerge, Not(Equals), 0)
 	c.Assert(steps, NotNil)
@@ -41,9 +41,13 @@ const (
 // Unhealthy replica management, mainly used for disaster recovery of TiKV.
 // Location management, mainly used for cross data center deployment.
 type ReplicaChecker struct {
-	name    string
-	cluster opt.Cluster
-	filters []filter.Filter
+	name      string
+	cluster   opt.Cluster
+	filters   []filter.Filter
+	downPeers *downPeerQueue
+	// explanation collects how the region is handled in the explain mode,
+	// and it is nil otherwise.
+	explanation *Explanation
 }
 
 // NewReplicaChecker creates a replica checker.
@@ -58,64 +62,152 @@ func NewReplicaChecker(cluster opt.Cluster, n ...string) *ReplicaChecker {
 		filter.NewSnapshotCountFilter(name),
 		filter.NewPendingPeerCountFilter(name),
 		filter.NewSpecialUseFilter(name),
+		filter.NewEngineFilter(name),
 	}
 
 	return &ReplicaChecker{
-		name:    name,
-		cluster: cluster,
-		filters: filters,
+		name:      name,
+		cluster:   cluster,
+		filters:   filters,
+		downPeers: newDownPeerQueue(),
 	}
 }
 
+// GetDeferredRegions returns at most limit regions with the down peers which
+// are deferred and ready to be checked again, the most urgent first.
+func (r *ReplicaChecker) GetDeferredRegions(limit int) []uint64 {
+	return r.downPeers.getReady(limit)
+}
+
+// ForgetDeferredRegion stops deferring the region, such as for it has been
+// removed, or it has an operator already.
+func (r *ReplicaChecker) ForgetDeferredRegion(regionID uint64) {
+	r.downPeers.remove(regionID)
+}
+
+// Explain checks the region in the explain mode, which leaves the metrics,
+// the region trace and the deferred regions intact, and returns how the region
+// is handled and why no operator is created if so.
+func (r *ReplicaChecker) Explain(region *core.RegionInfo) *Explanation {
+	explainer := *r
+	explainer.explanation = newExplanation(r.name)
+	if op := explainer.Check(region); op != nil {
+		explainer.explanation.Operator = op.String()
+	}
+	return explainer.explanation
+}
+
 // Check verifies a region's replicas, creating an operator.Operator if need.
 func (r *ReplicaChecker) Check(region *core.RegionInfo) *operator.Operator {
-	checkerCounter.WithLabelValues("replica_checker", "check").Inc()
-	if op := r.checkDownPeer(region); op != nil {
-		checkerCounter.WithLabelValues("replica_checker", "new-operator").Inc()
+	r.count("check")
+	op, deferred := r.checkDownPeer(region)
+	if op != nil {
+		r.count("new-operator")
 		op.SetPriorityLevel(core.HighPriority)
+		r.trace(region, "fix down peer with operator %s", op)
 		return op
 	}
+	if deferred {
+		return nil
+	}
 	if op := r.checkOfflinePeer(region); op != nil {
-		checkerCounter.WithLabelValues("replica_checker", "new-operator").Inc()
+		r.count("new-operator")
 		op.SetPriorityLevel(core.HighPriority)
+		r.trace(region, "fix offline peer with operator %s", op)
 		return op
 	}
 
+	if len(region.GetPeers()) < r.cluster.GetMaxReplicas() && !r.cluster.IsMakeUpReplicaEnabled() {
+		r.trace(region, "skip making up replica as it is disabled")
+	}
 	if len(region.GetPeers()) < r.cluster.GetMaxReplicas() && r.cluster.IsMakeUpReplicaEnabled() {
 		log.Debug("region has fewer than max replicas", zap.Uint64("region-id", region.GetID()), zap.Int("peers", len(region.GetPeers())))
-		newPeer, _ := r.selectBestPeerToAddReplica(region, filter.NewStorageThresholdFilter(r.name))
+		newPeer, score := r.selectBestPeerToAddReplica(region, filter.NewStorageThresholdFilter(r.name))
 		if newPeer == nil {
-			checkerCounter.WithLabelValues("replica_checker", "no-target-store").Inc()
+			r.count("no-target-store")
+			r.trace(region, "no target store to make up replica")
 			return nil
 		}
-		checkerCounter.WithLabelValues("replica_checker", "new-operator").Inc()
+		r.count("new-operator")
 		op, err := operator.CreateAddPeerOperator("make-up-replica", r.cluster, region, newPeer, operator.OpReplica)
 		if err != nil {
 			log.Debug("create make-up-replica operator fail", zap.Error(err))
+			r.trace(region, "failed to create make-up-replica operator: %v", err)
 			return nil
 		}
+		op.SetReason(operator.NewReason(operator.ReasonMissingReplica, 0, newPeer.GetStoreId()).
+			With("replicas", float64(len(region.GetPeers()))).
+			With("max-replicas", float64(r.cluster.GetMaxReplicas())).
+			With("distinct-score", score))
+		r.trace(region, "make up replica with operator %s", op)
 		return op
 	}
 
 	// when add learner peer, the number of peer will exceed max replicas for a while,
 	// just comparing the the number of voters to avoid too many cancel add operator log.
+	if len(region.GetVoters()) > r.cluster.GetMaxReplicas() && !r.cluster.IsRemoveExtraReplicaEnabled() {
+		r.trace(region, "skip removing extra replica as it is disabled")
+	}
 	if len(region.GetVoters()) > r.cluster.GetMaxReplicas() && r.cluster.IsRemoveExtraReplicaEnabled() {
 		log.Debug("region has more than max replicas", zap.Uint64("region-id", region.GetID()), zap.Int("peers", len(region.GetPeers())))
-		oldPeer, _ := r.selectWorstPeer(region)
+		oldPeer, score := r.selectWorstPeer(region)
 		if oldPeer == nil {
-			checkerCounter.WithLabelValues("replica_checker", "no-worst-peer").Inc()
+			r.count("no-worst-peer")
+			r.trace(region, "no worst peer to remove extra replica")
 			return nil
 		}
 		op, err := operator.CreateRemovePeerOperator("remove-extra-replica", r.cluster, operator.OpReplica, region, oldPeer.GetStoreId())
 		if err != nil {
-			checkerCounter.WithLabelValues("replica_checker", "create-operator-fail").Inc()
+			r.count("create-operator-fail")
+			r.trace(region, "failed to create remove-extra-replica operator: %v", err)
 			return nil
 		}
-		checkerCounter.WithLabelValues("replica_checker", "new-operator").Inc()
+		op.SetReason(operator.NewReason(operator.ReasonExtraReplica, oldPeer.GetStoreId(), 0).
+			With("replicas", float64(len(region.GetVoters()))).
+			With("max-replicas", float64(r.cluster.GetMaxReplicas())).
+			With("distinct-score", score))
+		r.count("new-operator")
+		r.trace(region, "remove extra replica with operator %s", op)
 		return op
 	}
 
-	return r.checkBestReplacement(region)
+	op = r.checkBestReplacement(region)
+	if op != nil {
+		r.trace(region, "move to better location with operator %s", op)
+	}
+	return op
+}
+
+func (r *ReplicaChecker) trace(region *core.RegionInfo, format string, args ...interface{}) {
+	if r.explanation != nil {
+		r.explanation.addEvent(format, args...)
+		return
+	}
+	r.cluster.GetRegionTracer().Record(region.GetID(), r.name, format, args...)
+}
+
+// count increases the checker metrics of the result, or records the result in
+// the explain mode.
+func (r *ReplicaChecker) count(result string) {
+	if r.explanation != nil {
+		r.explanation.Results = append(r.explanation.Results, result)
+		return
+	}
+	checkerCounter.WithLabelValues("replica_checker", result).Inc()
+}
+
+// deferDownPeer and forgetDownPeer update the deferred regions, which are left
+// intact in the explain mode.
+func (r *ReplicaChecker) deferDownPeer(region *core.RegionInfo, healthy int, downSeconds uint64, failed bool) {
+	if r.explanation == nil {
+		r.downPeers.push(region.GetID(), healthy, downSeconds, failed)
+	}
+}
+
+func (r *ReplicaChecker) forgetDownPeer(region *core.RegionInfo) {
+	if r.explanation == nil {
+		r.downPeers.remove(region.GetID())
+	}
 }
 
 // SelectBestReplacementStore returns a store id that to be used to replace the old peer and distinct score.
@@ -145,6 +237,15 @@ func (r *ReplicaChecker) selectBestStoreToAddReplica(region *core.RegionInfo, fi
 	filters = append(filters, r.filters...)
 	filters = append(filters, newFilters...)
 	regionStores := r.cluster.GetRegionStores(region)
+	if r.explanation != nil {
+		r.explanation.rejectStores(r.cluster, filters)
+	} else if r.cluster.GetRegionTracer().IsTracing(region.GetID()) {
+		for _, store := range r.cluster.GetStores() {
+			if f := filter.TargetRejectedBy(r.cluster, store, filters); f != nil {
+				r.trace(region, "store %d is rejected as target by %s", store.GetID(), f.Type())
+			}
+		}
+	}
 	s := selector.NewReplicaSelector(regionStores, r.cluster.GetLocationLabels(), r.filters...)
 	target := s.SelectTarget(r.cluster, r.cluster.GetStores(), filters...)
 	if target == nil {
@@ -165,9 +266,17 @@ func (r *ReplicaChecker) selectWorstPeer(region *core.RegionInfo) (*metapb.Peer,
 	return region.GetStorePeer(worstStore.GetID()), core.DistinctScore(r.cluster.GetLocationLabels(), regionStores, worstStore)
 }
 
-func (r *ReplicaChecker) checkDownPeer(region *core.RegionInfo) *operator.Operator {
+// checkDownPeer returns the operator to fix the down peer of the region. The
+// region is deferred, and it should not be checked further for now, if it is
+// waiting to be retried, or there are more urgent regions, which have fewer
+// healthy voters, to take the store limits first. If it fails to be fixed, it
+// is retried after the backoff.
+func (r *ReplicaChecker) checkDownPeer(region *core.RegionInfo) (op *operator.Operator, deferred bool) {
 	if !r.cluster.IsRemoveDownReplicaEnabled() {
-		return nil
+		if len(region.GetDownPeers()) > 0 {
+			r.trace(region, "skip fixing down peer as it is disabled")
+		}
+		return nil, false
 	}
 
 	for _, stats := range region.GetDownPeers() {
@@ -179,7 +288,7 @@ func (r *ReplicaChecker) checkDownPeer(region *core.RegionInfo) *operator.Operat
 		store := r.cluster.GetStore(storeID)
 		if store == nil {
 			log.Warn("lost the store, maybe you are recovering the PD cluster", zap.Uint64("store-id", storeID))
-			return nil
+			return nil, false
 		}
 		if store.DownTime() < r.cluster.GetMaxStoreDownTime() {
 			continue
@@ -188,21 +297,47 @@ func (r *ReplicaChecker) checkDownPeer(region *core.RegionInfo) *operator.Operat
 			continue
 		}
 
-		return r.fixPeer(region, peer, downStatus)
+		if r.downPeers.isBackingOff(region.GetID()) {
+			r.count("down-peer-backoff")
+			r.trace(region, "defer fixing down peer until the retry backoff expires")
+			return nil, true
+		}
+		healthy := countHealthyVoters(region)
+		if r.downPeers.holdBack(healthy) {
+			r.count("down-peer-hold")
+			r.trace(region, "defer fixing down peer for the regions with fewer than %d healthy voters", healthy)
+			r.deferDownPeer(region, healthy, stats.GetDownSeconds(), false)
+			return nil, true
+		}
+		reason := operator.NewReason(operator.ReasonDownPeer, storeID, 0).
+			With("down-seconds", float64(stats.GetDownSeconds())).
+			With("max-store-down-seconds", r.cluster.GetMaxStoreDownTime().Seconds())
+		op := r.fixPeer(region, peer, downStatus, reason)
+		if op == nil {
+			r.count("down-peer-retry")
+			r.deferDownPeer(region, healthy, stats.GetDownSeconds(), true)
+		} else {
+			r.forgetDownPeer(region)
+		}
+		return op, false
 	}
-	return nil
+	r.forgetDownPeer(region)
+	return nil, false
 }
 
-func (r *ReplicaChecker) checkOfflinePeer(region *core.RegionInfo) *operator.Operator {
-	if !r.cluster.IsReplaceOfflineReplicaEnabled() {
-		return nil
-	}
-
-	// just skip learner
-	if len(region.GetLearners()) != 0 {
-		return nil
+// countHealthyVoters returns the number of the voters which are neither down
+// nor pending.
+func countHealthyVoters(region *core.RegionInfo) int {
+	var healthy int
+	for _, peer := range region.GetVoters() {
+		if region.GetDownPeer(peer.GetId()) == nil && region.GetPendingPeer(peer.GetId()) == nil {
+			healthy++
+		}
 	}
+	return healthy
+}
 
+func (r *ReplicaChecker) checkOfflinePeer(region *core.RegionInfo) *operator.Operator {
 	for _, peer := range region.GetPeers() {
 		storeID := peer.GetStoreId()
 		store := r.cluster.GetStore(storeID)
@@ -214,7 +349,17 @@ func (r *ReplicaChecker) checkOfflinePeer(region *core.RegionInfo) *operator.Ope
 			continue
 		}
 
-		return r.fixPeer(region, peer, offlineStatus)
+		// The skips are traced only for the regions having offline peers.
+		if !r.cluster.IsReplaceOfflineReplicaEnabled() {
+			r.trace(region, "skip replacing offline replica as it is disabled")
+			return nil
+		}
+		// just skip learner
+		if len(region.GetLearners()) != 0 {
+			r.trace(region, "skip replacing offline replica as the region has learners")
+			return nil
+		}
+		return r.fixPeer(region, peer, offlineStatus, operator.NewReason(operator.ReasonOfflinePeer, storeID, 0))
 	}
 
 	return nil
@@ -222,52 +367,64 @@ func (r *ReplicaChecker) checkOfflinePeer(region *core.RegionInfo) *operator.Ope
 
 func (r *ReplicaChecker) checkBestReplacement(region *core.RegionInfo) *operator.Operator {
 	if !r.cluster.IsLocationReplacementEnabled() {
+		r.trace(region, "skip moving to better location as it is disabled")
 		return nil
 	}
 
 	oldPeer, oldScore := r.selectWorstPeer(region)
 	if oldPeer == nil {
-		checkerCounter.WithLabelValues("replica_checker", "all-right").Inc()
+		r.count("all-right")
 		return nil
 	}
 	storeID, newScore := r.SelectBestReplacementStore(region, oldPeer, filter.NewStorageThresholdFilter(r.name))
 	if storeID == 0 {
-		checkerCounter.WithLabelValues("replica_checker", "no-replacement-store").Inc()
+		r.count("no-replacement-store")
+		r.trace(region, "no store to move peer on store %d to better location", oldPeer.GetStoreId())
 		return nil
 	}
 	// Make sure the new peer is better than the old peer.
 	if newScore <= oldScore {
 		log.Debug("no better peer", zap.Uint64("region-id", region.GetID()), zap.Float64("new-score", newScore), zap.Float64("old-score", oldScore))
-		checkerCounter.WithLabelValues("replica_checker", "not-better").Inc()
+		r.count("not-better")
+		r.trace(region, "no better location, new score %v is not greater than old score %v", newScore, oldScore)
 		return nil
 	}
 	newPeer := &metapb.Peer{StoreId: storeID}
 	op, err := operator.CreateMovePeerOperator("move-to-better-location", r.cluster, region, operator.OpReplica, oldPeer.GetStoreId(), newPeer)
 	if err != nil {
-		checkerCounter.WithLabelValues("replica_checker", "create-operator-fail").Inc()
+		r.count("create-operator-fail")
 		return nil
 	}
-	checkerCounter.WithLabelValues("replica_checker", "new-operator").Inc()
+	op.SetReason(operator.NewReason(operator.ReasonBetterLocation, oldPeer.GetStoreId(), storeID).
+		With("old-score", oldScore).
+		With("new-score", newScore))
+	r.count("new-operator")
 	return op
 }
 
-func (r *ReplicaChecker) fixPeer(region *core.RegionInfo, peer *metapb.Peer, status string) *operator.Operator {
+// fixPeer removes or replaces the unhealthy peer, with the reason completed by
+// how it is fixed.
+func (r *ReplicaChecker) fixPeer(region *core.RegionInfo, peer *metapb.Peer, status string, reason *operator.Reason) *operator.Operator {
 	removeExtra := fmt.Sprintf("remove-extra-%s-replica", status)
 	// Check the number of replicas first.
 	if len(region.GetPeers()) > r.cluster.GetMaxReplicas() {
 		op, err := operator.CreateRemovePeerOperator(removeExtra, r.cluster, operator.OpReplica, region, peer.GetStoreId())
 		if err != nil {
-			reason := fmt.Sprintf("%s-fail", removeExtra)
-			checkerCounter.WithLabelValues("replica_checker", reason).Inc()
+			label := fmt.Sprintf("%s-fail", removeExtra)
+			r.count(label)
 			return nil
 		}
+		op.SetReason(reason.
+			With("replicas", float64(len(region.GetPeers()))).
+			With("max-replicas", float64(r.cluster.GetMaxReplicas())))
 		return op
 	}
 
-	storeID, _ := r.SelectBestReplacementStore(region, peer, filter.NewStorageThresholdFilter(r.name))
+	storeID, score := r.SelectBestReplacementStore(region, peer, filter.NewStorageThresholdFilter(r.name))
 	if storeID == 0 {
-		reason := fmt.Sprintf("no-store-%s", status)
-		checkerCounter.WithLabelValues("replica_checker", reason).Inc()
+		label := fmt.Sprintf("no-store-%s", status)
+		r.count(label)
+		r.trace(region, "no store to replace %s peer on store %d", status, peer.GetStoreId())
 		log.Debug("no best store to add replica", zap.Uint64("region-id", region.GetID()))
 		return nil
 	}
@@ -275,9 +432,11 @@ func (r *ReplicaChecker) fixPeer(region *core.RegionInfo, peer *metapb.Peer, sta
 	replace := fmt.Sprintf("replace-%s-replica", status)
 	op, err := operator.CreateMovePeerOperator(replace, r.cluster, region, operator.OpReplica, peer.GetStoreId(), newPeer)
 	if err != nil {
-		reason := fmt.Sprintf("%s-fail", replace)
-		checkerCounter.WithLabelValues("replica_checker", reason).Inc()
+		label := fmt.Sprintf("%s-fail", replace)
+		r.count(label)
 		return nil
 	}
+	reason.TargetStore = storeID
+	op.SetReason(reason.With("distinct-score", score))
 	return op
 }
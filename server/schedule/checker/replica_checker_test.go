@@ -138,3 +138,164 @@ func (s *testReplicaCheckerSuite) TestOfflineWithOneReplica(c *C) {
 	c.Assert(op, NotNil)
 	c.Assert(op.Desc(), Equals, "replace-offline-replica")
 }
+
+func (s *testReplicaCheckerSuite) TestTiFlashStores(c *C) {
+	tc := mockcluster.NewCluster(mockoption.NewScheduleOptions())
+	rc := NewReplicaChecker(tc)
+	// The empty TiFlash stores are preferred by the region score, but cannot
+	// receive the replicas.
+	tc.AddRegionStore(1, 10)
+	tc.AddRegionStore(2, 10)
+	tc.AddRegionStore(3, 10)
+	tc.AddLabelsStore(4, 0, map[string]string{"engine": "tiflash"})
+	tc.AddLabelsStore(5, 0, map[string]string{"engine": "tiflash"})
+	region := tc.AddLeaderRegion(1, 1, 2)
+	op := rc.Check(region)
+	c.Assert(op, NotNil)
+	c.Assert(op.Step(0).(operator.AddLearner).ToStore, Equals, uint64(3))
+
+	// No TiKV store is left to receive the replica.
+	tc.SetStoreDown(3)
+	c.Assert(rc.Check(region), IsNil)
+}
+
+func (s *testReplicaCheckerSuite) TestDownPeerPriority(c *C) {
+	tc := mockcluster.NewCluster(mockoption.NewScheduleOptions())
+	rc := NewReplicaChecker(tc)
+	now := time.Now()
+	rc.downPeers.now = func() time.Time { return now }
+	// The store limits allow as many operators as the tokens.
+	tokens := 0
+	for id := uint64(1); id <= 6; id++ {
+		tc.AddRegionStore(id, 10)
+		tc.PutStore(tc.GetStore(id).Clone(core.SetAvailableFunc(func() bool { return tokens > 0 })))
+	}
+	// Stores 4 and 5 fail simultaneously.
+	tc.SetStoreDown(4)
+	tc.SetStoreDown(5)
+	downPeers := func(region *core.RegionInfo, downSeconds uint64, storeIDs ...uint64) *core.RegionInfo {
+		var stats []*pdpb.PeerStats
+		for _, storeID := range storeIDs {
+			stats = append(stats, &pdpb.PeerStats{Peer: region.GetStorePeer(storeID), DownSeconds: downSeconds})
+		}
+		region = region.Clone(core.WithDownPeers(stats))
+		tc.PutRegion(region)
+		return region
+	}
+	r1 := downPeers(tc.AddLeaderRegion(1, 1, 2, 4), 3600, 4)
+	r2 := downPeers(tc.AddLeaderRegion(2, 1, 4, 5), 3600, 4, 5)
+	r3 := downPeers(tc.AddLeaderRegion(3, 2, 3, 5), 7200, 5)
+
+	// No operator can be created as the store limits are exhausted, so the
+	// regions are retried after the backoff, and the last one waits for the
+	// more urgent one.
+	for _, region := range []*core.RegionInfo{r1, r2, r3} {
+		c.Assert(rc.Check(region), IsNil)
+	}
+	c.Assert(rc.GetDeferredRegions(10), DeepEquals, []uint64{3})
+	tokens = 1
+	c.Assert(rc.Check(r1), IsNil)
+
+	// The region with only one healthy voter is the most urgent, and then the
+	// one whose peer has been down longer.
+	now = now.Add(downPeerRetryBackoff)
+	c.Assert(rc.GetDeferredRegions(10), DeepEquals, []uint64{2, 3, 1})
+	c.Assert(rc.GetDeferredRegions(1), DeepEquals, []uint64{2})
+	// The others wait for it even if they are checked first.
+	c.Assert(rc.Check(r1), IsNil)
+	c.Assert(rc.Check(r3), IsNil)
+	op := rc.Check(r2)
+	c.Assert(op, NotNil)
+	c.Assert(op.Desc(), Equals, "replace-down-replica")
+	c.Assert(op.RegionID(), Equals, uint64(2))
+	tokens--
+	c.Assert(rc.GetDeferredRegions(10), DeepEquals, []uint64{3, 1})
+	tokens = 2
+	for _, region := range []*core.RegionInfo{r3, r1} {
+		c.Assert(rc.Check(region), NotNil)
+	}
+	c.Assert(rc.downPeers.len(), Equals, 0)
+
+	// The region which keeps failing no longer holds back the others.
+	tokens = 0
+	c.Assert(rc.Check(r2), IsNil)
+	for i := 1; i < maxDownPeerHoldRetries; i++ {
+		c.Assert(rc.downPeers.holdBack(2), IsTrue)
+		now = now.Add(maxDownPeerRetryBackoff)
+		c.Assert(rc.GetDeferredRegions(10), DeepEquals, []uint64{2})
+		c.Assert(rc.Check(r2), IsNil)
+	}
+	c.Assert(rc.downPeers.holdBack(2), IsFalse)
+	tokens = 1
+	c.Assert(rc.Check(r1), NotNil)
+	// The region is forgotten once it has no down peer.
+	now = now.Add(maxDownPeerRetryBackoff)
+	r2 = downPeers(r2, 0)
+	c.Assert(rc.Check(r2), IsNil)
+	c.Assert(rc.downPeers.len(), Equals, 0)
+}
+
+func (s *testReplicaCheckerSuite) TestExplain(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	rc := NewReplicaChecker(tc)
+	available := false
+	for id := uint64(1); id <= 4; id++ {
+		tc.AddRegionStore(id, 10)
+	}
+	tc.PutStore(tc.GetStore(3).Clone(core.SetAvailableFunc(func() bool { return available })))
+	tc.SetStoreDown(4)
+	region := tc.AddLeaderRegion(1, 1, 2, 4)
+	region = region.Clone(core.WithDownPeers([]*pdpb.PeerStats{{Peer: region.GetStorePeer(4), DownSeconds: 3600}}))
+	tc.PutRegion(region)
+
+	// Store 3 is the only candidate, which is rejected by the store limit.
+	explanation := rc.Explain(region)
+	c.Assert(explanation.Checker, Equals, replicaCheckerName)
+	c.Assert(explanation.Results[:3], DeepEquals, []string{"check", "no-store-down", "down-peer-retry"})
+	c.Assert(explanation.Events[0], Equals, "no store to replace down peer on store 4")
+	c.Assert(explanation.RejectedStores["store-limit-filter"], DeepEquals, []uint64{3})
+	c.Assert(explanation.Operator, Equals, "")
+	// The region is not deferred by the explanation.
+	c.Assert(rc.downPeers.len(), Equals, 0)
+
+	available = true
+	explanation = rc.Explain(region)
+	c.Assert(explanation.Operator, Matches, "replace-down-replica .*")
+	c.Assert(explanation.RejectedStores["store-limit-filter"], HasLen, 0)
+
+	opt.EnableRemoveDownReplica = false
+	explanation = rc.Explain(region)
+	c.Assert(explanation.Events[0], Equals, "skip fixing down peer as it is disabled")
+	c.Assert(explanation.Results[0], Equals, "check")
+	c.Assert(explanation.Operator, Equals, "")
+}
+
+func (s *testReplicaCheckerSuite) TestTraceOfflineSkip(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	rc := NewReplicaChecker(tc)
+	for id := uint64(1); id <= 4; id++ {
+		tc.AddRegionStore(id, 10)
+	}
+	healthy := tc.AddLeaderRegion(1, 1, 2, 3)
+	offline := tc.AddLeaderRegion(2, 1, 2, 4)
+	tc.SetStoreOffline(4)
+	learner := &metapb.Peer{Id: 100, StoreId: 3, IsLearner: true}
+	hasEvent := func(region *core.RegionInfo, event string) bool {
+		for _, e := range rc.Explain(region).Events {
+			if e == event {
+				return true
+			}
+		}
+		return false
+	}
+
+	opt.EnableReplaceOfflineReplica = false
+	c.Assert(hasEvent(healthy, "skip replacing offline replica as it is disabled"), IsFalse)
+	c.Assert(hasEvent(offline, "skip replacing offline replica as it is disabled"), IsTrue)
+
+	opt.EnableReplaceOfflineReplica = true
+	c.Assert(hasEvent(healthy.Clone(core.WithAddPeer(learner)), "skip replacing offline replica as the region has learners"), IsFalse)
+	c.Assert(hasEvent(offline.Clone(core.WithAddPeer(learner)), "skip replacing offline replica as the region has learners"), IsTrue)
+}
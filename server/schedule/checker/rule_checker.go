@@ -32,6 +32,9 @@ type RuleChecker struct {
 	cluster     opt.Cluster
 	ruleManager *placement.RuleManager
 	name        string
+	// explanation collects how the region is handled in the explain mode,
+	// and it is nil otherwise.
+	explanation *Explanation
 }
 
 // NewRuleChecker creates a checker instance.
@@ -43,14 +46,43 @@ func NewRuleChecker(cluster opt.Cluster, ruleManager *placement.RuleManager) *Ru
 	}
 }
 
+// Explain checks the region in the explain mode, which leaves the metrics
+// intact, and returns how the region is handled and why no operator is
+// created if so.
+func (c *RuleChecker) Explain(region *core.RegionInfo) *Explanation {
+	explainer := *c
+	explainer.explanation = newExplanation(c.name)
+	if op := explainer.Check(region); op != nil {
+		explainer.explanation.Operator = op.String()
+	}
+	return explainer.explanation
+}
+
+// count increases the checker metrics of the result, or records the result in
+// the explain mode.
+func (c *RuleChecker) count(result string) {
+	if c.explanation != nil {
+		c.explanation.Results = append(c.explanation.Results, result)
+		return
+	}
+	checkerCounter.WithLabelValues("rule_checker", result).Inc()
+}
+
+// explain records the decision made on the region in the explain mode.
+func (c *RuleChecker) explain(format string, args ...interface{}) {
+	if c.explanation != nil {
+		c.explanation.addEvent(format, args...)
+	}
+}
+
 // Check checks if the region matches placement rules and returns Operator to
 // fix it.
 func (c *RuleChecker) Check(region *core.RegionInfo) *operator.Operator {
-	checkerCounter.WithLabelValues("rule_checker", "check").Inc()
+	c.count("check")
 
 	fit := c.cluster.FitRegion(region)
 	if len(fit.RuleFits) == 0 {
-		checkerCounter.WithLabelValues("rule_checker", "fix-range").Inc()
+		c.count("fix-range")
 		// If the region matches no rules, the most possible reason is it spans across
 		// multiple rules.
 		return c.fixRange(region)
@@ -59,6 +91,7 @@ func (c *RuleChecker) Check(region *core.RegionInfo) *operator.Operator {
 		op, err := c.fixRulePeer(region, fit, rf)
 		if err != nil {
 			log.Debug("fail to fix rule peer", zap.Error(err), zap.String("rule-group", rf.Rule.GroupID), zap.String("rule-id", rf.Rule.ID))
+			c.explain("failed to fix peer of rule %s/%s: %v", rf.Rule.GroupID, rf.Rule.ID, err)
 			return nil
 		}
 		if op != nil {
@@ -68,6 +101,7 @@ func (c *RuleChecker) Check(region *core.RegionInfo) *operator.Operator {
 	op, err := c.fixOrphanPeers(region, fit)
 	if err != nil {
 		log.Debug("fail to fix orphan peer", zap.Error(err))
+		c.explain("failed to fix orphan peer: %v", err)
 		return nil
 	}
 	return op
@@ -76,6 +110,7 @@ func (c *RuleChecker) Check(region *core.RegionInfo) *operator.Operator {
 func (c *RuleChecker) fixRange(region *core.RegionInfo) *operator.Operator {
 	keys := c.ruleManager.GetSplitKeys(region.GetStartKey(), region.GetEndKey())
 	if len(keys) == 0 {
+		c.explain("the region matches no rule, and no rule starts or ends in it")
 		return nil
 	}
 	return operator.CreateSplitRegionOperator("rule-split-region", region, 0, pdpb.CheckPolicy_USEKEY, keys)
@@ -89,11 +124,11 @@ func (c *RuleChecker) fixRulePeer(region *core.RegionInfo, fit *placement.Region
 	// fix down/offline peers.
 	for _, peer := range rf.Peers {
 		if c.isDownPeer(region, peer) {
-			checkerCounter.WithLabelValues("rule_checker", "replace-down").Inc()
+			c.count("replace-down")
 			return c.replaceRulePeer(region, fit, rf, peer, downStatus)
 		}
 		if c.isOfflinePeer(region, peer) {
-			checkerCounter.WithLabelValues("rule_checker", "replace-offline").Inc()
+			c.count("replace-offline")
 			return c.replaceRulePeer(region, fit, rf, peer, offlineStatus)
 		}
 	}
@@ -111,10 +146,13 @@ func (c *RuleChecker) fixRulePeer(region *core.RegionInfo, fit *placement.Region
 }
 
 func (c *RuleChecker) addRulePeer(region *core.RegionInfo, rf *placement.RuleFit) (*operator.Operator, error) {
-	checkerCounter.WithLabelValues("rule_checker", "add-rule-peer").Inc()
+	c.count("add-rule-peer")
 	store := SelectStoreToAddPeerByRule(c.name, c.cluster, region, rf)
 	if store == nil {
-		checkerCounter.WithLabelValues("rule_checker", "no-store-add").Inc()
+		c.count("no-store-add")
+		if c.explanation != nil {
+			c.explanation.rejectStores(c.cluster, ruleTargetFilters(c.name, region, rf))
+		}
 		return nil, errors.New("no store to add peer")
 	}
 	peer := &metapb.Peer{StoreId: store.GetID(), IsLearner: rf.Rule.Role == placement.Learner}
@@ -124,7 +162,10 @@ func (c *RuleChecker) addRulePeer(region *core.RegionInfo, rf *placement.RuleFit
 func (c *RuleChecker) replaceRulePeer(region *core.RegionInfo, fit *placement.RegionFit, rf *placement.RuleFit, peer *metapb.Peer, status string) (*operator.Operator, error) {
 	store := SelectStoreToReplacePeerByRule(c.name, c.cluster, region, fit, rf, peer)
 	if store == nil {
-		checkerCounter.WithLabelValues("rule_checker", "no-store-replace").Inc()
+		c.count("no-store-replace")
+		if c.explanation != nil {
+			c.explanation.rejectStores(c.cluster, ruleTargetFilters(c.name, region, rf))
+		}
 		return nil, errors.New("no store to replace peer")
 	}
 	newPeer := &metapb.Peer{StoreId: store.GetID(), IsLearner: rf.Rule.Role == placement.Learner}
@@ -133,17 +174,17 @@ func (c *RuleChecker) replaceRulePeer(region *core.RegionInfo, fit *placement.Re
 
 func (c *RuleChecker) fixLooseMatchPeer(region *core.RegionInfo, fit *placement.RegionFit, rf *placement.RuleFit, peer *metapb.Peer) (*operator.Operator, error) {
 	if peer.IsLearner && rf.Rule.Role != placement.Learner {
-		checkerCounter.WithLabelValues("rule_checker", "fix-peer-role").Inc()
+		c.count("fix-peer-role")
 		return operator.CreatePromoteLearnerOperator("fix-peer-role", c.cluster, region, peer)
 	}
 	if region.GetLeader().GetId() == peer.GetId() && rf.Rule.Role == placement.Follower {
-		checkerCounter.WithLabelValues("rule_checker", "fix-leader-role").Inc()
+		c.count("fix-leader-role")
 		for _, p := range region.GetPeers() {
 			if c.allowLeader(fit, p) {
 				return operator.CreateTransferLeaderOperator("fix-peer-role", c.cluster, region, peer.GetStoreId(), p.GetStoreId(), 0)
 			}
 		}
-		checkerCounter.WithLabelValues("rule_checker", "no-new-leader").Inc()
+		c.count("no-new-leader")
 		return nil, errors.New("no new leader")
 	}
 	return nil, nil
@@ -184,6 +225,7 @@ func (c *RuleChecker) fixBetterLocation(region *core.RegionInfo, fit *placement.
 	newPeerStore := SelectStoreToReplacePeerByRule("rule-checker", c.cluster, region, fit, rf, oldPeer)
 	if newPeerStore == nil {
 		log.Debug("no replacement store", zap.Uint64("region-id", region.GetID()))
+		c.explain("no store to move peer on store %d to better location of rule %s/%s", oldPeer.GetStoreId(), rf.Rule.GroupID, rf.Rule.ID)
 		return nil, nil
 	}
 	stores = getRuleFitStores(c.cluster, removePeerFromRuleFit(rf, oldPeer))
@@ -191,9 +233,10 @@ func (c *RuleChecker) fixBetterLocation(region *core.RegionInfo, fit *placement.
 	newScore := core.DistinctScore(rf.Rule.LocationLabels, stores, newPeerStore)
 	if newScore <= oldScore {
 		log.Debug("no better peer", zap.Uint64("region-id", region.GetID()), zap.Float64("new-score", newScore), zap.Float64("old-score", oldScore))
+		c.explain("no better location of rule %s/%s, new score %v is not greater than old score %v", rf.Rule.GroupID, rf.Rule.ID, newScore, oldScore)
 		return nil, nil
 	}
-	checkerCounter.WithLabelValues("rule_checker", "move-to-better-location").Inc()
+	c.count("move-to-better-location")
 	newPeer := &metapb.Peer{StoreId: newPeerStore.GetID(), IsLearner: oldPeer.IsLearner}
 	return operator.CreateMovePeerOperator("move-to-better-location", c.cluster, region, operator.OpReplica, oldPeer.GetStoreId(), newPeer)
 }
@@ -202,7 +245,7 @@ func (c *RuleChecker) fixOrphanPeers(region *core.RegionInfo, fit *placement.Reg
 	if len(fit.OrphanPeers) == 0 {
 		return nil, nil
 	}
-	checkerCounter.WithLabelValues("rule_checker", "remove-orphan-peer").Inc()
+	c.count("remove-orphan-peer")
 	peer := fit.OrphanPeers[0]
 	return operator.CreateRemovePeerOperator("remove-orphan-peer", c.cluster, 0, region, peer.StoreId)
 }
@@ -240,17 +283,23 @@ func (c *RuleChecker) isOfflinePeer(region *core.RegionInfo, peer *metapb.Peer)
 
 // SelectStoreToAddPeerByRule selects a store to add peer in order to fit the placement rule.
 func SelectStoreToAddPeerByRule(scope string, cluster opt.Cluster, region *core.RegionInfo, rf *placement.RuleFit, filters ...filter.Filter) *core.StoreInfo {
-	fs := []filter.Filter{
+	fs := append(ruleTargetFilters(scope, region, rf), filters...)
+	store := selector.NewReplicaSelector(getRuleFitStores(cluster, rf), rf.Rule.LocationLabels).
+		SelectTarget(cluster, cluster.GetStores(), fs...)
+	return store
+}
+
+// ruleTargetFilters returns the filters of the stores to add a peer of the
+// region for the rule.
+func ruleTargetFilters(scope string, region *core.RegionInfo, rf *placement.RuleFit) []filter.Filter {
+	return []filter.Filter{
 		filter.StoreStateFilter{ActionScope: scope, MoveRegion: true},
 		filter.NewStorageThresholdFilter(scope),
 		filter.NewLabelConstaintFilter(scope, rf.Rule.LabelConstraints),
 		filter.NewExcludedFilter(scope, nil, region.GetStoreIds()),
 		filter.NewSpecialUseFilter(scope),
+		filter.NewRuleEngineFilter(scope, rf.Rule),
 	}
-	fs = append(fs, filters...)
-	store := selector.NewReplicaSelector(getRuleFitStores(cluster, rf), rf.Rule.LocationLabels).
-		SelectTarget(cluster, cluster.GetStores(), fs...)
-	return store
 }
 
 // SelectStoreToReplacePeerByRule selects a store to replace a region peer in order to fit the placement rule.
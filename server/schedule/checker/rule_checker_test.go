@@ -198,3 +198,25 @@ func (s *testRuleCheckerSuite) TestNoBetterReplacement(c *C) {
 	op := s.rc.Check(s.cluster.GetRegion(1))
 	c.Assert(op, IsNil)
 }
+
+func (s *testRuleCheckerSuite) TestExplain(c *C) {
+	s.cluster.AddLabelsStore(1, 1, map[string]string{"host": "h1"})
+	s.cluster.AddLabelsStore(2, 1, map[string]string{"host": "h2"})
+	s.cluster.AddLabelsStore(3, 1, map[string]string{"host": "h3"})
+	s.cluster.AddLeaderRegionWithRange(1, "", "", 1, 2)
+	s.ruleManager.SetRule(&placement.Rule{
+		GroupID:          "pd",
+		ID:               "default",
+		Role:             placement.Voter,
+		Count:            3,
+		LabelConstraints: []placement.LabelConstraint{{Key: "host", Op: "in", Values: []string{"h1", "h2"}}},
+	})
+
+	explanation := s.rc.Explain(s.cluster.GetRegion(1))
+	c.Assert(explanation.Checker, Equals, "rule-checker")
+	c.Assert(explanation.Results, DeepEquals, []string{"check", "add-rule-peer", "no-store-add"})
+	c.Assert(explanation.Events, DeepEquals, []string{"failed to fix peer of rule pd/default: no store to add peer"})
+	c.Assert(explanation.RejectedStores["label-constraint-filter"], DeepEquals, []uint64{3})
+	c.Assert(explanation.RejectedStores["exclude-filter"], HasLen, 2)
+	c.Assert(explanation.Operator, Equals, "")
+}
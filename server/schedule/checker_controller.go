@@ -15,6 +15,7 @@ package schedule
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/pingcap/pd/v4/server/core"
 	"github.com/pingcap/pd/v4/server/schedule/checker"
@@ -23,6 +24,14 @@ import (
 	"github.com/pingcap/pd/v4/server/schedule/placement"
 )
 
+// The creators of the operators created by the checkers.
+const (
+	learnerCheckerCreator = "learner-checker"
+	replicaCheckerCreator = "replica-checker"
+	ruleCheckerCreator    = "rule-checker"
+	mergeCheckerCreator   = "merge-checker"
+)
+
 // CheckerController is used to manage all checkers.
 type CheckerController struct {
 	cluster        opt.Cluster
@@ -56,16 +65,19 @@ func (c *CheckerController) CheckRegion(region *core.RegionInfo) (bool, []*opera
 		if opController.OperatorCount(operator.OpReplica) < c.cluster.GetReplicaScheduleLimit() {
 			checkerIsBusy = false
 			if op := c.ruleChecker.Check(region); op != nil {
+				op.SetCreator(ruleCheckerCreator)
 				return checkerIsBusy, []*operator.Operator{op}
 			}
 		}
 	} else {
 		if op := c.learnerChecker.Check(region); op != nil {
+			op.SetCreator(learnerCheckerCreator)
 			return false, []*operator.Operator{op}
 		}
 		if opController.OperatorCount(operator.OpReplica) < c.cluster.GetReplicaScheduleLimit() {
 			checkerIsBusy = false
 			if op := c.replicaChecker.Check(region); op != nil {
+				op.SetCreator(replicaCheckerCreator)
 				return checkerIsBusy, []*operator.Operator{op}
 			}
 		}
@@ -74,6 +86,9 @@ func (c *CheckerController) CheckRegion(region *core.RegionInfo) (bool, []*opera
 	if c.mergeChecker != nil && opController.OperatorCount(operator.OpMerge) < c.cluster.GetMergeScheduleLimit() {
 		checkerIsBusy = false
 		if ops := c.mergeChecker.Check(region); ops != nil {
+			for _, op := range ops {
+				op.SetCreator(mergeCheckerCreator)
+			}
 			// It makes sure that two operators can be added successfully altogether.
 			return checkerIsBusy, ops
 		}
@@ -81,6 +96,42 @@ func (c *CheckerController) CheckRegion(region *core.RegionInfo) (bool, []*opera
 	return checkerIsBusy, nil
 }
 
+// ExplainRegion checks the region in the explain mode of the replica checker,
+// or the rule checker if the placement rules are enabled, and returns how it
+// is handled, along with the notes on why the operator would not be created
+// by CheckRegion.
+func (c *CheckerController) ExplainRegion(region *core.RegionInfo) *checker.Explanation {
+	var explanation *checker.Explanation
+	if c.cluster.IsPlacementRulesEnabled() {
+		explanation = c.ruleChecker.Explain(region)
+	} else {
+		explanation = c.replicaChecker.Explain(region)
+		if op := c.learnerChecker.Check(region); op != nil {
+			explanation.Notes = append(explanation.Notes, fmt.Sprintf("the learner checker creates operator %s first", op))
+		}
+	}
+	if limit := c.cluster.GetReplicaScheduleLimit(); c.opController.OperatorCount(operator.OpReplica) >= limit {
+		explanation.Notes = append(explanation.Notes, fmt.Sprintf("the replica operators reach the replica-schedule-limit %d", limit))
+	}
+	return explanation
+}
+
+// GetDeferredRegions returns at most limit regions with the down peers which
+// are deferred by the replica checker and ready to be checked again, the most
+// urgent first. There is none if the placement rules are enabled, as the
+// replica checker is not used.
+func (c *CheckerController) GetDeferredRegions(limit int) []uint64 {
+	if c.cluster.IsPlacementRulesEnabled() {
+		return nil
+	}
+	return c.replicaChecker.GetDeferredRegions(limit)
+}
+
+// ForgetDeferredRegion stops deferring the region by the replica checker.
+func (c *CheckerController) ForgetDeferredRegion(regionID uint64) {
+	c.replicaChecker.ForgetDeferredRegion(regionID)
+}
+
 // GetMergeChecker returns the merge checker.
 func (c *CheckerController) GetMergeChecker() *checker.MergeChecker {
 	return c.mergeChecker
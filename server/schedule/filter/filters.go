@@ -47,6 +47,17 @@ func filterStoresBy(stores []*core.StoreInfo, keepPred func(*core.StoreInfo) boo
 	return
 }
 
+// TargetRejectedBy returns the first filter that rejects the store as target
+// store. It returns nil if the store passes all filters.
+func TargetRejectedBy(opt opt.Options, store *core.StoreInfo, filters []Filter) Filter {
+	for _, filter := range filters {
+		if !filter.Target(opt, store) {
+			return filter
+		}
+	}
+	return nil
+}
+
 // Filter is an interface to filter source and target store.
 type Filter interface {
 	// Scope is used to indicate where the filter will act on.
@@ -140,6 +151,37 @@ func (f *storeLimitFilter) Target(opt opt.Options, store *core.StoreInfo) bool {
 	return store.IsAvailable()
 }
 
+type storeBudgetFilter struct {
+	scope     string
+	remaining map[uint64]float64
+	cost      float64
+}
+
+// NewStoreBudgetFilter creates a Filter that filters all stores whose
+// remaining budget of the store limit is less than the cost, so that the
+// operators waiting to be promoted are taken into account. The stores not in
+// remaining are not filtered.
+func NewStoreBudgetFilter(scope string, remaining map[uint64]float64, cost float64) Filter {
+	return &storeBudgetFilter{scope: scope, remaining: remaining, cost: cost}
+}
+
+func (f *storeBudgetFilter) Scope() string {
+	return f.scope
+}
+
+func (f *storeBudgetFilter) Type() string {
+	return "store-budget-filter"
+}
+
+func (f *storeBudgetFilter) Source(opt opt.Options, store *core.StoreInfo) bool {
+	return true
+}
+
+func (f *storeBudgetFilter) Target(opt opt.Options, store *core.StoreInfo) bool {
+	remaining, ok := f.remaining[store.GetID()]
+	return !ok || remaining >= f.cost
+}
+
 type stateFilter struct{ scope string }
 
 // NewStateFilter creates a Filter that filters all stores that are not UP.
@@ -165,7 +207,8 @@ func (f *stateFilter) Target(opt opt.Options, store *core.StoreInfo) bool {
 
 type healthFilter struct{ scope string }
 
-// NewHealthFilter creates a Filter that filters all stores that are Busy or Down.
+// NewHealthFilter creates a Filter that filters all stores that are Busy or Down,
+// and the flapping stores as targets.
 func NewHealthFilter(scope string) Filter {
 	return &healthFilter{scope: scope}
 }
@@ -190,7 +233,7 @@ func (f *healthFilter) Source(opt opt.Options, store *core.StoreInfo) bool {
 }
 
 func (f *healthFilter) Target(opt opt.Options, store *core.StoreInfo) bool {
-	return f.filter(opt, store)
+	return f.filter(opt, store) && !store.IsFlapping()
 }
 
 type pendingPeerCountFilter struct{ scope string }
@@ -257,7 +300,7 @@ func (f *snapshotCountFilter) Target(opt opt.Options, store *core.StoreInfo) boo
 type storageThresholdFilter struct{ scope string }
 
 // NewStorageThresholdFilter creates a Filter that filters all stores that are
-// almost full.
+// almost full, or below the space reserved on them.
 func NewStorageThresholdFilter(scope string) Filter {
 	return &storageThresholdFilter{scope: scope}
 }
@@ -275,7 +318,7 @@ func (f *storageThresholdFilter) Source(opt opt.Options, store *core.StoreInfo)
 }
 
 func (f *storageThresholdFilter) Target(opt opt.Options, store *core.StoreInfo) bool {
-	return !store.IsLowSpace(opt.GetLowSpaceRatio())
+	return !store.IsLowSpace(opt.GetLowSpaceRatio()) && !store.IsBelowSpaceFloor()
 }
 
 // distinctScoreFilter ensures that distinct score will not decrease.
@@ -363,6 +406,7 @@ func (f StoreStateFilter) Source(opt opt.Options, store *core.StoreInfo) bool {
 func (f StoreStateFilter) Target(opts opt.Options, store *core.StoreInfo) bool {
 	if store.IsTombstone() ||
 		store.IsOffline() ||
+		store.IsFlapping() ||
 		store.DownTime() > opts.GetMaxStoreDownTime() {
 		return false
 	}
@@ -589,3 +633,65 @@ const (
 )
 
 var allSpecialUses = []string{SpecialUseHotRegion, SpecialUseReserved}
+
+type engineFilter struct {
+	scope      string
+	constraint placement.LabelConstraint
+}
+
+// NewEngineFilter creates a filter that keeps the peers off the stores of the
+// engines other than TiKV, e.g. TiFlash, unless the engines are allowed. When
+// the placement rules are enabled, the rules decide which peers are placed on
+// these stores, so the stores are not filtered as the source.
+func NewEngineFilter(scope string, allowEngines ...string) Filter {
+	var values []string
+	for _, v := range allEngines {
+		if slice.NoneOf(allowEngines, func(i int) bool { return allowEngines[i] == v }) {
+			values = append(values, v)
+		}
+	}
+	return &engineFilter{
+		scope:      scope,
+		constraint: placement.LabelConstraint{Key: EngineKey, Op: "in", Values: values},
+	}
+}
+
+// NewRuleEngineFilter creates an engine filter allowing the engines explicitly
+// selected by the label constraints of the rule.
+func NewRuleEngineFilter(scope string, rule *placement.Rule) Filter {
+	var allowEngines []string
+	for _, c := range rule.LabelConstraints {
+		if c.Key == EngineKey && c.Op == placement.In {
+			allowEngines = append(allowEngines, c.Values...)
+		}
+	}
+	return NewEngineFilter(scope, allowEngines...)
+}
+
+func (f *engineFilter) Scope() string {
+	return f.scope
+}
+
+func (f *engineFilter) Type() string {
+	return "engine-filter"
+}
+
+func (f *engineFilter) Source(opt opt.Options, store *core.StoreInfo) bool {
+	if opt.IsPlacementRulesEnabled() {
+		return true
+	}
+	return !f.constraint.MatchStore(store)
+}
+
+func (f *engineFilter) Target(opt opt.Options, store *core.StoreInfo) bool {
+	return !f.constraint.MatchStore(store)
+}
+
+const (
+	// EngineKey is the label key of the storage engine of the stores.
+	EngineKey = "engine"
+	// EngineTiFlash is the TiFlash value of the engine label.
+	EngineTiFlash = "tiflash"
+)
+
+var allEngines = []string{EngineTiFlash}
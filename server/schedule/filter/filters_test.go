@@ -14,6 +14,7 @@ package filter
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -46,6 +47,39 @@ func (s *testFiltersSuite) TestPendingPeerFilter(c *C) {
 	c.Assert(filter.Target(tc, newStore), IsTrue)
 }
 
+func (s *testFiltersSuite) TestStoreBudgetFilter(c *C) {
+	tc := mockcluster.NewCluster(mockoption.NewScheduleOptions())
+	filter := NewStoreBudgetFilter("", map[uint64]float64{1: 0.5, 2: 1}, 1)
+	store1 := core.NewStoreInfo(&metapb.Store{Id: 1})
+	c.Assert(filter.Source(tc, store1), IsTrue)
+	c.Assert(filter.Target(tc, store1), IsFalse)
+	c.Assert(filter.Target(tc, core.NewStoreInfo(&metapb.Store{Id: 2})), IsTrue)
+	// The store without a limit is not filtered.
+	c.Assert(filter.Target(tc, core.NewStoreInfo(&metapb.Store{Id: 3})), IsTrue)
+}
+
+func (s *testFiltersSuite) TestFlappingStore(c *C) {
+	tc := mockcluster.NewCluster(mockoption.NewScheduleOptions())
+	tc.AddRegionStore(1, 10)
+	now := time.Now()
+	var restarts *core.StoreRestarts
+	for i := 0; i < 2; i++ {
+		restarts = restarts.Record(now, 1, time.Minute, time.Minute)
+	}
+	store := tc.GetStore(1).Clone(core.SetStoreRestarts(restarts))
+	c.Assert(store.IsFlapping(), IsTrue)
+	filters := []Filter{
+		NewHealthFilter(""),
+		StoreStateFilter{ActionScope: "", TransferLeader: true},
+		StoreStateFilter{ActionScope: "", MoveRegion: true},
+	}
+	for _, filter := range filters {
+		c.Assert(filter.Source(tc, store), IsTrue)
+		c.Assert(filter.Target(tc, store), IsFalse)
+		c.Assert(filter.Target(tc, tc.GetStore(1)), IsTrue)
+	}
+}
+
 func (s *testFiltersSuite) TestLabelConstraintsFilter(c *C) {
 	opt := mockoption.NewScheduleOptions()
 	tc := mockcluster.NewCluster(opt)
@@ -80,3 +114,33 @@ func (s *testFiltersSuite) TestRuleFitFilter(c *C) {
 	c.Assert(filter.Target(tc, tc.GetStore(4)), IsFalse)
 	c.Assert(filter.Source(tc, tc.GetStore(4)), IsTrue)
 }
+
+func (s *testFiltersSuite) TestEngineFilter(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	tikv := core.NewStoreInfoWithLabel(1, 1, nil)
+	tiflash := core.NewStoreInfoWithLabel(2, 1, map[string]string{"engine": "tiflash"})
+
+	filter := NewEngineFilter("")
+	c.Assert(filter.Source(tc, tikv), IsTrue)
+	c.Assert(filter.Target(tc, tikv), IsTrue)
+	c.Assert(filter.Source(tc, tiflash), IsFalse)
+	c.Assert(filter.Target(tc, tiflash), IsFalse)
+	filter = NewEngineFilter("", EngineTiFlash)
+	c.Assert(filter.Target(tc, tiflash), IsTrue)
+
+	// The rules decide which peers are moved out of the TiFlash stores.
+	opt.EnablePlacementRules = true
+	filter = NewEngineFilter("")
+	c.Assert(filter.Source(tc, tiflash), IsTrue)
+	c.Assert(filter.Target(tc, tiflash), IsFalse)
+
+	rule := &placement.Rule{GroupID: "pd", ID: "tiflash", Role: placement.Learner, Count: 1,
+		LabelConstraints: []placement.LabelConstraint{{Key: "engine", Op: "in", Values: []string{"tiflash"}}}}
+	filter = NewRuleEngineFilter("", rule)
+	c.Assert(filter.Target(tc, tiflash), IsTrue)
+	c.Assert(filter.Target(tc, tikv), IsTrue)
+	rule.LabelConstraints = []placement.LabelConstraint{{Key: "engine", Op: "notIn", Values: []string{"tiflash"}}}
+	filter = NewRuleEngineFilter("", rule)
+	c.Assert(filter.Target(tc, tiflash), IsFalse)
+}
@@ -50,6 +50,32 @@ var (
 			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
 		}, []string{"type"})
 
+	operatorCreatorWaitDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "operator_creator_wait_duration_seconds",
+			Help:      "Bucketed histogram of waiting time (s) from creating to starting of operator by creator.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+		}, []string{"creator"})
+
+	operatorCreatorExecutionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "operator_creator_execution_duration_seconds",
+			Help:      "Bucketed histogram of execution time (s) of finished operator by creator.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+		}, []string{"creator"})
+
+	operatorPingPongCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "operators_pingpong_count",
+			Help:      "Counter of the operators rejected or deferred for the regions operated too often.",
+		}, []string{"type", "action"})
+
 	storeLimitGauge = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "pd",
@@ -65,4 +91,7 @@ func init() {
 	prometheus.MustRegister(operatorWaitDuration)
 	prometheus.MustRegister(storeLimitGauge)
 	prometheus.MustRegister(operatorWaitCounter)
+	prometheus.MustRegister(operatorCreatorWaitDuration)
+	prometheus.MustRegister(operatorCreatorExecutionDuration)
+	prometheus.MustRegister(operatorPingPongCounter)
 }
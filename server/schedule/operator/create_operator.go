@@ -146,22 +146,28 @@ func isRegionMatch(a, b *core.RegionInfo) bool {
 	return true
 }
 
-// CreateScatterRegionOperator creates an operator that scatters the specified region.
-func CreateScatterRegionOperator(desc string, cluster Cluster, origin *core.RegionInfo, targetPeers map[uint64]*metapb.Peer) (*Operator, error) {
-	// randomly pick a leader.
-	var ids []uint64
-	for id := range targetPeers {
-		ids = append(ids, id)
-	}
-	var leader uint64
-	if len(ids) > 0 {
-		leader = ids[rand.Intn(len(ids))]
+// CreateScatterRegionOperator creates an operator that scatters the specified
+// region. The leader is transferred to the target leader store, or a random
+// one of the target stores if it is 0. The peers added by a light weight one
+// take no tokens of the store limits.
+func CreateScatterRegionOperator(desc string, cluster Cluster, origin *core.RegionInfo, targetPeers map[uint64]*metapb.Peer, leader uint64, lightWeight bool) (*Operator, error) {
+	if leader == 0 {
+		// randomly pick a leader.
+		var ids []uint64
+		for id := range targetPeers {
+			ids = append(ids, id)
+		}
+		if len(ids) > 0 {
+			leader = ids[rand.Intn(len(ids))]
+		}
 	}
-	return NewBuilder(desc, cluster, origin).
+	b := NewBuilder(desc, cluster, origin).
 		SetPeers(targetPeers).
-		SetLeader(leader).
-		SetLightWeight().
-		Build(0)
+		SetLeader(leader)
+	if lightWeight {
+		b.SetLightWeight()
+	}
+	return b.Build(0)
 }
 
 type u64Slice []uint64
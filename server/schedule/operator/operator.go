@@ -39,6 +39,17 @@ const (
 	RegionOperatorWaitTime = 10 * time.Minute
 )
 
+// The creators of the operators not created by a checker or a scheduler.
+const (
+	// CreatorAdmin is the creator of the operators added via the API.
+	CreatorAdmin = "admin"
+	// CreatorClient is the creator of the operators requested by the clients
+	// via gRPC, such as scattering a region.
+	CreatorClient = "client"
+	// CreatorUnknown is the creator of the operators whose creator is not set.
+	CreatorUnknown = "unknown"
+)
+
 // Cluster provides an overview of a cluster's regions distribution.
 type Cluster interface {
 	opt.Options
@@ -51,6 +62,8 @@ type Cluster interface {
 type Operator struct {
 	desc        string
 	brief       string
+	creator     string
+	reason      *Reason
 	regionID    uint64
 	regionEpoch *metapb.RegionEpoch
 	kind        OpKind
@@ -59,7 +72,17 @@ type Operator struct {
 	status      OpStatusTracker
 	stepTime    int64
 	level       core.PriorityLevel
-	Counters    []prometheus.Counter
+	// token identifies the operator among the ones of its region, and after
+	// is the operator which it waits for. Both are set by the controller.
+	token    uint64
+	after    *Dependency
+	Counters []prometheus.Counter
+}
+
+// Dependency refers to an operator by its region and token.
+type Dependency struct {
+	RegionID uint64 `json:"region_id"`
+	Token    uint64 `json:"token"`
 }
 
 // NewOperator creates a new operator.
@@ -86,6 +109,12 @@ func (o *Operator) String() string {
 		stepStrs[i] = o.steps[i].String()
 	}
 	s := fmt.Sprintf("%s {%s} (kind:%s, region:%v(%v,%v), createAt:%s, startAt:%s, currentStep:%v, steps:[%s])", o.desc, o.brief, o.kind, o.regionID, o.regionEpoch.GetVersion(), o.regionEpoch.GetConfVer(), o.GetCreateTime(), o.GetStartTime(), atomic.LoadInt32(&o.currentStep), strings.Join(stepStrs, ", "))
+	if o.reason != nil {
+		s = s + " reason:" + o.reason.String()
+	}
+	if o.after != nil {
+		s = s + fmt.Sprintf(" after:%d(%d)", o.after.RegionID, o.after.Token)
+	}
 	if o.CheckSuccess() {
 		s = s + " finished"
 	}
@@ -110,6 +139,66 @@ func (o *Operator) SetDesc(desc string) {
 	o.desc = desc
 }
 
+// Creator returns the name of the checker or the scheduler which creates the
+// operator, or one of the creators not being a checker or a scheduler.
+func (o *Operator) Creator() string {
+	if o.creator == "" {
+		return CreatorUnknown
+	}
+	return o.creator
+}
+
+// SetCreator sets the creator of the operator.
+func (o *Operator) SetCreator(creator string) {
+	o.creator = creator
+}
+
+// Reason returns the structured cause of the operator, or nil if its creator
+// does not set one.
+func (o *Operator) Reason() *Reason {
+	return o.reason
+}
+
+// SetReason sets the structured cause of the operator.
+func (o *Operator) SetReason(reason *Reason) {
+	o.reason = reason
+}
+
+// Clone returns an operator with the same steps and attributes, whose status
+// starts over from CREATED.
+func (o *Operator) Clone() *Operator {
+	op := NewOperator(o.desc, o.brief, o.regionID, o.regionEpoch, o.kind, o.steps...)
+	op.creator = o.creator
+	op.reason = o.reason
+	op.level = o.level
+	op.token = o.token
+	op.after = o.after
+	op.Counters = o.Counters
+	return op
+}
+
+// Token returns the token of the operator, which is 0 until it is added or
+// held by the controller.
+func (o *Operator) Token() uint64 {
+	return o.token
+}
+
+// SetToken sets the token of the operator.
+func (o *Operator) SetToken(token uint64) {
+	o.token = token
+}
+
+// After returns the operator which the operator waits for, or nil if it does
+// not wait.
+func (o *Operator) After() *Dependency {
+	return o.after
+}
+
+// SetAfter sets the operator which the operator waits for.
+func (o *Operator) SetAfter(after *Dependency) {
+	o.after = after
+}
+
 // AttachKind attaches an operator kind for the operator.
 func (o *Operator) AttachKind(kind OpKind) {
 	o.kind |= kind
@@ -252,6 +341,17 @@ func (o *Operator) Check(region *core.RegionInfo) OpStep {
 	return nil
 }
 
+// CurrentStep returns the first unfinished step without changing the state
+// of the operator. It returns nil if all steps are finished.
+func (o *Operator) CurrentStep(region *core.RegionInfo) OpStep {
+	for step := atomic.LoadInt32(&o.currentStep); int(step) < len(o.steps); step++ {
+		if !o.steps[int(step)].IsFinish(region) {
+			return o.steps[int(step)]
+		}
+	}
+	return nil
+}
+
 // ConfVerChanged returns the number of confver has consumed by steps
 func (o *Operator) ConfVerChanged(region *core.RegionInfo) int {
 	total := 0
@@ -294,27 +394,93 @@ func (o *Operator) TotalInfluence(opInfluence OpInfluence, region *core.RegionIn
 	}
 }
 
+// StoreIDs returns the IDs of the stores referenced by the steps, in the order
+// of the first reference. The merge steps reference the stores of the peers of
+// both regions.
+func (o *Operator) StoreIDs() []uint64 {
+	var ids []uint64
+	add := func(id uint64) {
+		if id == 0 {
+			return
+		}
+		for _, i := range ids {
+			if i == id {
+				return
+			}
+		}
+		ids = append(ids, id)
+	}
+	for _, step := range o.steps {
+		switch s := step.(type) {
+		case TransferLeader:
+			add(s.FromStore)
+			add(s.ToStore)
+		case AddPeer:
+			add(s.ToStore)
+		case AddLightPeer:
+			add(s.ToStore)
+		case AddLearner:
+			add(s.ToStore)
+		case AddLightLearner:
+			add(s.ToStore)
+		case PromoteLearner:
+			add(s.ToStore)
+		case RemovePeer:
+			add(s.FromStore)
+		case MergeRegion:
+			for _, p := range s.FromRegion.GetPeers() {
+				add(p.GetStoreId())
+			}
+			for _, p := range s.ToRegion.GetPeers() {
+				add(p.GetStoreId())
+			}
+		}
+	}
+	return ids
+}
+
+// OpHistoryResult is the result of a finished operator in its histories.
+type OpHistoryResult string
+
+// Results of the finished operators.
+const (
+	OpSucceeded OpHistoryResult = "succeeded"
+	OpTimedOut  OpHistoryResult = "timeout"
+	OpCanceled  OpHistoryResult = "canceled"
+)
+
 // OpHistory is used to log and visualize completed operators.
 type OpHistory struct {
 	FinishTime time.Time
 	From, To   uint64
 	Kind       core.ResourceKind
+	RegionID   uint64
+	Desc       string
+	OpKind     OpKind
+	// Duration is the running time of the operator.
+	Duration time.Duration
+	Result   OpHistoryResult
 }
 
 // History transfers the operator's steps to operator histories.
 func (o *Operator) History() []OpHistory {
 	now := time.Now()
+	base := OpHistory{
+		FinishTime: now,
+		RegionID:   o.RegionID(),
+		Desc:       o.Desc(),
+		OpKind:     o.Kind(),
+		Duration:   o.RunningTime(),
+		Result:     o.HistoryResult(),
+	}
 	var histories []OpHistory
 	var addPeerStores, removePeerStores []uint64
 	for _, step := range o.steps {
 		switch s := step.(type) {
 		case TransferLeader:
-			histories = append(histories, OpHistory{
-				FinishTime: now,
-				From:       s.FromStore,
-				To:         s.ToStore,
-				Kind:       core.LeaderKind,
-			})
+			history := base
+			history.From, history.To, history.Kind = s.FromStore, s.ToStore, core.LeaderKind
+			histories = append(histories, history)
 		case AddPeer:
 			addPeerStores = append(addPeerStores, s.ToStore)
 		case AddLightPeer:
@@ -329,13 +495,22 @@ func (o *Operator) History() []OpHistory {
 	}
 	for i := range addPeerStores {
 		if i < len(removePeerStores) {
-			histories = append(histories, OpHistory{
-				FinishTime: now,
-				From:       removePeerStores[i],
-				To:         addPeerStores[i],
-				Kind:       core.RegionKind,
-			})
+			history := base
+			history.From, history.To, history.Kind = removePeerStores[i], addPeerStores[i], core.RegionKind
+			histories = append(histories, history)
 		}
 	}
 	return histories
 }
+
+// HistoryResult returns the result of the finished operator in the histories.
+func (o *Operator) HistoryResult() OpHistoryResult {
+	switch o.Status() {
+	case SUCCESS:
+		return OpSucceeded
+	case TIMEOUT:
+		return OpTimedOut
+	default:
+		return OpCanceled
+	}
+}
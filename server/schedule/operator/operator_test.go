@@ -15,6 +15,7 @@ package operator
 
 import (
 	"encoding/json"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -373,3 +374,35 @@ func (s *testOperatorSuite) TestCheck(c *C) {
 		c.Assert(op.Status(), Equals, SUCCESS)
 	}
 }
+
+func (s *testOperatorSuite) TestStoreIDs(c *C) {
+	op := s.newTestOperator(1, OpLeader|OpRegion,
+		AddLearner{ToStore: 3, PeerID: 3},
+		PromoteLearner{ToStore: 3, PeerID: 3},
+		TransferLeader{FromStore: 1, ToStore: 3},
+		RemovePeer{FromStore: 1},
+	)
+	c.Assert(op.StoreIDs(), DeepEquals, []uint64{3, 1})
+
+	merge := MergeRegion{
+		FromRegion: &metapb.Region{Peers: []*metapb.Peer{{StoreId: 1}, {StoreId: 2}}},
+		ToRegion:   &metapb.Region{Peers: []*metapb.Peer{{StoreId: 2}, {StoreId: 4}}},
+	}
+	op = s.newTestOperator(1, OpMerge, merge)
+	c.Assert(op.StoreIDs(), DeepEquals, []uint64{1, 2, 4})
+	op = s.newTestOperator(1, OpSplit, SplitRegion{})
+	c.Assert(op.StoreIDs(), HasLen, 0)
+}
+
+func (s *testOperatorSuite) TestReason(c *C) {
+	op := s.newTestOperator(1, OpLeader, TransferLeader{FromStore: 1, ToStore: 3})
+	c.Assert(op.Reason(), IsNil)
+	c.Assert(strings.Contains(op.String(), "reason:"), IsFalse)
+
+	reason := NewReason(ReasonScoreImbalance, 1, 3).With("target-score", 10).With("source-score", 15.5)
+	op.SetReason(reason)
+	c.Assert(reason.String(), Equals, "score-imbalance(source-store:1, target-store:3, source-score:15.5, target-score:10)")
+	c.Assert(strings.Contains(op.String(), " reason:"+reason.String()), IsTrue)
+	c.Assert(op.Clone().Reason(), Equals, reason)
+	c.Assert(NewReason(ReasonOfflinePeer, 2, 0).String(), Equals, "offline-peer(source-store:2)")
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The codes of the reasons why the operators are created.
+const (
+	// ReasonDownPeer is the reason of replacing or removing a down peer.
+	ReasonDownPeer = "down-peer"
+	// ReasonOfflinePeer is the reason of replacing or removing a peer on an
+	// offline store.
+	ReasonOfflinePeer = "offline-peer"
+	// ReasonMissingReplica is the reason of adding a peer to the region with
+	// fewer replicas than configured.
+	ReasonMissingReplica = "missing-replica"
+	// ReasonExtraReplica is the reason of removing a peer from the region with
+	// more replicas than configured.
+	ReasonExtraReplica = "extra-replica"
+	// ReasonBetterLocation is the reason of moving a peer to the store whose
+	// distinct score is higher.
+	ReasonBetterLocation = "better-location"
+	// ReasonScoreImbalance is the reason of moving a leader or a peer from the
+	// store whose score is higher beyond the tolerance.
+	ReasonScoreImbalance = "score-imbalance"
+	// ReasonCountImbalance is the reason of moving a leader from the store
+	// whose leader count is higher beyond the tolerant count.
+	ReasonCountImbalance = "count-imbalance"
+	// ReasonHotLoad is the reason of moving a hot leader or peer from the
+	// store whose flow is higher.
+	ReasonHotLoad = "hot-load"
+	// ReasonFastGrowth is the reason of splitting the region whose approximate
+	// size grows faster than configured.
+	ReasonFastGrowth = "fast-growth"
+)
+
+// Reason is the structured cause of an operator: the rule or the comparison
+// which leads to it, and the values involved. It is optional, so the creators
+// such as the plugins can skip it.
+type Reason struct {
+	Code        string `json:"code"`
+	SourceStore uint64 `json:"source_store,omitempty"`
+	TargetStore uint64 `json:"target_store,omitempty"`
+	// Evidence is the numeric values compared, such as the scores of the
+	// stores, keyed by their names.
+	Evidence map[string]float64 `json:"evidence,omitempty"`
+}
+
+// NewReason creates a reason with the source and the target stores, either of
+// which is 0 if not involved.
+func NewReason(code string, sourceStore, targetStore uint64) *Reason {
+	return &Reason{
+		Code:        code,
+		SourceStore: sourceStore,
+		TargetStore: targetStore,
+	}
+}
+
+// With adds a value to the evidence and returns the reason.
+func (r *Reason) With(name string, value float64) *Reason {
+	if r.Evidence == nil {
+		r.Evidence = make(map[string]float64)
+	}
+	r.Evidence[name] = value
+	return r
+}
+
+func (r *Reason) String() string {
+	var fields []string
+	if r.SourceStore != 0 {
+		fields = append(fields, fmt.Sprintf("source-store:%d", r.SourceStore))
+	}
+	if r.TargetStore != 0 {
+		fields = append(fields, fmt.Sprintf("target-store:%d", r.TargetStore))
+	}
+	names := make([]string, 0, len(r.Evidence))
+	for name := range r.Evidence {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fields = append(fields, name+":"+strconv.FormatFloat(r.Evidence[name], 'g', -1, 64))
+	}
+	return fmt.Sprintf("%s(%s)", r.Code, strings.Join(fields, ", "))
+}
@@ -34,6 +34,18 @@ const (
 	smallRegionThreshold int64 = 20
 )
 
+// AddPeerStepCost returns the tokens of the store limit taken by adding a
+// peer of the region to a store.
+func AddPeerStepCost(region *core.RegionInfo) int64 {
+	regionSize := region.GetApproximateSize()
+	if regionSize > smallRegionThreshold {
+		return RegionInfluence
+	} else if regionSize > core.EmptyRegionApproximateSize {
+		return smallRegionInfluence
+	}
+	return 0
+}
+
 // OpStep describes the basic scheduling steps that can not be subdivided.
 type OpStep interface {
 	fmt.Stringer
@@ -104,14 +116,9 @@ func (ap AddPeer) IsFinish(region *core.RegionInfo) bool {
 func (ap AddPeer) Influence(opInfluence OpInfluence, region *core.RegionInfo) {
 	to := opInfluence.GetStoreInfluence(ap.ToStore)
 
-	regionSize := region.GetApproximateSize()
-	to.RegionSize += regionSize
+	to.RegionSize += region.GetApproximateSize()
 	to.RegionCount++
-	if regionSize > smallRegionThreshold {
-		to.StepCost += RegionInfluence
-	} else if regionSize <= smallRegionThreshold && regionSize > core.EmptyRegionApproximateSize {
-		to.StepCost += smallRegionInfluence
-	}
+	to.StepCost += AddPeerStepCost(region)
 }
 
 // AddLearner is an OpStep that adds a region learner peer.
@@ -147,14 +154,9 @@ func (al AddLearner) IsFinish(region *core.RegionInfo) bool {
 func (al AddLearner) Influence(opInfluence OpInfluence, region *core.RegionInfo) {
 	to := opInfluence.GetStoreInfluence(al.ToStore)
 
-	regionSize := region.GetApproximateSize()
-	to.RegionSize += regionSize
+	to.RegionSize += region.GetApproximateSize()
 	to.RegionCount++
-	if regionSize > smallRegionThreshold {
-		to.StepCost += RegionInfluence
-	} else if regionSize <= smallRegionThreshold && regionSize > core.EmptyRegionApproximateSize {
-		to.StepCost += smallRegionInfluence
-	}
+	to.StepCost += AddPeerStepCost(region)
 }
 
 // PromoteLearner is an OpStep that promotes a region learner peer to normal voter.
@@ -0,0 +1,284 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// chainOutcomeKeepTime is how long the end status of an operator is kept for
+// the operators submitted after it.
+const chainOutcomeKeepTime = 10 * time.Minute
+
+var (
+	// ErrPrerequisiteNotFound is error info for chaining operators after an
+	// operator which is unknown or ended too long ago.
+	ErrPrerequisiteNotFound = errors.New("prerequisite operator not found")
+	// ErrPrerequisiteFailed is error info for chaining operators after an
+	// operator which has not finished successfully.
+	ErrPrerequisiteFailed = errors.New("prerequisite operator failed")
+)
+
+// RebuildFunc builds the chained operators again from the current region, as
+// the region may be changed by the prerequisite operator.
+type RebuildFunc func() ([]*operator.Operator, error)
+
+// chainedOperators is the operators held for a prerequisite, which are
+// rebuilt when they are released.
+type chainedOperators struct {
+	ops     []*operator.Operator
+	rebuild RebuildFunc
+}
+
+type chainOutcome struct {
+	regionID uint64
+	status   operator.OpStatus
+	time     time.Time
+}
+
+// operatorChains tracks the operators by their tokens, and holds the
+// operators chained after the ones not ended yet. It is threadsafe.
+type operatorChains struct {
+	sync.Mutex
+	nextToken uint64
+	// live is the operators added or held, which have not ended.
+	live map[uint64]*operator.Operator
+	// outcomes is the end status of the operators ended recently.
+	outcomes map[uint64]chainOutcome
+	// held is the operators held by the token of the operator they wait for.
+	held map[uint64][]*chainedOperators
+	now  func() time.Time
+}
+
+func newOperatorChains() *operatorChains {
+	return &operatorChains{
+		nextToken: 1,
+		live:      make(map[uint64]*operator.Operator),
+		outcomes:  make(map[uint64]chainOutcome),
+		held:      make(map[uint64][]*chainedOperators),
+		now:       time.Now,
+	}
+}
+
+// observeAdd tracks the operator which is added, assigning a token to it if it
+// has none.
+func (c *operatorChains) observeAdd(op *operator.Operator) {
+	c.Lock()
+	defer c.Unlock()
+	c.trackLocked(op)
+}
+
+func (c *operatorChains) trackLocked(op *operator.Operator) {
+	if op.Token() == 0 {
+		op.SetToken(c.nextToken)
+		c.nextToken++
+	}
+	c.live[op.Token()] = op
+}
+
+// observeEnd records the end status of the operator. An operator replaced by
+// its clone is ignored.
+func (c *operatorChains) observeEnd(op *operator.Operator) {
+	c.Lock()
+	defer c.Unlock()
+	if op.Token() == 0 || c.live[op.Token()] != op {
+		return
+	}
+	delete(c.live, op.Token())
+	c.outcomes[op.Token()] = chainOutcome{regionID: op.RegionID(), status: op.Status(), time: c.now()}
+}
+
+// hold holds the operators until the prerequisite operator ends. It returns
+// false if the prerequisite has finished successfully, and the operators
+// should be added right away.
+func (c *operatorChains) hold(after operator.Dependency, rebuild RebuildFunc, ops []*operator.Operator) (bool, error) {
+	c.Lock()
+	defer c.Unlock()
+	if outcome, ok := c.outcomes[after.Token]; ok && outcome.regionID == after.RegionID {
+		if outcome.status != operator.SUCCESS {
+			return false, errors.Wrapf(ErrPrerequisiteFailed, "the operator %d of region %d ends with status %s",
+				after.Token, after.RegionID, operator.OpStatusToString(outcome.status))
+		}
+		return false, nil
+	}
+	if op, ok := c.live[after.Token]; !ok || op.RegionID() != after.RegionID {
+		return false, errors.Wrapf(ErrPrerequisiteNotFound, "no operator %d of region %d", after.Token, after.RegionID)
+	}
+	for _, op := range ops {
+		op.SetAfter(&operator.Dependency{RegionID: after.RegionID, Token: after.Token})
+		c.trackLocked(op)
+	}
+	c.held[after.Token] = append(c.held[after.Token], &chainedOperators{ops: ops, rebuild: rebuild})
+	return true, nil
+}
+
+// release takes out the held operators whose prerequisites have ended. It
+// also removes the outcomes kept too long.
+func (c *operatorChains) release() (ready, failed []*chainedOperators) {
+	c.Lock()
+	defer c.Unlock()
+	for token, held := range c.held {
+		if _, ok := c.live[token]; ok {
+			continue
+		}
+		delete(c.held, token)
+		if outcome, ok := c.outcomes[token]; !ok || outcome.status != operator.SUCCESS {
+			failed = append(failed, held...)
+			continue
+		}
+		ready = append(ready, held...)
+	}
+	cutoff := c.now().Add(-chainOutcomeKeepTime)
+	for token, outcome := range c.outcomes {
+		if outcome.time.Before(cutoff) {
+			delete(c.outcomes, token)
+		}
+	}
+	return
+}
+
+// replace tracks the operators rebuilt for the held ones by the tokens of
+// the held ones.
+func (c *operatorChains) replace(held, ops []*operator.Operator) {
+	c.Lock()
+	defer c.Unlock()
+	for i, op := range ops {
+		c.live[op.Token()] = op
+		// The held operator ends, so that it is no longer counted by the
+		// operator quota.
+		_ = held[i].Cancel()
+	}
+}
+
+// getHeld returns the held operators.
+func (c *operatorChains) getHeld() []*operator.Operator {
+	c.Lock()
+	defer c.Unlock()
+	var ops []*operator.Operator
+	for _, held := range c.held {
+		for _, chained := range held {
+			ops = append(ops, chained.ops...)
+		}
+	}
+	return ops
+}
+
+// AddChainedOperators adds the operators after the prerequisite operator
+// finishes successfully. They are added right away if it has finished
+// already, or held otherwise. The held operators are rebuilt by rebuild when
+// the prerequisite finishes, as it may change the region, or cloned if
+// rebuild is nil. They are canceled if the prerequisite fails. It returns
+// false if the operators are not added or held.
+func (oc *OperatorController) AddChainedOperators(after operator.Dependency, rebuild RebuildFunc, ops ...*operator.Operator) (bool, error) {
+	held, err := oc.chains.hold(after, rebuild, ops)
+	if err != nil {
+		return false, err
+	}
+	if !held {
+		return oc.AddOperator(ops...), nil
+	}
+	for _, op := range ops {
+		log.Info("operator is held for the prerequisite",
+			zap.Uint64("region-id", op.RegionID()),
+			zap.Reflect("operator", op))
+		operatorCounter.WithLabelValues(op.Desc(), "hold").Inc()
+		oc.trace(op, "operator %s is held until the operator %d of region %d finishes", op, after.Token, after.RegionID)
+	}
+	return true, nil
+}
+
+// promoteChainedOperators adds the held operators whose prerequisites have
+// finished successfully, and cancels the ones whose prerequisites have not.
+// The operators held for the canceled ones are canceled in turn, and so are
+// the ones held for the operators failed to be rebuilt or added.
+func (oc *OperatorController) promoteChainedOperators() {
+	for {
+		ready, failed := oc.chains.release()
+		if len(ready) == 0 && len(failed) == 0 {
+			return
+		}
+		for _, chained := range ready {
+			ops, err := oc.rebuildChainedOperators(chained)
+			if err != nil {
+				oc.cancelChainedOperators(chained.ops, "it fails to be rebuilt: "+err.Error())
+				continue
+			}
+			oc.chains.replace(chained.ops, ops)
+			if !oc.AddOperator(ops...) {
+				oc.cancelChainedOperators(ops, "it fails to be added")
+			}
+		}
+		for _, chained := range failed {
+			oc.cancelChainedOperators(chained.ops, "its prerequisite fails")
+		}
+	}
+}
+
+// rebuildChainedOperators builds the released operators again, which inherit
+// the tokens, the prerequisites and the creators of the held ones.
+func (oc *OperatorController) rebuildChainedOperators(chained *chainedOperators) ([]*operator.Operator, error) {
+	if chained.rebuild == nil {
+		ops := make([]*operator.Operator, 0, len(chained.ops))
+		for _, op := range chained.ops {
+			ops = append(ops, op.Clone())
+		}
+		return ops, nil
+	}
+	ops, err := chained.rebuild()
+	if err != nil {
+		return nil, err
+	}
+	if len(ops) != len(chained.ops) {
+		return nil, errors.Errorf("%d operators are rebuilt instead of %d", len(ops), len(chained.ops))
+	}
+	for i, op := range ops {
+		held := chained.ops[i]
+		op.SetToken(held.Token())
+		op.SetAfter(held.After())
+		op.SetCreator(held.Creator())
+		if reason := held.Reason(); reason != nil {
+			op.SetReason(reason)
+		}
+	}
+	return ops, nil
+}
+
+// cancelChainedOperators cancels and buries the operators not ended yet, so
+// that their outcomes are recorded for the operators held for them.
+func (oc *OperatorController) cancelChainedOperators(ops []*operator.Operator, reason string) {
+	for _, op := range ops {
+		if operator.IsEndStatus(op.Status()) {
+			continue
+		}
+		operatorCounter.WithLabelValues(op.Desc(), "cancel").Inc()
+		oc.trace(op, "operator %s is canceled because %s", op, reason)
+		_ = op.Cancel()
+		oc.buryOperator(op)
+	}
+}
+
+// getChainedOperators returns the operators held for their prerequisites,
+// sorted by the region ID.
+func (oc *OperatorController) getChainedOperators() []*operator.Operator {
+	ops := oc.chains.getHeld()
+	sort.Slice(ops, func(i, j int) bool { return ops[i].RegionID() < ops[j].RegionID() })
+	return ops
+}
@@ -18,8 +18,10 @@ import (
 	"container/list"
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pingcap/failpoint"
@@ -31,6 +33,7 @@ import (
 	"github.com/pingcap/pd/v4/server/core"
 	"github.com/pingcap/pd/v4/server/schedule/operator"
 	"github.com/pingcap/pd/v4/server/schedule/opt"
+	"github.com/pingcap/pd/v4/server/statistics"
 	"go.uber.org/zap"
 )
 
@@ -51,13 +54,79 @@ var (
 	StoreBalanceBaseTime float64 = 60
 )
 
+const (
+	// OperatorCountWindow is the window in which the operators created and
+	// finished are counted, and operatorCountBuckets divide it.
+	OperatorCountWindow  = 5 * time.Minute
+	operatorCountBuckets = 30
+)
+
+// operatorPresenceSlots is the number of slots of operatorPresence.
+const operatorPresenceSlots = 4096
+
+// operatorPresence counts the operators by slots hashed from the region IDs,
+// so that most regions without operators can be told without locking.
+type operatorPresence [operatorPresenceSlots]int32
+
+func (p *operatorPresence) add(regionID uint64) {
+	atomic.AddInt32(&p[regionID%operatorPresenceSlots], 1)
+}
+
+func (p *operatorPresence) remove(regionID uint64) {
+	atomic.AddInt32(&p[regionID%operatorPresenceSlots], -1)
+}
+
+// mayHave returns false if the region has no operator for sure.
+func (p *operatorPresence) mayHave(regionID uint64) bool {
+	return atomic.LoadInt32(&p[regionID%operatorPresenceSlots]) > 0
+}
+
+// storeOperators indexes the running and waiting operators by the stores
+// referenced by their steps.
+type storeOperators map[uint64]map[*operator.Operator]struct{}
+
+func (s storeOperators) add(op *operator.Operator) {
+	for _, storeID := range op.StoreIDs() {
+		ops, ok := s[storeID]
+		if !ok {
+			ops = make(map[*operator.Operator]struct{})
+			s[storeID] = ops
+		}
+		ops[op] = struct{}{}
+	}
+}
+
+func (s storeOperators) remove(op *operator.Operator) {
+	for _, storeID := range op.StoreIDs() {
+		if ops, ok := s[storeID]; ok {
+			delete(ops, op)
+			if len(ops) == 0 {
+				delete(s, storeID)
+			}
+		}
+	}
+}
+
+// get returns the operators involving the store sorted by the region ID.
+func (s storeOperators) get(storeID uint64) []*operator.Operator {
+	ops := make([]*operator.Operator, 0, len(s[storeID]))
+	for op := range s[storeID] {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].RegionID() < ops[j].RegionID() })
+	return ops
+}
+
 // OperatorController is used to limit the speed of scheduling.
 type OperatorController struct {
 	sync.RWMutex
 	ctx             context.Context
 	cluster         opt.Cluster
 	operators       map[uint64]*operator.Operator
+	presence        operatorPresence
+	storeOperators  storeOperators
 	hbStreams       opt.HeartbeatStreams
+	historyLock     sync.RWMutex
 	histories       *list.List
 	counts          map[operator.OpKind]uint64
 	opRecords       *OperatorRecords
@@ -65,6 +134,14 @@ type OperatorController struct {
 	wop             WaitingOperator
 	wopStatus       *WaitingOperatorStatus
 	opNotifierQueue operatorQueue
+	splitIntoJobs   *splitIntoJobs
+	finishObserver  func(op *operator.Operator, region *core.RegionInfo)
+	latencies       *operatorLatencies
+	createdOps      *statistics.SlidingCounterVec
+	finishedOps     *statistics.SlidingCounterVec
+	pingpong        *operatorPingPong
+	deferredOps     map[uint64][]*operator.Operator
+	chains          *operatorChains
 }
 
 // NewOperatorController creates a OperatorController.
@@ -73,6 +150,7 @@ func NewOperatorController(ctx context.Context, cluster opt.Cluster, hbStreams o
 		ctx:             ctx,
 		cluster:         cluster,
 		operators:       make(map[uint64]*operator.Operator),
+		storeOperators:  make(storeOperators),
 		hbStreams:       hbStreams,
 		histories:       list.New(),
 		counts:          make(map[operator.OpKind]uint64),
@@ -81,9 +159,22 @@ func NewOperatorController(ctx context.Context, cluster opt.Cluster, hbStreams o
 		wop:             NewRandBuckets(),
 		wopStatus:       NewWaitingOperatorStatus(),
 		opNotifierQueue: make(operatorQueue, 0),
+		splitIntoJobs:   newSplitIntoJobs(),
+		latencies:       newOperatorLatencies(),
+		createdOps:      statistics.NewSlidingCounterVec(OperatorCountWindow, operatorCountBuckets),
+		finishedOps:     statistics.NewSlidingCounterVec(OperatorCountWindow, operatorCountBuckets),
+		pingpong:        newOperatorPingPong(),
+		deferredOps:     make(map[uint64][]*operator.Operator),
+		chains:          newOperatorChains(),
 	}
 }
 
+// SetFinishObserver sets the function called with the operators finished
+// successfully and their regions. It should be set before dispatching.
+func (oc *OperatorController) SetFinishObserver(f func(op *operator.Operator, region *core.RegionInfo)) {
+	oc.finishObserver = f
+}
+
 // Ctx returns a context which will be canceled once RaftCluster is stopped.
 // For now, it is only used to control the lifetime of TTL cache in schedulers.
 func (oc *OperatorController) Ctx() context.Context {
@@ -100,7 +191,7 @@ func (oc *OperatorController) GetCluster() opt.Cluster {
 // Dispatch is used to dispatch the operator of a region.
 func (oc *OperatorController) Dispatch(region *core.RegionInfo, source string) {
 	// Check existed operator.
-	if op := oc.GetOperator(region.GetID()); op != nil {
+	if op := oc.getDispatchingOperator(region.GetID()); op != nil {
 		failpoint.Inject("concurrentRemoveOperator", func() {
 			time.Sleep(500 * time.Millisecond)
 		})
@@ -118,8 +209,10 @@ func (oc *OperatorController) Dispatch(region *core.RegionInfo, source string) {
 			}
 			oc.SendScheduleCommand(region, step, source)
 		case operator.SUCCESS:
-			oc.pushHistory(op)
 			if oc.RemoveOperator(op) {
+				if oc.finishObserver != nil {
+					oc.finishObserver(op, region)
+				}
 				oc.PromoteWaitingOperator()
 			}
 		case operator.TIMEOUT:
@@ -144,6 +237,20 @@ func (oc *OperatorController) Dispatch(region *core.RegionInfo, source string) {
 			}
 		}
 	}
+
+	// Continue splitting the region if it is a part of a split-into job.
+	if op := oc.splitIntoJobs.observe(region); op != nil && oc.GetOperator(region.GetID()) == nil {
+		oc.AddOperator(op)
+	}
+}
+
+// getDispatchingOperator returns the operator of the region. It checks the
+// presence of the operator before locking, as most regions have no operator.
+func (oc *OperatorController) getDispatchingOperator(regionID uint64) *operator.Operator {
+	if !oc.presence.mayHave(regionID) {
+		return nil
+	}
+	return oc.GetOperator(regionID)
 }
 
 func (oc *OperatorController) checkStaleOperator(op *operator.Operator, region *core.RegionInfo) bool {
@@ -228,6 +335,8 @@ func (oc *OperatorController) pollNeedDispatchRegion() (r *core.RegionInfo, next
 
 // PushOperators periodically pushes the unfinished operator to the executor(TiKV).
 func (oc *OperatorController) PushOperators() {
+	oc.promoteDeferredOperators()
+	oc.promoteChainedOperators()
 	for {
 		r, next := oc.pollNeedDispatchRegion()
 		if !next {
@@ -278,12 +387,14 @@ func (oc *OperatorController) AddWaitingOperator(ops ...*operator.Operator) int
 			return added
 		}
 		oc.wop.PutOperator(op)
+		oc.storeOperators.add(op)
 		if isMerge {
 			// count two merge operators as one, so wopStatus.ops[desc] should
 			// not be updated here
 			i++
 			added++
 			oc.wop.PutOperator(ops[i])
+			oc.storeOperators.add(ops[i])
 		}
 		operatorWaitCounter.WithLabelValues(desc, "put").Inc()
 		oc.wopStatus.ops[desc]++
@@ -300,6 +411,9 @@ func (oc *OperatorController) AddOperator(ops ...*operator.Operator) bool {
 	oc.Lock()
 	defer oc.Unlock()
 
+	if oc.checkPingPong(ops...) {
+		return false
+	}
 	if oc.exceedStoreLimit(ops...) || !oc.checkAddOperator(ops...) {
 		for _, op := range ops {
 			operatorCounter.WithLabelValues(op.Desc(), "cancel").Inc()
@@ -329,11 +443,20 @@ func (oc *OperatorController) PromoteWaitingOperator() {
 		}
 		operatorWaitCounter.WithLabelValues(ops[0].Desc(), "get").Inc()
 
+		// The operators of the schedulers and the checkers are guarded here.
+		if oc.checkPingPong(ops...) {
+			for _, op := range ops {
+				oc.storeOperators.remove(op)
+			}
+			oc.wopStatus.ops[ops[0].Desc()]--
+			continue
+		}
 		if oc.exceedStoreLimit(ops...) || !oc.checkAddOperator(ops...) {
 			for _, op := range ops {
 				operatorWaitCounter.WithLabelValues(op.Desc(), "promote_canceled").Inc()
 				_ = op.Cancel()
 				oc.buryOperator(op)
+				oc.storeOperators.remove(op)
 			}
 			oc.wopStatus.ops[ops[0].Desc()]--
 			continue
@@ -342,8 +465,12 @@ func (oc *OperatorController) PromoteWaitingOperator() {
 		break
 	}
 
-	for _, op := range ops {
+	for i, op := range ops {
 		if !oc.addOperatorLocked(op) {
+			// The rest are neither running nor waiting.
+			for _, op := range ops[i:] {
+				oc.storeOperators.remove(op)
+			}
 			break
 		}
 	}
@@ -372,13 +499,17 @@ func (oc *OperatorController) checkAddOperator(ops ...*operator.Operator) bool {
 				zap.Reflect("old", region.GetRegionEpoch()),
 				zap.Reflect("new", op.RegionEpoch()))
 			operatorWaitCounter.WithLabelValues(op.Desc(), "add_canceled").Inc()
+			oc.trace(op, "operator %s is canceled because the region epoch does not match", op)
+			oc.reject(op, "the region epoch does not match")
 			return false
 		}
-		if old := oc.operators[op.RegionID()]; old != nil && !isHigherPriorityOperator(op, old) {
+		if old := oc.getConflictingOperator(op); old != nil {
 			log.Debug("already have operator, cancel add operator",
 				zap.Uint64("region-id", op.RegionID()),
 				zap.Reflect("old", old))
 			operatorWaitCounter.WithLabelValues(op.Desc(), "add_canceled").Inc()
+			oc.trace(op, "operator %s is canceled because operator %s is running", op, old)
+			oc.reject(op, "operator %s is running", old.Desc())
 			return false
 		}
 		if op.Status() != operator.CREATED {
@@ -395,6 +526,7 @@ func (oc *OperatorController) checkAddOperator(ops ...*operator.Operator) bool {
 		if oc.wopStatus.ops[op.Desc()] >= oc.cluster.GetSchedulerMaxWaitingOperator() {
 			log.Debug("exceed_max return false", zap.Uint64("waiting", oc.wopStatus.ops[op.Desc()]), zap.String("desc", op.Desc()), zap.Uint64("max", oc.cluster.GetSchedulerMaxWaitingOperator()))
 			operatorWaitCounter.WithLabelValues(op.Desc(), "exceed_max").Inc()
+			oc.reject(op, "exceed the max number of the waiting operators")
 			return false
 		}
 	}
@@ -439,8 +571,13 @@ func (oc *OperatorController) addOperatorLocked(op *operator.Operator) bool {
 		return false
 	}
 	oc.operators[regionID] = op
+	oc.chains.observeAdd(op)
+	oc.presence.add(regionID)
+	oc.storeOperators.add(op)
 	operatorCounter.WithLabelValues(op.Desc(), "start").Inc()
 	operatorWaitDuration.WithLabelValues(op.Desc()).Observe(op.ElapsedTime().Seconds())
+	oc.latencies.observeStart(op)
+	oc.createdOps.Add(op.Kind().String(), 1)
 	opInfluence := NewTotalOpInfluence([]*operator.Operator{op}, oc.cluster)
 	for storeID := range opInfluence.StoresInfluence {
 		stepCost := opInfluence.GetStoreInfluence(storeID).StepCost
@@ -461,6 +598,7 @@ func (oc *OperatorController) addOperatorLocked(op *operator.Operator) bool {
 
 	heap.Push(&oc.opNotifierQueue, &operatorWithTime{op: op, time: oc.getNextPushOperatorTime(step, time.Now())})
 	operatorCounter.WithLabelValues(op.Desc(), "create").Inc()
+	oc.trace(op, "operator %s is created", op)
 	for _, counter := range op.Counters {
 		counter.Inc()
 	}
@@ -494,6 +632,8 @@ func (oc *OperatorController) removeOperatorLocked(op *operator.Operator) bool {
 	regionID := op.RegionID()
 	if cur := oc.operators[regionID]; cur == op {
 		delete(oc.operators, regionID)
+		oc.presence.remove(regionID)
+		oc.storeOperators.remove(op)
 		oc.updateCounts(oc.operators)
 		operatorCounter.WithLabelValues(op.Desc(), "remove").Inc()
 		return true
@@ -524,6 +664,9 @@ func (oc *OperatorController) buryOperator(op *operator.Operator) {
 			zap.Reflect("operator", op))
 		operatorCounter.WithLabelValues(op.Desc(), "finish").Inc()
 		operatorDuration.WithLabelValues(op.Desc()).Observe(op.RunningTime().Seconds())
+		oc.latencies.observeFinish(op)
+		oc.finishedOps.Add(op.Kind().String(), 1)
+		oc.pingpong.observeFinish(op)
 	case operator.REPLACED:
 		log.Info("replace old operator",
 			zap.Uint64("region-id", op.RegionID()),
@@ -544,9 +687,20 @@ func (oc *OperatorController) buryOperator(op *operator.Operator) {
 		operatorCounter.WithLabelValues(op.Desc(), "timeout").Inc()
 	}
 
+	// The operators never started are not in the histories as they have not
+	// moved anything.
+	if op.HasStarted() {
+		oc.pushHistory(op)
+	}
+	oc.chains.observeEnd(op)
+	oc.trace(op, "operator %s ends with status %s", op, operator.OpStatusToString(st))
 	oc.opRecords.Put(op)
 }
 
+func (oc *OperatorController) trace(op *operator.Operator, format string, args ...interface{}) {
+	oc.cluster.GetRegionTracer().Record(op.RegionID(), "operator-controller", format, args...)
+}
+
 // GetOperatorStatus gets the operator and its status with the specify id.
 func (oc *OperatorController) GetOperatorStatus(id uint64) *OperatorWithStatus {
 	oc.Lock()
@@ -557,6 +711,22 @@ func (oc *OperatorController) GetOperatorStatus(id uint64) *OperatorWithStatus {
 	return oc.opRecords.Get(id)
 }
 
+// AddSplitIntoOperator adds the operator created by CreateSplitIntoOperator, and
+// keeps splitting the new regions in halves until the region is split into parts.
+func (oc *OperatorController) AddSplitIntoOperator(region *core.RegionInfo, op *operator.Operator, parts int) bool {
+	oc.splitIntoJobs.add(region, parts)
+	if !oc.AddOperator(op) {
+		oc.splitIntoJobs.remove(region.GetID())
+		return false
+	}
+	return true
+}
+
+// GetSplitIntoStatus gets the progress of splitting the region into parts.
+func (oc *OperatorController) GetSplitIntoStatus(regionID uint64) *SplitIntoStatus {
+	return oc.splitIntoJobs.getStatus(regionID)
+}
+
 // GetOperator gets a operator from the given region.
 func (oc *OperatorController) GetOperator(regionID uint64) *operator.Operator {
 	oc.RLock()
@@ -577,11 +747,47 @@ func (oc *OperatorController) GetOperators() []*operator.Operator {
 	return operators
 }
 
-// GetWaitingOperators gets operators from the waiting operators.
+// GetStoreOperators gets the running and waiting operators involving the
+// store, sorted by the region ID. The controller is created with the
+// coordinator, so the index starts empty after the leader changes.
+func (oc *OperatorController) GetStoreOperators(storeID uint64) []*operator.Operator {
+	oc.RLock()
+	defer oc.RUnlock()
+	return oc.storeOperators.get(storeID)
+}
+
+// GetOperatorCounts returns the operators created and finished successfully
+// in the recent window by the kind.
+func (oc *OperatorController) GetOperatorCounts() (created, finished map[string]uint64) {
+	return oc.createdOps.Counts(), oc.finishedOps.Counts()
+}
+
+// GetFinishedOperatorRate returns the operators of the kind finished
+// successfully per second in the recent window.
+func (oc *OperatorController) GetFinishedOperatorRate(kind operator.OpKind) float64 {
+	var count uint64
+	for label, n := range oc.finishedOps.Counts() {
+		if k, err := operator.ParseOperatorKind(label); err == nil && k&kind != 0 {
+			count += n
+		}
+	}
+	return float64(count) / oc.finishedOps.Window().Seconds()
+}
+
+// GetOperatorQueueDepth returns the number of the running and waiting
+// operators.
+func (oc *OperatorController) GetOperatorQueueDepth() (running, waiting int) {
+	oc.RLock()
+	defer oc.RUnlock()
+	return len(oc.operators), len(oc.wop.ListOperator())
+}
+
+// GetWaitingOperators gets operators from the waiting operators, along with
+// the ones held for their prerequisites.
 func (oc *OperatorController) GetWaitingOperators() []*operator.Operator {
 	oc.RLock()
 	defer oc.RUnlock()
-	return oc.wop.ListOperator()
+	return append(oc.wop.ListOperator(), oc.getChainedOperators()...)
 }
 
 // SendScheduleCommand sends a command to the region.
@@ -703,30 +909,46 @@ func (oc *OperatorController) SendScheduleCommand(region *core.RegionInfo, step
 	}
 }
 
+// pushHistory logs the histories of a finished operator, and annotates its
+// region with it if the region still exists. It is called with or without
+// holding the lock of the controller, so the histories have their own.
 func (oc *OperatorController) pushHistory(op *operator.Operator) {
-	oc.Lock()
-	defer oc.Unlock()
+	if oc.cluster.GetRegion(op.RegionID()) != nil {
+		oc.cluster.GetRegionAnnotations().AnnotateFinished(op.RegionID(), op.Desc(), op.Creator(), string(op.HistoryResult()))
+	}
+	oc.historyLock.Lock()
+	defer oc.historyLock.Unlock()
 	for _, h := range op.History() {
 		oc.histories.PushFront(h)
 	}
 }
 
+// reject annotates the region with the operator failed to be added.
+func (oc *OperatorController) reject(op *operator.Operator, format string, args ...interface{}) {
+	oc.cluster.GetRegionAnnotations().AnnotateRejected(op.RegionID(), op.Desc(), op.Creator(), fmt.Sprintf(format, args...))
+}
+
 // PruneHistory prunes a part of operators' history.
 func (oc *OperatorController) PruneHistory() {
-	oc.Lock()
-	defer oc.Unlock()
+	oc.historyLock.Lock()
 	p := oc.histories.Back()
 	for p != nil && time.Since(p.Value.(operator.OpHistory).FinishTime) > historyKeepTime {
 		prev := p.Prev()
 		oc.histories.Remove(p)
 		p = prev
 	}
+	oc.historyLock.Unlock()
+	oc.cluster.GetRegionAnnotations().GC()
+	oc.Lock()
+	defer oc.Unlock()
+	oc.latencies.gc()
+	oc.pingpong.gc(oc.cluster.GetOperatorPingPongWindow())
 }
 
-// GetHistory gets operators' history.
+// GetHistory gets operators' history, the latest first.
 func (oc *OperatorController) GetHistory(start time.Time) []operator.OpHistory {
-	oc.RLock()
-	defer oc.RUnlock()
+	oc.historyLock.RLock()
+	defer oc.historyLock.RUnlock()
 	histories := make([]operator.OpHistory, 0, oc.histories.Len())
 	for p := oc.histories.Front(); p != nil; p = p.Next() {
 		history := p.Value.(operator.OpHistory)
@@ -799,7 +1021,13 @@ func NewTotalOpInfluence(operators []*operator.Operator, cluster opt.Cluster) op
 func (oc *OperatorController) SetOperator(op *operator.Operator) {
 	oc.Lock()
 	defer oc.Unlock()
+	if old, ok := oc.operators[op.RegionID()]; !ok {
+		oc.presence.add(op.RegionID())
+	} else {
+		oc.storeOperators.remove(old)
+	}
 	oc.operators[op.RegionID()] = op
+	oc.storeOperators.add(op)
 }
 
 // OperatorWithStatus records the operator and its status.
@@ -851,8 +1079,29 @@ func (o *OperatorRecords) Put(op *operator.Operator) {
 	o.ttl.Put(id, record)
 }
 
+// GetConflictingOperator returns the running operator of the region which
+// prevents the operator from being added, or nil if there is none.
+func (oc *OperatorController) GetConflictingOperator(op *operator.Operator) *operator.Operator {
+	oc.RLock()
+	defer oc.RUnlock()
+	return oc.getConflictingOperator(op)
+}
+
+func (oc *OperatorController) getConflictingOperator(op *operator.Operator) *operator.Operator {
+	if old := oc.operators[op.RegionID()]; old != nil && !isHigherPriorityOperator(op, old) {
+		return old
+	}
+	return nil
+}
+
 // exceedStoreLimit returns true if the store exceeds the cost limit after adding the operator. Otherwise, returns false.
 func (oc *OperatorController) exceedStoreLimit(ops ...*operator.Operator) bool {
+	return oc.exceededStore(ops...) != 0
+}
+
+// exceededStore returns the first store which exceeds the cost limit after
+// adding the operators, or 0 if there is none.
+func (oc *OperatorController) exceededStore(ops ...*operator.Operator) uint64 {
 	opInfluence := NewTotalOpInfluence(ops, oc.cluster)
 	for storeID := range opInfluence.StoresInfluence {
 		stepCost := opInfluence.GetStoreInfluence(storeID).StepCost
@@ -863,10 +1112,18 @@ func (oc *OperatorController) exceedStoreLimit(ops ...*operator.Operator) bool {
 		available := oc.getOrCreateStoreLimit(storeID).bucket.Available()
 		storeLimitGauge.WithLabelValues(strconv.FormatUint(storeID, 10), "available").Set(float64(available) / float64(operator.RegionInfluence))
 		if available < stepCost {
-			return true
+			return storeID
 		}
 	}
-	return false
+	return 0
+}
+
+// CheckStoreLimit returns the first store which would exceed the cost limit
+// if the operators were added, or 0 if there is none.
+func (oc *OperatorController) CheckStoreLimit(ops ...*operator.Operator) uint64 {
+	oc.Lock()
+	defer oc.Unlock()
+	return oc.exceededStore(ops...)
 }
 
 // SetAllStoresLimit is used to set limit of all stores.
@@ -902,6 +1159,30 @@ func (oc *OperatorController) SetStoreLimit(storeID uint64, rate float64, mode S
 	oc.newStoreLimit(storeID, rate, mode)
 }
 
+// ResetStoreLimit replaces the manual limit of a store with the automatic one
+// of the rate. It does nothing if the limit of the store is not manual.
+func (oc *OperatorController) ResetStoreLimit(storeID uint64, rate float64) {
+	oc.Lock()
+	defer oc.Unlock()
+	oc.resetStoreLimit(storeID, rate)
+}
+
+// ResetAllStoresLimit replaces the manual limits of all stores with the
+// automatic ones of the rate.
+func (oc *OperatorController) ResetAllStoresLimit(rate float64) {
+	oc.Lock()
+	defer oc.Unlock()
+	for storeID := range oc.storesLimit {
+		oc.resetStoreLimit(storeID, rate)
+	}
+}
+
+func (oc *OperatorController) resetStoreLimit(storeID uint64, rate float64) {
+	if old, ok := oc.storesLimit[storeID]; ok && old.Mode() == StoreLimitManual {
+		oc.newStoreLimit(storeID, rate, StoreLimitAuto)
+	}
+}
+
 // newStoreLimit is used to create the limit of a store.
 func (oc *OperatorController) newStoreLimit(storeID uint64, rate float64, mode StoreLimitMode) {
 	oc.storesLimit[storeID] = NewStoreLimit(rate, mode)
@@ -935,6 +1216,52 @@ func (oc *OperatorController) GetAllStoresLimit() map[uint64]*StoreLimit {
 	return limits
 }
 
+// StoreBudget is the forecast of the store limit of a store. The values are in
+// the unit of the cost of adding a regular region peer.
+type StoreBudget struct {
+	StoreID uint64 `json:"store_id"`
+	// Available is what the store limit allows currently.
+	Available float64 `json:"available"`
+	// Waiting is the projected cost of the waiting operators.
+	Waiting float64 `json:"waiting"`
+	// Remaining is what remains after the waiting operators are promoted.
+	Remaining float64 `json:"remaining"`
+}
+
+// GetStoreBudgets returns the forecast of the store limits, so that the
+// schedulers running in the same tick do not oversubscribe a store.
+func (oc *OperatorController) GetStoreBudgets() map[uint64]*StoreBudget {
+	oc.RLock()
+	defer oc.RUnlock()
+	// Only the waiting operators involving the limited stores are accounted.
+	var waitingOps []*operator.Operator
+	seen := make(map[*operator.Operator]struct{})
+	for storeID := range oc.storesLimit {
+		for op := range oc.storeOperators[storeID] {
+			if _, ok := seen[op]; !ok && oc.operators[op.RegionID()] != op {
+				seen[op] = struct{}{}
+				waitingOps = append(waitingOps, op)
+			}
+		}
+	}
+	waiting := NewTotalOpInfluence(waitingOps, oc.cluster)
+	budgets := make(map[uint64]*StoreBudget, len(oc.storesLimit))
+	for storeID, limit := range oc.storesLimit {
+		if store := oc.cluster.GetStore(storeID); store == nil || store.IsTombstone() {
+			continue
+		}
+		available := limit.Available()
+		cost := waiting.GetStoreInfluence(storeID).StepCost
+		budgets[storeID] = &StoreBudget{
+			StoreID:   storeID,
+			Available: float64(available) / float64(operator.RegionInfluence),
+			Waiting:   float64(cost) / float64(operator.RegionInfluence),
+			Remaining: float64(available-cost) / float64(operator.RegionInfluence),
+		}
+	}
+	return budgets
+}
+
 // GetLeaderSchedulePolicy is to get leader schedule policy.
 func (oc *OperatorController) GetLeaderSchedulePolicy() core.SchedulePolicy {
 	if oc.cluster == nil {
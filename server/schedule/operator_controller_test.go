@@ -17,6 +17,7 @@ import (
 	"container/heap"
 	"context"
 	"fmt"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
@@ -31,6 +32,7 @@ import (
 	"github.com/pingcap/pd/v4/server/core"
 	"github.com/pingcap/pd/v4/server/schedule/checker"
 	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"github.com/pkg/errors"
 )
 
 func Test(t *testing.T) {
@@ -129,6 +131,107 @@ func (t *testOperatorControllerSuite) TestOperatorStatus(c *C) {
 	c.Assert(oc.GetOperatorStatus(2).Status, Equals, pdpb.OperatorStatus_SUCCESS)
 }
 
+func (t *testOperatorControllerSuite) TestOperatorHistory(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	oc := NewOperatorController(t.ctx, tc, mockhbstream.NewHeartbeatStream())
+	tc.AddLeaderStore(1, 3)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderRegion(1, 1, 2)
+	tc.AddLeaderRegion(2, 1, 2)
+	tc.AddLeaderRegion(3, 1, 2)
+	steps := []operator.OpStep{
+		operator.RemovePeer{FromStore: 2},
+		operator.AddPeer{ToStore: 2, PeerID: 4},
+	}
+	op1 := operator.NewOperator("test", "test", 1, &metapb.RegionEpoch{}, operator.OpRegion, steps...)
+	op2 := operator.NewOperator("test", "test", 2, &metapb.RegionEpoch{}, operator.OpRegion, steps...)
+	op3 := operator.NewOperator("test", "test", 3, &metapb.RegionEpoch{}, operator.OpLeader|operator.OpAdmin, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	for _, op := range []*operator.Operator{op1, op2, op3} {
+		c.Assert(op.Start(), IsTrue)
+		oc.SetOperator(op)
+	}
+	// The operators never started are not in the histories.
+	op4 := operator.NewOperator("test", "test", 4, &metapb.RegionEpoch{}, operator.OpRegion, steps...)
+	c.Assert(op4.Cancel(), IsTrue)
+	oc.buryOperator(op4)
+
+	start := time.Now()
+	operator.SetOperatorStatusReachTime(op1, operator.STARTED, time.Now().Add(-10*time.Minute))
+	oc.Dispatch(tc.GetRegion(1), "test")
+	ApplyOperator(tc, op2)
+	oc.Dispatch(tc.GetRegion(2), "test")
+	c.Assert(oc.RemoveOperator(op3), IsTrue)
+
+	histories := oc.GetHistory(start)
+	c.Assert(histories, HasLen, 3)
+	c.Assert(histories[0].RegionID, Equals, uint64(3))
+	c.Assert(histories[0].Kind, Equals, core.LeaderKind)
+	c.Assert(histories[0].OpKind, Equals, operator.OpLeader|operator.OpAdmin)
+	c.Assert(histories[0].Result, Equals, operator.OpCanceled)
+	c.Assert(histories[1].RegionID, Equals, uint64(2))
+	c.Assert(histories[1].Kind, Equals, core.RegionKind)
+	c.Assert(histories[1].From, Equals, uint64(2))
+	c.Assert(histories[1].To, Equals, uint64(2))
+	c.Assert(histories[1].Result, Equals, operator.OpSucceeded)
+	c.Assert(histories[2].RegionID, Equals, uint64(1))
+	c.Assert(histories[2].Result, Equals, operator.OpTimedOut)
+	c.Assert(histories[2].Duration >= 10*time.Minute, IsTrue)
+	c.Assert(oc.GetHistory(time.Now().Add(time.Minute)), HasLen, 0)
+}
+
+func (t *testOperatorControllerSuite) TestFinishedOperatorRate(c *C) {
+	tc := mockcluster.NewCluster(mockoption.NewScheduleOptions())
+	oc := NewOperatorController(t.ctx, tc, mockhbstream.NewHeartbeatStream())
+	oc.finishedOps.Add((operator.OpRegion | operator.OpReplica).String(), 3)
+	oc.finishedOps.Add((operator.OpLeader | operator.OpBalance).String(), 5)
+	oc.finishedOps.Add(operator.OpRegion.String(), 1)
+	window := OperatorCountWindow.Seconds()
+	c.Assert(oc.GetFinishedOperatorRate(operator.OpReplica), Equals, 3/window)
+	c.Assert(oc.GetFinishedOperatorRate(operator.OpRegion), Equals, 4/window)
+	c.Assert(oc.GetFinishedOperatorRate(operator.OpMerge), Equals, 0.0)
+}
+
+func (t *testOperatorControllerSuite) TestOperatorPresence(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	oc := NewOperatorController(t.ctx, tc, mockhbstream.NewHeartbeatStream())
+	tc.AddLeaderStore(1, 2)
+	tc.AddLeaderStore(2, 0)
+	// Region 1 and region 1+operatorPresenceSlots share the same slot.
+	collided := uint64(1 + operatorPresenceSlots)
+	tc.AddLeaderRegion(1, 1)
+	tc.AddLeaderRegion(2, 1)
+	tc.AddLeaderRegion(collided, 1)
+	newOp := func(regionID uint64, kind operator.OpKind) *operator.Operator {
+		return operator.NewOperator("test", "test", regionID, &metapb.RegionEpoch{}, kind, operator.AddPeer{ToStore: 2, PeerID: 4})
+	}
+	c.Assert(oc.presence.mayHave(1), IsFalse)
+
+	op1 := newOp(1, operator.OpRegion)
+	c.Assert(oc.AddOperator(op1), IsTrue)
+	c.Assert(oc.presence.mayHave(1), IsTrue)
+	c.Assert(oc.presence.mayHave(collided), IsTrue)
+	c.Assert(oc.presence.mayHave(2), IsFalse)
+	c.Assert(oc.getDispatchingOperator(1), Equals, op1)
+	c.Assert(oc.getDispatchingOperator(collided), IsNil)
+
+	// Dispatching the regions without operators does not change anything.
+	oc.Dispatch(tc.GetRegion(collided), DispatchFromHeartBeat)
+	oc.Dispatch(tc.GetRegion(2), DispatchFromHeartBeat)
+	c.Assert(oc.GetOperator(1), Equals, op1)
+	c.Assert(oc.GetOperator(collided), IsNil)
+
+	// Replacing the operator does not count twice.
+	op2 := newOp(1, operator.OpRegion|operator.OpAdmin)
+	c.Assert(oc.AddOperator(op2), IsTrue)
+	c.Assert(oc.GetOperator(1), Equals, op2)
+	oc.SetOperator(op2)
+	c.Assert(oc.RemoveOperator(op2), IsTrue)
+	c.Assert(oc.presence.mayHave(1), IsFalse)
+	c.Assert(oc.getDispatchingOperator(1), IsNil)
+}
+
 func (t *testOperatorControllerSuite) TestCheckAddUnexpectedStatus(c *C) {
 	c.Assert(failpoint.Disable("github.com/pingcap/pd/v4/server/schedule/unexpectedOperator"), IsNil)
 	opt := mockoption.NewScheduleOptions()
@@ -341,6 +444,52 @@ func (t *testOperatorControllerSuite) TestStoreLimit(c *C) {
 	c.Assert(oc.RemoveOperator(op), IsFalse)
 }
 
+func (t *testOperatorControllerSuite) TestSnapshotFlows(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	oc := NewOperatorController(t.ctx, tc, mockhbstream.NewHeartbeatStream())
+	for i := uint64(1); i <= 5; i++ {
+		tc.AddRegionStore(i, 0)
+	}
+	tc.PutRegion(newRegionInfo(1, "", "a", 10, 10, []uint64{11, 1}, []uint64{11, 1}, []uint64{12, 2}))
+	tc.PutRegion(newRegionInfo(2, "a", "b", 20, 20, []uint64{21, 1}, []uint64{21, 1}, []uint64{22, 2}))
+	tc.PutRegion(newRegionInfo(3, "b", "c", 30, 30, []uint64{32, 2}, []uint64{31, 1}, []uint64{32, 2}))
+	tc.PutRegion(newRegionInfo(4, "c", "", 40, 40, []uint64{41, 1}, []uint64{41, 1}, []uint64{42, 2}))
+	tc.UpdateSnapshotCount(3, 2)
+
+	ops := []*operator.Operator{
+		operator.NewOperator("test", "test", 1, &metapb.RegionEpoch{}, operator.OpRegion, operator.AddLearner{ToStore: 3, PeerID: 13}),
+		operator.NewOperator("test", "test", 2, &metapb.RegionEpoch{}, operator.OpRegion, operator.AddPeer{ToStore: 3, PeerID: 23}),
+		operator.NewOperator("test", "test", 3, &metapb.RegionEpoch{}, operator.OpRegion, operator.AddLightLearner{ToStore: 4, PeerID: 34}),
+		// Transferring leader does not need a snapshot.
+		operator.NewOperator("test", "test", 4, &metapb.RegionEpoch{}, operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2}),
+	}
+	for _, op := range ops {
+		c.Assert(op.Start(), IsTrue)
+		oc.SetOperator(op)
+	}
+
+	flows := oc.GetSnapshotFlows()
+	c.Assert(flows.Flows, HasLen, 2)
+	c.Assert(flows.Flows[1], HasLen, 1)
+	c.Assert(flows.Flows[1][3].RegionCount, Equals, 2)
+	c.Assert(uint64(flows.Flows[1][3].EstimatedSize), Equals, uint64(30<<20))
+	c.Assert(flows.Flows[1][3].Regions, HasLen, 2)
+	c.Assert(flows.Flows[2], HasLen, 1)
+	c.Assert(flows.Flows[2][4].RegionCount, Equals, 1)
+	c.Assert(flows.Flows[2][4].Regions, DeepEquals, []uint64{3})
+	// Store 5 is idle.
+	c.Assert(flows.Stores, HasLen, 4)
+	c.Assert(flows.Stores[5], IsNil)
+	c.Assert(flows.Stores[3].ApplyingSnapCount, Equals, uint32(2))
+
+	// The flow disappears once the learner is added.
+	tc.PutRegion(tc.GetRegion(3).Clone(core.WithAddPeer(&metapb.Peer{Id: 34, StoreId: 4, IsLearner: true})))
+	flows = oc.GetSnapshotFlows()
+	c.Assert(flows.Flows, HasLen, 1)
+	c.Assert(flows.Flows[2], IsNil)
+}
+
 // #1652
 func (t *testOperatorControllerSuite) TestDispatchOutdatedRegion(c *C) {
 	cluster := mockcluster.NewCluster(mockoption.NewScheduleOptions())
@@ -677,3 +826,486 @@ func (t *testOperatorControllerSuite) TestAddWaitingOperator(c *C) {
 	// no space left, new operator can not be added.
 	c.Assert(controller.AddWaitingOperator(addPeerOp(0)), Equals, 0)
 }
+
+func (t *testOperatorControllerSuite) TestSplitInto(c *C) {
+	cluster := mockcluster.NewCluster(mockoption.NewScheduleOptions())
+	stream := mockhbstream.NewHeartbeatStreams(cluster.ID, true /* no need to run */)
+	controller := NewOperatorController(t.ctx, cluster, stream)
+	cluster.AddLeaderStore(1, 1)
+	cluster.AddLeaderRegionWithRange(1, "000", "256", 1)
+
+	c.Assert(controller.AddSplitIntoOperator(cluster.GetRegion(1), CreateSplitIntoOperator(cluster.GetRegion(1)), 5), IsTrue)
+	c.Assert(controller.GetSplitIntoStatus(1), DeepEquals, &SplitIntoStatus{RegionID: 1, Parts: 5, FinishedParts: 0, Status: "RUNNING"})
+
+	// The split commands only split the regions in halves, PD keeps splitting
+	// the new regions according to their heartbeats.
+	nextID := uint64(100)
+	for len(stream.MsgCh()) > 0 {
+		msg := <-stream.MsgCh()
+		c.Assert(msg.GetSplitRegion().GetPolicy(), Equals, pdpb.CheckPolicy_APPROXIMATE)
+		region := cluster.GetRegion(msg.GetRegionId())
+		start, err := strconv.Atoi(string(region.GetStartKey()))
+		c.Assert(err, IsNil)
+		end, err := strconv.Atoi(string(region.GetEndKey()))
+		c.Assert(err, IsNil)
+		splitKey := []byte(fmt.Sprintf("%03d", (start+end)/2))
+		left := region.Clone(core.WithNewRegionID(nextID), core.WithEndKey(splitKey), core.WithIncVersion())
+		right := region.Clone(core.WithStartKey(splitKey), core.WithIncVersion())
+		nextID++
+		cluster.PutRegion(left)
+		cluster.PutRegion(right)
+		controller.Dispatch(left, DispatchFromHeartBeat)
+		controller.Dispatch(right, DispatchFromHeartBeat)
+	}
+
+	c.Assert(cluster.GetRegionCount(), Equals, 5)
+	c.Assert(controller.GetSplitIntoStatus(1), DeepEquals, &SplitIntoStatus{RegionID: 1, Parts: 5, FinishedParts: 5, Status: "SUCCESS"})
+	c.Assert(controller.GetOperators(), HasLen, 0)
+
+	// The job times out if a region can not be split.
+	old := splitIntoTimeout
+	defer func() { splitIntoTimeout = old }()
+	splitIntoTimeout = 100 * time.Millisecond
+	cluster.AddLeaderRegionWithRange(2, "256", "512", 1)
+	c.Assert(controller.AddSplitIntoOperator(cluster.GetRegion(2), CreateSplitIntoOperator(cluster.GetRegion(2)), 2), IsTrue)
+	c.Assert(controller.GetSplitIntoStatus(2).Status, Equals, "RUNNING")
+	time.Sleep(200 * time.Millisecond)
+	c.Assert(controller.GetSplitIntoStatus(2).Status, Equals, "TIMEOUT")
+}
+
+func (t *testOperatorControllerSuite) TestOperatorLatencies(c *C) {
+	tc := mockcluster.NewCluster(mockoption.NewScheduleOptions())
+	stream := mockhbstream.NewHeartbeatStreams(tc.ID, true /* no need to run */)
+	oc := NewOperatorController(t.ctx, tc, stream)
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderStore(3, 0)
+
+	cases := []struct {
+		creator   string
+		wait      time.Duration
+		execution time.Duration // 0 means the operator is not finished.
+	}{
+		{replicaCheckerCreator, 500 * time.Millisecond, 10 * time.Second},
+		{replicaCheckerCreator, time.Second, 20 * time.Second},
+		{replicaCheckerCreator, 2 * time.Second, 30 * time.Second},
+		{"balance-region-scheduler", 100 * time.Millisecond, time.Minute},
+		{operator.CreatorAdmin, 200 * time.Millisecond, 0},
+	}
+	for i, cs := range cases {
+		regionID := uint64(i + 1)
+		tc.AddLeaderRegion(regionID, 1, 2)
+		op := operator.NewOperator("test", "test", regionID, &metapb.RegionEpoch{}, operator.OpRegion,
+			operator.AddPeer{ToStore: 3, PeerID: 10 + regionID})
+		op.SetCreator(cs.creator)
+		operator.SetOperatorStatusReachTime(op, operator.CREATED, time.Now().Add(-cs.wait))
+		c.Assert(oc.AddOperator(op), IsTrue)
+		if cs.execution == 0 {
+			continue
+		}
+		operator.SetOperatorStatusReachTime(op, operator.STARTED, time.Now().Add(-cs.execution))
+		ApplyOperator(tc, op)
+		oc.Dispatch(tc.GetRegion(regionID), DispatchFromHeartBeat)
+		c.Assert(oc.GetOperator(regionID), IsNil)
+	}
+
+	checkSummary := func(summary LatencySummary, count int, p50, p95 time.Duration) {
+		c.Assert(summary.Count, Equals, count)
+		c.Assert(summary.P50, GreaterEqual, p50.Seconds())
+		c.Assert(summary.P50, Less, p50.Seconds()+1)
+		c.Assert(summary.P95, GreaterEqual, p95.Seconds())
+		c.Assert(summary.P95, Less, p95.Seconds()+1)
+	}
+	latencies := oc.GetOperatorLatencies(10 * time.Minute)
+	c.Assert(latencies, HasLen, 3)
+	c.Assert(latencies[0].Creator, Equals, operator.CreatorAdmin)
+	checkSummary(latencies[0].Wait, 1, 200*time.Millisecond, 200*time.Millisecond)
+	c.Assert(latencies[0].Execution.Count, Equals, 0)
+	c.Assert(latencies[1].Creator, Equals, "balance-region-scheduler")
+	checkSummary(latencies[1].Wait, 1, 100*time.Millisecond, 100*time.Millisecond)
+	checkSummary(latencies[1].Execution, 1, time.Minute, time.Minute)
+	c.Assert(latencies[2].Creator, Equals, replicaCheckerCreator)
+	checkSummary(latencies[2].Wait, 3, time.Second, 2*time.Second)
+	checkSummary(latencies[2].Execution, 3, 20*time.Second, 30*time.Second)
+
+	// The latencies out of the window are not summarized, and the ones out of
+	// the longest window are removed.
+	now := time.Now().Add(30 * time.Minute)
+	oc.latencies.now = func() time.Time { return now }
+	c.Assert(oc.GetOperatorLatencies(10*time.Minute), HasLen, 0)
+	c.Assert(oc.GetOperatorLatencies(MaxOperatorLatencyWindow), HasLen, 3)
+	now = now.Add(MaxOperatorLatencyWindow)
+	oc.PruneHistory()
+	c.Assert(oc.latencies.waits, HasLen, 0)
+	c.Assert(oc.latencies.executions, HasLen, 0)
+}
+
+func (t *testOperatorControllerSuite) TestOperatorCreator(c *C) {
+	tc := mockcluster.NewCluster(mockoption.NewScheduleOptions())
+	oc := NewOperatorController(t.ctx, tc, mockhbstream.NewHeartbeatStream())
+	checkers := NewCheckerController(t.ctx, tc, nil, oc)
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderRegion(1, 1)
+	_, ops := checkers.CheckRegion(tc.GetRegion(1))
+	c.Assert(ops, HasLen, 1)
+	c.Assert(ops[0].Creator(), Equals, replicaCheckerCreator)
+	op := operator.NewOperator("test", "test", 1, &metapb.RegionEpoch{}, operator.OpRegion)
+	c.Assert(op.Creator(), Equals, operator.CreatorUnknown)
+}
+
+func (t *testOperatorControllerSuite) TestStoreOperators(c *C) {
+	tc := mockcluster.NewCluster(mockoption.NewScheduleOptions())
+	stream := mockhbstream.NewHeartbeatStreams(tc.ID, true /* no need to run */)
+	oc := NewOperatorController(t.ctx, tc, stream)
+	for id := uint64(1); id <= 4; id++ {
+		tc.AddLeaderStore(id, 0)
+	}
+	for id := uint64(1); id <= 3; id++ {
+		tc.AddLeaderRegion(id, 1, 2)
+	}
+	newOp := func(regionID uint64, steps ...operator.OpStep) *operator.Operator {
+		return operator.NewOperator("test", "test", regionID, &metapb.RegionEpoch{}, operator.OpRegion, steps...)
+	}
+	checkStoreOperators := func(storeID uint64, ops ...*operator.Operator) {
+		c.Assert(oc.GetStoreOperators(storeID), DeepEquals, append([]*operator.Operator{}, ops...))
+	}
+
+	op1 := newOp(1, operator.AddPeer{ToStore: 3, PeerID: 11}, operator.RemovePeer{FromStore: 2})
+	c.Assert(oc.AddOperator(op1), IsTrue)
+	// One of the operators is promoted, and the other is waiting.
+	op2 := newOp(2, operator.AddPeer{ToStore: 3, PeerID: 12}, operator.RemovePeer{FromStore: 1})
+	op3 := newOp(3, operator.TransferLeader{FromStore: 1, ToStore: 4})
+	c.Assert(oc.AddWaitingOperator(op2, op3), Equals, 2)
+	c.Assert(oc.GetWaitingOperators(), HasLen, 1)
+	checkStoreOperators(1, op2, op3)
+	checkStoreOperators(2, op1)
+	checkStoreOperators(3, op1, op2)
+	checkStoreOperators(4, op3)
+	checkStoreOperators(5)
+
+	// The replaced and removed operators are not indexed.
+	op4 := newOp(1, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	op4.SetPriorityLevel(core.HighPriority)
+	c.Assert(oc.AddOperator(op4), IsTrue)
+	checkStoreOperators(1, op4, op2, op3)
+	checkStoreOperators(3, op2)
+	c.Assert(oc.RemoveOperator(op2), IsTrue)
+	checkStoreOperators(3)
+
+	// The canceled waiting operators are not indexed after promoted.
+	tc.RemoveRegion(tc.GetRegion(3))
+	oc.PromoteWaitingOperator()
+	c.Assert(oc.GetWaitingOperators(), HasLen, 0)
+	checkStoreOperators(1, op4)
+	checkStoreOperators(4)
+	c.Assert(oc.storeOperators, HasLen, 2)
+
+	// The controller created after the leader changes starts empty.
+	oc = NewOperatorController(t.ctx, tc, stream)
+	checkStoreOperators(1)
+}
+
+func (t *testOperatorControllerSuite) TestConcurrentStoreOperators(c *C) {
+	tc := mockcluster.NewCluster(mockoption.NewScheduleOptions())
+	stream := mockhbstream.NewHeartbeatStreams(tc.ID, true /* no need to run */)
+	oc := NewOperatorController(t.ctx, tc, stream)
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderStore(3, 0)
+	const regions = 100
+	for id := uint64(1); id <= regions; id++ {
+		tc.AddLeaderRegion(id, 1, 2)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for id := uint64(i + 1); id <= regions; id += 4 {
+				op := operator.NewOperator("test", "test", id, &metapb.RegionEpoch{}, operator.OpRegion,
+					operator.AddPeer{ToStore: 3, PeerID: 100 + id}, operator.RemovePeer{FromStore: 2})
+				if id%2 == 0 {
+					oc.AddOperator(op)
+				} else {
+					oc.AddWaitingOperator(op)
+				}
+				oc.GetStoreOperators(3)
+				if id%3 == 0 {
+					oc.RemoveOperator(op)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// The index is consistent with the running and waiting operators.
+	ops := append(oc.GetOperators(), oc.GetWaitingOperators()...)
+	c.Assert(len(ops), Greater, 0)
+	for _, storeID := range []uint64{2, 3} {
+		c.Assert(oc.GetStoreOperators(storeID), HasLen, len(ops))
+	}
+	indexed := make(map[*operator.Operator]bool)
+	for _, op := range oc.GetStoreOperators(3) {
+		indexed[op] = true
+	}
+	for _, op := range ops {
+		c.Assert(indexed[op], IsTrue)
+	}
+	c.Assert(oc.GetStoreOperators(1), HasLen, 0)
+}
+
+func (t *testOperatorControllerSuite) TestPingPongGuard(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	opt.OperatorPingPongLimit = 4
+	tc := mockcluster.NewCluster(opt)
+	stream := mockhbstream.NewHeartbeatStreams(tc.ID, true /* no need to run */)
+	oc := NewOperatorController(t.ctx, tc, stream)
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderRegion(1, 1, 2)
+	now := time.Now()
+	oc.pingpong.now = func() time.Time { return now }
+
+	// Two schedulers fight over the leader of region 1.
+	newOp := func(kind operator.OpKind) *operator.Operator {
+		region := tc.GetRegion(1)
+		from := region.GetLeader().GetStoreId()
+		creator := fmt.Sprintf("scheduler-%d", from)
+		op := operator.NewOperator(creator, "test", 1, region.GetRegionEpoch(), kind|operator.OpLeader,
+			operator.TransferLeader{FromStore: from, ToStore: 3 - from})
+		op.SetCreator(creator)
+		return op
+	}
+	// The operators of the schedulers are added as the waiting operators.
+	fight := func(rounds int) (finished int) {
+		for i := 0; i < rounds; i++ {
+			op := newOp(0)
+			c.Assert(oc.AddWaitingOperator(op), Equals, 1)
+			if op.Status() != operator.STARTED {
+				c.Assert(op.Status(), Equals, operator.CANCELED)
+				continue
+			}
+			ApplyOperator(tc, op)
+			oc.Dispatch(tc.GetRegion(1), DispatchFromHeartBeat)
+			c.Assert(op.Status(), Equals, operator.SUCCESS)
+			finished++
+		}
+		return
+	}
+	c.Assert(fight(20), Equals, 4)
+	status := oc.GetPingPongStatus()
+	c.Assert(status.Offenders, HasLen, 16)
+	c.Assert(status.Deferred, HasLen, 0)
+	offender := status.Offenders[0]
+	c.Assert(offender.RegionID, Equals, uint64(1))
+	c.Assert(offender.Action, Equals, PingPongReject)
+	c.Assert(offender.Recent, HasLen, 4)
+	c.Assert(offender.Recent[0].Creator, Equals, "scheduler-1")
+	c.Assert(offender.Recent[0].Moves, DeepEquals, []*OperatorMove{{Kind: "leader", From: 1, To: 2}})
+	c.Assert(offender.Recent[1].Moves, DeepEquals, []*OperatorMove{{Kind: "leader", From: 2, To: 1}})
+	c.Assert(oc.wopStatus.ops["test"], Equals, uint64(0))
+	c.Assert(oc.GetStoreOperators(1), HasLen, 0)
+	// The operators added directly are guarded too.
+	op := newOp(0)
+	c.Assert(oc.AddOperator(op), IsFalse)
+	c.Assert(op.Status(), Equals, operator.CANCELED)
+	c.Assert(oc.GetPingPongStatus().Offenders, HasLen, 17)
+
+	// The admin operators bypass the guard.
+	op = newOp(operator.OpAdmin)
+	c.Assert(oc.AddOperator(op), IsTrue)
+	c.Assert(oc.RemoveOperator(op), IsTrue)
+
+	// The operators are deferred, and added once the region calms down.
+	opt.OperatorPingPongAction = PingPongDefer
+	op = newOp(0)
+	c.Assert(oc.AddOperator(op), IsFalse)
+	c.Assert(op.Status(), Equals, operator.CANCELED)
+	c.Assert(oc.GetPingPongStatus().Deferred, HasLen, 1)
+	oc.PushOperators()
+	c.Assert(oc.GetOperator(1), IsNil)
+	now = now.Add(opt.OperatorPingPongWindow + time.Second)
+	oc.PushOperators()
+	c.Assert(oc.GetPingPongStatus().Deferred, HasLen, 0)
+	c.Assert(oc.GetOperator(1), NotNil)
+	c.Assert(oc.GetOperator(1).Creator(), Equals, op.Creator())
+
+	// The finished operators out of the window are removed.
+	oc.PruneHistory()
+	c.Assert(oc.pingpong.finished, HasLen, 0)
+}
+
+func (t *testOperatorControllerSuite) TestOperatorChain(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	stream := mockhbstream.NewHeartbeatStreams(tc.ID, true /* no need to run */)
+	oc := NewOperatorController(t.ctx, tc, stream)
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+	for id := uint64(1); id <= 3; id++ {
+		tc.AddLeaderRegion(id, 1, 2)
+	}
+	newOp := func(regionID uint64) *operator.Operator {
+		region := tc.GetRegion(regionID)
+		from := region.GetLeader().GetStoreId()
+		return operator.NewOperator("test", "test", regionID, region.GetRegionEpoch(), operator.OpAdmin|operator.OpLeader,
+			operator.TransferLeader{FromStore: from, ToStore: 3 - from})
+	}
+	dependOn := func(op *operator.Operator) operator.Dependency {
+		return operator.Dependency{RegionID: op.RegionID(), Token: op.Token()}
+	}
+	finish := func(op *operator.Operator) {
+		ApplyOperator(tc, op)
+		oc.Dispatch(tc.GetRegion(op.RegionID()), DispatchFromHeartBeat)
+		c.Assert(op.Status(), Equals, operator.SUCCESS)
+		oc.PushOperators()
+	}
+
+	// Region 2 waits for region 1, and region 3 waits for region 2.
+	op1 := newOp(1)
+	c.Assert(oc.AddOperator(op1), IsTrue)
+	c.Assert(op1.Token(), Not(Equals), uint64(0))
+	op2, op3 := newOp(2), newOp(3)
+	added, err := oc.AddChainedOperators(dependOn(op1), nil, op2)
+	c.Assert(err, IsNil)
+	c.Assert(added, IsTrue)
+	added, err = oc.AddChainedOperators(dependOn(op2), nil, op3)
+	c.Assert(err, IsNil)
+	c.Assert(added, IsTrue)
+	waiting := oc.GetWaitingOperators()
+	c.Assert(waiting, HasLen, 2)
+	c.Assert(waiting[0], Equals, op2)
+	c.Assert(*waiting[0].After(), Equals, dependOn(op1))
+	oc.PushOperators()
+	c.Assert(oc.GetOperator(2), IsNil)
+
+	finish(op1)
+	c.Assert(oc.GetOperator(2), NotNil)
+	c.Assert(oc.GetOperator(2).Token(), Equals, op2.Token())
+	c.Assert(oc.GetOperator(3), IsNil)
+	finish(oc.GetOperator(2))
+	c.Assert(oc.GetOperator(3), NotNil)
+	c.Assert(oc.GetWaitingOperators(), HasLen, 0)
+	finish(oc.GetOperator(3))
+	// The prerequisite has finished already.
+	op4 := newOp(3)
+	added, err = oc.AddChainedOperators(dependOn(op1), nil, op4)
+	c.Assert(err, IsNil)
+	c.Assert(added, IsTrue)
+	c.Assert(oc.GetOperator(3), Equals, op4)
+	finish(op4)
+
+	// The operators are canceled in turn as the prerequisite fails.
+	op1 = newOp(1)
+	c.Assert(oc.AddOperator(op1), IsTrue)
+	op2, op3 = newOp(2), newOp(3)
+	_, err = oc.AddChainedOperators(dependOn(op1), nil, op2)
+	c.Assert(err, IsNil)
+	_, err = oc.AddChainedOperators(dependOn(op2), nil, op3)
+	c.Assert(err, IsNil)
+	c.Assert(oc.RemoveOperator(op1), IsTrue)
+	oc.PushOperators()
+	c.Assert(op2.Status(), Equals, operator.CANCELED)
+	c.Assert(op3.Status(), Equals, operator.CANCELED)
+	c.Assert(oc.GetOperatorStatus(3).Op, Equals, op3)
+	c.Assert(oc.GetOperatorStatus(3).Status, Equals, pdpb.OperatorStatus_CANCEL)
+	c.Assert(oc.GetWaitingOperators(), HasLen, 0)
+	_, err = oc.AddChainedOperators(dependOn(op1), nil, newOp(2))
+	c.Assert(errors.Cause(err), Equals, ErrPrerequisiteFailed)
+
+	// The prerequisite should be an operator of the region.
+	op1 = newOp(1)
+	c.Assert(oc.AddOperator(op1), IsTrue)
+	_, err = oc.AddChainedOperators(operator.Dependency{RegionID: 2, Token: op1.Token()}, nil, newOp(2))
+	c.Assert(errors.Cause(err), Equals, ErrPrerequisiteNotFound)
+	_, err = oc.AddChainedOperators(operator.Dependency{RegionID: 2, Token: oc.chains.nextToken}, nil, newOp(2))
+	c.Assert(errors.Cause(err), Equals, ErrPrerequisiteNotFound)
+}
+
+func (t *testOperatorControllerSuite) TestOperatorChainSameRegion(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	stream := mockhbstream.NewHeartbeatStreams(tc.ID, true /* no need to run */)
+	oc := NewOperatorController(t.ctx, tc, stream)
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderRegion(1, 1, 2)
+	tc.AddLeaderRegion(2, 1, 2)
+	newOp := func(regionID uint64) *operator.Operator {
+		region := tc.GetRegion(regionID)
+		from := region.GetLeader().GetStoreId()
+		return operator.NewOperator("test", "test", regionID, region.GetRegionEpoch(), operator.OpAdmin|operator.OpLeader,
+			operator.TransferLeader{FromStore: from, ToStore: 3 - from})
+	}
+	dependOn := func(op *operator.Operator) operator.Dependency {
+		return operator.Dependency{RegionID: op.RegionID(), Token: op.Token()}
+	}
+	// The prerequisite changes the epoch of the region, which makes the
+	// operators built before it stale.
+	finish := func(op *operator.Operator) {
+		ApplyOperator(tc, op)
+		region := tc.GetRegion(op.RegionID())
+		tc.PutRegion(region.Clone(core.SetRegionConfVer(region.GetRegionEpoch().GetConfVer() + 1)))
+		oc.Dispatch(tc.GetRegion(op.RegionID()), DispatchFromHeartBeat)
+		c.Assert(op.Status(), Equals, operator.SUCCESS)
+		oc.PushOperators()
+	}
+
+	// The stale clone fails to be added, and the operators waiting for it are
+	// canceled in turn.
+	op1 := newOp(1)
+	c.Assert(oc.AddOperator(op1), IsTrue)
+	op2, op3 := newOp(1), newOp(2)
+	_, err := oc.AddChainedOperators(dependOn(op1), nil, op2)
+	c.Assert(err, IsNil)
+	_, err = oc.AddChainedOperators(dependOn(op2), nil, op3)
+	c.Assert(err, IsNil)
+	finish(op1)
+	c.Assert(oc.GetOperator(1), IsNil)
+	c.Assert(oc.GetOperator(2), IsNil)
+	c.Assert(op3.Status(), Equals, operator.CANCELED)
+	c.Assert(oc.GetOperatorStatus(1).Status, Equals, pdpb.OperatorStatus_CANCEL)
+	c.Assert(oc.GetOperatorStatus(2).Status, Equals, pdpb.OperatorStatus_CANCEL)
+	c.Assert(oc.GetWaitingOperators(), HasLen, 0)
+	c.Assert(oc.chains.live, HasLen, 0)
+	c.Assert(oc.chains.outcomes[op2.Token()].status, Equals, operator.CANCELED)
+
+	// The operators rebuilt from the current region are added.
+	rebuild := func() ([]*operator.Operator, error) { return []*operator.Operator{newOp(1)}, nil }
+	op1 = newOp(1)
+	c.Assert(oc.AddOperator(op1), IsTrue)
+	op2, op3 = newOp(1), newOp(2)
+	_, err = oc.AddChainedOperators(dependOn(op1), rebuild, op2)
+	c.Assert(err, IsNil)
+	_, err = oc.AddChainedOperators(dependOn(op2), nil, op3)
+	c.Assert(err, IsNil)
+	finish(op1)
+	rebuilt := oc.GetOperator(1)
+	c.Assert(rebuilt, NotNil)
+	c.Assert(rebuilt, Not(Equals), op2)
+	c.Assert(rebuilt.Token(), Equals, op2.Token())
+	c.Assert(*rebuilt.After(), Equals, dependOn(op1))
+	c.Assert(oc.GetOperator(2), IsNil)
+	finish(rebuilt)
+	c.Assert(oc.GetOperator(2), NotNil)
+	c.Assert(oc.GetOperator(2).Token(), Equals, op3.Token())
+	finish(oc.GetOperator(2))
+
+	// The operators failed to be rebuilt are canceled.
+	op1 = newOp(1)
+	c.Assert(oc.AddOperator(op1), IsTrue)
+	op2, op3 = newOp(1), newOp(2)
+	_, err = oc.AddChainedOperators(dependOn(op1), func() ([]*operator.Operator, error) {
+		return nil, errors.New("region is changed")
+	}, op2)
+	c.Assert(err, IsNil)
+	_, err = oc.AddChainedOperators(dependOn(op2), nil, op3)
+	c.Assert(err, IsNil)
+	finish(op1)
+	c.Assert(oc.GetOperator(1), IsNil)
+	c.Assert(op2.Status(), Equals, operator.CANCELED)
+	c.Assert(op3.Status(), Equals, operator.CANCELED)
+	c.Assert(oc.chains.live, HasLen, 0)
+}
@@ -0,0 +1,185 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+)
+
+const (
+	// MaxOperatorLatencyWindow is the longest window in which the operator
+	// latencies are summarized.
+	MaxOperatorLatencyWindow = time.Hour
+	// maxOperatorLatencySamples is the most samples of a kind of the latency
+	// kept for a creator, the oldest samples are dropped beyond it.
+	maxOperatorLatencySamples = 4096
+)
+
+type latencySample struct {
+	time    time.Time
+	latency time.Duration
+}
+
+// latencySamples is the samples of a kind of the latency of a creator in the
+// order of the time.
+type latencySamples []latencySample
+
+func (s latencySamples) add(sample latencySample) latencySamples {
+	s = append(s, sample)
+	if len(s) > maxOperatorLatencySamples {
+		s = append(s[:0], s[len(s)-maxOperatorLatencySamples:]...)
+	}
+	return s
+}
+
+// gc removes the samples earlier than the cutoff.
+func (s latencySamples) gc(cutoff time.Time) latencySamples {
+	i := sort.Search(len(s), func(i int) bool { return !s[i].time.Before(cutoff) })
+	return append(s[:0], s[i:]...)
+}
+
+// LatencySummary is the summary of a kind of the latency in seconds.
+type LatencySummary struct {
+	Count int     `json:"count"`
+	P50   float64 `json:"p50"`
+	P95   float64 `json:"p95"`
+}
+
+// summarize summarizes the samples since the time.
+func (s latencySamples) summarize(since time.Time) LatencySummary {
+	var latencies []time.Duration
+	for _, sample := range s {
+		if !sample.time.Before(since) {
+			latencies = append(latencies, sample.latency)
+		}
+	}
+	summary := LatencySummary{Count: len(latencies)}
+	if len(latencies) == 0 {
+		return summary
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) float64 {
+		// The nearest rank.
+		rank := int(math.Ceil(p * float64(len(latencies))))
+		return latencies[rank-1].Seconds()
+	}
+	summary.P50, summary.P95 = percentile(0.5), percentile(0.95)
+	return summary
+}
+
+// OperatorLatency is the latency summary of the operators of a creator. The
+// wait time is from creating to starting, and the execution time is from
+// starting to finishing successfully.
+type OperatorLatency struct {
+	Creator   string         `json:"creator"`
+	Wait      LatencySummary `json:"wait"`
+	Execution LatencySummary `json:"execution"`
+}
+
+// operatorLatencies aggregates the recent wait and execution time of the
+// operators by the creator in memory. It is threadsafe.
+type operatorLatencies struct {
+	sync.Mutex
+	waits      map[string]latencySamples
+	executions map[string]latencySamples
+	now        func() time.Time
+}
+
+func newOperatorLatencies() *operatorLatencies {
+	return &operatorLatencies{
+		waits:      make(map[string]latencySamples),
+		executions: make(map[string]latencySamples),
+		now:        time.Now,
+	}
+}
+
+// observeStart records the wait time of the operator which has started.
+func (l *operatorLatencies) observeStart(op *operator.Operator) {
+	wait := op.GetStartTime().Sub(op.GetCreateTime())
+	operatorCreatorWaitDuration.WithLabelValues(op.Creator()).Observe(wait.Seconds())
+	l.Lock()
+	defer l.Unlock()
+	l.waits[op.Creator()] = l.waits[op.Creator()].add(latencySample{time: l.now(), latency: wait})
+}
+
+// observeFinish records the execution time of the operator which has
+// finished successfully.
+func (l *operatorLatencies) observeFinish(op *operator.Operator) {
+	execution := op.RunningTime()
+	operatorCreatorExecutionDuration.WithLabelValues(op.Creator()).Observe(execution.Seconds())
+	l.Lock()
+	defer l.Unlock()
+	l.executions[op.Creator()] = l.executions[op.Creator()].add(latencySample{time: l.now(), latency: execution})
+}
+
+// gc removes the samples out of the longest window.
+func (l *operatorLatencies) gc() {
+	l.Lock()
+	defer l.Unlock()
+	cutoff := l.now().Add(-MaxOperatorLatencyWindow)
+	for _, samples := range []map[string]latencySamples{l.waits, l.executions} {
+		for creator, s := range samples {
+			if s = s.gc(cutoff); len(s) == 0 {
+				delete(samples, creator)
+			} else {
+				samples[creator] = s
+			}
+		}
+	}
+}
+
+// summarize summarizes the latencies in the recent window by the creator,
+// sorted by the creator.
+func (l *operatorLatencies) summarize(window time.Duration) []*OperatorLatency {
+	l.Lock()
+	defer l.Unlock()
+	since := l.now().Add(-window)
+	latencies := make(map[string]*OperatorLatency)
+	get := func(creator string) *OperatorLatency {
+		latency, ok := latencies[creator]
+		if !ok {
+			latency = &OperatorLatency{Creator: creator}
+			latencies[creator] = latency
+		}
+		return latency
+	}
+	for creator, s := range l.waits {
+		if summary := s.summarize(since); summary.Count > 0 {
+			get(creator).Wait = summary
+		}
+	}
+	for creator, s := range l.executions {
+		if summary := s.summarize(since); summary.Count > 0 {
+			get(creator).Execution = summary
+		}
+	}
+	result := make([]*OperatorLatency, 0, len(latencies))
+	for _, latency := range latencies {
+		result = append(result, latency)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Creator < result[j].Creator })
+	return result
+}
+
+// GetOperatorLatencies returns the wait and execution time of the operators
+// started or finished in the recent window by the creator.
+func (oc *OperatorController) GetOperatorLatencies(window time.Duration) []*OperatorLatency {
+	oc.latencies.gc()
+	return oc.latencies.summarize(window)
+}
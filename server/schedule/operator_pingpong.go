@@ -0,0 +1,256 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"go.uber.org/zap"
+)
+
+// The actions on the new operators for a region which has finished too many
+// operators recently.
+const (
+	// PingPongReject cancels the operators.
+	PingPongReject = "reject"
+	// PingPongDefer holds the operators and adds them once the region has
+	// finished fewer operators in the window.
+	PingPongDefer = "defer"
+)
+
+const (
+	// maxRegionFinishedOperators is the most finished operators kept for a
+	// region, the oldest ones are dropped beyond it.
+	maxRegionFinishedOperators = 256
+	// maxPingPongOffenders is the most recent offenders kept.
+	maxPingPongOffenders = 64
+)
+
+// OperatorMove is a move of the leader or a peer of a region by an operator.
+type OperatorMove struct {
+	Kind string `json:"kind"`
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// FinishedOperator is an operator finished successfully for a region.
+type FinishedOperator struct {
+	Creator    string           `json:"creator"`
+	Desc       string           `json:"desc"`
+	Reason     *operator.Reason `json:"reason,omitempty"`
+	Moves      []*OperatorMove  `json:"moves"`
+	FinishTime time.Time        `json:"finish_time"`
+}
+
+// PingPongOffender is a new operator rejected or deferred because its region
+// has finished too many operators recently.
+type PingPongOffender struct {
+	RegionID uint64    `json:"region_id"`
+	Creator  string    `json:"creator"`
+	Desc     string    `json:"desc"`
+	Action   string    `json:"action"`
+	Time     time.Time `json:"time"`
+	// Recent is the operators finished for the region in the window.
+	Recent []*FinishedOperator `json:"recent"`
+}
+
+// PingPongStatus is the recent offenders from the latest to the oldest, and
+// the operators being deferred.
+type PingPongStatus struct {
+	Offenders []*PingPongOffender  `json:"offenders"`
+	Deferred  []*operator.Operator `json:"deferred"`
+}
+
+// operatorPingPong tracks the operators finished for the regions, by which
+// the new operators for the regions operated too often are guarded. It is
+// threadsafe.
+type operatorPingPong struct {
+	sync.Mutex
+	finished  map[uint64][]*FinishedOperator
+	offenders []*PingPongOffender
+	now       func() time.Time
+}
+
+func newOperatorPingPong() *operatorPingPong {
+	return &operatorPingPong{
+		finished: make(map[uint64][]*FinishedOperator),
+		now:      time.Now,
+	}
+}
+
+// observeFinish records the operator which has finished successfully.
+func (p *operatorPingPong) observeFinish(op *operator.Operator) {
+	finished := &FinishedOperator{
+		Creator:    op.Creator(),
+		Desc:       op.Desc(),
+		Reason:     op.Reason(),
+		FinishTime: p.now(),
+	}
+	for _, h := range op.History() {
+		finished.Moves = append(finished.Moves, &OperatorMove{Kind: h.Kind.String(), From: h.From, To: h.To})
+	}
+	p.Lock()
+	defer p.Unlock()
+	ops := append(p.finished[op.RegionID()], finished)
+	if len(ops) > maxRegionFinishedOperators {
+		ops = append(ops[:0], ops[len(ops)-maxRegionFinishedOperators:]...)
+	}
+	p.finished[op.RegionID()] = ops
+}
+
+// recentLocked returns the operators finished for the region in the window.
+func (p *operatorPingPong) recentLocked(regionID uint64, window time.Duration) []*FinishedOperator {
+	ops := p.finished[regionID]
+	cutoff := p.now().Add(-window)
+	i := sort.Search(len(ops), func(i int) bool { return !ops[i].FinishTime.Before(cutoff) })
+	return ops[i:]
+}
+
+// isGuarded returns true if the operator is not an admin one and its region
+// has finished at least limit operators in the window.
+func (p *operatorPingPong) isGuarded(op *operator.Operator, limit uint64, window time.Duration) bool {
+	if limit == 0 || op.Kind()&operator.OpAdmin != 0 {
+		return false
+	}
+	p.Lock()
+	defer p.Unlock()
+	return uint64(len(p.recentLocked(op.RegionID(), window))) >= limit
+}
+
+// recordOffender records the operator which is rejected or deferred.
+func (p *operatorPingPong) recordOffender(op *operator.Operator, action string, window time.Duration) {
+	operatorPingPongCounter.WithLabelValues(op.Desc(), action).Inc()
+	p.Lock()
+	defer p.Unlock()
+	recent := p.recentLocked(op.RegionID(), window)
+	offender := &PingPongOffender{
+		RegionID: op.RegionID(),
+		Creator:  op.Creator(),
+		Desc:     op.Desc(),
+		Action:   action,
+		Time:     p.now(),
+		Recent:   append([]*FinishedOperator(nil), recent...),
+	}
+	p.offenders = append(p.offenders, offender)
+	if len(p.offenders) > maxPingPongOffenders {
+		p.offenders = append(p.offenders[:0], p.offenders[len(p.offenders)-maxPingPongOffenders:]...)
+	}
+	log.Info("operator is guarded for the region ping-pong",
+		zap.Uint64("region-id", op.RegionID()),
+		zap.String("action", action),
+		zap.Int("recent-operators", len(recent)),
+		zap.Reflect("operator", op))
+}
+
+// getOffenders returns the recent offenders from the latest to the oldest.
+func (p *operatorPingPong) getOffenders() []*PingPongOffender {
+	p.Lock()
+	defer p.Unlock()
+	offenders := make([]*PingPongOffender, 0, len(p.offenders))
+	for i := len(p.offenders) - 1; i >= 0; i-- {
+		offenders = append(offenders, p.offenders[i])
+	}
+	return offenders
+}
+
+// gc removes the finished operators out of the window.
+func (p *operatorPingPong) gc(window time.Duration) {
+	p.Lock()
+	defer p.Unlock()
+	for regionID := range p.finished {
+		if ops := p.recentLocked(regionID, window); len(ops) == 0 {
+			delete(p.finished, regionID)
+		} else {
+			p.finished[regionID] = append(p.finished[regionID][:0], ops...)
+		}
+	}
+}
+
+// checkPingPong returns true if the operators are rejected or deferred
+// because their region has finished too many operators recently. The
+// deferred operators are canceled, and their clones are added later.
+func (oc *OperatorController) checkPingPong(ops ...*operator.Operator) bool {
+	limit, window := oc.cluster.GetOperatorPingPongLimit(), oc.cluster.GetOperatorPingPongWindow()
+	for _, op := range ops {
+		if !oc.pingpong.isGuarded(op, limit, window) {
+			continue
+		}
+		action := oc.cluster.GetOperatorPingPongAction()
+		oc.pingpong.recordOffender(op, action, window)
+		if action == PingPongDefer {
+			clones := make([]*operator.Operator, 0, len(ops))
+			for _, op := range ops {
+				clones = append(clones, op.Clone())
+			}
+			if old, ok := oc.deferredOps[ops[0].RegionID()]; ok {
+				for _, op := range old {
+					oc.trace(op, "deferred operator %s is replaced", op)
+				}
+			}
+			oc.deferredOps[ops[0].RegionID()] = clones
+		}
+		for _, op := range ops {
+			operatorCounter.WithLabelValues(op.Desc(), action).Inc()
+			oc.trace(op, "operator %s is canceled by the ping-pong guard with the action %s", op, action)
+			_ = op.Cancel()
+			oc.buryOperator(op)
+		}
+		return true
+	}
+	return false
+}
+
+// promoteDeferredOperators adds the deferred operators whose regions have
+// finished fewer operators in the window.
+func (oc *OperatorController) promoteDeferredOperators() {
+	limit, window := oc.cluster.GetOperatorPingPongLimit(), oc.cluster.GetOperatorPingPongWindow()
+	oc.Lock()
+	var ready [][]*operator.Operator
+	for regionID, ops := range oc.deferredOps {
+		guarded := false
+		for _, op := range ops {
+			guarded = guarded || oc.pingpong.isGuarded(op, limit, window)
+		}
+		if !guarded {
+			ready = append(ready, ops)
+			delete(oc.deferredOps, regionID)
+		}
+	}
+	oc.Unlock()
+	for _, ops := range ready {
+		clones := make([]*operator.Operator, 0, len(ops))
+		for _, op := range ops {
+			clones = append(clones, op.Clone())
+		}
+		oc.AddOperator(clones...)
+	}
+}
+
+// GetPingPongStatus returns the recent offenders of the ping-pong guard and
+// the operators being deferred.
+func (oc *OperatorController) GetPingPongStatus() *PingPongStatus {
+	status := &PingPongStatus{Offenders: oc.pingpong.getOffenders()}
+	oc.RLock()
+	defer oc.RUnlock()
+	status.Deferred = make([]*operator.Operator, 0, len(oc.deferredOps))
+	for _, ops := range oc.deferredOps {
+		status.Deferred = append(status.Deferred, ops...)
+	}
+	sort.Slice(status.Deferred, func(i, j int) bool { return status.Deferred[i].RegionID() < status.Deferred[j].RegionID() })
+	return status
+}
@@ -59,3 +59,15 @@ func IsRegionReplicated(cluster Cluster, region *core.RegionInfo) bool {
 func ReplicatedRegion(cluster Cluster) func(*core.RegionInfo) bool {
 	return func(region *core.RegionInfo) bool { return IsRegionReplicated(cluster, region) }
 }
+
+// UnlockedRegion returns a function that checks if a region is not protected
+// by any schedule lock.
+func UnlockedRegion(cluster Cluster) func(*core.RegionInfo) bool {
+	return func(region *core.RegionInfo) bool { return !cluster.IsRegionScheduleLocked(region) }
+}
+
+// UnpinnedRegion returns a function that checks if the leader of a region is
+// not pinned.
+func UnpinnedRegion(cluster Cluster) func(*core.RegionInfo) bool {
+	return func(region *core.RegionInfo) bool { return !cluster.IsRegionLeaderPinned(region.GetID()) }
+}
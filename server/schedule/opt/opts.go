@@ -49,10 +49,18 @@ type Options interface {
 	IsPlacementRulesEnabled() bool
 
 	GetHotRegionCacheHitsThreshold() int
+	GetHotWriteThresholds() (byteRate, keyRate float64)
+	GetHotReadThresholds() (byteRate, keyRate float64)
 	GetTolerantSizeRatio() float64
 	GetLowSpaceRatio() float64
 	GetHighSpaceRatio() float64
 	GetSchedulerMaxWaitingOperator() uint64
+	GetOperatorPingPongLimit() uint64
+	GetOperatorPingPongWindow() time.Duration
+	GetOperatorPingPongAction() string
+	GetScatterGroupTTL() time.Duration
+	GetScatterStoreSelectionLimit() uint64
+	IsScatterBusyStoresIncluded() bool
 
 	IsRemoveDownReplicaEnabled() bool
 	IsReplaceOfflineReplicaEnabled() bool
@@ -87,6 +95,10 @@ type Cluster interface {
 
 	AllocID() (uint64, error)
 	FitRegion(*core.RegionInfo) *placement.RegionFit
+	IsRegionScheduleLocked(*core.RegionInfo) bool
+	IsRegionLeaderPinned(regionID uint64) bool
+	GetRegionTracer() *core.RegionTracer
+	GetRegionAnnotations() *core.RegionAnnotations
 }
 
 // HeartbeatStream is an interface.
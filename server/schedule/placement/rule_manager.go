@@ -57,13 +57,7 @@ func (m *RuleManager) Initialize(maxReplica int, locationLabels []string) error
 	}
 	if len(m.rules) == 0 {
 		// migrate from old config.
-		defaultRule := &Rule{
-			GroupID:        "pd",
-			ID:             "default",
-			Role:           Voter,
-			Count:          maxReplica,
-			LocationLabels: locationLabels,
-		}
+		defaultRule := newDefaultRule(maxReplica, locationLabels)
 		if err := m.store.SaveRule(defaultRule.StoreKey(), defaultRule); err != nil {
 			return err
 		}
@@ -74,6 +68,50 @@ func (m *RuleManager) Initialize(maxReplica int, locationLabels []string) error
 	return nil
 }
 
+func newDefaultRule(maxReplica int, locationLabels []string) *Rule {
+	return &Rule{
+		GroupID:        "pd",
+		ID:             "default",
+		Role:           Voter,
+		Count:          maxReplica,
+		LocationLabels: locationLabels,
+	}
+}
+
+// PreviewRules returns the sorted rules which are applied after initializing,
+// without loading them or saving the default rule.
+func (m *RuleManager) PreviewRules(maxReplica int, locationLabels []string) ([]*Rule, error) {
+	if m.isInitialized() {
+		return m.GetAllRules(), nil
+	}
+	var rules []*Rule
+	_, err := m.store.LoadRules(func(k, v string) {
+		var r Rule
+		if err := json.Unmarshal([]byte(v), &r); err != nil {
+			return
+		}
+		// The bad rules are deleted when loading.
+		if err := m.adjustRule(&r); err != nil {
+			return
+		}
+		rules = append(rules, &r)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		rules = append(rules, newDefaultRule(maxReplica, locationLabels))
+	}
+	sortRules(rules)
+	return rules, nil
+}
+
+func (m *RuleManager) isInitialized() bool {
+	m.RLock()
+	defer m.RUnlock()
+	return m.initialized
+}
+
 func (m *RuleManager) loadRules() error {
 	var toSave []*Rule
 	var toDelete []string
@@ -256,3 +294,26 @@ func (m *RuleManager) FitRegion(stores core.StoreSetInformer, region *core.Regio
 	rules := m.GetRulesForApplyRegion(region)
 	return FitRegion(stores, region, rules)
 }
+
+// RuleSet is an immutable set of rules to fit the regions, which is used to
+// evaluate the rules before applying them.
+type RuleSet struct {
+	ruleList ruleList
+}
+
+// NewRuleSet creates a RuleSet instance.
+func NewRuleSet(rules []*Rule) *RuleSet {
+	m := make(map[[2]string]*Rule, len(rules))
+	for _, r := range rules {
+		m[r.Key()] = r
+	}
+	return &RuleSet{ruleList: buildRuleList(m)}
+}
+
+// FitRegion fits a region to the rules it matches.
+func (s *RuleSet) FitRegion(stores core.StoreSetInformer, region *core.RegionInfo) *RegionFit {
+	if len(s.ruleList.ranges) == 0 {
+		return FitRegion(stores, region, nil)
+	}
+	return FitRegion(stores, region, s.ruleList.getRulesForApplyRegion(region.GetStartKey(), region.GetEndKey()))
+}
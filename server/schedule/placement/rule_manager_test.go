@@ -88,6 +88,31 @@ func (s *testManagerSuite) TestSaveLoad(c *C) {
 	c.Assert(m2.GetRule("foo", "baz"), DeepEquals, rules[2])
 }
 
+func (s *testManagerSuite) TestPreviewRules(c *C) {
+	// The default rule is previewed if no rule is saved.
+	m := NewRuleManager(core.NewStorage(kv.NewMemoryKV()))
+	rules, err := m.PreviewRules(3, []string{"zone"})
+	c.Assert(err, IsNil)
+	c.Assert(rules, DeepEquals, []*Rule{newDefaultRule(3, []string{"zone"})})
+	c.Assert(m.GetAllRules(), HasLen, 0)
+	var saved int
+	_, err = m.store.LoadRules(func(k, v string) { saved++ })
+	c.Assert(err, IsNil)
+	c.Assert(saved, Equals, 0)
+
+	// The saved rules are previewed.
+	c.Assert(s.manager.SetRule(&Rule{GroupID: "foo", ID: "bar", Role: "learner", Count: 1}), IsNil)
+	m2 := NewRuleManager(s.store)
+	rules, err = m2.PreviewRules(5, nil)
+	c.Assert(err, IsNil)
+	c.Assert(rules, HasLen, 2)
+	c.Assert(rules[0].ID, Equals, "bar")
+	c.Assert(rules[1].Count, Equals, 3)
+	rules, err = s.manager.PreviewRules(5, nil)
+	c.Assert(err, IsNil)
+	c.Assert(rules, DeepEquals, s.manager.GetAllRules())
+}
+
 func (s *testManagerSuite) TestKeys(c *C) {
 	s.manager.DeleteRule("pd", "default")
 	rules := []*Rule{
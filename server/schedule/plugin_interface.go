@@ -22,6 +22,11 @@ import (
 	"go.uber.org/zap"
 )
 
+// PluginLoader is used to look up the symbols of plugins.
+type PluginLoader interface {
+	GetFunction(path string, funcName string) (plugin.Symbol, error)
+}
+
 // PluginInterface is used to manage all plugin.
 type PluginInterface struct {
 	pluginMap     map[string]*plugin.Plugin
@@ -15,10 +15,13 @@ package schedule
 
 import (
 	"math/rand"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/pkg/typeutil"
 	"github.com/pingcap/pd/v4/server/core"
 	"github.com/pingcap/pd/v4/server/schedule/filter"
 	"github.com/pingcap/pd/v4/server/schedule/operator"
@@ -29,49 +32,176 @@ import (
 
 const regionScatterName = "region-scatter"
 
+// ErrScatterStoreLimitExceeded is returned if the operator to scatter a region
+// exceeds the store limits.
+var ErrScatterStoreLimitExceeded = errors.New("scatter operator exceeds the store limits")
+
+// DefaultScatterGroup is the scatter group of the regions scattered without a
+// group.
+const DefaultScatterGroup = "default"
+
+// selectedStores counts the times the stores are selected in a scatter group,
+// and the leaders placed on the stores.
 type selectedStores struct {
-	mu     sync.Mutex
-	stores map[uint64]struct{}
+	mu      sync.Mutex
+	stores  map[uint64]uint64
+	leaders map[uint64]uint64
+	// scattered is the number of the regions scattered in the group.
+	scattered  uint64
+	createTime time.Time
+	lastUsed   time.Time
 }
 
-func newSelectedStores() *selectedStores {
+func newSelectedStores(now time.Time) *selectedStores {
 	return &selectedStores{
-		stores: make(map[uint64]struct{}),
+		stores:     make(map[uint64]uint64),
+		leaders:    make(map[uint64]uint64),
+		createTime: now,
+		lastUsed:   now,
 	}
 }
 
-func (s *selectedStores) put(id uint64) bool {
+// put selects the store if it is selected fewer than limit times.
+func (s *selectedStores) put(id uint64, limit uint64) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.stores[id]; ok {
+	if s.stores[id] >= limit {
 		return false
 	}
-	s.stores[id] = struct{}{}
+	s.stores[id]++
 	return true
 }
 
+// selectLeader selects the voter store of the target peers with the fewest
+// leaders in the group, breaking the ties randomly. It returns 0 if there is
+// no voter. Like the stores, the leader is counted even if the region is in
+// place already.
+func (s *selectedStores) selectLeader(targetPeers map[uint64]*metapb.Peer) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var candidates []uint64
+	var min uint64
+	for id, peer := range targetPeers {
+		if peer.GetIsLearner() {
+			continue
+		}
+		count := s.leaders[id]
+		if len(candidates) == 0 || count < min {
+			candidates, min = []uint64{id}, count
+		} else if count == min {
+			candidates = append(candidates, id)
+		}
+	}
+	if len(candidates) == 0 {
+		return 0
+	}
+	leader := candidates[rand.Intn(len(candidates))]
+	s.leaders[leader]++
+	return leader
+}
+
+// reset clears the selections of the stores. The leaders are still counted.
 func (s *selectedStores) reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.stores = make(map[uint64]struct{})
+	s.stores = make(map[uint64]uint64)
+}
+
+// clone copies the selected stores and leaders, on which the stores for a
+// region are selected before the selection is recorded.
+func (s *selectedStores) clone() *selectedStores {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cloned := newSelectedStores(s.createTime)
+	for id, count := range s.stores {
+		cloned.stores[id] = count
+	}
+	for id, count := range s.leaders {
+		cloned.leaders[id] = count
+	}
+	return cloned
+}
+
+// selection is the stores and the leader selected for a region.
+type selection struct {
+	// reset is true if the selected stores are reset for the region, and the
+	// stores are the ones selected after the reset.
+	reset  bool
+	stores []uint64
+	leader uint64
+}
+
+// record counts the region scattered with the selection.
+func (s *selectedStores) record(sel *selection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scattered++
+	if sel.reset {
+		s.stores = make(map[uint64]uint64)
+	}
+	for _, id := range sel.stores {
+		s.stores[id]++
+	}
+	if sel.leader != 0 {
+		s.leaders[sel.leader]++
+	}
 }
 
-func (s *selectedStores) newFilter(scope string) filter.Filter {
+// newFilter excludes the stores selected limit times.
+func (s *selectedStores) newFilter(scope string, limit uint64) filter.Filter {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	cloned := make(map[uint64]struct{})
-	for id := range s.stores {
-		cloned[id] = struct{}{}
+	for id, count := range s.stores {
+		if count >= limit {
+			cloned[id] = struct{}{}
+		}
 	}
 	return filter.NewExcludedFilter(scope, nil, cloned)
 }
 
-// RegionScatterer scatters regions.
+// ScatterGroupStatus is the bookkeeping of a scatter group.
+type ScatterGroupStatus struct {
+	Group            string            `json:"group"`
+	RegionsScattered uint64            `json:"regions-scattered"`
+	StoreCounts      map[uint64]uint64 `json:"store-counts"`
+	LeaderCounts     map[uint64]uint64 `json:"leader-counts"`
+	Age              typeutil.Duration `json:"age"`
+	Idle             typeutil.Duration `json:"idle"`
+}
+
+func (s *selectedStores) status(group string, now time.Time) *ScatterGroupStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[uint64]uint64, len(s.stores))
+	for id, count := range s.stores {
+		counts[id] = count
+	}
+	leaderCounts := make(map[uint64]uint64, len(s.leaders))
+	for id, count := range s.leaders {
+		leaderCounts[id] = count
+	}
+	return &ScatterGroupStatus{
+		Group:            group,
+		RegionsScattered: s.scattered,
+		StoreCounts:      counts,
+		LeaderCounts:     leaderCounts,
+		Age:              typeutil.NewDuration(now.Sub(s.createTime)),
+		Idle:             typeutil.NewDuration(now.Sub(s.lastUsed)),
+	}
+}
+
+// RegionScatterer scatters regions. The stores selected and the leaders are
+// counted by the scatter groups, so that the regions of a group are spread
+// evenly regardless of the other groups. A group idle for the scatter-group-ttl is removed.
 type RegionScatterer struct {
-	name     string
-	cluster  opt.Cluster
-	filters  []filter.Filter
-	selected *selectedStores
+	name    string
+	cluster opt.Cluster
+	filters []filter.Filter
+
+	mu     sync.Mutex
+	groups map[string]*selectedStores
+	now    func() time.Time
 }
 
 // NewRegionScatterer creates a region scatterer.
@@ -83,12 +213,86 @@ func NewRegionScatterer(cluster opt.Cluster) *RegionScatterer {
 		filters: []filter.Filter{
 			filter.StoreStateFilter{ActionScope: regionScatterName},
 		},
-		selected: newSelectedStores(),
+		groups: make(map[string]*selectedStores),
+		now:    time.Now,
 	}
 }
 
-// Scatter relocates the region.
-func (r *RegionScatterer) Scatter(region *core.RegionInfo) (*operator.Operator, error) {
+// getGroup returns the selected stores of the group, which is created if it
+// does not exist. The idle groups are removed.
+func (r *RegionScatterer) getGroup(group string) *selectedStores {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	r.gcLocked(now)
+	selected, ok := r.groups[group]
+	if !ok {
+		selected = newSelectedStores(now)
+		r.groups[group] = selected
+	}
+	selected.mu.Lock()
+	selected.lastUsed = now
+	selected.mu.Unlock()
+	return selected
+}
+
+func (r *RegionScatterer) gcLocked(now time.Time) {
+	ttl := r.cluster.GetScatterGroupTTL()
+	for group, selected := range r.groups {
+		selected.mu.Lock()
+		idle := now.Sub(selected.lastUsed)
+		selected.mu.Unlock()
+		if idle > ttl {
+			log.Info("scatter group is removed as it is idle", zap.String("group", group), zap.Duration("idle", idle))
+			delete(r.groups, group)
+		}
+	}
+}
+
+// GetGroupStatus returns the bookkeeping of the scatter groups, sorted by the
+// group name. The idle groups are removed.
+func (r *RegionScatterer) GetGroupStatus() []*ScatterGroupStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	r.gcLocked(now)
+	status := make([]*ScatterGroupStatus, 0, len(r.groups))
+	for group, selected := range r.groups {
+		status = append(status, selected.status(group, now))
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Group < status[j].Group })
+	return status
+}
+
+// ResetGroup removes the bookkeeping of the scatter group. It returns false if
+// the group does not exist.
+func (r *RegionScatterer) ResetGroup(group string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.groups[group]; !ok {
+		return false
+	}
+	delete(r.groups, group)
+	log.Info("scatter group is reset", zap.String("group", group))
+	return true
+}
+
+// Scatter relocates the region. The stores are selected evenly among the
+// regions of the same group, which is DefaultScatterGroup if it is empty.
+func (r *RegionScatterer) Scatter(region *core.RegionInfo, group string) (*operator.Operator, error) {
+	return r.scatter(region, group, true, nil)
+}
+
+// ScatterWithStoreLimit is like Scatter, but the peers added by the operator
+// take the tokens of the store limits, as the region may have lots of data to
+// be sent by the snapshots. The operator is checked by withinLimit, and
+// ErrScatterStoreLimitExceeded is returned if it exceeds the store limits.
+// The stores selected for a rejected operator are not recorded in the group.
+func (r *RegionScatterer) ScatterWithStoreLimit(region *core.RegionInfo, group string, withinLimit func(*operator.Operator) bool) (*operator.Operator, error) {
+	return r.scatter(region, group, false, withinLimit)
+}
+
+func (r *RegionScatterer) scatter(region *core.RegionInfo, group string, lightWeight bool, withinLimit func(*operator.Operator) bool) (*operator.Operator, error) {
 	if !opt.IsRegionReplicated(r.cluster, region) {
 		return nil, errors.Errorf("region %d is not fully replicated", region.GetID())
 	}
@@ -97,43 +301,71 @@ func (r *RegionScatterer) Scatter(region *core.RegionInfo) (*operator.Operator,
 		return nil, errors.Errorf("region %d has no leader", region.GetID())
 	}
 
-	return r.scatterRegion(region), nil
+	if group == "" {
+		group = DefaultScatterGroup
+	}
+	selected := r.getGroup(group)
+	op, sel := r.scatterRegion(region, selected, lightWeight)
+	if op != nil && withinLimit != nil && !withinLimit(op) {
+		return nil, ErrScatterStoreLimitExceeded
+	}
+	selected.record(sel)
+	return op, nil
 }
 
-func (r *RegionScatterer) scatterRegion(region *core.RegionInfo) *operator.Operator {
-	stores := r.collectAvailableStores(region)
+// scatterRegion selects the stores on a copy of the selected stores of the
+// group, and returns the operator and the selection to be recorded.
+func (r *RegionScatterer) scatterRegion(region *core.RegionInfo, selected *selectedStores, lightWeight bool) (*operator.Operator, *selection) {
+	tentative := selected.clone()
+	sel := &selection{}
+	limit := r.cluster.GetScatterStoreSelectionLimit()
+	stores := r.collectAvailableStores(region, tentative, limit)
 	targetPeers := make(map[uint64]*metapb.Peer)
+	// scattered records the placement chosen so far, so that every candidate
+	// is checked against the peers already selected rather than the original
+	// ones. Otherwise two replacements can land in the same isolation domain.
+	scattered := region
 	for _, peer := range region.GetPeers() {
 		if len(stores) == 0 {
 			// Reset selected stores if we have no available stores.
-			r.selected.reset()
-			stores = r.collectAvailableStores(region)
+			tentative.reset()
+			sel.reset, sel.stores = true, nil
+			stores = r.collectAvailableStores(region, tentative, limit)
 		}
 
-		if r.selected.put(peer.GetStoreId()) {
+		if tentative.put(peer.GetStoreId(), limit) {
+			sel.stores = append(sel.stores, peer.GetStoreId())
 			delete(stores, peer.GetStoreId())
 			targetPeers[peer.GetStoreId()] = peer
 			continue
 		}
-		newPeer := r.selectPeerToReplace(stores, region, peer)
+		newPeer := r.selectPeerToReplace(stores, scattered, peer)
 		if newPeer == nil {
 			targetPeers[peer.GetStoreId()] = peer
 			continue
 		}
 		// Remove it from stores and mark it as selected.
 		delete(stores, newPeer.GetStoreId())
-		r.selected.put(newPeer.GetStoreId())
+		if tentative.put(newPeer.GetStoreId(), limit) {
+			sel.stores = append(sel.stores, newPeer.GetStoreId())
+		}
 		targetPeers[newPeer.GetStoreId()] = newPeer
+		scattered = scattered.Clone(core.WithReplacePeerStore(peer.GetStoreId(), newPeer.GetStoreId()))
 	}
-	op, err := operator.CreateScatterRegionOperator("scatter-region", r.cluster, region, targetPeers)
+	sel.leader = tentative.selectLeader(targetPeers)
+	op, err := operator.CreateScatterRegionOperator("scatter-region", r.cluster, region, targetPeers, sel.leader, lightWeight)
 	if err != nil {
 		log.Debug("fail to create scatter region operator", zap.Error(err))
-		return nil
+		return nil, sel
 	}
 	op.SetPriorityLevel(core.HighPriority)
-	return op
+	return op, sel
 }
 
+// selectPeerToReplace picks a store to hold the replacement of oldPeer. The
+// region passed in should reflect the replacements made so far. Candidates that
+// would break the placement rules or decrease the isolation level are never
+// chosen, even if they are the only way to even out the peer counts.
 func (r *RegionScatterer) selectPeerToReplace(stores map[uint64]*core.StoreInfo, region *core.RegionInfo, oldPeer *metapb.Peer) *metapb.Peer {
 	// scoreGuard guarantees that the distinct score will not decrease.
 	regionStores := r.cluster.GetRegionStores(region)
@@ -168,17 +400,18 @@ func (r *RegionScatterer) selectPeerToReplace(stores map[uint64]*core.StoreInfo,
 	}
 }
 
-func (r *RegionScatterer) collectAvailableStores(region *core.RegionInfo) map[uint64]*core.StoreInfo {
+func (r *RegionScatterer) collectAvailableStores(region *core.RegionInfo, selected *selectedStores, limit uint64) map[uint64]*core.StoreInfo {
 	filters := []filter.Filter{
-		r.selected.newFilter(r.name),
+		selected.newFilter(r.name, limit),
 		filter.NewExcludedFilter(r.name, nil, region.GetStoreIds()),
 	}
 	filters = append(filters, r.filters...)
 
+	includeBusy := r.cluster.IsScatterBusyStoresIncluded()
 	stores := r.cluster.GetStores()
 	targets := make(map[uint64]*core.StoreInfo, len(stores))
 	for _, store := range stores {
-		if filter.Target(r.cluster, store, filters) && !store.IsBusy() {
+		if filter.Target(r.cluster, store, filters) && (includeBusy || !store.IsBusy()) {
 			targets[store.GetID()] = store
 		}
 	}
@@ -0,0 +1,226 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/pd/v4/pkg/mock/mockcluster"
+	"github.com/pingcap/pd/v4/pkg/mock/mockoption"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+)
+
+var _ = Suite(&testScatterGroupSuite{})
+
+type testScatterGroupSuite struct{}
+
+func (s *testScatterGroupSuite) TestGroupStatus(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	for id := uint64(1); id <= 6; id++ {
+		tc.AddRegionStore(id, 0)
+	}
+	for id := uint64(1); id <= 4; id++ {
+		tc.AddLeaderRegion(id, 1, 2, 3)
+	}
+	scatterer := NewRegionScatterer(tc)
+	scatter := func(regionID uint64, group string) {
+		op, err := scatterer.Scatter(tc.GetRegion(regionID), group)
+		c.Assert(err, IsNil)
+		if op != nil {
+			ApplyOperator(tc, op)
+		}
+	}
+	sum := func(counts map[uint64]uint64) uint64 {
+		var sum uint64
+		for _, count := range counts {
+			sum += count
+		}
+		return sum
+	}
+
+	// Region 1 keeps its stores, and region 2 is moved to the others.
+	scatter(1, "a")
+	status := scatterer.GetGroupStatus()
+	c.Assert(status, HasLen, 1)
+	c.Assert(status[0].Group, Equals, "a")
+	c.Assert(status[0].RegionsScattered, Equals, uint64(1))
+	c.Assert(status[0].StoreCounts, DeepEquals, map[uint64]uint64{1: 1, 2: 1, 3: 1})
+	scatter(2, "a")
+	status = scatterer.GetGroupStatus()
+	c.Assert(status[0].RegionsScattered, Equals, uint64(2))
+	c.Assert(status[0].StoreCounts, DeepEquals, map[uint64]uint64{1: 1, 2: 1, 3: 1, 4: 1, 5: 1, 6: 1})
+
+	// The groups are counted separately.
+	scatter(3, "")
+	status = scatterer.GetGroupStatus()
+	c.Assert(status, HasLen, 2)
+	c.Assert(status[0].Group, Equals, "a")
+	c.Assert(status[1].Group, Equals, DefaultScatterGroup)
+	c.Assert(status[1].StoreCounts, DeepEquals, map[uint64]uint64{1: 1, 2: 1, 3: 1})
+
+	// A store can be selected twice before the selections are reset.
+	opt.ScatterStoreSelectionLimit = 2
+	scatter(4, "a")
+	status = scatterer.GetGroupStatus()
+	c.Assert(status[0].RegionsScattered, Equals, uint64(3))
+	c.Assert(sum(status[0].StoreCounts), Equals, uint64(9))
+
+	c.Assert(scatterer.ResetGroup("a"), IsTrue)
+	c.Assert(scatterer.ResetGroup("a"), IsFalse)
+	status = scatterer.GetGroupStatus()
+	c.Assert(status, HasLen, 1)
+	c.Assert(status[0].Group, Equals, DefaultScatterGroup)
+}
+
+func (s *testScatterGroupSuite) TestLeaderDistribution(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	for id := uint64(1); id <= 3; id++ {
+		tc.AddRegionStore(id, 0)
+	}
+	// The leaders of the regions in both groups are on store 1.
+	for id := uint64(1); id <= 12; id++ {
+		tc.AddLeaderRegion(id, 1, 2, 3)
+	}
+	scatterer := NewRegionScatterer(tc)
+	for id := uint64(1); id <= 12; id++ {
+		group := "a"
+		if id%2 == 0 {
+			group = "b"
+		}
+		op, err := scatterer.Scatter(tc.GetRegion(id), group)
+		c.Assert(err, IsNil)
+		if op != nil {
+			ApplyOperator(tc, op)
+		}
+	}
+
+	// The leaders are spread evenly in each group.
+	status := scatterer.GetGroupStatus()
+	c.Assert(status, HasLen, 2)
+	for _, group := range status {
+		c.Assert(group.LeaderCounts, DeepEquals, map[uint64]uint64{1: 2, 2: 2, 3: 2}, Commentf("group %s", group.Group))
+	}
+	leaders := make(map[uint64]int)
+	for id := uint64(1); id <= 12; id++ {
+		leaders[tc.GetRegion(id).GetLeader().GetStoreId()]++
+	}
+	c.Assert(leaders, DeepEquals, map[uint64]int{1: 4, 2: 4, 3: 4})
+}
+
+func (s *testScatterGroupSuite) TestScatterWithStoreLimit(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	for id := uint64(1); id <= 6; id++ {
+		tc.AddRegionStore(id, 0)
+	}
+	tc.AddLeaderRegion(1, 1, 2, 3)
+	tc.AddLeaderRegion(2, 1, 2, 3)
+	scatterer := NewRegionScatterer(tc)
+	countSteps := func(op *operator.Operator) (light, normal int) {
+		for i := 0; i < op.Len(); i++ {
+			switch op.Step(i).(type) {
+			case operator.AddLightPeer, operator.AddLightLearner:
+				light++
+			case operator.AddPeer, operator.AddLearner:
+				normal++
+			}
+		}
+		return
+	}
+
+	// Region 2 is moved to the stores 4, 5 and 6 in both groups.
+	for _, group := range []string{"a", "b"} {
+		_, err := scatterer.Scatter(tc.GetRegion(1), group)
+		c.Assert(err, IsNil)
+	}
+	op, err := scatterer.Scatter(tc.GetRegion(2), "a")
+	c.Assert(err, IsNil)
+	light, normal := countSteps(op)
+	c.Assert(light, Equals, 3)
+	c.Assert(normal, Equals, 0)
+	groupB := func() *ScatterGroupStatus {
+		for _, status := range scatterer.GetGroupStatus() {
+			if status.Group == "b" {
+				return status
+			}
+		}
+		return nil
+	}
+	before := groupB()
+
+	// The selection for the operator exceeding the store limits is not
+	// recorded, so the region is scattered in the same way later.
+	var rejected *operator.Operator
+	op, err = scatterer.ScatterWithStoreLimit(tc.GetRegion(2), "b", func(op *operator.Operator) bool {
+		rejected = op
+		return false
+	})
+	c.Assert(err, Equals, ErrScatterStoreLimitExceeded)
+	c.Assert(op, IsNil)
+	c.Assert(rejected, NotNil)
+	after := groupB()
+	c.Assert(after.RegionsScattered, Equals, before.RegionsScattered)
+	c.Assert(after.StoreCounts, DeepEquals, before.StoreCounts)
+	c.Assert(after.LeaderCounts, DeepEquals, before.LeaderCounts)
+
+	op, err = scatterer.ScatterWithStoreLimit(tc.GetRegion(2), "b", func(*operator.Operator) bool { return true })
+	c.Assert(err, IsNil)
+	light, normal = countSteps(op)
+	c.Assert(light, Equals, 0)
+	c.Assert(normal, Equals, 3)
+	c.Assert(groupB().RegionsScattered, Equals, before.RegionsScattered+1)
+	for id := uint64(4); id <= 6; id++ {
+		c.Assert(groupB().StoreCounts[id], Equals, uint64(1))
+	}
+}
+
+func (s *testScatterGroupSuite) TestGroupTTL(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	for id := uint64(1); id <= 3; id++ {
+		tc.AddRegionStore(id, 0)
+	}
+	tc.AddLeaderRegion(1, 1, 2, 3)
+	scatterer := NewRegionScatterer(tc)
+	now := time.Now()
+	scatterer.now = func() time.Time { return now }
+	groups := func() []string {
+		var groups []string
+		for _, status := range scatterer.GetGroupStatus() {
+			groups = append(groups, status.Group)
+		}
+		return groups
+	}
+
+	opt.ScatterGroupTTL = 10 * time.Minute
+	_, err := scatterer.Scatter(tc.GetRegion(1), "a")
+	c.Assert(err, IsNil)
+	now = now.Add(5 * time.Minute)
+	_, err = scatterer.Scatter(tc.GetRegion(1), "b")
+	c.Assert(err, IsNil)
+	status := scatterer.GetGroupStatus()
+	c.Assert(status[0].Age.Duration, Equals, 5*time.Minute)
+	c.Assert(status[0].Idle.Duration, Equals, 5*time.Minute)
+	c.Assert(status[1].Idle.Duration, Equals, time.Duration(0))
+
+	// Group a is idle for more than the TTL.
+	now = now.Add(6 * time.Minute)
+	c.Assert(groups(), DeepEquals, []string{"b"})
+	// The TTL shortened at runtime removes group b too.
+	opt.ScatterGroupTTL = 5 * time.Minute
+	c.Assert(groups(), HasLen, 0)
+}
@@ -40,6 +40,30 @@ type Scheduler interface {
 	Cleanup(cluster opt.Cluster)
 	Schedule(cluster opt.Cluster) []*operator.Operator
 	IsScheduleAllowed(cluster opt.Cluster) bool
+	// GetEffectiveConfig returns the config items the scheduler uses at
+	// schedule time, merged from the cluster config and its own config.
+	GetEffectiveConfig(cluster opt.Cluster) []*EffectiveConfigItem
+}
+
+// The sources of the effective config items of a scheduler.
+const (
+	// ConfigSourceCluster is the schedule config of the cluster.
+	ConfigSourceCluster = "cluster-config"
+	// ConfigSourceScheduler is the persisted config of the scheduler.
+	ConfigSourceScheduler = "scheduler-config"
+	// ConfigSourceDefault is the default of the scheduler which is not
+	// overridden by its config.
+	ConfigSourceDefault = "default"
+)
+
+// EffectiveConfigItem is a config item a scheduler uses at schedule time.
+type EffectiveConfigItem struct {
+	Name   string      `json:"name"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+	// ShadowedBy is the name of the item which takes the place of this item,
+	// so that this item does not govern the schedules.
+	ShadowedBy string `json:"shadowed-by,omitempty"`
 }
 
 // EncodeConfig encode the custom config for each scheduler.
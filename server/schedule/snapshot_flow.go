@@ -0,0 +1,127 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"github.com/pingcap/pd/v4/pkg/typeutil"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+)
+
+// SnapshotFlow records the snapshots being transferred from one store to another.
+type SnapshotFlow struct {
+	RegionCount   int               `json:"region_count"`
+	EstimatedSize typeutil.ByteSize `json:"estimated_size"`
+	Regions       []uint64          `json:"regions"`
+}
+
+// StoreSnapshotStats records the snapshot stats reported by a store.
+type StoreSnapshotStats struct {
+	SendingSnapCount   uint32 `json:"sending_snap_count"`
+	ReceivingSnapCount uint32 `json:"receiving_snap_count"`
+	ApplyingSnapCount  uint32 `json:"applying_snap_count"`
+}
+
+// SnapshotFlows is the matrix of the snapshots in flight. Stores which are
+// neither sending nor receiving snapshots are omitted.
+type SnapshotFlows struct {
+	// Flows is indexed by the source store ID and then the target store ID.
+	Flows  map[uint64]map[uint64]*SnapshotFlow `json:"flows"`
+	Stores map[uint64]*StoreSnapshotStats      `json:"stores"`
+}
+
+func newSnapshotFlows() *SnapshotFlows {
+	return &SnapshotFlows{
+		Flows:  make(map[uint64]map[uint64]*SnapshotFlow),
+		Stores: make(map[uint64]*StoreSnapshotStats),
+	}
+}
+
+func (f *SnapshotFlows) add(region *core.RegionInfo, source, target uint64) {
+	targets, ok := f.Flows[source]
+	if !ok {
+		targets = make(map[uint64]*SnapshotFlow)
+		f.Flows[source] = targets
+	}
+	flow, ok := targets[target]
+	if !ok {
+		flow = &SnapshotFlow{}
+		targets[target] = flow
+	}
+	flow.RegionCount++
+	flow.EstimatedSize += typeutil.ByteSize(region.GetApproximateSize()) * (1 << 20)
+	flow.Regions = append(flow.Regions, region.GetID())
+}
+
+func (f *SnapshotFlows) addStore(store *core.StoreInfo) {
+	if store == nil {
+		return
+	}
+	f.Stores[store.GetID()] = &StoreSnapshotStats{
+		SendingSnapCount:   store.GetSendingSnapCount(),
+		ReceivingSnapCount: store.GetReceivingSnapCount(),
+		ApplyingSnapCount:  store.GetApplyingSnapCount(),
+	}
+}
+
+// snapshotTarget returns the store which receives a snapshot if the step adds
+// a new peer.
+func snapshotTarget(step operator.OpStep) (uint64, bool) {
+	switch s := step.(type) {
+	case operator.AddPeer:
+		return s.ToStore, true
+	case operator.AddLightPeer:
+		return s.ToStore, true
+	case operator.AddLearner:
+		return s.ToStore, true
+	case operator.AddLightLearner:
+		return s.ToStore, true
+	}
+	return 0, false
+}
+
+// GetSnapshotFlows estimates the snapshots being transferred between stores
+// according to the running operators. The snapshot is assumed to be sent by
+// the store where the region leader is located.
+func (oc *OperatorController) GetSnapshotFlows() *SnapshotFlows {
+	oc.RLock()
+	defer oc.RUnlock()
+	flows := newSnapshotFlows()
+	for _, op := range oc.operators {
+		if op.IsEnd() {
+			continue
+		}
+		region := oc.cluster.GetRegion(op.RegionID())
+		if region == nil || region.GetLeader() == nil {
+			continue
+		}
+		target, ok := snapshotTarget(op.CurrentStep(region))
+		if !ok {
+			continue
+		}
+		flows.add(region, region.GetLeader().GetStoreId(), target)
+	}
+	for source, targets := range flows.Flows {
+		flows.addStore(oc.cluster.GetStore(source))
+		for target := range targets {
+			flows.addStore(oc.cluster.GetStore(target))
+		}
+	}
+	for _, store := range oc.cluster.GetStores() {
+		if store.GetSendingSnapCount() > 0 || store.GetReceivingSnapCount() > 0 || store.GetApplyingSnapCount() > 0 {
+			flows.addStore(store)
+		}
+	}
+	return flows
+}
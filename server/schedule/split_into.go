@@ -0,0 +1,203 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+)
+
+// SplitIntoDesc is the description of the operators splitting a region into parts.
+const SplitIntoDesc = "split-region-into"
+
+// Limits of the parts a region can be split into.
+const (
+	MinSplitIntoParts = 2
+	MaxSplitIntoParts = 256
+)
+
+// splitIntoTimeout is how long a split-into job can go without any progress.
+var splitIntoTimeout = 10 * time.Minute
+
+// SplitIntoStatus is the progress of splitting a region into parts.
+type SplitIntoStatus struct {
+	RegionID      uint64 `json:"region_id"`
+	Parts         int    `json:"parts"`
+	FinishedParts int    `json:"finished_parts"`
+	Status        string `json:"status"`
+}
+
+// CreateSplitIntoOperator creates an operator which splits the region in halves
+// for splitting the region into parts.
+func CreateSplitIntoOperator(region *core.RegionInfo) *operator.Operator {
+	op := operator.CreateSplitRegionOperator(SplitIntoDesc, region, operator.OpAdmin, pdpb.CheckPolicy_APPROXIMATE, nil)
+	op.SetCreator(operator.CreatorAdmin)
+	return op
+}
+
+// splitIntoPiece is a key range which still needs to be split into parts.
+type splitIntoPiece struct {
+	startKey, endKey []byte
+	parts            int
+}
+
+func (p *splitIntoPiece) contains(region *core.RegionInfo) bool {
+	return bytes.Compare(region.GetStartKey(), p.startKey) >= 0 &&
+		(len(p.endKey) == 0 || (len(region.GetEndKey()) > 0 && bytes.Compare(region.GetEndKey(), p.endKey) <= 0))
+}
+
+func (p *splitIntoPiece) equal(region *core.RegionInfo) bool {
+	return bytes.Equal(region.GetStartKey(), p.startKey) && bytes.Equal(region.GetEndKey(), p.endKey)
+}
+
+// halve splits the piece at the key. Pieces which need no more split are dropped.
+func (p *splitIntoPiece) halve(key []byte) []*splitIntoPiece {
+	var res []*splitIntoPiece
+	if left := p.parts / 2; left > 1 {
+		res = append(res, &splitIntoPiece{startKey: p.startKey, endKey: key, parts: left})
+	}
+	if right := p.parts - p.parts/2; right > 1 {
+		res = append(res, &splitIntoPiece{startKey: key, endKey: p.endKey, parts: right})
+	}
+	return res
+}
+
+type splitIntoJob struct {
+	regionID     uint64
+	parts        int
+	pieces       []*splitIntoPiece
+	status       pdpb.OperatorStatus
+	lastProgress time.Time
+	endTime      time.Time
+}
+
+// checkEnd checks whether the job succeeds or times out.
+func (j *splitIntoJob) checkEnd(now time.Time) {
+	if j.status != pdpb.OperatorStatus_RUNNING {
+		return
+	}
+	if len(j.pieces) == 0 {
+		j.status = pdpb.OperatorStatus_SUCCESS
+	} else if now.Sub(j.lastProgress) > splitIntoTimeout {
+		j.status = pdpb.OperatorStatus_TIMEOUT
+	} else {
+		return
+	}
+	j.endTime = now
+}
+
+func (j *splitIntoJob) finishedParts() int {
+	finished := j.parts
+	for _, p := range j.pieces {
+		finished -= p.parts
+	}
+	return finished
+}
+
+// observe updates the pieces with the region, and returns the piece equal to
+// the region if there is one.
+func (j *splitIntoJob) observe(region *core.RegionInfo, now time.Time) *splitIntoPiece {
+	for i := 0; i < len(j.pieces); i++ {
+		p := j.pieces[i]
+		if !p.contains(region) {
+			continue
+		}
+		if p.equal(region) {
+			return p
+		}
+		// The piece has been split, the boundary of the region is the split key.
+		key := region.GetStartKey()
+		if bytes.Equal(key, p.startKey) {
+			key = region.GetEndKey()
+		}
+		j.pieces = append(append(j.pieces[:i:i], p.halve(key)...), j.pieces[i+1:]...)
+		j.lastProgress = now
+		// The region may be equal to or contained by the new pieces.
+		i--
+	}
+	return nil
+}
+
+// splitIntoJobs tracks the progress of splitting regions into parts. A region is
+// split into halves iteratively, each split is issued once the heartbeat of a
+// region equal to a piece to be split is received.
+type splitIntoJobs struct {
+	sync.Mutex
+	jobs map[uint64]*splitIntoJob
+}
+
+func newSplitIntoJobs() *splitIntoJobs {
+	return &splitIntoJobs{jobs: make(map[uint64]*splitIntoJob)}
+}
+
+func (s *splitIntoJobs) add(region *core.RegionInfo, parts int) {
+	s.Lock()
+	defer s.Unlock()
+	s.jobs[region.GetID()] = &splitIntoJob{
+		regionID:     region.GetID(),
+		parts:        parts,
+		pieces:       []*splitIntoPiece{{startKey: region.GetStartKey(), endKey: region.GetEndKey(), parts: parts}},
+		status:       pdpb.OperatorStatus_RUNNING,
+		lastProgress: time.Now(),
+	}
+}
+
+func (s *splitIntoJobs) remove(regionID uint64) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.jobs, regionID)
+}
+
+// observe updates the running jobs with the region, and returns the operator
+// to split the region if it needs to be split.
+func (s *splitIntoJobs) observe(region *core.RegionInfo) *operator.Operator {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now()
+	var op *operator.Operator
+	for id, job := range s.jobs {
+		if job.status != pdpb.OperatorStatus_RUNNING {
+			if now.Sub(job.endTime) > historyKeepTime {
+				delete(s.jobs, id)
+			}
+			continue
+		}
+		if p := job.observe(region, now); p != nil && op == nil {
+			op = CreateSplitIntoOperator(region)
+		}
+		job.checkEnd(now)
+	}
+	return op
+}
+
+func (s *splitIntoJobs) getStatus(regionID uint64) *SplitIntoStatus {
+	s.Lock()
+	defer s.Unlock()
+	job, ok := s.jobs[regionID]
+	if !ok {
+		return nil
+	}
+	job.checkEnd(time.Now())
+	return &SplitIntoStatus{
+		RegionID:      job.regionID,
+		Parts:         job.parts,
+		FinishedParts: job.finishedParts(),
+		Status:        job.status.String(),
+	}
+}
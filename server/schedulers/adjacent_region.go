@@ -154,6 +154,14 @@ func (l *balanceAdjacentRegionScheduler) GetNextInterval(interval time.Duration)
 	return intervalGrow(interval, maxAdjacentSchedulerInterval, linearGrowth)
 }
 
+func (l *balanceAdjacentRegionScheduler) GetEffectiveConfig(cluster opt.Cluster) []*schedule.EffectiveConfigItem {
+	return schedulerConfigItems(l.GetName(), l, &balanceAdjacentRegionConfig{
+		Name:        l.conf.Name,
+		LeaderLimit: defaultAdjacentLeaderLimit,
+		PeerLimit:   defaultAdjacentPeerLimit,
+	})
+}
+
 func (l *balanceAdjacentRegionScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
 	return l.allowBalanceLeader() || l.allowBalancePeer()
 }
@@ -14,6 +14,7 @@
 package schedulers
 
 import (
+	"net/http"
 	"sort"
 	"strconv"
 
@@ -55,7 +56,7 @@ func init() {
 	})
 
 	schedule.RegisterScheduler(BalanceLeaderType, func(opController *schedule.OperatorController, storage *core.Storage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
-		conf := &balanceLeaderSchedulerConfig{}
+		conf := &balanceLeaderSchedulerConfig{storage: storage}
 		if err := decoder(conf); err != nil {
 			return nil, err
 		}
@@ -63,11 +64,6 @@ func init() {
 	})
 }
 
-type balanceLeaderSchedulerConfig struct {
-	Name   string          `json:"name"`
-	Ranges []core.KeyRange `json:"ranges"`
-}
-
 type balanceLeaderScheduler struct {
 	*BaseScheduler
 	conf         *balanceLeaderSchedulerConfig
@@ -114,6 +110,10 @@ func WithBalanceLeaderName(name string) BalanceLeaderCreateOption {
 	}
 }
 
+func (l *balanceLeaderScheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.conf.ServeHTTP(w, r)
+}
+
 func (l *balanceLeaderScheduler) GetName() string {
 	return l.conf.Name
 }
@@ -123,7 +123,20 @@ func (l *balanceLeaderScheduler) GetType() string {
 }
 
 func (l *balanceLeaderScheduler) EncodeConfig() ([]byte, error) {
-	return schedule.EncodeConfig(l.conf)
+	return l.conf.EncodeConfig()
+}
+
+func (l *balanceLeaderScheduler) GetEffectiveConfig(cluster opt.Cluster) []*schedule.EffectiveConfigItem {
+	tolerantSizeRatio := clusterConfigItem("tolerant-size-ratio", cluster.GetTolerantSizeRatio())
+	if _, ok := l.conf.GetTolerantCount(); ok {
+		tolerantSizeRatio.ShadowedBy = "tolerant-count"
+	}
+	items := []*schedule.EffectiveConfigItem{
+		clusterConfigItem("leader-schedule-limit", cluster.GetLeaderScheduleLimit()),
+		clusterConfigItem("leader-schedule-policy", cluster.GetLeaderSchedulePolicy().String()),
+		tolerantSizeRatio,
+	}
+	return append(items, schedulerConfigItems(l.GetName(), l, nil)...)
 }
 
 func (l *balanceLeaderScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
@@ -193,7 +206,7 @@ func (l *balanceLeaderScheduler) Schedule(cluster opt.Cluster) []*operator.Opera
 // the best follower peer and transfers the leader.
 func (l *balanceLeaderScheduler) transferLeaderOut(cluster opt.Cluster, source *core.StoreInfo) []*operator.Operator {
 	sourceID := source.GetID()
-	region := cluster.RandLeaderRegion(sourceID, l.conf.Ranges, opt.HealthRegion(cluster))
+	region := cluster.RandLeaderRegion(sourceID, l.conf.Ranges, opt.HealthRegion(cluster), opt.UnlockedRegion(cluster), opt.UnpinnedRegion(cluster))
 	if region == nil {
 		log.Debug("store has no leader", zap.String("scheduler", l.GetName()), zap.Uint64("store-id", sourceID))
 		schedulerCounter.WithLabelValues(l.GetName(), "no-leader-region").Inc()
@@ -212,6 +225,7 @@ func (l *balanceLeaderScheduler) transferLeaderOut(cluster opt.Cluster, source *
 	}
 	log.Debug("region has no target store", zap.String("scheduler", l.GetName()), zap.Uint64("region-id", region.GetID()))
 	schedulerCounter.WithLabelValues(l.GetName(), "no-target-store").Inc()
+	cluster.GetRegionTracer().Record(region.GetID(), l.GetName(), "no target store to transfer leader out of store %d", sourceID)
 	return nil
 }
 
@@ -220,7 +234,7 @@ func (l *balanceLeaderScheduler) transferLeaderOut(cluster opt.Cluster, source *
 // the worst follower peer and transfers the leader.
 func (l *balanceLeaderScheduler) transferLeaderIn(cluster opt.Cluster, target *core.StoreInfo) []*operator.Operator {
 	targetID := target.GetID()
-	region := cluster.RandFollowerRegion(targetID, l.conf.Ranges, opt.HealthRegion(cluster))
+	region := cluster.RandFollowerRegion(targetID, l.conf.Ranges, opt.HealthRegion(cluster), opt.UnlockedRegion(cluster), opt.UnpinnedRegion(cluster))
 	if region == nil {
 		log.Debug("store has no follower", zap.String("scheduler", l.GetName()), zap.Uint64("store-id", targetID))
 		schedulerCounter.WithLabelValues(l.GetName(), "no-follower-region").Inc()
@@ -248,6 +262,7 @@ func (l *balanceLeaderScheduler) createOperator(cluster opt.Cluster, region *cor
 	if cluster.IsRegionHot(region) {
 		log.Debug("region is hot region, ignore it", zap.String("scheduler", l.GetName()), zap.Uint64("region-id", region.GetID()))
 		schedulerCounter.WithLabelValues(l.GetName(), "region-hot").Inc()
+		cluster.GetRegionTracer().Record(region.GetID(), l.GetName(), "skip because the region is hot")
 		return nil
 	}
 
@@ -255,17 +270,32 @@ func (l *balanceLeaderScheduler) createOperator(cluster opt.Cluster, region *cor
 	targetID := target.GetID()
 
 	opInfluence := l.opController.GetOpInfluence(cluster)
-	kind := core.NewScheduleKind(core.LeaderKind, cluster.GetLeaderSchedulePolicy())
-	if !shouldBalance(cluster, source, target, region, kind, opInfluence, l.GetName()) {
-		schedulerCounter.WithLabelValues(l.GetName(), "skip").Inc()
-		return nil
+	var reason *operator.Reason
+	if tolerantCount, ok := l.conf.GetTolerantCount(); ok {
+		if !shouldBalanceLeaderCount(source, target, opInfluence, tolerantCount) {
+			schedulerCounter.WithLabelValues(l.GetName(), "skip").Inc()
+			cluster.GetRegionTracer().Record(region.GetID(), l.GetName(), "skip transferring leader from store %d to store %d because the leader count difference is within %d", sourceID, targetID, tolerantCount)
+			return nil
+		}
+		reason = balanceLeaderCountReason(source, target, opInfluence, tolerantCount)
+	} else {
+		kind := core.NewScheduleKind(core.LeaderKind, cluster.GetLeaderSchedulePolicy())
+		if !shouldBalance(cluster, source, target, region, kind, opInfluence, l.GetName()) {
+			schedulerCounter.WithLabelValues(l.GetName(), "skip").Inc()
+			cluster.GetRegionTracer().Record(region.GetID(), l.GetName(), "skip transferring leader from store %d to store %d because it is not balanced", sourceID, targetID)
+			return nil
+		}
+		reason = balanceReason(cluster, source, target, region, kind, opInfluence)
 	}
 
 	op, err := operator.CreateTransferLeaderOperator(BalanceLeaderType, cluster, region, region.GetLeader().GetStoreId(), targetID, operator.OpBalance)
 	if err != nil {
 		log.Debug("fail to create balance leader operator", zap.Error(err))
+		cluster.GetRegionTracer().Record(region.GetID(), l.GetName(), "failed to create balance-leader operator: %v", err)
 		return nil
 	}
+	op.SetReason(reason)
+	cluster.GetRegionTracer().Record(region.GetID(), l.GetName(), "transfer leader from store %d to store %d by operator %s", sourceID, targetID, op)
 	sourceLabel := strconv.FormatUint(sourceID, 10)
 	targetLabel := strconv.FormatUint(targetID, 10)
 	op.Counters = append(op.Counters,
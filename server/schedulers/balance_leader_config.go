@@ -0,0 +1,102 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/unrolled/render"
+)
+
+type balanceLeaderSchedulerConfig struct {
+	sync.RWMutex
+	storage *core.Storage
+
+	Name   string          `json:"name"`
+	Ranges []core.KeyRange `json:"ranges"`
+	// TolerantCount is the absolute leader count difference that is tolerated
+	// between the source and target stores. When it is set, it takes the place
+	// of the ratio based tolerant resource. nil means not set.
+	TolerantCount *int64 `json:"tolerant-count,omitempty"`
+}
+
+func (conf *balanceLeaderSchedulerConfig) EncodeConfig() ([]byte, error) {
+	conf.RLock()
+	defer conf.RUnlock()
+	return schedule.EncodeConfig(conf)
+}
+
+// GetTolerantCount returns the absolute tolerant leader count and whether it is set.
+func (conf *balanceLeaderSchedulerConfig) GetTolerantCount() (int64, bool) {
+	conf.RLock()
+	defer conf.RUnlock()
+	if conf.TolerantCount == nil {
+		return 0, false
+	}
+	return *conf.TolerantCount, true
+}
+
+func (conf *balanceLeaderSchedulerConfig) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := mux.NewRouter()
+	router.HandleFunc("/list", conf.handleGetConfig).Methods("GET")
+	router.HandleFunc("/config", conf.handleSetConfig).Methods("POST")
+	router.ServeHTTP(w, r)
+}
+
+func (conf *balanceLeaderSchedulerConfig) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	rd := render.New(render.Options{IndentJSON: true})
+	conf.RLock()
+	defer conf.RUnlock()
+	rd.JSON(w, http.StatusOK, conf)
+}
+
+// handleSetConfig updates the tolerant count. A null "tolerant-count" unsets
+// it so that the ratio based tolerance is used again.
+func (conf *balanceLeaderSchedulerConfig) handleSetConfig(w http.ResponseWriter, r *http.Request) {
+	rd := render.New(render.Options{IndentJSON: true})
+	var input struct {
+		TolerantCount *int64 `json:"tolerant-count"`
+	}
+	if err := apiutil.ReadJSONRespondError(rd, w, r.Body, &input); err != nil {
+		return
+	}
+	if input.TolerantCount != nil && *input.TolerantCount < 0 {
+		rd.Text(w, http.StatusBadRequest, "tolerant-count should not be negative")
+		return
+	}
+
+	conf.Lock()
+	defer conf.Unlock()
+	old := conf.TolerantCount
+	conf.TolerantCount = input.TolerantCount
+	if err := conf.persist(); err != nil {
+		conf.TolerantCount = old // revert
+		rd.Text(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rd.Text(w, http.StatusOK, "")
+}
+
+func (conf *balanceLeaderSchedulerConfig) persist() error {
+	data, err := schedule.EncodeConfig(conf)
+	if err != nil {
+		return err
+	}
+	return conf.storage.SaveScheduleConfig(conf.Name, data)
+}
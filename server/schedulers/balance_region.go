@@ -14,8 +14,11 @@
 package schedulers
 
 import (
+	"math"
+	"net/http"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/log"
@@ -25,6 +28,7 @@ import (
 	"github.com/pingcap/pd/v4/server/schedule/filter"
 	"github.com/pingcap/pd/v4/server/schedule/operator"
 	"github.com/pingcap/pd/v4/server/schedule/opt"
+	"github.com/pingcap/pd/v4/server/statistics"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
@@ -47,7 +51,7 @@ func init() {
 		}
 	})
 	schedule.RegisterScheduler(BalanceRegionType, func(opController *schedule.OperatorController, storage *core.Storage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
-		conf := &balanceRegionSchedulerConfig{}
+		conf := &balanceRegionSchedulerConfig{storage: storage}
 		if err := decoder(conf); err != nil {
 			return nil, err
 		}
@@ -62,13 +66,11 @@ const (
 	BalanceRegionName = "balance-region-scheduler"
 	// BalanceRegionType is balance region scheduler type.
 	BalanceRegionType = "balance-region"
+	// balanceRegionCostCandidates is the number of the regions compared by
+	// the move cost when the write flow cost is enabled.
+	balanceRegionCostCandidates = 4
 )
 
-type balanceRegionSchedulerConfig struct {
-	Name   string          `json:"name"`
-	Ranges []core.KeyRange `json:"ranges"`
-}
-
 type balanceRegionScheduler struct {
 	*BaseScheduler
 	conf         *balanceRegionSchedulerConfig
@@ -93,6 +95,7 @@ func newBalanceRegionScheduler(opController *schedule.OperatorController, conf *
 	scheduler.filters = []filter.Filter{
 		filter.StoreStateFilter{ActionScope: scheduler.GetName(), MoveRegion: true},
 		filter.NewSpecialUseFilter(scheduler.GetName()),
+		filter.NewEngineFilter(scheduler.GetName()),
 	}
 	return scheduler
 }
@@ -114,6 +117,10 @@ func WithBalanceRegionName(name string) BalanceRegionCreateOption {
 	}
 }
 
+func (s *balanceRegionScheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.conf.ServeHTTP(w, r)
+}
+
 func (s *balanceRegionScheduler) GetName() string {
 	return s.conf.Name
 }
@@ -123,7 +130,17 @@ func (s *balanceRegionScheduler) GetType() string {
 }
 
 func (s *balanceRegionScheduler) EncodeConfig() ([]byte, error) {
-	return schedule.EncodeConfig(s.conf)
+	return s.conf.EncodeConfig()
+}
+
+func (s *balanceRegionScheduler) GetEffectiveConfig(cluster opt.Cluster) []*schedule.EffectiveConfigItem {
+	items := []*schedule.EffectiveConfigItem{
+		clusterConfigItem("region-schedule-limit", cluster.GetRegionScheduleLimit()),
+		clusterConfigItem("tolerant-size-ratio", cluster.GetTolerantSizeRatio()),
+		clusterConfigItem("low-space-ratio", cluster.GetLowSpaceRatio()),
+		clusterConfigItem("high-space-ratio", cluster.GetHighSpaceRatio()),
+	}
+	return append(items, schedulerConfigItems(s.GetName(), s, &balanceRegionSchedulerConfig{Name: s.conf.Name, Ranges: s.conf.Ranges})...)
 }
 
 func (s *balanceRegionScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
@@ -146,31 +163,19 @@ func (s *balanceRegionScheduler) Schedule(cluster opt.Cluster) []*operator.Opera
 		sourceID := source.GetID()
 
 		for i := 0; i < balanceRegionRetryLimit; i++ {
-			// Priority pick the region that has a pending peer.
-			// Pending region may means the disk is overload, remove the pending region firstly.
-			region := cluster.RandPendingRegion(sourceID, s.conf.Ranges, opt.HealthAllowPending(cluster), opt.ReplicatedRegion(cluster))
-			if region == nil {
-				// Then pick the region that has a follower in the source store.
-				region = cluster.RandFollowerRegion(sourceID, s.conf.Ranges, opt.HealthRegion(cluster), opt.ReplicatedRegion(cluster))
-			}
-			if region == nil {
-				// Then pick the region has the leader in the source store.
-				region = cluster.RandLeaderRegion(sourceID, s.conf.Ranges, opt.HealthRegion(cluster), opt.ReplicatedRegion(cluster))
-			}
-			if region == nil {
-				// Finally pick learner.
-				region = cluster.RandLearnerRegion(sourceID, s.conf.Ranges, opt.HealthRegion(cluster), opt.ReplicatedRegion(cluster))
-			}
+			region := s.selectRegion(cluster, sourceID)
 			if region == nil {
 				schedulerCounter.WithLabelValues(s.GetName(), "no-region").Inc()
 				continue
 			}
 			log.Debug("select region", zap.String("scheduler", s.GetName()), zap.Uint64("region-id", region.GetID()))
+			cluster.GetRegionTracer().Record(region.GetID(), s.GetName(), "selected to move out of store %d", sourceID)
 
 			// Skip hot regions.
 			if cluster.IsRegionHot(region) {
 				log.Debug("region is hot", zap.String("scheduler", s.GetName()), zap.Uint64("region-id", region.GetID()))
 				schedulerCounter.WithLabelValues(s.GetName(), "region-hot").Inc()
+				cluster.GetRegionTracer().Record(region.GetID(), s.GetName(), "skip because the region is hot")
 				continue
 			}
 
@@ -184,6 +189,71 @@ func (s *balanceRegionScheduler) Schedule(cluster opt.Cluster) []*operator.Opera
 	return nil
 }
 
+// selectRegion picks a region to move out of the source store. When the write
+// flow cost is enabled, it picks the one of the least move cost per size among
+// a few candidates, so that a cold region is preferred to a write-hot one of
+// similar size, whose snapshot chases the ongoing writes.
+func (s *balanceRegionScheduler) selectRegion(cluster opt.Cluster, sourceID uint64) *core.RegionInfo {
+	multiplier, snapshotDuration := s.conf.GetWriteFlowCost()
+	if multiplier == 0 {
+		return s.randRegion(cluster, sourceID)
+	}
+	var (
+		best     *core.RegionInfo
+		bestCost float64
+	)
+	picked := make(map[uint64]struct{})
+	for i := 0; i < balanceRegionCostCandidates; i++ {
+		region := s.randRegion(cluster, sourceID, func(r *core.RegionInfo) bool {
+			_, ok := picked[r.GetID()]
+			return !ok
+		})
+		if region == nil {
+			break
+		}
+		picked[region.GetID()] = struct{}{}
+		cost := regionMoveCost(region, multiplier, snapshotDuration) / math.Max(float64(region.GetApproximateSize()), 1)
+		if best == nil || cost < bestCost {
+			best, bestCost = region, cost
+		}
+	}
+	return best
+}
+
+// randRegion picks a random region on the source store, from the ones with a
+// pending peer, a follower, the leader and a learner there in order.
+func (s *balanceRegionScheduler) randRegion(cluster opt.Cluster, sourceID uint64, opts ...core.RegionOption) *core.RegionInfo {
+	// Priority pick the region that has a pending peer.
+	// Pending region may means the disk is overload, remove the pending region firstly.
+	region := cluster.RandPendingRegion(sourceID, s.conf.Ranges, append(opts, opt.HealthAllowPending(cluster), opt.ReplicatedRegion(cluster), opt.UnlockedRegion(cluster))...)
+	if region == nil {
+		// Then pick the region that has a follower in the source store.
+		region = cluster.RandFollowerRegion(sourceID, s.conf.Ranges, append(opts, opt.HealthRegion(cluster), opt.ReplicatedRegion(cluster), opt.UnlockedRegion(cluster))...)
+	}
+	if region == nil {
+		// Then pick the region has the leader in the source store.
+		region = cluster.RandLeaderRegion(sourceID, s.conf.Ranges, append(opts, opt.HealthRegion(cluster), opt.ReplicatedRegion(cluster), opt.UnlockedRegion(cluster))...)
+	}
+	if region == nil {
+		// Finally pick learner.
+		region = cluster.RandLearnerRegion(sourceID, s.conf.Ranges, append(opts, opt.HealthRegion(cluster), opt.ReplicatedRegion(cluster), opt.UnlockedRegion(cluster))...)
+	}
+	return region
+}
+
+// regionMoveCost returns the effective cost in MB to move a peer of the
+// region, which is the approximate size plus the multiplier times the bytes
+// written during the snapshot at the recent write rate.
+func regionMoveCost(region *core.RegionInfo, multiplier float64, snapshotDuration time.Duration) float64 {
+	interval := region.GetInterval()
+	seconds := uint64(statistics.RegionHeartBeatReportInterval)
+	if interval.GetEndTimestamp() > interval.GetStartTimestamp() {
+		seconds = interval.GetEndTimestamp() - interval.GetStartTimestamp()
+	}
+	writeRate := float64(region.GetBytesWritten()) / float64(seconds) / (1 << 20)
+	return float64(region.GetApproximateSize()) + multiplier*writeRate*snapshotDuration.Seconds()
+}
+
 // transferPeer selects the best store to create a new peer to replace the old peer.
 func (s *balanceRegionScheduler) transferPeer(cluster opt.Cluster, region *core.RegionInfo, oldPeer *metapb.Peer) *operator.Operator {
 	// scoreGuard guarantees that the distinct score will not decrease.
@@ -196,6 +266,13 @@ func (s *balanceRegionScheduler) transferPeer(cluster opt.Cluster, region *core.
 	}
 	exclude := make(map[uint64]struct{})
 	excludeFilter := filter.NewExcludedFilter(s.GetName(), nil, exclude)
+	// Skip the stores whose limits are to be consumed by the waiting operators.
+	remaining := make(map[uint64]float64)
+	for storeID, budget := range s.opController.GetStoreBudgets() {
+		remaining[storeID] = budget.Remaining
+	}
+	cost := float64(operator.AddPeerStepCost(region)) / float64(operator.RegionInfluence)
+	budgetFilter := filter.NewStoreBudgetFilter(s.GetName(), remaining, cost)
 	for {
 		var target *core.StoreInfo
 		if cluster.IsPlacementRulesEnabled() {
@@ -206,17 +283,19 @@ func (s *balanceRegionScheduler) transferPeer(cluster opt.Cluster, region *core.
 				schedulerCounter.WithLabelValues(s.GetName(), "skip-orphan-peer").Inc()
 				return nil
 			}
-			target = checker.SelectStoreToReplacePeerByRule(s.GetName(), cluster, region, fit, rf, oldPeer, scoreGuard, excludeFilter)
+			target = checker.SelectStoreToReplacePeerByRule(s.GetName(), cluster, region, fit, rf, oldPeer, scoreGuard, excludeFilter, budgetFilter)
 		} else {
 			scoreGuard := filter.NewDistinctScoreFilter(s.GetName(), cluster.GetLocationLabels(), stores, source)
 			replicaChecker := checker.NewReplicaChecker(cluster, s.GetName())
-			storeID, _ := replicaChecker.SelectBestReplacementStore(region, oldPeer, scoreGuard, excludeFilter)
+			storageFilter := filter.NewStorageThresholdFilter(s.GetName())
+			storeID, _ := replicaChecker.SelectBestReplacementStore(region, oldPeer, scoreGuard, excludeFilter, budgetFilter, storageFilter)
 			if storeID != 0 {
 				target = cluster.GetStore(storeID)
 			}
 		}
 		if target == nil {
 			schedulerCounter.WithLabelValues(s.GetName(), "no-replacement").Inc()
+			cluster.GetRegionTracer().Record(region.GetID(), s.GetName(), "no replacement store for the peer in store %d", sourceStoreID)
 			return nil
 		}
 		exclude[target.GetID()] = struct{}{} // exclude next round.
@@ -230,6 +309,7 @@ func (s *balanceRegionScheduler) transferPeer(cluster opt.Cluster, region *core.
 		kind := core.NewScheduleKind(core.RegionKind, core.BySize)
 		if !shouldBalance(cluster, source, target, region, kind, opInfluence, s.GetName()) {
 			schedulerCounter.WithLabelValues(s.GetName(), "skip").Inc()
+			cluster.GetRegionTracer().Record(regionID, s.GetName(), "skip moving from store %d to store %d because it is not balanced", sourceID, targetID)
 			continue
 		}
 
@@ -237,8 +317,11 @@ func (s *balanceRegionScheduler) transferPeer(cluster opt.Cluster, region *core.
 		op, err := operator.CreateMovePeerOperator("balance-region", cluster, region, operator.OpBalance, oldPeer.GetStoreId(), newPeer)
 		if err != nil {
 			schedulerCounter.WithLabelValues(s.GetName(), "create-operator-fail").Inc()
+			cluster.GetRegionTracer().Record(regionID, s.GetName(), "failed to create balance-region operator: %v", err)
 			return nil
 		}
+		op.SetReason(balanceReason(cluster, source, target, region, kind, opInfluence))
+		cluster.GetRegionTracer().Record(regionID, s.GetName(), "move from store %d to store %d by operator %s", sourceID, targetID, op)
 		sourceLabel := strconv.FormatUint(sourceID, 10)
 		targetLabel := strconv.FormatUint(targetID, 10)
 		op.Counters = append(op.Counters,
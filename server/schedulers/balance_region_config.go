@@ -0,0 +1,123 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/pd/v4/pkg/apiutil"
+	"github.com/pingcap/pd/v4/pkg/typeutil"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/unrolled/render"
+)
+
+// defaultBalanceRegionSnapshotDuration is the expected duration of a snapshot
+// when it is not configured.
+const defaultBalanceRegionSnapshotDuration = 30 * time.Second
+
+type balanceRegionSchedulerConfig struct {
+	sync.RWMutex
+	storage *core.Storage
+
+	Name   string          `json:"name"`
+	Ranges []core.KeyRange `json:"ranges"`
+	// WriteFlowCostMultiplier weights the write flow of a region in its move
+	// cost, which is the approximate size plus the multiplier times the write
+	// bytes written during the snapshot. 0 means the move cost is not used.
+	WriteFlowCostMultiplier float64 `json:"write-flow-cost-multiplier"`
+	// SnapshotDuration is the expected duration of a snapshot. 0 means the
+	// default one.
+	SnapshotDuration typeutil.Duration `json:"snapshot-duration"`
+}
+
+func (conf *balanceRegionSchedulerConfig) EncodeConfig() ([]byte, error) {
+	conf.RLock()
+	defer conf.RUnlock()
+	return schedule.EncodeConfig(conf)
+}
+
+// GetWriteFlowCost returns the write flow cost multiplier and the expected
+// snapshot duration.
+func (conf *balanceRegionSchedulerConfig) GetWriteFlowCost() (float64, time.Duration) {
+	conf.RLock()
+	defer conf.RUnlock()
+	duration := conf.SnapshotDuration.Duration
+	if duration == 0 {
+		duration = defaultBalanceRegionSnapshotDuration
+	}
+	return conf.WriteFlowCostMultiplier, duration
+}
+
+func (conf *balanceRegionSchedulerConfig) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := mux.NewRouter()
+	router.HandleFunc("/list", conf.handleGetConfig).Methods("GET")
+	router.HandleFunc("/config", conf.handleSetConfig).Methods("POST")
+	router.ServeHTTP(w, r)
+}
+
+func (conf *balanceRegionSchedulerConfig) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	rd := render.New(render.Options{IndentJSON: true})
+	conf.RLock()
+	defer conf.RUnlock()
+	rd.JSON(w, http.StatusOK, conf)
+}
+
+// handleSetConfig updates the write flow cost multiplier and the expected
+// snapshot duration. The missing ones are left as they are.
+func (conf *balanceRegionSchedulerConfig) handleSetConfig(w http.ResponseWriter, r *http.Request) {
+	rd := render.New(render.Options{IndentJSON: true})
+	var input struct {
+		WriteFlowCostMultiplier *float64           `json:"write-flow-cost-multiplier"`
+		SnapshotDuration        *typeutil.Duration `json:"snapshot-duration"`
+	}
+	if err := apiutil.ReadJSONRespondError(rd, w, r.Body, &input); err != nil {
+		return
+	}
+	if input.WriteFlowCostMultiplier != nil && *input.WriteFlowCostMultiplier < 0 {
+		rd.Text(w, http.StatusBadRequest, "write-flow-cost-multiplier should not be negative")
+		return
+	}
+	if input.SnapshotDuration != nil && input.SnapshotDuration.Duration < 0 {
+		rd.Text(w, http.StatusBadRequest, "snapshot-duration should not be negative")
+		return
+	}
+
+	conf.Lock()
+	defer conf.Unlock()
+	oldMultiplier, oldDuration := conf.WriteFlowCostMultiplier, conf.SnapshotDuration
+	if input.WriteFlowCostMultiplier != nil {
+		conf.WriteFlowCostMultiplier = *input.WriteFlowCostMultiplier
+	}
+	if input.SnapshotDuration != nil {
+		conf.SnapshotDuration = *input.SnapshotDuration
+	}
+	if err := conf.persist(); err != nil {
+		conf.WriteFlowCostMultiplier, conf.SnapshotDuration = oldMultiplier, oldDuration // revert
+		rd.Text(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rd.Text(w, http.StatusOK, "")
+}
+
+func (conf *balanceRegionSchedulerConfig) persist() error {
+	data, err := schedule.EncodeConfig(conf)
+	if err != nil {
+		return err
+	}
+	return conf.storage.SaveScheduleConfig(conf.Name, data)
+}
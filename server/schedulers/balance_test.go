@@ -14,10 +14,15 @@
 package schedulers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
@@ -31,6 +36,7 @@ import (
 	"github.com/pingcap/pd/v4/server/schedule"
 	"github.com/pingcap/pd/v4/server/schedule/checker"
 	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"github.com/pingcap/pd/v4/server/schedule/placement"
 )
 
 func newTestReplication(mso *mockoption.ScheduleOptions, maxReplicas int, locationLabels ...string) {
@@ -268,11 +274,45 @@ func (s *testBalanceLeaderSchedulerSuite) TestBalanceLeaderTolerantRatio(c *C) {
 	c.Assert(s.tc.GetStore(1).GetLeaderCount(), Equals, 14)
 	s.tc.AddLeaderStore(1, 15, 100)
 	c.Assert(s.tc.GetStore(1).GetLeaderCount(), Equals, 15)
-	c.Check(s.schedule(), NotNil)
+	ops := s.schedule()
+	c.Check(ops, NotNil)
+	c.Assert(ops[0].Reason(), DeepEquals, operator.NewReason(operator.ReasonScoreImbalance, 1, ops[0].Step(0).(operator.TransferLeader).ToStore).
+		With("source-score", 15).
+		With("target-score", 10).
+		With("tolerant-resource", 2))
 	s.tc.TolerantSizeRatio = 6 // (15-10)<6
 	c.Check(s.schedule(), IsNil)
 }
 
+func (s *testBalanceLeaderSchedulerSuite) TestBalanceLeaderTolerantCount(c *C) {
+	// Stores:          1       2
+	// Leader Count:    11      10
+	// Region1:         L       F
+	s.tc.AddLeaderStore(1, 11)
+	s.tc.AddLeaderStore(2, 10)
+	s.tc.AddLeaderRegion(1, 1, 2)
+	conf := s.lb.(*balanceLeaderScheduler).conf
+	tolerantCount := int64(2)
+	conf.TolerantCount = &tolerantCount
+	c.Check(s.schedule(), IsNil)
+	tolerantCount = 0
+	op := s.schedule()[0]
+	testutil.CheckTransferLeader(c, op, operator.OpBalance, 1, 2)
+	c.Assert(op.Reason(), DeepEquals, operator.NewReason(operator.ReasonCountImbalance, 1, 2).
+		With("source-count", 11).
+		With("target-count", 10).
+		With("tolerant-count", 0))
+
+	// The absolute tolerant count takes the place of the ratio.
+	s.tc.UpdateLeaderCount(1, 20)
+	s.tc.TolerantSizeRatio = 20
+	c.Check(s.schedule(), NotNil)
+	tolerantCount = 10
+	c.Check(s.schedule(), IsNil)
+	conf.TolerantCount = nil
+	c.Check(s.schedule(), IsNil)
+}
+
 func (s *testBalanceLeaderSchedulerSuite) TestScheduleWithOpInfluence(c *C) {
 	// Stores:     1    2    3    4
 	// Leaders:    7    8    9   14
@@ -357,6 +397,44 @@ func (s *testBalanceLeaderSchedulerSuite) TestLeaderWeight(c *C) {
 	testutil.CheckTransferLeader(c, s.schedule()[0], operator.OpBalance, 1, 3)
 }
 
+func (s *testBalanceLeaderSchedulerSuite) TestScheduleLock(c *C) {
+	// Stores:     1    2
+	// Leaders:    1    16
+	// Region1:    F    L
+	s.tc.AddLeaderStore(1, 1)
+	s.tc.AddLeaderStore(2, 16)
+	s.tc.AddLeaderRegionWithRange(1, "a", "b", 2, 1)
+	testutil.CheckTransferLeader(c, s.schedule()[0], operator.OpBalance, 2, 1)
+
+	// The region inside a locked range is skipped.
+	c.Assert(s.tc.AddLock(&core.ScheduleLock{ID: 1, StartKey: []byte("a"), EndKey: []byte("c"), ExpireTime: time.Now().Add(time.Hour)}), IsNil)
+	c.Assert(s.schedule(), HasLen, 0)
+
+	// The lock outside the region does not matter.
+	_, err := s.tc.RemoveLock(1)
+	c.Assert(err, IsNil)
+	c.Assert(s.tc.AddLock(&core.ScheduleLock{ID: 2, StartKey: []byte("b"), EndKey: []byte("c"), ExpireTime: time.Now().Add(time.Hour)}), IsNil)
+	testutil.CheckTransferLeader(c, s.schedule()[0], operator.OpBalance, 2, 1)
+}
+
+func (s *testBalanceLeaderSchedulerSuite) TestLeaderPin(c *C) {
+	// Stores:     1    2
+	// Leaders:    1    16
+	// Region1:    F    L
+	s.tc.AddLeaderStore(1, 1)
+	s.tc.AddLeaderStore(2, 16)
+	s.tc.AddLeaderRegion(1, 2, 1)
+	testutil.CheckTransferLeader(c, s.schedule()[0], operator.OpBalance, 2, 1)
+
+	// The region whose leader is pinned is skipped.
+	c.Assert(s.tc.SetPin(&core.LeaderPin{RegionID: 1, StoreID: 2}), IsNil)
+	c.Assert(s.schedule(), HasLen, 0)
+
+	_, err := s.tc.RemovePin(1)
+	c.Assert(err, IsNil)
+	testutil.CheckTransferLeader(c, s.schedule()[0], operator.OpBalance, 2, 1)
+}
+
 func (s *testBalanceLeaderSchedulerSuite) TestBalanceSelector(c *C) {
 	// Stores:     1    2    3    4
 	// Leaders:    1    2    3   16
@@ -542,7 +620,14 @@ func (s *testBalanceRegionSchedulerSuite) TestBalance(c *C) {
 	tc.AddRegionStore(4, 16)
 	// Add region 1 with leader in store 4.
 	tc.AddLeaderRegion(1, 4)
-	testutil.CheckTransferPeerWithLeaderTransfer(c, sb.Schedule(tc)[0], operator.OpBalance, 4, 1)
+	op := sb.Schedule(tc)[0]
+	testutil.CheckTransferPeerWithLeaderTransfer(c, op, operator.OpBalance, 4, 1)
+	reason := op.Reason()
+	c.Assert(reason.Code, Equals, operator.ReasonScoreImbalance)
+	c.Assert(reason.SourceStore, Equals, uint64(4))
+	c.Assert(reason.TargetStore, Equals, uint64(1))
+	c.Assert(reason.Evidence["source-score"], Greater, reason.Evidence["target-score"])
+	c.Assert(reason.Evidence["tolerant-resource"], Greater, float64(0))
 
 	// Test stateFilter.
 	tc.SetStoreOffline(1)
@@ -558,6 +643,28 @@ func (s *testBalanceRegionSchedulerSuite) TestBalance(c *C) {
 	c.Assert(sb.Schedule(tc), NotNil)
 }
 
+func (s *testBalanceRegionSchedulerSuite) TestStoreSpaceFloor(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+
+	sb, err := schedule.CreateScheduler(BalanceRegionType, oc, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(BalanceRegionType, []string{"", ""}))
+	c.Assert(err, IsNil)
+
+	opt.SetMaxReplicas(1)
+
+	tc.AddRegionStore(1, 6)
+	tc.AddRegionStore(2, 8)
+	tc.AddRegionStore(3, 16)
+	tc.AddLeaderRegion(1, 3)
+	testutil.CheckTransferPeerWithLeaderTransfer(c, sb.Schedule(tc)[0], operator.OpBalance, 3, 1)
+
+	// Store 1 is below its own floor, so the store with more regions is chosen.
+	store := tc.GetStore(1)
+	tc.PutStore(store.Clone(core.SetStoreSpaceFloor(&core.StoreSpaceFloor{MinFreeBytes: store.GetCapacity() + 1})))
+	testutil.CheckTransferPeerWithLeaderTransfer(c, sb.Schedule(tc)[0], operator.OpBalance, 3, 2)
+}
+
 func (s *testBalanceRegionSchedulerSuite) TestReplicas3(c *C) {
 	opt := mockoption.NewScheduleOptions()
 	newTestReplication(opt, 3, "zone", "rack", "host")
@@ -619,6 +726,40 @@ func (s *testBalanceRegionSchedulerSuite) checkReplica3(c *C, tc *mockcluster.Cl
 	c.Assert(sb.Schedule(tc), IsNil)
 }
 
+func (s *testBalanceRegionSchedulerSuite) TestTiFlashStores(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+
+	sb, err := schedule.CreateScheduler(BalanceRegionType, oc, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(BalanceRegionType, []string{"", ""}))
+	c.Assert(err, IsNil)
+
+	// Stores 1-3 are TiKV, and 4-5 are TiFlash with much less regions.
+	tc.AddRegionStore(1, 16)
+	tc.AddRegionStore(2, 15)
+	tc.AddRegionStore(3, 14)
+	tc.AddLabelsStore(4, 0, map[string]string{"engine": "tiflash"})
+	tc.AddLabelsStore(5, 0, map[string]string{"engine": "tiflash"})
+	region := tc.AddLeaderRegion(1, 1, 2, 3)
+	c.Assert(sb.Schedule(tc), IsNil)
+
+	// The learners selected by the rule are balanced among the TiFlash stores,
+	// while the voters are never moved to them.
+	opt.EnablePlacementRules = true
+	c.Assert(tc.SetRule(&placement.Rule{
+		GroupID:          "pd",
+		ID:               "tiflash",
+		Index:            100,
+		Role:             placement.Learner,
+		Count:            1,
+		LabelConstraints: []placement.LabelConstraint{{Key: "engine", Op: "in", Values: []string{"tiflash"}}},
+	}), IsNil)
+	c.Assert(sb.Schedule(tc), IsNil)
+	tc.PutRegion(region.Clone(core.WithAddPeer(&metapb.Peer{Id: 100, StoreId: 4, IsLearner: true})))
+	tc.UpdateRegionCount(4, 20)
+	testutil.CheckTransferLearner(c, sb.Schedule(tc)[0], operator.OpBalance, 4, 5)
+}
+
 func (s *testBalanceRegionSchedulerSuite) TestReplicas5(c *C) {
 	opt := mockoption.NewScheduleOptions()
 	newTestReplication(opt, 5, "zone", "rack", "host")
@@ -717,6 +858,45 @@ func (s *testBalanceRegionSchedulerSuite) TestBalance1(c *C) {
 	testutil.CheckTransferPeer(c, sb.Schedule(tc)[0], operator.OpBalance, 1, 4)
 }
 
+func (s *testBalanceRegionSchedulerSuite) TestWriteFlowCost(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	opt.MaxReplicas = 1
+	opt.TolerantSizeRatio = 1
+
+	sb, err := schedule.CreateScheduler(BalanceRegionType, oc, core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(BalanceRegionType, []string{"", ""}))
+	c.Assert(err, IsNil)
+
+	// Regions 1 and 2 are of the same size on store 1, and region 2 is write
+	// heavy.
+	tc.AddRegionStore(1, 10)
+	tc.AddRegionStore(2, 0)
+	tc.AddLeaderRegion(1, 1)
+	region := tc.AddLeaderRegion(2, 1)
+	tc.PutRegion(region.Clone(core.SetWrittenBytes(600*1024*1024), core.SetReportInterval(60)))
+
+	conf := sb.(*balanceRegionScheduler).conf
+	c.Assert(regionMoveCost(tc.GetRegion(1), 1, time.Minute), Equals, 10.0)
+	c.Assert(regionMoveCost(tc.GetRegion(2), 0, time.Minute), Equals, 10.0)
+	c.Assert(regionMoveCost(tc.GetRegion(2), 1, time.Minute), Equals, 610.0)
+
+	// The cold region is chosen when the write flow cost is enabled.
+	conf.WriteFlowCostMultiplier = 1
+	for i := 0; i < 10; i++ {
+		op := sb.Schedule(tc)[0]
+		testutil.CheckTransferPeer(c, op, operator.OpBalance, 1, 2)
+		c.Assert(op.RegionID(), Equals, uint64(1))
+	}
+	// Either region may be chosen otherwise.
+	conf.WriteFlowCostMultiplier = 0
+	chosen := make(map[uint64]struct{})
+	for i := 0; i < 100 && len(chosen) < 2; i++ {
+		chosen[sb.Schedule(tc)[0].RegionID()] = struct{}{}
+	}
+	c.Assert(chosen, HasLen, 2)
+}
+
 func (s *testBalanceRegionSchedulerSuite) TestStoreWeight(c *C) {
 	opt := mockoption.NewScheduleOptions()
 	tc := mockcluster.NewCluster(opt)
@@ -784,6 +964,47 @@ func (s *testBalanceRegionSchedulerSuite) TestOpInfluence(c *C) {
 	testutil.CheckTransferPeerWithLeaderTransfer(c, sb.Schedule(tc)[0], operator.OpBalance, 3, 1)
 }
 
+func (s *testBalanceRegionSchedulerSuite) TestStoreBudget(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	oc := schedule.NewOperatorController(s.ctx, tc, mockhbstream.NewHeartbeatStream())
+	sb := newBalanceRegionScheduler(oc, &balanceRegionSchedulerConfig{}, WithBalanceRegionName("balance-region-b"))
+	opt.SetMaxReplicas(1)
+	tc.AddRegionStore(1, 100)
+	tc.AddRegionStore(2, 30)
+	tc.AddRegionStore(3, 30)
+	tc.AddRegionStore(4, 10)
+	for i := uint64(1); i <= 3; i++ {
+		tc.PutRegion(tc.AddLeaderRegion(i, 1).Clone(core.SetApproximateSize(100)))
+	}
+	// Store 4 allows 2 operators at most.
+	oc.SetStoreLimit(4, 2, schedule.StoreLimitManual)
+	testutil.CheckTransferPeerWithLeaderTransfer(c, sb.Schedule(tc)[0], operator.OpBalance, 1, 4)
+
+	// Another scheduler creates 2 operators to store 4 in the same tick, one
+	// of them is promoted and the other is waiting.
+	var ops []*operator.Operator
+	for i := uint64(1); i <= 2; i++ {
+		op, err := operator.CreateMovePeerOperator("balance-region", tc, tc.GetRegion(i), operator.OpBalance, 1, &metapb.Peer{StoreId: 4})
+		c.Assert(err, IsNil)
+		ops = append(ops, op)
+	}
+	c.Assert(oc.AddWaitingOperator(ops...), Equals, 2)
+	c.Assert(oc.GetOperators(), HasLen, 1)
+	c.Assert(oc.GetWaitingOperators(), HasLen, 1)
+	budget := oc.GetStoreBudgets()[4]
+	c.Assert(budget.Available, GreaterEqual, 1.0)
+	c.Assert(budget.Waiting, Equals, 1.0)
+	c.Assert(budget.Remaining, Less, 1.0)
+
+	// Store 4 can still take an operator now, but the budget is reserved for the waiting one.
+	for i := 0; i < 10; i++ {
+		ops = sb.Schedule(tc)
+		c.Assert(ops, HasLen, 1)
+		c.Assert(ops[0].Step(0).(operator.AddLearner).ToStore, Not(Equals), uint64(4))
+	}
+}
+
 func (s *testBalanceRegionSchedulerSuite) checkReplacePendingRegion(c *C, tc *mockcluster.Cluster, opt *mockoption.ScheduleOptions, sb schedule.Scheduler) {
 	// Store 1 has the largest region score, so the balance scheduler try to replace peer in store 1.
 	tc.AddLabelsStore(1, 16, map[string]string{"zone": "z1", "rack": "r1", "host": "h1"})
@@ -882,6 +1103,72 @@ func (s *testReplicaCheckerSuite) TestBasic(c *C) {
 	testutil.CheckTransferPeer(c, rc.Check(region), operator.OpReplica, 3, 1)
 }
 
+func (s *testReplicaCheckerSuite) TestReason(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	rc := checker.NewReplicaChecker(tc)
+
+	tc.AddRegionStore(1, 4)
+	tc.AddRegionStore(2, 3)
+	tc.AddRegionStore(3, 2)
+	tc.AddRegionStore(4, 1)
+	tc.AddLeaderRegion(1, 1, 2)
+
+	region := tc.GetRegion(1)
+	op := rc.Check(region)
+	testutil.CheckAddPeer(c, op, operator.OpReplica, 4)
+	c.Assert(op.Reason(), DeepEquals, operator.NewReason(operator.ReasonMissingReplica, 0, 4).
+		With("replicas", 2).
+		With("max-replicas", 3).
+		With("distinct-score", 0))
+
+	peer4, _ := tc.AllocPeer(4)
+	peer3, _ := tc.AllocPeer(3)
+	region = region.Clone(core.WithAddPeer(peer4), core.WithAddPeer(peer3))
+	op = rc.Check(region)
+	testutil.CheckRemovePeer(c, op, 1)
+	c.Assert(op.Reason(), DeepEquals, operator.NewReason(operator.ReasonExtraReplica, 1, 0).
+		With("replicas", 4).
+		With("max-replicas", 3).
+		With("distinct-score", 0))
+
+	// The down peer is replaced.
+	region = region.Clone(core.WithRemoveStorePeer(4), core.WithLeader(region.GetStorePeer(3)))
+	tc.SetStoreDown(2)
+	downPeer := &pdpb.PeerStats{
+		Peer:        region.GetStorePeer(2),
+		DownSeconds: 24 * 60 * 60,
+	}
+	op = rc.Check(region.Clone(core.WithDownPeers([]*pdpb.PeerStats{downPeer})))
+	testutil.CheckTransferPeer(c, op, operator.OpReplica, 2, 4)
+	c.Assert(op.Reason(), DeepEquals, operator.NewReason(operator.ReasonDownPeer, 2, 4).
+		With("down-seconds", 24*60*60).
+		With("max-store-down-seconds", opt.GetMaxStoreDownTime().Seconds()).
+		With("distinct-score", 0))
+	tc.SetStoreUp(2)
+
+	// The peer on the offline store is replaced.
+	tc.SetStoreOffline(3)
+	op = rc.Check(region)
+	testutil.CheckTransferPeer(c, op, operator.OpReplica, 3, 4)
+	c.Assert(op.Reason(), DeepEquals, operator.NewReason(operator.ReasonOfflinePeer, 3, 4).
+		With("distinct-score", 0))
+	tc.SetStoreUp(3)
+
+	// The peer is moved to the zone without any peer.
+	opt.LocationLabels = []string{"zone"}
+	tc.AddLabelsStore(1, 4, map[string]string{"zone": "z1"})
+	tc.AddLabelsStore(2, 3, map[string]string{"zone": "z1"})
+	tc.AddLabelsStore(3, 2, map[string]string{"zone": "z2"})
+	tc.AddLabelsStore(4, 1, map[string]string{"zone": "z3"})
+	op = rc.Check(region)
+	c.Assert(op, NotNil)
+	reason := op.Reason()
+	c.Assert(reason.Code, Equals, operator.ReasonBetterLocation)
+	c.Assert(reason.TargetStore, Equals, uint64(4))
+	c.Assert(reason.Evidence["new-score"], Greater, reason.Evidence["old-score"])
+}
+
 func (s *testReplicaCheckerSuite) TestLostStore(c *C) {
 	opt := mockoption.NewScheduleOptions()
 	tc := mockcluster.NewCluster(opt)
@@ -1099,6 +1386,34 @@ func (s *testReplicaCheckerSuite) TestStorageThreshold(c *C) {
 	testutil.CheckAddPeer(c, rc.Check(region), operator.OpReplica, 2)
 }
 
+func (s *testReplicaCheckerSuite) TestStorageSpaceFloor(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	rc := checker.NewReplicaChecker(tc)
+
+	tc.AddRegionStore(1, 1)
+	tc.AddRegionStore(2, 1)
+	tc.AddRegionStore(3, 0)
+	tc.UpdateStoreRegionSize(3, 100*MB)
+	tc.UpdateStorageRatio(3, 0.1, 0.9)
+	tc.AddRegionStore(4, 0)
+	tc.UpdateStoreRegionSize(4, 500*MB)
+	tc.UpdateStorageRatio(4, 0.5, 0.5)
+
+	tc.AddLeaderRegion(1, 1, 2)
+	region := tc.GetRegion(1)
+	testutil.CheckAddPeer(c, rc.Check(region), operator.OpReplica, 3)
+
+	// Store 3 is below its own floor, though it has more free space.
+	store := tc.GetStore(3)
+	tc.PutStore(store.Clone(core.SetStoreSpaceFloor(&core.StoreSpaceFloor{MinFreePercent: 95})))
+	testutil.CheckAddPeer(c, rc.Check(region), operator.OpReplica, 4)
+	tc.PutStore(store.Clone(core.SetStoreSpaceFloor(&core.StoreSpaceFloor{MinFreeBytes: 1 << 30})))
+	testutil.CheckAddPeer(c, rc.Check(region), operator.OpReplica, 4)
+	tc.PutStore(store.Clone(core.SetStoreSpaceFloor(&core.StoreSpaceFloor{MinFreePercent: 50})))
+	testutil.CheckAddPeer(c, rc.Check(region), operator.OpReplica, 3)
+}
+
 func (s *testReplicaCheckerSuite) TestOpts(c *C) {
 	opt := mockoption.NewScheduleOptions()
 	tc := mockcluster.NewCluster(opt)
@@ -1276,6 +1591,93 @@ func (s *testScatterRangeLeaderSuite) TestConcurrencyUpdateConfig(c *C) {
 	ch <- struct{}{}
 }
 
+// removeSchedulerRecorder records the schedulers removed from the cluster.
+type removeSchedulerRecorder struct {
+	*mockcluster.Cluster
+	removed []string
+}
+
+func (r *removeSchedulerRecorder) RemoveScheduler(name string) error {
+	r.removed = append(r.removed, name)
+	return nil
+}
+
+func (s *testScatterRangeLeaderSuite) TestAutoComplete(c *C) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	tc.AddRegionStore(1, 0)
+	tc.AddRegionStore(2, 0)
+	tc.AddRegionStore(3, 0)
+	// All the leaders of the range are on store 1.
+	for i := 0; i < 12; i++ {
+		id := uint64(i)*4 + 4
+		meta := &metapb.Region{
+			Id:       id,
+			Peers:    []*metapb.Peer{{Id: id + 1, StoreId: 1}, {Id: id + 2, StoreId: 2}, {Id: id + 3, StoreId: 3}},
+			StartKey: []byte(fmt.Sprintf("s_%02d", i)),
+			EndKey:   []byte(fmt.Sprintf("s_%02d", i+1)),
+		}
+		tc.PutRegion(core.NewRegionInfo(meta, meta.Peers[0], core.SetApproximateSize(96), core.SetApproximateKeys(96)))
+	}
+	for i := uint64(1); i <= 3; i++ {
+		tc.UpdateStoreStatus(i)
+	}
+	recorder := &removeSchedulerRecorder{Cluster: tc}
+
+	storage := core.NewStorage(kv.NewMemoryKV())
+	oc := schedule.NewOperatorController(s.ctx, nil, nil)
+	hb, err := schedule.CreateScheduler(ScatterRangeType, oc, storage, schedule.ConfigSliceDecoder(ScatterRangeType, []string{"s_00", "s_12", "t"}))
+	c.Assert(err, IsNil)
+	sche := hb.(*scatterRangeScheduler)
+	now := time.Now()
+	sche.now = func() time.Time { return now }
+
+	post := func(body string) int {
+		w := httptest.NewRecorder()
+		sche.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/config", bytes.NewBufferString(body)))
+		return w.Code
+	}
+	c.Assert(post(`{"auto-complete": true, "max-score-spread": 1}`), Equals, http.StatusBadRequest)
+	c.Assert(post(`{"auto-complete": true, "stable-intervals": 0}`), Equals, http.StatusBadRequest)
+	c.Assert(post(`{"auto-complete": true, "auto-remove": true, "max-score-spread": 0.5, "stable-intervals": 3}`), Equals, http.StatusOK)
+	status := func() map[string]interface{} {
+		var status map[string]interface{}
+		w := httptest.NewRecorder()
+		sche.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/list", nil))
+		c.Assert(w.Code, Equals, http.StatusOK)
+		c.Assert(json.Unmarshal(w.Body.Bytes(), &status), IsNil)
+		return status
+	}
+	c.Assert(status()["status"], Equals, "active")
+
+	var completedAt int
+	for i := 1; i <= 100; i++ {
+		now = now.Add(time.Minute)
+		ops := sche.Schedule(recorder)
+		for _, op := range ops {
+			schedule.ApplyOperator(tc, op)
+		}
+		if completedAt == 0 && sche.config.isCompleted() {
+			completedAt = i
+		}
+	}
+	// The range is stable once the leaders are balanced to 6:3:3, and it is
+	// completed after 3 stable evaluations.
+	c.Assert(completedAt, Greater, 3)
+	c.Assert(getScoreSpread(schedule.GenRangeCluster(tc, []byte("s_00"), []byte("s_12"))), LessEqual, 0.5)
+	c.Assert(recorder.removed, DeepEquals, []string{"scatter-range-t"})
+	c.Assert(status()["status"], Equals, "completed")
+	data, err := storage.LoadScheduleConfig(sche.GetName())
+	c.Assert(err, IsNil)
+	conf := &scatterRangeSchedulerConfig{}
+	c.Assert(schedule.DecodeConfig([]byte(data), conf), IsNil)
+	c.Assert(conf.Completed, IsTrue)
+
+	// Updating the config restarts the completed scheduler.
+	c.Assert(post(`{"auto-complete": false}`), Equals, http.StatusOK)
+	c.Assert(status()["status"], Equals, "active")
+}
+
 func (s *testScatterRangeLeaderSuite) TestBalanceWhenRegionNotHeartbeat(c *C) {
 	opt := mockoption.NewScheduleOptions()
 	tc := mockcluster.NewCluster(opt)
@@ -74,6 +74,11 @@ func (s *BaseScheduler) GetMinInterval() time.Duration {
 	return MinScheduleInterval
 }
 
+// GetEffectiveConfig returns no item for the scheduler
+func (s *BaseScheduler) GetEffectiveConfig(cluster opt.Cluster) []*schedule.EffectiveConfigItem {
+	return nil
+}
+
 // EncodeConfig encode config for the scheduler
 func (s *BaseScheduler) EncodeConfig() ([]byte, error) {
 	return schedule.EncodeConfig(nil)
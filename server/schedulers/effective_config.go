@@ -0,0 +1,63 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"go.uber.org/zap"
+)
+
+func clusterConfigItem(name string, value interface{}) *schedule.EffectiveConfigItem {
+	return &schedule.EffectiveConfigItem{Name: name, Value: value, Source: schedule.ConfigSourceCluster}
+}
+
+type configEncoder interface {
+	EncodeConfig() ([]byte, error)
+}
+
+// schedulerConfigItems returns the items of the persisted config of the
+// scheduler sorted by the name. The items equal to the ones of the defaults
+// are from the default, and defaults can be nil if the scheduler has none.
+func schedulerConfigItems(name string, conf configEncoder, defaults interface{}) []*schedule.EffectiveConfigItem {
+	decode := func(data []byte, err error) map[string]json.RawMessage {
+		var m map[string]json.RawMessage
+		if err == nil {
+			err = json.Unmarshal(data, &m)
+		}
+		if err != nil {
+			log.Error("failed to decode the scheduler config", zap.String("scheduler", name), zap.Error(err))
+		}
+		return m
+	}
+	items := decode(conf.EncodeConfig())
+	var defaultConf map[string]json.RawMessage
+	if defaults != nil {
+		defaultConf = decode(json.Marshal(defaults))
+	}
+	res := make([]*schedule.EffectiveConfigItem, 0, len(items))
+	for key, value := range items {
+		item := &schedule.EffectiveConfigItem{Name: key, Value: value, Source: schedule.ConfigSourceScheduler}
+		if d, ok := defaultConf[key]; ok && bytes.Equal(d, value) {
+			item.Source = schedule.ConfigSourceDefault
+		}
+		res = append(res, item)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
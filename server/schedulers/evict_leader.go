@@ -15,7 +15,9 @@ package schedulers
 
 import (
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/gorilla/mux"
@@ -26,6 +28,7 @@ import (
 	"github.com/pingcap/pd/v4/server/schedule/filter"
 	"github.com/pingcap/pd/v4/server/schedule/operator"
 	"github.com/pingcap/pd/v4/server/schedule/opt"
+	"github.com/pingcap/pd/v4/server/schedule/placement"
 	"github.com/pingcap/pd/v4/server/schedule/selector"
 	"github.com/pkg/errors"
 	"github.com/unrolled/render"
@@ -43,6 +46,39 @@ const (
 	lastStoreDeleteInfo  = "The last store has been deleted"
 )
 
+// ParseStoreLabelSelector parses a label selector in the format of
+// "key1=value1,key2=value2", which selects the stores having all the labels.
+// It returns the selector in the canonical format, with the labels sorted by key.
+func ParseStoreLabelSelector(selector string) (string, error) {
+	labels := strings.Split(selector, ",")
+	for i, label := range labels {
+		kv := strings.Split(label, "=")
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return "", errors.Errorf("invalid label selector %s", selector)
+		}
+		labels[i] = strings.TrimSpace(kv[0]) + "=" + strings.TrimSpace(kv[1])
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ","), nil
+}
+
+// isStoreLabelSelector checks whether the scheduler argument is a label
+// selector rather than a store ID.
+func isStoreLabelSelector(arg string) bool {
+	return strings.Contains(arg, "=")
+}
+
+// matchStoreLabelSelector checks whether the store has all the labels of the
+// canonical selector.
+func matchStoreLabelSelector(store *core.StoreInfo, selector string) bool {
+	var constraints []placement.LabelConstraint
+	for _, label := range strings.Split(selector, ",") {
+		kv := strings.SplitN(label, "=", 2)
+		constraints = append(constraints, placement.LabelConstraint{Key: kv[0], Op: placement.In, Values: []string{kv[1]}})
+	}
+	return placement.MatchLabelConstraints(store, constraints)
+}
+
 func init() {
 	schedule.RegisterSliceDecoderBuilder(EvictLeaderType, func(args []string) schedule.ConfigDecoder {
 		return func(v interface{}) error {
@@ -54,6 +90,14 @@ func init() {
 				return ErrScheduleConfigNotExist
 			}
 
+			if isStoreLabelSelector(args[0]) {
+				selector, err := ParseStoreLabelSelector(args[0])
+				if err != nil {
+					return err
+				}
+				conf.Selectors = append(conf.Selectors, selector)
+				return nil
+			}
 			id, err := strconv.ParseUint(args[0], 10, 64)
 			if err != nil {
 				return errors.WithStack(err)
@@ -82,7 +126,14 @@ type evictLeaderSchedulerConfig struct {
 	mu                sync.RWMutex
 	storage           *core.Storage
 	StoreIDWithRanges map[uint64][]core.KeyRange `json:"store-id-ranges"`
-	cluster           opt.Cluster
+	// Selectors are the label selectors of the stores to evict leaders from.
+	// They are re-resolved when scheduling, so that the new stores matching
+	// the selectors are evicted too.
+	Selectors []string `json:"selectors,omitempty"`
+	// selectedStores are the stores resolved from Selectors, excluding the
+	// stores in StoreIDWithRanges.
+	selectedStores map[uint64]struct{}
+	cluster        opt.Cluster
 }
 
 func (conf *evictLeaderSchedulerConfig) BuildWithArgs(args []string) error {
@@ -90,6 +141,21 @@ func (conf *evictLeaderSchedulerConfig) BuildWithArgs(args []string) error {
 		return errors.New("should specify the store-id")
 	}
 
+	if isStoreLabelSelector(args[0]) {
+		selector, err := ParseStoreLabelSelector(args[0])
+		if err != nil {
+			return err
+		}
+		conf.mu.Lock()
+		defer conf.mu.Unlock()
+		for _, s := range conf.Selectors {
+			if s == selector {
+				return nil
+			}
+		}
+		conf.Selectors = append(conf.Selectors, selector)
+		return nil
+	}
 	id, err := strconv.ParseUint(args[0], 10, 64)
 	if err != nil {
 		return errors.WithStack(err)
@@ -109,6 +175,7 @@ func (conf *evictLeaderSchedulerConfig) Clone() *evictLeaderSchedulerConfig {
 	defer conf.mu.RUnlock()
 	return &evictLeaderSchedulerConfig{
 		StoreIDWithRanges: conf.StoreIDWithRanges,
+		Selectors:         append(conf.Selectors[:0:0], conf.Selectors...),
 	}
 }
 
@@ -149,11 +216,69 @@ func (conf *evictLeaderSchedulerConfig) mayBeRemoveStoreFromConfig(id uint64) (s
 		delete(conf.StoreIDWithRanges, id)
 		conf.cluster.UnblockStore(id)
 		succ = true
-		last = len(conf.StoreIDWithRanges) == 0
+		last = len(conf.StoreIDWithRanges) == 0 && len(conf.Selectors) == 0
 	}
 	return succ, last
 }
 
+func (conf *evictLeaderSchedulerConfig) mayBeRemoveSelectorFromConfig(selector string) (succ bool, last bool) {
+	conf.mu.Lock()
+	defer conf.mu.Unlock()
+	for i, s := range conf.Selectors {
+		if s == selector {
+			conf.Selectors = append(conf.Selectors[:i], conf.Selectors[i+1:]...)
+			// Unblocks the stores which are no longer selected.
+			conf.resolveSelectors(conf.cluster)
+			return true, len(conf.StoreIDWithRanges) == 0 && len(conf.Selectors) == 0
+		}
+	}
+	return false, false
+}
+
+// isSelected checks whether the store is resolved from the selectors.
+func (conf *evictLeaderSchedulerConfig) isSelected(id uint64) bool {
+	conf.mu.RLock()
+	defer conf.mu.RUnlock()
+	_, ok := conf.selectedStores[id]
+	return ok
+}
+
+// resolveSelectors resolves the selectors to the stores. It blocks the newly
+// selected stores, and unblocks the stores which are no longer selected. The
+// caller should hold the write lock.
+func (conf *evictLeaderSchedulerConfig) resolveSelectors(cluster opt.Cluster) {
+	selected := make(map[uint64]struct{})
+	if len(conf.Selectors) > 0 {
+		for _, store := range cluster.GetStores() {
+			if _, ok := conf.StoreIDWithRanges[store.GetID()]; ok || store.IsTombstone() {
+				continue
+			}
+			for _, selector := range conf.Selectors {
+				if matchStoreLabelSelector(store, selector) {
+					selected[store.GetID()] = struct{}{}
+					break
+				}
+			}
+		}
+	}
+	for id := range conf.selectedStores {
+		_, stillSelected := selected[id]
+		_, explicit := conf.StoreIDWithRanges[id]
+		if !stillSelected && !explicit {
+			cluster.UnblockStore(id)
+		}
+	}
+	for id := range selected {
+		if _, ok := conf.selectedStores[id]; !ok {
+			if err := cluster.BlockStore(id); err != nil {
+				log.Warn("failed to block the selected store", zap.Uint64("store-id", id), zap.Error(err))
+			}
+			log.Info("evict leaders from the store selected by labels", zap.Uint64("store-id", id))
+		}
+	}
+	conf.selectedStores = selected
+}
+
 type evictLeaderScheduler struct {
 	*BaseScheduler
 	conf     *evictLeaderSchedulerConfig
@@ -197,14 +322,15 @@ func (s *evictLeaderScheduler) EncodeConfig() ([]byte, error) {
 }
 
 func (s *evictLeaderScheduler) Prepare(cluster opt.Cluster) error {
-	s.conf.mu.RLock()
-	defer s.conf.mu.RUnlock()
+	s.conf.mu.Lock()
+	defer s.conf.mu.Unlock()
 	var res error
 	for id := range s.conf.StoreIDWithRanges {
 		if err := cluster.BlockStore(id); err != nil {
 			res = err
 		}
 	}
+	s.conf.resolveSelectors(cluster)
 	return res
 }
 
@@ -214,6 +340,16 @@ func (s *evictLeaderScheduler) Cleanup(cluster opt.Cluster) {
 	for id := range s.conf.StoreIDWithRanges {
 		cluster.UnblockStore(id)
 	}
+	for id := range s.conf.selectedStores {
+		cluster.UnblockStore(id)
+	}
+}
+
+func (s *evictLeaderScheduler) GetEffectiveConfig(cluster opt.Cluster) []*schedule.EffectiveConfigItem {
+	items := []*schedule.EffectiveConfigItem{
+		clusterConfigItem("leader-schedule-limit", cluster.GetLeaderScheduleLimit()),
+	}
+	return append(items, schedulerConfigItems(s.GetName(), s, nil)...)
 }
 
 func (s *evictLeaderScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
@@ -223,28 +359,39 @@ func (s *evictLeaderScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
 func (s *evictLeaderScheduler) scheduleOnce(cluster opt.Cluster) []*operator.Operator {
 	var ops []*operator.Operator
 	for id, ranges := range s.conf.StoreIDWithRanges {
-		region := cluster.RandLeaderRegion(id, ranges, opt.HealthRegion(cluster))
-		if region == nil {
-			schedulerCounter.WithLabelValues(s.GetName(), "no-leader").Inc()
-			continue
+		if op := s.scheduleStore(cluster, id, ranges); op != nil {
+			ops = append(ops, op)
 		}
-		target := s.selector.SelectTarget(cluster, cluster.GetFollowerStores(region))
-		if target == nil {
-			schedulerCounter.WithLabelValues(s.GetName(), "no-target-store").Inc()
-			continue
-		}
-		op, err := operator.CreateTransferLeaderOperator(EvictLeaderType, cluster, region, region.GetLeader().GetStoreId(), target.GetID(), operator.OpLeader)
-		if err != nil {
-			log.Debug("fail to create evict leader operator", zap.Error(err))
-			continue
+	}
+	for id := range s.conf.selectedStores {
+		if op := s.scheduleStore(cluster, id, []core.KeyRange{core.NewKeyRange("", "")}); op != nil {
+			ops = append(ops, op)
 		}
-		op.SetPriorityLevel(core.HighPriority)
-		op.Counters = append(op.Counters, schedulerCounter.WithLabelValues(s.GetName(), "new-operator"))
-		ops = append(ops, op)
 	}
 	return ops
 }
 
+func (s *evictLeaderScheduler) scheduleStore(cluster opt.Cluster, id uint64, ranges []core.KeyRange) *operator.Operator {
+	region := cluster.RandLeaderRegion(id, ranges, opt.HealthRegion(cluster))
+	if region == nil {
+		schedulerCounter.WithLabelValues(s.GetName(), "no-leader").Inc()
+		return nil
+	}
+	target := s.selector.SelectTarget(cluster, cluster.GetFollowerStores(region))
+	if target == nil {
+		schedulerCounter.WithLabelValues(s.GetName(), "no-target-store").Inc()
+		return nil
+	}
+	op, err := operator.CreateTransferLeaderOperator(EvictLeaderType, cluster, region, region.GetLeader().GetStoreId(), target.GetID(), operator.OpLeader)
+	if err != nil {
+		log.Debug("fail to create evict leader operator", zap.Error(err))
+		return nil
+	}
+	op.SetPriorityLevel(core.HighPriority)
+	op.Counters = append(op.Counters, schedulerCounter.WithLabelValues(s.GetName(), "new-operator"))
+	return op
+}
+
 func (s *evictLeaderScheduler) uniqueAppend(dst []*operator.Operator, src ...*operator.Operator) []*operator.Operator {
 	regionIDs := make(map[uint64]struct{})
 	for i := range dst {
@@ -263,6 +410,10 @@ func (s *evictLeaderScheduler) uniqueAppend(dst []*operator.Operator, src ...*op
 func (s *evictLeaderScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
 	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
 	var ops []*operator.Operator
+	// Re-resolves the selectors to evict leaders from the new stores matching them.
+	s.conf.mu.Lock()
+	s.conf.resolveSelectors(cluster)
+	s.conf.mu.Unlock()
 	s.conf.mu.RLock()
 	defer s.conf.mu.RUnlock()
 
@@ -282,6 +433,45 @@ func (s *evictLeaderScheduler) Schedule(cluster opt.Cluster) []*operator.Operato
 	return ops
 }
 
+// LeaderEvictor is the scheduler evicting the leaders from the stores on
+// demand.
+type LeaderEvictor interface {
+	// EvictLeader starts to evict all the leaders from the store. It returns
+	// false if the store is evicted already.
+	EvictLeader(storeID uint64) (bool, error)
+	// UnevictLeader stops evicting the leaders from the store. It returns true
+	// if no store is evicted any more, and then the scheduler can be removed.
+	// It returns ErrScheduleConfigNotExist if the store is not evicted.
+	UnevictLeader(storeID uint64) (bool, error)
+}
+
+func (s *evictLeaderScheduler) EvictLeader(storeID uint64) (bool, error) {
+	s.conf.mu.RLock()
+	_, exists := s.conf.StoreIDWithRanges[storeID]
+	s.conf.mu.RUnlock()
+	if exists {
+		return false, nil
+	}
+	// The store selected by labels has been blocked.
+	if !s.conf.isSelected(storeID) {
+		if err := s.conf.cluster.BlockStore(storeID); err != nil {
+			return false, err
+		}
+	}
+	if err := s.conf.BuildWithArgs([]string{strconv.FormatUint(storeID, 10)}); err != nil {
+		return false, err
+	}
+	return true, s.conf.Persist()
+}
+
+func (s *evictLeaderScheduler) UnevictLeader(storeID uint64) (bool, error) {
+	succ, last := s.conf.mayBeRemoveStoreFromConfig(storeID)
+	if !succ {
+		return false, ErrScheduleConfigNotExist
+	}
+	return last, s.conf.Persist()
+}
+
 type evictLeaderHandler struct {
 	rd     *render.Render
 	config *evictLeaderSchedulerConfig
@@ -292,13 +482,27 @@ func (handler *evictLeaderHandler) UpdateConfig(w http.ResponseWriter, r *http.R
 	if err := apiutil.ReadJSONRespondError(handler.rd, w, r.Body, &input); err != nil {
 		return
 	}
+	if selector, ok := input["selector"].(string); ok {
+		if err := handler.config.BuildWithArgs([]string{selector}); err != nil {
+			handler.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := handler.config.Persist(); err != nil {
+			handler.rd.JSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		handler.rd.JSON(w, http.StatusOK, nil)
+		return
+	}
+
 	var args []string
 	var exists bool
 	var id uint64
 	idFloat, ok := input["store_id"].(float64)
 	if ok {
 		id = (uint64)(idFloat)
-		if _, exists = handler.config.StoreIDWithRanges[id]; !exists {
+		// The store selected by labels has been blocked.
+		if _, exists = handler.config.StoreIDWithRanges[id]; !exists && !handler.config.isSelected(id) {
 			if err := handler.config.cluster.BlockStore(id); err != nil {
 				handler.rd.JSON(w, http.StatusInternalServerError, err)
 				return
@@ -358,6 +562,33 @@ func (handler *evictLeaderHandler) DeleteConfig(w http.ResponseWriter, r *http.R
 	handler.rd.JSON(w, http.StatusInternalServerError, ErrScheduleConfigNotExist)
 }
 
+func (handler *evictLeaderHandler) DeleteSelector(w http.ResponseWriter, r *http.Request) {
+	selector, err := ParseStoreLabelSelector(mux.Vars(r)["selector"])
+	if err != nil {
+		handler.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var resp interface{}
+	succ, last := handler.config.mayBeRemoveSelectorFromConfig(selector)
+	if !succ {
+		handler.rd.JSON(w, http.StatusInternalServerError, ErrScheduleConfigNotExist)
+		return
+	}
+	if err := handler.config.Persist(); err != nil {
+		handler.rd.JSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	if last {
+		if err := handler.config.cluster.RemoveScheduler(EvictLeaderName); err != nil {
+			handler.rd.JSON(w, http.StatusInternalServerError, err)
+			return
+		}
+		resp = lastStoreDeleteInfo
+	}
+	handler.rd.JSON(w, http.StatusOK, resp)
+}
+
 func newEvictLeaderHandler(config *evictLeaderSchedulerConfig) http.Handler {
 	h := &evictLeaderHandler{
 		config: config,
@@ -367,5 +598,6 @@ func newEvictLeaderHandler(config *evictLeaderSchedulerConfig) http.Handler {
 	router.HandleFunc("/config", h.UpdateConfig).Methods("POST")
 	router.HandleFunc("/list", h.ListConfig).Methods("GET")
 	router.HandleFunc("/delete/{store_id}", h.DeleteConfig).Methods("DELETE")
+	router.HandleFunc("/delete-selector/{selector}", h.DeleteSelector).Methods("DELETE")
 	return router
 }
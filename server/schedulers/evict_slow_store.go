@@ -0,0 +1,530 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/pkg/typeutil"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/schedule/filter"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+	"github.com/pingcap/pd/v4/server/schedule/opt"
+	"github.com/pingcap/pd/v4/server/schedule/selector"
+	"github.com/pkg/errors"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+const (
+	// EvictSlowStoreName is evict slow store scheduler name.
+	EvictSlowStoreName = "evict-slow-store-scheduler"
+	// EvictSlowStoreType is evict slow store scheduler type.
+	EvictSlowStoreType = "evict-slow-store"
+
+	// slowStoreMinStores is the min number of the stores reporting the
+	// latency to tell an outlier.
+	slowStoreMinStores = 3
+	// slowStoreHysteresis is the number of the consecutive heartbeats of a
+	// store to be detected as slow or recovered, which prevents flapping.
+	slowStoreHysteresis = 3
+)
+
+// slowStoreLatencyKeys are the keys of the operation latencies in the store
+// heartbeats which are used to detect the slow stores, in milliseconds.
+var slowStoreLatencyKeys = []string{"apply", "commit"}
+
+func init() {
+	schedule.RegisterSliceDecoderBuilder(EvictSlowStoreType, func(args []string) schedule.ConfigDecoder {
+		return func(v interface{}) error {
+			return nil
+		}
+	})
+	schedule.RegisterScheduler(EvictSlowStoreType, func(opController *schedule.OperatorController, storage *core.Storage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
+		conf := initEvictSlowStoreSchedulerConfig()
+		if err := decoder(conf); err != nil {
+			return nil, err
+		}
+		conf.storage = storage
+		return newEvictSlowStoreScheduler(opController, conf), nil
+	})
+}
+
+func initEvictSlowStoreSchedulerConfig() *evictSlowStoreSchedulerConfig {
+	return &evictSlowStoreSchedulerConfig{
+		SlowRatio:        3,
+		RecoverRatio:     1.5,
+		MinLatency:       100,
+		MaxEvictDuration: typeutil.NewDuration(30 * time.Minute),
+	}
+}
+
+type evictSlowStoreSchedulerConfig struct {
+	sync.RWMutex
+	storage *core.Storage
+
+	// A store is slow if its latency is at least SlowRatio times the median
+	// latency of the other stores and MinLatency milliseconds.
+	SlowRatio  float64 `json:"slow-ratio"`
+	MinLatency float64 `json:"min-latency"`
+	// An evicted store is recovered if its latency drops to at most
+	// RecoverRatio times the median latency of the other stores.
+	RecoverRatio     float64           `json:"recover-ratio"`
+	MaxEvictDuration typeutil.Duration `json:"max-evict-duration"`
+
+	// EvictedStore is the store whose leaders are being evicted, 0 if none.
+	EvictedStore   uint64    `json:"evicted-store"`
+	EvictStartTime time.Time `json:"evict-start-time"`
+}
+
+func (conf *evictSlowStoreSchedulerConfig) validate() error {
+	if conf.SlowRatio <= 1 {
+		return errors.New("slow-ratio should be greater than 1")
+	}
+	if conf.RecoverRatio < 1 || conf.RecoverRatio >= conf.SlowRatio {
+		return errors.New("recover-ratio should be in [1, slow-ratio)")
+	}
+	if conf.MinLatency < 0 {
+		return errors.New("min-latency should not be negative")
+	}
+	if conf.MaxEvictDuration.Duration <= 0 {
+		return errors.New("max-evict-duration should be positive")
+	}
+	return nil
+}
+
+// setRules copies the rules of detecting the slow store from another config.
+func (conf *evictSlowStoreSchedulerConfig) setRules(other *evictSlowStoreSchedulerConfig) {
+	conf.SlowRatio = other.SlowRatio
+	conf.MinLatency = other.MinLatency
+	conf.RecoverRatio = other.RecoverRatio
+	conf.MaxEvictDuration = other.MaxEvictDuration
+}
+
+func (conf *evictSlowStoreSchedulerConfig) getEvictedStore() (uint64, time.Time) {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.EvictedStore, conf.EvictStartTime
+}
+
+func (conf *evictSlowStoreSchedulerConfig) setEvictedStore(id uint64, start time.Time) error {
+	conf.Lock()
+	defer conf.Unlock()
+	conf.EvictedStore, conf.EvictStartTime = id, start
+	return conf.persist()
+}
+
+func (conf *evictSlowStoreSchedulerConfig) persist() error {
+	data, err := schedule.EncodeConfig(conf)
+	if err != nil {
+		return err
+	}
+	return conf.storage.SaveScheduleConfig(EvictSlowStoreName, data)
+}
+
+// slowStoreCandidate is a store observed as slow or recovered by consecutive
+// heartbeats.
+type slowStoreCandidate struct {
+	storeID       uint64
+	rounds        int
+	lastHeartbeat time.Time
+}
+
+// observe counts the heartbeat of the store, the same heartbeat is counted once.
+func (c *slowStoreCandidate) observe(store *core.StoreInfo) {
+	if c.storeID != store.GetID() {
+		*c = slowStoreCandidate{storeID: store.GetID()}
+	}
+	if store.GetLastHeartbeatTS().After(c.lastHeartbeat) {
+		c.rounds++
+		c.lastHeartbeat = store.GetLastHeartbeatTS()
+	}
+}
+
+type evictSlowStoreScheduler struct {
+	*BaseScheduler
+	conf     *evictSlowStoreSchedulerConfig
+	selector *selector.RandomSelector
+	handler  http.Handler
+
+	mu sync.RWMutex
+	// slow is the store detected as slow, and recovered is the evicted store
+	// detected as recovered.
+	slow      slowStoreCandidate
+	recovered slowStoreCandidate
+	// expiredStore is the store evicted for the max duration, which is not
+	// evicted again until it recovers.
+	expiredStore uint64
+}
+
+// newEvictSlowStoreScheduler creates a scheduler that detects the store much
+// slower than the others and evicts its leaders automatically. At most one
+// store is evicted at a time.
+func newEvictSlowStoreScheduler(opController *schedule.OperatorController, conf *evictSlowStoreSchedulerConfig) schedule.Scheduler {
+	filters := []filter.Filter{
+		filter.StoreStateFilter{ActionScope: EvictSlowStoreName, TransferLeader: true},
+	}
+	s := &evictSlowStoreScheduler{
+		BaseScheduler: NewBaseScheduler(opController),
+		conf:          conf,
+		selector:      selector.NewRandomSelector(filters),
+	}
+	s.handler = newEvictSlowStoreHandler(s)
+	return s
+}
+
+func (s *evictSlowStoreScheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *evictSlowStoreScheduler) GetName() string {
+	return EvictSlowStoreName
+}
+
+func (s *evictSlowStoreScheduler) GetType() string {
+	return EvictSlowStoreType
+}
+
+func (s *evictSlowStoreScheduler) EncodeConfig() ([]byte, error) {
+	s.conf.RLock()
+	defer s.conf.RUnlock()
+	return schedule.EncodeConfig(s.conf)
+}
+
+func (s *evictSlowStoreScheduler) Prepare(cluster opt.Cluster) error {
+	if id, _ := s.conf.getEvictedStore(); id != 0 {
+		return cluster.BlockStore(id)
+	}
+	return nil
+}
+
+func (s *evictSlowStoreScheduler) Cleanup(cluster opt.Cluster) {
+	if id, _ := s.conf.getEvictedStore(); id != 0 {
+		cluster.UnblockStore(id)
+	}
+}
+
+func (s *evictSlowStoreScheduler) GetEffectiveConfig(cluster opt.Cluster) []*schedule.EffectiveConfigItem {
+	items := []*schedule.EffectiveConfigItem{
+		clusterConfigItem("leader-schedule-limit", cluster.GetLeaderScheduleLimit()),
+	}
+	return append(items, schedulerConfigItems(s.GetName(), s, initEvictSlowStoreSchedulerConfig())...)
+}
+
+func (s *evictSlowStoreScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
+	return s.OpController.OperatorCount(operator.OpLeader) < cluster.GetLeaderScheduleLimit()
+}
+
+// getStoreLatency returns the larger of the apply and commit latencies reported
+// by the store, in milliseconds. A store not reporting them is not judged, as
+// the heartbeat timestamps are by the store's clock, which may skew from PD's.
+func getStoreLatency(store *core.StoreInfo) (float64, bool) {
+	stats := store.GetStoreStats()
+	var latency uint64
+	var reported bool
+	for _, pair := range stats.GetOpLatencies() {
+		for _, key := range slowStoreLatencyKeys {
+			if pair.GetKey() == key {
+				reported = true
+				if pair.GetValue() > latency {
+					latency = pair.GetValue()
+				}
+			}
+		}
+	}
+	return float64(latency), reported
+}
+
+// getStoreLatencies returns the latencies of the up stores which are
+// heartbeating normally.
+func getStoreLatencies(cluster opt.Cluster) map[uint64]float64 {
+	latencies := make(map[uint64]float64)
+	for _, store := range cluster.GetStores() {
+		if !store.IsUp() || store.IsDisconnected() {
+			continue
+		}
+		if latency, ok := getStoreLatency(store); ok {
+			latencies[store.GetID()] = latency
+		}
+	}
+	return latencies
+}
+
+// medianLatencyExcept returns the median latency of the stores except the given one.
+func medianLatencyExcept(latencies map[uint64]float64, storeID uint64) float64 {
+	others := make([]float64, 0, len(latencies))
+	for id, latency := range latencies {
+		if id != storeID {
+			others = append(others, latency)
+		}
+	}
+	if len(others) == 0 {
+		return 0
+	}
+	sort.Float64s(others)
+	n := len(others)
+	if n%2 == 1 {
+		return others[n/2]
+	}
+	return (others[n/2-1] + others[n/2]) / 2
+}
+
+// detectSlowStore returns the only store which is much slower than the
+// others, or 0 if there is no such store or there are more than one.
+func (s *evictSlowStoreScheduler) detectSlowStore(latencies map[uint64]float64) uint64 {
+	if len(latencies) < slowStoreMinStores {
+		return 0
+	}
+	s.conf.RLock()
+	slowRatio, minLatency := s.conf.SlowRatio, s.conf.MinLatency
+	s.conf.RUnlock()
+	var slow uint64
+	for id, latency := range latencies {
+		if latency >= minLatency && latency >= slowRatio*medianLatencyExcept(latencies, id) {
+			if slow != 0 {
+				return 0
+			}
+			slow = id
+		}
+	}
+	return slow
+}
+
+func (s *evictSlowStoreScheduler) isRecovered(latencies map[uint64]float64, storeID uint64) bool {
+	latency, ok := latencies[storeID]
+	if !ok {
+		return false
+	}
+	s.conf.RLock()
+	recoverRatio, minLatency := s.conf.RecoverRatio, s.conf.MinLatency
+	s.conf.RUnlock()
+	return latency < minLatency || latency <= recoverRatio*medianLatencyExcept(latencies, storeID)
+}
+
+// GetSlowStore returns the store currently detected as slow, and the number
+// of consecutive heartbeats it is detected.
+func (s *evictSlowStoreScheduler) GetSlowStore() (uint64, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.slow.storeID, s.slow.rounds
+}
+
+func (s *evictSlowStoreScheduler) startEviction(cluster opt.Cluster, storeID uint64) {
+	if err := cluster.BlockStore(storeID); err != nil {
+		log.Warn("failed to block the slow store", zap.Uint64("store-id", storeID), zap.Error(err))
+		return
+	}
+	if err := s.conf.setEvictedStore(storeID, time.Now()); err != nil {
+		log.Warn("failed to persist the evicted slow store", zap.Uint64("store-id", storeID), zap.Error(err))
+	}
+	s.slow = slowStoreCandidate{}
+	s.recovered = slowStoreCandidate{}
+	schedulerCounter.WithLabelValues(s.GetName(), "start-evict").Inc()
+	log.Info("start to evict leaders from the slow store", zap.Uint64("store-id", storeID))
+}
+
+func (s *evictSlowStoreScheduler) stopEviction(cluster opt.Cluster, storeID uint64, reason string) {
+	cluster.UnblockStore(storeID)
+	if err := s.conf.setEvictedStore(0, time.Time{}); err != nil {
+		log.Warn("failed to persist the evicted slow store", zap.Uint64("store-id", storeID), zap.Error(err))
+	}
+	s.recovered = slowStoreCandidate{}
+	schedulerCounter.WithLabelValues(s.GetName(), "stop-evict").Inc()
+	log.Info("stop evicting leaders from the slow store", zap.Uint64("store-id", storeID), zap.String("reason", reason))
+}
+
+// updateEviction detects the slow store, and starts or stops the eviction.
+// It returns the store to evict leaders from, or 0 if none.
+func (s *evictSlowStoreScheduler) updateEviction(cluster opt.Cluster) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	latencies := getStoreLatencies(cluster)
+	if s.expiredStore != 0 && s.isRecovered(latencies, s.expiredStore) {
+		s.expiredStore = 0
+	}
+
+	evicted, start := s.conf.getEvictedStore()
+	if evicted != 0 {
+		store := cluster.GetStore(evicted)
+		if store == nil || store.IsTombstone() {
+			s.stopEviction(cluster, evicted, "store removed")
+			return 0
+		}
+		s.conf.RLock()
+		maxDuration := s.conf.MaxEvictDuration.Duration
+		s.conf.RUnlock()
+		if time.Since(start) >= maxDuration {
+			s.expiredStore = evicted
+			s.stopEviction(cluster, evicted, "max evict duration elapsed")
+			return 0
+		}
+		if s.isRecovered(latencies, evicted) {
+			s.recovered.observe(store)
+		} else {
+			s.recovered = slowStoreCandidate{}
+		}
+		if s.recovered.rounds >= slowStoreHysteresis {
+			s.stopEviction(cluster, evicted, "store recovered")
+			return 0
+		}
+		return evicted
+	}
+
+	slow := s.detectSlowStore(latencies)
+	if slow == 0 || slow == s.expiredStore || cluster.GetStore(slow).IsBlocked() {
+		s.slow = slowStoreCandidate{}
+		return 0
+	}
+	if s.slow.storeID != slow {
+		schedulerCounter.WithLabelValues(s.GetName(), "slow-store").Inc()
+		log.Info("detect a slow store", zap.Uint64("store-id", slow), zap.Float64("latency", latencies[slow]))
+	}
+	s.slow.observe(cluster.GetStore(slow))
+	if s.slow.rounds >= slowStoreHysteresis {
+		s.startEviction(cluster, slow)
+		if evicted, _ = s.conf.getEvictedStore(); evicted != 0 {
+			return evicted
+		}
+	}
+	return 0
+}
+
+func (s *evictSlowStoreScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
+	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	evicted := s.updateEviction(cluster)
+	if evicted == 0 {
+		return nil
+	}
+	var ops []*operator.Operator
+	regionIDs := make(map[uint64]struct{})
+	for i := 0; i < EvictLeaderBatchSize; i++ {
+		region := cluster.RandLeaderRegion(evicted, []core.KeyRange{core.NewKeyRange("", "")}, opt.HealthRegion(cluster))
+		if region == nil {
+			schedulerCounter.WithLabelValues(s.GetName(), "no-leader").Inc()
+			break
+		}
+		if _, ok := regionIDs[region.GetID()]; ok {
+			continue
+		}
+		target := s.selector.SelectTarget(cluster, cluster.GetFollowerStores(region))
+		if target == nil {
+			schedulerCounter.WithLabelValues(s.GetName(), "no-target-store").Inc()
+			continue
+		}
+		op, err := operator.CreateTransferLeaderOperator(EvictSlowStoreType, cluster, region, evicted, target.GetID(), operator.OpLeader)
+		if err != nil {
+			log.Debug("fail to create evict slow store operator", zap.Error(err))
+			continue
+		}
+		op.SetPriorityLevel(core.HighPriority)
+		op.Counters = append(op.Counters, schedulerCounter.WithLabelValues(s.GetName(), "new-operator"))
+		regionIDs[region.GetID()] = struct{}{}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+type evictSlowStoreHandler struct {
+	rd        *render.Render
+	scheduler *evictSlowStoreScheduler
+}
+
+// evictSlowStoreStatus is the config of the scheduler with the store
+// currently detected as slow.
+type evictSlowStoreStatus struct {
+	*evictSlowStoreSchedulerConfig
+	SlowStore       uint64 `json:"slow-store"`
+	SlowStoreRounds int    `json:"slow-store-rounds"`
+}
+
+func (handler *evictSlowStoreHandler) ListConfig(w http.ResponseWriter, r *http.Request) {
+	status := &evictSlowStoreStatus{evictSlowStoreSchedulerConfig: handler.scheduler.conf}
+	status.SlowStore, status.SlowStoreRounds = handler.scheduler.GetSlowStore()
+	status.RLock()
+	defer status.RUnlock()
+	handler.rd.JSON(w, http.StatusOK, status)
+}
+
+func (handler *evictSlowStoreHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
+	data, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		handler.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var input struct {
+		SlowRatio        *float64           `json:"slow-ratio"`
+		MinLatency       *float64           `json:"min-latency"`
+		RecoverRatio     *float64           `json:"recover-ratio"`
+		MaxEvictDuration *typeutil.Duration `json:"max-evict-duration"`
+	}
+	if err = json.Unmarshal(data, &input); err != nil {
+		handler.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conf := handler.scheduler.conf
+	conf.Lock()
+	defer conf.Unlock()
+	updated := &evictSlowStoreSchedulerConfig{
+		SlowRatio:        conf.SlowRatio,
+		MinLatency:       conf.MinLatency,
+		RecoverRatio:     conf.RecoverRatio,
+		MaxEvictDuration: conf.MaxEvictDuration,
+	}
+	if input.SlowRatio != nil {
+		updated.SlowRatio = *input.SlowRatio
+	}
+	if input.MinLatency != nil {
+		updated.MinLatency = *input.MinLatency
+	}
+	if input.RecoverRatio != nil {
+		updated.RecoverRatio = *input.RecoverRatio
+	}
+	if input.MaxEvictDuration != nil {
+		updated.MaxEvictDuration = *input.MaxEvictDuration
+	}
+	if err = updated.validate(); err != nil {
+		handler.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	old := &evictSlowStoreSchedulerConfig{}
+	old.setRules(conf)
+	conf.setRules(updated)
+	if err = conf.persist(); err != nil {
+		conf.setRules(old) // revert
+		handler.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	handler.rd.JSON(w, http.StatusOK, nil)
+}
+
+func newEvictSlowStoreHandler(scheduler *evictSlowStoreScheduler) http.Handler {
+	h := &evictSlowStoreHandler{
+		scheduler: scheduler,
+		rd:        render.New(render.Options{IndentJSON: true}),
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/config", h.UpdateConfig).Methods("POST")
+	router.HandleFunc("/list", h.ListConfig).Methods("GET")
+	return router
+}
@@ -0,0 +1,251 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
+	"github.com/pingcap/pd/v4/pkg/mock/mockcluster"
+	"github.com/pingcap/pd/v4/pkg/mock/mockoption"
+	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/kv"
+	"github.com/pingcap/pd/v4/server/schedule"
+	"github.com/pingcap/pd/v4/server/schedule/operator"
+)
+
+var _ = Suite(&testEvictSlowStoreSuite{})
+
+type testEvictSlowStoreSuite struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (s *testEvictSlowStoreSuite) SetUpTest(c *C) {
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+}
+
+func (s *testEvictSlowStoreSuite) TearDownTest(c *C) {
+	s.cancel()
+}
+
+func (s *testEvictSlowStoreSuite) prepare(c *C) (*mockcluster.Cluster, *core.Storage, *evictSlowStoreScheduler) {
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	for id := uint64(1); id <= 4; id++ {
+		tc.AddLeaderStore(id, 0)
+	}
+	tc.AddLeaderRegion(1, 1, 2, 3)
+	tc.AddLeaderRegion(2, 1, 3, 4)
+	tc.AddLeaderRegion(3, 2, 1, 3)
+	storage := core.NewStorage(kv.NewMemoryKV())
+	sche, err := schedule.CreateScheduler(EvictSlowStoreType, schedule.NewOperatorController(s.ctx, tc, nil), storage, schedule.ConfigSliceDecoder(EvictSlowStoreType, nil))
+	c.Assert(err, IsNil)
+	c.Assert(sche.Prepare(tc), IsNil)
+	return tc, storage, sche.(*evictSlowStoreScheduler)
+}
+
+// heartbeat simulates the heartbeats of the stores 1 to 4 reporting the apply latencies.
+func heartbeatLatencies(tc *mockcluster.Cluster, latencies ...uint64) {
+	for i, latency := range latencies {
+		tc.UpdateStoreOpLatency(uint64(i+1), "apply", latency)
+	}
+}
+
+func (s *testEvictSlowStoreSuite) checkEvicting(c *C, tc *mockcluster.Cluster, sche *evictSlowStoreScheduler, storeID uint64) {
+	ops := sche.Schedule(tc)
+	if storeID == 0 {
+		c.Assert(ops, HasLen, 0)
+		for id := uint64(1); id <= 4; id++ {
+			c.Assert(tc.GetStore(id).IsBlocked(), IsFalse)
+		}
+		return
+	}
+	c.Assert(ops, Not(HasLen), 0)
+	for _, op := range ops {
+		c.Assert(op.Step(0).(operator.TransferLeader).FromStore, Equals, storeID)
+		c.Assert(op.GetPriorityLevel(), Equals, core.HighPriority)
+	}
+	c.Assert(tc.GetStore(storeID).IsBlocked(), IsTrue)
+}
+
+func (s *testEvictSlowStoreSuite) TestDetect(c *C) {
+	tc, _, sche := s.prepare(c)
+
+	// A single slow store is not evicted until detected by enough heartbeats.
+	for i := 1; i < slowStoreHysteresis; i++ {
+		heartbeatLatencies(tc, 500, 10, 10, 20)
+		s.checkEvicting(c, tc, sche, 0)
+		// The same heartbeat is counted once.
+		s.checkEvicting(c, tc, sche, 0)
+		id, rounds := sche.GetSlowStore()
+		c.Assert(id, Equals, uint64(1))
+		c.Assert(rounds, Equals, i)
+	}
+	// Flapping resets the detection.
+	heartbeatLatencies(tc, 10, 10, 10, 20)
+	s.checkEvicting(c, tc, sche, 0)
+	id, _ := sche.GetSlowStore()
+	c.Assert(id, Equals, uint64(0))
+
+	// The latencies below min-latency are not slow.
+	for i := 0; i < slowStoreHysteresis; i++ {
+		heartbeatLatencies(tc, 90, 1, 1, 1)
+		s.checkEvicting(c, tc, sche, 0)
+	}
+	// More than one slow store is not an outlier.
+	for i := 0; i < slowStoreHysteresis; i++ {
+		heartbeatLatencies(tc, 500, 500, 10, 10)
+		s.checkEvicting(c, tc, sche, 0)
+	}
+	// Too few stores reporting the latencies.
+	tc.SetStoreDown(3)
+	tc.SetStoreDown(4)
+	for i := 0; i < slowStoreHysteresis; i++ {
+		heartbeatLatencies(tc, 500, 10)
+		s.checkEvicting(c, tc, sche, 0)
+	}
+}
+
+func (s *testEvictSlowStoreSuite) TestStoreLatency(c *C) {
+	// The clock of the store is a minute behind or ahead of PD's.
+	now := time.Now()
+	for _, skew := range []time.Duration{-time.Minute, time.Minute} {
+		stats := &pdpb.StoreStats{Interval: &pdpb.TimeInterval{EndTimestamp: uint64(now.Add(skew).Unix())}}
+		store := core.NewStoreInfo(&metapb.Store{Id: 1}, core.SetStoreStats(stats), core.SetLastHeartbeatTS(now))
+		_, ok := getStoreLatency(store)
+		c.Assert(ok, IsFalse)
+
+		stats.OpLatencies = []*pdpb.RecordPair{{Key: "commit", Value: 30}, {Key: "apply", Value: 50}, {Key: "get", Value: 999}}
+		latency, ok := getStoreLatency(store.Clone(core.SetStoreStats(stats)))
+		c.Assert(ok, IsTrue)
+		c.Assert(latency, Equals, 50.0)
+	}
+}
+
+func (s *testEvictSlowStoreSuite) TestEvictAndRecover(c *C) {
+	tc, _, sche := s.prepare(c)
+
+	for i := 0; i < slowStoreHysteresis; i++ {
+		heartbeatLatencies(tc, 10, 10, 500, 20)
+		sche.Schedule(tc)
+	}
+	// Store 3 has no leader to evict.
+	c.Assert(tc.GetStore(3).IsBlocked(), IsTrue)
+	c.Assert(sche.Schedule(tc), HasLen, 0)
+	sche.Cleanup(tc)
+	c.Assert(tc.GetStore(3).IsBlocked(), IsFalse)
+
+	tc, storage, sche := s.prepare(c)
+	for i := 0; i < slowStoreHysteresis; i++ {
+		heartbeatLatencies(tc, 500, 10, 10, 20)
+		sche.Schedule(tc)
+	}
+	s.checkEvicting(c, tc, sche, 1)
+
+	// The eviction is persisted.
+	sche2, err := schedule.CreateScheduler(EvictSlowStoreType, schedule.NewOperatorController(s.ctx, tc, nil), storage, schedule.ConfigJSONDecoder(loadEvictSlowStoreConfig(c, storage)))
+	c.Assert(err, IsNil)
+	evicted, _ := sche2.(*evictSlowStoreScheduler).conf.getEvictedStore()
+	c.Assert(evicted, Equals, uint64(1))
+
+	// The other stores are not evicted at the same time.
+	heartbeatLatencies(tc, 500, 500, 10, 10)
+	s.checkEvicting(c, tc, sche, 1)
+	c.Assert(tc.GetStore(2).IsBlocked(), IsFalse)
+
+	// The eviction is removed after the store recovers for enough heartbeats.
+	for i := 1; i < slowStoreHysteresis; i++ {
+		heartbeatLatencies(tc, 12, 10, 10, 20)
+		s.checkEvicting(c, tc, sche, 1)
+	}
+	heartbeatLatencies(tc, 200, 10, 10, 20)
+	s.checkEvicting(c, tc, sche, 1)
+	for i := 1; i < slowStoreHysteresis; i++ {
+		heartbeatLatencies(tc, 12, 10, 10, 20)
+		s.checkEvicting(c, tc, sche, 1)
+	}
+	heartbeatLatencies(tc, 12, 10, 10, 20)
+	s.checkEvicting(c, tc, sche, 0)
+	evicted, _ = sche.conf.getEvictedStore()
+	c.Assert(evicted, Equals, uint64(0))
+}
+
+func (s *testEvictSlowStoreSuite) TestMaxEvictDuration(c *C) {
+	tc, _, sche := s.prepare(c)
+	for i := 0; i < slowStoreHysteresis; i++ {
+		heartbeatLatencies(tc, 500, 10, 10, 20)
+		sche.Schedule(tc)
+	}
+	s.checkEvicting(c, tc, sche, 1)
+
+	sche.conf.EvictStartTime = time.Now().Add(-sche.conf.MaxEvictDuration.Duration)
+	s.checkEvicting(c, tc, sche, 0)
+	// The store is not evicted again until it recovers.
+	for i := 0; i < slowStoreHysteresis; i++ {
+		heartbeatLatencies(tc, 500, 10, 10, 20)
+		s.checkEvicting(c, tc, sche, 0)
+	}
+	heartbeatLatencies(tc, 10, 10, 10, 20)
+	s.checkEvicting(c, tc, sche, 0)
+	for i := 0; i < slowStoreHysteresis; i++ {
+		heartbeatLatencies(tc, 500, 10, 10, 20)
+		sche.Schedule(tc)
+	}
+	s.checkEvicting(c, tc, sche, 1)
+}
+
+func (s *testEvictSlowStoreSuite) TestHandler(c *C) {
+	tc, storage, sche := s.prepare(c)
+	heartbeatLatencies(tc, 500, 10, 10, 20)
+	sche.Schedule(tc)
+
+	var status map[string]interface{}
+	w := httptest.NewRecorder()
+	sche.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/list", nil))
+	c.Assert(w.Code, Equals, http.StatusOK)
+	c.Assert(json.Unmarshal(w.Body.Bytes(), &status), IsNil)
+	c.Assert(status["slow-store"], Equals, 1.0)
+	c.Assert(status["slow-store-rounds"], Equals, 1.0)
+	c.Assert(status["slow-ratio"], Equals, 3.0)
+	c.Assert(status["max-evict-duration"], Equals, "30m0s")
+
+	post := func(body string) int {
+		w := httptest.NewRecorder()
+		sche.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/config", bytes.NewBufferString(body)))
+		return w.Code
+	}
+	c.Assert(post(`{"slow-ratio": 1}`), Equals, http.StatusBadRequest)
+	c.Assert(post(`{"recover-ratio": 4}`), Equals, http.StatusBadRequest)
+	c.Assert(post(`{"max-evict-duration": "0s"}`), Equals, http.StatusBadRequest)
+	c.Assert(post(`{"slow-ratio": 5, "max-evict-duration": "1h"}`), Equals, http.StatusOK)
+	c.Assert(sche.conf.SlowRatio, Equals, 5.0)
+	c.Assert(sche.conf.MaxEvictDuration.Duration, Equals, time.Hour)
+	conf := initEvictSlowStoreSchedulerConfig()
+	c.Assert(schedule.DecodeConfig(loadEvictSlowStoreConfig(c, storage), conf), IsNil)
+	c.Assert(conf.SlowRatio, Equals, 5.0)
+}
+
+func loadEvictSlowStoreConfig(c *C, storage *core.Storage) []byte {
+	data, err := storage.LoadScheduleConfig(EvictSlowStoreName)
+	c.Assert(err, IsNil)
+	return []byte(data)
+}
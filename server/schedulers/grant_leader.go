@@ -203,6 +203,13 @@ func (s *grantLeaderScheduler) Cleanup(cluster opt.Cluster) {
 	}
 }
 
+func (s *grantLeaderScheduler) GetEffectiveConfig(cluster opt.Cluster) []*schedule.EffectiveConfigItem {
+	items := []*schedule.EffectiveConfigItem{
+		clusterConfigItem("leader-schedule-limit", cluster.GetLeaderScheduleLimit()),
+	}
+	return append(items, schedulerConfigItems(s.GetName(), s, nil)...)
+}
+
 func (s *grantLeaderScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
 	return s.OpController.OperatorCount(operator.OpLeader) < cluster.GetLeaderScheduleLimit()
 }
@@ -232,6 +239,42 @@ func (s *grantLeaderScheduler) Schedule(cluster opt.Cluster) []*operator.Operato
 	return ops
 }
 
+// LeaderGranter is the scheduler granting the leaders to the stores on
+// demand.
+type LeaderGranter interface {
+	// GrantLeader starts to grant all the leaders to the store. It returns
+	// false if the store is granted already.
+	GrantLeader(storeID uint64) (bool, error)
+	// UngrantLeader stops granting the leaders to the store. It returns true
+	// if no store is granted any more, and then the scheduler can be removed.
+	// It returns ErrScheduleConfigNotExist if the store is not granted.
+	UngrantLeader(storeID uint64) (bool, error)
+}
+
+func (s *grantLeaderScheduler) GrantLeader(storeID uint64) (bool, error) {
+	s.conf.mu.RLock()
+	_, exists := s.conf.StoreIDWithRanges[storeID]
+	s.conf.mu.RUnlock()
+	if exists {
+		return false, nil
+	}
+	if err := s.conf.cluster.BlockStore(storeID); err != nil {
+		return false, err
+	}
+	if err := s.conf.BuildWithArgs([]string{strconv.FormatUint(storeID, 10)}); err != nil {
+		return false, err
+	}
+	return true, s.conf.Persist()
+}
+
+func (s *grantLeaderScheduler) UngrantLeader(storeID uint64) (bool, error) {
+	succ, last := s.conf.mayBeRemoveStoreFromConfig(storeID)
+	if !succ {
+		return false, ErrScheduleConfigNotExist
+	}
+	return last, s.conf.Persist()
+}
+
 type grantLeaderHandler struct {
 	rd     *render.Render
 	config *grantLeaderSchedulerConfig
@@ -138,6 +138,21 @@ func (h *hotScheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.conf.ServeHTTP(w, r)
 }
 
+func (h *hotScheduler) GetEffectiveConfig(cluster opt.Cluster) []*schedule.EffectiveConfigItem {
+	writeByteRate, writeKeyRate := cluster.GetHotWriteThresholds()
+	readByteRate, readKeyRate := cluster.GetHotReadThresholds()
+	items := []*schedule.EffectiveConfigItem{
+		clusterConfigItem("hot-region-schedule-limit", cluster.GetHotRegionScheduleLimit()),
+		clusterConfigItem("leader-schedule-limit", cluster.GetLeaderScheduleLimit()),
+		clusterConfigItem("hot-region-cache-hits-threshold", cluster.GetHotRegionCacheHitsThreshold()),
+		clusterConfigItem("hot-write-byte-rate-threshold", writeByteRate),
+		clusterConfigItem("hot-write-key-rate-threshold", writeKeyRate),
+		clusterConfigItem("hot-read-byte-rate-threshold", readByteRate),
+		clusterConfigItem("hot-read-key-rate-threshold", readKeyRate),
+	}
+	return append(items, schedulerConfigItems(h.GetName(), h.conf, initHotRegionScheduleConfig())...)
+}
+
 func (h *hotScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
 	return h.allowBalanceLeader(cluster) || h.allowBalanceRegion(cluster)
 }
@@ -156,6 +171,13 @@ func (h *hotScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
 	return h.dispatch(h.types[h.r.Int()%len(h.types)], cluster)
 }
 
+// UpdateHotStatus updates the hot status of the stores without scheduling.
+func (h *hotScheduler) UpdateHotStatus(cluster opt.Cluster) {
+	h.Lock()
+	defer h.Unlock()
+	h.prepareForBalance(cluster)
+}
+
 func (h *hotScheduler) dispatch(typ rwType, cluster opt.Cluster) []*operator.Operator {
 	h.Lock()
 	defer h.Unlock()
@@ -177,6 +199,7 @@ func (h *hotScheduler) prepareForBalance(cluster opt.Cluster) {
 	storesStat := cluster.GetStoresStats()
 
 	minHotDegree := cluster.GetHotRegionCacheHitsThreshold()
+	ioFactors := core.IOCapacityFactors(cluster.GetStores())
 	{ // update read statistics
 		regionRead := cluster.RegionReadStats()
 		storeByte := storesStat.GetStoresBytesReadStat()
@@ -187,6 +210,7 @@ func (h *hotScheduler) prepareForBalance(cluster opt.Cluster) {
 			storeKey,
 			h.pendingSums[readLeader],
 			regionRead,
+			ioFactors,
 			minHotDegree,
 			read, core.LeaderKind)
 	}
@@ -201,6 +225,7 @@ func (h *hotScheduler) prepareForBalance(cluster opt.Cluster) {
 			storeKey,
 			h.pendingSums[writeLeader],
 			regionWrite,
+			ioFactors,
 			minHotDegree,
 			write, core.LeaderKind)
 
@@ -209,6 +234,7 @@ func (h *hotScheduler) prepareForBalance(cluster opt.Cluster) {
 			storeKey,
 			h.pendingSums[writePeer],
 			regionWrite,
+			ioFactors,
 			minHotDegree,
 			write, core.RegionKind)
 	}
@@ -249,6 +275,7 @@ func summaryStoresLoad(
 	storeKeyRate map[uint64]float64,
 	pendings map[uint64]Influence,
 	storeHotPeers map[uint64][]*statistics.HotPeerStat,
+	ioFactors map[uint64]float64,
 	minHotDegree int,
 	rwTy rwType,
 	kind core.ResourceKind,
@@ -293,10 +320,19 @@ func summaryStoresLoad(
 			Count:    float64(len(hotPeers)),
 		}).ToLoadPred(pendings[id])
 
+		// The byte rates are normalized by the IO capacity of the store when
+		// the loads are compared, so that the byte rates of the stores
+		// converge in proportion to their capacities.
+		ioFactor := 1.0
+		if factor, ok := ioFactors[id]; ok && factor > 0 {
+			ioFactor = factor
+		}
+
 		// Construct store load info.
 		loadDetail[id] = &storeLoadDetail{
 			LoadPred: stLoadPred,
 			HotPeers: hotPeers,
+			IOFactor: ioFactor,
 		}
 	}
 	return loadDetail
@@ -425,9 +461,10 @@ func (bs *balanceSolver) init() {
 	maxCur := &storeLoad{}
 
 	for _, detail := range bs.stLoadDetail {
-		bs.maxSrc = maxLoad(bs.maxSrc, detail.LoadPred.min())
-		bs.minDst = minLoad(bs.minDst, detail.LoadPred.max())
-		maxCur = maxLoad(maxCur, &detail.LoadPred.Current)
+		lp := detail.normalizedLoadPred()
+		bs.maxSrc = maxLoad(bs.maxSrc, lp.min())
+		bs.minDst = minLoad(bs.minDst, lp.max())
+		maxCur = maxLoad(maxCur, &lp.Current)
 	}
 
 	bs.rankStep = &storeLoad{
@@ -715,8 +752,9 @@ func (bs *balanceSolver) filterDstStores() map[uint64]*storeLoadDetail {
 // calcProgressiveRank calculates `bs.cur.progressiveRank`.
 // See the comments of `solution.progressiveRank` for more about progressive rank.
 func (bs *balanceSolver) calcProgressiveRank() {
-	srcLd := bs.stLoadDetail[bs.cur.srcStoreID].LoadPred.min()
-	dstLd := bs.stLoadDetail[bs.cur.dstStoreID].LoadPred.max()
+	srcLd := bs.stLoadDetail[bs.cur.srcStoreID].normalizedLoadPred().min()
+	dstDetail := bs.stLoadDetail[bs.cur.dstStoreID]
+	dstLd := dstDetail.normalizedLoadPred().max()
 	peer := bs.cur.srcPeerStat
 	rank := int64(0)
 	if bs.rwTy == write && bs.opTy == transferLeader {
@@ -729,7 +767,9 @@ func (bs *balanceSolver) calcProgressiveRank() {
 	} else {
 		keyDecRatio := (dstLd.KeyRate + peer.GetKeyRate()) / (srcLd.KeyRate + 1)
 		keyHot := peer.GetKeyRate() >= bs.sche.conf.GetMinHotKeyRate()
-		byteDecRatio := (dstLd.ByteRate + peer.GetByteRate()) / (srcLd.ByteRate + 1)
+		// The byte rates of the stores are normalized, so is the peer on the
+		// destination store.
+		byteDecRatio := (dstLd.ByteRate + peer.GetByteRate()/dstDetail.IOFactor) / (srcLd.ByteRate + 1)
 		byteHot := peer.GetByteRate() > bs.sche.conf.GetMinHotByteRate()
 		greatDecRatio, minorDecRatio := bs.sche.conf.GetGreatDecRatio(), bs.sche.conf.GetMinorGreatDecRatio()
 		switch {
@@ -842,8 +882,8 @@ func (bs *balanceSolver) compareSrcStore(st1, st2 uint64) int {
 			)
 		}
 
-		lp1 := bs.stLoadDetail[st1].LoadPred
-		lp2 := bs.stLoadDetail[st2].LoadPred
+		lp1 := bs.stLoadDetail[st1].normalizedLoadPred()
+		lp2 := bs.stLoadDetail[st2].normalizedLoadPred()
 		return lpCmp(lp1, lp2)
 	}
 	return 0
@@ -878,8 +918,8 @@ func (bs *balanceSolver) compareDstStore(st1, st2 uint64) int {
 			)
 		}
 
-		lp1 := bs.stLoadDetail[st1].LoadPred
-		lp2 := bs.stLoadDetail[st2].LoadPred
+		lp1 := bs.stLoadDetail[st1].normalizedLoadPred()
+		lp2 := bs.stLoadDetail[st2].normalizedLoadPred()
 		return lpCmp(lp1, lp2)
 	}
 	return 0
@@ -945,6 +985,7 @@ func (bs *balanceSolver) buildOperators() ([]*operator.Operator, []Influence) {
 	}
 
 	op.SetPriorityLevel(core.HighPriority)
+	op.SetReason(bs.buildReason())
 	op.Counters = append(op.Counters,
 		schedulerCounter.WithLabelValues(bs.sche.GetName(), "new-operator"),
 		schedulerCounter.WithLabelValues(bs.sche.GetName(), bs.opTy.String()))
@@ -958,6 +999,29 @@ func (bs *balanceSolver) buildOperators() ([]*operator.Operator, []Influence) {
 	return []*operator.Operator{op}, []Influence{infl}
 }
 
+// buildReason returns the reason of the current solution, with the flow of the
+// hot peer and the current flow of the stores.
+func (bs *balanceSolver) buildReason() *operator.Reason {
+	reason := operator.NewReason(operator.ReasonHotLoad, bs.cur.srcStoreID, bs.cur.dstStoreID).
+		With("peer-byte-rate", bs.cur.srcPeerStat.GetByteRate()).
+		With("peer-key-rate", bs.cur.srcPeerStat.GetKeyRate())
+	if detail := bs.stLoadDetail[bs.cur.srcStoreID]; detail != nil {
+		reason.With("source-byte-rate", detail.LoadPred.Current.ByteRate).
+			With("source-key-rate", detail.LoadPred.Current.KeyRate)
+		if detail.IOFactor != 1 {
+			reason.With("source-io-factor", detail.IOFactor)
+		}
+	}
+	if detail := bs.stLoadDetail[bs.cur.dstStoreID]; detail != nil {
+		reason.With("target-byte-rate", detail.LoadPred.Current.ByteRate).
+			With("target-key-rate", detail.LoadPred.Current.KeyRate)
+		if detail.IOFactor != 1 {
+			reason.With("target-io-factor", detail.IOFactor)
+		}
+	}
+	return reason
+}
+
 func (h *hotScheduler) adjustBalanceLimit(storeID uint64, loadDetail map[uint64]*storeLoadDetail) uint64 {
 	srcStoreStatistics := loadDetail[storeID]
 
@@ -107,6 +107,33 @@ func (s *testHotSchedulerSuite) TestGCPendingOpInfos(c *C) {
 	}
 }
 
+func (s *testHotSchedulerSuite) TestEffectiveConfig(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+	sche, err := schedule.CreateScheduler(HotRegionType, schedule.NewOperatorController(ctx, tc, nil), core.NewStorage(kv.NewMemoryKV()), schedule.ConfigJSONDecoder([]byte("null")))
+	c.Assert(err, IsNil)
+	hb := sche.(*hotScheduler)
+
+	sources := func() map[string]string {
+		m := make(map[string]string)
+		for _, item := range hb.GetEffectiveConfig(tc) {
+			m[item.Name] = item.Source
+		}
+		return m
+	}
+	m := sources()
+	c.Assert(m["hot-region-schedule-limit"], Equals, schedule.ConfigSourceCluster)
+	c.Assert(m["hot-write-byte-rate-threshold"], Equals, schedule.ConfigSourceCluster)
+	c.Assert(m["min-hot-byte-rate"], Equals, schedule.ConfigSourceDefault)
+
+	hb.conf.MinHotByteRate = 200
+	m = sources()
+	c.Assert(m["min-hot-byte-rate"], Equals, schedule.ConfigSourceScheduler)
+	c.Assert(m["min-hot-key-rate"], Equals, schedule.ConfigSourceDefault)
+}
+
 func newTestRegion(id uint64) *core.RegionInfo {
 	peers := []*metapb.Peer{{Id: id*100 + 1, StoreId: 1}, {Id: id*100 + 2, StoreId: 2}, {Id: id*100 + 3, StoreId: 3}}
 	return core.NewRegionInfo(&metapb.Region{Id: id, Peers: peers}, peers[0])
@@ -173,6 +200,11 @@ func (s *testHotWriteRegionSchedulerSuite) checkByteRateOnly(c *C, tc *mockclust
 	// which is hot for store 1 is more larger than other stores.
 	op := hb.Schedule(tc)[0]
 	hb.(*hotScheduler).clearPendingInfluence()
+	reason := op.Reason()
+	c.Assert(reason.Code, Equals, operator.ReasonHotLoad)
+	c.Assert(reason.SourceStore, Equals, uint64(1))
+	c.Assert(reason.Evidence["peer-byte-rate"], Greater, float64(0))
+	c.Assert(reason.Evidence["source-byte-rate"], Greater, reason.Evidence["target-byte-rate"])
 	switch op.Len() {
 	case 1:
 		// balance by leader selected
@@ -472,6 +504,74 @@ func (s *testHotWriteRegionSchedulerSuite) TestWithPendingInfluence(c *C) {
 	}
 }
 
+func (s *testHotWriteRegionSchedulerSuite) TestIOCapacity(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	statistics.Denoising = false
+	opt := mockoption.NewScheduleOptions()
+	newTestReplication(opt, 1)
+	opt.HotRegionCacheHitsThreshold = 0
+	opt.LeaderScheduleLimit = 0
+
+	// The stores converge to the same byte rates if the IO capacities are not
+	// labeled, or the byte rates in proportion to the IO capacities.
+	for _, ioCapacities := range [][]string{{"", ""}, {"200MiB", "100MiB"}} {
+		tc := mockcluster.NewCluster(opt)
+		for i, capacity := range ioCapacities {
+			labels := map[string]string{}
+			if capacity != "" {
+				labels[core.IOCapacityLabel] = capacity
+			}
+			tc.AddLabelsStore(uint64(i+1), 12, labels)
+		}
+		hb, err := schedule.CreateScheduler(HotWriteRegionType, schedule.NewOperatorController(ctx, nil, nil), core.NewStorage(kv.NewMemoryKV()), nil)
+		c.Assert(err, IsNil)
+
+		// 12 hot regions are all on store 1 at first.
+		placement := make(map[uint64]uint64)
+		for id := uint64(1); id <= 12; id++ {
+			placement[id] = 1
+		}
+		for i := 0; ; i++ {
+			c.Assert(i, Less, 12)
+			var regions []testRegionInfo
+			rates := make(map[uint64]float64)
+			for id, storeID := range placement {
+				regions = append(regions, testRegionInfo{id, []uint64{storeID}, 512 * KB, 0})
+				rates[storeID] += 512 * KB
+			}
+			for storeID := uint64(1); storeID <= 2; storeID++ {
+				tc.UpdateStorageWrittenBytes(storeID, uint64(rates[storeID]*statistics.StoreHeartBeatReportInterval))
+			}
+			addRegionInfo(tc, write, regions)
+
+			hb.(*hotScheduler).clearPendingInfluence()
+			ops := hb.Schedule(tc)
+			// The loads keep the absolute byte rates.
+			for storeID, detail := range hb.(*hotScheduler).stLoadInfos[writePeer] {
+				c.Assert(detail.toHotPeersStat().TotalBytesRate, Equals, rates[storeID])
+			}
+			if len(ops) == 0 {
+				break
+			}
+			// Assume the operator is finished.
+			reason := ops[0].Reason()
+			c.Assert(reason.SourceStore, Equals, placement[ops[0].RegionID()])
+			placement[ops[0].RegionID()] = reason.TargetStore
+		}
+
+		counts := make(map[uint64]int)
+		for _, storeID := range placement {
+			counts[storeID]++
+		}
+		if ioCapacities[0] == "" {
+			c.Assert(counts, DeepEquals, map[uint64]int{1: 6, 2: 6})
+		} else {
+			c.Assert(counts, DeepEquals, map[uint64]int{1: 8, 2: 4})
+		}
+	}
+}
+
 var _ = Suite(&testHotReadRegionSchedulerSuite{})
 
 type testHotReadRegionSchedulerSuite struct{}
@@ -897,6 +997,53 @@ func (s *testHotCacheSuite) TestByteAndKey(c *C) {
 	}
 }
 
+func (s *testHotCacheSuite) TestConfigurableThresholds(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := mockoption.NewScheduleOptions()
+	opt.HotRegionCacheHitsThreshold = 0
+	tc := mockcluster.NewCluster(opt)
+	hb, err := schedule.CreateScheduler(HotWriteRegionType, schedule.NewOperatorController(ctx, nil, nil), core.NewStorage(kv.NewMemoryKV()), nil)
+	c.Assert(err, IsNil)
+	h := hb.(*hotScheduler)
+	hotWritePeers := func() int {
+		h.prepareForBalance(tc)
+		count := 0
+		for _, stat := range h.GetHotWriteStatus().AsPeer {
+			count += stat.Count
+		}
+		return count
+	}
+
+	// The region writing slower than the default threshold is not hot.
+	for i := uint64(1); i <= 3; i++ {
+		tc.AddRegionStore(i, 1)
+		tc.UpdateStorageWrittenBytes(i, 0.5*KB*statistics.StoreHeartBeatReportInterval)
+	}
+	addRegionInfo(tc, write, []testRegionInfo{{1, []uint64{1, 2, 3}, 0.5 * KB, 0}})
+	c.Assert(tc.RegionStats(statistics.WriteFlow)[1], HasLen, 0)
+	c.Assert(hotWritePeers(), Equals, 0)
+
+	// It becomes hot for the new samples after the threshold is lowered.
+	opt.HotWriteByteRateThreshold = 0.2 * KB
+	addRegionInfo(tc, write, []testRegionInfo{{1, []uint64{1, 2, 3}, 0.5 * KB, 0}})
+	c.Assert(tc.RegionStats(statistics.WriteFlow)[1], HasLen, 1)
+	c.Assert(hotWritePeers(), Equals, 3)
+
+	// The key rate threshold works independently.
+	opt.HotWriteByteRateThreshold = 1 * KB
+	opt.HotWriteKeyRateThreshold = 10
+	addRegionInfo(tc, write, []testRegionInfo{{2, []uint64{1, 2, 3}, 0, 20}})
+	c.Assert(tc.RegionStats(statistics.WriteFlow)[1], HasLen, 2)
+
+	// The read thresholds are not affected.
+	addRegionInfo(tc, read, []testRegionInfo{{3, []uint64{1, 2, 3}, 0.5 * KB, 0}})
+	c.Assert(tc.RegionStats(statistics.ReadFlow)[1], HasLen, 0)
+	opt.HotReadByteRateThreshold = 0.2 * KB
+	addRegionInfo(tc, read, []testRegionInfo{{3, []uint64{1, 2, 3}, 0.5 * KB, 0}})
+	c.Assert(tc.RegionStats(statistics.ReadFlow)[1], HasLen, 1)
+}
+
 type testRegionInfo struct {
 	id       uint64
 	peers    []uint64
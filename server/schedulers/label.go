@@ -96,6 +96,13 @@ func (s *labelScheduler) EncodeConfig() ([]byte, error) {
 	return schedule.EncodeConfig(s.conf)
 }
 
+func (s *labelScheduler) GetEffectiveConfig(cluster opt.Cluster) []*schedule.EffectiveConfigItem {
+	items := []*schedule.EffectiveConfigItem{
+		clusterConfigItem("leader-schedule-limit", cluster.GetLeaderScheduleLimit()),
+	}
+	return append(items, schedulerConfigItems(s.GetName(), s, nil)...)
+}
+
 func (s *labelScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
 	return s.OpController.OperatorCount(operator.OpLeader) < cluster.GetLeaderScheduleLimit()
 }
@@ -97,6 +97,14 @@ func (s *randomMergeScheduler) EncodeConfig() ([]byte, error) {
 	return schedule.EncodeConfig(s.conf)
 }
 
+func (s *randomMergeScheduler) GetEffectiveConfig(cluster opt.Cluster) []*schedule.EffectiveConfigItem {
+	items := []*schedule.EffectiveConfigItem{
+		clusterConfigItem("merge-schedule-limit", cluster.GetMergeScheduleLimit()),
+		clusterConfigItem("enable-one-way-merge", cluster.IsOneWayMergeEnabled()),
+	}
+	return append(items, schedulerConfigItems(s.GetName(), s, nil)...)
+}
+
 func (s *randomMergeScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
 	return s.OpController.OperatorCount(operator.OpMerge) < cluster.GetMergeScheduleLimit()
 }
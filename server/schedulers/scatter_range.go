@@ -15,10 +15,13 @@ package schedulers
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/pingcap/log"
 	"github.com/pingcap/pd/v4/pkg/apiutil"
 	"github.com/pingcap/pd/v4/server/core"
 	"github.com/pingcap/pd/v4/server/schedule"
@@ -26,6 +29,7 @@ import (
 	"github.com/pingcap/pd/v4/server/schedule/opt"
 	"github.com/pkg/errors"
 	"github.com/unrolled/render"
+	"go.uber.org/zap"
 )
 
 func init() {
@@ -51,7 +55,9 @@ func init() {
 
 	schedule.RegisterScheduler(ScatterRangeType, func(opController *schedule.OperatorController, storage *core.Storage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
 		conf := &scatterRangeSchedulerConfig{
-			storage: storage,
+			storage:         storage,
+			MaxScoreSpread:  defaultScatterRangeMaxScoreSpread,
+			StableIntervals: defaultScatterRangeStableIntervals,
 		}
 		if err := decoder(conf); err != nil {
 			return nil, err
@@ -69,6 +75,12 @@ const (
 	ScatterRangeType = "scatter-range"
 	// ScatterRangeName is scatter range scheduler name
 	ScatterRangeName = "scatter-range"
+
+	defaultScatterRangeMaxScoreSpread  = 0.2
+	defaultScatterRangeStableIntervals = 5
+	// scatterRangeEvaluateInterval is the interval of evaluating whether the
+	// range is stable, which is independent of the scheduling interval.
+	scatterRangeEvaluateInterval = time.Minute
 )
 
 type scatterRangeSchedulerConfig struct {
@@ -77,6 +89,14 @@ type scatterRangeSchedulerConfig struct {
 	RangeName string `json:"range-name"`
 	StartKey  string `json:"start-key"`
 	EndKey    string `json:"end-key"`
+	// AutoComplete completes the scheduler once the score spread of the range
+	// stays at most MaxScoreSpread for StableIntervals consecutive evaluations.
+	// The completed scheduler stops scheduling, and is removed if AutoRemove.
+	AutoComplete    bool    `json:"auto-complete"`
+	AutoRemove      bool    `json:"auto-remove"`
+	MaxScoreSpread  float64 `json:"max-score-spread"`
+	StableIntervals int     `json:"stable-intervals"`
+	Completed       bool    `json:"completed"`
 }
 
 func (conf *scatterRangeSchedulerConfig) BuildWithArgs(args []string) error {
@@ -96,10 +116,44 @@ func (conf *scatterRangeSchedulerConfig) Clone() *scatterRangeSchedulerConfig {
 	conf.mu.RLock()
 	defer conf.mu.RUnlock()
 	return &scatterRangeSchedulerConfig{
-		StartKey:  conf.StartKey,
-		EndKey:    conf.EndKey,
-		RangeName: conf.RangeName,
+		StartKey:        conf.StartKey,
+		EndKey:          conf.EndKey,
+		RangeName:       conf.RangeName,
+		AutoComplete:    conf.AutoComplete,
+		AutoRemove:      conf.AutoRemove,
+		MaxScoreSpread:  conf.MaxScoreSpread,
+		StableIntervals: conf.StableIntervals,
+		Completed:       conf.Completed,
+	}
+}
+
+// setAutoComplete updates the auto complete mode, and restarts the scheduler
+// if it is completed.
+func (conf *scatterRangeSchedulerConfig) setAutoComplete(autoComplete, autoRemove bool, maxScoreSpread float64, stableIntervals int) error {
+	if maxScoreSpread < 0 || maxScoreSpread >= 1 {
+		return errors.New("max-score-spread should be in [0, 1)")
 	}
+	if stableIntervals <= 0 {
+		return errors.New("stable-intervals should be positive")
+	}
+	conf.mu.Lock()
+	defer conf.mu.Unlock()
+	conf.AutoComplete, conf.AutoRemove = autoComplete, autoRemove
+	conf.MaxScoreSpread, conf.StableIntervals = maxScoreSpread, stableIntervals
+	conf.Completed = false
+	return nil
+}
+
+func (conf *scatterRangeSchedulerConfig) setCompleted() {
+	conf.mu.Lock()
+	defer conf.mu.Unlock()
+	conf.Completed = true
+}
+
+func (conf *scatterRangeSchedulerConfig) isCompleted() bool {
+	conf.mu.RLock()
+	defer conf.mu.RUnlock()
+	return conf.Completed
 }
 
 func (conf *scatterRangeSchedulerConfig) Persist() error {
@@ -145,6 +199,13 @@ type scatterRangeScheduler struct {
 	balanceLeader schedule.Scheduler
 	balanceRegion schedule.Scheduler
 	handler       http.Handler
+
+	mu sync.Mutex
+	// stableIntervals is the number of the consecutive evaluations in which
+	// the range is stable.
+	stableIntervals int
+	lastEvaluate    time.Time
+	now             func() time.Time
 }
 
 // newScatterRangeScheduler creates a scheduler that balances the distribution of leaders and regions that in the specified key range.
@@ -152,12 +213,11 @@ func newScatterRangeScheduler(opController *schedule.OperatorController, config
 	base := NewBaseScheduler(opController)
 
 	name := config.getSchedulerName()
-	handler := newScatterRangeHandler(config)
 	scheduler := &scatterRangeScheduler{
 		BaseScheduler: base,
 		config:        config,
-		handler:       handler,
 		name:          name,
+		now:           time.Now,
 		balanceLeader: newBalanceLeaderScheduler(
 			opController,
 			&balanceLeaderSchedulerConfig{Ranges: []core.KeyRange{core.NewKeyRange("", "")}},
@@ -171,6 +231,7 @@ func newScatterRangeScheduler(opController *schedule.OperatorController, config
 			WithBalanceRegionCounter(scatterRangeRegionCounter),
 		),
 	}
+	scheduler.handler = newScatterRangeHandler(scheduler)
 	return scheduler
 }
 
@@ -192,15 +253,32 @@ func (l *scatterRangeScheduler) EncodeConfig() ([]byte, error) {
 	return schedule.EncodeConfig(l.config)
 }
 
+func (l *scatterRangeScheduler) GetEffectiveConfig(cluster opt.Cluster) []*schedule.EffectiveConfigItem {
+	items := []*schedule.EffectiveConfigItem{
+		clusterConfigItem("region-schedule-limit", cluster.GetRegionScheduleLimit()),
+		clusterConfigItem("leader-schedule-policy", cluster.GetLeaderSchedulePolicy().String()),
+		clusterConfigItem("low-space-ratio", cluster.GetLowSpaceRatio()),
+		clusterConfigItem("high-space-ratio", cluster.GetHighSpaceRatio()),
+	}
+	return append(items, schedulerConfigItems(l.GetName(), l, nil)...)
+}
+
 func (l *scatterRangeScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
 	return l.OpController.OperatorCount(operator.OpRange) < cluster.GetRegionScheduleLimit()
 }
 
 func (l *scatterRangeScheduler) Schedule(cluster opt.Cluster) []*operator.Operator {
 	schedulerCounter.WithLabelValues(l.GetName(), "schedule").Inc()
+	if l.config.isCompleted() {
+		schedulerCounter.WithLabelValues(l.GetName(), "completed").Inc()
+		return nil
+	}
 	// isolate a new cluster according to the key range
 	c := schedule.GenRangeCluster(cluster, l.config.GetStartKey(), l.config.GetEndKey())
 	c.SetTolerantSizeRatio(2)
+	if l.checkCompletion(cluster, c) {
+		return nil
+	}
 	ops := l.balanceLeader.Schedule(c)
 	if len(ops) > 0 {
 		ops[0].SetDesc(fmt.Sprintf("scatter-range-leader-%s", l.config.RangeName))
@@ -224,9 +302,103 @@ func (l *scatterRangeScheduler) Schedule(cluster opt.Cluster) []*operator.Operat
 	return nil
 }
 
+// getStableProgress returns the number of the consecutive evaluations in
+// which the range is stable.
+func (l *scatterRangeScheduler) getStableProgress() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stableIntervals
+}
+
+func (l *scatterRangeScheduler) resetStableProgress() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stableIntervals = 0
+	l.lastEvaluate = time.Time{}
+}
+
+// checkCompletion evaluates whether the range is stable at most once per
+// scatterRangeEvaluateInterval, and completes the scheduler once the range
+// stays stable. It returns true if the scheduler is completed.
+func (l *scatterRangeScheduler) checkCompletion(cluster opt.Cluster, rangeCluster *schedule.RangeCluster) bool {
+	l.config.mu.RLock()
+	autoComplete, autoRemove := l.config.AutoComplete, l.config.AutoRemove
+	maxScoreSpread, stableIntervals := l.config.MaxScoreSpread, l.config.StableIntervals
+	l.config.mu.RUnlock()
+	if !autoComplete {
+		return false
+	}
+
+	l.mu.Lock()
+	now := l.now()
+	if now.Sub(l.lastEvaluate) < scatterRangeEvaluateInterval {
+		l.mu.Unlock()
+		return false
+	}
+	l.lastEvaluate = now
+	if getScoreSpread(rangeCluster) <= maxScoreSpread {
+		l.stableIntervals++
+	} else {
+		l.stableIntervals = 0
+	}
+	stable := l.stableIntervals >= stableIntervals
+	l.mu.Unlock()
+	if !stable {
+		return false
+	}
+
+	l.config.setCompleted()
+	if err := l.config.Persist(); err != nil {
+		log.Warn("failed to persist the completed scatter range scheduler", zap.String("scheduler-name", l.GetName()), zap.Error(err))
+	}
+	schedulerCounter.WithLabelValues(l.GetName(), "complete").Inc()
+	log.Info("scatter range scheduler completed",
+		zap.String("scheduler-name", l.GetName()),
+		zap.String("start-key", core.HexRegionKeyStr(l.config.GetStartKey())),
+		zap.String("end-key", core.HexRegionKeyStr(l.config.GetEndKey())),
+		zap.Bool("auto-remove", autoRemove))
+	if autoRemove {
+		if err := cluster.RemoveScheduler(l.GetName()); err != nil {
+			log.Warn("failed to remove the completed scatter range scheduler", zap.String("scheduler-name", l.GetName()), zap.Error(err))
+		}
+	}
+	return true
+}
+
+// getScoreSpread returns the larger one of the leader and region score
+// spreads among the up stores, relative to the max score.
+func getScoreSpread(cluster opt.Cluster) float64 {
+	policy := cluster.GetLeaderSchedulePolicy()
+	highSpaceRatio, lowSpaceRatio := cluster.GetHighSpaceRatio(), cluster.GetLowSpaceRatio()
+	var leaderScores, regionScores []float64
+	for _, store := range cluster.GetStores() {
+		if !store.IsUp() || store.IsDisconnected() {
+			continue
+		}
+		leaderScores = append(leaderScores, store.LeaderScore(policy, 0))
+		regionScores = append(regionScores, store.RegionScore(highSpaceRatio, lowSpaceRatio, 0))
+	}
+	return math.Max(relativeSpread(leaderScores), relativeSpread(regionScores))
+}
+
+func relativeSpread(scores []float64) float64 {
+	if len(scores) == 0 {
+		return 0
+	}
+	min, max := scores[0], scores[0]
+	for _, score := range scores[1:] {
+		min, max = math.Min(min, score), math.Max(max, score)
+	}
+	if max <= 0 {
+		return 0
+	}
+	return (max - min) / max
+}
+
 type scatterRangeHandler struct {
-	rd     *render.Render
-	config *scatterRangeSchedulerConfig
+	rd        *render.Render
+	config    *scatterRangeSchedulerConfig
+	scheduler *scatterRangeScheduler
 }
 
 func (handler *scatterRangeHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
@@ -259,6 +431,27 @@ func (handler *scatterRangeHandler) UpdateConfig(w http.ResponseWriter, r *http.
 	} else {
 		args = append(args, string(handler.config.GetEndKey()))
 	}
+
+	conf := handler.config.Clone()
+	autoComplete, autoRemove := conf.AutoComplete, conf.AutoRemove
+	maxScoreSpread, stableIntervals := conf.MaxScoreSpread, conf.StableIntervals
+	if v, ok := input["auto-complete"].(bool); ok {
+		autoComplete = v
+	}
+	if v, ok := input["auto-remove"].(bool); ok {
+		autoRemove = v
+	}
+	if v, ok := input["max-score-spread"].(float64); ok {
+		maxScoreSpread = v
+	}
+	if v, ok := input["stable-intervals"].(float64); ok {
+		stableIntervals = int(v)
+	}
+	if err := handler.config.setAutoComplete(autoComplete, autoRemove, maxScoreSpread, stableIntervals); err != nil {
+		handler.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	handler.scheduler.resetStableProgress()
 	handler.config.BuildWithArgs(args)
 	err := handler.config.Persist()
 	if err != nil {
@@ -267,15 +460,32 @@ func (handler *scatterRangeHandler) UpdateConfig(w http.ResponseWriter, r *http.
 	handler.rd.JSON(w, http.StatusOK, nil)
 }
 
+// scatterRangeStatus is the config of the scheduler with the progress toward
+// the stable range.
+type scatterRangeStatus struct {
+	*scatterRangeSchedulerConfig
+	// Status is either "active" or "completed".
+	Status         string `json:"status"`
+	StableProgress int    `json:"stable-progress"`
+}
+
 func (handler *scatterRangeHandler) ListConfig(w http.ResponseWriter, r *http.Request) {
-	conf := handler.config.Clone()
-	handler.rd.JSON(w, http.StatusOK, conf)
+	status := &scatterRangeStatus{
+		scatterRangeSchedulerConfig: handler.config.Clone(),
+		Status:                      "active",
+		StableProgress:              handler.scheduler.getStableProgress(),
+	}
+	if status.Completed {
+		status.Status = "completed"
+	}
+	handler.rd.JSON(w, http.StatusOK, status)
 }
 
-func newScatterRangeHandler(config *scatterRangeSchedulerConfig) http.Handler {
+func newScatterRangeHandler(scheduler *scatterRangeScheduler) http.Handler {
 	h := &scatterRangeHandler{
-		config: config,
-		rd:     render.New(render.Options{IndentJSON: true}),
+		config:    scheduler.config,
+		scheduler: scheduler,
+		rd:        render.New(render.Options{IndentJSON: true}),
 	}
 	router := mux.NewRouter()
 	router.HandleFunc("/config", h.UpdateConfig).Methods("POST")
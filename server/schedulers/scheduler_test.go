@@ -230,7 +230,7 @@ func (s *testScatterRegionSuite) scatter(c *C, numStores, numRegions uint64) {
 
 	for i := uint64(1); i <= numRegions; i++ {
 		region := tc.GetRegion(i)
-		if op, _ := scatterer.Scatter(region); op != nil {
+		if op, _ := scatterer.Scatter(region, ""); op != nil {
 			s.checkOperator(op, c)
 			schedule.ApplyOperator(tc, op)
 		}
@@ -274,12 +274,49 @@ func (s *testScatterRegionSuite) TestStoreLimit(c *C) {
 
 	for i := uint64(1); i <= 5; i++ {
 		region := tc.GetRegion(i)
-		if op, _ := scatterer.Scatter(region); op != nil {
+		if op, _ := scatterer.Scatter(region, ""); op != nil {
 			c.Assert(oc.AddWaitingOperator(op), Equals, 1)
 		}
 	}
 }
 
+func (s *testScatterRegionSuite) TestIsolation(c *C) {
+	s.checkIsolation(c, false)
+	s.checkIsolation(c, true)
+}
+
+func (s *testScatterRegionSuite) checkIsolation(c *C, enablePlacementRules bool) {
+	opt := mockoption.NewScheduleOptions()
+	opt.LocationLabels = []string{"zone"}
+	opt.EnablePlacementRules = enablePlacementRules
+	tc := mockcluster.NewCluster(opt)
+
+	// Stores 1~3 are in different zones, stores 4 and 5 share zone z4.
+	tc.AddLabelsStore(1, 0, map[string]string{"zone": "z1"})
+	tc.AddLabelsStore(2, 0, map[string]string{"zone": "z2"})
+	tc.AddLabelsStore(3, 0, map[string]string{"zone": "z3"})
+	tc.AddLabelsStore(4, 0, map[string]string{"zone": "z4"})
+	tc.AddLabelsStore(5, 0, map[string]string{"zone": "z4"})
+	tc.AddLeaderRegion(1, 1, 2, 3)
+	tc.AddLeaderRegion(2, 1, 2, 3)
+
+	scatterer := schedule.NewRegionScatterer(tc)
+	// Region 1 keeps its peers and marks stores 1~3 as selected, so evening out
+	// the counts for region 2 would move two peers to stores 4 and 5.
+	for i := uint64(1); i <= 2; i++ {
+		if op, _ := scatterer.Scatter(tc.GetRegion(i), ""); op != nil {
+			schedule.ApplyOperator(tc, op)
+		}
+	}
+
+	zones := make(map[string]struct{})
+	region := tc.GetRegion(2)
+	for _, peer := range region.GetPeers() {
+		zones[tc.GetStore(peer.GetStoreId()).GetLabelValue("zone")] = struct{}{}
+	}
+	c.Assert(zones, HasLen, len(region.GetPeers()))
+}
+
 var _ = Suite(&testRejectLeaderSuite{})
 
 type testRejectLeaderSuite struct{}
@@ -456,6 +493,71 @@ func (s *testEvictLeaderSuite) TestEvictLeader(c *C) {
 	testutil.CheckTransferLeader(c, op[0], operator.OpLeader, 1, 2)
 }
 
+func (s *testEvictLeaderSuite) TestEvictLeaderByLabel(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := mockoption.NewScheduleOptions()
+	tc := mockcluster.NewCluster(opt)
+
+	// Add stores 1, 2 in zone z1 and store 3 in zone z2.
+	tc.AddLabelsStore(1, 0, map[string]string{"zone": "z1", "host": "h1"})
+	tc.AddLabelsStore(2, 0, map[string]string{"zone": "z1", "host": "h2"})
+	tc.AddLabelsStore(3, 0, map[string]string{"zone": "z2", "host": "h3"})
+	tc.AddLeaderRegion(1, 1, 3)
+	tc.AddLeaderRegion(2, 2, 3)
+	tc.AddLeaderRegion(3, 3, 1)
+
+	_, err := schedule.CreateScheduler(EvictLeaderType, schedule.NewOperatorController(ctx, tc, nil), core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(EvictLeaderType, []string{"zone"}))
+	c.Assert(err, NotNil)
+	sl, err := schedule.CreateScheduler(EvictLeaderType, schedule.NewOperatorController(ctx, tc, nil), core.NewStorage(kv.NewMemoryKV()), schedule.ConfigSliceDecoder(EvictLeaderType, []string{"zone=z1"}))
+	c.Assert(err, IsNil)
+	c.Assert(sl.Prepare(tc), IsNil)
+	c.Assert(tc.GetStore(1).IsBlocked(), IsTrue)
+	c.Assert(tc.GetStore(2).IsBlocked(), IsTrue)
+	c.Assert(tc.GetStore(3).IsBlocked(), IsFalse)
+	checkEvictedStores := func(expect ...uint64) {
+		stores := make(map[uint64]struct{})
+		for _, op := range sl.Schedule(tc) {
+			testutil.CheckTransferLeader(c, op, operator.OpLeader, op.Step(0).(operator.TransferLeader).FromStore, 3)
+			stores[op.Step(0).(operator.TransferLeader).FromStore] = struct{}{}
+		}
+		c.Assert(stores, HasLen, len(expect))
+		for _, id := range expect {
+			c.Assert(stores, HasKey, id)
+		}
+	}
+	checkEvictedStores(1, 2)
+
+	// The new store matching the selector is evicted too.
+	tc.AddLabelsStore(4, 0, map[string]string{"zone": "z1", "host": "h4"})
+	tc.AddLeaderRegion(4, 4, 3)
+	checkEvictedStores(1, 2, 4)
+	c.Assert(tc.GetStore(4).IsBlocked(), IsTrue)
+
+	// Removing the selector resumes the stores.
+	conf := sl.(*evictLeaderScheduler).conf
+	succ, last := conf.mayBeRemoveSelectorFromConfig("zone=z2")
+	c.Assert(succ, IsFalse)
+	c.Assert(last, IsFalse)
+	succ, last = conf.mayBeRemoveSelectorFromConfig("zone=z1")
+	c.Assert(succ, IsTrue)
+	c.Assert(last, IsTrue)
+	for _, id := range []uint64{1, 2, 4} {
+		c.Assert(tc.GetStore(id).IsBlocked(), IsFalse)
+	}
+	checkEvictedStores()
+}
+
+func (s *testEvictLeaderSuite) TestParseStoreLabelSelector(c *C) {
+	selector, err := ParseStoreLabelSelector(" zone = z1,host=h1")
+	c.Assert(err, IsNil)
+	c.Assert(selector, Equals, "host=h1,zone=z1")
+	for _, invalid := range []string{"", "zone", "zone=", "=z1", "zone=z1,", "zone=z1=z2"} {
+		_, err = ParseStoreLabelSelector(invalid)
+		c.Assert(err, NotNil, Commentf("selector %q", invalid))
+	}
+}
+
 var _ = Suite(&testShuffleRegionSuite{})
 
 type testShuffleRegionSuite struct{}
@@ -108,6 +108,15 @@ func (s *shuffleHotRegionScheduler) EncodeConfig() ([]byte, error) {
 	return schedule.EncodeConfig(s.conf)
 }
 
+func (s *shuffleHotRegionScheduler) GetEffectiveConfig(cluster opt.Cluster) []*schedule.EffectiveConfigItem {
+	items := []*schedule.EffectiveConfigItem{
+		clusterConfigItem("region-schedule-limit", cluster.GetRegionScheduleLimit()),
+		clusterConfigItem("leader-schedule-limit", cluster.GetLeaderScheduleLimit()),
+		clusterConfigItem("hot-region-cache-hits-threshold", cluster.GetHotRegionCacheHitsThreshold()),
+	}
+	return append(items, schedulerConfigItems(s.GetName(), s, &shuffleHotRegionSchedulerConfig{Name: s.conf.Name, Limit: 1})...)
+}
+
 func (s *shuffleHotRegionScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
 	return s.OpController.OperatorCount(operator.OpHotRegion) < s.conf.Limit &&
 		s.OpController.OperatorCount(operator.OpRegion) < cluster.GetRegionScheduleLimit() &&
@@ -130,6 +139,7 @@ func (s *shuffleHotRegionScheduler) dispatch(typ rwType, cluster opt.Cluster) []
 			storesStats.GetStoresKeysReadStat(),
 			map[uint64]Influence{},
 			cluster.RegionReadStats(),
+			nil,
 			minHotDegree,
 			read, core.LeaderKind)
 		return s.randomSchedule(cluster, s.stLoadInfos[readLeader])
@@ -139,6 +149,7 @@ func (s *shuffleHotRegionScheduler) dispatch(typ rwType, cluster opt.Cluster) []
 			storesStats.GetStoresKeysWriteStat(),
 			map[uint64]Influence{},
 			cluster.RegionWriteStats(),
+			nil,
 			minHotDegree,
 			write, core.LeaderKind)
 		return s.randomSchedule(cluster, s.stLoadInfos[writeLeader])
@@ -95,6 +95,13 @@ func (s *shuffleRegionScheduler) EncodeConfig() ([]byte, error) {
 	return s.conf.EncodeConfig()
 }
 
+func (s *shuffleRegionScheduler) GetEffectiveConfig(cluster opt.Cluster) []*schedule.EffectiveConfigItem {
+	items := []*schedule.EffectiveConfigItem{
+		clusterConfigItem("region-schedule-limit", cluster.GetRegionScheduleLimit()),
+	}
+	return append(items, schedulerConfigItems(s.GetName(), s, &shuffleRegionSchedulerConfig{Ranges: s.conf.GetRanges(), Roles: allRoles})...)
+}
+
 func (s *shuffleRegionScheduler) IsScheduleAllowed(cluster opt.Cluster) bool {
 	return s.OpController.OperatorCount(operator.OpRegion) < cluster.GetRegionScheduleLimit()
 }
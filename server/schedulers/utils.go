@@ -69,8 +69,7 @@ func shouldBalance(cluster opt.Cluster, source, target *core.StoreInfo, region *
 	tolerantResource := getTolerantResource(cluster, region, kind)
 	sourceInfluence := opInfluence.GetStoreInfluence(sourceID).ResourceProperty(kind)
 	targetInfluence := opInfluence.GetStoreInfluence(targetID).ResourceProperty(kind)
-	sourceScore := source.ResourceScore(kind, cluster.GetHighSpaceRatio(), cluster.GetLowSpaceRatio(), sourceInfluence-tolerantResource)
-	targetScore := target.ResourceScore(kind, cluster.GetHighSpaceRatio(), cluster.GetLowSpaceRatio(), targetInfluence+tolerantResource)
+	sourceScore, targetScore := balanceScores(cluster, source, target, kind, sourceInfluence-tolerantResource, targetInfluence+tolerantResource)
 	if cluster.IsDebugMetricsEnabled() {
 		opInfluenceStatus.WithLabelValues(scheduleName, strconv.FormatUint(sourceID, 10), "source").Set(float64(sourceInfluence))
 		opInfluenceStatus.WithLabelValues(scheduleName, strconv.FormatUint(targetID, 10), "target").Set(float64(targetInfluence))
@@ -92,6 +91,49 @@ func shouldBalance(cluster opt.Cluster, source, target *core.StoreInfo, region *
 	return shouldBalance
 }
 
+// balanceScores returns the scores of the source and the target stores with
+// the deltas.
+func balanceScores(cluster opt.Cluster, source, target *core.StoreInfo, kind core.ScheduleKind, sourceDelta, targetDelta int64) (float64, float64) {
+	sourceScore := source.ResourceScore(kind, cluster.GetHighSpaceRatio(), cluster.GetLowSpaceRatio(), sourceDelta)
+	targetScore := target.ResourceScore(kind, cluster.GetHighSpaceRatio(), cluster.GetLowSpaceRatio(), targetDelta)
+	return sourceScore, targetScore
+}
+
+// balanceReason returns the reason of the operator which passes shouldBalance,
+// with the scores of the stores including the operator influence and the
+// tolerance.
+func balanceReason(cluster opt.Cluster, source, target *core.StoreInfo, region *core.RegionInfo, kind core.ScheduleKind, opInfluence operator.OpInfluence) *operator.Reason {
+	sourceInfluence := opInfluence.GetStoreInfluence(source.GetID()).ResourceProperty(kind)
+	targetInfluence := opInfluence.GetStoreInfluence(target.GetID()).ResourceProperty(kind)
+	sourceScore, targetScore := balanceScores(cluster, source, target, kind, sourceInfluence, targetInfluence)
+	return operator.NewReason(operator.ReasonScoreImbalance, source.GetID(), target.GetID()).
+		With("source-score", sourceScore).
+		With("target-score", targetScore).
+		With("tolerant-resource", float64(getTolerantResource(cluster, region, kind)))
+}
+
+// shouldBalanceLeaderCount checks whether the leader count difference between
+// the source and target stores, including the operator influence, exceeds the
+// absolute tolerant count.
+func shouldBalanceLeaderCount(source, target *core.StoreInfo, opInfluence operator.OpInfluence, tolerantCount int64) bool {
+	kind := core.NewScheduleKind(core.LeaderKind, core.ByCount)
+	sourceCount := int64(source.GetLeaderCount()) + opInfluence.GetStoreInfluence(source.GetID()).ResourceProperty(kind)
+	targetCount := int64(target.GetLeaderCount()) + opInfluence.GetStoreInfluence(target.GetID()).ResourceProperty(kind)
+	return sourceCount-targetCount > tolerantCount
+}
+
+// balanceLeaderCountReason returns the reason of the operator which passes
+// shouldBalanceLeaderCount.
+func balanceLeaderCountReason(source, target *core.StoreInfo, opInfluence operator.OpInfluence, tolerantCount int64) *operator.Reason {
+	kind := core.NewScheduleKind(core.LeaderKind, core.ByCount)
+	sourceCount := int64(source.GetLeaderCount()) + opInfluence.GetStoreInfluence(source.GetID()).ResourceProperty(kind)
+	targetCount := int64(target.GetLeaderCount()) + opInfluence.GetStoreInfluence(target.GetID()).ResourceProperty(kind)
+	return operator.NewReason(operator.ReasonCountImbalance, source.GetID(), target.GetID()).
+		With("source-count", float64(sourceCount)).
+		With("target-count", float64(targetCount)).
+		With("tolerant-count", float64(tolerantCount))
+}
+
 func getTolerantResource(cluster opt.Cluster, region *core.RegionInfo, kind core.ScheduleKind) int64 {
 	if kind.Resource == core.LeaderKind && kind.Policy == core.ByCount {
 		tolerantSizeRatio := cluster.GetTolerantSizeRatio()
@@ -341,6 +383,19 @@ func maxLoad(a, b *storeLoad) *storeLoad {
 type storeLoadDetail struct {
 	LoadPred *storeLoadPred
 	HotPeers []*statistics.HotPeerStat
+	// IOFactor is the ratio of the IO capacity of the store to the average.
+	IOFactor float64
+}
+
+// normalizedLoadPred returns the load of the store whose byte rates are
+// divided by the IO factor, which is used to compare the loads of the stores.
+func (li *storeLoadDetail) normalizedLoadPred() *storeLoadPred {
+	lp := *li.LoadPred
+	if li.IOFactor > 0 {
+		lp.Current.ByteRate /= li.IOFactor
+		lp.Future.ByteRate /= li.IOFactor
+	}
+	return &lp
 }
 
 func (li *storeLoadDetail) toHotPeersStat() *statistics.HotPeersStat {
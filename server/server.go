@@ -53,6 +53,7 @@ import (
 	"github.com/pingcap/pd/v4/server/member"
 	syncer "github.com/pingcap/pd/v4/server/region_syncer"
 	"github.com/pingcap/pd/v4/server/schedule/opt"
+	"github.com/pingcap/pd/v4/server/statistics"
 	"github.com/pingcap/pd/v4/server/tso"
 	"github.com/pingcap/sysutil"
 	"github.com/pkg/errors"
@@ -62,6 +63,10 @@ import (
 	"go.etcd.io/etcd/pkg/types"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+
+	// Register the gzip compressor, so that the responses are compressed for
+	// the clients requesting the gzip encoding.
+	_ "google.golang.org/grpc/encoding/gzip"
 )
 
 const (
@@ -124,6 +129,13 @@ type Server struct {
 	basicCluster *core.BasicCluster
 	// for tso.
 	tso *tso.TimestampOracle
+	// tsoRequests counts the TSO requests handled.
+	tsoRequests *statistics.SlidingCounter
+	// tsoResetMu serializes the TSO resets and the updates of their history.
+	tsoResetMu sync.Mutex
+	// configLock serializes the updates of the schedule, replication and label
+	// property configs, so that a config batch is applied atomically.
+	configLock sync.Mutex
 	// for raft cluster
 	cluster *cluster.RaftCluster
 	// For async region heartbeat.
@@ -227,6 +239,7 @@ func CreateServer(ctx context.Context, cfg *config.Config, serviceBuilders ...Ha
 		ctx:               ctx,
 		startTimestamp:    time.Now().Unix(),
 		DiagnosticsServer: sysutil.NewDiagnosticsServer(cfg.Log.File.Filename),
+		tsoRequests:       statistics.NewSlidingCounter(TSORateWindow, tsoRateBuckets),
 	}
 
 	s.cfgManager = configmanager.NewConfigManager(s)
@@ -247,7 +260,7 @@ func CreateServer(ctx context.Context, cfg *config.Config, serviceBuilders ...Ha
 	etcdCfg.ServiceRegister = func(gs *grpc.Server) {
 		pdpb.RegisterPDServer(gs, s)
 		diagnosticspb.RegisterDiagnosticsServer(gs, s)
-		configpb.RegisterConfigServer(gs, s.cfgManager)
+		configpb.RegisterConfigServer(gs, &configService{ConfigManager: s.cfgManager, s: s})
 	}
 	s.etcdCfg = etcdCfg
 	if EnableZap {
@@ -539,6 +552,10 @@ func (s *Server) bootstrapCluster(req *pdpb.BootstrapRequest) (*pdpb.BootstrapRe
 	timeData := typeutil.Uint64ToBytes(uint64(nano))
 	ops = append(ops, clientv3.OpPut(bootstrapKey, string(timeData)))
 
+	// Set bootstrap store version
+	storeVersionKey := makeBootstrapStoreVersionKey(clusterRootPath)
+	ops = append(ops, clientv3.OpPut(storeVersionKey, req.GetStore().GetVersion()))
+
 	// Set store meta
 	storeMeta := req.GetStore()
 	storePath := makeStoreKey(clusterRootPath, storeMeta.GetId())
@@ -642,6 +659,12 @@ func (s *Server) GetMember() *member.Member {
 	return s.member
 }
 
+// GetRegionSyncer returns the region syncer, which syncs the regions to the
+// followers on the leader, or from the leader on a follower.
+func (s *Server) GetRegionSyncer() *syncer.RegionSyncer {
+	return s.cluster.GetRegionSyncer()
+}
+
 // GetStorage returns the backend storage of server.
 func (s *Server) GetStorage() *core.Storage {
 	return s.storage
@@ -741,6 +764,8 @@ func (s *Server) GetScheduleConfig() *config.ScheduleConfig {
 
 // SetScheduleConfig sets the balance config information.
 func (s *Server) SetScheduleConfig(cfg config.ScheduleConfig) error {
+	s.configLock.Lock()
+	defer s.configLock.Unlock()
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
@@ -770,6 +795,8 @@ func (s *Server) GetReplicationConfig() *config.ReplicationConfig {
 
 // SetReplicationConfig sets the replication config.
 func (s *Server) SetReplicationConfig(cfg config.ReplicationConfig) error {
+	s.configLock.Lock()
+	defer s.configLock.Unlock()
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
@@ -804,6 +831,16 @@ func (s *Server) SetReplicationConfig(cfg config.ReplicationConfig) error {
 		return err
 	}
 	log.Info("replication config is updated", zap.Reflect("new", cfg), zap.Reflect("old", old))
+	if cfg.EnablePlacementRules != old.EnablePlacementRules {
+		if raftCluster := s.GetRaftCluster(); raftCluster != nil {
+			raftCluster.OnPlacementRulesToggled(cfg.EnablePlacementRules)
+		}
+	}
+	if strings.Join(cfg.LocationLabels, ",") != strings.Join(old.LocationLabels, ",") {
+		if raftCluster := s.GetRaftCluster(); raftCluster != nil {
+			raftCluster.OnLocationLabelsUpdated(old.LocationLabels, cfg.LocationLabels)
+		}
+	}
 	return nil
 }
 
@@ -816,6 +853,9 @@ func (s *Server) GetPDServerConfig() *config.PDServerConfig {
 
 // SetPDServerConfig sets the server config.
 func (s *Server) SetPDServerConfig(cfg config.PDServerConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
 	old := s.scheduleOpt.LoadPDServerConfig()
 	s.scheduleOpt.SetPDServerConfig(&cfg)
 	if err := s.scheduleOpt.Persist(s.storage); err != nil {
@@ -832,6 +872,8 @@ func (s *Server) SetPDServerConfig(cfg config.PDServerConfig) error {
 
 // SetLabelPropertyConfig sets the label property config.
 func (s *Server) SetLabelPropertyConfig(cfg config.LabelPropertyConfig) error {
+	s.configLock.Lock()
+	defer s.configLock.Unlock()
 	old := s.scheduleOpt.LoadLabelPropertyConfig()
 	s.scheduleOpt.SetLabelPropertyConfig(cfg)
 	if err := s.scheduleOpt.Persist(s.storage); err != nil {
@@ -873,6 +915,8 @@ func (s *Server) SetLogConfig(cfg log.Config) error {
 
 // SetLabelProperty inserts a label property config.
 func (s *Server) SetLabelProperty(typ, labelKey, labelValue string) error {
+	s.configLock.Lock()
+	defer s.configLock.Unlock()
 	s.scheduleOpt.SetLabelProperty(typ, labelKey, labelValue)
 	err := s.scheduleOpt.Persist(s.storage)
 	if err != nil {
@@ -892,6 +936,8 @@ func (s *Server) SetLabelProperty(typ, labelKey, labelValue string) error {
 
 // DeleteLabelProperty deletes a label property config.
 func (s *Server) DeleteLabelProperty(typ, labelKey, labelValue string) error {
+	s.configLock.Lock()
+	defer s.configLock.Unlock()
 	s.scheduleOpt.DeleteLabelProperty(typ, labelKey, labelValue)
 	err := s.scheduleOpt.Persist(s.storage)
 	if err != nil {
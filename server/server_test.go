@@ -21,6 +21,8 @@ import (
 	"testing"
 
 	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/v4/pkg/etcdutil"
 	"github.com/pingcap/pd/v4/pkg/testutil"
 	"github.com/pingcap/pd/v4/server/config"
@@ -185,6 +187,54 @@ func (s *testServerSuite) TestCheckClusterID(c *C) {
 	testutil.CleanServer(cfgA.DataDir)
 }
 
+var _ = Suite(&testBootstrapVersionSuite{})
+
+type testBootstrapVersionSuite struct{}
+
+func (s *testBootstrapVersionSuite) TestBootstrapVersion(c *C) {
+	svr, cleanup, err := NewTestServer(c)
+	defer cleanup()
+	c.Assert(err, IsNil)
+	mustWaitLeader(c, []*Server{svr})
+	grpcPDClient := testutil.MustNewGrpcClient(c, svr.GetAddr())
+
+	bootstrap := func(version string) *pdpb.BootstrapResponse {
+		req := &pdpb.BootstrapRequest{
+			Header: testutil.NewRequestHeader(svr.clusterID),
+			Store:  &metapb.Store{Id: 1, Address: "127.0.0.1:0", Version: version},
+			Region: &metapb.Region{Id: 2, Peers: []*metapb.Peer{{Id: 3, StoreId: 1}}},
+		}
+		resp, err := grpcPDClient.Bootstrap(context.Background(), req)
+		c.Assert(err, IsNil)
+		return resp
+	}
+
+	// Lower than the minimum version supported by PD.
+	resp := bootstrap("1.0.0-alpha")
+	c.Assert(resp.GetHeader().GetError().GetType(), Equals, pdpb.ErrorType_INCOMPATIBLE_VERSION)
+
+	cfg := svr.GetPDServerConfig()
+	cfg.MinBootstrapVersion = "invalid"
+	c.Assert(svr.SetPDServerConfig(*cfg), NotNil)
+	cfg.MinBootstrapVersion = "v4.0.0"
+	c.Assert(svr.SetPDServerConfig(*cfg), IsNil)
+
+	// Lower than the configured minimum version.
+	for _, version := range []string{"", "3.1.2", "4.0.0-rc.1", "invalid"} {
+		resp = bootstrap(version)
+		c.Assert(resp.GetHeader().GetError().GetType(), Equals, pdpb.ErrorType_INCOMPATIBLE_VERSION)
+		c.Assert(svr.GetRaftCluster(), IsNil)
+	}
+
+	// Equal to the configured minimum version.
+	resp = bootstrap("v4.0.0")
+	c.Assert(resp.GetHeader().GetError(), IsNil)
+	c.Assert(svr.GetRaftCluster(), NotNil)
+	status, err := svr.GetClusterStatus()
+	c.Assert(err, IsNil)
+	c.Assert(status.BootstrapStoreVersion, Equals, "v4.0.0")
+}
+
 var _ = Suite(&testServerHandlerSuite{})
 
 type testServerHandlerSuite struct{}
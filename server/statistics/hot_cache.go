@@ -30,10 +30,10 @@ type HotCache struct {
 }
 
 // NewHotCache creates a new hot spot cache.
-func NewHotCache() *HotCache {
+func NewHotCache(opt ScheduleOptions) *HotCache {
 	return &HotCache{
-		writeFlow: NewHotStoresStats(WriteFlow),
-		readFlow:  NewHotStoresStats(ReadFlow),
+		writeFlow: NewHotStoresStats(WriteFlow, opt),
+		readFlow:  NewHotStoresStats(ReadFlow, opt),
 	}
 }
 
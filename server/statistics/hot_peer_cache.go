@@ -33,30 +33,19 @@ const (
 	hotRegionAntiCount = 2
 )
 
-var (
-	minHotThresholds = [2][dimLen]float64{
-		WriteFlow: {
-			byteDim: 1 * 1024,
-			keyDim:  32,
-		},
-		ReadFlow: {
-			byteDim: 8 * 1024,
-			keyDim:  128,
-		},
-	}
-)
-
 // hotPeerCache saves the hot peer's statistics.
 type hotPeerCache struct {
 	kind           FlowKind
+	opt            ScheduleOptions
 	peersOfStore   map[uint64]*TopN               // storeID -> hot peers
 	storesOfRegion map[uint64]map[uint64]struct{} // regionID -> storeIDs
 }
 
 // NewHotStoresStats creates a HotStoresStats
-func NewHotStoresStats(kind FlowKind) *hotPeerCache {
+func NewHotStoresStats(kind FlowKind, opt ScheduleOptions) *hotPeerCache {
 	return &hotPeerCache{
 		kind:           kind,
+		opt:            opt,
 		peersOfStore:   make(map[uint64]*TopN),
 		storesOfRegion: make(map[uint64]map[uint64]struct{}),
 	}
@@ -217,7 +206,7 @@ func (f *hotPeerCache) isRegionExpired(region *core.RegionInfo, storeID uint64)
 }
 
 func (f *hotPeerCache) calcHotThresholds(stats *StoresStats, storeID uint64) [dimLen]float64 {
-	minThresholds := minHotThresholds[f.kind]
+	minThresholds := f.minHotThresholds()
 	tn, ok := f.peersOfStore[storeID]
 	if !ok || tn.Len() < topNN {
 		return minThresholds
@@ -232,6 +221,19 @@ func (f *hotPeerCache) calcHotThresholds(stats *StoresStats, storeID uint64) [di
 	return ret
 }
 
+// minHotThresholds returns the configured min rates of a hot peer, which are
+// read on every check so that the changes apply to the new samples.
+func (f *hotPeerCache) minHotThresholds() [dimLen]float64 {
+	var ret [dimLen]float64
+	switch f.kind {
+	case WriteFlow:
+		ret[byteDim], ret[keyDim] = f.opt.GetHotWriteThresholds()
+	case ReadFlow:
+		ret[byteDim], ret[keyDim] = f.opt.GetHotReadThresholds()
+	}
+	return ret
+}
+
 // gets the storeIDs, including old region and new region
 func (f *hotPeerCache) getAllStoreIDs(region *core.RegionInfo) []uint64 {
 	storeIDs := make(map[uint64]struct{})
@@ -16,6 +16,7 @@ package statistics
 import (
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/pkg/mock/mockoption"
 	"github.com/pingcap/pd/v4/server/core"
 )
 
@@ -24,7 +25,7 @@ var _ = Suite(&testHotPeerCache{})
 type testHotPeerCache struct{}
 
 func (t *testHotPeerCache) TestStoreTimeUnsync(c *C) {
-	cache := NewHotStoresStats(WriteFlow)
+	cache := NewHotStoresStats(WriteFlow, mockoption.NewScheduleOptions())
 	stats := NewStoresStats()
 	peers := newPeers(3,
 		func(i int) uint64 { return uint64(10000 + i) },
@@ -71,6 +71,22 @@ var (
 			Name:      "label_level",
 			Help:      "Number of regions in the different label level.",
 		}, []string{"type"})
+
+	regionActivityGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "regions",
+			Name:      "activity",
+			Help:      "Number of regions in the different activity classes.",
+		}, []string{"type"})
+
+	regionLeaderChurnGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "regions",
+			Name:      "leader_churn",
+			Help:      "Number of the leader changes of the regions in the recent window.",
+		})
 )
 
 func init() {
@@ -81,4 +97,6 @@ func init() {
 	prometheus.MustRegister(placementStatusGauge)
 	prometheus.MustRegister(configStatusGauge)
 	prometheus.MustRegister(regionLabelLevelGauge)
+	prometheus.MustRegister(regionActivityGauge)
+	prometheus.MustRegister(regionLeaderChurnGauge)
 }
@@ -0,0 +1,166 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+// RegionActivity is the activity class of a region.
+type RegionActivity int
+
+// Activity classes of regions.
+const (
+	ActivityNormal RegionActivity = iota
+	ActivityHot
+	ActivityIdle
+)
+
+const (
+	// RegionIdleByteRate is the max read and write byte rate of an idle region.
+	RegionIdleByteRate = 1024
+	// RegionIdleKeyRate is the max read and write key rate of an idle region.
+	RegionIdleKeyRate = 1
+	// regionActiveAfterEpochChange is how long a region is not considered as
+	// idle after its epoch changes.
+	regionActiveAfterEpochChange = 10 * time.Minute
+)
+
+var regionActivityNames = map[RegionActivity]string{
+	ActivityNormal: "normal",
+	ActivityHot:    "hot",
+	ActivityIdle:   "idle",
+}
+
+func (a RegionActivity) String() string {
+	if name, ok := regionActivityNames[a]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// SuggestedHeartbeatInterval returns the heartbeat interval suggested for the
+// regions of the activity class.
+func (a RegionActivity) SuggestedHeartbeatInterval() time.Duration {
+	switch a {
+	case ActivityHot:
+		return RegionHeartBeatReportInterval / 2 * time.Second
+	case ActivityIdle:
+		return 5 * RegionHeartBeatReportInterval * time.Second
+	default:
+		return RegionHeartBeatReportInterval * time.Second
+	}
+}
+
+type regionActivityEntry struct {
+	activity       RegionActivity
+	epochChangedAt time.Time
+}
+
+// RegionActivityStats classifies the regions by their activity.
+type RegionActivityStats struct {
+	sync.RWMutex
+	entries map[uint64]*regionActivityEntry
+	counter map[RegionActivity]int
+}
+
+// NewRegionActivityStats creates a new RegionActivityStats.
+func NewRegionActivityStats() *RegionActivityStats {
+	return &RegionActivityStats{
+		entries: make(map[uint64]*regionActivityEntry),
+		counter: make(map[RegionActivity]int),
+	}
+}
+
+// Observe classifies the region by the flow of its heartbeat and records the
+// class. A hot region is always hot, and a region whose epoch changed recently
+// is never idle.
+func (s *RegionActivityStats) Observe(region *core.RegionInfo, isHot, epochChanged bool) RegionActivity {
+	s.Lock()
+	defer s.Unlock()
+	now := time.Now()
+	regionID := region.GetID()
+	entry, ok := s.entries[regionID]
+	if !ok {
+		entry = &regionActivityEntry{}
+		s.entries[regionID] = entry
+	} else {
+		s.counter[entry.activity]--
+	}
+	if epochChanged {
+		entry.epochChangedAt = now
+	}
+
+	switch {
+	case isHot:
+		entry.activity = ActivityHot
+	case !entry.epochChangedAt.IsZero() && now.Sub(entry.epochChangedAt) < regionActiveAfterEpochChange:
+		entry.activity = ActivityNormal
+	case isRegionFlowIdle(region):
+		entry.activity = ActivityIdle
+	default:
+		entry.activity = ActivityNormal
+	}
+	s.counter[entry.activity]++
+	return entry.activity
+}
+
+func isRegionFlowIdle(region *core.RegionInfo) bool {
+	interval := region.GetInterval()
+	seconds := uint64(RegionHeartBeatReportInterval)
+	if interval.GetEndTimestamp() > interval.GetStartTimestamp() {
+		seconds = interval.GetEndTimestamp() - interval.GetStartTimestamp()
+	}
+	byteRate := float64(region.GetBytesWritten()+region.GetBytesRead()) / float64(seconds)
+	keyRate := float64(region.GetKeysWritten()+region.GetKeysRead()) / float64(seconds)
+	return byteRate <= RegionIdleByteRate && keyRate <= RegionIdleKeyRate
+}
+
+// GetRegionActivity returns the activity class of the region.
+func (s *RegionActivityStats) GetRegionActivity(regionID uint64) (RegionActivity, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	entry, ok := s.entries[regionID]
+	if !ok {
+		return ActivityNormal, false
+	}
+	return entry.activity, true
+}
+
+// ClearDefunctRegion is used to handle the overlap region.
+func (s *RegionActivityStats) ClearDefunctRegion(regionID uint64) {
+	s.Lock()
+	defer s.Unlock()
+	if entry, ok := s.entries[regionID]; ok {
+		s.counter[entry.activity]--
+		delete(s.entries, regionID)
+	}
+}
+
+// Collect collects the metrics of the regions' activity classes.
+func (s *RegionActivityStats) Collect() {
+	s.RLock()
+	defer s.RUnlock()
+	for activity, name := range regionActivityNames {
+		regionActivityGauge.WithLabelValues(name).Set(float64(s.counter[activity]))
+	}
+}
+
+// Reset resets the metrics of the regions' activity classes.
+func (s *RegionActivityStats) Reset() {
+	regionActivityGauge.Reset()
+}
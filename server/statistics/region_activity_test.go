@@ -0,0 +1,71 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+var _ = Suite(&testRegionActivitySuite{})
+
+type testRegionActivitySuite struct{}
+
+func (t *testRegionActivitySuite) TestRegionActivity(c *C) {
+	peer := &metapb.Peer{Id: 2, StoreId: 1}
+	meta := &metapb.Region{Id: 1, Peers: []*metapb.Peer{peer}}
+	newRegion := func(bytes, keys uint64) *core.RegionInfo {
+		return core.NewRegionInfo(meta, peer,
+			core.SetWrittenBytes(bytes),
+			core.SetWrittenKeys(keys),
+			core.SetReportInterval(10))
+	}
+	stats := NewRegionActivityStats()
+	_, ok := stats.GetRegionActivity(1)
+	c.Assert(ok, IsFalse)
+
+	// The region without flow is idle.
+	activity := stats.Observe(newRegion(0, 0), false, false)
+	c.Assert(activity, Equals, ActivityIdle)
+	c.Assert(activity.SuggestedHeartbeatInterval(), Equals, 5*RegionHeartBeatReportInterval*time.Second)
+	c.Assert(stats.counter[ActivityIdle], Equals, 1)
+
+	// The suggested interval changes with the flow.
+	activity = stats.Observe(newRegion(100*1024, 100), false, false)
+	c.Assert(activity, Equals, ActivityNormal)
+	c.Assert(activity.SuggestedHeartbeatInterval(), Equals, RegionHeartBeatReportInterval*time.Second)
+	activity = stats.Observe(newRegion(100*1024, 100), true, false)
+	c.Assert(activity, Equals, ActivityHot)
+	c.Assert(activity.SuggestedHeartbeatInterval(), Equals, RegionHeartBeatReportInterval/2*time.Second)
+	c.Assert(stats.counter[ActivityIdle], Equals, 0)
+	c.Assert(stats.counter[ActivityNormal], Equals, 0)
+	c.Assert(stats.counter[ActivityHot], Equals, 1)
+
+	// The region is not idle for a while after its epoch changes.
+	c.Assert(stats.Observe(newRegion(0, 0), false, true), Equals, ActivityNormal)
+	c.Assert(stats.Observe(newRegion(0, 0), false, false), Equals, ActivityNormal)
+	stats.entries[1].epochChangedAt = time.Now().Add(-regionActiveAfterEpochChange)
+	c.Assert(stats.Observe(newRegion(0, 0), false, false), Equals, ActivityIdle)
+	activity, ok = stats.GetRegionActivity(1)
+	c.Assert(ok, IsTrue)
+	c.Assert(activity, Equals, ActivityIdle)
+
+	stats.ClearDefunctRegion(1)
+	_, ok = stats.GetRegionActivity(1)
+	c.Assert(ok, IsFalse)
+	c.Assert(stats.counter[ActivityIdle], Equals, 0)
+}
@@ -66,6 +66,11 @@ func (r *RegionStatistics) GetRegionStatsByType(typ RegionStatisticType) []*core
 	return res
 }
 
+// GetRegionStatsCount gets the count of the regions of the type.
+func (r *RegionStatistics) GetRegionStatsCount(typ RegionStatisticType) int {
+	return len(r.stats[typ])
+}
+
 func (r *RegionStatistics) deleteEntry(deleteIndex RegionStatisticType, regionID uint64) {
 	for typ := RegionStatisticType(1); typ <= deleteIndex; typ <<= 1 {
 		if deleteIndex&typ != 0 {
@@ -0,0 +1,188 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+const (
+	// RegionGrowthDecay is the time constant of the exponentially weighted
+	// moving average of the growth rates, so that the rate of the last
+	// RegionGrowthDecay weighs about 63%.
+	RegionGrowthDecay = 5 * time.Minute
+	// RegionGrowthStaleTime is how long a region is tracked without being
+	// reported by a heartbeat.
+	RegionGrowthStaleTime = 10 * time.Minute
+	// regionGrowthMinInterval is the shortest interval between the samples of
+	// a region, so that the rates are not skewed by the close heartbeats.
+	regionGrowthMinInterval = time.Second
+)
+
+// RegionGrowth is the growth rates of the approximate size and keys of a
+// region.
+type RegionGrowth struct {
+	RegionID uint64 `json:"region_id"`
+	// ApproximateSize is the latest approximate size in MB.
+	ApproximateSize int64 `json:"approximate_size"`
+	ApproximateKeys int64 `json:"approximate_keys"`
+	// SizeRate is the growth rate of the approximate size in MB per minute.
+	SizeRate float64 `json:"size_rate"`
+	// KeysRate is the growth rate of the approximate keys per minute.
+	KeysRate float64 `json:"keys_rate"`
+}
+
+type regionGrowthEntry struct {
+	version    uint64
+	size, keys int64
+	sampledAt  time.Time
+	observedAt time.Time
+	// The rates are per minute, and valid if sampled is true.
+	sizeRate, keysRate float64
+	sampled            bool
+}
+
+// RegionGrowthStats tracks the growth rates of the approximate size and keys
+// of the regions over the recent heartbeats, by the exponentially weighted
+// moving average. The regions which are split, merged or not reported for
+// RegionGrowthStaleTime are tracked from scratch or evicted.
+type RegionGrowthStats struct {
+	sync.RWMutex
+	entries map[uint64]*regionGrowthEntry
+	now     func() time.Time
+}
+
+// NewRegionGrowthStats creates a new RegionGrowthStats.
+func NewRegionGrowthStats() *RegionGrowthStats {
+	return &RegionGrowthStats{
+		entries: make(map[uint64]*regionGrowthEntry),
+		now:     time.Now,
+	}
+}
+
+// Observe samples the approximate size and keys of the region. The rates
+// restart if the range of the region changes, as the size changes by the
+// split or merge rather than the writes.
+func (s *RegionGrowthStats) Observe(region *core.RegionInfo) {
+	s.Lock()
+	defer s.Unlock()
+	now := s.now()
+	size, keys := region.GetApproximateSize(), region.GetApproximateKeys()
+	version := region.GetRegionEpoch().GetVersion()
+	entry, ok := s.entries[region.GetID()]
+	if !ok || entry.version != version || now.Sub(entry.observedAt) >= RegionGrowthStaleTime {
+		s.entries[region.GetID()] = &regionGrowthEntry{
+			version:    version,
+			size:       size,
+			keys:       keys,
+			sampledAt:  now,
+			observedAt: now,
+		}
+		return
+	}
+	entry.observedAt = now
+	interval := now.Sub(entry.sampledAt)
+	if interval < regionGrowthMinInterval {
+		return
+	}
+	minutes := interval.Minutes()
+	// The shrinking by the compactions is not a growth.
+	sizeRate := math.Max(float64(size-entry.size)/minutes, 0)
+	keysRate := math.Max(float64(keys-entry.keys)/minutes, 0)
+	if entry.sampled {
+		weight := 1 - math.Exp(-float64(interval)/float64(RegionGrowthDecay))
+		entry.sizeRate += weight * (sizeRate - entry.sizeRate)
+		entry.keysRate += weight * (keysRate - entry.keysRate)
+	} else {
+		entry.sizeRate, entry.keysRate, entry.sampled = sizeRate, keysRate, true
+	}
+	entry.size, entry.keys, entry.sampledAt = size, keys, now
+}
+
+// GetRegionGrowth returns the growth rates of the region, or nil if they are
+// not sampled yet.
+func (s *RegionGrowthStats) GetRegionGrowth(regionID uint64) *RegionGrowth {
+	s.RLock()
+	defer s.RUnlock()
+	entry, ok := s.entries[regionID]
+	if !ok || !entry.sampled || s.now().Sub(entry.observedAt) >= RegionGrowthStaleTime {
+		return nil
+	}
+	return entry.growth(regionID)
+}
+
+func (e *regionGrowthEntry) growth(regionID uint64) *RegionGrowth {
+	return &RegionGrowth{
+		RegionID:        regionID,
+		ApproximateSize: e.size,
+		ApproximateKeys: e.keys,
+		SizeRate:        e.sizeRate,
+		KeysRate:        e.keysRate,
+	}
+}
+
+// GetTopGrowers returns at most top regions growing in size, the fastest
+// first. The stale regions are evicted.
+func (s *RegionGrowthStats) GetTopGrowers(top int) []*RegionGrowth {
+	s.Lock()
+	defer s.Unlock()
+	s.evictStaleLocked()
+	res := make([]*RegionGrowth, 0, len(s.entries))
+	for id, entry := range s.entries {
+		if entry.sampled && (entry.sizeRate > 0 || entry.keysRate > 0) {
+			res = append(res, entry.growth(id))
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].SizeRate != res[j].SizeRate {
+			return res[i].SizeRate > res[j].SizeRate
+		}
+		if res[i].KeysRate != res[j].KeysRate {
+			return res[i].KeysRate > res[j].KeysRate
+		}
+		return res[i].RegionID < res[j].RegionID
+	})
+	if len(res) > top {
+		res = res[:top]
+	}
+	return res
+}
+
+// EvictStale evicts the regions not reported for RegionGrowthStaleTime.
+func (s *RegionGrowthStats) EvictStale() {
+	s.Lock()
+	defer s.Unlock()
+	s.evictStaleLocked()
+}
+
+func (s *RegionGrowthStats) evictStaleLocked() {
+	now := s.now()
+	for id, entry := range s.entries {
+		if now.Sub(entry.observedAt) >= RegionGrowthStaleTime {
+			delete(s.entries, id)
+		}
+	}
+}
+
+// ClearDefunctRegion is used to handle the overlap region.
+func (s *RegionGrowthStats) ClearDefunctRegion(regionID uint64) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.entries, regionID)
+}
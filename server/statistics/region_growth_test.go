@@ -0,0 +1,93 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"math"
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+var _ = Suite(&testRegionGrowthSuite{})
+
+type testRegionGrowthSuite struct{}
+
+func (t *testRegionGrowthSuite) TestRegionGrowth(c *C) {
+	stats := NewRegionGrowthStats()
+	now := time.Now()
+	stats.now = func() time.Time { return now }
+	// heartbeat reports the regions of the version with the sizes in MB and
+	// the keys of 1000 times the sizes.
+	heartbeat := func(version uint64, sizes map[uint64]int64) {
+		for id, size := range sizes {
+			region := core.NewRegionInfo(&metapb.Region{Id: id, RegionEpoch: &metapb.RegionEpoch{Version: version}}, nil,
+				core.SetApproximateSize(size), core.SetApproximateKeys(size*1000))
+			stats.Observe(region)
+		}
+	}
+	tops := func(top int) []uint64 {
+		var res []uint64
+		for _, growth := range stats.GetTopGrowers(top) {
+			res = append(res, growth.RegionID)
+		}
+		return res
+	}
+
+	// The first heartbeats are the baselines.
+	heartbeat(1, map[uint64]int64{1: 10, 2: 10, 3: 100})
+	c.Assert(stats.GetTopGrowers(10), HasLen, 0)
+	c.Assert(stats.GetRegionGrowth(1), IsNil)
+
+	now = now.Add(time.Minute)
+	heartbeat(1, map[uint64]int64{1: 20, 2: 40, 3: 90})
+	c.Assert(tops(10), DeepEquals, []uint64{2, 1})
+	growth := stats.GetRegionGrowth(2)
+	c.Assert(growth.SizeRate, Equals, float64(30))
+	c.Assert(growth.KeysRate, Equals, float64(30000))
+	c.Assert(growth.ApproximateSize, Equals, int64(40))
+	// The shrinking region is not growing.
+	c.Assert(stats.GetRegionGrowth(3).SizeRate, Equals, float64(0))
+
+	// The rates decay exponentially.
+	now = now.Add(time.Minute)
+	heartbeat(1, map[uint64]int64{1: 30, 2: 40, 3: 90})
+	decayed := 30 * math.Exp(-float64(time.Minute)/float64(RegionGrowthDecay))
+	c.Assert(math.Abs(stats.GetRegionGrowth(2).SizeRate-decayed) < 1e-9, IsTrue)
+	c.Assert(stats.GetRegionGrowth(1).SizeRate, Equals, float64(10))
+	c.Assert(tops(10), DeepEquals, []uint64{2, 1})
+	c.Assert(tops(1), DeepEquals, []uint64{2})
+	now = now.Add(5 * time.Minute)
+	heartbeat(1, map[uint64]int64{1: 80, 2: 40, 3: 90})
+	c.Assert(tops(10), DeepEquals, []uint64{1, 2})
+
+	// The close heartbeats are not sampled.
+	now = now.Add(time.Millisecond)
+	heartbeat(1, map[uint64]int64{1: 1000})
+	c.Assert(stats.GetRegionGrowth(1).ApproximateSize, Equals, int64(80))
+
+	// The split region is tracked from scratch.
+	heartbeat(2, map[uint64]int64{1: 40})
+	c.Assert(stats.GetRegionGrowth(1), IsNil)
+	c.Assert(tops(10), DeepEquals, []uint64{2})
+
+	// The merged and the stale regions are evicted.
+	stats.ClearDefunctRegion(2)
+	c.Assert(stats.GetTopGrowers(10), HasLen, 0)
+	now = now.Add(RegionGrowthStaleTime)
+	stats.EvictStale()
+	c.Assert(stats.entries, HasLen, 0)
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+const (
+	// LeaderChurnWindow is the sliding window in which the leader changes of
+	// the regions are counted.
+	LeaderChurnWindow = time.Hour
+	// leaderChurnHistorySize is the number of the latest leader stores kept for a region.
+	leaderChurnHistorySize = 5
+)
+
+// RegionLeaderChurn is the leader changes of a region in the recent window.
+type RegionLeaderChurn struct {
+	RegionID uint64 `json:"region_id"`
+	Changes  int    `json:"changes"`
+	// LeaderStores are the stores of the latest leaders, the current one last.
+	LeaderStores []uint64 `json:"leader_stores"`
+}
+
+type regionLeaderChurnEntry struct {
+	changes      []time.Time
+	leaderStores []uint64
+}
+
+// expire drops the leader changes out of the window.
+func (e *regionLeaderChurnEntry) expire(now time.Time) {
+	i := sort.Search(len(e.changes), func(i int) bool { return now.Sub(e.changes[i]) < LeaderChurnWindow })
+	e.changes = e.changes[i:]
+}
+
+// RegionLeaderChurnStats counts the leader changes of the regions in a
+// sliding window.
+type RegionLeaderChurnStats struct {
+	sync.RWMutex
+	entries map[uint64]*regionLeaderChurnEntry
+	now     func() time.Time
+}
+
+// NewRegionLeaderChurnStats creates a new RegionLeaderChurnStats.
+func NewRegionLeaderChurnStats() *RegionLeaderChurnStats {
+	return &RegionLeaderChurnStats{
+		entries: make(map[uint64]*regionLeaderChurnEntry),
+		now:     time.Now,
+	}
+}
+
+// Observe records a leader change if the leader of the region differs from
+// the one of the cached region.
+func (s *RegionLeaderChurnStats) Observe(origin, region *core.RegionInfo) {
+	if origin == nil || origin.GetLeader().GetId() == 0 || region.GetLeader().GetId() == 0 ||
+		origin.GetLeader().GetId() == region.GetLeader().GetId() {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	now := s.now()
+	entry, ok := s.entries[region.GetID()]
+	if !ok {
+		entry = &regionLeaderChurnEntry{leaderStores: []uint64{origin.GetLeader().GetStoreId()}}
+		s.entries[region.GetID()] = entry
+	}
+	entry.expire(now)
+	entry.changes = append(entry.changes, now)
+	entry.leaderStores = append(entry.leaderStores, region.GetLeader().GetStoreId())
+	if len(entry.leaderStores) > leaderChurnHistorySize {
+		entry.leaderStores = entry.leaderStores[len(entry.leaderStores)-leaderChurnHistorySize:]
+	}
+}
+
+// GetTopRegions returns at most top regions with the most leader changes in
+// the window.
+func (s *RegionLeaderChurnStats) GetTopRegions(top int) []*RegionLeaderChurn {
+	s.Lock()
+	defer s.Unlock()
+	s.expireLocked()
+	res := make([]*RegionLeaderChurn, 0, len(s.entries))
+	for id, entry := range s.entries {
+		res = append(res, &RegionLeaderChurn{
+			RegionID:     id,
+			Changes:      len(entry.changes),
+			LeaderStores: append([]uint64(nil), entry.leaderStores...),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Changes != res[j].Changes {
+			return res[i].Changes > res[j].Changes
+		}
+		return res[i].RegionID < res[j].RegionID
+	})
+	if len(res) > top {
+		res = res[:top]
+	}
+	return res
+}
+
+// GetTotalChanges returns the leader changes of all regions in the window.
+func (s *RegionLeaderChurnStats) GetTotalChanges() int {
+	s.Lock()
+	defer s.Unlock()
+	s.expireLocked()
+	var total int
+	for _, entry := range s.entries {
+		total += len(entry.changes)
+	}
+	return total
+}
+
+// expireLocked drops the leader changes out of the window, and the regions
+// without any leader change in the window.
+func (s *RegionLeaderChurnStats) expireLocked() {
+	now := s.now()
+	for id, entry := range s.entries {
+		entry.expire(now)
+		if len(entry.changes) == 0 {
+			delete(s.entries, id)
+		}
+	}
+}
+
+// ClearDefunctRegion is used to handle the overlap region.
+func (s *RegionLeaderChurnStats) ClearDefunctRegion(regionID uint64) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.entries, regionID)
+}
+
+// Collect collects the metrics of the leader changes.
+func (s *RegionLeaderChurnStats) Collect() {
+	regionLeaderChurnGauge.Set(float64(s.GetTotalChanges()))
+}
+
+// Reset resets the metrics of the leader changes.
+func (s *RegionLeaderChurnStats) Reset() {
+	regionLeaderChurnGauge.Set(0)
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+var _ = Suite(&testRegionLeaderChurnSuite{})
+
+type testRegionLeaderChurnSuite struct{}
+
+func (t *testRegionLeaderChurnSuite) TestRegionLeaderChurn(c *C) {
+	newRegion := func(id, leaderStore uint64) *core.RegionInfo {
+		var peers []*metapb.Peer
+		for storeID := uint64(1); storeID <= 3; storeID++ {
+			peers = append(peers, &metapb.Peer{Id: id*10 + storeID, StoreId: storeID})
+		}
+		return core.NewRegionInfo(&metapb.Region{Id: id, Peers: peers}, peers[leaderStore-1])
+	}
+	stats := NewRegionLeaderChurnStats()
+	now := time.Now()
+	stats.now = func() time.Time { return now }
+	// heartbeat simulates the heartbeats of the region with the leaders in turn.
+	heartbeat := func(id uint64, leaderStores ...uint64) {
+		origin := newRegion(id, leaderStores[0])
+		for _, store := range leaderStores[1:] {
+			region := newRegion(id, store)
+			stats.Observe(origin, region)
+			origin = region
+		}
+	}
+	tops := func(top int) []uint64 {
+		var res []uint64
+		for _, churn := range stats.GetTopRegions(top) {
+			res = append(res, churn.RegionID)
+		}
+		return res
+	}
+
+	// New regions and unchanged leaders are not counted.
+	stats.Observe(nil, newRegion(1, 1))
+	heartbeat(1, 1, 1, 1)
+	c.Assert(stats.GetTopRegions(10), HasLen, 0)
+
+	heartbeat(1, 1, 2, 1)
+	heartbeat(2, 1, 2, 1, 2, 1, 2, 3, 1)
+	heartbeat(3, 3, 1, 3, 1)
+	c.Assert(tops(10), DeepEquals, []uint64{2, 3, 1})
+	c.Assert(tops(2), DeepEquals, []uint64{2, 3})
+	churn := stats.GetTopRegions(1)[0]
+	c.Assert(churn.Changes, Equals, 7)
+	// Only the latest leader stores are kept.
+	c.Assert(churn.LeaderStores, DeepEquals, []uint64{2, 1, 2, 3, 1})
+	c.Assert(stats.GetTotalChanges(), Equals, 12)
+
+	// The changes out of the window are aged out.
+	now = now.Add(LeaderChurnWindow / 2)
+	heartbeat(1, 1, 2, 1, 2)
+	c.Assert(tops(10), DeepEquals, []uint64{2, 1, 3})
+	now = now.Add(LeaderChurnWindow / 2)
+	c.Assert(tops(10), DeepEquals, []uint64{1})
+	c.Assert(stats.GetTotalChanges(), Equals, 3)
+
+	stats.ClearDefunctRegion(1)
+	c.Assert(stats.GetTopRegions(10), HasLen, 0)
+}
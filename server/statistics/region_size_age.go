@@ -0,0 +1,106 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/pd/v4/pkg/typeutil"
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+// regionSizeAgeBuckets are the upper bounds of the buckets of the region size age distribution.
+var regionSizeAgeBuckets = []time.Duration{10 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+// RegionSizeAgeBucket is a bucket of the region size age distribution.
+// The regions in the bucket have the age in [Min, Max), Max is zero for the last bucket.
+type RegionSizeAgeBucket struct {
+	Min   typeutil.Duration `json:"min"`
+	Max   typeutil.Duration `json:"max"`
+	Count int               `json:"count"`
+}
+
+type regionSizeAgeEntry struct {
+	size, keys int64
+	updatedAt  time.Time
+}
+
+// age returns how long the stats have not been updated.
+func (e *regionSizeAgeEntry) age(now time.Time) time.Duration {
+	return now.Sub(e.updatedAt)
+}
+
+// RegionSizeAgeStats tracks how long the approximate size and keys of the
+// regions have not changed. PD does not ask the stores to re-report the stale
+// stats, as RegionHeartbeatResponse has no message only refreshing them, and a
+// split check with the APPROXIMATE policy splits the region.
+type RegionSizeAgeStats struct {
+	sync.RWMutex
+	entries map[uint64]*regionSizeAgeEntry
+	now     func() time.Time
+}
+
+// NewRegionSizeAgeStats creates a new RegionSizeAgeStats.
+func NewRegionSizeAgeStats() *RegionSizeAgeStats {
+	return &RegionSizeAgeStats{
+		entries: make(map[uint64]*regionSizeAgeEntry),
+		now:     time.Now,
+	}
+}
+
+// Observe records the approximate size and keys of the region.
+func (s *RegionSizeAgeStats) Observe(region *core.RegionInfo) {
+	s.Lock()
+	defer s.Unlock()
+	size, keys := region.GetApproximateSize(), region.GetApproximateKeys()
+	entry, ok := s.entries[region.GetID()]
+	if ok && entry.size == size && entry.keys == keys {
+		return
+	}
+	s.entries[region.GetID()] = &regionSizeAgeEntry{
+		size:      size,
+		keys:      keys,
+		updatedAt: s.now(),
+	}
+}
+
+// GetDistribution returns the distribution of the age of the regions' stats.
+func (s *RegionSizeAgeStats) GetDistribution() []*RegionSizeAgeBucket {
+	s.RLock()
+	defer s.RUnlock()
+	buckets := make([]*RegionSizeAgeBucket, 0, len(regionSizeAgeBuckets)+1)
+	var min time.Duration
+	for _, max := range regionSizeAgeBuckets {
+		buckets = append(buckets, &RegionSizeAgeBucket{Min: typeutil.NewDuration(min), Max: typeutil.NewDuration(max)})
+		min = max
+	}
+	buckets = append(buckets, &RegionSizeAgeBucket{Min: typeutil.NewDuration(min)})
+
+	now := s.now()
+	for _, entry := range s.entries {
+		age := entry.age(now)
+		i := sort.Search(len(regionSizeAgeBuckets), func(i int) bool { return age < regionSizeAgeBuckets[i] })
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// ClearDefunctRegion is used to handle the overlap region.
+func (s *RegionSizeAgeStats) ClearDefunctRegion(regionID uint64) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.entries, regionID)
+}
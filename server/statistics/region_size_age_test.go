@@ -0,0 +1,66 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/server/core"
+)
+
+var _ = Suite(&testRegionSizeAgeSuite{})
+
+type testRegionSizeAgeSuite struct{}
+
+func (t *testRegionSizeAgeSuite) TestRegionSizeAge(c *C) {
+	newRegion := func(id uint64, size, keys int64) *core.RegionInfo {
+		peer := &metapb.Peer{Id: id + 100, StoreId: 1}
+		return core.NewRegionInfo(&metapb.Region{Id: id, Peers: []*metapb.Peer{peer}}, peer,
+			core.SetApproximateSize(size),
+			core.SetApproximateKeys(keys))
+	}
+	stats := NewRegionSizeAgeStats()
+	now := time.Now()
+	stats.now = func() time.Time { return now }
+
+	stats.Observe(newRegion(1, 10, 100))
+	now = now.Add(10 * time.Minute)
+	stats.Observe(newRegion(2, 10, 100))
+	stats.Observe(newRegion(3, 10, 100))
+	now = now.Add(50 * time.Minute)
+	// Unchanged stats do not refresh the age.
+	stats.Observe(newRegion(1, 10, 100))
+	stats.Observe(newRegion(2, 10, 100))
+	// Either changed size or changed keys refreshes the age.
+	stats.Observe(newRegion(3, 10, 200))
+
+	counts := func() []int {
+		var res []int
+		for _, b := range stats.GetDistribution() {
+			res = append(res, b.Count)
+		}
+		return res
+	}
+	c.Assert(counts(), DeepEquals, []int{1, 1, 1, 0, 0})
+
+	now = now.Add(50 * time.Minute)
+	c.Assert(counts(), DeepEquals, []int{0, 1, 2, 0, 0})
+	now = now.Add(24 * time.Hour)
+	c.Assert(counts(), DeepEquals, []int{0, 0, 0, 0, 3})
+
+	stats.ClearDefunctRegion(2)
+	c.Assert(counts(), DeepEquals, []int{0, 0, 0, 0, 2})
+}
@@ -37,6 +37,8 @@ type ScheduleOptions interface {
 	GetHotRegionScheduleLimit() uint64
 	GetMaxReplicas() int
 	GetHotRegionCacheHitsThreshold() int
+	GetHotWriteThresholds() (byteRate, keyRate float64)
+	GetHotReadThresholds() (byteRate, keyRate float64)
 	GetMaxSnapshotCount() uint64
 	GetMaxPendingPeerCount() uint64
 	GetMaxMergeRegionSize() uint64
@@ -0,0 +1,148 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"sync"
+	"time"
+)
+
+// SlidingCounter counts the events in a sliding window by a ring of buckets,
+// so that both adding and reading take constant time. As the oldest bucket
+// is partially expired, a count covers more than the window minus a bucket.
+// It is threadsafe.
+type SlidingCounter struct {
+	sync.Mutex
+	window time.Duration
+	bucket time.Duration
+	counts []uint64
+	// head is the number of the buckets from the epoch to the latest bucket.
+	head int64
+	now  func() time.Time
+}
+
+// NewSlidingCounter creates a SlidingCounter with the buckets dividing the
+// window.
+func NewSlidingCounter(window time.Duration, buckets int) *SlidingCounter {
+	return &SlidingCounter{
+		window: window,
+		bucket: window / time.Duration(buckets),
+		counts: make([]uint64, buckets),
+		now:    time.Now,
+	}
+}
+
+// advanceLocked clears the buckets expired since the latest bucket.
+func (c *SlidingCounter) advanceLocked() int {
+	head := c.now().UnixNano() / int64(c.bucket)
+	n := int64(len(c.counts))
+	if head-c.head >= n {
+		for i := range c.counts {
+			c.counts[i] = 0
+		}
+	} else {
+		for i := c.head + 1; i <= head; i++ {
+			c.counts[i%n] = 0
+		}
+	}
+	if head > c.head {
+		c.head = head
+	}
+	return int(c.head % n)
+}
+
+// Add adds the events happened now.
+func (c *SlidingCounter) Add(delta uint64) {
+	c.Lock()
+	defer c.Unlock()
+	c.counts[c.advanceLocked()] += delta
+}
+
+// Count returns the events in the window.
+func (c *SlidingCounter) Count() uint64 {
+	c.Lock()
+	defer c.Unlock()
+	c.advanceLocked()
+	var total uint64
+	for _, count := range c.counts {
+		total += count
+	}
+	return total
+}
+
+// Rate returns the events per second in the window.
+func (c *SlidingCounter) Rate() float64 {
+	return float64(c.Count()) / c.window.Seconds()
+}
+
+// Window returns the window of the counter.
+func (c *SlidingCounter) Window() time.Duration {
+	return c.window
+}
+
+// SlidingCounterVec is the SlidingCounters partitioned by a label. It is
+// threadsafe.
+type SlidingCounterVec struct {
+	sync.RWMutex
+	window   time.Duration
+	buckets  int
+	counters map[string]*SlidingCounter
+	now      func() time.Time
+}
+
+// NewSlidingCounterVec creates a SlidingCounterVec.
+func NewSlidingCounterVec(window time.Duration, buckets int) *SlidingCounterVec {
+	return &SlidingCounterVec{
+		window:   window,
+		buckets:  buckets,
+		counters: make(map[string]*SlidingCounter),
+		now:      time.Now,
+	}
+}
+
+// Add adds the events of the label happened now.
+func (v *SlidingCounterVec) Add(label string, delta uint64) {
+	v.RLock()
+	counter, ok := v.counters[label]
+	v.RUnlock()
+	if !ok {
+		v.Lock()
+		if counter, ok = v.counters[label]; !ok {
+			counter = NewSlidingCounter(v.window, v.buckets)
+			counter.now = v.now
+			v.counters[label] = counter
+		}
+		v.Unlock()
+	}
+	counter.Add(delta)
+}
+
+// Counts returns the events in the window by the label, and the labels
+// without any event in the window are omitted.
+func (v *SlidingCounterVec) Counts() map[string]uint64 {
+	v.RLock()
+	defer v.RUnlock()
+	counts := make(map[string]uint64, len(v.counters))
+	for label, counter := range v.counters {
+		if count := counter.Count(); count > 0 {
+			counts[label] = count
+		}
+	}
+	return counts
+}
+
+// Window returns the window of the counters.
+func (v *SlidingCounterVec) Window() time.Duration {
+	return v.window
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"time"
+
+	. "github.com/pingcap/check"
+)
+
+var _ = Suite(&testSlidingCounterSuite{})
+
+type testSlidingCounterSuite struct{}
+
+func (s *testSlidingCounterSuite) TestSlidingCounter(c *C) {
+	now := time.Unix(1000, 0)
+	counter := NewSlidingCounter(time.Minute, 6)
+	counter.now = func() time.Time { return now }
+	c.Assert(counter.Window(), Equals, time.Minute)
+	c.Assert(counter.Count(), Equals, uint64(0))
+
+	// 10 events in each 10s bucket of the first minute.
+	for i := 0; i < 6; i++ {
+		counter.Add(10)
+		c.Assert(counter.Count(), Equals, uint64(10*(i+1)))
+		now = now.Add(10 * time.Second)
+	}
+	// The oldest bucket expires.
+	c.Assert(counter.Count(), Equals, uint64(50))
+	c.Assert(counter.Rate(), Equals, float64(50)/60)
+	// The events in the same bucket are summed.
+	now = now.Add(5 * time.Second)
+	counter.Add(1)
+	counter.Add(2)
+	c.Assert(counter.Count(), Equals, uint64(53))
+
+	// The buckets skipped are cleared.
+	now = now.Add(30 * time.Second)
+	c.Assert(counter.Count(), Equals, uint64(23))
+	// All the buckets expire after the window.
+	now = now.Add(time.Minute)
+	c.Assert(counter.Count(), Equals, uint64(0))
+	counter.Add(1)
+	c.Assert(counter.Count(), Equals, uint64(1))
+
+	// The events happened earlier are counted in the latest bucket.
+	now = now.Add(-time.Minute)
+	counter.Add(1)
+	c.Assert(counter.Count(), Equals, uint64(2))
+}
+
+func (s *testSlidingCounterSuite) TestSlidingCounterVec(c *C) {
+	now := time.Unix(1000, 0)
+	vec := NewSlidingCounterVec(5*time.Minute, 10)
+	vec.now = func() time.Time { return now }
+	c.Assert(vec.Counts(), HasLen, 0)
+
+	vec.Add("leader", 1)
+	vec.Add("leader", 2)
+	now = now.Add(3 * time.Minute)
+	vec.Add("region", 1)
+	c.Assert(vec.Counts(), DeepEquals, map[string]uint64{"leader": 3, "region": 1})
+
+	// The labels without any event in the window are omitted.
+	now = now.Add(3 * time.Minute)
+	c.Assert(vec.Counts(), DeepEquals, map[string]uint64{"region": 1})
+	c.Assert(vec.Window(), Equals, 5*time.Minute)
+}
@@ -39,10 +39,15 @@ const (
 	maxLogical           = int64(1 << 18)
 )
 
+// ErrPaused is returned when allocating timestamps while the TSO is paused
+// for a reset. The clients should retry later.
+var ErrPaused = errors.New("tso is paused for resetting, please retry later")
+
 // TimestampOracle is used to maintain the logic of tso.
 type TimestampOracle struct {
 	// For tso, set after pd becomes leader.
 	ts            unsafe.Pointer
+	paused        int32
 	lastSavedTime atomic.Value
 	lease         *member.LeaderLease
 
@@ -179,6 +184,24 @@ func (t *TimestampOracle) ResetUserTimestamp(tso uint64) error {
 	return nil
 }
 
+// Pause stops allocating timestamps until Resume is called, so that the
+// clients re-sync after a reset instead of mixing the old and new timestamps.
+func (t *TimestampOracle) Pause() {
+	atomic.StoreInt32(&t.paused, 1)
+	tsoCounter.WithLabelValues("pause").Inc()
+}
+
+// Resume resumes allocating timestamps.
+func (t *TimestampOracle) Resume() {
+	atomic.StoreInt32(&t.paused, 0)
+	tsoCounter.WithLabelValues("resume").Inc()
+}
+
+// IsPaused returns true if the TSO is paused.
+func (t *TimestampOracle) IsPaused() bool {
+	return atomic.LoadInt32(&t.paused) == 1
+}
+
 // UpdateTimestamp is used to update the timestamp.
 // This function will do two things:
 // 1. When the logical time is going to be used up, the current physical time needs to increase.
@@ -247,6 +270,56 @@ func (t *TimestampOracle) UpdateTimestamp() error {
 	return nil
 }
 
+// Status is the status of the timestamp oracle.
+type Status struct {
+	// Physical is the current physical time in milliseconds.
+	Physical   int64 `json:"physical"`
+	Logical    int64 `json:"logical"`
+	MaxLogical int64 `json:"max_logical"`
+	// SavedPhysical is the max physical time persisted in etcd in milliseconds,
+	// the physical time never exceeds it.
+	SavedPhysical int64 `json:"saved_physical"`
+	// SaveHeadroom is how far the physical time can go before the saved time
+	// needs to be updated in etcd.
+	SaveHeadroom   typeutil.Duration `json:"save_headroom"`
+	SaveInterval   typeutil.Duration `json:"save_interval"`
+	UpdateInterval typeutil.Duration `json:"update_interval"`
+	LeaseRemaining typeutil.Duration `json:"lease_remaining"`
+}
+
+// GetStatus returns the status of the timestamp oracle without allocating any
+// timestamp.
+func (t *TimestampOracle) GetStatus() (*Status, error) {
+	current := (*atomicObject)(atomic.LoadPointer(&t.ts))
+	if current == nil || current.physical == typeutil.ZeroTime {
+		return nil, errors.New("timestamp is not synced")
+	}
+	lastSaved, ok := t.lastSavedTime.Load().(time.Time)
+	if !ok {
+		return nil, errors.New("timestamp is not saved")
+	}
+	// The logical part may exceed maxLogical when the allocation overflows,
+	// but no timestamp is allocated beyond it.
+	logical := atomic.LoadInt64(&current.logical)
+	if logical > maxLogical {
+		logical = maxLogical
+	}
+	var leaseRemaining time.Duration
+	if t.lease != nil {
+		leaseRemaining = t.lease.GetRemainingTime()
+	}
+	return &Status{
+		Physical:       current.physical.UnixNano() / int64(time.Millisecond),
+		Logical:        logical,
+		MaxLogical:     maxLogical,
+		SavedPhysical:  lastSaved.UnixNano() / int64(time.Millisecond),
+		SaveHeadroom:   typeutil.NewDuration(typeutil.SubTimeByWallClock(lastSaved, current.physical)),
+		SaveInterval:   typeutil.NewDuration(t.saveInterval),
+		UpdateInterval: typeutil.NewDuration(UpdateTimestampStep),
+		LeaseRemaining: typeutil.NewDuration(leaseRemaining),
+	}, nil
+}
+
 // ResetTimestamp is used to reset the timestamp.
 func (t *TimestampOracle) ResetTimestamp() {
 	zero := &atomicObject{
@@ -269,6 +342,11 @@ func (t *TimestampOracle) GetRespTS(count uint32) (pdpb.Timestamp, error) {
 		maxRetryCount = 1
 	})
 
+	if t.IsPaused() {
+		tsoCounter.WithLabelValues("err_paused").Inc()
+		return resp, ErrPaused
+	}
+
 	for i := 0; i < maxRetryCount; i++ {
 		current := (*atomicObject)(atomic.LoadPointer(&t.ts))
 		if current == nil || current.physical == typeutil.ZeroTime {
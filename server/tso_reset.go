@@ -0,0 +1,90 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/pd/v4/pkg/tsoutil"
+	"github.com/pingcap/pd/v4/pkg/typeutil"
+	"go.uber.org/zap"
+)
+
+// maxTSOResetHistory is the max number of the TSO resets kept in the history.
+const maxTSOResetHistory = 64
+
+// ResetTSOptions is the options of resetting the TSO.
+type ResetTSOptions struct {
+	// SafeMode pauses the TSO for the configured window before the reset, so
+	// that the clients re-sync instead of mixing the old and new timestamps.
+	SafeMode bool
+	Consumer string
+}
+
+// TSOResetRecord is a TSO reset in the history.
+type TSOResetRecord struct {
+	OldTS    uint64    `json:"old_ts"`
+	NewTS    uint64    `json:"new_ts"`
+	Consumer string    `json:"consumer"`
+	Time     time.Time `json:"time"`
+	// PauseWindow is zero if the reset is not in the safe mode.
+	PauseWindow typeutil.Duration `json:"pause_window"`
+}
+
+func (s *Server) resetTS(ts uint64, opts ResetTSOptions) error {
+	s.tsoResetMu.Lock()
+	defer s.tsoResetMu.Unlock()
+
+	var oldTS uint64
+	if status, err := s.tso.GetStatus(); err == nil {
+		oldTS = tsoutil.ComposeTS(status.Physical, status.Logical)
+	}
+	var window time.Duration
+	if opts.SafeMode {
+		window = s.scheduleOpt.LoadPDServerConfig().ResetTSPauseWindow.Duration
+		s.tso.Pause()
+		defer s.tso.Resume()
+		time.Sleep(window)
+	}
+	if err := s.tso.ResetUserTimestamp(ts); err != nil {
+		return err
+	}
+
+	record := &TSOResetRecord{
+		OldTS:       oldTS,
+		NewTS:       ts,
+		Consumer:    opts.Consumer,
+		Time:        time.Now(),
+		PauseWindow: typeutil.NewDuration(window),
+	}
+	log.Info("tso is reset", zap.Reflect("record", record))
+	history, err := s.loadTSOResetHistory()
+	if err != nil {
+		return err
+	}
+	history = append(history, record)
+	if len(history) > maxTSOResetHistory {
+		history = history[len(history)-maxTSOResetHistory:]
+	}
+	return s.storage.SaveTSOResetHistory(history)
+}
+
+func (s *Server) loadTSOResetHistory() ([]*TSOResetRecord, error) {
+	var history []*TSOResetRecord
+	if _, err := s.storage.LoadTSOResetHistory(&history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
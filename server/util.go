@@ -163,6 +163,37 @@ func makeBootstrapTimeKey(clusterRootPath string) string {
 	return path.Join(makeRaftClusterStatusPrefix(clusterRootPath), "raft_bootstrap_time")
 }
 
+func makeBootstrapStoreVersionKey(clusterRootPath string) string {
+	return path.Join(makeRaftClusterStatusPrefix(clusterRootPath), "raft_bootstrap_store_version")
+}
+
+// checkBootstrapVersion checks whether the version of the store is allowed to
+// bootstrap the cluster. The store should not be lower than both the minimum
+// version supported by PD and the configured minBootstrapVersion.
+func checkBootstrapVersion(store *metapb.Store, minBootstrapVersion string) error {
+	if store == nil {
+		return nil
+	}
+	storeVersion, err := cluster.ParseVersion(store.GetVersion())
+	if err != nil {
+		return errors.Errorf("invalid version %q of the bootstrapping store %d", store.GetVersion(), store.GetId())
+	}
+	minVersion := cluster.MinSupportedVersion(cluster.Base)
+	if minBootstrapVersion != "" {
+		v, err := cluster.ParseVersion(minBootstrapVersion)
+		if err != nil {
+			return err
+		}
+		if minVersion.LessThan(*v) {
+			minVersion = v
+		}
+	}
+	if storeVersion.LessThan(*minVersion) {
+		return errors.Errorf("the version %s of the bootstrapping store %d is lower than the minimum bootstrap version %s", storeVersion, store.GetId(), minVersion)
+	}
+	return nil
+}
+
 func checkBootstrapRequest(clusterID uint64, req *pdpb.BootstrapRequest) error {
 	// TODO: do more check for request fields validation.
 
@@ -28,13 +28,17 @@ import (
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	pd "github.com/pingcap/pd/v4/client"
+	"github.com/pingcap/pd/v4/pkg/grpcutil"
 	"github.com/pingcap/pd/v4/pkg/mock/mockid"
 	"github.com/pingcap/pd/v4/pkg/testutil"
 	"github.com/pingcap/pd/v4/server"
 	"github.com/pingcap/pd/v4/server/core"
+	"github.com/pingcap/pd/v4/server/schedule"
 	"github.com/pingcap/pd/v4/tests"
 	"go.etcd.io/etcd/clientv3"
 	"go.uber.org/goleak"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func Test(t *testing.T) {
@@ -378,6 +382,85 @@ func (s *testClientSuite) TestGetRegion(c *C) {
 	c.Succeed()
 }
 
+func (s *testClientSuite) TestGetRegionPeerStatus(c *C) {
+	// The keys are out of the ones scanned by TestScanRegions.
+	keys := [][]byte{{50}, {51}, {52}}
+	regions := make([]*metapb.Region, 0, 2)
+	for i := 0; i < 2; i++ {
+		region := &metapb.Region{
+			Id: regionIDAllocator.alloc(),
+			RegionEpoch: &metapb.RegionEpoch{
+				ConfVer: 1,
+				Version: 1,
+			},
+			StartKey: keys[i],
+			EndKey:   keys[i+1],
+			Peers:    peers,
+		}
+		regions = append(regions, region)
+		req := &pdpb.RegionHeartbeatRequest{
+			Header: newHeader(s.srv),
+			Region: region,
+			Leader: peers[0],
+		}
+		if i == 0 {
+			req.DownPeers = []*pdpb.PeerStats{{Peer: peers[1], DownSeconds: 100}}
+			req.PendingPeers = []*metapb.Peer{peers[2]}
+		}
+		err := s.regionHeartbeat.Send(req)
+		c.Assert(err, IsNil)
+	}
+
+	checkPeerStatus := func(status *pd.RegionPeerStatus) {
+		c.Assert(status.DownPeers, HasLen, 1)
+		c.Assert(status.DownPeers[0].GetPeer(), DeepEquals, peers[1])
+		c.Assert(status.DownPeers[0].GetDownSeconds(), Equals, uint64(100))
+		c.Assert(status.PendingPeers, DeepEquals, []*metapb.Peer{peers[2]})
+	}
+	testutil.WaitUntil(c, func(c *C) bool {
+		r, _, err := s.client.GetRegion(context.Background(), keys[1])
+		return err == nil && r.GetId() == regions[1].GetId()
+	})
+
+	var status pd.RegionPeerStatus
+	r, leader, err := s.client.GetRegion(pd.WithPeerStatus(context.Background(), &status), keys[0])
+	c.Assert(err, IsNil)
+	c.Assert(r, DeepEquals, regions[0])
+	c.Assert(leader, DeepEquals, peers[0])
+	checkPeerStatus(&status)
+
+	status = pd.RegionPeerStatus{}
+	r, _, err = s.client.GetPrevRegion(pd.WithPeerStatus(context.Background(), &status), keys[1])
+	c.Assert(err, IsNil)
+	c.Assert(r, DeepEquals, regions[0])
+	checkPeerStatus(&status)
+
+	status = pd.RegionPeerStatus{}
+	r, _, err = s.client.GetRegionByID(pd.WithPeerStatus(context.Background(), &status), regions[0].GetId())
+	c.Assert(err, IsNil)
+	c.Assert(r, DeepEquals, regions[0])
+	checkPeerStatus(&status)
+
+	// The healthy region has no down or pending peers.
+	status = pd.RegionPeerStatus{}
+	r, _, err = s.client.GetRegionByID(pd.WithPeerStatus(context.Background(), &status), regions[1].GetId())
+	c.Assert(err, IsNil)
+	c.Assert(r, DeepEquals, regions[1])
+	c.Assert(status.DownPeers, HasLen, 0)
+	c.Assert(status.PendingPeers, HasLen, 0)
+
+	// The peer status is not sent unless it is requested.
+	var header metadata.MD
+	resp, err := s.grpcPDClient.GetRegionByID(context.Background(), &pdpb.GetRegionByIDRequest{
+		Header:   newHeader(s.srv),
+		RegionId: regions[0].GetId(),
+	}, grpc.Header(&header))
+	c.Assert(err, IsNil)
+	c.Assert(resp.GetRegion(), DeepEquals, regions[0])
+	c.Assert(header.Get(grpcutil.DownPeersMetadataKey), HasLen, 0)
+	c.Assert(header.Get(grpcutil.PendingPeersMetadataKey), HasLen, 0)
+}
+
 func (s *testClientSuite) TestGetPrevRegion(c *C) {
 	regionLen := 10
 	regions := make([]*metapb.Region, 0, regionLen)
@@ -624,5 +707,14 @@ func (s *testClientSuite) TestScatterRegion(c *C) {
 		}
 		return c.Check(resp.GetRegionId(), Equals, regionID) && c.Check(string(resp.GetDesc()), Equals, "scatter-region") && c.Check(resp.GetStatus(), Equals, pdpb.OperatorStatus_RUNNING)
 	})
+
+	// The scatter group is passed by the gRPC metadata.
+	ctx := pd.WithScatterGroup(context.Background(), "client-group")
+	c.Assert(s.client.ScatterRegion(ctx, regionID), IsNil)
+	var groups []string
+	for _, status := range s.srv.GetRaftCluster().GetRegionScatter().GetGroupStatus() {
+		groups = append(groups, status.Group)
+	}
+	c.Assert(groups, DeepEquals, []string{"client-group", schedule.DefaultScatterGroup})
 	c.Succeed()
 }
@@ -202,6 +202,20 @@ func (s *configTestSuite) TestConfig(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(strings.Contains(string(output), "already been deprecated"), IsTrue)
 
+	// the update of the location labels is applied only if confirmed.
+	args1 = []string{"-u", pdAddr, "config", "set", "location-labels", "zone,rack"}
+	_, output, err = pdctl.ExecuteCommandC(cmd, args1...)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), "[428]"), IsTrue)
+	c.Assert(strings.Contains(string(output), "--confirm"), IsTrue)
+	c.Assert(svr.GetReplicationConfig().LocationLabels, HasLen, 0)
+	args1 = []string{"-u", pdAddr, "config", "set", "--confirm", "location-labels", "zone,rack"}
+	_, output, err = pdctl.ExecuteCommandC(cmd, args1...)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), "Success!"), IsTrue)
+	time.Sleep(20 * time.Millisecond)
+	c.Assert([]string(svr.GetReplicationConfig().LocationLabels), DeepEquals, []string{"zone", "rack"})
+
 	// set enable-placement-rules twice, make sure it does not return error.
 	args1 = []string{"-u", pdAddr, "config", "set", "enable-placement-rules", "true"}
 	_, _, err = pdctl.ExecuteCommandC(cmd, args1...)
@@ -42,6 +42,7 @@ type hotTestSuite struct{}
 func (s *hotTestSuite) SetUpSuite(c *C) {
 	server.EnableZap = true
 	server.ConfigCheckInterval = 10 * time.Millisecond
+	server.StatsWarmUpDuration = 0
 }
 
 func (s *hotTestSuite) TestHot(c *C) {
@@ -116,7 +116,12 @@ func (s *regionTestSuite) TestRegion(c *C) {
 	regionInfo := api.RegionInfo{}
 	c.Assert(json.Unmarshal(output, &regionInfo), IsNil)
 	region := leaderServer.GetRegionInfoByID(1)
-	c.Assert(api.NewRegionInfo(region), DeepEquals, &regionInfo)
+	// The region detail shows the activity, and the new regions are not idle
+	// as their epochs are just changed.
+	expected := api.NewRegionInfo(region)
+	expected.Activity = "normal"
+	expected.SuggestedHeartbeatInterval = "1m0s"
+	c.Assert(&regionInfo, DeepEquals, expected)
 
 	// region sibling <region_id> command
 	args = []string{"-u", pdAddr, "region", "sibling", "2"}
@@ -226,7 +231,10 @@ func (s *regionTestSuite) TestRegion(c *C) {
 	c.Assert(err, IsNil)
 	regionInfo = api.RegionInfo{}
 	c.Assert(json.Unmarshal(output, &regionInfo), IsNil)
-	c.Assert(&regionInfo, DeepEquals, api.NewRegionInfo(r2))
+	expected = api.NewRegionInfo(r2)
+	expected.Activity = "normal"
+	expected.SuggestedHeartbeatInterval = "1m0s"
+	c.Assert(&regionInfo, DeepEquals, expected)
 
 	// region key --format=hex <key> command
 	args = []string{"-u", pdAddr, "region", "key", "--format=hex", "62"}
@@ -234,7 +242,7 @@ func (s *regionTestSuite) TestRegion(c *C) {
 	c.Assert(err, IsNil)
 	regionInfo = api.RegionInfo{}
 	c.Assert(json.Unmarshal(output, &regionInfo), IsNil)
-	c.Assert(&regionInfo, DeepEquals, api.NewRegionInfo(r2))
+	c.Assert(&regionInfo, DeepEquals, expected)
 
 	// region startkey --format=raw <key> command
 	args = []string{"-u", pdAddr, "region", "startkey", "--format=raw", "b", "2"}
@@ -239,4 +239,19 @@ func (s *schedulerTestSuite) TestScheduler(c *C) {
 	c.Assert(strings.Contains(echo, "Success!"), IsTrue)
 	echo = pdctl.GetEcho([]string{"-u", pdAddr, "scheduler", "remove", "balance-region-scheduler"})
 	c.Assert(strings.Contains(echo, "Success!"), IsFalse)
+
+	// test show paused schedulers
+	mustExec([]string{"-u", pdAddr, "scheduler", "pause", "balance-leader-scheduler", "60"}, nil)
+	var statuses []map[string]interface{}
+	mustExec([]string{"-u", pdAddr, "scheduler", "show", "--status", "paused"}, &statuses)
+	c.Assert(statuses, HasLen, 1)
+	c.Assert(statuses[0]["name"], Equals, "balance-leader-scheduler")
+	c.Assert(statuses[0], HasKey, "paused_until")
+	c.Assert(statuses[0], HasKey, "paused_remaining")
+	mustExec([]string{"-u", pdAddr, "scheduler", "resume", "balance-leader-scheduler"}, nil)
+	mustExec([]string{"-u", pdAddr, "scheduler", "show", "--status", "paused"}, &statuses)
+	c.Assert(statuses, HasLen, 0)
+	mustExec([]string{"-u", pdAddr, "scheduler", "show", "--status", "disabled"}, &statuses)
+	c.Assert(statuses, HasLen, 1)
+	c.Assert(statuses[0]["name"], Equals, "balance-region-scheduler")
 }
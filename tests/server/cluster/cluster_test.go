@@ -15,7 +15,10 @@ package cluster_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -23,6 +26,7 @@ import (
 	"github.com/coreos/go-semver/semver"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/failpoint"
+	"github.com/pingcap/kvproto/pkg/configpb"
 	"github.com/pingcap/kvproto/pkg/metapb"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/v4/pkg/mock/mockid"
@@ -37,6 +41,7 @@ import (
 	"github.com/pingcap/pd/v4/tests"
 	"github.com/pingcap/pd/v4/tests/pdctl"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc"
 )
 
 func Test(t *testing.T) {
@@ -212,6 +217,111 @@ func (s *clusterTestSuite) TestReloadConfig(c *C) {
 	c.Assert(rc.GetOperatorController().GetOperator(3), IsNil)
 }
 
+func (s *clusterTestSuite) TestStoreLimitAfterLeaderTransfer(c *C) {
+	tc, err := tests.NewTestCluster(s.ctx, 3)
+	defer tc.Destroy()
+	c.Assert(err, IsNil)
+
+	err = tc.RunInitialServers()
+	c.Assert(err, IsNil)
+	tc.WaitLeader()
+	leaderServer := tc.GetServer(tc.GetLeader())
+	c.Assert(leaderServer.BootstrapCluster(), IsNil)
+	rc := leaderServer.GetRaftCluster()
+	c.Assert(rc, NotNil)
+	stores := rc.GetStores()
+	c.Assert(stores, HasLen, 1)
+	storeID := stores[0].GetID()
+	c.Assert(leaderServer.GetServer().GetHandler().SetStoreLimit(storeID, 3), IsNil)
+
+	// transfer leader
+	tc.ResignLeader()
+	tc.WaitLeader()
+	leaderServer = tc.GetServer(tc.GetLeader())
+	rc = leaderServer.GetRaftCluster()
+	c.Assert(rc, NotNil)
+	limit := rc.GetOperatorController().GetAllStoresLimit()[storeID]
+	c.Assert(limit, NotNil)
+	c.Assert(math.Abs(limit.Rate()-3), Less, 1e-3)
+	c.Assert(limit.Mode(), Equals, schedule.StoreLimitManual)
+}
+
+func (s *clusterTestSuite) TestStoreLimitRPC(c *C) {
+	tc, err := tests.NewTestCluster(s.ctx, 2)
+	defer tc.Destroy()
+	c.Assert(err, IsNil)
+
+	err = tc.RunInitialServers()
+	c.Assert(err, IsNil)
+	tc.WaitLeader()
+	leaderServer := tc.GetServer(tc.GetLeader())
+	clusterID := leaderServer.GetClusterID()
+	header := &configpb.Header{ClusterId: clusterID}
+	newConfigClient := func(addr string) configpb.ConfigClient {
+		conn, err := grpc.Dial(strings.TrimPrefix(addr, "http://"), grpc.WithInsecure())
+		c.Assert(err, IsNil)
+		return configpb.NewConfigClient(conn)
+	}
+	client := newConfigClient(leaderServer.GetAddr())
+	getLimits := func() (*configpb.Status, []*server.StoreLimit) {
+		resp, err := client.Get(context.Background(), &configpb.GetRequest{Header: header, Component: server.StoreLimitComponent})
+		c.Assert(err, IsNil)
+		var limits []*server.StoreLimit
+		if resp.GetStatus().GetCode() == configpb.StatusCode_OK {
+			c.Assert(json.Unmarshal([]byte(resp.GetConfig()), &limits), IsNil)
+		}
+		return resp.GetStatus(), limits
+	}
+	setLimit := func(client configpb.ConfigClient, storeID, rate string) (*configpb.Status, error) {
+		resp, err := client.Update(context.Background(), &configpb.UpdateRequest{
+			Header:  header,
+			Kind:    &configpb.ConfigKind{Kind: &configpb.ConfigKind_Global{Global: &configpb.Global{Component: server.StoreLimitComponent}}},
+			Entries: []*configpb.ConfigEntry{{Name: storeID, Value: rate}},
+		})
+		return resp.GetStatus(), err
+	}
+
+	status, _ := getLimits()
+	c.Assert(status.GetMessage(), Matches, "NOT_BOOTSTRAPPED.*")
+
+	c.Assert(leaderServer.BootstrapCluster(), IsNil)
+	rc := leaderServer.GetRaftCluster()
+	storeID := rc.GetStores()[0].GetID()
+	grpcPDClient := testutil.MustNewGrpcClient(c, leaderServer.GetAddr())
+	_, err = putStore(c, grpcPDClient, clusterID, &metapb.Store{Id: storeID + 1, Address: "mock://tikv-2", Version: "2.0.0"})
+	c.Assert(err, IsNil)
+	// The limits are auto as tuned by the store limiter.
+	rc.GetOperatorController().SetAllStoresLimitAuto(1)
+
+	status, err = setLimit(client, fmt.Sprint(storeID), "30")
+	c.Assert(err, IsNil)
+	c.Assert(status.GetCode(), Equals, configpb.StatusCode_OK)
+	status, limits := getLimits()
+	c.Assert(status.GetCode(), Equals, configpb.StatusCode_OK)
+	// The limit set by the RPC is manual, and the other one is auto.
+	c.Assert(limits, HasLen, 2)
+	c.Assert(limits[0].StoreID, Equals, storeID)
+	c.Assert(math.Abs(limits[0].Rate-30), Less, 1e-3)
+	c.Assert(limits[0].Mode, Equals, "manual")
+	c.Assert(limits[1].StoreID, Equals, storeID+1)
+	c.Assert(limits[1].Mode, Equals, "auto")
+	limit := rc.GetOperatorController().GetAllStoresLimit()[storeID]
+	c.Assert(math.Abs(limit.Rate()-0.5), Less, 1e-3)
+
+	for _, t := range [][2]string{{fmt.Sprint(storeID), "-1"}, {fmt.Sprint(storeID + 100), "30"}, {"a", "30"}, {fmt.Sprint(storeID), "a"}} {
+		status, err = setLimit(client, t[0], t[1])
+		c.Assert(err, IsNil)
+		c.Assert(status.GetCode(), Equals, configpb.StatusCode_UNKNOWN)
+	}
+
+	// The cluster ID is checked.
+	_, err = client.Get(context.Background(), &configpb.GetRequest{Header: &configpb.Header{ClusterId: clusterID + 1}, Component: server.StoreLimitComponent})
+	c.Assert(err, NotNil)
+	// The follower rejects the requests.
+	_, err = setLimit(newConfigClient(tc.GetServer(tc.GetFollower()).GetAddr()), fmt.Sprint(storeID), "30")
+	c.Assert(err, NotNil)
+}
+
 func testPutStore(c *C, clusterID uint64, rc *cluster.RaftCluster, grpcPDClient pdpb.PDClient, store *metapb.Store) {
 	// Update store.
 	_, err := putStore(c, grpcPDClient, clusterID, store)
@@ -15,14 +15,19 @@ package syncer_test
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
 	. "github.com/pingcap/check"
 	"github.com/pingcap/kvproto/pkg/metapb"
+	"github.com/pingcap/pd/v4/pkg/apiutil/serverapi"
 	"github.com/pingcap/pd/v4/pkg/mock/mockid"
 	"github.com/pingcap/pd/v4/pkg/testutil"
 	"github.com/pingcap/pd/v4/server"
+	"github.com/pingcap/pd/v4/server/api"
 	"github.com/pingcap/pd/v4/server/config"
 	"github.com/pingcap/pd/v4/server/core"
 	"github.com/pingcap/pd/v4/tests"
@@ -53,6 +58,12 @@ func (s *serverTestSuite) TearDownSuite(c *C) {
 	s.cancel()
 }
 
+var dialClient = &http.Client{
+	Transport: &http.Transport{
+		DisableKeepAlives: true,
+	},
+}
+
 type idAllocator struct {
 	allocator *mockid.IDAllocator
 }
@@ -213,3 +224,101 @@ func (s *serverTestSuite) TestFullSyncWithAddMember(c *C) {
 	loadRegions := pd2.GetServer().GetRaftCluster().GetRegions()
 	c.Assert(len(loadRegions), Equals, regionLen)
 }
+
+func (s *serverTestSuite) getSyncStatus(c *C, svr *tests.TestServer, name string, followerHandle bool) (int, *api.MemberSyncStatus) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/pd/api/v1/members/%s/sync-status", svr.GetAddr(), name), nil)
+	c.Assert(err, IsNil)
+	if followerHandle {
+		req.Header.Add(serverapi.AllowFollowerHandle, "true")
+	}
+	resp, err := dialClient.Do(req)
+	c.Assert(err, IsNil)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+	status := &api.MemberSyncStatus{}
+	c.Assert(json.NewDecoder(resp.Body).Decode(status), IsNil)
+	return resp.StatusCode, status
+}
+
+func (s *serverTestSuite) TestSyncStatusAndResync(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 3, func(conf *config.Config) { conf.PDServerCfg.UseRegionStorage = true })
+	defer cluster.Destroy()
+	c.Assert(err, IsNil)
+
+	err = cluster.RunInitialServers()
+	c.Assert(err, IsNil)
+	cluster.WaitLeader()
+	leaderServer := cluster.GetServer(cluster.GetLeader())
+	c.Assert(leaderServer.BootstrapCluster(), IsNil)
+	rc := leaderServer.GetServer().GetRaftCluster()
+	c.Assert(rc, NotNil)
+	followerServer := cluster.GetServer(cluster.GetFollower())
+	c.Assert(followerServer, NotNil)
+	followerName := followerServer.GetServer().Name()
+
+	regionLen := 50
+	allocator := &idAllocator{allocator: mockid.NewIDAllocator()}
+	for i := 0; i < regionLen; i++ {
+		r := &metapb.Region{
+			Id:          allocator.alloc(),
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+			StartKey:    []byte{byte(i)},
+			EndKey:      []byte{byte(i + 1)},
+			Peers:       []*metapb.Peer{{Id: allocator.alloc(), StoreId: uint64(0)}},
+		}
+		c.Assert(rc.HandleRegionHeartbeat(core.NewRegionInfo(r, r.Peers[0])), IsNil)
+	}
+
+	// The lag goes to 0 after the writes are synced.
+	testutil.WaitUntil(c, func(c *C) bool {
+		code, status := s.getSyncStatus(c, leaderServer, followerName, false)
+		return code == http.StatusOK && status.Leader.LagRecords == 0 && status.Leader.SentIndex >= uint64(regionLen)
+	})
+	_, status := s.getSyncStatus(c, leaderServer, followerName, false)
+	c.Assert(status.Leader.LagSeconds, Equals, float64(0))
+	c.Assert(status.Follower, IsNil)
+	sentIndex := status.Leader.SentIndex
+	testutil.WaitUntil(c, func(c *C) bool {
+		_, status = s.getSyncStatus(c, followerServer, followerName, true)
+		return status.Follower.AppliedIndex == sentIndex
+	})
+	c.Assert(status.Follower.Leader, Equals, leaderServer.GetServer().Name())
+	c.Assert(status.Leader, IsNil)
+	code, _ := s.getSyncStatus(c, leaderServer, "unknown", false)
+	c.Assert(code, Equals, http.StatusNotFound)
+
+	// Resync rebuilds the wiped regions of the follower.
+	basicCluster := followerServer.GetServer().GetBasicCluster()
+	for _, region := range basicCluster.GetRegions() {
+		basicCluster.RemoveRegion(region)
+	}
+	c.Assert(basicCluster.GetRegions(), HasLen, 0)
+	resp, err := dialClient.Post(fmt.Sprintf("%s/pd/api/v1/members/%s/resync", leaderServer.GetAddr(), followerName), "application/json", nil)
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, http.StatusOK)
+	testutil.WaitUntil(c, func(c *C) bool {
+		return len(basicCluster.GetRegions()) == regionLen
+	})
+	for _, region := range rc.GetRegions() {
+		c.Assert(basicCluster.GetRegion(region.GetID()).GetMeta(), DeepEquals, region.GetMeta())
+	}
+
+	// The follower keeps syncing after the resync.
+	r := &metapb.Region{
+		Id:          allocator.alloc(),
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+		StartKey:    []byte{byte(regionLen)},
+		EndKey:      []byte{byte(regionLen + 1)},
+		Peers:       []*metapb.Peer{{Id: allocator.alloc(), StoreId: uint64(0)}},
+	}
+	c.Assert(rc.HandleRegionHeartbeat(core.NewRegionInfo(r, r.Peers[0])), IsNil)
+	testutil.WaitUntil(c, func(c *C) bool {
+		_, status = s.getSyncStatus(c, followerServer, followerName, true)
+		return basicCluster.GetRegion(r.GetId()) != nil && status.Follower.AppliedIndex == uint64(regionLen+1)
+	})
+	_, status = s.getSyncStatus(c, leaderServer, followerName, false)
+	c.Assert(status.Leader.LagRecords, Equals, uint64(0))
+}
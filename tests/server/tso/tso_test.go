@@ -24,9 +24,12 @@ import (
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/kvproto/pkg/pdpb"
 	"github.com/pingcap/pd/v4/pkg/testutil"
+	"github.com/pingcap/pd/v4/pkg/tsoutil"
 	"github.com/pingcap/pd/v4/server"
 	"github.com/pingcap/pd/v4/tests"
 	"go.uber.org/goleak"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func Test(t *testing.T) {
@@ -144,6 +147,76 @@ func (s *testTsoSuite) TestConcurrcyRequest(c *C) {
 	wg.Wait()
 }
 
+func (s *testTsoSuite) TestResetTSSafeMode(c *C) {
+	cluster, err := tests.NewTestCluster(s.ctx, 1)
+	defer cluster.Destroy()
+	c.Assert(err, IsNil)
+
+	err = cluster.RunInitialServers()
+	c.Assert(err, IsNil)
+	cluster.WaitLeader()
+
+	leaderServer := cluster.GetServer(cluster.GetLeader())
+	grpcPDClient := testutil.MustNewGrpcClient(c, leaderServer.GetAddr())
+	clusterID := leaderServer.GetClusterID()
+	req := &pdpb.TsoRequest{Header: testutil.NewRequestHeader(clusterID), Count: 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	paused := make([]int, 4)
+	for i := range paused {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var last uint64
+			for ctx.Err() == nil {
+				tsoClient, err := grpcPDClient.Tso(ctx)
+				c.Assert(err, IsNil)
+				for {
+					if err = tsoClient.Send(req); err == nil {
+						var resp *pdpb.TsoResponse
+						if resp, err = tsoClient.Recv(); err == nil {
+							ts := tsoutil.ComposeTS(resp.GetTimestamp().GetPhysical(), resp.GetTimestamp().GetLogical())
+							c.Assert(ts, Greater, last)
+							last = ts
+							continue
+						}
+					}
+					break
+				}
+				tsoClient.CloseSend()
+				if ctx.Err() != nil {
+					return
+				}
+				// The clients retry after the pause window.
+				c.Assert(status.Code(err), Equals, codes.Unavailable)
+				c.Assert(err, ErrorMatches, ".*paused.*")
+				paused[i]++
+				time.Sleep(10 * time.Millisecond)
+			}
+		}(i)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	ts := tsoutil.ComposeTS(time.Now().Add(time.Hour).UnixNano()/int64(time.Millisecond), 0)
+	err = leaderServer.GetServer().GetHandler().ResetTSWithOptions(ts, server.ResetTSOptions{SafeMode: true, Consumer: "test"})
+	c.Assert(err, IsNil)
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	wg.Wait()
+	for _, n := range paused {
+		c.Assert(n, Greater, 0)
+	}
+
+	history, err := leaderServer.GetServer().GetHandler().GetTSOResetHistory()
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Assert(history[0].NewTS, Equals, ts)
+	c.Assert(history[0].OldTS, Less, ts)
+	c.Assert(history[0].Consumer, Equals, "test")
+}
+
 func (s *testTsoSuite) TestTsoCount0(c *C) {
 	var err error
 	cluster, err := tests.NewTestCluster(s.ctx, 1)
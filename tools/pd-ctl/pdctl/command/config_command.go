@@ -16,9 +16,11 @@ package command
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"path"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -115,6 +117,7 @@ func NewSetConfigCommand() *cobra.Command {
 		Short: "set the option with value",
 		Run:   setConfigCommandFunc,
 	}
+	sc.Flags().Bool("confirm", false, "apply the update of the location labels without estimating the impact")
 	sc.AddCommand(NewSetLabelPropertyCommand())
 	sc.AddCommand(NewSetClusterVersionCommand())
 	return sc
@@ -269,9 +272,18 @@ func setConfigCommandFunc(cmd *cobra.Command, args []string) {
 		return
 	}
 	opt, val := args[0], args[1]
-	err := postConfigDataWithPath(cmd, opt, val, configPrefix)
+	prefix := configPrefix
+	if confirm, _ := cmd.Flags().GetBool("confirm"); confirm {
+		prefix += "?confirm=true"
+	}
+	err := postConfigDataWithPath(cmd, opt, val, prefix)
 	if err != nil {
 		cmd.Printf("Failed to set config: %s\n", err)
+		// The server returns the estimated impact of updating the location
+		// labels, which is applied only if confirmed.
+		if strings.Contains(err.Error(), fmt.Sprintf("[%d]", http.StatusPreconditionRequired)) {
+			cmd.Println("The update is not applied, rerun with --confirm to apply it.")
+		}
 		return
 	}
 	cmd.Println("Success!")
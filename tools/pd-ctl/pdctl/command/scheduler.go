@@ -21,7 +21,9 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/pingcap/pd/v4/server/cluster"
 	"github.com/spf13/cobra"
 )
 
@@ -84,6 +86,7 @@ func NewShowSchedulerCommand() *cobra.Command {
 		Short: "show schedulers",
 		Run:   showSchedulerCommandFunc,
 	}
+	c.Flags().String("status", "", "show the status of the paused, disabled or all schedulers")
 	return c
 }
 
@@ -103,6 +106,11 @@ func showSchedulerCommandFunc(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	status, _ := cmd.Flags().GetString("status")
+	if status != "" {
+		showSchedulerStatuses(cmd, status)
+		return
+	}
 	r, err := doRequest(cmd, schedulersPrefix, http.MethodGet)
 	if err != nil {
 		cmd.Println(err)
@@ -111,6 +119,40 @@ func showSchedulerCommandFunc(cmd *cobra.Command, args []string) {
 	cmd.Println(r)
 }
 
+// showSchedulerStatuses shows the statuses of the schedulers, with the
+// remaining pause durations of the paused ones.
+func showSchedulerStatuses(cmd *cobra.Command, status string) {
+	r, err := doRequest(cmd, schedulersPrefix+"?status="+url.QueryEscape(status), http.MethodGet)
+	if err != nil {
+		cmd.Println(err)
+		return
+	}
+	var statuses []*cluster.SchedulerStatus
+	if err = json.Unmarshal([]byte(r), &statuses); err != nil {
+		cmd.Printf("Failed to unmarshal scheduler statuses: %s\n", err)
+		return
+	}
+	type statusWithRemaining struct {
+		*cluster.SchedulerStatus
+		PausedRemaining string `json:"paused_remaining,omitempty"`
+	}
+	res := make([]statusWithRemaining, 0, len(statuses))
+	now := time.Now()
+	for _, s := range statuses {
+		item := statusWithRemaining{SchedulerStatus: s}
+		if s.PausedUntil != nil && s.PausedUntil.After(now) {
+			item.PausedRemaining = s.PausedUntil.Sub(now).Round(time.Second).String()
+		}
+		res = append(res, item)
+	}
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		cmd.Printf("Failed to marshal scheduler statuses: %s\n", err)
+		return
+	}
+	cmd.Println(string(data))
+}
+
 // NewAddSchedulerCommand returns a command to add scheduler.
 func NewAddSchedulerCommand() *cobra.Command {
 	c := &cobra.Command{
@@ -119,6 +161,7 @@ func NewAddSchedulerCommand() *cobra.Command {
 	}
 	c.AddCommand(NewGrantLeaderSchedulerCommand())
 	c.AddCommand(NewEvictLeaderSchedulerCommand())
+	c.AddCommand(NewEvictSlowStoreSchedulerCommand())
 	c.AddCommand(NewShuffleLeaderSchedulerCommand())
 	c.AddCommand(NewShuffleRegionSchedulerCommand())
 	c.AddCommand(NewShuffleHotRegionSchedulerCommand())
@@ -145,8 +188,8 @@ func NewGrantLeaderSchedulerCommand() *cobra.Command {
 // NewEvictLeaderSchedulerCommand returns a command to add a evict-leader-scheduler.
 func NewEvictLeaderSchedulerCommand() *cobra.Command {
 	c := &cobra.Command{
-		Use:   "evict-leader-scheduler <store_id>",
-		Short: "add a scheduler to evict leader from a store",
+		Use:   "evict-leader-scheduler <store_id|label_selector>",
+		Short: "add a scheduler to evict leader from a store, or the stores matching a label selector such as zone=z1",
 		Run:   addSchedulerForStoreCommandFunc,
 	}
 	return c
@@ -173,6 +216,14 @@ func addSchedulerForStoreCommandFunc(cmd *cobra.Command, args []string) {
 		cmd.Println(cmd.UsageString())
 		return
 	}
+	// The evict-leader-scheduler is updated by the server if it exists.
+	if cmd.Name() == evictLeaderSchedulerName && strings.Contains(args[0], "=") {
+		input := make(map[string]interface{})
+		input["name"] = cmd.Name()
+		input["selector"] = args[0]
+		postJSON(cmd, schedulersPrefix, input)
+		return
+	}
 	// we should ensure whether it is the first time to create evict-leader-scheduler
 	// or just update the evict-leader. But is add one ttl time.
 	switch cmd.Name() {
@@ -281,6 +332,16 @@ func NewBalanceHotRegionSchedulerCommand() *cobra.Command {
 	return c
 }
 
+// NewEvictSlowStoreSchedulerCommand returns a command to add an evict-slow-store-scheduler.
+func NewEvictSlowStoreSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "evict-slow-store-scheduler",
+		Short: "add a scheduler to detect and evict leaders from the slow store automatically",
+		Run:   addSchedulerCommandFunc,
+	}
+	return c
+}
+
 // NewRandomMergeSchedulerCommand returns a command to add a random-merge-scheduler.
 func NewRandomMergeSchedulerCommand() *cobra.Command {
 	c := &cobra.Command{
@@ -405,6 +466,15 @@ func removeSchedulerCommandFunc(cmd *cobra.Command, args []string) {
 	}
 	// FIXME: maybe there is a more graceful method to handler it
 	switch {
+	case strings.HasPrefix(args[0], evictLeaderSchedulerName+"-") && strings.Contains(args[0], "="):
+		// The label selector of the evict-leader-scheduler is removed by the server.
+		path := schedulersPrefix + "/" + url.PathEscape(args[0])
+		_, err := doRequest(cmd, path, http.MethodDelete)
+		if err != nil {
+			cmd.Println(err)
+			return
+		}
+		cmd.Println("Success!")
 	case strings.HasPrefix(args[0], evictLeaderSchedulerName) && args[0] != evictLeaderSchedulerName:
 		redirectReomveSchedulerToDeleteConfig(cmd, evictLeaderSchedulerName, args)
 	case strings.HasPrefix(args[0], grantLeaderSchedulerName) && args[0] != grantLeaderSchedulerName:
@@ -432,6 +502,7 @@ func NewConfigSchedulerCommand() *cobra.Command {
 		newConfigGrantLeaderCommand(),
 		newConfigHotRegionCommand(),
 		newConfigShuffleRegionCommand(),
+		newConfigEvictSlowStoreCommand(),
 	)
 	return c
 }
@@ -453,6 +524,19 @@ func newConfigHotRegionCommand() *cobra.Command {
 	return c
 }
 
+func newConfigEvictSlowStoreCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "evict-slow-store-scheduler",
+		Short: "show evict-slow-store-scheduler config and the detected slow store",
+		Run:   listSchedulerConfigCommandFunc,
+	}
+	c.AddCommand(&cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "set the config item",
+		Run:   func(cmd *cobra.Command, args []string) { postSchedulerConfigCommandFunc(cmd, c.Name(), args) }})
+	return c
+}
+
 func newConfigEvictLeaderCommand() *cobra.Command {
 	c := &cobra.Command{
 		Use:   "evict-leader-scheduler",